@@ -0,0 +1,100 @@
+package client
+
+import (
+	"sync/atomic"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Output backpressure policies
+const (
+	BackpressurePolicyLog  = "log"  // Only log sustained backpressure
+	BackpressurePolicyFail = "fail" // Fail the task with "output backpressure"
+)
+
+// backpressureCheckInterval is how often the output buffer fill level is sampled
+const backpressureCheckInterval = time.Second
+
+// backpressureMonitor watches a task's output buffer and trips when it stays full too long
+// A full buffer means sends to the master are not keeping up and the command is stalled
+type backpressureMonitor struct {
+	taskID    string
+	outputs   chan *pb.TaskOutput
+	threshold time.Duration
+	policy    string
+	onTrip    func() // Called once when the fail policy triggers
+	tripped   atomic.Bool
+	done      chan struct{}
+}
+
+// newBackpressureMonitor creates a monitor for a task's output channel
+func newBackpressureMonitor(taskID string, outputs chan *pb.TaskOutput, threshold time.Duration, policy string, onTrip func()) *backpressureMonitor {
+	return &backpressureMonitor{
+		taskID:    taskID,
+		outputs:   outputs,
+		threshold: threshold,
+		policy:    policy,
+		onTrip:    onTrip,
+		done:      make(chan struct{}),
+	}
+}
+
+// run samples the buffer until stop is called
+func (m *backpressureMonitor) run() {
+	ticker := time.NewTicker(backpressureCheckInterval)
+	defer ticker.Stop()
+
+	var fullSince time.Time
+	warned := false
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			if len(m.outputs) < cap(m.outputs) {
+				if warned {
+					logger.Info("Task output backpressure cleared",
+						zap.String("task_id", m.taskID),
+						zap.Duration("stalled_for", now.Sub(fullSince)),
+					)
+				}
+				fullSince = time.Time{}
+				warned = false
+				continue
+			}
+
+			if fullSince.IsZero() {
+				fullSince = now
+				continue
+			}
+			if warned || now.Sub(fullSince) < m.threshold {
+				continue
+			}
+
+			warned = true
+			logger.Warn("Sustained task output backpressure, master is not keeping up",
+				zap.String("task_id", m.taskID),
+				zap.Duration("stalled_for", now.Sub(fullSince)),
+				zap.String("policy", m.policy),
+			)
+			if m.policy == BackpressurePolicyFail {
+				m.tripped.Store(true)
+				m.onTrip()
+				return
+			}
+		}
+	}
+}
+
+// isTripped reports whether the fail policy has triggered
+func (m *backpressureMonitor) isTripped() bool {
+	return m.tripped.Load()
+}
+
+// stop ends monitoring
+func (m *backpressureMonitor) stop() {
+	close(m.done)
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestBackpressureMonitor(t *testing.T) {
+	full := make(chan *pb.TaskOutput, 1)
+	full <- &pb.TaskOutput{}
+
+	tripped := make(chan struct{})
+	fail := newBackpressureMonitor("t1", full, time.Millisecond, BackpressurePolicyFail, func() { close(tripped) })
+	logOnly := newBackpressureMonitor("t2", full, time.Millisecond, BackpressurePolicyLog, func() {
+		t.Error("log policy called onTrip")
+	})
+	go fail.run()
+	go logOnly.run()
+	defer logOnly.stop()
+
+	// The buffer must be seen full on two samples before it counts as sustained
+	select {
+	case <-tripped:
+	case <-time.After(3 * backpressureCheckInterval):
+		t.Fatal("fail policy did not trip on a full buffer")
+	}
+	if !fail.isTripped() {
+		t.Error("isTripped() = false after tripping")
+	}
+	if logOnly.isTripped() {
+		t.Error("log policy tripped")
+	}
+
+	// A buffer with room never trips
+	free := newBackpressureMonitor("t3", make(chan *pb.TaskOutput, 1), time.Millisecond, BackpressurePolicyFail, func() {
+		t.Error("tripped with room in the buffer")
+	})
+	go free.run()
+	time.Sleep(2*backpressureCheckInterval + 100*time.Millisecond)
+	free.stop()
+	if free.isTripped() {
+		t.Error("isTripped() = true with room in the buffer")
+	}
+}
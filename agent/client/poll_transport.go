@@ -0,0 +1,199 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// pollOutboxMax bounds the messages waiting for the next poll
+	pollOutboxMax = 10000
+	// pollMaxResponseBytes bounds one poll response from the master
+	pollMaxResponseBytes = 64 << 20
+	// pollRequestTimeout bounds one poll round trip
+	pollRequestTimeout = 30 * time.Second
+)
+
+// errPollOutboxFull is returned by Send when polls do not keep up with the agent's messages
+var errPollOutboxFull = errors.New("poll outbox full")
+
+// pollTransport carries the agent's stream messages over short HTTP polls
+// Messages sent are batched into the next poll, which runs every interval or as soon as
+// something is queued; the master's replies come back through Recv. It implements the
+// gRPC stream client so StreamClient works unchanged in poll mode.
+type pollTransport struct {
+	grpc.ClientStream // Not used; only Send, Recv, CloseSend and Context are called
+
+	url      string
+	header   http.Header // Credentials sent with every poll
+	client   *http.Client
+	interval time.Duration
+
+	mu     sync.Mutex
+	outbox []*pb.AgentMessage
+	err    error // Why the transport stopped, returned by Recv
+
+	wake      chan struct{}
+	inbox     chan *pb.MasterMessage
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+// newPollTransport starts polling url every interval
+func newPollTransport(url string, header http.Header, client *http.Client, interval time.Duration) *pollTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &pollTransport{
+		url:      url,
+		header:   header,
+		client:   client,
+		interval: interval,
+		wake:     make(chan struct{}, 1),
+		inbox:    make(chan *pb.MasterMessage, 100),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	go t.run()
+	return t
+}
+
+// Send queues a message for the next poll
+func (t *pollTransport) Send(msg *pb.AgentMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.err != nil {
+		return t.err
+	}
+	if len(t.outbox) >= pollOutboxMax {
+		return errPollOutboxFull
+	}
+	t.outbox = append(t.outbox, msg)
+
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Recv returns the next message from the master, or the error that stopped polling
+func (t *pollTransport) Recv() (*pb.MasterMessage, error) {
+	select {
+	case msg := <-t.inbox:
+		return msg, nil
+	case <-t.ctx.Done():
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return nil, t.err
+	}
+}
+
+// CloseSend stops polling; Recv then returns io.EOF
+func (t *pollTransport) CloseSend() error {
+	t.stop(io.EOF)
+	return nil
+}
+
+// Context returns the transport's context, cancelled when polling stops
+func (t *pollTransport) Context() context.Context {
+	return t.ctx
+}
+
+// stop ends polling with err (the first reason wins)
+func (t *pollTransport) stop(err error) {
+	t.closeOnce.Do(func() {
+		t.mu.Lock()
+		t.err = err
+		t.mu.Unlock()
+		t.cancel()
+	})
+}
+
+// run polls until the transport is stopped or a poll fails
+func (t *pollTransport) run() {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-t.wake:
+		}
+
+		if err := t.poll(); err != nil {
+			if t.ctx.Err() == nil {
+				logger.Warn("Poll to master failed", zap.Error(err))
+			}
+			t.stop(err)
+			return
+		}
+	}
+}
+
+// poll sends the queued messages and delivers the master's replies to Recv
+func (t *pollTransport) poll() error {
+	t.mu.Lock()
+	batch := t.outbox
+	t.outbox = nil
+	t.mu.Unlock()
+
+	body, err := proto.Marshal(&pb.PollRequest{Messages: batch})
+	if err != nil {
+		return fmt.Errorf("failed to encode poll: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.ctx, pollRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = t.header.Clone()
+	req.Header.Set("Content-Type", "application/x-protobuf")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("poll failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("poll rejected: %s: %s", resp.Status, strings.TrimSpace(string(reason)))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, pollMaxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read poll response: %w", err)
+	}
+	var polled pb.PollResponse
+	if err := proto.Unmarshal(data, &polled); err != nil {
+		return fmt.Errorf("invalid poll response: %w", err)
+	}
+
+	for _, msg := range polled.Messages {
+		select {
+		case t.inbox <- msg:
+		case <-t.ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakePollMaster answers each poll with respond, recording the messages it was sent
+func fakePollMaster(t *testing.T, respond func(w http.ResponseWriter, req *pb.PollRequest)) (*httptest.Server, chan *pb.AgentMessage) {
+	t.Helper()
+	received := make(chan *pb.AgentMessage, 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			http.Error(w, "missing API key", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req pb.PollRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			http.Error(w, "invalid poll request", http.StatusBadRequest)
+			return
+		}
+		for _, msg := range req.Messages {
+			received <- msg
+		}
+		respond(w, &req)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+func TestPollTransportRoundTrip(t *testing.T) {
+	srv, received := fakePollMaster(t, func(w http.ResponseWriter, req *pb.PollRequest) {
+		var resp pb.PollResponse
+		for _, msg := range req.Messages {
+			if msg.Type == pb.AgentMessage_TYPE_REGISTER {
+				resp.Messages = append(resp.Messages, &pb.MasterMessage{
+					Type:    pb.MasterMessage_TYPE_REGISTER_RESPONSE,
+					Payload: &pb.MasterMessage_RegisterResponse{RegisterResponse: &pb.RegisterResponse{Success: true}},
+				})
+			}
+		}
+		data, _ := proto.Marshal(&resp)
+		w.Write(data)
+	})
+
+	transport := newPollTransport(srv.URL, http.Header{"X-Api-Key": []string{"secret"}}, srv.Client(), time.Hour)
+	defer transport.CloseSend()
+
+	// Sending wakes the transport up without waiting for the interval
+	if err := transport.Send(&pb.AgentMessage{Type: pb.AgentMessage_TYPE_REGISTER}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	select {
+	case msg := <-received:
+		if msg.Type != pb.AgentMessage_TYPE_REGISTER {
+			t.Errorf("master received %v, want REGISTER", msg.Type)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not polled")
+	}
+
+	msg, err := transport.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if !msg.GetRegisterResponse().GetSuccess() {
+		t.Errorf("Recv() = %v, want a successful register response", msg)
+	}
+
+	transport.CloseSend()
+	if _, err := transport.Recv(); err != io.EOF {
+		t.Errorf("Recv() after CloseSend = %v, want EOF", err)
+	}
+}
+
+func TestPollTransportStopsWhenSessionExpired(t *testing.T) {
+	// The master dropped the session: every poll is refused until the agent registers again
+	srv, _ := fakePollMaster(t, func(w http.ResponseWriter, req *pb.PollRequest) {
+		http.Error(w, "agent not registered", http.StatusConflict)
+	})
+
+	transport := newPollTransport(srv.URL, http.Header{"X-Api-Key": []string{"secret"}}, srv.Client(), time.Hour)
+	if err := transport.Send(&pb.AgentMessage{Type: pb.AgentMessage_TYPE_HEARTBEAT}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	// Recv fails, so the stream client reconnects and registers on a new transport
+	_, err := transport.Recv()
+	if err == nil || !strings.Contains(err.Error(), "409") || !strings.Contains(err.Error(), "agent not registered") {
+		t.Errorf("Recv() error = %v, want the 409 rejection", err)
+	}
+	if err := transport.Send(&pb.AgentMessage{Type: pb.AgentMessage_TYPE_HEARTBEAT}); err == nil {
+		t.Error("Send() succeeded on a stopped transport")
+	}
+}
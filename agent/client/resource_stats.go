@@ -0,0 +1,38 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// ResourceStats holds host resource usage reported in heartbeats
+type ResourceStats struct {
+	MemPercent  float64
+	DiskPercent float64
+}
+
+// StatsSource returns the current resource usage of the host
+type StatsSource func() (*ResourceStats, error)
+
+// NewHostStatsSource creates a StatsSource backed by gopsutil
+// diskPath selects the filesystem whose usage is reported (e.g. "/")
+func NewHostStatsSource(diskPath string) StatsSource {
+	return func() (*ResourceStats, error) {
+		vm, err := mem.VirtualMemory()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read memory stats: %w", err)
+		}
+
+		usage, err := disk.Usage(diskPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read disk stats for %s: %w", diskPath, err)
+		}
+
+		return &ResourceStats{
+			MemPercent:  vm.UsedPercent,
+			DiskPercent: usage.UsedPercent,
+		}, nil
+	}
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/lureiny/lookingglass/agent/config"
+	pb "github.com/lureiny/lookingglass/pb"
+	"google.golang.org/grpc"
+)
+
+// sentStream records the messages an agent sends on its stream
+type sentStream struct {
+	grpc.ClientStream
+	mu   sync.Mutex
+	sent []*pb.AgentMessage
+}
+
+func (s *sentStream) Send(msg *pb.AgentMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+// taskOutputs returns the task outputs sent so far
+func (s *sentStream) taskOutputs() []*pb.TaskOutput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var outputs []*pb.TaskOutput
+	for _, msg := range s.sent {
+		if output := msg.GetTaskOutput(); output != nil {
+			outputs = append(outputs, output)
+		}
+	}
+	return outputs
+}
+
+func (s *sentStream) Recv() (*pb.MasterMessage, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestHeartbeatReportsResourceStats(t *testing.T) {
+	c := NewStreamClient(&config.Config{Agent: config.AgentConfig{ID: "agent-1"}}, func() int { return 2 }, nil, nil)
+	stream := &sentStream{}
+	c.stream = stream
+
+	// Without a stats source nothing is reported
+	if err := c.sendHeartbeat(); err != nil {
+		t.Fatalf("sendHeartbeat() error = %v", err)
+	}
+	c.SetStatsSource(func() (*ResourceStats, error) {
+		return &ResourceStats{MemPercent: 41.5, DiskPercent: 87.25}, nil
+	})
+	if err := c.sendHeartbeat(); err != nil {
+		t.Fatalf("sendHeartbeat() error = %v", err)
+	}
+	// A failing source still sends the heartbeat, without stats
+	c.SetStatsSource(func() (*ResourceStats, error) { return nil, errors.New("unavailable") })
+	if err := c.sendHeartbeat(); err != nil {
+		t.Fatalf("sendHeartbeat() error = %v", err)
+	}
+
+	if len(stream.sent) != 3 {
+		t.Fatalf("sent %d messages, want 3 heartbeats", len(stream.sent))
+	}
+	for i, want := range []*ResourceStats{{}, {MemPercent: 41.5, DiskPercent: 87.25}, {}} {
+		hb := stream.sent[i].GetHeartbeat()
+		if hb.GetAgentId() != "agent-1" || hb.GetCurrentTasks() != 2 {
+			t.Errorf("heartbeat %d = %v", i, hb)
+		}
+		if hb.GetMemPercent() != want.MemPercent || hb.GetDiskPercent() != want.DiskPercent {
+			t.Errorf("heartbeat %d stats = %v/%v, want %v/%v", i, hb.GetMemPercent(), hb.GetDiskPercent(), want.MemPercent, want.DiskPercent)
+		}
+	}
+}
+
+func TestHostStatsSource(t *testing.T) {
+	stats, err := NewHostStatsSource("/")()
+	if err != nil {
+		t.Skipf("host stats unavailable: %v", err)
+	}
+	if stats.MemPercent <= 0 || stats.MemPercent > 100 || stats.DiskPercent < 0 || stats.DiskPercent > 100 {
+		t.Errorf("stats = %+v, want percentages", stats)
+	}
+
+	if _, err := NewHostStatsSource("/no/such/path")(); err == nil {
+		t.Error("missing disk path: no error")
+	}
+}
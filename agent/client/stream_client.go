@@ -2,22 +2,30 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
+	"net/http"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lureiny/lookingglass/agent/config"
+	"github.com/lureiny/lookingglass/agent/executor"
 	"github.com/lureiny/lookingglass/agent/task"
 	pb "github.com/lureiny/lookingglass/pb"
 	"github.com/lureiny/lookingglass/pkg/logger"
+	"github.com/lureiny/lookingglass/pkg/netutil"
+	"github.com/lureiny/lookingglass/pkg/outputblock"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // StreamClient handles bidirectional stream communication with the master server
@@ -30,6 +38,8 @@ type StreamClient struct {
 	taskCountFunc   func() int
 	taskDisplayInfo []*pb.TaskDisplayInfo // Task display info (name + display_name)
 	taskManager     *task.Manager
+	statsSource     StatsSource             // Optional host resource stats for heartbeats (nil = not reported)
+	targetDenylist  *netutil.TargetDenylist // Targets tasks are refused for (nil = none)
 
 	// Reconnection management
 	stopChan        chan struct{}
@@ -42,8 +52,17 @@ type StreamClient struct {
 	// Heartbeat
 	heartbeatTicker   *time.Ticker
 	heartbeatInterval time.Duration
+
+	// Shutdown draining
+	drainMutex     sync.Mutex
+	draining       bool           // Set on Stop: new tasks are rejected
+	drainCancelled bool           // Set once tasks still running after the drain timeout are cancelled
+	inflight       sync.WaitGroup // Tasks being executed or forwarded (Add only while not draining)
 }
 
+// drainCancelGrace bounds how long cancelled tasks get to report before the stream closes
+const drainCancelGrace = 5 * time.Second
+
 // NewStreamClient creates a new stream-based master client
 func NewStreamClient(cfg *config.Config, taskCountFunc func() int, taskDisplayInfo []*pb.TaskDisplayInfo, taskMgr *task.Manager) *StreamClient {
 	return &StreamClient{
@@ -60,6 +79,16 @@ func NewStreamClient(cfg *config.Config, taskCountFunc func() int, taskDisplayIn
 	}
 }
 
+// SetTargetDenylist sets the targets tasks are refused for (nil = none)
+func (c *StreamClient) SetTargetDenylist(denylist *netutil.TargetDenylist) {
+	c.targetDenylist = denylist
+}
+
+// SetStatsSource sets the source of resource stats included in heartbeats
+func (c *StreamClient) SetStatsSource(source StatsSource) {
+	c.statsSource = source
+}
+
 // Start establishes the stream connection and starts the client
 func (c *StreamClient) Start() error {
 	logger.Info("Starting stream client")
@@ -78,40 +107,15 @@ func (c *StreamClient) Start() error {
 
 // connect establishes connection and stream to master
 func (c *StreamClient) connect() error {
-	logger.Info("Connecting to master server",
-		zap.String("host", c.config.Master.Host),
-	)
-
-	// Setup dial options
-	// 客户端将每隔 30 秒发送一次 PING 帧
-	connParams := keepalive.ClientParameters{
-		Time:                30 * time.Second, // PING 间隔时间
-		Timeout:             10 * time.Second, // 等待服务器 PONG 的超时时间
-		PermitWithoutStream: true,             // 允许在没有活动应用流时发送 PING
-	}
-	opts := []grpc.DialOption{grpc.WithKeepaliveParams(connParams)}
-
-	if c.config.Master.TLSEnabled {
-		// 使用tls
-		opts = append(opts, grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(nil, "")))
+	var stream pb.MasterService_AgentStreamClient
+	var err error
+	if c.config.Master.Mode == config.MasterModePoll {
+		stream, err = c.openPollStream()
 	} else {
-		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		stream, err = c.openGRPCStream()
 	}
-
-	conn, err := grpc.NewClient(c.config.Master.Host, opts...)
 	if err != nil {
-		return fmt.Errorf("failed to connect to master: %w", err)
-	}
-
-	c.conn = conn
-	c.client = pb.NewMasterServiceClient(conn)
-
-	// Create bidirectional stream with auth metadata
-	ctx := c.createContextWithAuth(context.Background())
-	stream, err := c.client.AgentStream(ctx)
-	if err != nil {
-		c.conn.Close()
-		return fmt.Errorf("failed to create stream: %w", err)
+		return err
 	}
 
 	c.streamMutex.Lock()
@@ -167,6 +171,78 @@ func (c *StreamClient) connect() error {
 	return nil
 }
 
+// openGRPCStream dials the master and opens the bidirectional gRPC stream
+func (c *StreamClient) openGRPCStream() (pb.MasterService_AgentStreamClient, error) {
+	logger.Info("Connecting to master server",
+		zap.String("host", c.config.Master.Host),
+	)
+
+	// Setup dial options
+	// 客户端将每隔 30 秒发送一次 PING 帧
+	connParams := keepalive.ClientParameters{
+		Time:                30 * time.Second, // PING 间隔时间
+		Timeout:             10 * time.Second, // 等待服务器 PONG 的超时时间
+		PermitWithoutStream: true,             // 允许在没有活动应用流时发送 PING
+	}
+	opts := []grpc.DialOption{grpc.WithKeepaliveParams(connParams)}
+
+	if c.config.Master.TLSEnabled {
+		// 使用tls
+		tlsConfig, err := c.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+
+	conn, err := grpc.NewClient(c.config.Master.Host, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to master: %w", err)
+	}
+
+	c.conn = conn
+	c.client = pb.NewMasterServiceClient(conn)
+
+	// Create bidirectional stream with auth metadata
+	ctx := c.createContextWithAuth(context.Background())
+	stream, err := c.client.AgentStream(ctx)
+	if err != nil {
+		c.conn.Close()
+		return nil, fmt.Errorf("failed to create stream: %w", err)
+	}
+	return stream, nil
+}
+
+// openPollStream starts polling the master over HTTP in place of a gRPC stream
+func (c *StreamClient) openPollStream() (pb.MasterService_AgentStreamClient, error) {
+	logger.Info("Polling master server",
+		zap.String("url", c.config.Master.PollURL),
+		zap.Int("interval", c.config.Master.PollInterval),
+	)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.config.Master.TLSEnabled {
+		tlsConfig, err := c.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	header := http.Header{}
+	header.Set("X-API-Key", c.config.Master.APIKey)
+	header.Set("X-Agent-ID", c.config.Agent.ID)
+
+	return newPollTransport(
+		c.config.Master.PollURL,
+		header,
+		&http.Client{Transport: transport},
+		time.Duration(c.config.Master.PollInterval)*time.Second,
+	), nil
+}
+
 // sendRegistration sends a registration message to the master
 func (c *StreamClient) sendRegistration() error {
 	agentInfo := &pb.AgentInfo{
@@ -182,6 +258,7 @@ func (c *StreamClient) sendRegistration() error {
 		Provider:        c.config.Agent.Metadata.Provider,
 		Idc:             c.config.Agent.Metadata.IDC,
 		Description:     c.config.Agent.Metadata.Description,
+		Tags:            c.config.Agent.Metadata.Tags,
 	}
 
 	msg := &pb.AgentMessage{
@@ -239,14 +316,29 @@ func (c *StreamClient) sendHeartbeat() error {
 		currentTasks = c.taskCountFunc()
 	}
 
+	heartbeat := &pb.HeartbeatRequest{
+		AgentId:      c.config.Agent.ID,
+		CurrentTasks: int32(currentTasks),
+	}
+
+	if c.taskManager != nil {
+		heartbeat.TaskConcurrency = c.taskManager.GetTaskConcurrency()
+	}
+
+	if c.statsSource != nil {
+		if stats, err := c.statsSource(); err != nil {
+			logger.Warn("Failed to collect resource stats", zap.Error(err))
+		} else {
+			heartbeat.MemPercent = stats.MemPercent
+			heartbeat.DiskPercent = stats.DiskPercent
+		}
+	}
+
 	msg := &pb.AgentMessage{
 		RequestId: uuid.New().String(),
 		Type:      pb.AgentMessage_TYPE_HEARTBEAT,
 		Payload: &pb.AgentMessage_Heartbeat{
-			Heartbeat: &pb.HeartbeatRequest{
-				AgentId:      c.config.Agent.ID,
-				CurrentTasks: int32(currentTasks),
-			},
+			Heartbeat: heartbeat,
 		},
 	}
 
@@ -313,6 +405,20 @@ func (c *StreamClient) handleMasterMessage(msg *pb.MasterMessage) {
 	case pb.MasterMessage_TYPE_CANCEL_TASK:
 		c.handleCancelTask(msg)
 
+	case pb.MasterMessage_TYPE_DESCRIBE:
+		c.handleDescribe(msg)
+
+	case pb.MasterMessage_TYPE_LOGS:
+		c.handleLogs(msg)
+
+	case pb.MasterMessage_TYPE_PROBE:
+		if err := c.sendMessage(&pb.AgentMessage{
+			RequestId: msg.RequestId,
+			Type:      pb.AgentMessage_TYPE_PROBE_RESPONSE,
+		}); err != nil {
+			logger.Error("Failed to send probe response", zap.Error(err))
+		}
+
 	default:
 		logger.Warn("Unknown message type from master",
 			zap.Int32("type", int32(msg.Type)),
@@ -328,12 +434,80 @@ func (c *StreamClient) handleExecuteTask(msg *pb.MasterMessage) {
 		return
 	}
 
+	// Tasks from older clients may carry only the deprecated TaskType
+	if req.Task.TaskName == "" {
+		if name, err := task.ResolveTaskName(req.Task); err == nil {
+			req.Task.TaskName = name
+		}
+	}
+
 	task := req.Task
 	logger.Info("Received task execution request",
 		zap.String("task_id", task.TaskId),
 		zap.String("type", task.Type.String()),
 	)
 
+	// Refuse new work once shutdown has started
+	c.drainMutex.Lock()
+	if c.draining {
+		c.drainMutex.Unlock()
+		c.sendTaskOutput(&pb.TaskOutput{
+			TaskId:       task.TaskId,
+			Status:       pb.TaskStatus_TASK_STATUS_FAILED,
+			ErrorMessage: "agent shutting down",
+			AgentFault:   true,
+		})
+		return
+	}
+	c.inflight.Add(1)
+	c.drainMutex.Unlock()
+	defer c.inflight.Done()
+
+	// Enforce the local allowlist regardless of what the master dispatches
+	if !c.config.Executor.IsTaskAllowed(task.TaskName) {
+		logger.Warn("Rejected task not allowed on this agent",
+			zap.String("task_id", task.TaskId),
+			zap.String("task_name", task.TaskName),
+		)
+		c.sendTaskOutput(&pb.TaskOutput{
+			TaskId:       task.TaskId,
+			Status:       pb.TaskStatus_TASK_STATUS_FAILED,
+			ErrorMessage: fmt.Sprintf("task %q is not allowed on this agent", task.TaskName),
+		})
+		return
+	}
+
+	// Enforce the local target denylist on every host the task may contact, on the hostname
+	// and on what it resolves to
+	if err := c.checkTargets(task); err != nil {
+		logger.Warn("Rejected task target blocked by policy",
+			zap.String("task_id", task.TaskId),
+			zap.Error(err),
+		)
+		c.sendTaskOutput(&pb.TaskOutput{
+			TaskId:       task.TaskId,
+			Status:       pb.TaskStatus_TASK_STATUS_FAILED,
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	// Reject tasks whose absolute deadline has already passed
+	if task.Deadline != nil && !time.Now().Before(task.Deadline.AsTime()) {
+		logger.Warn("Task deadline already passed",
+			zap.String("task_id", task.TaskId),
+		)
+		c.sendTaskOutput(&pb.TaskOutput{
+			TaskId:       task.TaskId,
+			Status:       pb.TaskStatus_TASK_STATUS_FAILED,
+			ErrorMessage: "task deadline already passed",
+		})
+		return
+	}
+
+	// Record the start in the agent's own time, reported with the final status
+	startedAt := time.Now()
+
 	// Create output channel
 	outputChan := make(chan *pb.TaskOutput, 100)
 
@@ -342,30 +516,159 @@ func (c *StreamClient) handleExecuteTask(msg *pb.MasterMessage) {
 		defer close(outputChan)
 
 		ctx := context.Background()
+		if task.Deadline != nil {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, task.Deadline.AsTime())
+			defer cancel()
+		}
 		if err := c.taskManager.Execute(ctx, task, outputChan); err != nil {
 			logger.Error("Task execution failed",
 				zap.String("task_id", task.TaskId),
 				zap.Error(err),
 			)
 			// Send error message
-			c.sendTaskOutput(&pb.TaskOutput{
+			failed := &pb.TaskOutput{
 				TaskId:       task.TaskId,
 				Status:       pb.TaskStatus_TASK_STATUS_FAILED,
 				ErrorMessage: err.Error(),
-			})
+				AgentFault:   executor.IsAgentFault(err),
+			}
+			attachLocalTime(failed, startedAt)
+			c.sendTaskOutput(failed)
 		}
 	}()
 
-	// Forward output to master
+	// Watch for the master not keeping up with this task's output
+	backpressure := c.config.Executor.OutputBackpressure
+	var monitor *backpressureMonitor
+	if backpressure.Threshold > 0 {
+		monitor = newBackpressureMonitor(task.TaskId, outputChan,
+			time.Duration(backpressure.Threshold)*time.Second, backpressure.Policy,
+			func() { _ = c.taskManager.Cancel(task.TaskId) })
+		go monitor.run()
+		defer monitor.stop()
+	}
+	failedBackpressure := false
+	undeliverable := false
+
+	// Forward output to master, numbering every output so the master can spot drops
+	var sequence uint64
+	var held []string // Output lines of a compress_output task, sent as gzip blocks
+	heldBytes := 0
+	flushHeld := func() {
+		if len(held) > 0 && !undeliverable && !(monitor != nil && monitor.isTripped()) {
+			sequence++
+			if err := c.sendCompressedOutput(task.TaskId, held, sequence); err != nil {
+				logger.Error("Failed to send compressed task output, cancelling task",
+					zap.String("task_id", task.TaskId),
+					zap.Error(err),
+				)
+				undeliverable = true
+				_ = c.taskManager.Cancel(task.TaskId)
+			}
+		}
+		held, heldBytes = nil, 0
+	}
 	for output := range outputChan {
+		if task.CompressOutput {
+			if output.Status == pb.TaskStatus_TASK_STATUS_RUNNING && output.OutputLine != "" {
+				held = append(held, output.OutputLine)
+				heldBytes += len(output.OutputLine) + 1
+				// Lines are sent in blocks at completion, or earlier once a block is full
+				if heldBytes >= outputblock.MaxRawBytes {
+					flushHeld()
+				}
+				if output.Summary == nil && output.ErrorMessage == "" {
+					continue
+				}
+				output.OutputLine = ""
+			}
+			if isFinalStatus(output.Status) {
+				flushHeld()
+			}
+		}
+
+		sequence++
+		output.Sequence = sequence
+
+		if output.Status == pb.TaskStatus_TASK_STATUS_CANCELLED && c.isDrainCancelled() {
+			output.ErrorMessage = "agent shutting down"
+		}
+
+		if isFinalStatus(output.Status) {
+			attachLocalTime(output, startedAt)
+		}
+
+		// Once the fail policy trips, report the failure and drain the rest without sending
+		if monitor != nil && monitor.isTripped() {
+			if !failedBackpressure {
+				failedBackpressure = true
+				c.sendTaskOutput(&pb.TaskOutput{
+					TaskId:       task.TaskId,
+					Timestamp:    timestamppb.New(time.Now()),
+					Status:       pb.TaskStatus_TASK_STATUS_FAILED,
+					ErrorMessage: "output backpressure",
+				})
+			}
+			continue
+		}
+
+		// Once output cannot be delivered, stop the task and drain the rest without sending
+		if undeliverable {
+			continue
+		}
+
 		if err := c.sendTaskOutput(output); err != nil {
-			logger.Error("Failed to send task output",
+			logger.Error("Failed to send task output, cancelling task",
 				zap.String("task_id", task.TaskId),
 				zap.Error(err),
 			)
-			break
+			undeliverable = true
+			_ = c.taskManager.Cancel(task.TaskId)
+		}
+	}
+}
+
+// sendCompressedOutput sends the held output lines of a task as one gzip block
+func (c *StreamClient) sendCompressedOutput(taskID string, lines []string, sequence uint64) error {
+	block, err := outputblock.Encode(lines)
+	if err != nil {
+		return err
+	}
+	return c.sendTaskOutput(&pb.TaskOutput{
+		TaskId:           taskID,
+		Timestamp:        timestamppb.New(time.Now()),
+		Status:           pb.TaskStatus_TASK_STATUS_RUNNING,
+		CompressedOutput: block,
+		Sequence:         sequence,
+	})
+}
+
+// isFinalStatus reports whether status ends a task
+func isFinalStatus(status pb.TaskStatus) bool {
+	return status == pb.TaskStatus_TASK_STATUS_COMPLETED ||
+		status == pb.TaskStatus_TASK_STATUS_FAILED ||
+		status == pb.TaskStatus_TASK_STATUS_CANCELLED
+}
+
+// attachLocalTime adds the task start in the agent's local time and time zone to the summary
+func attachLocalTime(output *pb.TaskOutput, startedAt time.Time) {
+	if output.Summary == nil {
+		output.Summary = &pb.TaskSummary{}
+	}
+	zone, _ := startedAt.Zone()
+	output.Summary.AgentStartTime = startedAt.Format(time.RFC3339)
+	output.Summary.AgentTimezone = zone
+}
+
+// checkTargets checks every host the task may contact against the target denylist
+func (c *StreamClient) checkTargets(task *pb.Task) error {
+	for _, target := range executor.TaskTargets(task.GetNetworkTest()) {
+		if err := c.targetDenylist.Check(context.Background(), target); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
 // handleCancelTask processes task cancellation requests
@@ -388,6 +691,51 @@ func (c *StreamClient) handleCancelTask(msg *pb.MasterMessage) {
 	}
 }
 
+// handleDescribe replies with the agent's effective config (secrets redacted)
+func (c *StreamClient) handleDescribe(msg *pb.MasterMessage) {
+	resp := &pb.DescribeResponse{AgentId: c.config.Agent.ID}
+
+	effective, err := c.config.Redacted().ToJSON()
+	if err != nil {
+		logger.Error("Failed to build effective config", zap.Error(err))
+		resp.Error = err.Error()
+	} else {
+		resp.EffectiveConfig = effective
+	}
+
+	if err := c.sendMessage(&pb.AgentMessage{
+		RequestId: msg.RequestId,
+		Type:      pb.AgentMessage_TYPE_DESCRIBE_RESPONSE,
+		Payload: &pb.AgentMessage_DescribeResponse{
+			DescribeResponse: resp,
+		},
+	}); err != nil {
+		logger.Error("Failed to send describe response", zap.Error(err))
+	}
+}
+
+// handleLogs replies with the agent's most recent log lines
+func (c *StreamClient) handleLogs(msg *pb.MasterMessage) {
+	resp := &pb.LogsResponse{AgentId: c.config.Agent.ID}
+
+	lines, ok := logger.Tail(int(msg.GetLogs().GetLines()))
+	if ok {
+		resp.Lines = lines
+	} else {
+		resp.Error = "log buffer disabled on this agent (log.ring_size)"
+	}
+
+	if err := c.sendMessage(&pb.AgentMessage{
+		RequestId: msg.RequestId,
+		Type:      pb.AgentMessage_TYPE_LOGS_RESPONSE,
+		Payload: &pb.AgentMessage_LogsResponse{
+			LogsResponse: resp,
+		},
+	}); err != nil {
+		logger.Error("Failed to send logs response", zap.Error(err))
+	}
+}
+
 // sendTaskOutput sends task output to the master
 func (c *StreamClient) sendTaskOutput(output *pb.TaskOutput) error {
 	var msgType pb.AgentMessage_Type
@@ -429,6 +777,13 @@ func (c *StreamClient) handleStreamError() {
 	logger.Warn("Stream error detected, triggering reconnection")
 	c.setConnected(false)
 	c.closeStream()
+
+	// Output of running tasks can no longer reach the master that dispatched them
+	if cancelled := c.taskManager.CancelAll(); cancelled > 0 {
+		logger.Warn("Cancelled running tasks after losing the master stream",
+			zap.Int("cancelled", cancelled),
+		)
+	}
 }
 
 // reconnectionLoop handles automatic reconnection with exponential backoff
@@ -504,9 +859,12 @@ func (c *StreamClient) setConnected(connected bool) {
 }
 
 // Stop stops the client and closes all connections
+// Running tasks are drained first (see drain)
 func (c *StreamClient) Stop() {
 	logger.Info("Stopping stream client")
 
+	c.drain()
+
 	close(c.stopChan)
 
 	if c.heartbeatTicker != nil {
@@ -516,10 +874,91 @@ func (c *StreamClient) Stop() {
 	c.closeStream()
 }
 
+// drain stops accepting tasks and waits up to the shutdown drain timeout for running ones
+// Tasks still running after that are cancelled and get a short grace period to report it
+func (c *StreamClient) drain() {
+	c.drainMutex.Lock()
+	c.draining = true
+	c.drainMutex.Unlock()
+
+	timeout := time.Duration(c.config.Agent.ShutdownDrainTimeout) * time.Second
+	if running := c.taskManager.GetCurrentTaskCount(); running > 0 {
+		logger.Info("Draining running tasks",
+			zap.Int("running", running),
+			zap.Duration("timeout", timeout),
+		)
+	}
+	if waitTimeout(&c.inflight, timeout) {
+		return
+	}
+
+	c.drainMutex.Lock()
+	c.drainCancelled = true
+	c.drainMutex.Unlock()
+
+	cancelled := c.taskManager.CancelAll()
+	logger.Warn("Drain timeout reached, cancelling remaining tasks",
+		zap.Int("cancelled", cancelled),
+	)
+	if !waitTimeout(&c.inflight, drainCancelGrace) {
+		logger.Warn("Cancelled tasks did not finish reporting before shutdown")
+	}
+}
+
+// isDrainCancelled reports whether running tasks were cancelled because of shutdown
+func (c *StreamClient) isDrainCancelled() bool {
+	c.drainMutex.Lock()
+	defer c.drainMutex.Unlock()
+	return c.drainCancelled
+}
+
+// waitTimeout waits for wg, reporting false if timeout elapses first
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// tlsConfig builds the client TLS settings: the master is verified against
+// tls_cert (or the system roots), and a client certificate is presented for
+// mutual TLS when configured
+func (c *StreamClient) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.config.Master.TLSCert != "" {
+		caPEM, err := os.ReadFile(c.config.Master.TLSCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read master CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in %s", c.config.Master.TLSCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.config.Master.TLSClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(c.config.Master.TLSClientCert, c.config.Master.TLSClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
 // createContextWithAuth creates a context with API key in metadata
 func (c *StreamClient) createContextWithAuth(ctx context.Context) context.Context {
 	md := metadata.New(map[string]string{
-		"x-api-key": c.config.Master.APIKey,
+		"x-api-key":  c.config.Master.APIKey,
+		"x-agent-id": c.config.Agent.ID, // Lets the master check a per-agent key
 	})
 	return metadata.NewOutgoingContext(ctx, md)
 }
@@ -0,0 +1,367 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lureiny/lookingglass/agent/config"
+	"github.com/lureiny/lookingglass/agent/executor"
+	"github.com/lureiny/lookingglass/agent/task"
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/netutil"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCheckTargetsCoversEveryHost(t *testing.T) {
+	c := NewStreamClient(&config.Config{}, func() int { return 0 }, nil, nil)
+	denylist, err := netutil.ParseTargetDenylist([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("ParseTargetDenylist() error = %v", err)
+	}
+	c.SetTargetDenylist(denylist)
+
+	task := func(target string, options map[string]string) *pb.Task {
+		return &pb.Task{
+			TaskId: "t1",
+			Params: &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{Target: target, ExtraOptions: options}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		task    *pb.Task
+		blocked bool
+	}{
+		{"allowed", task("1.1.1.1", nil), false},
+		{"primary target", task("10.0.0.1", nil), true},
+		{"comma-separated ping target", task("1.1.1.1,10.0.0.1", nil), true},
+		{"ping targets option", task("1.1.1.1", map[string]string{"targets": "8.8.8.8,10.0.0.1"}), true},
+		{"dig server", task("example.com", map[string]string{"server": "10.0.0.53"}), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.checkTargets(tt.task)
+			if (err != nil) != tt.blocked {
+				t.Errorf("checkTargets() error = %v, want blocked %v", err, tt.blocked)
+			}
+		})
+	}
+}
+
+// executeMessage wraps a task in an execute request from the master
+func executeMessage(task *pb.Task) *pb.MasterMessage {
+	return &pb.MasterMessage{
+		Type:    pb.MasterMessage_TYPE_EXECUTE_TASK,
+		Payload: &pb.MasterMessage_ExecuteTask{ExecuteTask: &pb.ExecuteTaskRequest{Task: task}},
+	}
+}
+
+func TestHandleExecuteTaskRejectsPastDeadline(t *testing.T) {
+	c := NewStreamClient(&config.Config{}, func() int { return 0 }, nil, nil)
+	stream := &sentStream{}
+	c.stream = stream
+
+	c.handleExecuteTask(executeMessage(&pb.Task{
+		TaskId:   "t1",
+		TaskName: "ping",
+		Deadline: timestamppb.New(time.Now().Add(-time.Second)),
+		Params:   &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{Target: "192.0.2.1"}},
+	}))
+
+	if len(stream.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1 failure", len(stream.sent))
+	}
+	output := stream.sent[0].GetTaskOutput()
+	if stream.sent[0].Type != pb.AgentMessage_TYPE_TASK_FAILED || output.GetErrorMessage() != "task deadline already passed" {
+		t.Errorf("sent %v, want a deadline failure", stream.sent[0])
+	}
+}
+
+func TestHandleExecuteTaskEnforcesAllowlist(t *testing.T) {
+	c := NewStreamClient(&config.Config{Executor: config.ExecutorConfig{AllowedTasks: []string{"ping"}}}, func() int { return 0 }, nil, nil)
+	stream := &sentStream{}
+	c.stream = stream
+
+	c.handleExecuteTask(executeMessage(&pb.Task{
+		TaskId:   "t1",
+		TaskName: "mtr",
+		Params:   &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{Target: "192.0.2.1"}},
+	}))
+
+	if len(stream.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1 failure", len(stream.sent))
+	}
+	if got := stream.sent[0].GetTaskOutput().GetErrorMessage(); got != `task "mtr" is not allowed on this agent` {
+		t.Errorf("error = %q, want the allowlist rejection", got)
+	}
+}
+
+func TestHandleDescribe(t *testing.T) {
+	c := NewStreamClient(&config.Config{
+		Agent:  config.AgentConfig{ID: "agent-1"},
+		Master: config.MasterConfig{APIKey: "secret"},
+	}, func() int { return 0 }, nil, nil)
+	stream := &sentStream{}
+	c.stream = stream
+
+	c.handleMasterMessage(&pb.MasterMessage{RequestId: "r1", Type: pb.MasterMessage_TYPE_DESCRIBE})
+
+	if len(stream.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(stream.sent))
+	}
+	msg := stream.sent[0]
+	resp := msg.GetDescribeResponse()
+	if msg.RequestId != "r1" || msg.Type != pb.AgentMessage_TYPE_DESCRIBE_RESPONSE || resp.GetAgentId() != "agent-1" {
+		t.Fatalf("response = %v", msg)
+	}
+	if strings.Contains(resp.EffectiveConfig, "secret") || !strings.Contains(resp.EffectiveConfig, `"api_key":"[REDACTED]"`) {
+		t.Errorf("effective config = %s, want the api key redacted", resp.EffectiveConfig)
+	}
+}
+
+// blockingExecutor emits one line, then runs until its task is cancelled
+type blockingExecutor struct{}
+
+func (blockingExecutor) Execute(ctx context.Context, t *pb.Task, outputChan chan<- *pb.TaskOutput) error {
+	outputChan <- &pb.TaskOutput{TaskId: t.TaskId, OutputLine: "line", Status: pb.TaskStatus_TASK_STATUS_RUNNING}
+	<-ctx.Done()
+	outputChan <- &pb.TaskOutput{TaskId: t.TaskId, Status: pb.TaskStatus_TASK_STATUS_CANCELLED}
+	return ctx.Err()
+}
+
+func (blockingExecutor) Cancel(taskID string) error { return nil }
+
+// newBlockingTaskManager returns a task manager running "block" tasks on blockingExecutor
+func newBlockingTaskManager(t *testing.T) *task.Manager {
+	t.Helper()
+	registry := executor.NewRegistry()
+	err := registry.Register("block", func(*config.TaskConfig) (executor.Executor, error) {
+		return blockingExecutor{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := task.NewManager(registry, 4)
+	if err := m.RegisterTask(&task.TaskInfo{Name: "block", ExecutorType: "block"}); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestUndeliverableOutputCancelsTask(t *testing.T) {
+	m := newBlockingTaskManager(t)
+	c := NewStreamClient(&config.Config{}, m.GetCurrentTaskCount, nil, m)
+
+	// No stream: the first output cannot be sent, so the task is cancelled instead of left running
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.handleExecuteTask(executeMessage(&pb.Task{TaskId: "t1", TaskName: "block"}))
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("task kept running after its output could not be delivered")
+	}
+	if n := m.GetCurrentTaskCount(); n != 0 {
+		t.Errorf("running tasks = %d, want 0", n)
+	}
+}
+
+func TestStreamErrorCancelsRunningTasks(t *testing.T) {
+	m := newBlockingTaskManager(t)
+	c := NewStreamClient(&config.Config{}, m.GetCurrentTaskCount, nil, m)
+
+	outputChan := make(chan *pb.TaskOutput, 4)
+	done := make(chan error, 1)
+	go func() { done <- m.Execute(context.Background(), &pb.Task{TaskId: "t1", TaskName: "block"}, outputChan) }()
+	<-outputChan
+
+	c.handleStreamError()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Execute() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("running task not cancelled when the stream dropped")
+	}
+}
+
+func TestAttachLocalTime(t *testing.T) {
+	startedAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.FixedZone("JST", 9*60*60))
+
+	output := &pb.TaskOutput{Status: pb.TaskStatus_TASK_STATUS_COMPLETED, Summary: &pb.TaskSummary{ResolvedIps: []string{"192.0.2.1"}}}
+	attachLocalTime(output, startedAt)
+	if output.Summary.AgentStartTime != "2026-03-01T09:30:00+09:00" || output.Summary.AgentTimezone != "JST" {
+		t.Errorf("summary = %v, want the local start time and zone", output.Summary)
+	}
+	if len(output.Summary.ResolvedIps) != 1 {
+		t.Error("existing summary fields dropped")
+	}
+
+	bare := &pb.TaskOutput{Status: pb.TaskStatus_TASK_STATUS_FAILED}
+	attachLocalTime(bare, startedAt)
+	if bare.Summary.GetAgentTimezone() != "JST" {
+		t.Errorf("summary = %v, want one created", bare.Summary)
+	}
+
+	for status, want := range map[pb.TaskStatus]bool{
+		pb.TaskStatus_TASK_STATUS_RUNNING:   false,
+		pb.TaskStatus_TASK_STATUS_COMPLETED: true,
+		pb.TaskStatus_TASK_STATUS_FAILED:    true,
+		pb.TaskStatus_TASK_STATUS_CANCELLED: true,
+	} {
+		if got := isFinalStatus(status); got != want {
+			t.Errorf("isFinalStatus(%v) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestHandleProbe(t *testing.T) {
+	c := NewStreamClient(&config.Config{}, func() int { return 0 }, nil, nil)
+	stream := &sentStream{}
+	c.stream = stream
+
+	c.handleMasterMessage(&pb.MasterMessage{RequestId: "r1", Type: pb.MasterMessage_TYPE_PROBE})
+
+	if len(stream.sent) != 1 || stream.sent[0].RequestId != "r1" || stream.sent[0].Type != pb.AgentMessage_TYPE_PROBE_RESPONSE {
+		t.Errorf("sent %v, want one probe response to r1", stream.sent)
+	}
+}
+
+func TestDrainCancelsTasksPastTimeout(t *testing.T) {
+	m := newBlockingTaskManager(t)
+	// A zero drain timeout cancels running tasks right away
+	c := NewStreamClient(&config.Config{}, m.GetCurrentTaskCount, nil, m)
+	stream := &sentStream{}
+	c.stream = stream
+
+	go c.handleExecuteTask(executeMessage(&pb.Task{TaskId: "t1", TaskName: "block"}))
+	deadline := time.Now().Add(2 * time.Second)
+	for m.GetCurrentTaskCount() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("task did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c.drain()
+	if n := m.GetCurrentTaskCount(); n != 0 {
+		t.Fatalf("running tasks = %d after drain, want 0", n)
+	}
+	var cancelled *pb.TaskOutput
+	for _, output := range stream.taskOutputs() {
+		if output.GetStatus() == pb.TaskStatus_TASK_STATUS_CANCELLED {
+			cancelled = output
+		}
+	}
+	if cancelled == nil || cancelled.GetErrorMessage() != "agent shutting down" {
+		t.Errorf("cancelled output = %v, want error agent shutting down", cancelled)
+	}
+
+	// No new work once draining has started
+	c.handleExecuteTask(executeMessage(&pb.Task{TaskId: "t2", TaskName: "block"}))
+	outputs := stream.taskOutputs()
+	rejected := outputs[len(outputs)-1]
+	if rejected.GetTaskId() != "t2" || rejected.GetStatus() != pb.TaskStatus_TASK_STATUS_FAILED || rejected.GetErrorMessage() != "agent shutting down" {
+		t.Errorf("output for a task received while draining = %v, want FAILED agent shutting down", rejected)
+	}
+}
+
+func TestDrainWaitsForRunningTasks(t *testing.T) {
+	m := newBlockingTaskManager(t)
+	c := NewStreamClient(&config.Config{Agent: config.AgentConfig{ShutdownDrainTimeout: 5}}, m.GetCurrentTaskCount, nil, m)
+	c.stream = &sentStream{}
+
+	go c.handleExecuteTask(executeMessage(&pb.Task{TaskId: "t1", TaskName: "block"}))
+	deadline := time.Now().Add(2 * time.Second)
+	for m.GetCurrentTaskCount() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("task did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The task finishes on its own well within the drain timeout
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		m.Cancel("t1")
+	}()
+	start := time.Now()
+	c.drain()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("drain took %s for a task that finished after 50ms", elapsed)
+	}
+	if c.isDrainCancelled() {
+		t.Error("drain cancelled a task that finished in time")
+	}
+}
+
+func TestDenylistedResolvedTargetNotExecuted(t *testing.T) {
+	m := newBlockingTaskManager(t)
+	c := NewStreamClient(&config.Config{}, m.GetCurrentTaskCount, nil, m)
+	stream := &sentStream{}
+	c.stream = stream
+	denylist, err := netutil.ParseTargetDenylist([]string{"127.0.0.0/8", "::1"})
+	if err != nil {
+		t.Fatalf("ParseTargetDenylist() error = %v", err)
+	}
+	c.SetTargetDenylist(denylist)
+
+	// The hostnames pass the patterns; only resolving them shows they are denied or unknown
+	for i, target := range []string{"localhost", "unresolvable.invalid"} {
+		taskID := fmt.Sprintf("t%d", i)
+		c.handleExecuteTask(executeMessage(&pb.Task{
+			TaskId:   taskID,
+			TaskName: "block",
+			Params:   &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{Target: target}},
+		}))
+
+		outputs := stream.taskOutputs()
+		last := outputs[len(outputs)-1]
+		if last.GetTaskId() != taskID || last.GetStatus() != pb.TaskStatus_TASK_STATUS_FAILED {
+			t.Errorf("output for %s = %v, want FAILED", target, last)
+		}
+		if !strings.Contains(last.GetErrorMessage(), "policy") {
+			t.Errorf("error for %s = %q, want a policy error", target, last.GetErrorMessage())
+		}
+	}
+	if n := m.GetCurrentTaskCount(); n != 0 {
+		t.Errorf("running tasks = %d, want 0: a denied target reached the executor", n)
+	}
+	for _, output := range stream.taskOutputs() {
+		if output.GetOutputLine() != "" {
+			t.Errorf("executor ran for a denied target: %v", output)
+		}
+	}
+}
+
+func TestHandleLogs(t *testing.T) {
+	c := NewStreamClient(&config.Config{Agent: config.AgentConfig{ID: "agent-1"}}, func() int { return 0 }, nil, nil)
+	stream := &sentStream{}
+	c.stream = stream
+
+	c.handleLogs(&pb.MasterMessage{
+		RequestId: "req-1",
+		Type:      pb.MasterMessage_TYPE_LOGS,
+		Payload:   &pb.MasterMessage_Logs{Logs: &pb.LogsRequest{Lines: 10}},
+	})
+
+	if len(stream.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(stream.sent))
+	}
+	msg := stream.sent[0]
+	if msg.RequestId != "req-1" || msg.Type != pb.AgentMessage_TYPE_LOGS_RESPONSE {
+		t.Errorf("reply = %s %v, want req-1 LOGS_RESPONSE", msg.RequestId, msg.Type)
+	}
+	// The test binary never initializes the global logger, so there is no ring buffer
+	resp := msg.GetLogsResponse()
+	if resp.GetAgentId() != "agent-1" || resp.GetError() == "" || len(resp.GetLines()) != 0 {
+		t.Errorf("response = %v, want agent-1 with a disabled buffer error", resp)
+	}
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lureiny/lookingglass/agent/config"
+)
+
+// writeCert writes a self-signed certificate and its key for commonName as PEM files
+func writeCert(t *testing.T, dir, commonName string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, commonName+".crt")
+	keyFile = filepath.Join(dir, commonName+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	caFile, _ := writeCert(t, dir, "master-ca")
+	clientCert, clientKey := writeCert(t, dir, "agent-1")
+
+	tlsConfig := func(master config.MasterConfig) error {
+		c := NewStreamClient(&config.Config{Master: master}, func() int { return 0 }, nil, nil)
+		_, err := c.tlsConfig()
+		return err
+	}
+
+	c := NewStreamClient(&config.Config{Master: config.MasterConfig{
+		TLSCert:       caFile,
+		TLSClientCert: clientCert,
+		TLSClientKey:  clientKey,
+	}}, func() int { return 0 }, nil, nil)
+	cfg, err := c.tlsConfig()
+	if err != nil {
+		t.Fatalf("tlsConfig() error = %v", err)
+	}
+	if cfg.RootCAs == nil {
+		t.Error("master CA not loaded")
+	}
+	if len(cfg.Certificates) != 1 {
+		t.Errorf("client certificates = %d, want 1", len(cfg.Certificates))
+	}
+
+	// Without mutual TLS no client certificate is presented
+	if err := tlsConfig(config.MasterConfig{TLSCert: caFile}); err != nil {
+		t.Errorf("CA only: tlsConfig() error = %v", err)
+	}
+
+	if err := tlsConfig(config.MasterConfig{TLSCert: filepath.Join(dir, "missing.crt")}); err == nil {
+		t.Error("tlsConfig() accepted a missing CA file")
+	}
+	if err := tlsConfig(config.MasterConfig{TLSCert: clientKey}); err == nil {
+		t.Error("tlsConfig() accepted a CA file without certificates")
+	}
+	if err := tlsConfig(config.MasterConfig{TLSClientCert: clientCert, TLSClientKey: filepath.Join(dir, "missing.key")}); err == nil {
+		t.Error("tlsConfig() accepted a client certificate without its key")
+	}
+}
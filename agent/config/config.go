@@ -3,6 +3,10 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/lureiny/lookingglass/pkg/netutil"
 	"gopkg.in/yaml.v3"
@@ -18,22 +22,42 @@ type Config struct {
 
 // AgentMetadata contains agent descriptive information
 type AgentMetadata struct {
-	Location    string `yaml:"location"`    // Geographic location (e.g., "Los Angeles", "Singapore")
-	Provider    string `yaml:"provider"`    // Service provider (e.g., "AWS", "DigitalOcean", "Vultr")
-	IDC         string `yaml:"idc"`         // Data center identifier (e.g., "us-west-1a", "sgp1")
-	Description string `yaml:"description"` // Additional description
+	Location    string            `yaml:"location"`    // Geographic location (e.g., "Los Angeles", "Singapore")
+	Provider    string            `yaml:"provider"`    // Service provider (e.g., "AWS", "DigitalOcean", "Vultr")
+	IDC         string            `yaml:"idc"`         // Data center identifier (e.g., "us-west-1a", "sgp1")
+	Description string            `yaml:"description"` // Additional description
+	Tags        map[string]string `yaml:"tags"`        // Grouping labels for filtering (e.g., continent: "asia")
 }
 
 // AgentConfig contains agent-specific settings
 type AgentConfig struct {
 	ID            string        `yaml:"id"`
-	Name          string        `yaml:"name"`
+	Name          string        `yaml:"name"` // Supports placeholders: {hostname}, {env:VAR}, {ipv4}, {ipv6}, {id}
 	IPv4          string        `yaml:"ipv4"`
 	IPv6          string        `yaml:"ipv6"`
 	HideIP        bool          `yaml:"hide_ip"`        // Whether to hide IP address (mask last 2 octets)
 	GRPCPort      int           `yaml:"grpc_port"`      // DEPRECATED: No longer used in stream mode
 	MaxConcurrent int           `yaml:"max_concurrent"` // Maximum concurrent tasks
 	Metadata      AgentMetadata `yaml:"metadata"`       // Agent metadata (location, provider, etc.)
+	ResourceStats ResourceStats `yaml:"resource_stats"` // Host memory/disk usage reporting
+	IPDetect      IPDetect      `yaml:"ip_detect"`      // Services used to auto-detect ipv4/ipv6
+
+	ShutdownDrainTimeout int `yaml:"shutdown_drain_timeout"` // Seconds to let running tasks finish on shutdown
+}
+
+// ResourceStats controls reporting of host resource usage in heartbeats
+type ResourceStats struct {
+	Enabled  bool   `yaml:"enabled"`   // Include memory/disk usage in heartbeats
+	DiskPath string `yaml:"disk_path"` // Filesystem to report disk usage for (default: "/")
+}
+
+// IPDetect lists the services queried, in order, to detect the agent's public IPs
+// Each must answer a plain GET with the caller's address; empty uses the built-in list
+type IPDetect struct {
+	IPv4Services []string `yaml:"ipv4_services"`
+	IPv6Services []string `yaml:"ipv6_services"`
+	CacheTTL     int      `yaml:"cache_ttl"` // Seconds to reuse detected IPs across restarts (negative disables the cache)
+	Refresh      bool     `yaml:"refresh"`   // Ignore the cache and detect again on this start
 }
 
 // MasterConfig contains master connection settings
@@ -41,17 +65,30 @@ type MasterConfig struct {
 	Host              string `yaml:"host"`
 	APIKey            string `yaml:"api_key"`
 	TLSEnabled        bool   `yaml:"tls_enabled"`
-	TLSCert           string `yaml:"tls_cert"`
+	TLSCert           string `yaml:"tls_cert"`           // CA bundle to verify the master with (empty = system roots)
+	TLSClientCert     string `yaml:"tls_client_cert"`    // Client certificate for mutual TLS
+	TLSClientKey      string `yaml:"tls_client_key"`     // Client private key for mutual TLS
 	HeartbeatInterval int    `yaml:"heartbeat_interval"` // seconds
 	RetryTimes        int    `yaml:"retry_times"`
 	RetryInterval     int    `yaml:"retry_interval"` // seconds
+
+	Mode         string `yaml:"mode"`          // "stream" (gRPC, default) or "poll" (HTTP polling)
+	PollURL      string `yaml:"poll_url"`      // Master poll endpoint in poll mode, e.g. https://lg.example.com/agent/poll
+	PollInterval int    `yaml:"poll_interval"` // seconds between polls while idle
 }
 
+// Master connection modes
+const (
+	MasterModeStream = "stream" // Long-lived gRPC stream
+	MasterModePoll   = "poll"   // Periodic HTTP polls, for hosts that cannot hold a stream
+)
+
 // ExecutorType specifies the type of executor
 type ExecutorType string
 
 const (
 	ExecutorTypeCommand ExecutorType = "command" // Execute external command
+	ExecutorTypeHTTP    ExecutorType = "http"    // Request the target URL and report status and timings
 )
 
 // ExecutorSpec defines how to execute a task
@@ -60,7 +97,7 @@ type ExecutorSpec struct {
 	Path          string       `yaml:"path"`           // Path to executable (for command type)
 	DefaultArgs   []string     `yaml:"default_args"`   // Default arguments (used when no params from frontend)
 	ArgsBuilder   string       `yaml:"args_builder"`   // Named args builder function (builtin, custom)
-	LineFormatter string       `yaml:"line_formatter"` // Named line formatter function (none, newline)
+	LineFormatter string       `yaml:"line_formatter"` // Named line formatter function (none, newline, strip_ansi, fping; comma-separated to combine)
 }
 
 // ConcurrencyConfig contains concurrency settings
@@ -75,6 +112,27 @@ type TaskConfig struct {
 	RequiresTarget *bool             `yaml:"requires_target"` // Whether this task requires target parameter (nil = true)
 	Executor       *ExecutorSpec     `yaml:"executor"`        // Executor specification (nil = use default)
 	Concurrency    ConcurrencyConfig `yaml:"concurrency"`     // Concurrency settings
+	Priority       PriorityConfig    `yaml:"priority"`        // OS scheduling priority of the command
+	Params         []ParamConfig     `yaml:"params"`          // Accepted parameters, validated by the master (empty = not validated)
+	PTY            bool              `yaml:"pty"`             // Run the command on a pseudo-terminal (for tools that buffer when piped)
+	TargetPattern  string            `yaml:"target_pattern"`  // Regexp targets of command tasks must match (empty = hostname, IP or http(s) URL)
+}
+
+// ParamConfig declares one task parameter and its constraints
+type ParamConfig struct {
+	Name        string `yaml:"name"`        // "target", "count", "timeout", "ipv6", or an extra_options key
+	Type        string `yaml:"type"`        // "string", "int" or "bool"
+	Required    bool   `yaml:"required"`    // Reject submissions without this parameter
+	Min         *int64 `yaml:"min"`         // Inclusive lower bound (int only)
+	Max         *int64 `yaml:"max"`         // Inclusive upper bound (int only)
+	Description string `yaml:"description"` // Hint shown by the frontend
+}
+
+// PriorityConfig lowers (or raises) the OS scheduling priority of a task's command
+type PriorityConfig struct {
+	Nice    int    `yaml:"nice"`     // -20 (highest) .. 19 (lowest), 0 = unchanged; negative values need privileges
+	IOClass string `yaml:"io_class"` // "" = unchanged, "best-effort" or "idle" (Linux only)
+	IOLevel int    `yaml:"io_level"` // 0 (highest) .. 7 (lowest), used with io_class best-effort
 }
 
 // ExecutorConfig contains executor settings
@@ -82,14 +140,40 @@ type ExecutorConfig struct {
 	GlobalConcurrency int                    `yaml:"global_concurrency"` // Global max concurrent tasks (0 = use default)
 	DefaultTimeout    int                    `yaml:"default_timeout"`    // seconds
 	WorkDir           string                 `yaml:"work_dir"`
-	Tasks             map[string]*TaskConfig `yaml:"tasks"` // Task configurations keyed by task name (ping, mtr, nexttrace, custom)
+	Tasks             map[string]*TaskConfig `yaml:"tasks"`            // Task configurations keyed by task name (ping, mtr, nexttrace, traceroute, dns, custom)
+	AllowedTasks      []string               `yaml:"allowed_tasks"`    // Task names accepted from master (empty = all enabled tasks)
+	TargetDenylist    []string               `yaml:"target_denylist"`  // IPs/CIDRs, hostname globs or "re:" regexes that tasks may not target
+	MaxOutputLines    int                    `yaml:"max_output_lines"` // Output lines per task before it is stopped (0 = unlimited)
+	MaxOutputBytes    int                    `yaml:"max_output_bytes"` // Output bytes per task before it is stopped (0 = unlimited)
+
+	OutputBackpressure OutputBackpressureConfig `yaml:"output_backpressure"` // Handling of output the master is slow to accept
+
+	CustomTaskGlobalConcurrency int `yaml:"custom_task_global_concurrency"` // Max custom (non-builtin) tasks running at once, combined (0 = no separate cap)
+}
+
+// OutputBackpressureConfig controls detection of a task's output backing up behind a slow stream
+type OutputBackpressureConfig struct {
+	Threshold int    `yaml:"threshold"` // Seconds the output buffer may stay full before it counts as backpressure (0 = not monitored)
+	Policy    string `yaml:"policy"`    // "log" = warn only, "fail" = fail the task with "output backpressure"
+}
+
+// IsTaskAllowed reports whether the master may run the named task on this agent
+func (c *ExecutorConfig) IsTaskAllowed(taskName string) bool {
+	return len(c.AllowedTasks) == 0 || slices.Contains(c.AllowedTasks, taskName)
 }
 
 // LogConfig contains logging settings
 type LogConfig struct {
-	Level   string `yaml:"level"`
-	File    string `yaml:"file"`
-	Console bool   `yaml:"console"`
+	Level    string `yaml:"level"`
+	File     string `yaml:"file"`
+	Console  bool   `yaml:"console"`
+	Format   string `yaml:"format"`    // console (default) or json
+	RingSize int    `yaml:"ring_size"` // Recent lines kept in memory for the master to fetch (0 = default, -1 = none)
+
+	// Log file rotation (0 = default)
+	MaxSizeMB  int `yaml:"max_size_mb"`  // Rotate at this size (default 100)
+	MaxBackups int `yaml:"max_backups"`  // Rotated files kept (default 3)
+	MaxAgeDays int `yaml:"max_age_days"` // Rotated files deleted after this many days (default 28)
 }
 
 // Helper function to create a bool pointer
@@ -139,6 +223,47 @@ func GetBuiltinTaskDefaults() map[string]*TaskConfig {
 				Max: 2, // Default: 2 concurrent nexttrace tasks per agent
 			},
 		},
+		"traceroute": {
+			Enabled:     boolPtr(false), // Opt-in: the classic traceroute binary is not installed everywhere
+			DisplayName: "Traceroute",
+			Executor: &ExecutorSpec{
+				Type:          ExecutorTypeCommand,
+				Path:          "/usr/bin/traceroute",
+				ArgsBuilder:   "builtin_traceroute",
+				LineFormatter: "none",
+			},
+			Concurrency: ConcurrencyConfig{
+				Max: 2, // Default: 2 concurrent traceroute tasks per agent
+			},
+		},
+		"dns": {
+			Enabled:        boolPtr(false), // Opt-in: dig (dnsutils/bind-utils) is not installed everywhere
+			DisplayName:    "DNS Lookup",
+			RequiresTarget: boolPtr(true),
+			Executor: &ExecutorSpec{
+				Type:          ExecutorTypeCommand,
+				Path:          "/usr/bin/dig",
+				ArgsBuilder:   "builtin_dig",
+				LineFormatter: "none",
+			},
+			Concurrency: ConcurrencyConfig{
+				Max: 5, // Default: 5 concurrent DNS lookups per agent
+			},
+		},
+		"fping": {
+			Enabled:        boolPtr(false), // Opt-in: fping is not installed everywhere
+			DisplayName:    "fping",
+			RequiresTarget: boolPtr(true),
+			Executor: &ExecutorSpec{
+				Type:          ExecutorTypeCommand,
+				Path:          "/usr/bin/fping",
+				ArgsBuilder:   "builtin_fping",
+				LineFormatter: "fping",
+			},
+			Concurrency: ConcurrencyConfig{
+				Max: 5, // Default: 5 concurrent fping tasks per agent
+			},
+		},
 	}
 }
 
@@ -165,6 +290,13 @@ func MergeTaskConfig(userTask *TaskConfig, defaultTask *TaskConfig) *TaskConfig
 		merged.DisplayName = defaultTask.DisplayName
 	}
 
+	// RequiresTarget: user value overrides, nil means use default
+	if userTask.RequiresTarget != nil {
+		merged.RequiresTarget = userTask.RequiresTarget
+	} else {
+		merged.RequiresTarget = defaultTask.RequiresTarget
+	}
+
 	// Executor: merge executor specs if both exist, otherwise use whichever is present
 	if userTask.Executor != nil && defaultTask.Executor != nil {
 		merged.Executor = &ExecutorSpec{
@@ -200,11 +332,33 @@ func MergeTaskConfig(userTask *TaskConfig, defaultTask *TaskConfig) *TaskConfig
 		merged.Concurrency.Max = defaultTask.Concurrency.Max
 	}
 
+	// Priority/PTY/TargetPattern: builtin defaults leave them off, so the user value always applies
+	merged.Priority = userTask.Priority
+	merged.PTY = userTask.PTY
+	merged.TargetPattern = userTask.TargetPattern
+
+	// Params: non-empty user schema overrides
+	if len(userTask.Params) > 0 {
+		merged.Params = userTask.Params
+	} else {
+		merged.Params = defaultTask.Params
+	}
+
 	return merged
 }
 
+// LoadOptions adjusts how Load prepares the configuration
+type LoadOptions struct {
+	RefreshIP bool // Detect public IPs again instead of reusing the cache
+}
+
 // Load loads configuration from a YAML file
 func Load(path string) (*Config, error) {
+	return LoadWithOptions(path, LoadOptions{})
+}
+
+// LoadWithOptions loads configuration from a YAML file with the given options
+func LoadWithOptions(path string, opts LoadOptions) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
@@ -217,6 +371,9 @@ func Load(path string) (*Config, error) {
 
 	// Set defaults
 	cfg.setDefaults()
+	if opts.RefreshIP {
+		cfg.Agent.IPDetect.Refresh = true
+	}
 
 	// Auto-detect IP addresses if not configured
 	if err := cfg.autoDetectIPs(); err != nil {
@@ -224,6 +381,13 @@ func Load(path string) (*Config, error) {
 		fmt.Fprintf(os.Stderr, "Warning: failed to auto-detect IP addresses: %v\n", err)
 	}
 
+	// Resolve name template (after IP detection so {ipv4}/{ipv6} are available)
+	name, err := cfg.resolveNameTemplate(cfg.Agent.Name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	cfg.Agent.Name = name
+
 	// Validate
 	if err := cfg.validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
@@ -240,6 +404,14 @@ func (c *Config) setDefaults() {
 		c.Agent.MaxConcurrent = 5
 	}
 
+	if c.Agent.ShutdownDrainTimeout == 0 {
+		c.Agent.ShutdownDrainTimeout = 30
+	}
+
+	if c.Agent.ResourceStats.DiskPath == "" {
+		c.Agent.ResourceStats.DiskPath = "/"
+	}
+
 	if c.Master.HeartbeatInterval == 0 {
 		c.Master.HeartbeatInterval = 30
 	}
@@ -252,6 +424,18 @@ func (c *Config) setDefaults() {
 		c.Master.RetryInterval = 5
 	}
 
+	if c.Master.Mode == "" {
+		c.Master.Mode = MasterModeStream
+	}
+
+	if c.Agent.IPDetect.CacheTTL == 0 {
+		c.Agent.IPDetect.CacheTTL = 86400
+	}
+
+	if c.Master.PollInterval == 0 {
+		c.Master.PollInterval = 2
+	}
+
 	if c.Executor.DefaultTimeout == 0 {
 		c.Executor.DefaultTimeout = 300
 	}
@@ -260,6 +444,10 @@ func (c *Config) setDefaults() {
 		c.Executor.WorkDir = "/tmp/lookingglass"
 	}
 
+	if c.Executor.OutputBackpressure.Policy == "" {
+		c.Executor.OutputBackpressure.Policy = "log"
+	}
+
 	// Set global concurrency default
 	if c.Executor.GlobalConcurrency == 0 {
 		c.Executor.GlobalConcurrency = 10 // Default: 10 concurrent tasks globally
@@ -302,41 +490,136 @@ func (c *Config) setDefaults() {
 		c.Log.Level = "info"
 	}
 
+	if c.Log.RingSize == 0 {
+		c.Log.RingSize = 1000
+	}
+
 	if c.Log.File == "" {
 		c.Log.File = "logs/agent.log"
 	}
+
+	if c.Log.MaxSizeMB == 0 {
+		c.Log.MaxSizeMB = 100
+	}
+
+	if c.Log.MaxBackups == 0 {
+		c.Log.MaxBackups = 3
+	}
+
+	if c.Log.MaxAgeDays == 0 {
+		c.Log.MaxAgeDays = 28
+	}
 }
 
 // autoDetectIPs automatically detects and fills in IPv4 and IPv6 addresses if not configured
+// Detected addresses are cached under executor.work_dir and reused until agent.ip_detect.cache_ttl
+// expires, so restarts do not wait on the detection services
 func (c *Config) autoDetectIPs() error {
+	if c.Agent.IPv4 != "" && c.Agent.IPv6 != "" {
+		return nil
+	}
+
+	cache := c.loadIPCache()
+	detected, ipv4Failed := false, false
+
 	// Auto-detect IPv4 if not configured
 	if c.Agent.IPv4 == "" {
-		fmt.Println("IPv4 not configured, attempting auto-detection...")
-		ipv4, err := netutil.GetPublicIPv4()
-		if err == nil && ipv4 != "" {
-			c.Agent.IPv4 = ipv4
-			fmt.Printf("Auto-detected IPv4: %s\n", ipv4)
+		if cache != nil && isIPv4(cache.IPv4) {
+			c.Agent.IPv4 = cache.IPv4
+			fmt.Printf("Using cached IPv4: %s (detected %s)\n", cache.IPv4, cache.DetectedAt.Format(time.RFC3339))
 		} else {
-			fmt.Printf("Failed to auto-detect IPv4: %v\n", err)
+			fmt.Println("IPv4 not configured, attempting auto-detection...")
+			ipv4, err := netutil.GetPublicIPv4WithServices(c.Agent.IPDetect.IPv4Services)
+			if err == nil && ipv4 != "" {
+				c.Agent.IPv4 = ipv4
+				detected = true
+				fmt.Printf("Auto-detected IPv4: %s\n", ipv4)
+			} else {
+				ipv4Failed = true
+				fmt.Printf("Failed to auto-detect IPv4: %v\n", err)
+			}
 		}
 	}
 
-	// Auto-detect IPv6 if not configured
+	// Auto-detect IPv6 if not configured; a cached empty IPv6 means the host had none
 	if c.Agent.IPv6 == "" {
-		fmt.Println("IPv6 not configured, attempting auto-detection...")
-		ipv6, err := netutil.GetPublicIPv6()
-		if err == nil && ipv6 != "" {
-			c.Agent.IPv6 = ipv6
-			fmt.Printf("Auto-detected IPv6: %s\n", ipv6)
+		if cache != nil && (cache.IPv6 == "" || isIPv6(cache.IPv6)) {
+			c.Agent.IPv6 = cache.IPv6
+			if cache.IPv6 != "" {
+				fmt.Printf("Using cached IPv6: %s (detected %s)\n", cache.IPv6, cache.DetectedAt.Format(time.RFC3339))
+			}
 		} else {
-			// IPv6 is optional, just log the failure
-			fmt.Printf("Failed to auto-detect IPv6 (this is normal if IPv6 is not available): %v\n", err)
+			fmt.Println("IPv6 not configured, attempting auto-detection...")
+			detected = true
+			ipv6, err := netutil.GetPublicIPv6WithServices(c.Agent.IPDetect.IPv6Services)
+			if err == nil && ipv6 != "" {
+				c.Agent.IPv6 = ipv6
+				fmt.Printf("Auto-detected IPv6: %s\n", ipv6)
+			} else {
+				// IPv6 is optional, just log the failure
+				fmt.Printf("Failed to auto-detect IPv6 (this is normal if IPv6 is not available): %v\n", err)
+			}
+		}
+	}
+
+	// A failed IPv4 lookup is not cached so the next start tries again
+	if detected && !ipv4Failed {
+		if err := c.saveIPCache(c.Agent.IPv4, c.Agent.IPv6); err != nil {
+			fmt.Printf("Failed to cache detected IPs: %v\n", err)
 		}
 	}
 
 	return nil
 }
 
+// namePlaceholderPattern matches name template placeholders like {hostname} or {env:REGION}
+var namePlaceholderPattern = regexp.MustCompile(`\{([a-z0-9]+)(?::([^{}]*))?\}`)
+
+// resolveNameTemplate replaces placeholders in the agent name template
+// Supported placeholders:
+//   - {hostname}: Host name reported by the kernel
+//   - {env:VAR}: Value of environment variable VAR
+//   - {ipv4}, {ipv6}: Configured or auto-detected IP addresses (masked when hide_ip is set)
+//   - {id}: Agent ID
+//
+// Unknown placeholders are left unchanged, so literal names pass through as-is
+func (c *Config) resolveNameTemplate(name string) (string, error) {
+	var err error
+	resolved := namePlaceholderPattern.ReplaceAllStringFunc(name, func(placeholder string) string {
+		match := namePlaceholderPattern.FindStringSubmatch(placeholder)
+		key, arg := match[1], match[2]
+
+		switch key {
+		case "hostname":
+			hostname, hostErr := os.Hostname()
+			if hostErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to resolve {hostname} in agent name: %v\n", hostErr)
+				return ""
+			}
+			return hostname
+		case "env":
+			if arg == "" && err == nil {
+				err = fmt.Errorf("agent.name placeholder %s needs a variable name, e.g. {env:REGION}", placeholder)
+			}
+			return os.Getenv(arg)
+		case "ipv4":
+			return c.displayIP(c.Agent.IPv4)
+		case "ipv6":
+			return c.displayIP(c.Agent.IPv6)
+		case "id":
+			return c.Agent.ID
+		default:
+			return placeholder
+		}
+	})
+	return resolved, err
+}
+
+// displayIP returns ip as it may be shown to users, masked when hide_ip is set
+func (c *Config) displayIP(ip string) string {
+	return netutil.DisplayIP(ip, c.Agent.HideIP)
+}
+
 // validate validates the configuration
 func (c *Config) validate() error {
 	if c.Agent.ID == "" {
@@ -347,17 +630,151 @@ func (c *Config) validate() error {
 		return fmt.Errorf("agent.name is required")
 	}
 
-	if c.Master.Host == "" {
-		return fmt.Errorf("master.host is required")
+	switch c.Master.Mode {
+	case MasterModeStream:
+		if c.Master.Host == "" {
+			return fmt.Errorf("master.host is required")
+		}
+	case MasterModePoll:
+		if c.Master.PollURL == "" {
+			return fmt.Errorf("master.poll_url is required when master.mode is 'poll'")
+		}
+		if c.Master.PollInterval < 1 {
+			return fmt.Errorf("master.poll_interval must be at least 1")
+		}
+	default:
+		return fmt.Errorf("master.mode must be 'stream' or 'poll'")
 	}
 
-	if c.Master.APIKey == "" {
+	if (c.Master.TLSClientCert == "") != (c.Master.TLSClientKey == "") {
+		return fmt.Errorf("master.tls_client_cert and master.tls_client_key must be set together")
+	}
+
+	if c.Master.TLSClientCert != "" && !c.Master.TLSEnabled {
+		return fmt.Errorf("master.tls_client_cert requires master.tls_enabled")
+	}
+
+	if c.Master.APIKey == "" && c.Master.TLSClientCert == "" {
 		return fmt.Errorf("master.api_key is required")
 	}
 
+	for _, service := range append(append([]string{}, c.Agent.IPDetect.IPv4Services...), c.Agent.IPDetect.IPv6Services...) {
+		if !strings.HasPrefix(service, "http://") && !strings.HasPrefix(service, "https://") {
+			return fmt.Errorf("agent.ip_detect service %q must be an http(s) URL", service)
+		}
+	}
+
+	if c.Agent.ShutdownDrainTimeout < 0 {
+		return fmt.Errorf("agent.shutdown_drain_timeout cannot be negative")
+	}
+
 	if c.Agent.MaxConcurrent < 1 {
 		return fmt.Errorf("agent.max_concurrent must be at least 1")
 	}
 
+	if c.Executor.MaxOutputLines < 0 {
+		return fmt.Errorf("executor.max_output_lines cannot be negative")
+	}
+
+	if c.Executor.MaxOutputBytes < 0 {
+		return fmt.Errorf("executor.max_output_bytes cannot be negative")
+	}
+
+	if c.Executor.CustomTaskGlobalConcurrency < 0 {
+		return fmt.Errorf("executor.custom_task_global_concurrency cannot be negative")
+	}
+
+	if c.Log.RingSize < -1 {
+		return fmt.Errorf("log.ring_size must be -1 (disabled) or greater")
+	}
+
+	if c.Log.Format != "" && c.Log.Format != "console" && c.Log.Format != "json" {
+		return fmt.Errorf("log.format must be 'console' or 'json'")
+	}
+
+	if c.Log.MaxSizeMB < 0 || c.Log.MaxBackups < 0 || c.Log.MaxAgeDays < 0 {
+		return fmt.Errorf("log.max_size_mb, log.max_backups and log.max_age_days cannot be negative")
+	}
+
+	if c.Executor.OutputBackpressure.Threshold < 0 {
+		return fmt.Errorf("executor.output_backpressure.threshold cannot be negative")
+	}
+
+	for name, task := range c.Executor.Tasks {
+		if task == nil {
+			continue
+		}
+		if task.Priority.Nice < -20 || task.Priority.Nice > 19 {
+			return fmt.Errorf("executor.tasks.%s.priority.nice must be between -20 and 19", name)
+		}
+		switch task.Priority.IOClass {
+		case "", "best-effort", "idle":
+		default:
+			return fmt.Errorf("executor.tasks.%s.priority.io_class must be \"best-effort\" or \"idle\", got %q", name, task.Priority.IOClass)
+		}
+		if task.Priority.IOLevel < 0 || task.Priority.IOLevel > 7 {
+			return fmt.Errorf("executor.tasks.%s.priority.io_level must be between 0 and 7", name)
+		}
+		if task.Executor != nil {
+			switch task.Executor.Type {
+			case "", ExecutorTypeCommand, ExecutorTypeHTTP:
+			default:
+				return fmt.Errorf("executor.tasks.%s.executor.type must be \"command\" or \"http\", got %q", name, task.Executor.Type)
+			}
+			for _, formatter := range strings.Split(task.Executor.LineFormatter, ",") {
+				switch strings.TrimSpace(formatter) {
+				case "", "none", "newline", "strip_ansi", "fping":
+				default:
+					return fmt.Errorf("executor.tasks.%s.executor.line_formatter: unknown formatter %q", name, formatter)
+				}
+			}
+		}
+		if task.TargetPattern != "" {
+			if _, err := regexp.Compile(task.TargetPattern); err != nil {
+				return fmt.Errorf("executor.tasks.%s.target_pattern is invalid: %w", name, err)
+			}
+		}
+		if err := validateParams(task.Params); err != nil {
+			return fmt.Errorf("executor.tasks.%s.params: %w", name, err)
+		}
+	}
+
+	switch c.Executor.OutputBackpressure.Policy {
+	case "log", "fail":
+	default:
+		return fmt.Errorf("executor.output_backpressure.policy must be \"log\" or \"fail\", got %q", c.Executor.OutputBackpressure.Policy)
+	}
+
+	if _, err := netutil.ParseTargetDenylist(c.Executor.TargetDenylist); err != nil {
+		return fmt.Errorf("executor.target_denylist: %w", err)
+	}
+
+	return nil
+}
+
+// validateParams checks a task's parameter schema
+func validateParams(params []ParamConfig) error {
+	seen := make(map[string]bool, len(params))
+	for _, p := range params {
+		if p.Name == "" {
+			return fmt.Errorf("name is required")
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate param %q", p.Name)
+		}
+		seen[p.Name] = true
+
+		switch p.Type {
+		case "string", "int", "bool":
+		default:
+			return fmt.Errorf("%s: type must be \"string\", \"int\" or \"bool\", got %q", p.Name, p.Type)
+		}
+		if (p.Min != nil || p.Max != nil) && p.Type != "int" {
+			return fmt.Errorf("%s: min/max only apply to int params", p.Name)
+		}
+		if p.Min != nil && p.Max != nil && *p.Min > *p.Max {
+			return fmt.Errorf("%s: min cannot exceed max", p.Name)
+		}
+	}
 	return nil
 }
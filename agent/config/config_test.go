@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/lureiny/lookingglass/pkg/netutil"
+)
+
+func TestResolveNameTemplate(t *testing.T) {
+	t.Setenv("LG_TEST_REGION", "eu-west")
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("no hostname: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		tmpl    string
+		hideIP  bool
+		want    string
+		wantErr bool
+	}{
+		{"literal", "Paris DC", false, "Paris DC", false},
+		{"hostname", "{hostname}", false, hostname, false},
+		{"env", "lg-{env:LG_TEST_REGION}", false, "lg-eu-west", false},
+		{"unset env", "lg-{env:LG_TEST_UNSET}", false, "lg-", false},
+		{"bare env", "lg-{env}", false, "", true},
+		{"ids and ips", "{id} {ipv4} {ipv6}", false, "agent-1 203.0.113.7 2001:db8::1", false},
+		{"hidden ips", "{ipv4}", true, netutil.MaskIP("203.0.113.7"), false},
+		{"unknown placeholder", "{region}", false, "{region}", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Agent: AgentConfig{ID: "agent-1", IPv4: "203.0.113.7", IPv6: "2001:db8::1", HideIP: tt.hideIP}}
+			got, err := c.resolveNameTemplate(tt.tmpl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveNameTemplate(%q) error = %v, wantErr %v", tt.tmpl, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("resolveNameTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTaskAllowed(t *testing.T) {
+	all := &ExecutorConfig{}
+	if !all.IsTaskAllowed("mtr") {
+		t.Error("empty allowlist rejected mtr")
+	}
+
+	limited := &ExecutorConfig{AllowedTasks: []string{"ping", "mtr"}}
+	if !limited.IsTaskAllowed("ping") || !limited.IsTaskAllowed("mtr") {
+		t.Error("allowlisted task rejected")
+	}
+	if limited.IsTaskAllowed("nexttrace") {
+		t.Error("task outside the allowlist accepted")
+	}
+}
+
+func TestValidateIPDetectServices(t *testing.T) {
+	cfg := &Config{
+		Agent:  AgentConfig{ID: "agent-1", Name: "Tokyo", MaxConcurrent: 1},
+		Master: MasterConfig{Mode: MasterModeStream, Host: "master:50051", APIKey: "key"},
+		Executor: ExecutorConfig{
+			OutputBackpressure: OutputBackpressureConfig{Policy: "log"},
+		},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("base config invalid: %v", err)
+	}
+
+	cfg.Agent.IPDetect = IPDetect{
+		IPv4Services: []string{"https://ipv4.example.com"},
+		IPv6Services: []string{"http://ipv6.example.com/ip"},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Errorf("validate() error = %v", err)
+	}
+
+	cfg.Agent.IPDetect.IPv6Services = []string{"ipv6.example.com"}
+	if err := cfg.validate(); err == nil {
+		t.Error("validate() accepted an IP detection service that is not an http(s) URL")
+	}
+}
+
+func TestDefaultConfigValid(t *testing.T) {
+	cfg := &Config{
+		Agent:  AgentConfig{ID: "agent-1", Name: "Tokyo"},
+		Master: MasterConfig{Host: "master:50051", APIKey: "key"},
+	}
+	cfg.setDefaults()
+	// Every builtin task default, fping's line formatter included, must pass validation
+	if err := cfg.validate(); err != nil {
+		t.Errorf("validate() error = %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ipCacheFile is the name of the detected-IP cache under executor.work_dir
+const ipCacheFile = "ip_cache.json"
+
+// ipCache records the public IPs detected on a previous start
+type ipCache struct {
+	IPv4       string    `json:"ipv4"`
+	IPv6       string    `json:"ipv6"` // Empty when the host had no IPv6
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// ipCachePath returns the location of the detected-IP cache
+func (c *Config) ipCachePath() string {
+	return filepath.Join(c.Executor.WorkDir, ipCacheFile)
+}
+
+// loadIPCache returns the cached IPs if caching is enabled and the cache is younger than the TTL
+func (c *Config) loadIPCache() *ipCache {
+	if c.Agent.IPDetect.Refresh || c.Agent.IPDetect.CacheTTL < 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.ipCachePath())
+	if err != nil {
+		return nil
+	}
+	var cache ipCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil
+	}
+
+	ttl := time.Duration(c.Agent.IPDetect.CacheTTL) * time.Second
+	if cache.DetectedAt.After(time.Now()) || time.Since(cache.DetectedAt) > ttl {
+		return nil
+	}
+	return &cache
+}
+
+// saveIPCache records freshly detected IPs for the next start
+func (c *Config) saveIPCache(ipv4, ipv6 string) error {
+	if c.Agent.IPDetect.CacheTTL < 0 {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(ipCache{IPv4: ipv4, IPv6: ipv6, DetectedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Executor.WorkDir, 0755); err != nil {
+		return err
+	}
+
+	// Write then rename so a crash never leaves a truncated cache behind
+	tmp := c.ipCachePath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.ipCachePath())
+}
+
+// isIPv4 reports whether ip is a valid IPv4 address
+func isIPv4(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.To4() != nil
+}
+
+// isIPv6 reports whether ip is a valid IPv6 address
+func isIPv6(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.To4() == nil
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ipCacheConfig returns a config caching detected IPs in a temporary work dir for ttl seconds
+func ipCacheConfig(t *testing.T, ttl int) *Config {
+	t.Helper()
+	return &Config{
+		Agent:    AgentConfig{IPDetect: IPDetect{CacheTTL: ttl}},
+		Executor: ExecutorConfig{WorkDir: t.TempDir()},
+	}
+}
+
+func TestIPCacheRoundTrip(t *testing.T) {
+	c := ipCacheConfig(t, 3600)
+	if cache := c.loadIPCache(); cache != nil {
+		t.Fatalf("loadIPCache() without a cache file = %v", cache)
+	}
+
+	if err := c.saveIPCache("203.0.113.7", ""); err != nil {
+		t.Fatalf("saveIPCache() error = %v", err)
+	}
+	cache := c.loadIPCache()
+	if cache == nil || cache.IPv4 != "203.0.113.7" || cache.IPv6 != "" {
+		t.Fatalf("loadIPCache() = %v, want the saved IPs", cache)
+	}
+
+	// A forced refresh ignores the cache
+	c.Agent.IPDetect.Refresh = true
+	if cache := c.loadIPCache(); cache != nil {
+		t.Errorf("loadIPCache() with refresh = %v, want nil", cache)
+	}
+}
+
+func TestIPCacheExpiry(t *testing.T) {
+	c := ipCacheConfig(t, 60)
+	write := func(detectedAt time.Time) {
+		data, _ := json.Marshal(ipCache{IPv4: "203.0.113.7", DetectedAt: detectedAt})
+		if err := os.WriteFile(c.ipCachePath(), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(time.Now().Add(-30 * time.Second))
+	if c.loadIPCache() == nil {
+		t.Error("cache within the TTL not used")
+	}
+	write(time.Now().Add(-2 * time.Minute))
+	if c.loadIPCache() != nil {
+		t.Error("cache older than the TTL used")
+	}
+	// A clock that went backwards must not keep a cache forever
+	write(time.Now().Add(time.Hour))
+	if c.loadIPCache() != nil {
+		t.Error("cache detected in the future used")
+	}
+}
+
+func TestIPCacheDisabled(t *testing.T) {
+	c := ipCacheConfig(t, -1)
+	if err := c.saveIPCache("203.0.113.7", ""); err != nil {
+		t.Fatalf("saveIPCache() error = %v", err)
+	}
+	if _, err := os.Stat(c.ipCachePath()); !os.IsNotExist(err) {
+		t.Errorf("cache file written with caching disabled: %v", err)
+	}
+}
+
+func TestAutoDetectIPsUsesCache(t *testing.T) {
+	var lookups atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		w.Write([]byte("198.51.100.1"))
+	}))
+	defer srv.Close()
+
+	c := ipCacheConfig(t, 3600)
+	c.Agent.IPDetect.IPv4Services = []string{srv.URL}
+	c.Agent.IPDetect.IPv6Services = []string{srv.URL} // Never answers with an IPv6 address
+
+	// First start: detected and cached
+	if err := c.autoDetectIPs(); err != nil {
+		t.Fatalf("autoDetectIPs() error = %v", err)
+	}
+	if c.Agent.IPv4 != "198.51.100.1" {
+		t.Fatalf("IPv4 = %q, want the detected address", c.Agent.IPv4)
+	}
+	detectLookups := lookups.Load()
+
+	// Next start: the cache answers without asking the services
+	restarted := ipCacheConfig(t, 3600)
+	restarted.Executor.WorkDir = c.Executor.WorkDir
+	restarted.Agent.IPDetect = c.Agent.IPDetect
+	if err := restarted.autoDetectIPs(); err != nil {
+		t.Fatalf("autoDetectIPs() error = %v", err)
+	}
+	if restarted.Agent.IPv4 != "198.51.100.1" {
+		t.Errorf("IPv4 after restart = %q, want the cached address", restarted.Agent.IPv4)
+	}
+	if n := lookups.Load() - detectLookups; n != 0 {
+		t.Errorf("restart made %d detection requests, want 0", n)
+	}
+}
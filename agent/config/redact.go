@@ -0,0 +1,39 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces secrets in the redacted config
+const redactedValue = "[REDACTED]"
+
+// Redacted returns a copy of the config with secrets (the master API key) masked
+func (c *Config) Redacted() *Config {
+	out := *c
+	if out.Master.APIKey != "" {
+		out.Master.APIKey = redactedValue
+	}
+	return &out
+}
+
+// ToJSON encodes the config as JSON keyed by its YAML field names
+func (c *Config) ToJSON() (string, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	var generic map[string]interface{}
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return "", fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	out, err := json.Marshal(generic)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode config: %w", err)
+	}
+	return string(out), nil
+}
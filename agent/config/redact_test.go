@@ -0,0 +1,36 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRedactedToJSON(t *testing.T) {
+	cfg := &Config{
+		Agent:  AgentConfig{ID: "agent-1"},
+		Master: MasterConfig{APIKey: "secret"},
+	}
+
+	redacted := cfg.Redacted()
+	if redacted.Master.APIKey != redactedValue {
+		t.Errorf("redacted api key = %q, want %q", redacted.Master.APIKey, redactedValue)
+	}
+	if cfg.Master.APIKey != "secret" {
+		t.Error("Redacted() modified the original config")
+	}
+	if (&Config{}).Redacted().Master.APIKey != "" {
+		t.Error("empty api key redacted, want it left empty")
+	}
+
+	data, err := redacted.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	var got map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	if got["master"]["api_key"] != redactedValue || got["agent"]["id"] != "agent-1" {
+		t.Errorf("json = %s, want YAML field names with the key redacted", data)
+	}
+}
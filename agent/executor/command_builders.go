@@ -2,6 +2,7 @@ package executor
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -90,8 +91,16 @@ func BuildNextTraceArgs(params *pb.NetworkTestParams) []string {
 		args = append(args, "-t", strconv.Itoa(int(params.Timeout)))
 	}
 
+	// Structured output mode
+	if isNextTraceJSON(params) {
+		args = append(args, "--json")
+	}
+
 	// Extra options from the map
 	for key, value := range params.ExtraOptions {
+		if key == NextTraceFormatOption {
+			continue
+		}
 		if value == "" {
 			// Flag without value
 			args = append(args, key)
@@ -106,13 +115,170 @@ func BuildNextTraceArgs(params *pb.NetworkTestParams) []string {
 	return args
 }
 
+// BuildTracerouteArgs builds classic traceroute command arguments from parameters
+func BuildTracerouteArgs(params *pb.NetworkTestParams) []string {
+	args := make([]string, 0)
+
+	// Max hops
+	if params.Count > 0 {
+		args = append(args, "-m", strconv.Itoa(int(params.Count)))
+	}
+
+	// Timeout (wait time for each probe)
+	if params.Timeout > 0 {
+		args = append(args, "-w", strconv.Itoa(int(params.Timeout)))
+	}
+
+	// IPv6
+	if params.Ipv6 {
+		args = append(args, "-6")
+	} else {
+		args = append(args, "-4")
+	}
+
+	// Target (must be last)
+	args = append(args, params.Target)
+
+	return args
+}
+
+// FpingPeriodOption is the extra option key holding the interval between probes to a target, in ms
+const FpingPeriodOption = "period"
+
+// BuildFpingArgs builds fping command arguments from parameters
+// Probes are sent quietly (-q) so fping prints one result line per target once done
+func BuildFpingArgs(params *pb.NetworkTestParams) []string {
+	args := make([]string, 0)
+
+	// Count (probes per target, reported individually)
+	count := 10
+	if params.Count > 0 {
+		count = int(params.Count)
+	}
+	args = append(args, "-C", strconv.Itoa(count))
+
+	// Period between probes to the target (fping needs at least 10ms without root)
+	if period, err := strconv.Atoi(strings.TrimSpace(params.ExtraOptions[FpingPeriodOption])); err == nil && period >= 10 {
+		args = append(args, "-p", strconv.Itoa(period))
+	}
+
+	// Timeout (wait time for each probe, fping takes ms)
+	if params.Timeout > 0 {
+		args = append(args, "-t", strconv.Itoa(int(params.Timeout)*1000))
+	}
+
+	// Quiet: no per-probe lines, only the per-target results
+	args = append(args, "-q")
+
+	// IPv6
+	if params.Ipv6 {
+		args = append(args, "-6")
+	} else {
+		args = append(args, "-4")
+	}
+
+	// Target (must be last)
+	args = append(args, params.Target)
+
+	return args
+}
+
+// digRecordTypes are the record types accepted from ExtraOptions["type"]
+var digRecordTypes = map[string]bool{
+	"A":     true,
+	"AAAA":  true,
+	"MX":    true,
+	"TXT":   true,
+	"CNAME": true,
+	"NS":    true,
+}
+
+// BuildDigArgs builds dig command arguments from parameters
+// ExtraOptions["type"] selects the record type (default A), ExtraOptions["server"] the resolver to query
+func BuildDigArgs(params *pb.NetworkTestParams) []string {
+	args := make([]string, 0)
+
+	// Resolver (default: system resolver)
+	if server := strings.TrimSpace(params.ExtraOptions["server"]); server != "" {
+		args = append(args, "@"+server)
+	}
+
+	// Transport to the resolver
+	if params.Ipv6 {
+		args = append(args, "-6")
+	}
+
+	// Query timeout per try
+	if params.Timeout > 0 {
+		args = append(args, "+time="+strconv.Itoa(int(params.Timeout)))
+	}
+
+	// Record type (unknown types fall back to A)
+	recordType := strings.ToUpper(strings.TrimSpace(params.ExtraOptions["type"]))
+	if !digRecordTypes[recordType] {
+		recordType = "A"
+	}
+
+	// -q/-t keep the name and type from being parsed as options
+	args = append(args, "-t", recordType, "-q", params.Target)
+
+	return args
+}
+
 // AppendNewline is a line formatter that adds a newline to each line
 func AppendNewline(line string) string {
 	return line + "\n"
 }
 
+// ansiEscapeRe matches ANSI escape sequences: CSI (colors, cursor movement), OSC and single-character escapes
+var ansiEscapeRe = regexp.MustCompile(`\x1b(?:\[[0-9;?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[@-Z\\-_])`)
+
+// StripANSI is a line formatter that removes ANSI escape sequences (e.g. nexttrace colors)
+func StripANSI(line string) string {
+	return ansiEscapeRe.ReplaceAllString(line, "")
+}
+
+// lineFormatters are the formatters that can be named in executor.line_formatter
+var lineFormatters = map[string]LineFormatter{
+	"newline":    AppendNewline,
+	"strip_ansi": StripANSI,
+	"fping":      FormatFpingLine,
+}
+
+// NewLineFormatter builds the line formatter named in config: "none", "newline", "strip_ansi", "fping",
+// or a comma-separated list applied in order (e.g. "strip_ansi,newline")
+// Returns nil when no formatting is needed
+func NewLineFormatter(spec string) (LineFormatter, error) {
+	var chain []LineFormatter
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || name == "none" {
+			continue
+		}
+		formatter, ok := lineFormatters[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown line formatter: %s", name)
+		}
+		chain = append(chain, formatter)
+	}
+
+	switch len(chain) {
+	case 0:
+		return nil, nil
+	case 1:
+		return chain[0], nil
+	default:
+		return func(line string) string {
+			for _, formatter := range chain {
+				line = formatter(line)
+			}
+			return line
+		}, nil
+	}
+}
+
 // BuildCustomCommandArgs builds custom command arguments with template support
-// Supports placeholders: {target}, {count}, {timeout}, {ipv6}
+// Supports placeholders: {target}, {count}, {timeout}, {ipv6}, and {key} for each extra_options key
 func BuildCustomCommandArgs(defaultArgs []string, params *pb.NetworkTestParams) []string {
 	if params == nil {
 		return defaultArgs
@@ -128,14 +294,20 @@ func BuildCustomCommandArgs(defaultArgs []string, params *pb.NetworkTestParams)
 		"{timeout}": strconv.Itoa(int(params.Timeout)),
 		"{ipv6}":    strconv.FormatBool(params.Ipv6),
 	}
+	for key, value := range params.ExtraOptions {
+		if _, builtin := replacements["{"+key+"}"]; !builtin {
+			replacements["{"+key+"}"] = value
+		}
+	}
 
-	// Apply replacements to each argument
+	// One pass over each argument: substituted values are never expanded again
+	pairs := make([]string, 0, len(replacements)*2)
+	for placeholder, value := range replacements {
+		pairs = append(pairs, placeholder, value)
+	}
+	replacer := strings.NewReplacer(pairs...)
 	for i, arg := range args {
-		for placeholder, value := range replacements {
-			if strings.Contains(arg, placeholder) {
-				args[i] = strings.ReplaceAll(arg, placeholder, value)
-			}
-		}
+		args[i] = replacer.Replace(arg)
 	}
 
 	return args
@@ -154,12 +326,14 @@ func NewPingExecutor(pingPath string) *CommandExecutor {
 	if pingPath == "" {
 		pingPath = "/bin/ping" // Default path
 	}
-	return NewCommandExecutor(
+	executor := NewCommandExecutor(
 		"ping",
 		pingPath,
 		BuildPingArgs,
 		nil, // No line formatter needed
 	)
+	executor.SetResolveTarget(true)
+	return executor
 }
 
 // NewMTRExecutor creates a new MTR executor
@@ -167,12 +341,14 @@ func NewMTRExecutor(mtrPath string) *CommandExecutor {
 	if mtrPath == "" {
 		mtrPath = "/usr/bin/mtr" // Default path
 	}
-	return NewCommandExecutor(
+	executor := NewCommandExecutor(
 		"MTR",
 		mtrPath,
 		BuildMTRArgs,
 		nil, // No line formatter needed
 	)
+	executor.SetResolveTarget(true)
+	return executor
 }
 
 // NewNextTraceExecutor creates a new nexttrace executor
@@ -180,12 +356,61 @@ func NewNextTraceExecutor(nexttracePath string) *CommandExecutor {
 	if nexttracePath == "" {
 		nexttracePath = "/usr/bin/nexttrace" // Default path
 	}
-	return NewCommandExecutor(
+	executor := NewCommandExecutor(
 		"nexttrace",
 		nexttracePath,
 		BuildNextTraceArgs,
 		AppendNewline, // NextTrace needs newline appended
 	)
+	executor.SetSummaryParser(NextTraceJSONParser{}) // ExtraOptions["format"]="json"
+	executor.SetResolveTarget(true)
+	return executor
+}
+
+// NewTracerouteExecutor creates a new classic traceroute executor
+func NewTracerouteExecutor(traceroutePath string) *CommandExecutor {
+	if traceroutePath == "" {
+		traceroutePath = "/usr/bin/traceroute" // Default path
+	}
+	executor := NewCommandExecutor(
+		"traceroute",
+		traceroutePath,
+		BuildTracerouteArgs,
+		nil, // No line formatter needed
+	)
+	executor.SetResolveTarget(true)
+	return executor
+}
+
+// NewFpingExecutor creates a new fping executor
+// fping exits with 1 when probes were lost; that is a result, not a failure
+func NewFpingExecutor(fpingPath string) *CommandExecutor {
+	if fpingPath == "" {
+		fpingPath = "/usr/bin/fping" // Default path
+	}
+	executor := NewCommandExecutor(
+		"fping",
+		fpingPath,
+		BuildFpingArgs,
+		FormatFpingLine, // Per-probe RTTs -> one summary line
+	)
+	executor.SetSuccessExitCodes(1)
+	executor.SetResolveTarget(true)
+	return executor
+}
+
+// NewDigExecutor creates a new dig (DNS lookup) executor
+// The target is the name to look up, so it is not resolved beforehand
+func NewDigExecutor(digPath string) *CommandExecutor {
+	if digPath == "" {
+		digPath = "/usr/bin/dig" // Default path
+	}
+	return NewCommandExecutor(
+		"dns",
+		digPath,
+		BuildDigArgs,
+		nil, // No line formatter needed
+	)
 }
 
 // NewCustomCommandExecutor creates a custom command executor
@@ -193,13 +418,8 @@ func NewNextTraceExecutor(nexttracePath string) *CommandExecutor {
 //   - name: Display name for the executor
 //   - cmdPath: Path to the executable
 //   - defaultArgs: Default arguments (supports template placeholders)
-//   - needsNewline: Whether to append newline to each output line
-func NewCustomCommandExecutor(name, cmdPath string, defaultArgs []string, needsNewline bool) *CommandExecutor {
-	var lineFormatter LineFormatter
-	if needsNewline {
-		lineFormatter = AppendNewline
-	}
-
+//   - lineFormatter: Optional formatter applied to each output line (see NewLineFormatter)
+func NewCustomCommandExecutor(name, cmdPath string, defaultArgs []string, lineFormatter LineFormatter) *CommandExecutor {
 	return NewCommandExecutor(
 		name,
 		cmdPath,
@@ -211,12 +431,16 @@ func NewCustomCommandExecutor(name, cmdPath string, defaultArgs []string, needsN
 // Factory functions for executor registry
 
 // PingExecutorFactory creates a ping executor from configuration
+// Multi-target pings run at most Concurrency.Max sub-pings at once
 func PingExecutorFactory(cfg *config.TaskConfig) (Executor, error) {
 	path := "/bin/ping"
 	if cfg.Executor != nil && cfg.Executor.Path != "" {
 		path = cfg.Executor.Path
 	}
-	return NewPingExecutor(path), nil
+	executor := NewMultiPingExecutor(path, cfg.Concurrency.Max)
+	executor.SetPriority(cfg.Priority)
+	executor.SetPTY(cfg.PTY)
+	return executor, nil
 }
 
 // MTRExecutorFactory creates an MTR executor from configuration
@@ -225,7 +449,10 @@ func MTRExecutorFactory(cfg *config.TaskConfig) (Executor, error) {
 	if cfg.Executor != nil && cfg.Executor.Path != "" {
 		path = cfg.Executor.Path
 	}
-	return NewMTRExecutor(path), nil
+	executor := NewMTRExecutor(path)
+	executor.SetPriority(cfg.Priority)
+	executor.SetPTY(cfg.PTY)
+	return executor, nil
 }
 
 // NextTraceExecutorFactory creates a nexttrace executor from configuration
@@ -234,7 +461,62 @@ func NextTraceExecutorFactory(cfg *config.TaskConfig) (Executor, error) {
 	if cfg.Executor != nil && cfg.Executor.Path != "" {
 		path = cfg.Executor.Path
 	}
-	return NewNextTraceExecutor(path), nil
+	executor := NewNextTraceExecutor(path)
+	// A configured formatter replaces the default newline one, e.g. "strip_ansi,newline"
+	if cfg.Executor != nil && cfg.Executor.LineFormatter != "" {
+		lineFormatter, err := NewLineFormatter(cfg.Executor.LineFormatter)
+		if err != nil {
+			return nil, err
+		}
+		executor.SetLineFormatter(lineFormatter)
+	}
+	executor.SetPriority(cfg.Priority)
+	executor.SetPTY(cfg.PTY)
+	return executor, nil
+}
+
+// TracerouteExecutorFactory creates a traceroute executor from configuration
+func TracerouteExecutorFactory(cfg *config.TaskConfig) (Executor, error) {
+	path := "/usr/bin/traceroute"
+	if cfg.Executor != nil && cfg.Executor.Path != "" {
+		path = cfg.Executor.Path
+	}
+	executor := NewTracerouteExecutor(path)
+	executor.SetPriority(cfg.Priority)
+	executor.SetPTY(cfg.PTY)
+	return executor, nil
+}
+
+// FpingExecutorFactory creates an fping executor from configuration
+func FpingExecutorFactory(cfg *config.TaskConfig) (Executor, error) {
+	path := "/usr/bin/fping"
+	if cfg.Executor != nil && cfg.Executor.Path != "" {
+		path = cfg.Executor.Path
+	}
+	executor := NewFpingExecutor(path)
+	// A configured formatter replaces the summary one, e.g. "none" for fping's raw RTT lists
+	if cfg.Executor != nil && cfg.Executor.LineFormatter != "" {
+		lineFormatter, err := NewLineFormatter(cfg.Executor.LineFormatter)
+		if err != nil {
+			return nil, err
+		}
+		executor.SetLineFormatter(lineFormatter)
+	}
+	executor.SetPriority(cfg.Priority)
+	executor.SetPTY(cfg.PTY)
+	return executor, nil
+}
+
+// DigExecutorFactory creates a dig executor from configuration
+func DigExecutorFactory(cfg *config.TaskConfig) (Executor, error) {
+	path := "/usr/bin/dig"
+	if cfg.Executor != nil && cfg.Executor.Path != "" {
+		path = cfg.Executor.Path
+	}
+	executor := NewDigExecutor(path)
+	executor.SetPriority(cfg.Priority)
+	executor.SetPTY(cfg.PTY)
+	return executor, nil
 }
 
 // CommandExecutorFactory creates a custom command executor from configuration
@@ -247,14 +529,25 @@ func CommandExecutorFactory(cfg *config.TaskConfig) (Executor, error) {
 		return nil, fmt.Errorf("executor path is required for command executor")
 	}
 
-	needsNewline := cfg.Executor.LineFormatter == "newline"
+	lineFormatter, err := NewLineFormatter(cfg.Executor.LineFormatter)
+	if err != nil {
+		return nil, err
+	}
+	targetValidator, err := NewTargetValidator(cfg.TargetPattern)
+	if err != nil {
+		return nil, err
+	}
 
-	return NewCustomCommandExecutor(
+	executor := NewCustomCommandExecutor(
 		cfg.DisplayName,
 		cfg.Executor.Path,
 		cfg.Executor.DefaultArgs,
-		needsNewline,
-	), nil
+		lineFormatter,
+	)
+	executor.SetTargetValidator(targetValidator)
+	executor.SetPriority(cfg.Priority)
+	executor.SetPTY(cfg.PTY)
+	return executor, nil
 }
 
 // init registers all builtin executor factories
@@ -262,5 +555,9 @@ func init() {
 	RegisterGlobal("ping", PingExecutorFactory)
 	RegisterGlobal("mtr", MTRExecutorFactory)
 	RegisterGlobal("nexttrace", NextTraceExecutorFactory)
+	RegisterGlobal("traceroute", TracerouteExecutorFactory)
+	RegisterGlobal("dns", DigExecutorFactory)
+	RegisterGlobal("fping", FpingExecutorFactory)
+	RegisterGlobal("http", HTTPExecutorFactory)
 	RegisterGlobal("command", CommandExecutorFactory)
 }
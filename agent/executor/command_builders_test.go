@@ -0,0 +1,156 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"plain", "1  10.0.0.1  1.2 ms", "1  10.0.0.1  1.2 ms"},
+		{"color", "\x1b[1;32m1\x1b[0m  10.0.0.1", "1  10.0.0.1"},
+		{"cursor", "\x1b[2K\x1b[1Ahop", "hop"},
+		{"osc title", "\x1b]0;nexttrace\x07hop", "hop"},
+		{"single char", "\x1bMhop", "hop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripANSI(tt.line); got != tt.want {
+				t.Errorf("StripANSI(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLineFormatter(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		isNil   bool
+		wantErr bool
+	}{
+		{spec: "", isNil: true},
+		{spec: "none", isNil: true},
+		{spec: "newline", want: "\x1b[31mhop\x1b[0m\n"},
+		{spec: "strip_ansi", want: "hop"},
+		{spec: "strip_ansi, newline", want: "hop\n"},
+		{spec: "none,strip_ansi", want: "hop"},
+		{spec: "strip_ansi,upper", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			formatter, err := NewLineFormatter(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewLineFormatter(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (formatter == nil) != tt.isNil {
+				t.Fatalf("NewLineFormatter(%q) nil = %v, want %v", tt.spec, formatter == nil, tt.isNil)
+			}
+			if formatter != nil {
+				if got := formatter("\x1b[31mhop\x1b[0m"); got != tt.want {
+					t.Errorf("formatter(line) = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildTracerouteArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *pb.NetworkTestParams
+		want   []string
+	}{
+		{"defaults", &pb.NetworkTestParams{Target: "192.0.2.1"}, []string{"-4", "192.0.2.1"}},
+		{"hops and wait", &pb.NetworkTestParams{Target: "192.0.2.1", Count: 20, Timeout: 3}, []string{"-m", "20", "-w", "3", "-4", "192.0.2.1"}},
+		{"ipv6", &pb.NetworkTestParams{Target: "2001:db8::1", Ipv6: true}, []string{"-6", "2001:db8::1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildTracerouteArgs(tt.params); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildTracerouteArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildDigArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *pb.NetworkTestParams
+		want   []string
+	}{
+		{"defaults", &pb.NetworkTestParams{Target: "example.com"}, []string{"-t", "A", "-q", "example.com"}},
+		{"server and type", &pb.NetworkTestParams{
+			Target:       "example.com",
+			Timeout:      2,
+			ExtraOptions: map[string]string{"server": "192.0.2.53", "type": "mx"},
+		}, []string{"@192.0.2.53", "+time=2", "-t", "MX", "-q", "example.com"}},
+		{"unknown type", &pb.NetworkTestParams{Target: "example.com", ExtraOptions: map[string]string{"type": "ANY"}}, []string{"-t", "A", "-q", "example.com"}},
+		{"ipv6 transport", &pb.NetworkTestParams{Target: "example.com", Ipv6: true, ExtraOptions: map[string]string{"type": "AAAA"}}, []string{"-6", "-t", "AAAA", "-q", "example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildDigArgs(tt.params); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildDigArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildFpingArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *pb.NetworkTestParams
+		want   []string
+	}{
+		{"defaults", &pb.NetworkTestParams{Target: "1.1.1.1"}, []string{"-C", "10", "-q", "-4", "1.1.1.1"}},
+		{"count, period and timeout", &pb.NetworkTestParams{
+			Target:       "example.com",
+			Count:        5,
+			Timeout:      2,
+			ExtraOptions: map[string]string{FpingPeriodOption: "200"},
+		}, []string{"-C", "5", "-p", "200", "-t", "2000", "-q", "-4", "example.com"}},
+		{"period below the minimum", &pb.NetworkTestParams{Target: "1.1.1.1", ExtraOptions: map[string]string{FpingPeriodOption: "5"}}, []string{"-C", "10", "-q", "-4", "1.1.1.1"}},
+		{"ipv6", &pb.NetworkTestParams{Target: "2001:db8::1", Ipv6: true}, []string{"-C", "10", "-q", "-6", "2001:db8::1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildFpingArgs(tt.params); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildFpingArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatFpingLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"1.1.1.1 : 1.00 2.00 3.00", "1.1.1.1 : xmt/rcv/%loss = 3/3/0%, min/avg/max = 1.00/2.00/3.00"},
+		{"example.com : 1.20 - 1.40 -", "example.com : xmt/rcv/%loss = 4/2/50%, min/avg/max = 1.20/1.30/1.40"},
+		{"192.0.2.1 : - - -", "192.0.2.1 : xmt/rcv/%loss = 3/0/100%"},
+		{"ICMP Host Unreachable from 10.0.0.1", "ICMP Host Unreachable from 10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatFpingLine(tt.line); got != tt.want {
+			t.Errorf("FormatFpingLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -3,28 +3,60 @@ package executor
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/creack/pty"
+	"github.com/lureiny/lookingglass/agent/config"
 	pb "github.com/lureiny/lookingglass/pb"
 	"github.com/lureiny/lookingglass/pkg/logger"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// reapTimeout bounds how long a cancelled task waits for its process to be reaped
+const reapTimeout = 5 * time.Second
+
+// ptySize is the terminal size given to PTY-mode commands; wide enough that report lines do not wrap
+var ptySize = &pty.Winsize{Rows: 24, Cols: 200}
+
 // ArgsBuilder is a function that builds command-line arguments from task parameters
 type ArgsBuilder func(*pb.NetworkTestParams) []string
 
 // LineFormatter is an optional function to format output lines
 type LineFormatter func(string) string
 
+// SummaryParser converts the complete stdout of a command into a structured summary
+type SummaryParser interface {
+	// Accepts reports whether output for these parameters should be collected and parsed
+	Accepts(params *pb.NetworkTestParams) bool
+
+	// Parse returns the structured summary and human-readable lines to emit in its place
+	Parse(lines []string) (*pb.TaskSummary, []string, error)
+}
+
 // CommandExecutor is a generic executor for external commands
 type CommandExecutor struct {
-	name          string        // Display name for logging
-	cmdPath       string        // Path to the command binary
-	argsBuilder   ArgsBuilder   // Function to build command arguments
-	lineFormatter LineFormatter // Optional formatter for output lines (nil if not needed)
+	name          string                // Display name for logging
+	cmdPath       string                // Path to the command binary
+	argsBuilder   ArgsBuilder           // Function to build command arguments
+	lineFormatter LineFormatter         // Optional formatter for output lines (nil if not needed)
+	summaryParser SummaryParser         // Optional parser for structured output (nil if not needed)
+	targetCheck   *TargetValidator      // Optional allowlist for the target before args are built (nil = any)
+	resolveTarget bool                  // Whether to report the resolved target IPs before running
+	priority      config.PriorityConfig // OS scheduling priority applied to the started process
+	usePTY        bool                  // Run the command on a pseudo-terminal (stdout and stderr merged)
+	maxLines      int                   // Output lines after which the task is stopped (0 = unlimited)
+	maxBytes      int                   // Output bytes after which the task is stopped (0 = unlimited)
+	okExitCodes   []int                 // Non-zero exit codes that still mean the command succeeded
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -40,6 +72,49 @@ func NewCommandExecutor(name, cmdPath string, argsBuilder ArgsBuilder, lineForma
 	}
 }
 
+// SetSummaryParser sets the parser used for structured output modes
+func (e *CommandExecutor) SetSummaryParser(parser SummaryParser) {
+	e.summaryParser = parser
+}
+
+// SetTargetValidator rejects tasks whose target the validator does not accept
+func (e *CommandExecutor) SetTargetValidator(validator *TargetValidator) {
+	e.targetCheck = validator
+}
+
+// SetLineFormatter replaces the formatter applied to each output line (nil = none)
+func (e *CommandExecutor) SetLineFormatter(formatter LineFormatter) {
+	e.lineFormatter = formatter
+}
+
+// SetResolveTarget enables reporting the IPs a hostname target resolves to before the command runs
+func (e *CommandExecutor) SetResolveTarget(enabled bool) {
+	e.resolveTarget = enabled
+}
+
+// SetPriority sets the nice value / I/O class the command runs with
+func (e *CommandExecutor) SetPriority(priority config.PriorityConfig) {
+	e.priority = priority
+}
+
+// SetPTY runs the command on a pseudo-terminal, for tools that buffer or change output when not on a TTY
+func (e *CommandExecutor) SetPTY(enabled bool) {
+	e.usePTY = enabled
+}
+
+// SetSuccessExitCodes treats these non-zero exit codes as a completed task, for tools that
+// report their findings (e.g. lost probes) through the exit status
+func (e *CommandExecutor) SetSuccessExitCodes(codes ...int) {
+	e.okExitCodes = codes
+}
+
+// SetOutputLimits stops the command with TASK_STATUS_FAILED once its output exceeds
+// maxLines lines or maxBytes bytes (0 = unlimited)
+func (e *CommandExecutor) SetOutputLimits(maxLines, maxBytes int) {
+	e.maxLines = maxLines
+	e.maxBytes = maxBytes
+}
+
 // Execute executes a command task
 func (e *CommandExecutor) Execute(ctx context.Context, task *pb.Task, outputChan chan<- *pb.TaskOutput) error {
 	e.ctx, e.cancel = context.WithCancel(ctx)
@@ -50,6 +125,14 @@ func (e *CommandExecutor) Execute(ctx context.Context, task *pb.Task, outputChan
 		return fmt.Errorf("invalid parameters for %s task", e.name)
 	}
 
+	// Targets are substituted into the arguments; keep out anything a command could read as an option
+	if err := e.targetCheck.Validate(params.Target); err != nil {
+		return err
+	}
+	if err := validateOptionValues(params.ExtraOptions); err != nil {
+		return err
+	}
+
 	// Build command arguments
 	args := e.argsBuilder(params)
 	cmd := exec.CommandContext(e.ctx, e.cmdPath, args...)
@@ -60,31 +143,76 @@ func (e *CommandExecutor) Execute(ctx context.Context, task *pb.Task, outputChan
 		zap.Strings("args", args),
 	)
 
-	// Get stdout pipe
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stdout pipe: %w", err)
+	// Report which IPs a hostname target resolves to on this agent
+	if e.resolveTarget {
+		e.emitResolvedTarget(e.ctx, task.TaskId, params, outputChan)
 	}
 
-	// Get stderr pipe
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stderr pipe: %w", err)
+	// Start the command, reading its output from pipes or from a single pseudo-terminal
+	// (a PTY carries stdout and stderr together, so there is no separate stderr reader)
+	var stdout, stderr io.ReadCloser
+	if e.usePTY {
+		ptmx, err := pty.StartWithSize(cmd, ptySize)
+		if err != nil {
+			return AgentFault(fmt.Errorf("failed to start %s command on a pty: %w", e.name, err))
+		}
+		defer ptmx.Close()
+		stdout = ptmx
+	} else {
+		var err error
+		stdout, err = cmd.StdoutPipe()
+		if err != nil {
+			return AgentFault(fmt.Errorf("failed to get stdout pipe: %w", err))
+		}
+
+		stderr, err = cmd.StderrPipe()
+		if err != nil {
+			return AgentFault(fmt.Errorf("failed to get stderr pipe: %w", err))
+		}
+
+		if err := cmd.Start(); err != nil {
+			return AgentFault(fmt.Errorf("failed to start %s command: %w", e.name, err))
+		}
 	}
 
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start %s command: %w", e.name, err)
+	// Lower priority right after start so heavy commands do not starve the agent
+	if err := applyPriority(cmd.Process.Pid, e.priority); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to set %s command priority", e.name),
+			zap.String("task_id", task.TaskId),
+			zap.Error(err),
+		)
 	}
 
+	// Structured mode collects stdout and emits a parsed summary on completion
+	collect := e.summaryParser != nil && e.summaryParser.Accepts(params)
+	var collected []string
+
+	// Output caps, counted across stdout and stderr
+	limit := newOutputLimit(e.maxLines, e.maxBytes)
+
 	// Stream output
 	errChan := make(chan error, 1)
+	var readers sync.WaitGroup
+	readers.Add(1)
 
 	// Read stdout
 	go func() {
+		defer readers.Done()
 		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
 		for scanner.Scan() {
 			line := scanner.Text()
+			if e.usePTY {
+				line = strings.TrimSuffix(line, "\r") // Terminal line endings are \r\n
+			}
+			if e.checkOutputLimit(task.TaskId, limit, line, outputChan) {
+				return
+			}
+
+			if collect {
+				collected = append(collected, line)
+				continue
+			}
 
 			// Apply line formatter if provided
 			if e.lineFormatter != nil {
@@ -92,7 +220,7 @@ func (e *CommandExecutor) Execute(ctx context.Context, task *pb.Task, outputChan
 			}
 
 			select {
-			case <-ctx.Done():
+			case <-e.ctx.Done():
 				return
 			case outputChan <- &pb.TaskOutput{
 				TaskId:     task.TaskId,
@@ -102,7 +230,8 @@ func (e *CommandExecutor) Execute(ctx context.Context, task *pb.Task, outputChan
 			}:
 			}
 		}
-		if err := scanner.Err(); err != nil {
+		// Reading a PTY fails with EIO once the command exits; that is its EOF
+		if err := scanner.Err(); err != nil && !(e.usePTY && errors.Is(err, syscall.EIO)) {
 			logger.Error(fmt.Sprintf("Error reading %s stdout", e.name),
 				zap.String("task_id", task.TaskId),
 				zap.Error(err),
@@ -110,40 +239,53 @@ func (e *CommandExecutor) Execute(ctx context.Context, task *pb.Task, outputChan
 		}
 	}()
 
-	// Read stderr
-	go func() {
-		scanner := bufio.NewScanner(stderr)
-		for scanner.Scan() {
-			line := scanner.Text()
+	// Read stderr (not used in PTY mode)
+	if stderr != nil {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			scanner := bufio.NewScanner(stderr)
+			for scanner.Scan() {
+				line := scanner.Text()
+				if e.checkOutputLimit(task.TaskId, limit, line, outputChan) {
+					return
+				}
 
-			// Apply line formatter if provided
-			if e.lineFormatter != nil {
-				line = e.lineFormatter(line)
-			}
+				// Apply line formatter if provided
+				if e.lineFormatter != nil {
+					line = e.lineFormatter(line)
+				}
 
-			select {
-			case <-ctx.Done():
-				return
-			case outputChan <- &pb.TaskOutput{
-				TaskId:       task.TaskId,
-				OutputLine:   line,
-				Timestamp:    timestamppb.New(time.Now()),
-				Status:       pb.TaskStatus_TASK_STATUS_RUNNING,
-				ErrorMessage: scanner.Text(), // Original line without formatting
-			}:
+				select {
+				case <-e.ctx.Done():
+					return
+				case outputChan <- &pb.TaskOutput{
+					TaskId:       task.TaskId,
+					OutputLine:   line,
+					Timestamp:    timestamppb.New(time.Now()),
+					Status:       pb.TaskStatus_TASK_STATUS_RUNNING,
+					ErrorMessage: scanner.Text(), // Original line without formatting
+				}:
+				}
 			}
-		}
-		if err := scanner.Err(); err != nil {
-			logger.Error(fmt.Sprintf("Error reading %s stderr", e.name),
-				zap.String("task_id", task.TaskId),
-				zap.Error(err),
-			)
-		}
-	}()
+			if err := scanner.Err(); err != nil {
+				logger.Error(fmt.Sprintf("Error reading %s stderr", e.name),
+					zap.String("task_id", task.TaskId),
+					zap.Error(err),
+				)
+			}
+		}()
+	}
 
-	// Wait for command to complete
+	// Wait for command to complete (pipes must be fully read before Wait)
+	// This goroutine is the only caller of cmd.Wait, which reaps the child on every path
 	go func() {
+		readers.Wait()
 		err := cmd.Wait()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && slices.Contains(e.okExitCodes, exitErr.ExitCode()) {
+			err = nil
+		}
 		if err != nil {
 			logger.Error(fmt.Sprintf("%s command failed", e.name),
 				zap.String("task_id", task.TaskId),
@@ -164,6 +306,16 @@ func (e *CommandExecutor) Execute(ctx context.Context, task *pb.Task, outputChan
 		if cmd.Process != nil {
 			_ = cmd.Process.Kill()
 		}
+		// Unblock the readers even if a grandchild still holds the pipes, so cmd.Wait runs
+		_ = stdout.Close()
+		if stderr != nil {
+			_ = stderr.Close()
+		}
+		e.awaitReap(task.TaskId, cmd, errChan)
+
+		if err := limit.err(); err != nil {
+			return e.failOutputLimit(task.TaskId, err, outputChan)
+		}
 		outputChan <- &pb.TaskOutput{
 			TaskId:       task.TaskId,
 			Timestamp:    timestamppb.New(time.Now()),
@@ -173,6 +325,15 @@ func (e *CommandExecutor) Execute(ctx context.Context, task *pb.Task, outputChan
 		return e.ctx.Err()
 
 	case err := <-errChan:
+		if limitErr := limit.err(); limitErr != nil {
+			return e.failOutputLimit(task.TaskId, limitErr, outputChan)
+		}
+		// Structured output is emitted whether or not the command succeeded, so a failed
+		// trace still reports the hops it got
+		if collect {
+			e.emitSummary(task.TaskId, collected, outputChan)
+		}
+
 		if err != nil {
 			outputChan <- &pb.TaskOutput{
 				TaskId:       task.TaskId,
@@ -193,6 +354,133 @@ func (e *CommandExecutor) Execute(ctx context.Context, task *pb.Task, outputChan
 	}
 }
 
+// checkOutputLimit counts line against the output caps and reports whether the reader must stop
+// The first time a cap is exceeded it sends the truncation notice and cancels the command
+func (e *CommandExecutor) checkOutputLimit(taskID string, limit *outputLimit, line string, outputChan chan<- *pb.TaskOutput) bool {
+	notice, stop := limit.add(line)
+	if notice == "" {
+		return stop
+	}
+
+	logger.Warn(fmt.Sprintf("%s output limit exceeded, stopping task", e.name),
+		zap.String("task_id", taskID),
+		zap.String("notice", notice),
+	)
+	select {
+	case <-e.ctx.Done():
+	case outputChan <- &pb.TaskOutput{
+		TaskId:     taskID,
+		OutputLine: notice,
+		Timestamp:  timestamppb.New(time.Now()),
+		Status:     pb.TaskStatus_TASK_STATUS_RUNNING,
+	}:
+	}
+	e.cancel()
+	return true
+}
+
+// failOutputLimit reports a task stopped for exceeding an output cap
+func (e *CommandExecutor) failOutputLimit(taskID string, err error, outputChan chan<- *pb.TaskOutput) error {
+	outputChan <- &pb.TaskOutput{
+		TaskId:       taskID,
+		Timestamp:    timestamppb.New(time.Now()),
+		Status:       pb.TaskStatus_TASK_STATUS_FAILED,
+		ErrorMessage: err.Error(),
+	}
+	return err
+}
+
+// awaitReap waits for cmd.Wait to reap a killed process so it does not linger as a zombie
+// Gives up waiting (but not reaping) after reapTimeout, logging the stuck process
+func (e *CommandExecutor) awaitReap(taskID string, cmd *exec.Cmd, errChan <-chan error) {
+	select {
+	case <-errChan:
+	case <-time.After(reapTimeout):
+		logger.Warn(fmt.Sprintf("%s process not reaped after cancellation", e.name),
+			zap.String("task_id", taskID),
+			zap.Int("pid", cmd.Process.Pid),
+		)
+	}
+}
+
+// emitResolvedTarget resolves a hostname target and sends the result as the first output line
+// IP targets and resolution failures are skipped; the command itself reports lookup errors
+func (e *CommandExecutor) emitResolvedTarget(ctx context.Context, taskID string, params *pb.NetworkTestParams, outputChan chan<- *pb.TaskOutput) {
+	if params.Target == "" || net.ParseIP(params.Target) != nil {
+		return
+	}
+
+	network := "ip4"
+	if params.Ipv6 {
+		network = "ip6"
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	ips, err := net.DefaultResolver.LookupIP(lookupCtx, network, params.Target)
+	if err != nil || len(ips) == 0 {
+		logger.Debug("Failed to resolve target",
+			zap.String("task_id", taskID),
+			zap.String("target", params.Target),
+			zap.Error(err),
+		)
+		return
+	}
+
+	resolved := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		resolved = append(resolved, ip.String())
+	}
+
+	line := fmt.Sprintf("Resolved %s -> %s", params.Target, strings.Join(resolved, ", "))
+	if e.lineFormatter != nil {
+		line = e.lineFormatter(line)
+	}
+
+	outputChan <- &pb.TaskOutput{
+		TaskId:     taskID,
+		OutputLine: line,
+		Timestamp:  timestamppb.New(time.Now()),
+		Status:     pb.TaskStatus_TASK_STATUS_RUNNING,
+		Summary:    &pb.TaskSummary{ResolvedIps: resolved},
+	}
+}
+
+// emitSummary parses collected output and sends the summary followed by its display lines
+// Falls back to the raw output if parsing fails
+func (e *CommandExecutor) emitSummary(taskID string, collected []string, outputChan chan<- *pb.TaskOutput) {
+	summary, lines, err := e.summaryParser.Parse(collected)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to parse %s output", e.name),
+			zap.String("task_id", taskID),
+			zap.Error(err),
+		)
+		summary, lines = nil, collected
+	}
+
+	for _, line := range lines {
+		if e.lineFormatter != nil {
+			line = e.lineFormatter(line)
+		}
+		outputChan <- &pb.TaskOutput{
+			TaskId:     taskID,
+			OutputLine: line,
+			Timestamp:  timestamppb.New(time.Now()),
+			Status:     pb.TaskStatus_TASK_STATUS_RUNNING,
+		}
+	}
+
+	if summary != nil {
+		outputChan <- &pb.TaskOutput{
+			TaskId:    taskID,
+			Timestamp: timestamppb.New(time.Now()),
+			Status:    pb.TaskStatus_TASK_STATUS_RUNNING,
+			Summary:   summary,
+		}
+	}
+}
+
 // Cancel cancels a running task
 func (e *CommandExecutor) Cancel(taskID string) error {
 	if e.cancel != nil {
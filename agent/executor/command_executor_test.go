@@ -0,0 +1,149 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestEmitResolvedTarget(t *testing.T) {
+	e := NewCommandExecutor("ping", "ping", nil, nil)
+
+	emit := func(params *pb.NetworkTestParams) []*pb.TaskOutput {
+		outputChan := make(chan *pb.TaskOutput, 1)
+		e.emitResolvedTarget(context.Background(), "t1", params, outputChan)
+		close(outputChan)
+		var outputs []*pb.TaskOutput
+		for output := range outputChan {
+			outputs = append(outputs, output)
+		}
+		return outputs
+	}
+
+	// IP targets need no lookup
+	if got := emit(&pb.NetworkTestParams{Target: "192.0.2.1"}); len(got) != 0 {
+		t.Errorf("IP target: outputs = %v, want none", got)
+	}
+	if got := emit(&pb.NetworkTestParams{Target: "name.invalid"}); len(got) != 0 {
+		t.Errorf("unresolvable target: outputs = %v, want none", got)
+	}
+
+	got := emit(&pb.NetworkTestParams{Target: "localhost"})
+	if len(got) != 1 {
+		t.Skip("localhost does not resolve to IPv4 here")
+	}
+	output := got[0]
+	if output.TaskId != "t1" || output.Status != pb.TaskStatus_TASK_STATUS_RUNNING {
+		t.Errorf("output = %v", output)
+	}
+	ips := output.GetSummary().GetResolvedIps()
+	if len(ips) == 0 || !strings.HasPrefix(output.OutputLine, "Resolved localhost -> "+ips[0]) {
+		t.Errorf("line = %q, resolved IPs = %v", output.OutputLine, ips)
+	}
+}
+
+func TestCancelReapsProcess(t *testing.T) {
+	if _, err := os.Stat("/proc/self"); err != nil {
+		t.Skip("needs /proc to check for zombies")
+	}
+	// The shell reports its pid, then leaves a grandchild holding the output pipes
+	e := NewCommandExecutor("sh", "/bin/sh", func(*pb.NetworkTestParams) []string {
+		return []string{"-c", "echo $$; sleep 30 & sleep 30"}
+	}, nil)
+	task := &pb.Task{TaskId: "t1", Params: &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{Target: "192.0.2.1"}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	outputChan := make(chan *pb.TaskOutput, 8)
+	done := make(chan error, 1)
+	go func() { done <- e.Execute(ctx, task, outputChan) }()
+
+	var pid string
+	select {
+	case output := <-outputChan:
+		pid = output.OutputLine
+	case <-time.After(2 * time.Second):
+		t.Fatal("no pid reported")
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Execute() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(reapTimeout):
+		t.Fatal("Execute did not return after cancellation")
+	}
+	if last := <-outputChan; last.Status != pb.TaskStatus_TASK_STATUS_CANCELLED {
+		t.Errorf("final status = %v, want CANCELLED", last.Status)
+	}
+	if _, err := os.Stat("/proc/" + pid); !os.IsNotExist(err) {
+		t.Errorf("process %s not reaped after cancellation", pid)
+	}
+}
+
+func TestPTYMode(t *testing.T) {
+	run := func(usePTY bool) []string {
+		e := NewCommandExecutor("sh", "/bin/sh", func(*pb.NetworkTestParams) []string {
+			return []string{"-c", "if [ -t 1 ]; then echo tty; else echo pipe; fi; echo err >&2"}
+		}, nil)
+		e.SetPTY(usePTY)
+		task := &pb.Task{TaskId: "t1", Params: &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{Target: "192.0.2.1"}}}
+
+		outputChan := make(chan *pb.TaskOutput, 16)
+		if err := e.Execute(context.Background(), task, outputChan); err != nil {
+			t.Fatalf("pty=%v: Execute() error = %v", usePTY, err)
+		}
+		close(outputChan)
+		var lines []string
+		for output := range outputChan {
+			if output.Status == pb.TaskStatus_TASK_STATUS_RUNNING {
+				lines = append(lines, output.OutputLine)
+			}
+		}
+		return lines
+	}
+
+	// A PTY merges stderr into stdout and its \r\n line endings are trimmed
+	if got := strings.Join(run(true), ","); got != "tty,err" {
+		t.Errorf("pty lines = %q, want %q", got, "tty,err")
+	}
+	got := run(false)
+	if len(got) != 2 || !slices.Contains(got, "pipe") || !slices.Contains(got, "err") {
+		t.Errorf("pipe lines = %q, want pipe and err", got)
+	}
+}
+
+func TestSuccessExitCodes(t *testing.T) {
+	run := func(okCodes ...int) *pb.TaskOutput {
+		// Exits 1, as fping does when probes were lost
+		e := NewCommandExecutor("exit1", "/bin/sh", func(*pb.NetworkTestParams) []string {
+			return []string{"-c", "echo result; exit 1"}
+		}, nil)
+		e.SetSuccessExitCodes(okCodes...)
+
+		outputChan := make(chan *pb.TaskOutput, 10)
+		e.Execute(context.Background(), &pb.Task{
+			TaskId: "t1",
+			Params: &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{}},
+		}, outputChan)
+		close(outputChan)
+		var last *pb.TaskOutput
+		for output := range outputChan {
+			last = output
+		}
+		return last
+	}
+
+	if got := run(1).GetStatus(); got != pb.TaskStatus_TASK_STATUS_COMPLETED {
+		t.Errorf("exit 1 listed as success: status = %v, want COMPLETED", got)
+	}
+	if got := run().GetStatus(); got != pb.TaskStatus_TASK_STATUS_FAILED {
+		t.Errorf("exit 1 not listed: status = %v, want FAILED", got)
+	}
+}
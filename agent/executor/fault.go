@@ -0,0 +1,27 @@
+package executor
+
+import "errors"
+
+// agentFaultError marks an error as the agent's own fault, e.g. a task binary that would not start,
+// as opposed to the task's (bad target, policy rejection, output limit)
+type agentFaultError struct {
+	err error
+}
+
+func (e *agentFaultError) Error() string { return e.err.Error() }
+
+func (e *agentFaultError) Unwrap() error { return e.err }
+
+// AgentFault marks err as caused by the agent itself; the master's circuit breaker counts only these
+func AgentFault(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &agentFaultError{err: err}
+}
+
+// IsAgentFault reports whether err, or an error it wraps, was marked by AgentFault
+func IsAgentFault(err error) bool {
+	var fault *agentFaultError
+	return errors.As(err, &fault)
+}
@@ -0,0 +1,49 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fpingResultRe matches a per-target result line of `fping -C N -q`: "1.1.1.1 : 1.23 - 1.45"
+// where "-" marks a lost probe
+var fpingResultRe = regexp.MustCompile(`^(\S+)\s+:\s+((?:[\d.]+|-)(?:\s+(?:[\d.]+|-))*)\s*$`)
+
+// FormatFpingLine is a line formatter that turns the per-probe RTTs fping prints for a target
+// into one summary line: "1.1.1.1 : xmt/rcv/%loss = 5/4/20%, min/avg/max = 1.23/1.35/1.45"
+// A target that lost every probe gets the loss figures alone; other lines pass through unchanged
+func FormatFpingLine(line string) string {
+	m := fpingResultRe.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return line
+	}
+
+	var rtts []float64
+	fields := strings.Fields(m[2])
+	for _, field := range fields {
+		if field == "-" {
+			continue
+		}
+		rtt, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return line
+		}
+		rtts = append(rtts, rtt)
+	}
+
+	sent, received := len(fields), len(rtts)
+	summary := fmt.Sprintf("%s : xmt/rcv/%%loss = %d/%d/%d%%", m[1], sent, received, (sent-received)*100/sent)
+	if received == 0 {
+		return summary
+	}
+
+	minRTT, maxRTT, total := rtts[0], rtts[0], 0.0
+	for _, rtt := range rtts {
+		minRTT = min(minRTT, rtt)
+		maxRTT = max(maxRTT, rtt)
+		total += rtt
+	}
+	return fmt.Sprintf("%s, min/avg/max = %.2f/%.2f/%.2f", summary, minRTT, total/float64(received), maxRTT)
+}
@@ -0,0 +1,301 @@
+package executor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lureiny/lookingglass/agent/config"
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"github.com/lureiny/lookingglass/pkg/netutil"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// HTTPMethodOption is the ExtraOptions key selecting the request method (GET or HEAD, default GET)
+	HTTPMethodOption = "method"
+	// HTTPExpectStatusOption is the ExtraOptions key holding comma-separated acceptable status codes
+	// (default: any status below 400)
+	HTTPExpectStatusOption = "expected_status"
+
+	// httpDefaultTimeout applies when the task sets no timeout
+	httpDefaultTimeout = 30 * time.Second
+	// httpMaxBody bounds how much of the response body is read to measure the total time
+	httpMaxBody = 10 * 1024 * 1024
+)
+
+// HTTPExecutor requests a URL and reports status and timings
+// Redirects are not followed; the redirect response itself is the result
+type HTTPExecutor struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	denylist *netutil.TargetDenylist // Checked on every connection (nil = none)
+}
+
+// NewHTTPExecutor creates a new HTTP executor
+func NewHTTPExecutor() *HTTPExecutor {
+	return &HTTPExecutor{}
+}
+
+// SetTargetDenylist checks every address the request connects to against denylist
+func (e *HTTPExecutor) SetTargetDenylist(denylist *netutil.TargetDenylist) {
+	e.denylist = denylist
+}
+
+// httpTimings collects the phases of one request
+type httpTimings struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	remoteAddr   string
+}
+
+// Execute performs the request and streams the result
+func (e *HTTPExecutor) Execute(ctx context.Context, task *pb.Task, outputChan chan<- *pb.TaskOutput) error {
+	e.ctx, e.cancel = context.WithCancel(ctx)
+
+	params := task.GetNetworkTest()
+	if params == nil {
+		return fmt.Errorf("invalid parameters for http task")
+	}
+
+	fail := func(err error) error {
+		outputChan <- &pb.TaskOutput{
+			TaskId:       task.TaskId,
+			Timestamp:    timestamppb.New(time.Now()),
+			Status:       pb.TaskStatus_TASK_STATUS_FAILED,
+			ErrorMessage: err.Error(),
+		}
+		return err
+	}
+
+	target, err := httpTargetURL(params.Target)
+	if err != nil {
+		return fail(err)
+	}
+
+	method := strings.ToUpper(params.ExtraOptions[HTTPMethodOption])
+	if method == "" {
+		method = http.MethodGet
+	}
+	if method != http.MethodGet && method != http.MethodHead {
+		return fail(fmt.Errorf("unsupported method %q (use GET or HEAD)", method))
+	}
+
+	expected, err := parseExpectedStatus(params.ExtraOptions[HTTPExpectStatusOption])
+	if err != nil {
+		return fail(err)
+	}
+
+	timeout := httpDefaultTimeout
+	if params.Timeout > 0 {
+		timeout = time.Duration(params.Timeout) * time.Second
+	}
+	reqCtx, cancel := context.WithTimeout(e.ctx, timeout)
+	defer cancel()
+
+	var timings httpTimings
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { timings.dnsStart = time.Now() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { timings.dnsDone = time.Now() },
+		ConnectStart:      func(string, string) { timings.connectStart = time.Now() },
+		ConnectDone:       func(string, string, error) { timings.connectDone = time.Now() },
+		TLSHandshakeStart: func() { timings.tlsStart = time.Now() },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { timings.tlsDone = time.Now() },
+		GotConn: func(info httptrace.GotConnInfo) {
+			timings.remoteAddr = info.Conn.RemoteAddr().String()
+		},
+		GotFirstResponseByte: func() { timings.firstByte = time.Now() },
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(reqCtx, trace), method, target, nil)
+	if err != nil {
+		return fail(fmt.Errorf("invalid request: %w", err))
+	}
+	req.Header.Set("User-Agent", "lookingglass-agent")
+
+	logger.Info("Starting http request",
+		zap.String("task_id", task.TaskId),
+		zap.String("url", target),
+		zap.String("method", method),
+	)
+
+	client := newHTTPClient(params.Ipv6, e.denylist)
+	defer client.CloseIdleConnections()
+
+	timings.start = time.Now()
+	resp, err := client.Do(req)
+	if err == nil {
+		_, err = io.Copy(io.Discard, io.LimitReader(resp.Body, httpMaxBody))
+		resp.Body.Close()
+	}
+	total := time.Since(timings.start)
+
+	if e.ctx.Err() != nil {
+		outputChan <- &pb.TaskOutput{
+			TaskId:       task.TaskId,
+			Timestamp:    timestamppb.New(time.Now()),
+			Status:       pb.TaskStatus_TASK_STATUS_CANCELLED,
+			ErrorMessage: "Task cancelled",
+		}
+		return e.ctx.Err()
+	}
+	if err != nil {
+		return fail(fmt.Errorf("request failed: %w", err))
+	}
+
+	for _, line := range httpResultLines(resp, &timings, total) {
+		outputChan <- &pb.TaskOutput{
+			TaskId:     task.TaskId,
+			OutputLine: line,
+			Timestamp:  timestamppb.New(time.Now()),
+			Status:     pb.TaskStatus_TASK_STATUS_RUNNING,
+		}
+	}
+
+	if !statusExpected(resp.StatusCode, expected) {
+		return fail(fmt.Errorf("unexpected status %d", resp.StatusCode))
+	}
+
+	outputChan <- &pb.TaskOutput{
+		TaskId:    task.TaskId,
+		Timestamp: timestamppb.New(time.Now()),
+		Status:    pb.TaskStatus_TASK_STATUS_COMPLETED,
+	}
+	return nil
+}
+
+// Cancel cancels a running request
+func (e *HTTPExecutor) Cancel(taskID string) error {
+	if e.cancel != nil {
+		logger.Info("Cancelling http task",
+			zap.String("task_id", taskID),
+		)
+		e.cancel()
+	}
+	return nil
+}
+
+// newHTTPClient creates a client that connects over IPv4 or IPv6 only and does not follow redirects
+// It dials only addresses the denylist has checked, resolving each host once
+func newHTTPClient(ipv6 bool, denylist *netutil.TargetDenylist) *http.Client {
+	network := "tcp4"
+	if ipv6 {
+		network = "tcp6"
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:         denylist.DialContext(dialer, network),
+			TLSHandshakeTimeout: 10 * time.Second,
+			DisableKeepAlives:   true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// httpTargetURL turns a target into an http(s) URL, defaulting to https
+func httpTargetURL(target string) (string, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return "", fmt.Errorf("target URL is required")
+	}
+	if !strings.Contains(target, "://") {
+		target = "https://" + target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid target URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("target URL has no host")
+	}
+	return u.String(), nil
+}
+
+// parseExpectedStatus parses a comma-separated list of status codes (empty = none given)
+func parseExpectedStatus(value string) ([]int, error) {
+	var codes []int
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		code, err := strconv.Atoi(field)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid expected status %q", field)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// statusExpected reports whether a status is acceptable: one of expected, or below 400 if none are given
+func statusExpected(status int, expected []int) bool {
+	if len(expected) == 0 {
+		return status < 400
+	}
+	for _, code := range expected {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
+
+// httpResultLines formats the response status and request timings
+func httpResultLines(resp *http.Response, t *httpTimings, total time.Duration) []string {
+	lines := []string{fmt.Sprintf("%s %s", resp.Proto, resp.Status)}
+	if t.remoteAddr != "" {
+		lines = append(lines, fmt.Sprintf("Connected to: %s", t.remoteAddr))
+	}
+	if location := resp.Header.Get("Location"); location != "" {
+		lines = append(lines, fmt.Sprintf("Redirect to: %s", location))
+	}
+	if !t.dnsDone.IsZero() {
+		lines = append(lines, fmt.Sprintf("DNS lookup: %s", formatPhase(t.dnsDone.Sub(t.dnsStart))))
+	}
+	if !t.connectDone.IsZero() {
+		lines = append(lines, fmt.Sprintf("TCP connect: %s", formatPhase(t.connectDone.Sub(t.connectStart))))
+	}
+	if !t.tlsDone.IsZero() {
+		lines = append(lines, fmt.Sprintf("TLS handshake: %s", formatPhase(t.tlsDone.Sub(t.tlsStart))))
+	}
+	if !t.firstByte.IsZero() {
+		lines = append(lines, fmt.Sprintf("TTFB: %s", formatPhase(t.firstByte.Sub(t.start))))
+	}
+	lines = append(lines, fmt.Sprintf("Total: %s", formatPhase(total)))
+	return lines
+}
+
+// formatPhase rounds a duration for display
+func formatPhase(d time.Duration) string {
+	return d.Round(100 * time.Microsecond).String()
+}
+
+// HTTPExecutorFactory creates an HTTP executor from configuration
+func HTTPExecutorFactory(cfg *config.TaskConfig) (Executor, error) {
+	return NewHTTPExecutor(), nil
+}
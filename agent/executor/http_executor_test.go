@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// runHTTP executes an http task against target and returns its outputs
+func runHTTP(t *testing.T, target string, options map[string]string) []*pb.TaskOutput {
+	t.Helper()
+	outputChan := make(chan *pb.TaskOutput, 32)
+	task := &pb.Task{TaskId: "t1", Params: &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{
+		Target:       target,
+		Timeout:      5,
+		ExtraOptions: options,
+	}}}
+	NewHTTPExecutor().Execute(context.Background(), task, outputChan)
+	close(outputChan)
+
+	var outputs []*pb.TaskOutput
+	for output := range outputChan {
+		outputs = append(outputs, output)
+	}
+	return outputs
+}
+
+func TestHTTPExecutor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	outputs := runHTTP(t, srv.URL, nil)
+	if last := outputs[len(outputs)-1]; last.Status != pb.TaskStatus_TASK_STATUS_COMPLETED {
+		t.Fatalf("final output = %v, want COMPLETED", last)
+	}
+	if outputs[0].OutputLine != "HTTP/1.1 200 OK" {
+		t.Errorf("first line = %q, want the response status", outputs[0].OutputLine)
+	}
+
+	// The redirect itself is the result, and 302 is acceptable by default
+	outputs = runHTTP(t, srv.URL+"/old", nil)
+	var redirect bool
+	for _, output := range outputs {
+		redirect = redirect || output.OutputLine == "Redirect to: /new"
+	}
+	if !redirect || outputs[len(outputs)-1].Status != pb.TaskStatus_TASK_STATUS_COMPLETED {
+		t.Errorf("redirect outputs = %v, want a completed redirect", outputs)
+	}
+
+	// A status outside expected_status fails the task
+	outputs = runHTTP(t, srv.URL, map[string]string{HTTPExpectStatusOption: "204"})
+	if last := outputs[len(outputs)-1]; last.Status != pb.TaskStatus_TASK_STATUS_FAILED || last.ErrorMessage != "unexpected status 200" {
+		t.Errorf("final output = %v, want unexpected status 200", last)
+	}
+
+	outputs = runHTTP(t, srv.URL, map[string]string{HTTPMethodOption: "POST"})
+	if last := outputs[len(outputs)-1]; last.Status != pb.TaskStatus_TASK_STATUS_FAILED || !strings.Contains(last.ErrorMessage, "unsupported method") {
+		t.Errorf("final output = %v, want an unsupported method failure", last)
+	}
+}
+
+func TestHTTPTargetURL(t *testing.T) {
+	tests := []struct {
+		target  string
+		want    string
+		wantErr bool
+	}{
+		{"example.com", "https://example.com", false},
+		{"http://example.com/path", "http://example.com/path", false},
+		{"ftp://example.com", "", true},
+		{"https://", "", true},
+		{" ", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := httpTargetURL(tt.target)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("httpTargetURL(%q) = %q, %v, want %q (error %v)", tt.target, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseExpectedStatus(t *testing.T) {
+	codes, err := parseExpectedStatus("200, 204")
+	if err != nil || len(codes) != 2 || !statusExpected(204, codes) || statusExpected(301, codes) {
+		t.Errorf("parseExpectedStatus(200, 204) = %v, %v", codes, err)
+	}
+	if _, err := parseExpectedStatus("200,abc"); err == nil {
+		t.Error("non-numeric status accepted")
+	}
+	if _, err := parseExpectedStatus("99"); err == nil {
+		t.Error("out-of-range status accepted")
+	}
+	if !statusExpected(302, nil) || statusExpected(404, nil) {
+		t.Error("without expected codes, statuses below 400 should pass")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/netutil"
 )
 
 // Executor defines the interface for command executors
@@ -14,3 +15,22 @@ type Executor interface {
 	// Cancel cancels a running task
 	Cancel(taskID string) error
 }
+
+// OutputLimiter is implemented by executors that can stop a task whose output grows too large
+type OutputLimiter interface {
+	// SetOutputLimits caps a task's output lines and bytes (0 = unlimited)
+	SetOutputLimits(maxLines, maxBytes int)
+}
+
+// TargetDenylistUser is implemented by executors that resolve and connect to targets themselves
+type TargetDenylistUser interface {
+	// SetTargetDenylist makes every connection go only to addresses the denylist has checked
+	SetTargetDenylist(denylist *netutil.TargetDenylist)
+}
+
+// TaskSlotUser is implemented by executors that run several processes for one task
+type TaskSlotUser interface {
+	// SetTaskSlots shares the task's concurrency semaphore, of which the task already holds one slot;
+	// every process beyond the first takes another
+	SetTaskSlots(slots chan struct{})
+}
@@ -0,0 +1,293 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lureiny/lookingglass/agent/config"
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// PingTargetsOption is the ExtraOptions key holding a comma-separated target list
+const PingTargetsOption = "targets"
+
+// maxPingTargets caps the number of targets a single ping task may fan out to
+const maxPingTargets = 16
+
+// MultiPingExecutor runs ping and fans out to several targets in one task
+// Targets come from ExtraOptions["targets"] or a comma-separated Target; a single
+// target behaves exactly like the plain ping executor
+type MultiPingExecutor struct {
+	pingPath    string
+	maxParallel int // Sub-pings running at once (the ping task's concurrency limit)
+	priority    config.PriorityConfig
+	usePTY      bool
+	maxLines    int // Output caps applied to each sub-ping (0 = unlimited)
+	maxBytes    int
+	taskSlots   chan struct{} // The ping task's concurrency semaphore, shared with other ping tasks (nil = none)
+
+	cancel context.CancelFunc
+	mutex  sync.Mutex
+}
+
+// NewMultiPingExecutor creates a ping executor that supports multiple targets
+func NewMultiPingExecutor(pingPath string, maxParallel int) *MultiPingExecutor {
+	if maxParallel < 1 {
+		maxParallel = 1
+	}
+	return &MultiPingExecutor{
+		pingPath:    pingPath,
+		maxParallel: maxParallel,
+	}
+}
+
+// SetPriority sets the nice value / I/O class every sub-ping runs with
+func (e *MultiPingExecutor) SetPriority(priority config.PriorityConfig) {
+	e.priority = priority
+}
+
+// SetPTY runs every sub-ping on a pseudo-terminal
+func (e *MultiPingExecutor) SetPTY(enabled bool) {
+	e.usePTY = enabled
+}
+
+// SetOutputLimits caps the output of every sub-ping (0 = unlimited)
+func (e *MultiPingExecutor) SetOutputLimits(maxLines, maxBytes int) {
+	e.maxLines = maxLines
+	e.maxBytes = maxBytes
+}
+
+// SetTaskSlots makes sub-pings beyond the first take a slot of the ping task's concurrency limit
+func (e *MultiPingExecutor) SetTaskSlots(slots chan struct{}) {
+	e.taskSlots = slots
+}
+
+// newPing creates a single-target ping executor with this executor's priority and PTY mode
+func (e *MultiPingExecutor) newPing() *CommandExecutor {
+	ping := NewPingExecutor(e.pingPath)
+	ping.SetPriority(e.priority)
+	ping.SetPTY(e.usePTY)
+	ping.SetOutputLimits(e.maxLines, e.maxBytes)
+	return ping
+}
+
+// pingTargetResult is the outcome of one sub-ping
+type pingTargetResult struct {
+	status pb.TaskStatus
+	err    string
+}
+
+// Execute pings every target, streaming lines labeled "[target] ..." followed by a per-target summary
+func (e *MultiPingExecutor) Execute(ctx context.Context, task *pb.Task, outputChan chan<- *pb.TaskOutput) error {
+	params := task.GetNetworkTest()
+	if params == nil {
+		return fmt.Errorf("invalid parameters for ping task")
+	}
+
+	targets := pingTargets(params)
+	if len(targets) > maxPingTargets {
+		return fmt.Errorf("too many ping targets: %d (max %d)", len(targets), maxPingTargets)
+	}
+	if len(targets) <= 1 {
+		single := task
+		if len(targets) == 1 && targets[0] != params.Target {
+			single = subPingTask(task, targets[0])
+		}
+		return e.newPing().Execute(ctx, single, outputChan)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	e.mutex.Lock()
+	e.cancel = cancel
+	e.mutex.Unlock()
+
+	logger.Info("Starting multi-target ping",
+		zap.String("task_id", task.TaskId),
+		zap.Strings("targets", targets),
+		zap.Int("max_parallel", e.maxParallel),
+	)
+
+	results := make([]pingTargetResult, len(targets))
+	semaphore := make(chan struct{}, e.maxParallel)
+	// The slot the task already holds runs one sub-ping; the others borrow from taskSlots
+	owned := make(chan struct{}, 1)
+	owned <- struct{}{}
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				results[i] = pingTargetResult{status: pb.TaskStatus_TASK_STATUS_CANCELLED}
+				return
+			}
+
+			release, ok := e.acquireSlot(ctx, owned)
+			if !ok {
+				results[i] = pingTargetResult{status: pb.TaskStatus_TASK_STATUS_CANCELLED}
+				return
+			}
+			defer release()
+
+			results[i] = e.pingTarget(ctx, task, target, outputChan)
+		}(i, target)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		outputChan <- &pb.TaskOutput{
+			TaskId:       task.TaskId,
+			Timestamp:    timestamppb.New(time.Now()),
+			Status:       pb.TaskStatus_TASK_STATUS_CANCELLED,
+			ErrorMessage: "Task cancelled",
+		}
+		return ctx.Err()
+	}
+
+	// Per-target summary
+	failed := 0
+	for i, target := range targets {
+		line := fmt.Sprintf("[%s] ok", target)
+		if results[i].status != pb.TaskStatus_TASK_STATUS_COMPLETED {
+			failed++
+			line = fmt.Sprintf("[%s] failed: %s", target, results[i].err)
+		}
+		outputChan <- &pb.TaskOutput{
+			TaskId:     task.TaskId,
+			OutputLine: line,
+			Timestamp:  timestamppb.New(time.Now()),
+			Status:     pb.TaskStatus_TASK_STATUS_RUNNING,
+		}
+	}
+
+	if failed == len(targets) {
+		err := fmt.Errorf("ping failed for all %d targets", failed)
+		outputChan <- &pb.TaskOutput{
+			TaskId:       task.TaskId,
+			Timestamp:    timestamppb.New(time.Now()),
+			Status:       pb.TaskStatus_TASK_STATUS_FAILED,
+			ErrorMessage: err.Error(),
+		}
+		return err
+	}
+
+	outputChan <- &pb.TaskOutput{
+		TaskId:    task.TaskId,
+		Timestamp: timestamppb.New(time.Now()),
+		Status:    pb.TaskStatus_TASK_STATUS_COMPLETED,
+	}
+	return nil
+}
+
+// acquireSlot waits for the task's own slot or a free one in taskSlots
+// It reports false if ctx ends first; release returns the slot to where it came from
+func (e *MultiPingExecutor) acquireSlot(ctx context.Context, owned chan struct{}) (release func(), ok bool) {
+	if e.taskSlots == nil {
+		return func() {}, true
+	}
+	// Prefer the task's own slot so other ping tasks keep theirs
+	select {
+	case <-owned:
+		return func() { owned <- struct{}{} }, true
+	default:
+	}
+	select {
+	case <-owned:
+		return func() { owned <- struct{}{} }, true
+	case e.taskSlots <- struct{}{}:
+		return func() { <-e.taskSlots }, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// pingTarget runs one sub-ping, forwarding its output labeled with the target
+// The sub-ping's terminal status is captured rather than forwarded
+func (e *MultiPingExecutor) pingTarget(ctx context.Context, task *pb.Task, target string, outputChan chan<- *pb.TaskOutput) pingTargetResult {
+	subChan := make(chan *pb.TaskOutput, 100)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(subChan)
+		errChan <- e.newPing().Execute(ctx, subPingTask(task, target), subChan)
+	}()
+
+	result := pingTargetResult{status: pb.TaskStatus_TASK_STATUS_UNSPECIFIED}
+	for output := range subChan {
+		switch output.Status {
+		case pb.TaskStatus_TASK_STATUS_COMPLETED,
+			pb.TaskStatus_TASK_STATUS_FAILED,
+			pb.TaskStatus_TASK_STATUS_CANCELLED:
+			result = pingTargetResult{status: output.Status, err: output.ErrorMessage}
+			continue
+		}
+
+		labeled := proto.Clone(output).(*pb.TaskOutput)
+		labeled.TaskId = task.TaskId
+		if labeled.OutputLine != "" {
+			labeled.OutputLine = fmt.Sprintf("[%s] %s", target, labeled.OutputLine)
+		}
+		outputChan <- labeled
+	}
+
+	// Errors returned before any terminal output (e.g. the command failed to start)
+	if err := <-errChan; err != nil && result.status == pb.TaskStatus_TASK_STATUS_UNSPECIFIED {
+		result = pingTargetResult{status: pb.TaskStatus_TASK_STATUS_FAILED, err: err.Error()}
+	}
+	return result
+}
+
+// Cancel cancels all running sub-pings
+func (e *MultiPingExecutor) Cancel(taskID string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.cancel != nil {
+		logger.Info("Cancelling multi-target ping task",
+			zap.String("task_id", taskID),
+		)
+		e.cancel()
+	}
+	return nil
+}
+
+// pingTargets returns the de-duplicated target list from ExtraOptions["targets"] or Target
+func pingTargets(params *pb.NetworkTestParams) []string {
+	raw := params.ExtraOptions[PingTargetsOption]
+	if raw == "" {
+		raw = params.Target
+	}
+
+	seen := make(map[string]bool)
+	var targets []string
+	for _, target := range strings.Split(raw, ",") {
+		target = strings.TrimSpace(target)
+		if target == "" || seen[target] {
+			continue
+		}
+		seen[target] = true
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// subPingTask returns a copy of task pinging a single target
+func subPingTask(task *pb.Task, target string) *pb.Task {
+	sub := proto.Clone(task).(*pb.Task)
+	params := sub.GetNetworkTest()
+	params.Target = target
+	delete(params.ExtraOptions, PingTargetsOption)
+	return sub
+}
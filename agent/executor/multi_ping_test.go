@@ -0,0 +1,114 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// writeFakePing writes a ping stand-in that prints one reply for its last argument
+// Each run appends "start" and "end" to log, if set
+func writeFakePing(t *testing.T, log string) string {
+	t.Helper()
+	script := "#!/bin/sh\nfor last; do :; done\n"
+	if log != "" {
+		script += fmt.Sprintf("echo start >> %s\nsleep 0.1\necho end >> %s\n", log, log)
+	}
+	script += "echo \"64 bytes from $last: icmp_seq=1 ttl=57 time=1.0 ms\"\n"
+	path := filepath.Join(t.TempDir(), "ping")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake ping: %v", err)
+	}
+	return path
+}
+
+// runMultiPing executes a ping task and returns all outputs
+func runMultiPing(t *testing.T, e *MultiPingExecutor, params *pb.NetworkTestParams) ([]*pb.TaskOutput, error) {
+	t.Helper()
+	task := &pb.Task{TaskId: "t1", TaskName: "ping", Params: &pb.Task_NetworkTest{NetworkTest: params}}
+	outputChan := make(chan *pb.TaskOutput, 256)
+	err := e.Execute(context.Background(), task, outputChan)
+	close(outputChan)
+
+	var outputs []*pb.TaskOutput
+	for output := range outputChan {
+		outputs = append(outputs, output)
+	}
+	return outputs, err
+}
+
+func TestMultiPingLabelsEachTarget(t *testing.T) {
+	e := NewMultiPingExecutor(writeFakePing(t, ""), 3)
+	targets := []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"}
+
+	outputs, err := runMultiPing(t, e, &pb.NetworkTestParams{Target: strings.Join(targets, ", "), Count: 1})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	var lines []string
+	for _, output := range outputs {
+		if output.OutputLine != "" {
+			lines = append(lines, output.OutputLine)
+		}
+	}
+	for _, target := range targets {
+		reply := fmt.Sprintf("[%s] 64 bytes from %s", target, target)
+		summary := fmt.Sprintf("[%s] ok", target)
+		var gotReply, gotSummary bool
+		for _, line := range lines {
+			gotReply = gotReply || strings.HasPrefix(line, reply)
+			gotSummary = gotSummary || line == summary
+		}
+		if !gotReply || !gotSummary {
+			t.Errorf("target %s: reply=%v summary=%v in %q", target, gotReply, gotSummary, lines)
+		}
+	}
+
+	if last := outputs[len(outputs)-1]; last.Status != pb.TaskStatus_TASK_STATUS_COMPLETED {
+		t.Errorf("final status = %s, want COMPLETED", last.Status)
+	}
+}
+
+func TestMultiPingTargetsOption(t *testing.T) {
+	got := pingTargets(&pb.NetworkTestParams{
+		Target:       "ignored",
+		ExtraOptions: map[string]string{PingTargetsOption: "a, b,a,,c"},
+	})
+	if strings.Join(got, ",") != "a,b,c" {
+		t.Errorf("pingTargets() = %v, want [a b c]", got)
+	}
+}
+
+func TestMultiPingSubPingsTakeTaskSlots(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "runs.log")
+	e := NewMultiPingExecutor(writeFakePing(t, log), 3)
+
+	// The ping limit is 2 and another ping task holds the second slot:
+	// this task may only run one sub-ping at a time, on the slot it holds itself
+	slots := make(chan struct{}, 2)
+	slots <- struct{}{}
+	slots <- struct{}{}
+	e.SetTaskSlots(slots)
+
+	if _, err := runMultiPing(t, e, &pb.NetworkTestParams{Target: "192.0.2.1,192.0.2.2,192.0.2.3", Count: 1}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	runs := strings.Fields(string(data))
+	if want := "start end start end start end"; strings.Join(runs, " ") != want {
+		t.Errorf("sub-pings overlapped: %v", runs)
+	}
+	if len(slots) != 2 {
+		t.Errorf("slots in use after the task = %d, want 2", len(slots))
+	}
+}
@@ -0,0 +1,140 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// NextTraceFormatOption is the extra option key that selects nexttrace output format
+const NextTraceFormatOption = "format"
+
+// nexttraceResult mirrors the document printed by `nexttrace --json`
+type nexttraceResult struct {
+	Hops [][]nexttraceProbe `json:"Hops"`
+}
+
+// nexttraceProbe is a single probe result within a hop
+type nexttraceProbe struct {
+	Success  bool   `json:"Success"`
+	TTL      int32  `json:"TTL"`
+	Hostname string `json:"Hostname"`
+	RTT      int64  `json:"RTT"` // time.Duration in nanoseconds
+	Address  *struct {
+		IP string `json:"IP"`
+	} `json:"Address"`
+	Geo *struct {
+		ASNumber string `json:"asnumber"`
+		Country  string `json:"country"`
+		Prov     string `json:"prov"`
+		City     string `json:"city"`
+		Owner    string `json:"owner"`
+		ISP      string `json:"isp"`
+	} `json:"Geo"`
+}
+
+// NextTraceJSONParser parses nexttrace JSON output into per-hop summaries
+type NextTraceJSONParser struct{}
+
+// Accepts reports whether the task requested JSON output
+func (NextTraceJSONParser) Accepts(params *pb.NetworkTestParams) bool {
+	return isNextTraceJSON(params)
+}
+
+// Parse converts nexttrace JSON output into trace hops and display lines
+func (NextTraceJSONParser) Parse(lines []string) (*pb.TaskSummary, []string, error) {
+	raw := strings.Join(lines, "\n")
+	// nexttrace may print banner lines before the JSON document
+	start := strings.Index(raw, "{")
+	if start < 0 {
+		return nil, nil, fmt.Errorf("no JSON document in output")
+	}
+
+	var result nexttraceResult
+	if err := json.Unmarshal([]byte(raw[start:]), &result); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode nexttrace JSON: %w", err)
+	}
+
+	summary := &pb.TaskSummary{}
+	display := make([]string, 0, len(result.Hops))
+	for i, probes := range result.Hops {
+		hop := buildTraceHop(int32(i+1), probes)
+		summary.TraceHops = append(summary.TraceHops, hop)
+		display = append(display, formatTraceHop(hop))
+	}
+
+	return summary, display, nil
+}
+
+// buildTraceHop merges the probes of one TTL into a single hop
+// Uses the first responding probe for address/geo data and averages RTT over responses
+func buildTraceHop(ttl int32, probes []nexttraceProbe) *pb.TraceHop {
+	hop := &pb.TraceHop{
+		Ttl:        ttl,
+		ProbesSent: int32(len(probes)),
+	}
+
+	var totalRTT time.Duration
+	for _, probe := range probes {
+		if !probe.Success || probe.Address == nil || probe.Address.IP == "" {
+			continue
+		}
+		hop.ProbesReceived++
+		totalRTT += time.Duration(probe.RTT)
+
+		if hop.Ip != "" {
+			continue
+		}
+		if probe.TTL > 0 {
+			hop.Ttl = probe.TTL
+		}
+		hop.Ip = probe.Address.IP
+		hop.Hostname = probe.Hostname
+		if probe.Geo != nil {
+			hop.Asn = probe.Geo.ASNumber
+			hop.Geo = joinNonEmpty([]string{probe.Geo.Country, probe.Geo.Prov, probe.Geo.City}, " ")
+			hop.Owner = probe.Geo.Owner
+			if hop.Owner == "" {
+				hop.Owner = probe.Geo.ISP
+			}
+		}
+	}
+
+	if hop.ProbesReceived > 0 {
+		hop.RttMs = float64(totalRTT) / float64(hop.ProbesReceived) / float64(time.Millisecond)
+	}
+	return hop
+}
+
+// formatTraceHop renders a hop as a single text line
+func formatTraceHop(hop *pb.TraceHop) string {
+	if hop.Ip == "" {
+		return fmt.Sprintf("%-3d *", hop.Ttl)
+	}
+
+	asn := ""
+	if hop.Asn != "" {
+		asn = "AS" + hop.Asn
+	}
+	return fmt.Sprintf("%-3d %-39s %-10s %-30s %-20s %.2f ms",
+		hop.Ttl, hop.Ip, asn, hop.Geo, hop.Owner, hop.RttMs)
+}
+
+// joinNonEmpty joins the non-empty parts with sep
+func joinNonEmpty(parts []string, sep string) string {
+	kept := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, sep)
+}
+
+// isNextTraceJSON reports whether the task asks for nexttrace JSON output
+func isNextTraceJSON(params *pb.NetworkTestParams) bool {
+	return params != nil && strings.EqualFold(params.ExtraOptions[NextTraceFormatOption], "json")
+}
@@ -0,0 +1,77 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestNextTraceJSONParserParse(t *testing.T) {
+	lines := []string{
+		"NextTrace v1.3.0",
+		`{"Hops": [`,
+		`  [{"Success": true, "TTL": 1, "Hostname": "gw.local", "RTT": 1000000, "Address": {"IP": "192.0.2.1"}},`,
+		`   {"Success": true, "TTL": 1, "RTT": 3000000, "Address": {"IP": "192.0.2.1"}}],`,
+		`  [{"Success": false, "TTL": 2}],`,
+		`  [{"Success": true, "TTL": 3, "RTT": 10000000, "Address": {"IP": "203.0.113.9"},`,
+		`    "Geo": {"asnumber": "64500", "country": "JP", "prov": "", "city": "Tokyo", "owner": "", "isp": "Example ISP"}}]`,
+		`]}`,
+	}
+
+	summary, display, err := NextTraceJSONParser{}.Parse(lines)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(summary.TraceHops) != 3 || len(display) != 3 {
+		t.Fatalf("got %d hops and %d display lines, want 3", len(summary.TraceHops), len(display))
+	}
+
+	first := summary.TraceHops[0]
+	if first.Ttl != 1 || first.Ip != "192.0.2.1" || first.Hostname != "gw.local" {
+		t.Errorf("hop 1 = %v", first)
+	}
+	if first.ProbesSent != 2 || first.ProbesReceived != 2 || first.RttMs != 2 {
+		t.Errorf("hop 1 probes = %d/%d, rtt = %v; want 2/2 and 2 ms average", first.ProbesReceived, first.ProbesSent, first.RttMs)
+	}
+
+	if silent := summary.TraceHops[1]; silent.Ip != "" || silent.ProbesReceived != 0 || display[1] != "2   *" {
+		t.Errorf("hop 2 = %v, display %q; want a silent hop", silent, display[1])
+	}
+
+	third := summary.TraceHops[2]
+	if third.Asn != "64500" || third.Geo != "JP Tokyo" || third.Owner != "Example ISP" {
+		t.Errorf("hop 3 = %v", third)
+	}
+	if !strings.Contains(display[2], "AS64500") || !strings.Contains(display[2], "10.00 ms") {
+		t.Errorf("hop 3 display = %q", display[2])
+	}
+}
+
+func TestNextTraceJSONParserRejectsBadOutput(t *testing.T) {
+	for _, lines := range [][]string{
+		{"traceroute failed"},
+		{`{"Hops": [`},
+	} {
+		if _, _, err := (NextTraceJSONParser{}).Parse(lines); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", lines)
+		}
+	}
+}
+
+func TestIsNextTraceJSON(t *testing.T) {
+	tests := []struct {
+		params *pb.NetworkTestParams
+		want   bool
+	}{
+		{nil, false},
+		{&pb.NetworkTestParams{}, false},
+		{&pb.NetworkTestParams{ExtraOptions: map[string]string{"format": "JSON"}}, true},
+		{&pb.NetworkTestParams{ExtraOptions: map[string]string{"format": "text"}}, false},
+	}
+	for _, tt := range tests {
+		if got := isNextTraceJSON(tt.params); got != tt.want {
+			t.Errorf("isNextTraceJSON(%v) = %v, want %v", tt.params, got, tt.want)
+		}
+	}
+}
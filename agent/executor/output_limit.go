@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"fmt"
+	"sync"
+)
+
+// outputLimit counts the output of one command run against the configured caps
+// It is shared by the stdout and stderr readers
+type outputLimit struct {
+	maxLines int // 0 = unlimited
+	maxBytes int // 0 = unlimited
+
+	mutex    sync.Mutex
+	lines    int
+	bytes    int
+	exceeded string // Description of the cap that was exceeded ("" = within limits)
+}
+
+// newOutputLimit creates a counter for the given caps
+func newOutputLimit(maxLines, maxBytes int) *outputLimit {
+	return &outputLimit{maxLines: maxLines, maxBytes: maxBytes}
+}
+
+// add counts line and reports whether output must stop
+// notice is the truncation line to send; it is only returned the first time a cap is exceeded
+func (l *outputLimit) add(line string) (notice string, stop bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.exceeded != "" {
+		return "", true
+	}
+
+	l.lines++
+	l.bytes += len(line)
+	switch {
+	case l.maxLines > 0 && l.lines > l.maxLines:
+		l.exceeded = fmt.Sprintf("%d lines", l.maxLines)
+	case l.maxBytes > 0 && l.bytes > l.maxBytes:
+		l.exceeded = fmt.Sprintf("%d bytes", l.maxBytes)
+	default:
+		return "", false
+	}
+	return fmt.Sprintf("[output truncated: exceeded %s]", l.exceeded), true
+}
+
+// err returns the error reported for a run that exceeded a cap, or nil
+func (l *outputLimit) err() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.exceeded == "" {
+		return nil
+	}
+	return fmt.Errorf("output limit exceeded: %s", l.exceeded)
+}
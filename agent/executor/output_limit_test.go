@@ -0,0 +1,77 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestOutputLimit(t *testing.T) {
+	l := newOutputLimit(2, 0)
+	for i := 0; i < 2; i++ {
+		if notice, stop := l.add("line"); notice != "" || stop {
+			t.Fatalf("add() #%d = %q, %v, want within limits", i+1, notice, stop)
+		}
+	}
+	if notice, stop := l.add("line"); notice != "[output truncated: exceeded 2 lines]" || !stop {
+		t.Errorf("add() over the line cap = %q, %v", notice, stop)
+	}
+	// The notice is only sent once
+	if notice, stop := l.add("line"); notice != "" || !stop {
+		t.Errorf("add() after the cap = %q, %v, want no notice and stop", notice, stop)
+	}
+	if err := l.err(); err == nil || err.Error() != "output limit exceeded: 2 lines" {
+		t.Errorf("err() = %v", err)
+	}
+
+	l = newOutputLimit(0, 10)
+	if _, stop := l.add("12345"); stop {
+		t.Error("add() stopped within the byte cap")
+	}
+	if notice, stop := l.add("123456"); notice != "[output truncated: exceeded 10 bytes]" || !stop {
+		t.Errorf("add() over the byte cap = %q, %v", notice, stop)
+	}
+
+	l = newOutputLimit(0, 0)
+	for i := 0; i < 1000; i++ {
+		if _, stop := l.add("line"); stop {
+			t.Fatal("add() stopped without caps")
+		}
+	}
+	if err := l.err(); err != nil {
+		t.Errorf("err() without caps = %v", err)
+	}
+}
+
+func TestCommandExecutorStopsAtOutputLimit(t *testing.T) {
+	// yes prints forever, so only the cap ends the task
+	e := NewCustomCommandExecutor("yes", "/usr/bin/yes", nil, nil)
+	e.SetOutputLimits(5, 0)
+
+	outputChan := make(chan *pb.TaskOutput, 100)
+	err := e.Execute(context.Background(), &pb.Task{
+		TaskId: "t1",
+		Params: &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{}},
+	}, outputChan)
+	close(outputChan)
+	if err == nil || !strings.Contains(err.Error(), "output limit exceeded") {
+		t.Fatalf("Execute() error = %v, want output limit exceeded", err)
+	}
+
+	var lines int
+	var last *pb.TaskOutput
+	for output := range outputChan {
+		if output.OutputLine != "" && !strings.HasPrefix(output.OutputLine, "[output truncated") {
+			lines++
+		}
+		last = output
+	}
+	if lines > 5 {
+		t.Errorf("sent %d lines, want at most 5", lines)
+	}
+	if last.GetStatus() != pb.TaskStatus_TASK_STATUS_FAILED || last.GetErrorMessage() != "output limit exceeded: 5 lines" {
+		t.Errorf("final output = %v, want FAILED output limit exceeded", last)
+	}
+}
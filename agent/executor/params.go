@@ -0,0 +1,40 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/lureiny/lookingglass/agent/config"
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// ParamSchema converts a task's configured parameters to the schema reported to the master
+func ParamSchema(params []config.ParamConfig) []*pb.ParamSchema {
+	schema := make([]*pb.ParamSchema, 0, len(params))
+	for _, p := range params {
+		schema = append(schema, &pb.ParamSchema{
+			Name:        p.Name,
+			Type:        p.Type,
+			Required:    p.Required,
+			Min:         p.Min,
+			Max:         p.Max,
+			Description: p.Description,
+		})
+	}
+	return schema
+}
+
+// validateOptionValues rejects extra option values a command could read as an option (leading '-')
+// or that carry whitespace, since they are substituted into command arguments
+func validateOptionValues(options map[string]string) error {
+	for key, value := range options {
+		if strings.HasPrefix(strings.TrimSpace(value), "-") {
+			return fmt.Errorf("option %s rejected: it must not start with '-'", key)
+		}
+		if strings.IndexFunc(value, unicode.IsSpace) >= 0 {
+			return fmt.Errorf("option %s rejected: it must not contain whitespace", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestValidateOptionValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]string
+		wantErr bool
+	}{
+		{"plain value", map[string]string{"port": "443"}, false},
+		{"leading dash", map[string]string{"port": "-oProxyCommand=x"}, true},
+		{"leading dash after space", map[string]string{"port": " -x"}, true},
+		{"inner whitespace", map[string]string{"port": "443 -x"}, true},
+		{"tab", map[string]string{"port": "443\t"}, true},
+		{"dash inside value", map[string]string{"host": "my-host"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOptionValues(tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateOptionValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildCustomCommandArgsSubstitutesInOnePass(t *testing.T) {
+	params := &pb.NetworkTestParams{
+		Target:       "example.com",
+		ExtraOptions: map[string]string{"port": "443", "path": "{target}"},
+	}
+	got := BuildCustomCommandArgs([]string{"-zv", "{target}:{port}", "{path}"}, params)
+	want := []string{"-zv", "example.com:443", "{target}"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildCustomCommandArgs() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,39 @@
+package executor
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/lureiny/lookingglass/agent/config"
+)
+
+// I/O scheduling classes and ioprio_set "who" value, from linux/ioprio.h
+const (
+	ioprioClassBE    = 2
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+	ioprioWhoProcess = 1
+)
+
+// applyPriority sets the nice value and I/O class of a started process
+func applyPriority(pid int, priority config.PriorityConfig) error {
+	if priority.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, priority.Nice); err != nil {
+			return fmt.Errorf("failed to set nice %d: %w", priority.Nice, err)
+		}
+	}
+
+	var ioprio int
+	switch priority.IOClass {
+	case "best-effort":
+		ioprio = ioprioClassBE<<ioprioClassShift | priority.IOLevel
+	case "idle":
+		ioprio = ioprioClassIdle << ioprioClassShift
+	default:
+		return nil
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(pid), uintptr(ioprio)); errno != 0 {
+		return fmt.Errorf("failed to set io class %s: %w", priority.IOClass, errno)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package executor
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"github.com/lureiny/lookingglass/agent/config"
+)
+
+func TestApplyPriority(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	pid := cmd.Process.Pid
+
+	if err := applyPriority(pid, config.PriorityConfig{}); err != nil {
+		t.Fatalf("unchanged priority: %v", err)
+	}
+	if err := applyPriority(pid, config.PriorityConfig{Nice: 10, IOClass: "idle"}); err != nil {
+		t.Fatalf("applyPriority() error = %v", err)
+	}
+
+	// The raw getpriority syscall returns 20 - nice
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nice := 20 - prio; nice != 10 {
+		t.Errorf("nice = %d, want 10", nice)
+	}
+	ioprio, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(pid), 0)
+	if errno != 0 {
+		t.Fatal(errno)
+	}
+	if class := ioprio >> ioprioClassShift; class != ioprioClassIdle {
+		t.Errorf("io class = %d, want idle (%d)", class, ioprioClassIdle)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package executor
+
+import (
+	"fmt"
+
+	"github.com/lureiny/lookingglass/agent/config"
+)
+
+// applyPriority is only supported on Linux
+func applyPriority(pid int, priority config.PriorityConfig) error {
+	if priority.Nice != 0 || priority.IOClass != "" {
+		return fmt.Errorf("task priority is not supported on this platform")
+	}
+	return nil
+}
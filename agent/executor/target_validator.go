@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// DefaultTargetPattern accepts a hostname, an IPv4/IPv6 address (optionally with a port or in
+// brackets) or an http(s) URL; notably it rejects anything starting with '-' or containing spaces,
+// which a command could read as an option
+const DefaultTargetPattern = `^(?:https?://\S+|[A-Za-z0-9_\[:][A-Za-z0-9._:\[\]%-]*)$`
+
+// TargetValidator rejects task targets that do not match an allowlist pattern
+// A nil *TargetValidator accepts every target
+type TargetValidator struct {
+	pattern     *regexp.Regexp
+	description string // What an accepted target looks like, for the error message
+}
+
+// NewTargetValidator creates a validator for pattern; an empty pattern uses DefaultTargetPattern
+func NewTargetValidator(pattern string) (*TargetValidator, error) {
+	if pattern == "" {
+		return &TargetValidator{
+			pattern:     regexp.MustCompile(DefaultTargetPattern),
+			description: "a hostname, IP address or http(s) URL",
+		}, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target pattern: %w", err)
+	}
+	return &TargetValidator{
+		pattern:     re,
+		description: fmt.Sprintf("a value matching %s", pattern),
+	}, nil
+}
+
+// Validate returns an error if target is not allowed
+// An empty target is left to the task's requires_target check
+func (v *TargetValidator) Validate(target string) error {
+	if v == nil || target == "" {
+		return nil
+	}
+	if !v.pattern.MatchString(target) {
+		return fmt.Errorf("target %q rejected: it must be %s", target, v.description)
+	}
+	return nil
+}
+
+// TaskTargets returns every host a task may contact: the target, each entry of a comma-separated
+// target or ExtraOptions["targets"] (multi-target ping) and ExtraOptions["server"] (dig resolver)
+func TaskTargets(params *pb.NetworkTestParams) []string {
+	var targets []string
+	for _, raw := range []string{params.GetTarget(), params.GetExtraOptions()[PingTargetsOption]} {
+		for _, target := range strings.Split(raw, ",") {
+			if target = strings.TrimSpace(target); target != "" {
+				targets = append(targets, target)
+			}
+		}
+	}
+	if server := strings.TrimSpace(params.GetExtraOptions()["server"]); server != "" {
+		targets = append(targets, server)
+	}
+	return targets
+}
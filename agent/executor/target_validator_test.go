@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lureiny/lookingglass/agent/config"
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestTaskTargets(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *pb.NetworkTestParams
+		want   []string
+	}{
+		{"single", &pb.NetworkTestParams{Target: "1.1.1.1"}, []string{"1.1.1.1"}},
+		{"comma-separated", &pb.NetworkTestParams{Target: "1.1.1.1, 10.0.0.1"}, []string{"1.1.1.1", "10.0.0.1"}},
+		{"targets option", &pb.NetworkTestParams{
+			Target:       "1.1.1.1",
+			ExtraOptions: map[string]string{PingTargetsOption: "8.8.8.8,10.0.0.1"},
+		}, []string{"1.1.1.1", "8.8.8.8", "10.0.0.1"}},
+		{"dig server", &pb.NetworkTestParams{
+			Target:       "example.com",
+			ExtraOptions: map[string]string{"server": "10.0.0.53"},
+		}, []string{"example.com", "10.0.0.53"}},
+		{"no target", &pb.NetworkTestParams{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TaskTargets(tt.params); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TaskTargets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetValidator(t *testing.T) {
+	v, err := NewTargetValidator("")
+	if err != nil {
+		t.Fatalf("NewTargetValidator() error = %v", err)
+	}
+	for _, target := range []string{"example.com", "1.1.1.1", "[2001:db8::1]:443", "https://example.com/x"} {
+		if err := v.Validate(target); err != nil {
+			t.Errorf("Validate(%q) error = %v", target, err)
+		}
+	}
+	for _, target := range []string{"-oProxyCommand=x", "example.com -x"} {
+		if err := v.Validate(target); err == nil {
+			t.Errorf("Validate(%q) accepted an option-like target", target)
+		}
+	}
+}
+
+func TestCustomTargetPattern(t *testing.T) {
+	v, err := NewTargetValidator(`^[a-z]+\.example\.com$`)
+	if err != nil {
+		t.Fatalf("NewTargetValidator() error = %v", err)
+	}
+	if err := v.Validate("host.example.com"); err != nil {
+		t.Errorf("Validate(host.example.com) error = %v", err)
+	}
+	// A custom pattern replaces the default one, so targets it would accept are rejected too
+	for _, target := range []string{"1.1.1.1", "other.example.org"} {
+		if err := v.Validate(target); err == nil {
+			t.Errorf("Validate(%q) accepted a target outside the pattern", target)
+		}
+	}
+
+	if _, err := NewTargetValidator("("); err == nil {
+		t.Error("NewTargetValidator(\"(\") accepted an invalid pattern")
+	}
+}
+
+func TestCommandExecutorChecksTargetPattern(t *testing.T) {
+	cfg := &config.TaskConfig{
+		Executor:      &config.ExecutorSpec{Path: "/bin/echo"},
+		TargetPattern: `^[a-z]+\.example\.com$`,
+	}
+	exec, err := CommandExecutorFactory(cfg)
+	if err != nil {
+		t.Fatalf("CommandExecutorFactory() error = %v", err)
+	}
+
+	run := func(target string) error {
+		outputChan := make(chan *pb.TaskOutput, 100)
+		return exec.Execute(context.Background(), &pb.Task{
+			TaskId: "t1",
+			Params: &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{Target: target}},
+		}, outputChan)
+	}
+	if err := run("host.example.com"); err != nil {
+		t.Errorf("Execute(host.example.com) error = %v", err)
+	}
+	if err := run("-x"); err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("Execute(-x) error = %v, want the target rejected", err)
+	}
+
+	cfg.TargetPattern = "("
+	if _, err := CommandExecutorFactory(cfg); err == nil {
+		t.Error("CommandExecutorFactory() accepted an invalid target pattern")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/lureiny/lookingglass/agent/client"
 	"github.com/lureiny/lookingglass/agent/config"
@@ -13,11 +14,13 @@ import (
 	"github.com/lureiny/lookingglass/agent/task"
 	pb "github.com/lureiny/lookingglass/pb"
 	"github.com/lureiny/lookingglass/pkg/logger"
+	"github.com/lureiny/lookingglass/pkg/netutil"
 	"go.uber.org/zap"
 )
 
 var (
 	configPath = flag.String("config", "agent/config.yaml", "path to configuration file")
+	refreshIP  = flag.Bool("refresh-ip", false, "detect public IPs again instead of using the cached ones")
 
 	Version   = "dev"
 	BuildTime = ""
@@ -32,7 +35,7 @@ func main() {
 	flag.Parse()
 
 	// Load configuration
-	cfg, err := config.Load(*configPath)
+	cfg, err := config.LoadWithOptions(*configPath, config.LoadOptions{RefreshIP: *refreshIP})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
 		os.Exit(1)
@@ -40,15 +43,24 @@ func main() {
 
 	// Initialize unified logger
 	if err := logger.Init(logger.Config{
-		Level:   cfg.Log.Level,
-		File:    cfg.Log.File,
-		Console: cfg.Log.Console,
+		Level:    cfg.Log.Level,
+		File:     cfg.Log.File,
+		Console:  cfg.Log.Console,
+		Format:   cfg.Log.Format,
+		RingSize: cfg.Log.RingSize,
+
+		MaxSizeMB:  cfg.Log.MaxSizeMB,
+		MaxBackups: cfg.Log.MaxBackups,
+		MaxAgeDays: cfg.Log.MaxAgeDays,
 	}); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
 		os.Exit(1)
 	}
 	defer logger.Sync()
 
+	// SIGUSR1 switches to debug logging, SIGUSR2 back to the configured level
+	logger.HandleLevelSignals()
+
 	logger.Info("Starting LookingGlass Agent",
 		zap.String("id", cfg.Agent.ID),
 		zap.String("name", cfg.Agent.Name),
@@ -57,6 +69,9 @@ func main() {
 
 	// Create task manager with executor registry
 	taskManager := task.NewManager(executor.GetGlobalRegistry(), cfg.Executor.GlobalConcurrency)
+	taskManager.SetDefaultTimeout(time.Duration(cfg.Executor.DefaultTimeout) * time.Second)
+	taskManager.SetOutputLimits(cfg.Executor.MaxOutputLines, cfg.Executor.MaxOutputBytes)
+	taskManager.SetCustomTaskConcurrency(cfg.Executor.CustomTaskGlobalConcurrency)
 
 	// Collect task display info (task_name + display_name)
 	taskDisplayInfo := []*pb.TaskDisplayInfo{}
@@ -71,6 +86,7 @@ func main() {
 
 		// Determine executor type
 		var executorType string
+		custom := false
 
 		switch taskName {
 		case "ping":
@@ -79,7 +95,20 @@ func main() {
 			executorType = "mtr"
 		case "nexttrace":
 			executorType = "nexttrace"
+		case "traceroute":
+			executorType = "traceroute"
+		case "dns":
+			executorType = "dns"
+		case "fping":
+			executorType = "fping"
 		default:
+			custom = true
+			if taskCfg.Executor != nil && taskCfg.Executor.Type == config.ExecutorTypeHTTP {
+				// HTTP check task (no executable needed)
+				executorType = "http"
+				break
+			}
+
 			// Custom command task
 			if taskCfg.Executor == nil || taskCfg.Executor.Path == "" {
 				logger.Error("Custom task must specify executor path",
@@ -98,6 +127,7 @@ func main() {
 			ExecutorType: executorType,
 			Config:       taskCfg,
 			Concurrency:  taskCfg.Concurrency.Max,
+			Custom:       custom,
 		}
 
 		// Register task with task manager
@@ -126,6 +156,7 @@ func main() {
 			DisplayName:    displayName,
 			Description:    "", // Could add description to config if needed
 			RequiresTarget: requiresTarget,
+			Params:         executor.ParamSchema(taskCfg.Params),
 		})
 
 		logger.Info("Task registered",
@@ -141,8 +172,16 @@ func main() {
 
 	// Create stream-based master client
 	streamClient := client.NewStreamClient(cfg, taskManager.GetCurrentTaskCount, taskDisplayInfo, taskManager)
+	if cfg.Agent.ResourceStats.Enabled {
+		streamClient.SetStatsSource(client.NewHostStatsSource(cfg.Agent.ResourceStats.DiskPath))
+	}
 
 	// Start stream client (with automatic reconnection)
+	// Config validation already rejected malformed entries
+	targetDenylist, _ := netutil.ParseTargetDenylist(cfg.Executor.TargetDenylist)
+	streamClient.SetTargetDenylist(targetDenylist)
+	taskManager.SetTargetDenylist(targetDenylist)
+
 	if err := streamClient.Start(); err != nil {
 		logger.Fatal("Failed to start stream client", zap.Error(err))
 	}
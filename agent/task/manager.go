@@ -2,13 +2,17 @@ package task
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/lureiny/lookingglass/agent/config"
 	"github.com/lureiny/lookingglass/agent/executor"
 	pb "github.com/lureiny/lookingglass/pb"
 	"github.com/lureiny/lookingglass/pkg/logger"
+	"github.com/lureiny/lookingglass/pkg/netutil"
+	"github.com/lureiny/lookingglass/pkg/params"
 	"go.uber.org/zap"
 )
 
@@ -20,6 +24,7 @@ type TaskInfo struct {
 	ExecutorType string             // Executor type (e.g., "ping", "command")
 	Config       *config.TaskConfig // Full task configuration
 	Concurrency  int                // Max concurrent tasks
+	Custom       bool               // User-defined (not builtin); counts against the custom task limit
 }
 
 // Manager manages task lifecycle: configuration, concurrency, and execution
@@ -37,8 +42,15 @@ type Manager struct {
 
 	// Concurrency control
 	globalSemaphore chan struct{}
+	customSemaphore chan struct{} // Shared by all custom tasks (nil = only the global limit applies)
 	taskSemaphores  map[string]chan struct{}
 	semaphoreMutex  sync.RWMutex
+
+	defaultTimeout time.Duration // Applied when a task carries no timeout (0 = unbounded)
+	maxOutputLines int           // Output caps passed to executors that support them (0 = unlimited)
+	maxOutputBytes int
+
+	targetDenylist *netutil.TargetDenylist // Passed to executors that connect to targets themselves (nil = none)
 }
 
 // NewManager creates a new task manager
@@ -52,6 +64,32 @@ func NewManager(registry *executor.Registry, globalMaxConcurrent int) *Manager {
 	}
 }
 
+// SetDefaultTimeout sets the execution timeout for tasks that do not specify one
+func (m *Manager) SetDefaultTimeout(timeout time.Duration) {
+	m.defaultTimeout = timeout
+}
+
+// SetOutputLimits caps the output lines and bytes of every task (0 = unlimited)
+func (m *Manager) SetOutputLimits(maxLines, maxBytes int) {
+	m.maxOutputLines = maxLines
+	m.maxOutputBytes = maxBytes
+}
+
+// SetTargetDenylist sets the denylist executors check their own connections against (nil = none)
+func (m *Manager) SetTargetDenylist(denylist *netutil.TargetDenylist) {
+	m.targetDenylist = denylist
+}
+
+// SetCustomTaskConcurrency caps the custom (non-builtin) tasks running at once, all combined
+// A max <= 0 leaves custom tasks bounded only by the global and per-task limits
+func (m *Manager) SetCustomTaskConcurrency(max int) {
+	if max <= 0 {
+		m.customSemaphore = nil
+		return
+	}
+	m.customSemaphore = make(chan struct{}, max)
+}
+
 // RegisterTask registers a task with its configuration
 func (m *Manager) RegisterTask(info *TaskInfo) error {
 	m.mutex.Lock()
@@ -132,6 +170,21 @@ func (m *Manager) InitializeTaskSemaphores() {
 	}
 }
 
+// GetTaskConcurrency returns current/max utilization for each task with a concurrency limit
+func (m *Manager) GetTaskConcurrency() map[string]*pb.TaskConcurrency {
+	m.semaphoreMutex.RLock()
+	defer m.semaphoreMutex.RUnlock()
+
+	usage := make(map[string]*pb.TaskConcurrency, len(m.taskSemaphores))
+	for name, semaphore := range m.taskSemaphores {
+		usage[name] = &pb.TaskConcurrency{
+			Current: int32(len(semaphore)),
+			Max:     int32(cap(semaphore)),
+		}
+	}
+	return usage
+}
+
 // Execute executes a task by extracting task name from pb.Task
 func (m *Manager) Execute(ctx context.Context, pbTask *pb.Task, outputChan chan<- *pb.TaskOutput) error {
 	// Extract task name from pb.Task
@@ -146,10 +199,34 @@ func (m *Manager) Execute(ctx context.Context, pbTask *pb.Task, outputChan chan<
 		return fmt.Errorf("task not found: %s", taskName)
 	}
 
+	// The master validates too, but only the agent knows what ends up in a command line
+	if taskInfo.Config != nil {
+		if err := params.Validate(executor.ParamSchema(taskInfo.Config.Params), pbTask.GetNetworkTest()); err != nil {
+			return fmt.Errorf("invalid parameters for task %s: %w", taskName, err)
+		}
+	}
+
 	// Create executor instance dynamically using registry
 	exec, err := m.registry.Create(taskInfo.ExecutorType, taskInfo.Config)
 	if err != nil {
-		return fmt.Errorf("failed to create executor for task %s: %w", taskName, err)
+		return executor.AgentFault(fmt.Errorf("failed to create executor for task %s: %w", taskName, err))
+	}
+	if limiter, ok := exec.(executor.OutputLimiter); ok {
+		limiter.SetOutputLimits(m.maxOutputLines, m.maxOutputBytes)
+	}
+	if user, ok := exec.(executor.TargetDenylistUser); ok {
+		user.SetTargetDenylist(m.targetDenylist)
+	}
+
+	// Acquire the custom task semaphore first, so custom tasks waiting on it hold no global slot
+	// and builtin tasks keep running
+	if taskInfo.Custom && m.customSemaphore != nil {
+		select {
+		case m.customSemaphore <- struct{}{}:
+			defer func() { <-m.customSemaphore }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 
 	// Acquire global semaphore (global concurrency control)
@@ -172,10 +249,25 @@ func (m *Manager) Execute(ctx context.Context, pbTask *pb.Task, outputChan chan<
 		case <-ctx.Done():
 			return ctx.Err()
 		}
+		// Executors running several processes (multi-target ping) take a slot for each
+		if slotUser, ok := exec.(executor.TaskSlotUser); ok {
+			slotUser.SetTaskSlots(taskSemaphore)
+		}
 	}
 
-	// Create cancellable context for this task
-	taskCtx, cancel := context.WithCancel(ctx)
+	// Create cancellable context for this task, bounded by its timeout (or the agent default)
+	timeout := m.defaultTimeout
+	if pbTask.Timeout > 0 {
+		timeout = time.Duration(pbTask.Timeout) * time.Second
+	}
+	var taskCtx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		taskCtx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		taskCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
 
 	// Store cancel function for task cancellation
 	m.tasksMutex.Lock()
@@ -194,18 +286,75 @@ func (m *Manager) Execute(ctx context.Context, pbTask *pb.Task, outputChan chan<
 		zap.String("task_id", pbTask.TaskId),
 		zap.String("task_name", taskName),
 		zap.String("executor_type", taskInfo.ExecutorType),
+		zap.Duration("timeout", timeout),
 	)
 
-	return exec.Execute(taskCtx, pbTask, outputChan)
+	return executeWithTimeout(taskCtx, exec, pbTask, outputChan)
+}
+
+// executeWithTimeout runs exec, reporting an expired context as FAILED "task timed out"
+// instead of the CANCELLED status executors emit when their context ends
+func executeWithTimeout(ctx context.Context, exec executor.Executor, pbTask *pb.Task, outputChan chan<- *pb.TaskOutput) error {
+	execChan := make(chan *pb.TaskOutput, cap(outputChan))
+	timedOut := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for output := range execChan {
+			if output.Status == pb.TaskStatus_TASK_STATUS_CANCELLED && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				output.Status = pb.TaskStatus_TASK_STATUS_FAILED
+				output.ErrorMessage = "task timed out"
+				timedOut = true
+			}
+			outputChan <- output
+		}
+	}()
+
+	err := exec.Execute(ctx, pbTask, execChan)
+	close(execChan)
+	<-done
+
+	if timedOut {
+		logger.Warn("Task timed out", zap.String("task_id", pbTask.TaskId))
+		return nil // Already reported to the master
+	}
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("task timed out")
+	}
+	return err
 }
 
 // extractTaskName extracts task name from pb.Task
-// Simply returns the task_name field directly
 func (m *Manager) extractTaskName(pbTask *pb.Task) (string, error) {
-	if pbTask.TaskName == "" {
-		return "", fmt.Errorf("task_name is required but was empty")
+	return ResolveTaskName(pbTask)
+}
+
+// ResolveTaskName returns the name of the task to run
+// task_name takes precedence; without it the name is derived from the deprecated TaskType
+func ResolveTaskName(pbTask *pb.Task) (string, error) {
+	if pbTask.TaskName != "" {
+		return pbTask.TaskName, nil
 	}
-	return pbTask.TaskName, nil
+
+	// Older clients set only the deprecated TaskType
+	if pbTask.Type == pb.TaskType_TASK_TYPE_CUSTOM_COMMAND {
+		if name := pbTask.GetNetworkTest().GetCustomTaskName(); name != "" {
+			return name, nil
+		}
+	}
+	if name, ok := legacyTaskNames[pbTask.Type]; ok {
+		return name, nil
+	}
+	return "", fmt.Errorf("task_name is required but was empty")
+}
+
+// legacyTaskNames maps the deprecated TaskType enum to task names
+var legacyTaskNames = map[pb.TaskType]string{
+	pb.TaskType_TASK_TYPE_PING:       "ping",
+	pb.TaskType_TASK_TYPE_MTR:        "mtr",
+	pb.TaskType_TASK_TYPE_TRACEROUTE: "traceroute",
+	pb.TaskType_TASK_TYPE_SYSBENCH:   "sysbench",
+	pb.TaskType_TASK_TYPE_NEXTTRACE:  "nexttrace",
 }
 
 // Cancel cancels a running task by task ID
@@ -223,6 +372,17 @@ func (m *Manager) Cancel(taskID string) error {
 	return nil
 }
 
+// CancelAll cancels every running task, returning how many were cancelled
+func (m *Manager) CancelAll() int {
+	m.tasksMutex.RLock()
+	defer m.tasksMutex.RUnlock()
+
+	for _, cancel := range m.runningTasks {
+		cancel()
+	}
+	return len(m.runningTasks)
+}
+
 // GetCurrentTaskCount returns the number of currently running tasks
 func (m *Manager) GetCurrentTaskCount() int {
 	m.tasksMutex.RLock()
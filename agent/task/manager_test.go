@@ -0,0 +1,211 @@
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lureiny/lookingglass/agent/config"
+	"github.com/lureiny/lookingglass/agent/executor"
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/netutil"
+)
+
+func TestGetTaskConcurrency(t *testing.T) {
+	m := NewManager(executor.NewRegistry(), 10)
+	for _, info := range []*TaskInfo{
+		{Name: "ping", Concurrency: 3},
+		{Name: "mtr", Concurrency: 1},
+		{Name: "curl", Concurrency: 0},
+	} {
+		if err := m.RegisterTask(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.InitializeTaskSemaphores()
+	m.taskSemaphores["ping"] <- struct{}{}
+	m.taskSemaphores["ping"] <- struct{}{}
+
+	usage := m.GetTaskConcurrency()
+	if len(usage) != 2 {
+		t.Fatalf("got %d entries, want 2 (unlimited tasks are omitted): %v", len(usage), usage)
+	}
+	if got := usage["ping"]; got.Current != 2 || got.Max != 3 {
+		t.Errorf("ping = %d/%d, want 2/3", got.Current, got.Max)
+	}
+	if got := usage["mtr"]; got.Current != 0 || got.Max != 1 {
+		t.Errorf("mtr = %d/%d, want 0/1", got.Current, got.Max)
+	}
+}
+
+// blockingExecutor runs until its context ends, then reports CANCELLED like the command executors
+type blockingExecutor struct{}
+
+func (blockingExecutor) Execute(ctx context.Context, task *pb.Task, outputChan chan<- *pb.TaskOutput) error {
+	<-ctx.Done()
+	outputChan <- &pb.TaskOutput{TaskId: task.TaskId, Status: pb.TaskStatus_TASK_STATUS_CANCELLED, ErrorMessage: "Task cancelled"}
+	return ctx.Err()
+}
+
+func (blockingExecutor) Cancel(taskID string) error { return nil }
+
+func TestExecuteWithTimeout(t *testing.T) {
+	run := func(ctx context.Context) (*pb.TaskOutput, error) {
+		outputChan := make(chan *pb.TaskOutput, 1)
+		err := executeWithTimeout(ctx, blockingExecutor{}, &pb.Task{TaskId: "t1"}, outputChan)
+		return <-outputChan, err
+	}
+
+	// An expired timeout is a failure, already reported through the output
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	output, err := run(ctx)
+	if err != nil {
+		t.Errorf("timed out: err = %v, want nil", err)
+	}
+	if output.Status != pb.TaskStatus_TASK_STATUS_FAILED || output.ErrorMessage != "task timed out" {
+		t.Errorf("timed out: output = %v, want FAILED task timed out", output)
+	}
+
+	// A cancellation stays a cancellation
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	output, err = run(ctx)
+	if err != context.Canceled {
+		t.Errorf("cancelled: err = %v, want %v", err, context.Canceled)
+	}
+	if output.Status != pb.TaskStatus_TASK_STATUS_CANCELLED {
+		t.Errorf("cancelled: status = %v, want CANCELLED", output.Status)
+	}
+}
+
+func TestResolveTaskName(t *testing.T) {
+	tests := []struct {
+		name    string
+		task    *pb.Task
+		want    string
+		wantErr bool
+	}{
+		{"task name", &pb.Task{TaskName: "ping", Type: pb.TaskType_TASK_TYPE_MTR}, "ping", false},
+		{"legacy type", &pb.Task{Type: pb.TaskType_TASK_TYPE_MTR}, "mtr", false},
+		{"legacy custom", &pb.Task{
+			Type:   pb.TaskType_TASK_TYPE_CUSTOM_COMMAND,
+			Params: &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{CustomTaskName: "dig"}},
+		}, "dig", false},
+		{"legacy custom without name", &pb.Task{Type: pb.TaskType_TASK_TYPE_CUSTOM_COMMAND}, "", true},
+		{"empty", &pb.Task{}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveTaskName(tt.task)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveTaskName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveTaskName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomTaskConcurrency(t *testing.T) {
+	registry := executor.NewRegistry()
+	err := registry.Register("block", func(*config.TaskConfig) (executor.Executor, error) {
+		return blockingExecutor{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(registry, 2)
+	m.SetCustomTaskConcurrency(1)
+	for _, info := range []*TaskInfo{
+		{Name: "custom", ExecutorType: "block", Custom: true},
+		{Name: "builtin", ExecutorType: "block"},
+	} {
+		if err := m.RegisterTask(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	start := func(taskID, name string) {
+		go func() {
+			m.Execute(ctx, &pb.Task{TaskId: taskID, TaskName: name}, make(chan *pb.TaskOutput, 1))
+			done <- struct{}{}
+		}()
+	}
+	waitSlots := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for len(m.globalSemaphore) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("global slots in use = %d, want %d", len(m.globalSemaphore), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	start("c1", "custom")
+	waitSlots(1)
+
+	// A second custom task waits for the custom cap without holding a global slot
+	waitCtx, waitCancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer waitCancel()
+	if err := m.Execute(waitCtx, &pb.Task{TaskId: "c2", TaskName: "custom"}, make(chan *pb.TaskOutput, 1)); err != context.DeadlineExceeded {
+		t.Errorf("second custom task: err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Builtin tasks still get the remaining global slot
+	start("b1", "builtin")
+	waitSlots(2)
+
+	cancel()
+	<-done
+	<-done
+}
+
+// denylistExecutor records the denylist it is given, like executors that connect to targets themselves
+type denylistExecutor struct {
+	got chan *netutil.TargetDenylist
+	set *netutil.TargetDenylist
+}
+
+func (e *denylistExecutor) SetTargetDenylist(denylist *netutil.TargetDenylist) { e.set = denylist }
+
+func (e *denylistExecutor) Execute(ctx context.Context, task *pb.Task, outputChan chan<- *pb.TaskOutput) error {
+	e.got <- e.set
+	outputChan <- &pb.TaskOutput{TaskId: task.TaskId, Status: pb.TaskStatus_TASK_STATUS_COMPLETED}
+	return nil
+}
+
+func (e *denylistExecutor) Cancel(taskID string) error { return nil }
+
+func TestExecutorReceivesTargetDenylist(t *testing.T) {
+	got := make(chan *netutil.TargetDenylist, 1)
+	registry := executor.NewRegistry()
+	err := registry.Register("dial", func(*config.TaskConfig) (executor.Executor, error) {
+		return &denylistExecutor{got: got}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(registry, 4)
+	if err := m.RegisterTask(&TaskInfo{Name: "dial", ExecutorType: "dial"}); err != nil {
+		t.Fatal(err)
+	}
+	denylist, err := netutil.ParseTargetDenylist([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetTargetDenylist(denylist)
+
+	outputChan := make(chan *pb.TaskOutput, 10)
+	if err := m.Execute(context.Background(), &pb.Task{TaskId: "t1", TaskName: "dial"}, outputChan); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if set := <-got; set != denylist {
+		t.Errorf("executor denylist = %p, want %p", set, denylist)
+	}
+}
@@ -2,14 +2,18 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/websocket"
 	pb "github.com/lureiny/lookingglass/pb"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -21,27 +25,84 @@ type Client struct {
 	url    string
 	conn   *websocket.Conn
 	taskID string
+	output io.Writer // Where task output lines are written (stdout by default)
+	format string    // FormatText or FormatNDJSON
+
+	onStarted func(ack *pb.WSResponse) // Called with the master's acknowledgment, before it is written
+
+	connectRetries       int           // Extra dial attempts after the first failure
+	connectRetryInterval time.Duration // Initial delay between attempts (doubles each retry)
 }
 
+// Task output formats
+const (
+	FormatText   = "text"   // Output lines as the agent printed them
+	FormatNDJSON = "ndjson" // One JSON object per response, for jq and scripts
+)
+
+// minConnectRetryInterval and maxConnectRetryInterval bound the backoff between connection attempts
+const (
+	minConnectRetryInterval = 100 * time.Millisecond
+	maxConnectRetryInterval = 30 * time.Second
+)
+
 // NewClient creates a new WebSocket client
 func NewClient(url string) *Client {
 	return &Client{
-		url: url,
+		url:    url,
+		output: os.Stdout,
+		format: FormatText,
 	}
 }
 
+// SetFormat sets how task responses are written (FormatText or FormatNDJSON)
+func (c *Client) SetFormat(format string) {
+	c.format = format
+}
+
+// SetOutput sets where task output lines are written, e.g. stdout tee'd to a file
+func (c *Client) SetOutput(w io.Writer) {
+	c.output = w
+}
+
+// SetStartHandler sets a function called with the master's acknowledgment of a submitted task,
+// before any of the task's output is written
+func (c *Client) SetStartHandler(fn func(ack *pb.WSResponse)) {
+	c.onStarted = fn
+}
+
+// SetConnectRetry configures retries of the initial WebSocket dial with exponential backoff
+func (c *Client) SetConnectRetry(retries int, interval time.Duration) {
+	c.connectRetries = retries
+	c.connectRetryInterval = max(interval, minConnectRetryInterval)
+}
+
 // Connect establishes WebSocket connection to master
 func (c *Client) Connect() error {
 	dialer := websocket.DefaultDialer
 	dialer.HandshakeTimeout = 10 * time.Second
 
-	conn, _, err := dialer.Dial(c.url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to connect to master: %w", err)
-	}
+	interval := c.connectRetryInterval
+	for attempt := 0; ; attempt++ {
+		conn, _, err := dialer.Dial(c.url, nil)
+		if err == nil {
+			c.conn = conn
+			return nil
+		}
 
-	c.conn = conn
-	return nil
+		if attempt >= c.connectRetries {
+			return fmt.Errorf("failed to connect to master: %w", err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Connection attempt %d/%d failed: %v (retrying in %s)\n",
+			attempt+1, c.connectRetries+1, err, interval)
+		time.Sleep(interval)
+
+		interval *= 2
+		if interval > maxConnectRetryInterval {
+			interval = maxConnectRetryInterval
+		}
+	}
 }
 
 // ExecuteTask sends a task execution request and streams the output
@@ -103,7 +164,7 @@ func (c *Client) ExecuteTask(ctx context.Context, task *pb.Task) error {
 			return ctx.Err()
 
 		case <-sigChan:
-			fmt.Println("\nReceived interrupt signal, cancelling task...")
+			fmt.Fprintln(os.Stderr, "\nReceived interrupt signal, cancelling task...")
 			if err := c.cancelTask(); err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to cancel task: %v\n", err)
 			}
@@ -128,13 +189,162 @@ func (c *Client) ExecuteTask(ctx context.Context, task *pb.Task) error {
 	}
 }
 
+// ListAgents requests the agent list from master and returns it
+func (c *Client) ListAgents(ctx context.Context) ([]*pb.AgentStatusInfo, error) {
+	resp, err := c.request(ctx, &pb.WSRequest{Action: pb.WSRequest_ACTION_LIST_AGENTS}, pb.WSResponse_TYPE_AGENT_LIST)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Agents, nil
+}
+
+// AgentLogs fetches up to lines recent log lines of an agent (lines <= 0 = agent default)
+// token is the master's admin token
+func (c *Client) AgentLogs(ctx context.Context, agentID string, lines int, token string) ([]string, error) {
+	resp, err := c.request(ctx, &pb.WSRequest{
+		Action:       pb.WSRequest_ACTION_AGENT_LOGS,
+		AgentId:      agentID,
+		Lines:        int32(lines),
+		ConfirmToken: token,
+	}, pb.WSResponse_TYPE_AGENT_LOGS)
+	if err != nil {
+		return nil, err
+	}
+	return resp.LogLines, nil
+}
+
+// CancelTask asks the master to cancel a task by ID, including one submitted by another client
+// It returns the master's confirmation message
+func (c *Client) CancelTask(ctx context.Context, taskID string) (string, error) {
+	resp, err := c.request(ctx, &pb.WSRequest{
+		Action: pb.WSRequest_ACTION_CANCEL,
+		TaskId: taskID,
+	}, pb.WSResponse_TYPE_COMPLETE)
+	if err != nil {
+		return "", err
+	}
+	return resp.Message, nil
+}
+
+// request sends req and waits for the response of type want or an error
+func (c *Client) request(ctx context.Context, req *pb.WSRequest, want pb.WSResponse_Type) (*pb.WSResponse, error) {
+	if c.conn == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	if err := c.conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetReadDeadline(deadline)
+		defer c.conn.SetReadDeadline(time.Time{})
+	}
+
+	// Master may push status updates before the response arrives; skip them
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return nil, fmt.Errorf("websocket error: %w", err)
+		}
+
+		var resp pb.WSResponse
+		if err := proto.Unmarshal(data, &resp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+
+		switch resp.Type {
+		case want:
+			return &resp, nil
+		case pb.WSResponse_TYPE_ERROR:
+			return nil, fmt.Errorf("error: %s", resp.Message)
+		}
+	}
+}
+
+// ndjsonRecord is the NDJSON form of a task response
+type ndjsonRecord struct {
+	Type     string          `json:"type"`
+	TaskID   string          `json:"task_id"`
+	Status   string          `json:"status"`
+	Output   string          `json:"output,omitempty"`
+	Message  string          `json:"message,omitempty"`
+	AgentID  string          `json:"agent_id,omitempty"`
+	GroupID  string          `json:"group_id,omitempty"`
+	Sequence uint64          `json:"sequence,omitempty"`
+	Cached   bool            `json:"cached,omitempty"`
+	Summary  json.RawMessage `json:"summary,omitempty"`
+}
+
+// writeNDJSON writes resp as one JSON line
+// The status is the task's own (e.g. "cancelled"); masters that don't send it get one derived from the type
+func (c *Client) writeNDJSON(resp *pb.WSResponse) error {
+	var status string
+	switch {
+	case resp.Status != pb.TaskStatus_TASK_STATUS_UNSPECIFIED:
+		status = strings.ToLower(strings.TrimPrefix(resp.Status.String(), "TASK_STATUS_"))
+	case resp.Type == pb.WSResponse_TYPE_TASK_STARTED:
+		status = "started"
+	case resp.Type == pb.WSResponse_TYPE_OUTPUT:
+		status = "running"
+	case resp.Type == pb.WSResponse_TYPE_COMPLETE, resp.Type == pb.WSResponse_TYPE_GROUP_COMPLETE:
+		status = "completed"
+	case resp.Type == pb.WSResponse_TYPE_ERROR:
+		status = "failed"
+	}
+
+	record := ndjsonRecord{
+		Type:     strings.ToLower(strings.TrimPrefix(resp.Type.String(), "TYPE_")),
+		TaskID:   resp.TaskId,
+		Status:   status,
+		Output:   resp.Output,
+		Message:  resp.Message,
+		AgentID:  resp.AgentId,
+		GroupID:  resp.GroupId,
+		Sequence: resp.Sequence,
+		Cached:   resp.Cached,
+	}
+	if resp.Summary != nil {
+		summary, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(resp.Summary)
+		if err != nil {
+			return fmt.Errorf("failed to encode summary: %w", err)
+		}
+		record.Summary = summary
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %w", err)
+	}
+	_, err = fmt.Fprintln(c.output, string(data))
+	return err
+}
+
 // handleResponse processes a task response
 func (c *Client) handleResponse(resp *pb.WSResponse) error {
+	if resp.Type == pb.WSResponse_TYPE_TASK_STARTED && c.onStarted != nil {
+		c.onStarted(resp)
+	}
+
+	if c.format == FormatNDJSON {
+		if err := c.writeNDJSON(resp); err != nil {
+			return err
+		}
+		if resp.Type == pb.WSResponse_TYPE_ERROR {
+			return fmt.Errorf("error: %s", resp.Message)
+		}
+		return nil
+	}
+
 	switch resp.Type {
 	case pb.WSResponse_TYPE_OUTPUT:
 		// Print output line
 		if resp.Output != "" {
-			fmt.Println(resp.Output)
+			fmt.Fprintln(c.output, resp.Output)
 		}
 
 		// Print error message if any
@@ -147,7 +357,7 @@ func (c *Client) handleResponse(resp *pb.WSResponse) error {
 
 	case pb.WSResponse_TYPE_COMPLETE:
 		if resp.Message != "" {
-			fmt.Println(resp.Message)
+			fmt.Fprintln(c.output, resp.Message)
 		}
 		return nil
 
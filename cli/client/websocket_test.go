@@ -0,0 +1,252 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	pb "github.com/lureiny/lookingglass/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// freeAddr returns a local address nothing is listening on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestConnectRetriesUntilListenerUp(t *testing.T) {
+	addr := freeAddr(t)
+
+	upgrader := websocket.Upgrader{}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err == nil {
+			conn.Close()
+		}
+	})}
+	defer srv.Close()
+
+	// The first dial is refused; the listener comes up before the retry fires
+	started := make(chan error, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		ln, err := net.Listen("tcp", addr)
+		started <- err
+		if err == nil {
+			srv.Serve(ln)
+		}
+	}()
+
+	c := NewClient("ws://" + addr + "/ws/task")
+	c.SetConnectRetry(3, 200*time.Millisecond)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Close()
+
+	if err := <-started; err != nil {
+		t.Fatalf("listener: %v", err)
+	}
+}
+
+func TestConnectGivesUpAfterRetries(t *testing.T) {
+	c := NewClient("ws://" + freeAddr(t) + "/ws/task")
+	c.SetConnectRetry(1, 0)
+	if err := c.Connect(); err == nil {
+		t.Fatal("Connect() succeeded without a listener")
+	}
+}
+
+func TestSetConnectRetryEnforcesMinimumInterval(t *testing.T) {
+	c := NewClient("ws://127.0.0.1:1/ws/task")
+	c.SetConnectRetry(2, 0)
+	if c.connectRetryInterval != minConnectRetryInterval {
+		t.Errorf("interval = %s, want %s", c.connectRetryInterval, minConnectRetryInterval)
+	}
+}
+
+// startFakeMaster serves a WebSocket endpoint that answers each request with the responses
+// respond returns, and returns its ws:// URL
+func startFakeMaster(t *testing.T, respond func(req *pb.WSRequest) []*pb.WSResponse) string {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req pb.WSRequest
+			if err := proto.Unmarshal(data, &req); err != nil {
+				return
+			}
+			for _, resp := range respond(&req) {
+				out, _ := proto.Marshal(resp)
+				if err := conn.WriteMessage(websocket.BinaryMessage, out); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestExecuteTaskCallsStartHandlerBeforeOutput(t *testing.T) {
+	url := startFakeMaster(t, func(req *pb.WSRequest) []*pb.WSResponse {
+		id := req.Task.GetTaskId()
+		return []*pb.WSResponse{
+			{Type: pb.WSResponse_TYPE_TASK_STARTED, TaskId: id, AgentId: "agent-1"},
+			{Type: pb.WSResponse_TYPE_OUTPUT, TaskId: id, Output: "line 1"},
+			{Type: pb.WSResponse_TYPE_COMPLETE, TaskId: id},
+		}
+	})
+
+	c := NewClient(url)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Close()
+
+	var out strings.Builder
+	c.SetOutput(&strings.Builder{})
+	var ack *pb.WSResponse
+	c.SetStartHandler(func(resp *pb.WSResponse) {
+		ack = resp
+		out.WriteString("header\n")
+		c.SetOutput(&out)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.ExecuteTask(ctx, &pb.Task{TaskId: "t1", TaskName: "ping"}); err != nil {
+		t.Fatalf("ExecuteTask() error = %v", err)
+	}
+
+	if ack == nil || ack.TaskId != "t1" || ack.AgentId != "agent-1" {
+		t.Fatalf("start handler got %v", ack)
+	}
+	if got := out.String(); got != "header\nline 1\n" {
+		t.Errorf("output = %q, want header before the task output", got)
+	}
+}
+
+func TestWriteNDJSONRecords(t *testing.T) {
+	var out strings.Builder
+	c := NewClient("ws://unused")
+	c.SetFormat(FormatNDJSON)
+	c.SetOutput(&out)
+
+	responses := []*pb.WSResponse{
+		{Type: pb.WSResponse_TYPE_OUTPUT, TaskId: "t1", GroupId: "g1", AgentId: "a1", Output: "line",
+			Summary: &pb.TaskSummary{ResolvedIps: []string{"192.0.2.1"}}},
+		{Type: pb.WSResponse_TYPE_COMPLETE, TaskId: "t1", GroupId: "g1", Status: pb.TaskStatus_TASK_STATUS_CANCELLED},
+		{Type: pb.WSResponse_TYPE_COMPLETE, TaskId: "t2"},
+	}
+	for _, resp := range responses {
+		if err := c.writeNDJSON(resp); err != nil {
+			t.Fatalf("writeNDJSON() error = %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(responses) {
+		t.Fatalf("got %d lines, want %d: %q", len(lines), len(responses), out.String())
+	}
+	var records []map[string]any
+	for _, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+
+	if records[0]["group_id"] != "g1" || records[0]["status"] != "running" {
+		t.Errorf("output record = %v, want group_id g1 and status running", records[0])
+	}
+	summary, _ := records[0]["summary"].(map[string]any)
+	if ips, _ := summary["resolved_ips"].([]any); len(ips) != 1 || ips[0] != "192.0.2.1" {
+		t.Errorf("output record summary = %v, want resolved_ips [192.0.2.1]", records[0]["summary"])
+	}
+	if records[1]["status"] != "cancelled" {
+		t.Errorf("cancelled record status = %v, want cancelled", records[1]["status"])
+	}
+	if records[2]["status"] != "completed" {
+		t.Errorf("record without a task status = %v, want completed", records[2]["status"])
+	}
+}
+
+func TestListAgentsSkipsStatusUpdates(t *testing.T) {
+	url := startFakeMaster(t, func(req *pb.WSRequest) []*pb.WSResponse {
+		if req.Action != pb.WSRequest_ACTION_LIST_AGENTS {
+			return []*pb.WSResponse{{Type: pb.WSResponse_TYPE_ERROR, Message: "unexpected action"}}
+		}
+		return []*pb.WSResponse{
+			{Type: pb.WSResponse_TYPE_AGENT_STATUS_UPDATE},
+			{Type: pb.WSResponse_TYPE_AGENT_LIST, Agents: []*pb.AgentStatusInfo{{Id: "agent-1"}, {Id: "agent-2"}}},
+		}
+	})
+
+	c := NewClient(url)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	agents, err := c.ListAgents(ctx)
+	if err != nil {
+		t.Fatalf("ListAgents() error = %v", err)
+	}
+	if len(agents) != 2 || agents[0].Id != "agent-1" || agents[1].Id != "agent-2" {
+		t.Errorf("agents = %v", agents)
+	}
+}
+
+func TestCancelTask(t *testing.T) {
+	url := startFakeMaster(t, func(req *pb.WSRequest) []*pb.WSResponse {
+		if req.Action != pb.WSRequest_ACTION_CANCEL {
+			return []*pb.WSResponse{{Type: pb.WSResponse_TYPE_ERROR, Message: "unexpected action"}}
+		}
+		if req.TaskId != "t1" {
+			return []*pb.WSResponse{{Type: pb.WSResponse_TYPE_ERROR, TaskId: req.TaskId, Message: "task not found"}}
+		}
+		return []*pb.WSResponse{{Type: pb.WSResponse_TYPE_COMPLETE, TaskId: "t1", Message: "Task cancelled"}}
+	})
+
+	c := NewClient(url)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	message, err := c.CancelTask(ctx, "t1")
+	if err != nil || message != "Task cancelled" {
+		t.Errorf("CancelTask(t1) = %q, %v, want the confirmation", message, err)
+	}
+	if _, err := c.CancelTask(ctx, "t2"); err == nil || !strings.Contains(err.Error(), "task not found") {
+		t.Errorf("CancelTask(t2) error = %v, want task not found", err)
+	}
+}
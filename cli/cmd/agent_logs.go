@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lureiny/lookingglass/cli/client"
+	"github.com/spf13/cobra"
+)
+
+var (
+	agentLogsLines int
+	agentLogsToken string
+)
+
+var agentLogsCmd = &cobra.Command{
+	Use:   "agent-logs",
+	Short: "Show an agent's recent log lines",
+	Long: `Fetch the most recent log lines an agent keeps in memory, through the master.
+Requires the master's admin token (--token or LOOKINGGLASS_ADMIN_TOKEN).
+
+Example:
+  lookingglass-cli agent-logs --agent=agent-001 --lines=200 --token=$ADMIN_TOKEN`,
+	Run: runAgentLogs,
+}
+
+func init() {
+	rootCmd.AddCommand(agentLogsCmd)
+
+	agentLogsCmd.Flags().IntVar(&agentLogsLines, "lines", 200, "Number of log lines to fetch (0 = agent default)")
+	agentLogsCmd.Flags().StringVar(&agentLogsToken, "token", "", "Master admin token (default $LOOKINGGLASS_ADMIN_TOKEN)")
+}
+
+func runAgentLogs(cmd *cobra.Command, args []string) {
+	if agentID == "" {
+		exitWithError(fmt.Errorf("--agent flag is required"))
+	}
+	if agentLogsLines < 0 {
+		exitWithError(fmt.Errorf("--lines cannot be negative"))
+	}
+	token := agentLogsToken
+	if token == "" {
+		token = os.Getenv("LOOKINGGLASS_ADMIN_TOKEN")
+	}
+
+	wsClient := client.NewClient(masterURL)
+	wsClient.SetConnectRetry(connectRetries, connectRetryInterval)
+
+	fmt.Fprintf(os.Stderr, "Connecting to master at %s...\n", masterURL)
+	if err := wsClient.Connect(); err != nil {
+		exitWithError(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer wsClient.Close()
+
+	// The master waits up to 10s for the agent
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	lines, err := wsClient.AgentLogs(ctx, agentID, agentLogsLines, token)
+	if err != nil {
+		exitWithError(err)
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
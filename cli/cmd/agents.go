@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lureiny/lookingglass/cli/client"
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var agentsJSON bool
+
+var agentsCmd = &cobra.Command{
+	Use:   "agents",
+	Short: "List agents registered with the master",
+	Long: `List the agents registered with the master, their status, load and supported tasks.
+
+Example:
+  lookingglass-cli agents
+  lookingglass-cli agents --master=ws://master.example.com:8081/ws/task --json`,
+	Run: runAgents,
+}
+
+func init() {
+	rootCmd.AddCommand(agentsCmd)
+
+	agentsCmd.Flags().BoolVar(&agentsJSON, "json", false, "Print the agent list as JSON")
+}
+
+func runAgents(cmd *cobra.Command, args []string) {
+	wsClient := client.NewClient(masterURL)
+	wsClient.SetConnectRetry(connectRetries, connectRetryInterval)
+
+	// Progress goes to stderr so --json output stays parseable
+	fmt.Fprintf(os.Stderr, "Connecting to master at %s...\n", masterURL)
+	if err := wsClient.Connect(); err != nil {
+		exitWithError(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer wsClient.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	agents, err := wsClient.ListAgents(ctx)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	if agentsJSON {
+		if err := printAgentsJSON(agents); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+	printAgentsTable(agents)
+}
+
+// printAgentsJSON prints agents as a JSON array using the protobuf field names
+func printAgentsJSON(agents []*pb.AgentStatusInfo) error {
+	items := make([]json.RawMessage, 0, len(agents))
+	for _, ag := range agents {
+		data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(ag)
+		if err != nil {
+			return fmt.Errorf("failed to encode agent %s: %w", ag.Id, err)
+		}
+		items = append(items, data)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(items)
+}
+
+// printAgentsTable prints one row per agent
+func printAgentsTable(agents []*pb.AgentStatusInfo) {
+	if len(agents) == 0 {
+		fmt.Println("No agents registered.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tLOCATION\tSTATUS\tTASKS\tSUPPORTED")
+	for _, ag := range agents {
+		status := strings.ToLower(strings.TrimPrefix(ag.Status.String(), "AGENT_STATUS_"))
+		if ag.Degraded {
+			status += " (degraded)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d/%d\t%s\n",
+			ag.Id,
+			ag.Name,
+			ag.Location,
+			status,
+			ag.CurrentTasks,
+			ag.MaxConcurrent,
+			strings.Join(agentTaskNames(ag), ","),
+		)
+	}
+	w.Flush()
+}
+
+// agentTaskNames returns the task names an agent supports
+func agentTaskNames(ag *pb.AgentStatusInfo) []string {
+	if len(ag.TaskDisplayInfo) == 0 {
+		return ag.TaskNames
+	}
+	names := make([]string, 0, len(ag.TaskDisplayInfo))
+	for _, info := range ag.TaskDisplayInfo {
+		names = append(names, info.TaskName)
+	}
+	return names
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestAgentTaskNames(t *testing.T) {
+	legacy := &pb.AgentStatusInfo{TaskNames: []string{"ping", "mtr"}}
+	if got := agentTaskNames(legacy); !slices.Equal(got, []string{"ping", "mtr"}) {
+		t.Errorf("without display info = %v, want the task names", got)
+	}
+
+	current := &pb.AgentStatusInfo{
+		TaskNames:       []string{"ping"},
+		TaskDisplayInfo: []*pb.TaskDisplayInfo{{TaskName: "ping", DisplayName: "Ping"}, {TaskName: "curl_test"}},
+	}
+	if got := agentTaskNames(current); !slices.Equal(got, []string{"ping", "curl_test"}) {
+		t.Errorf("with display info = %v, want its task names", got)
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lureiny/lookingglass/cli/client"
+	"github.com/spf13/cobra"
+)
+
+var cancelTaskID string
+
+var cancelCmd = &cobra.Command{
+	Use:   "cancel",
+	Short: "Cancel a running or queued task",
+	Long: `Ask the master to cancel a task by ID, e.g. one submitted by a script or another client.
+Exits non-zero if the task is unknown or has already finished.
+
+Example:
+  lookingglass-cli cancel --task-id=3f1c2a9e-8b7d-4c1e-9f0a-2d6b5e4c3a21`,
+	Run: runCancel,
+}
+
+func init() {
+	rootCmd.AddCommand(cancelCmd)
+
+	cancelCmd.Flags().StringVar(&cancelTaskID, "task-id", "", "ID of the task to cancel (required)")
+}
+
+func runCancel(cmd *cobra.Command, args []string) {
+	if cancelTaskID == "" {
+		exitWithError(fmt.Errorf("--task-id flag is required"))
+	}
+
+	wsClient := client.NewClient(masterURL)
+	wsClient.SetConnectRetry(connectRetries, connectRetryInterval)
+
+	fmt.Fprintf(os.Stderr, "Connecting to master at %s...\n", masterURL)
+	if err := wsClient.Connect(); err != nil {
+		exitWithError(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer wsClient.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	message, err := wsClient.CancelTask(ctx, cancelTaskID)
+	if err != nil {
+		exitWithError(err)
+	}
+	fmt.Println(message)
+}
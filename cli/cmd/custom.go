@@ -37,6 +37,7 @@ func init() {
 	customCmd.Flags().Int32Var(&customCount, "count", 4, "Count parameter for custom command")
 	customCmd.Flags().Int32Var(&customTimeout, "timeout", 10, "Timeout in seconds for custom command")
 	customCmd.Flags().BoolVar(&customIPv6, "ipv6", false, "Use IPv6")
+	addOutputFlags(customCmd)
 
 	customCmd.MarkFlagRequired("target")
 	customCmd.MarkFlagRequired("task-name")
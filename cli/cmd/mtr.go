@@ -33,6 +33,7 @@ func init() {
 	mtrCmd.Flags().StringVar(&mtrTarget, "target", "", "Target IP address or hostname (required)")
 	mtrCmd.Flags().Int32Var(&mtrCount, "count", 10, "Number of pings to send to each hop")
 	mtrCmd.Flags().BoolVar(&mtrIPv6, "ipv6", false, "Use IPv6")
+	addOutputFlags(mtrCmd)
 
 	mtrCmd.MarkFlagRequired("target")
 }
@@ -32,6 +32,7 @@ func init() {
 	nexttraceCmd.Flags().StringVar(&nexttraceTarget, "target", "", "Target IP address or hostname (required)")
 	nexttraceCmd.Flags().BoolVar(&nexttraceIPv6, "ipv6", false, "Use IPv6")
 	nexttraceCmd.Flags().Int32Var(&nexttraceHops, "hops", 30, "Maximum number of hops")
+	addOutputFlags(nexttraceCmd)
 
 	nexttraceCmd.MarkFlagRequired("target")
 }
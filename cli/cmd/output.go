@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/lureiny/lookingglass/cli/client"
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/spf13/cobra"
+)
+
+var (
+	outputPath     string
+	outputAppend   bool
+	outputFormat   string
+	outputCompress bool
+)
+
+// addOutputFlags adds --output, --append, --format and --compress to a task command
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&outputPath, "output", "", "Also write task output to this file")
+	cmd.Flags().BoolVar(&outputAppend, "append", false, "Append to the --output file instead of truncating it")
+	cmd.Flags().StringVar(&outputFormat, "format", client.FormatText, "Output format: text or ndjson (one JSON object per response)")
+	cmd.Flags().BoolVar(&outputCompress, "compress", false, "Have the agent send the whole output compressed when the task ends (for very large results)")
+}
+
+// statusWriter returns where progress messages go: stderr when stdout carries NDJSON
+func statusWriter() io.Writer {
+	if outputFormat == client.FormatNDJSON {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// openOutputFile opens the --output file, truncating it unless --append is set
+func openOutputFile() (*os.File, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if outputAppend {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(outputPath, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %w", err)
+	}
+	return file, nil
+}
+
+// writeOutputHeader writes the header line describing a task the master has acknowledged
+// The ack names the task and, when the master picked it, the agent
+func writeOutputHeader(w io.Writer, task *pb.Task, ack *pb.WSResponse) {
+	taskID := ack.TaskId
+	if taskID == "" {
+		taskID = task.TaskId
+	}
+	agentID := ack.AgentId
+	if agentID == "" {
+		agentID = task.AgentId
+	}
+
+	if outputFormat == client.FormatNDJSON {
+		header, _ := json.Marshal(map[string]string{
+			"type":    "header",
+			"task_id": taskID,
+			"name":    task.TaskName,
+			"agent":   agentID,
+			"target":  task.GetNetworkTest().GetTarget(),
+			"time":    time.Now().Format(time.RFC3339),
+		})
+		fmt.Fprintln(w, string(header))
+		return
+	}
+	fmt.Fprintf(w, "# task=%s name=%s agent=%s target=%s time=%s\n",
+		taskID,
+		task.TaskName,
+		agentID,
+		task.GetNetworkTest().GetTarget(),
+		time.Now().Format(time.RFC3339),
+	)
+}
+
+// teeTaskOutput tees the task output of wsClient to file once the master acknowledges task,
+// starting with a header line
+func teeTaskOutput(wsClient *client.Client, task *pb.Task, file *os.File) {
+	wsClient.SetStartHandler(func(ack *pb.WSResponse) {
+		writeOutputHeader(file, task, ack)
+		wsClient.SetOutput(io.MultiWriter(os.Stdout, file))
+	})
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lureiny/lookingglass/cli/client"
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestWriteOutputHeaderUsesAck(t *testing.T) {
+	task := &pb.Task{
+		TaskId:   "local-id",
+		TaskName: "ping",
+		Params:   &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{Target: "1.1.1.1"}},
+	}
+	ack := &pb.WSResponse{Type: pb.WSResponse_TYPE_TASK_STARTED, TaskId: "task-1", AgentId: "agent-picked"}
+
+	t.Cleanup(func() { outputFormat = client.FormatText })
+
+	outputFormat = client.FormatText
+	var text strings.Builder
+	writeOutputHeader(&text, task, ack)
+	if !strings.HasPrefix(text.String(), "# task=task-1 name=ping agent=agent-picked target=1.1.1.1 ") {
+		t.Errorf("text header = %q", text.String())
+	}
+
+	outputFormat = client.FormatNDJSON
+	var ndjson strings.Builder
+	writeOutputHeader(&ndjson, task, ack)
+	var header map[string]string
+	if err := json.Unmarshal([]byte(ndjson.String()), &header); err != nil {
+		t.Fatalf("ndjson header: %v", err)
+	}
+	if header["type"] != "header" || header["task_id"] != "task-1" || header["agent"] != "agent-picked" {
+		t.Errorf("ndjson header = %v", header)
+	}
+}
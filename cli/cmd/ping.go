@@ -25,7 +25,8 @@ var pingCmd = &cobra.Command{
 
 Example:
   lookingglass-cli ping --agent=us-west-1 --target=8.8.8.8 --count=4
-  lookingglass-cli ping --agent=eu-central-1 --target=google.com --count=10 --ipv6`,
+  lookingglass-cli ping --agent=eu-central-1 --target=google.com --count=10 --ipv6
+  lookingglass-cli ping --agent=us-west-1 --target=8.8.8.8 --output=ping.log --append`,
 	Run: runPing,
 }
 
@@ -36,6 +37,7 @@ func init() {
 	pingCmd.Flags().Int32Var(&pingCount, "count", 4, "Number of ping packets to send")
 	pingCmd.Flags().Int32Var(&pingTimeout, "timeout", 5, "Timeout in seconds for each ping")
 	pingCmd.Flags().BoolVar(&pingIPv6, "ipv6", false, "Use IPv6")
+	addOutputFlags(pingCmd)
 
 	pingCmd.MarkFlagRequired("target")
 }
@@ -73,18 +75,37 @@ func runPing(cmd *cobra.Command, args []string) {
 }
 
 func executeTask(task *pb.Task) error {
+	if outputFormat != client.FormatText && outputFormat != client.FormatNDJSON {
+		return fmt.Errorf("unknown --format %q (want text or ndjson)", outputFormat)
+	}
+
+	task.CompressOutput = outputCompress
+
 	// Create WebSocket client
 	wsClient := client.NewClient(masterURL)
+	wsClient.SetConnectRetry(connectRetries, connectRetryInterval)
+	wsClient.SetFormat(outputFormat)
 
 	// Connect to master
-	fmt.Printf("Connecting to master at %s...\n", masterURL)
+	status := statusWriter()
+	fmt.Fprintf(status, "Connecting to master at %s...\n", masterURL)
 	if err := wsClient.Connect(); err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
 	defer wsClient.Close()
 
-	fmt.Printf("Connected. Submitting %s task to agent %s...\n", task.Type.String(), task.AgentId)
-	fmt.Printf("Task ID: %s\n\n", task.TaskId)
+	// Tee output to --output if given; opened only now so a failed connection leaves it untouched
+	if outputPath != "" {
+		file, err := openOutputFile()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		teeTaskOutput(wsClient, task, file)
+	}
+
+	fmt.Fprintf(status, "Connected. Submitting %s task to agent %s...\n", task.Type.String(), task.AgentId)
+	fmt.Fprintf(status, "Task ID: %s\n\n", task.TaskId)
 
 	// Execute task with context
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(task.Timeout)*time.Second)
@@ -94,6 +115,6 @@ func executeTask(task *pb.Task) error {
 		return err
 	}
 
-	fmt.Println("\nTask completed successfully.")
+	fmt.Fprintln(status, "\nTask completed successfully.")
 	return nil
 }
@@ -3,13 +3,16 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	masterURL string
-	agentID   string
+	masterURL            string
+	agentID              string
+	connectRetries       int
+	connectRetryInterval time.Duration
 )
 
 var rootCmd = &cobra.Command{
@@ -17,6 +20,20 @@ var rootCmd = &cobra.Command{
 	Short: "LookingGlass CLI - Network diagnostic tool client",
 	Long: `LookingGlass CLI is a command-line client for the LookingGlass distributed network diagnostic system.
 It allows you to execute network diagnostic commands (ping, mtr, etc.) on remote agents and view real-time results.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return validateConnectRetry()
+	},
+}
+
+// validateConnectRetry rejects retry settings that would spin on a down master
+func validateConnectRetry() error {
+	if connectRetries < 0 {
+		return fmt.Errorf("--connect-retries cannot be negative")
+	}
+	if connectRetryInterval <= 0 {
+		return fmt.Errorf("--connect-retry-interval must be positive")
+	}
+	return nil
 }
 
 func Execute() error {
@@ -26,8 +43,9 @@ func Execute() error {
 func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&masterURL, "master", "ws://localhost:8081/ws/task", "Master WebSocket URL")
-	rootCmd.PersistentFlags().StringVar(&agentID, "agent", "", "Agent ID to execute the task on (required)")
-	rootCmd.MarkPersistentFlagRequired("agent")
+	rootCmd.PersistentFlags().StringVar(&agentID, "agent", "", "Agent ID to execute the task on (required by task commands)")
+	rootCmd.PersistentFlags().IntVar(&connectRetries, "connect-retries", 0, "Number of times to retry connecting to master")
+	rootCmd.PersistentFlags().DurationVar(&connectRetryInterval, "connect-retry-interval", time.Second, "Initial delay between connection retries (doubles each retry)")
 }
 
 func exitWithError(err error) {
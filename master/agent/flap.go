@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// DefaultFlapHistorySize is the number of status transitions kept per agent
+const DefaultFlapHistorySize = 20
+
+// Transition is a recorded change of an agent's online/offline status
+type Transition struct {
+	Time   time.Time
+	Status pb.AgentStatus
+	Reason string
+}
+
+// flapHistory is a fixed-size ring buffer of an agent's recent transitions
+type flapHistory struct {
+	entries []Transition
+	next    int
+	full    bool
+}
+
+// newFlapHistory creates a ring buffer holding up to size transitions
+func newFlapHistory(size int) *flapHistory {
+	return &flapHistory{entries: make([]Transition, size)}
+}
+
+// add records a transition, overwriting the oldest entry once full
+func (h *flapHistory) add(t Transition) {
+	if len(h.entries) == 0 {
+		return
+	}
+	h.entries[h.next] = t
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list returns the recorded transitions, oldest first
+func (h *flapHistory) list() []Transition {
+	if !h.full {
+		return append([]Transition(nil), h.entries[:h.next]...)
+	}
+	out := make([]Transition, 0, len(h.entries))
+	out = append(out, h.entries[h.next:]...)
+	return append(out, h.entries[:h.next]...)
+}
+
+// SetFlapHistorySize sets how many status transitions are kept per agent
+// Applies to agents registered after the call; call before agents connect
+func (m *Manager) SetFlapHistorySize(size int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.flapHistorySize = size
+}
+
+// recordTransition appends a status transition to the agent's history
+// Caller must hold m.mutex
+func (m *Manager) recordTransition(agent *Agent, status pb.AgentStatus, reason string) {
+	if agent.history == nil {
+		agent.history = newFlapHistory(m.flapHistorySize)
+	}
+	agent.history.add(Transition{Time: time.Now(), Status: status, Reason: reason})
+}
+
+// GetTransitions returns an agent's recent status transitions, oldest first
+func (m *Manager) GetTransitions(agentID string) ([]Transition, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	agent, ok := m.agents[agentID]
+	if !ok {
+		return nil, fmt.Errorf("agent not found: %s", agentID)
+	}
+	if agent.history == nil {
+		return nil, nil
+	}
+	return agent.history.list(), nil
+}
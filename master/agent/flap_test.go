@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestFlapHistoryRing(t *testing.T) {
+	h := newFlapHistory(3)
+	if got := h.list(); len(got) != 0 {
+		t.Fatalf("empty history = %v", got)
+	}
+	for _, reason := range []string{"a", "b", "c", "d", "e"} {
+		h.add(Transition{Reason: reason})
+	}
+	got := h.list()
+	if len(got) != 3 || got[0].Reason != "c" || got[1].Reason != "d" || got[2].Reason != "e" {
+		t.Errorf("history = %v, want the last 3 oldest first", got)
+	}
+
+	// A zero size keeps nothing
+	none := newFlapHistory(0)
+	none.add(Transition{Reason: "a"})
+	if got := none.list(); len(got) != 0 {
+		t.Errorf("zero-size history = %v", got)
+	}
+}
+
+func TestGetTransitions(t *testing.T) {
+	m := NewManager(time.Minute, time.Minute)
+	t.Cleanup(m.Stop)
+	m.SetFlapHistorySize(2)
+	if err := m.RegisterAgentFromStream(&pb.AgentInfo{Id: "a", Name: "Paris"}); err != nil {
+		t.Fatal(err)
+	}
+	m.MarkAgentOffline("a")
+	if err := m.UpdateHeartbeat("a", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	transitions, err := m.GetTransitions("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 2 {
+		t.Fatalf("transitions = %v, want the last 2", transitions)
+	}
+	if transitions[0].Status != pb.AgentStatus_AGENT_STATUS_OFFLINE || transitions[0].Reason != "stream closed" {
+		t.Errorf("first = %+v, want OFFLINE (stream closed)", transitions[0])
+	}
+	if transitions[1].Status != pb.AgentStatus_AGENT_STATUS_ONLINE || transitions[1].Reason != "heartbeat resumed" {
+		t.Errorf("second = %+v, want ONLINE (heartbeat resumed)", transitions[1])
+	}
+	if _, err := m.GetTransitions("unknown"); err == nil {
+		t.Error("unknown agent: no error")
+	}
+}
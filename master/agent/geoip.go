@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// GeoInfo is the result of a GeoIP lookup
+type GeoInfo struct {
+	Location string // e.g. "Tokyo, Japan"
+	Provider string // ISP / hosting provider
+}
+
+// GeoLookup resolves an IP address to location information
+type GeoLookup func(ctx context.Context, ip string) (*GeoInfo, error)
+
+// NewHTTPGeoLookup returns a lookup querying an ip-api.com compatible JSON endpoint
+func NewHTTPGeoLookup(urlTemplate string) GeoLookup {
+	client := &http.Client{}
+	return func(ctx context.Context, ip string) (*GeoInfo, error) {
+		url := strings.ReplaceAll(urlTemplate, "{ip}", ip)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create geoip request: %w", err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("geoip request failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("geoip request failed with status %d", resp.StatusCode)
+		}
+
+		var result struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Country string `json:"country"`
+			City    string `json:"city"`
+			ISP     string `json:"isp"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode geoip response: %w", err)
+		}
+		if result.Status == "fail" {
+			return nil, fmt.Errorf("geoip lookup failed: %s", result.Message)
+		}
+
+		location := result.Country
+		if result.City != "" && result.Country != "" {
+			location = result.City + ", " + result.Country
+		}
+		return &GeoInfo{Location: location, Provider: result.ISP}, nil
+	}
+}
+
+// geoEnricher fills in missing agent location/provider from GeoIP, off the registration path
+type geoEnricher struct {
+	lookup    GeoLookup
+	timeout   time.Duration
+	semaphore chan struct{} // Bounds concurrent lookups during registration storms
+	cache     map[string]*GeoInfo
+	cacheMu   sync.Mutex
+}
+
+// SetGeoEnrichment enables async GeoIP enrichment for agents registering without a location or provider
+// Lookups run at most maxConcurrent at a time, each bounded by timeout
+func (m *Manager) SetGeoEnrichment(lookup GeoLookup, timeout time.Duration, maxConcurrent int) {
+	if lookup == nil {
+		m.geo = nil
+		return
+	}
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	m.geo = &geoEnricher{
+		lookup:    lookup,
+		timeout:   timeout,
+		semaphore: make(chan struct{}, maxConcurrent),
+		cache:     make(map[string]*GeoInfo),
+	}
+}
+
+// enrichGeoAsync starts a background lookup for an agent missing location or provider
+func (m *Manager) enrichGeoAsync(info *pb.AgentInfo) {
+	if m.geo == nil || (info.Location != "" && info.Provider != "") {
+		return
+	}
+	ip := info.Ipv4
+	if ip == "" {
+		ip = info.Ipv6
+	}
+	if ip == "" {
+		return
+	}
+	go m.enrichGeo(info.Id, ip)
+}
+
+// enrichGeo looks up an agent's address and updates its record when the lookup completes
+func (m *Manager) enrichGeo(agentID, ip string) {
+	geo, err := m.geo.resolve(ip)
+	if err != nil {
+		logger.Warn("GeoIP enrichment failed",
+			zap.String("agent_id", agentID),
+			zap.String("ip", ip),
+			zap.Error(err),
+		)
+		return
+	}
+
+	m.mutex.Lock()
+	agent, ok := m.agents[agentID]
+	if !ok {
+		m.mutex.Unlock()
+		return
+	}
+	// Replace rather than mutate Info: readers may hold the old message without the lock
+	updated := proto.Clone(agent.Info).(*pb.AgentInfo)
+	if updated.Location == "" {
+		updated.Location = geo.Location
+	}
+	if updated.Provider == "" {
+		updated.Provider = geo.Provider
+	}
+	agent.Info = updated
+	m.mutex.Unlock()
+
+	logger.Info("Agent enriched from GeoIP",
+		zap.String("agent_id", agentID),
+		zap.String("location", geo.Location),
+		zap.String("provider", geo.Provider),
+	)
+	m.notifyStatusChange()
+}
+
+// resolve returns cached geo info for ip, or performs a bounded lookup
+func (g *geoEnricher) resolve(ip string) (*GeoInfo, error) {
+	g.cacheMu.Lock()
+	cached, ok := g.cache[ip]
+	g.cacheMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	select {
+	case g.semaphore <- struct{}{}:
+		defer func() { <-g.semaphore }()
+	case <-ctx.Done():
+		return nil, fmt.Errorf("timed out waiting for a geoip lookup slot")
+	}
+
+	geo, err := g.lookup(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	g.cacheMu.Lock()
+	g.cache[ip] = geo
+	g.cacheMu.Unlock()
+	return geo, nil
+}
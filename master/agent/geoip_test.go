@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestHTTPGeoLookup(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json/203.0.113.7":
+			w.Write([]byte(`{"status":"success","country":"Japan","city":"Tokyo","isp":"Example Net"}`))
+		case "/json/198.51.100.1":
+			w.Write([]byte(`{"status":"fail","message":"private range"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	lookup := NewHTTPGeoLookup(srv.URL + "/json/{ip}")
+
+	geo, err := lookup(context.Background(), "203.0.113.7")
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if geo.Location != "Tokyo, Japan" || geo.Provider != "Example Net" {
+		t.Errorf("geo = %+v", geo)
+	}
+	if _, err := lookup(context.Background(), "198.51.100.1"); err == nil {
+		t.Error("status fail: no error")
+	}
+	if _, err := lookup(context.Background(), "192.0.2.1"); err == nil {
+		t.Error("HTTP 404: no error")
+	}
+}
+
+func TestGeoEnrichmentFillsMissingFields(t *testing.T) {
+	m := NewManager(time.Minute, time.Minute)
+	t.Cleanup(m.Stop)
+	var lookups atomic.Int32
+	m.SetGeoEnrichment(func(ctx context.Context, ip string) (*GeoInfo, error) {
+		lookups.Add(1)
+		return &GeoInfo{Location: "Tokyo, Japan", Provider: "Example Net"}, nil
+	}, time.Second, 2)
+
+	if err := m.RegisterAgentFromStream(&pb.AgentInfo{Id: "a", Name: "a", Ipv4: "203.0.113.7", Provider: "Own ISP"}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		// Info is swapped under the manager lock once the lookup completes
+		m.mutex.RLock()
+		info := m.agents["a"].Info
+		m.mutex.RUnlock()
+		if info.Location != "" {
+			if info.Location != "Tokyo, Japan" || info.Provider != "Own ISP" {
+				t.Errorf("info = %s/%s, want GeoIP location and the agent's own provider", info.Location, info.Provider)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("agent not enriched")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Complete agents are not looked up; repeated addresses come from the cache
+	if err := m.RegisterAgentFromStream(&pb.AgentInfo{Id: "b", Name: "b", Ipv4: "203.0.113.8", Location: "Paris", Provider: "P"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.geo.resolve("203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	if n := lookups.Load(); n != 1 {
+		t.Errorf("lookups = %d, want 1", n)
+	}
+}
@@ -20,9 +20,17 @@ type Agent struct {
 	Status        pb.AgentStatus
 	LastHeartbeat time.Time
 	CurrentTasks  int32
+	MemPercent    float64 // Host memory usage reported in heartbeats (0 if not reported)
+	DiskPercent   float64 // Host disk usage reported in heartbeats (0 if not reported)
+	TaskUsage     map[string]*pb.TaskConcurrency // Per-task-name utilization reported in heartbeats
 	GRPCClient    pb.AgentServiceClient // Deprecated: use stream instead
 	GRPCConn      *grpc.ClientConn      // Deprecated: use stream instead
 	UseStream     bool                   // If true, use stream communication
+	DegradedUntil time.Time              // Dispatch paused by the scheduler's circuit breaker until then; zero once a trial task succeeds
+
+	history      *flapHistory // Recent online/offline transitions, guarded by Manager.mutex
+	offlineTimer *time.Timer  // Pending offline after a stream drop, guarded by Manager.mutex
+	unhealthy    bool         // Sends to the agent timed out; heartbeats don't bring it back until it registers again, guarded by Manager.mutex
 }
 
 // AgentStatusChangeCallback is called when an agent's status changes
@@ -38,14 +46,20 @@ type Manager struct {
 	notifier              *notifier.Manager
 	eventConfig           *notifier.EventConfig
 	statusChangeCallbacks []AgentStatusChangeCallback
+	geo                   *geoEnricher                // Optional GeoIP enrichment (nil = disabled)
+	flapHistorySize       int                         // Transitions kept per agent
+	offlineGrace          time.Duration               // Wait before a dropped stream marks the agent offline (0 = immediately)
+	metadataOverrides     map[string]MetadataOverride // Agent ID -> displayed metadata set by an admin
 }
 
 // NewManager creates a new agent manager
 func NewManager(heartbeatTimeout time.Duration, offlineCheckInterval time.Duration) *Manager {
 	m := &Manager{
-		agents:           make(map[string]*Agent),
-		heartbeatTimeout: heartbeatTimeout,
-		stopChan:         make(chan struct{}),
+		agents:            make(map[string]*Agent),
+		heartbeatTimeout:  heartbeatTimeout,
+		stopChan:          make(chan struct{}),
+		flapHistorySize:   DefaultFlapHistorySize,
+		metadataOverrides: make(map[string]MetadataOverride),
 	}
 
 	// Start offline check routine
@@ -164,6 +178,18 @@ func (m *Manager) RegisterAgentFromStream(info *pb.AgentInfo) error {
 		existingAgent.Status = pb.AgentStatus_AGENT_STATUS_ONLINE
 		existingAgent.LastHeartbeat = time.Now()
 		existingAgent.UseStream = true
+		existingAgent.unhealthy = false
+		if existingAgent.offlineTimer != nil {
+			// Reconnected within the grace period: the agent never appeared offline
+			existingAgent.offlineTimer.Stop()
+			existingAgent.offlineTimer = nil
+			logger.Info("Agent reconnected within offline grace period",
+				zap.String("id", info.Id),
+			)
+		}
+		if wasOffline {
+			m.recordTransition(existingAgent, pb.AgentStatus_AGENT_STATUS_ONLINE, "reconnected")
+		}
 		// Close old gRPC connection if exists
 		if existingAgent.GRPCConn != nil {
 			existingAgent.GRPCConn.Close()
@@ -197,6 +223,7 @@ func (m *Manager) RegisterAgentFromStream(info *pb.AgentInfo) error {
 			m.notifyStatusChange()
 		}
 
+		m.enrichGeoAsync(info)
 		return nil
 	}
 
@@ -208,6 +235,7 @@ func (m *Manager) RegisterAgentFromStream(info *pb.AgentInfo) error {
 		CurrentTasks:  0,
 		UseStream:     true,
 	}
+	m.recordTransition(agent, pb.AgentStatus_AGENT_STATUS_ONLINE, "registered")
 
 	m.agents[info.Id] = agent
 
@@ -240,6 +268,7 @@ func (m *Manager) RegisterAgentFromStream(info *pb.AgentInfo) error {
 	// Notify status change callbacks
 	m.notifyStatusChange()
 
+	m.enrichGeoAsync(info)
 	return nil
 }
 
@@ -255,6 +284,16 @@ func (m *Manager) UpdateHeartbeat(agentID string, currentTasks int) error {
 
 	agent.LastHeartbeat = time.Now()
 	agent.CurrentTasks = int32(currentTasks)
+	if agent.unhealthy {
+		// Its stream is being torn down; the agent is back once it reconnects and registers
+		logger.Debug("Heartbeat from unhealthy agent",
+			zap.String("id", agentID),
+		)
+		return nil
+	}
+	if agent.Status == pb.AgentStatus_AGENT_STATUS_OFFLINE {
+		m.recordTransition(agent, pb.AgentStatus_AGENT_STATUS_ONLINE, "heartbeat resumed")
+	}
 	agent.Status = pb.AgentStatus_AGENT_STATUS_ONLINE
 
 	logger.Debug("Heartbeat updated",
@@ -265,9 +304,108 @@ func (m *Manager) UpdateHeartbeat(agentID string, currentTasks int) error {
 	return nil
 }
 
-// MarkAgentOffline marks a specific agent as offline
+// SetAgentDegraded records that the scheduler paused dispatch to an agent until the given time
+// A zero time clears it once the agent has recovered
+func (m *Manager) SetAgentDegraded(agentID string, until time.Time) {
+	m.mutex.Lock()
+	agent, ok := m.agents[agentID]
+	if ok {
+		agent.DegradedUntil = until
+	}
+	m.mutex.Unlock()
+
+	if ok {
+		m.notifyStatusChange()
+	}
+}
+
+// UpdateResourceStats records host resource usage reported by an agent
+func (m *Manager) UpdateResourceStats(agentID string, memPercent, diskPercent float64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if agent, ok := m.agents[agentID]; ok {
+		agent.MemPercent = memPercent
+		agent.DiskPercent = diskPercent
+	}
+}
+
+// UpdateTaskUsage records per-task-name concurrency utilization reported by an agent
+func (m *Manager) UpdateTaskUsage(agentID string, usage map[string]*pb.TaskConcurrency) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if agent, ok := m.agents[agentID]; ok {
+		agent.TaskUsage = usage
+	}
+}
+
+// SetOfflineGrace delays marking an agent offline after its stream closes
+// An agent that reconnects within grace never shows as offline
+func (m *Manager) SetOfflineGrace(grace time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.offlineGrace = grace
+}
+
+// MarkAgentOffline marks a specific agent as offline, after the offline grace period if set
 func (m *Manager) MarkAgentOffline(agentID string) {
 	m.mutex.Lock()
+	if m.offlineGrace <= 0 {
+		m.mutex.Unlock()
+		m.markOffline(agentID, "stream closed")
+		return
+	}
+
+	agent, ok := m.agents[agentID]
+	if !ok || agent.Status != pb.AgentStatus_AGENT_STATUS_ONLINE {
+		m.mutex.Unlock()
+		return
+	}
+	if agent.offlineTimer != nil {
+		agent.offlineTimer.Stop()
+	}
+
+	// The callback takes the lock, so it cannot observe timer before it is assigned below
+	var timer *time.Timer
+	timer = time.AfterFunc(m.offlineGrace, func() {
+		m.mutex.Lock()
+		current := agent.offlineTimer == timer
+		if current {
+			agent.offlineTimer = nil
+		}
+		m.mutex.Unlock()
+
+		if current {
+			m.markOffline(agentID, "stream closed")
+		}
+	})
+	agent.offlineTimer = timer
+	grace := m.offlineGrace
+	m.mutex.Unlock()
+
+	logger.Info("Agent stream closed, waiting for reconnect",
+		zap.String("id", agentID),
+		zap.Duration("grace", grace),
+	)
+}
+
+// MarkAgentUnhealthy marks an agent offline immediately, ignoring the offline grace period
+// Used when its stream is still open but unusable (e.g. sends to it time out)
+// The agent stays offline, heartbeats notwithstanding, until it registers again
+func (m *Manager) MarkAgentUnhealthy(agentID string, reason string) {
+	m.mutex.Lock()
+	if agent, ok := m.agents[agentID]; ok {
+		agent.unhealthy = true
+	}
+	m.mutex.Unlock()
+
+	m.markOffline(agentID, reason)
+}
+
+// markOffline marks a specific agent as offline immediately
+func (m *Manager) markOffline(agentID string, reason string) {
+	m.mutex.Lock()
 
 	agent, ok := m.agents[agentID]
 	if !ok {
@@ -277,9 +415,11 @@ func (m *Manager) MarkAgentOffline(agentID string) {
 
 	if agent.Status == pb.AgentStatus_AGENT_STATUS_ONLINE {
 		agent.Status = pb.AgentStatus_AGENT_STATUS_OFFLINE
+		m.recordTransition(agent, pb.AgentStatus_AGENT_STATUS_OFFLINE, reason)
 		logger.Warn("Agent marked as offline",
 			zap.String("id", agentID),
 			zap.String("name", agent.Info.Name),
+			zap.String("reason", reason),
 		)
 
 		// Release lock before sending notifications
@@ -343,6 +483,31 @@ func (m *Manager) GetOnlineAgents() []*Agent {
 	return agents
 }
 
+// GetAgentsByTag returns all agents carrying every one of tags (all agents if tags is empty)
+func (m *Manager) GetAgentsByTag(tags map[string]string) []*Agent {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	agents := make([]*Agent, 0)
+	for _, agent := range m.agents {
+		if agent.HasTags(tags) {
+			agents = append(agents, agent)
+		}
+	}
+
+	return agents
+}
+
+// HasTags reports whether the agent carries every one of tags with the same value
+func (a *Agent) HasTags(tags map[string]string) bool {
+	for k, v := range tags {
+		if got, ok := a.Info.GetTags()[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
 // IncrementTaskCount increments the task count for an agent
 func (m *Manager) IncrementTaskCount(agentID string) error {
 	m.mutex.Lock()
@@ -398,6 +563,7 @@ func (m *Manager) checkOfflineAgents() {
 		if agent.Status == pb.AgentStatus_AGENT_STATUS_ONLINE {
 			if now.Sub(agent.LastHeartbeat) > m.heartbeatTimeout {
 				agent.Status = pb.AgentStatus_AGENT_STATUS_OFFLINE
+				m.recordTransition(agent, pb.AgentStatus_AGENT_STATUS_OFFLINE, "heartbeat timeout")
 				logger.Warn("Agent marked as offline",
 					zap.String("id", id),
 					zap.String("name", agent.Info.Name),
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestUnhealthyAgentStaysOfflineUntilReregistered(t *testing.T) {
+	m := NewManager(time.Minute, time.Minute)
+	t.Cleanup(m.Stop)
+	info := &pb.AgentInfo{Id: "a", Name: "Paris"}
+	if err := m.RegisterAgentFromStream(info); err != nil {
+		t.Fatal(err)
+	}
+
+	m.MarkAgentUnhealthy("a", "send timed out")
+	if err := m.UpdateHeartbeat("a", 2); err != nil {
+		t.Fatal(err)
+	}
+	agent, _ := m.GetAgent("a")
+	if agent.Status != pb.AgentStatus_AGENT_STATUS_OFFLINE {
+		t.Fatalf("status after heartbeat = %v, want OFFLINE", agent.Status)
+	}
+	if agent.CurrentTasks != 2 {
+		t.Errorf("current tasks = %d, want 2", agent.CurrentTasks)
+	}
+
+	if err := m.RegisterAgentFromStream(info); err != nil {
+		t.Fatal(err)
+	}
+	m.MarkAgentOffline("a")
+	if err := m.UpdateHeartbeat("a", 0); err != nil {
+		t.Fatal(err)
+	}
+	agent, _ = m.GetAgent("a")
+	if agent.Status != pb.AgentStatus_AGENT_STATUS_ONLINE {
+		t.Errorf("status after re-registration and heartbeat = %v, want ONLINE", agent.Status)
+	}
+}
+
+func TestUpdateResourceStats(t *testing.T) {
+	m := NewManager(time.Minute, time.Minute)
+	t.Cleanup(m.Stop)
+	if err := m.RegisterAgentFromStream(&pb.AgentInfo{Id: "a", Name: "Paris"}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.UpdateResourceStats("a", 41.5, 87.25)
+	m.UpdateResourceStats("unknown", 1, 1)
+
+	agent, _ := m.GetAgent("a")
+	if agent.MemPercent != 41.5 || agent.DiskPercent != 87.25 {
+		t.Errorf("stats = %v/%v, want 41.5/87.25", agent.MemPercent, agent.DiskPercent)
+	}
+}
+
+func TestUpdateTaskUsage(t *testing.T) {
+	m := NewManager(time.Minute, time.Minute)
+	t.Cleanup(m.Stop)
+	if err := m.RegisterAgentFromStream(&pb.AgentInfo{Id: "a", Name: "Paris"}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.UpdateTaskUsage("a", map[string]*pb.TaskConcurrency{"ping": {Current: 1, Max: 4}})
+	m.UpdateTaskUsage("unknown", map[string]*pb.TaskConcurrency{"ping": {Current: 1, Max: 1}})
+
+	agent, _ := m.GetAgent("a")
+	if got := agent.TaskUsage["ping"]; got == nil || got.Current != 1 || got.Max != 4 {
+		t.Errorf("ping usage = %v, want 1/4", got)
+	}
+}
+
+func TestOfflineGrace(t *testing.T) {
+	m := NewManager(time.Minute, time.Minute)
+	t.Cleanup(m.Stop)
+	m.SetOfflineGrace(30 * time.Millisecond)
+	info := &pb.AgentInfo{Id: "a", Name: "Paris"}
+	if err := m.RegisterAgentFromStream(info); err != nil {
+		t.Fatal(err)
+	}
+	status := func() pb.AgentStatus {
+		m.mutex.RLock()
+		defer m.mutex.RUnlock()
+		return m.agents["a"].Status
+	}
+
+	// Reconnecting within the grace period keeps the agent online throughout
+	m.MarkAgentOffline("a")
+	if got := status(); got != pb.AgentStatus_AGENT_STATUS_ONLINE {
+		t.Fatalf("status during grace = %v, want ONLINE", got)
+	}
+	if err := m.RegisterAgentFromStream(info); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if got := status(); got != pb.AgentStatus_AGENT_STATUS_ONLINE {
+		t.Fatalf("status after reconnect = %v, want ONLINE", got)
+	}
+
+	m.MarkAgentOffline("a")
+	deadline := time.Now().Add(time.Second)
+	for status() != pb.AgentStatus_AGENT_STATUS_OFFLINE {
+		if time.Now().After(deadline) {
+			t.Fatal("agent not marked offline after the grace period")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestGetAgentsByTag(t *testing.T) {
+	m := NewManager(time.Minute, time.Minute)
+	t.Cleanup(m.Stop)
+	for _, info := range []*pb.AgentInfo{
+		{Id: "tokyo", Tags: map[string]string{"continent": "asia", "tier": "edge"}},
+		{Id: "singapore", Tags: map[string]string{"continent": "asia"}},
+		{Id: "paris", Tags: map[string]string{"continent": "europe", "tier": "edge"}},
+		{Id: "untagged"},
+	} {
+		if err := m.RegisterAgentFromStream(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(tags map[string]string) map[string]bool {
+		got := make(map[string]bool)
+		for _, agent := range m.GetAgentsByTag(tags) {
+			got[agent.Info.Id] = true
+		}
+		return got
+	}
+
+	if got := ids(map[string]string{"continent": "asia"}); len(got) != 2 || !got["tokyo"] || !got["singapore"] {
+		t.Errorf("continent=asia = %v, want tokyo and singapore", got)
+	}
+	// Every tag must match
+	if got := ids(map[string]string{"continent": "asia", "tier": "edge"}); len(got) != 1 || !got["tokyo"] {
+		t.Errorf("continent=asia,tier=edge = %v, want tokyo", got)
+	}
+	if got := ids(map[string]string{"continent": "africa"}); len(got) != 0 {
+		t.Errorf("continent=africa = %v, want none", got)
+	}
+	if got := ids(nil); len(got) != 4 {
+		t.Errorf("no tags = %v, want all 4 agents", got)
+	}
+}
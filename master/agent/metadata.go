@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"fmt"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// MetadataOverride replaces an agent's displayed metadata without touching its config
+// Set fields take precedence over what the agent reports; empty fields keep the reported value
+type MetadataOverride struct {
+	Name        string `json:"name,omitempty"`
+	Location    string `json:"location,omitempty"`
+	Provider    string `json:"provider,omitempty"`
+	Idc         string `json:"idc,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// IsEmpty reports whether the override sets no field
+func (o MetadataOverride) IsEmpty() bool {
+	return o == MetadataOverride{}
+}
+
+// apply overwrites the fields of info that the override sets
+func (o MetadataOverride) apply(info *pb.AgentStatusInfo) {
+	if o.Name != "" {
+		info.Name = o.Name
+	}
+	if o.Location != "" {
+		info.Location = o.Location
+	}
+	if o.Provider != "" {
+		info.Provider = o.Provider
+	}
+	if o.Idc != "" {
+		info.Idc = o.Idc
+	}
+	if o.Description != "" {
+		info.Description = o.Description
+	}
+}
+
+// SetMetadataOverride sets the displayed metadata of a registered agent; an empty override clears it
+// Connected clients receive the updated agent list
+func (m *Manager) SetMetadataOverride(agentID string, override MetadataOverride) error {
+	m.mutex.Lock()
+	if _, ok := m.agents[agentID]; !ok {
+		m.mutex.Unlock()
+		return fmt.Errorf("agent not found: %s", agentID)
+	}
+	if override.IsEmpty() {
+		delete(m.metadataOverrides, agentID)
+	} else {
+		m.metadataOverrides[agentID] = override
+	}
+	m.mutex.Unlock()
+
+	m.notifyStatusChange()
+	return nil
+}
+
+// ClearMetadataOverride restores the metadata the agent reports
+func (m *Manager) ClearMetadataOverride(agentID string) {
+	m.mutex.Lock()
+	_, ok := m.metadataOverrides[agentID]
+	delete(m.metadataOverrides, agentID)
+	m.mutex.Unlock()
+
+	if ok {
+		m.notifyStatusChange()
+	}
+}
+
+// GetMetadataOverrides returns the current overrides by agent ID
+func (m *Manager) GetMetadataOverrides() map[string]MetadataOverride {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	overrides := make(map[string]MetadataOverride, len(m.metadataOverrides))
+	for agentID, override := range m.metadataOverrides {
+		overrides[agentID] = override
+	}
+	return overrides
+}
+
+// ApplyMetadataOverride applies the override set for info's agent, if any, and returns info
+func (m *Manager) ApplyMetadataOverride(info *pb.AgentStatusInfo) *pb.AgentStatusInfo {
+	m.mutex.RLock()
+	override, ok := m.metadataOverrides[info.Id]
+	m.mutex.RUnlock()
+
+	if ok {
+		override.apply(info)
+	}
+	return info
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestMetadataOverride(t *testing.T) {
+	m := NewManager(time.Minute, time.Minute)
+	t.Cleanup(m.Stop)
+	if err := m.RegisterAgentFromStream(&pb.AgentInfo{Id: "a", Name: "agent-a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.SetMetadataOverride("missing", MetadataOverride{Name: "x"}); err == nil {
+		t.Error("override for an unknown agent accepted")
+	}
+
+	// Only the fields the override sets replace the reported ones
+	if err := m.SetMetadataOverride("a", MetadataOverride{Location: "Tokyo"}); err != nil {
+		t.Fatalf("SetMetadataOverride: %v", err)
+	}
+	info := m.ApplyMetadataOverride(&pb.AgentStatusInfo{Id: "a", Name: "agent-a", Location: "JP", Provider: "p1"})
+	if info.Name != "agent-a" || info.Location != "Tokyo" || info.Provider != "p1" {
+		t.Errorf("applied = %v, want only the location replaced", info)
+	}
+	if got := m.GetMetadataOverrides(); len(got) != 1 || got["a"].Location != "Tokyo" {
+		t.Errorf("overrides = %v", got)
+	}
+
+	// Clearing restores what the agent reports
+	m.ClearMetadataOverride("a")
+	if info := m.ApplyMetadataOverride(&pb.AgentStatusInfo{Id: "a", Location: "JP"}); info.Location != "JP" {
+		t.Errorf("location after clear = %q, want JP", info.Location)
+	}
+
+	// An empty override clears too
+	m.SetMetadataOverride("a", MetadataOverride{Name: "renamed"})
+	m.SetMetadataOverride("a", MetadataOverride{})
+	if got := m.GetMetadataOverrides(); len(got) != 0 {
+		t.Errorf("overrides after empty set = %v, want none", got)
+	}
+}
@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// RegionTag is the agent tag matched by region selection, besides the agent's location
+const RegionTag = "region"
+
+// SelectAgentForTask picks the online agent in region that supports taskName and has the most free
+// capacity (lowest CurrentTasks / MaxConcurrent); ties go to the lowest agent ID
+// An agent is in region if its "region" tag equals it or its location contains it (case-insensitive)
+func (m *Manager) SelectAgentForTask(taskName, region string) (*Agent, error) {
+	best := m.leastLoaded(taskName, func(agent *Agent) bool { return agent.inRegion(region) })
+	if best == nil {
+		return nil, fmt.Errorf("no online agent in region %q supports task %s", region, taskName)
+	}
+	return best, nil
+}
+
+// SelectAgentNear picks the online agent in agentID's region that supports taskName and has the most
+// free capacity, skipping the agents in exclude (e.g. those a retried task already failed on)
+// The region is agentID's "region" tag, or its location without one
+func (m *Manager) SelectAgentNear(taskName, agentID string, exclude []string) (*Agent, error) {
+	m.mutex.RLock()
+	origin, ok := m.agents[agentID]
+	region := ""
+	if ok {
+		region = origin.region()
+	}
+	m.mutex.RUnlock()
+	if region == "" {
+		return nil, fmt.Errorf("agent %s has no region", agentID)
+	}
+
+	best := m.leastLoaded(taskName, func(agent *Agent) bool {
+		return agent.inRegion(region) && !slices.Contains(exclude, agent.Info.Id)
+	})
+	if best == nil {
+		return nil, fmt.Errorf("no other online agent in region %q supports task %s", region, taskName)
+	}
+	return best, nil
+}
+
+// leastLoaded returns the online agent accepted by match that supports taskName and has the lowest
+// CurrentTasks / MaxConcurrent; ties go to the lowest agent ID. Returns nil if none qualifies
+// Agents at capacity are skipped, as are degraded ones; one whose cooldown has passed is only picked when no healthy agent
+// qualifies, so its recovery trial can still run
+func (m *Manager) leastLoaded(taskName string, match func(*Agent) bool) *Agent {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	now := time.Now()
+	var best, bestDegraded *Agent
+	var bestLoad, bestDegradedLoad float64
+	for _, agent := range m.agents {
+		if agent.Status != pb.AgentStatus_AGENT_STATUS_ONLINE || !agent.supportsTaskName(taskName) || !match(agent) {
+			continue
+		}
+		if now.Before(agent.DegradedUntil) {
+			continue
+		}
+		if agent.Info.MaxConcurrent > 0 && agent.CurrentTasks >= agent.Info.MaxConcurrent {
+			continue
+		}
+
+		load := float64(agent.CurrentTasks) / float64(max(agent.Info.MaxConcurrent, 1))
+		if !agent.DegradedUntil.IsZero() {
+			if bestDegraded == nil || load < bestDegradedLoad || (load == bestDegradedLoad && agent.Info.Id < bestDegraded.Info.Id) {
+				bestDegraded, bestDegradedLoad = agent, load
+			}
+			continue
+		}
+		if best == nil || load < bestLoad || (load == bestLoad && agent.Info.Id < best.Info.Id) {
+			best, bestLoad = agent, load
+		}
+	}
+	if best == nil {
+		return bestDegraded
+	}
+	return best
+}
+
+// region returns the agent's "region" tag, or its location without one
+func (a *Agent) region() string {
+	if region := strings.TrimSpace(a.Info.GetTags()[RegionTag]); region != "" {
+		return region
+	}
+	return strings.TrimSpace(a.Info.Location)
+}
+
+// inRegion reports whether the agent's region tag or location matches region
+func (a *Agent) inRegion(region string) bool {
+	region = strings.TrimSpace(region)
+	if region == "" {
+		return false
+	}
+	if strings.EqualFold(a.Info.GetTags()[RegionTag], region) {
+		return true
+	}
+	return strings.Contains(strings.ToLower(a.Info.Location), strings.ToLower(region))
+}
+
+// supportsTaskName reports whether the agent advertises taskName
+func (a *Agent) supportsTaskName(taskName string) bool {
+	for _, info := range a.Info.TaskDisplayInfo {
+		if info.TaskName == taskName {
+			return true
+		}
+	}
+	for _, name := range a.Info.TaskNames {
+		if name == taskName {
+			return true
+		}
+	}
+	return false
+}
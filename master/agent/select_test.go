@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// newSelectManager returns a manager with online agents supporting ping in Tokyo
+func newSelectManager(t *testing.T, ids ...string) *Manager {
+	t.Helper()
+	m := NewManager(time.Minute, time.Minute)
+	t.Cleanup(m.Stop)
+	for _, id := range ids {
+		err := m.RegisterAgentFromStream(&pb.AgentInfo{
+			Id:              id,
+			Name:            id,
+			Location:        "Tokyo",
+			MaxConcurrent:   4,
+			TaskDisplayInfo: []*pb.TaskDisplayInfo{{TaskName: "ping"}},
+		})
+		if err != nil {
+			t.Fatalf("register %s: %v", id, err)
+		}
+	}
+	return m
+}
+
+func TestSelectAgentSkipsDegradedAgents(t *testing.T) {
+	m := newSelectManager(t, "a", "b")
+	// a is idle but degraded; b is busier
+	m.SetAgentDegraded("a", time.Now().Add(time.Minute))
+	_ = m.IncrementTaskCount("b")
+
+	got, err := m.SelectAgentForTask("ping", "tokyo")
+	if err != nil || got.Info.Id != "b" {
+		t.Fatalf("SelectAgentForTask() = %v, %v, want b", got, err)
+	}
+	got, err = m.SelectAgentNear("ping", "a", nil)
+	if err != nil || got.Info.Id != "b" {
+		t.Fatalf("SelectAgentNear() = %v, %v, want b", got, err)
+	}
+
+	if _, err := m.SelectAgentNear("ping", "a", []string{"b"}); err == nil {
+		t.Error("SelectAgentNear() picked an agent in its cooldown")
+	}
+}
+
+func TestSelectAgentFallsBackToDegradedPastCooldown(t *testing.T) {
+	m := newSelectManager(t, "a", "b")
+	m.SetAgentDegraded("a", time.Now().Add(-time.Second))
+
+	got, err := m.SelectAgentForTask("ping", "tokyo")
+	if err != nil || got.Info.Id != "b" {
+		t.Fatalf("SelectAgentForTask() = %v, %v, want healthy b", got, err)
+	}
+	got, err = m.SelectAgentNear("ping", "b", []string{"b"})
+	if err != nil || got.Info.Id != "a" {
+		t.Fatalf("SelectAgentNear() = %v, %v, want a for its recovery trial", got, err)
+	}
+}
+
+func TestSelectAgentSkipsAgentsAtCapacity(t *testing.T) {
+	m := newSelectManager(t, "a", "b")
+	for range 4 {
+		_ = m.IncrementTaskCount("a")
+	}
+
+	got, err := m.SelectAgentNear("ping", "a", nil)
+	if err != nil || got.Info.Id != "b" {
+		t.Fatalf("SelectAgentNear() = %v, %v, want b", got, err)
+	}
+	for range 4 {
+		_ = m.IncrementTaskCount("b")
+	}
+	if got, err := m.SelectAgentForTask("ping", "tokyo"); err == nil {
+		t.Errorf("SelectAgentForTask() = %s, want an error with every agent at capacity", got.Info.Id)
+	}
+}
+
+func TestSelectAgentNearStaysInRegion(t *testing.T) {
+	m := newSelectManager(t, "a", "b")
+	err := m.RegisterAgentFromStream(&pb.AgentInfo{
+		Id:              "c",
+		Location:        "Paris",
+		MaxConcurrent:   4,
+		TaskDisplayInfo: []*pb.TaskDisplayInfo{{TaskName: "ping"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = m.IncrementTaskCount("b")
+
+	got, err := m.SelectAgentNear("ping", "a", []string{"a"})
+	if err != nil || got.Info.Id != "b" {
+		t.Fatalf("SelectAgentNear() = %v, %v, want b in Tokyo", got, err)
+	}
+	if _, err := m.SelectAgentNear("ping", "c", []string{"c"}); err == nil {
+		t.Error("SelectAgentNear() left the region of c")
+	}
+}
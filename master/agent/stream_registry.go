@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -10,26 +11,62 @@ import (
 	"go.uber.org/zap"
 )
 
+// DefaultSendTimeout bounds how long SendToAgent waits on a stream the agent is not reading
+const DefaultSendTimeout = 10 * time.Second
+
+// ErrSendTimeout is returned by SendToAgent when the agent's stream does not accept a message in time
+var ErrSendTimeout = errors.New("timed out sending to agent")
+
 // StreamRegistry manages agent streams and pending requests
 type StreamRegistry struct {
 	mu              sync.RWMutex
 	agentStreams    map[string]pb.MasterService_AgentStreamServer // agentID -> stream
-	pendingRequests map[string]chan *pb.AgentMessage               // requestID -> response channel
+	generations     map[string]uint64                              // agentID -> generation of the current stream
+	sendLocks       map[string]chan struct{}                       // agentID -> send slot of the current stream (one send at a time)
+	cancels         map[string]context.CancelFunc                  // agentID -> ends the current stream (nil if it cannot be cancelled)
+	nextGeneration  uint64
+	pendingRequests map[string]chan *pb.AgentMessage // requestID -> response channel
 	logger          *zap.Logger
+
+	sendTimeout   time.Duration
+	onSendTimeout func(agentID string) // Called (asynchronously) when a send to the agent times out
 }
 
 // NewStreamRegistry creates a new stream registry
 func NewStreamRegistry(logger *zap.Logger) *StreamRegistry {
 	return &StreamRegistry{
 		agentStreams:    make(map[string]pb.MasterService_AgentStreamServer),
+		generations:     make(map[string]uint64),
+		sendLocks:       make(map[string]chan struct{}),
+		cancels:         make(map[string]context.CancelFunc),
 		pendingRequests: make(map[string]chan *pb.AgentMessage),
 		logger:          logger,
+		sendTimeout:     DefaultSendTimeout,
 	}
 }
 
+// SetSendTimeout sets how long SendToAgent may wait for an agent's stream (<= 0 keeps the default)
+func (r *StreamRegistry) SetSendTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultSendTimeout
+	}
+	r.mu.Lock()
+	r.sendTimeout = timeout
+	r.mu.Unlock()
+}
+
+// OnSendTimeout sets a hook called when a send to an agent times out, e.g. to mark it unhealthy
+func (r *StreamRegistry) OnSendTimeout(callback func(agentID string)) {
+	r.mu.Lock()
+	r.onSendTimeout = callback
+	r.mu.Unlock()
+}
+
 // RegisterAgentStream registers a new agent stream
 // If agent is already registered, replaces the old stream (handles reconnection)
-func (r *StreamRegistry) RegisterAgentStream(agentID string, stream pb.MasterService_AgentStreamServer) error {
+// cancel ends the stream and is called when a send to it times out (nil if it cannot be cancelled)
+// Returns the generation of the registered stream, used to scope its cleanup
+func (r *StreamRegistry) RegisterAgentStream(agentID string, stream pb.MasterService_AgentStreamServer, cancel context.CancelFunc) (uint64, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -41,11 +78,17 @@ func (r *StreamRegistry) RegisterAgentStream(agentID string, stream pb.MasterSer
 		// The old stream's goroutine will detect disconnection and clean up
 	}
 
+	r.nextGeneration++
+	generation := r.nextGeneration
 	r.agentStreams[agentID] = stream
+	r.generations[agentID] = generation
+	r.sendLocks[agentID] = make(chan struct{}, 1)
+	r.cancels[agentID] = cancel
 	r.logger.Info("Agent stream registered",
 		zap.String("agent_id", agentID),
+		zap.Uint64("generation", generation),
 	)
-	return nil
+	return generation, nil
 }
 
 // UnregisterAgentStream removes an agent stream
@@ -54,11 +97,39 @@ func (r *StreamRegistry) UnregisterAgentStream(agentID string) {
 	defer r.mu.Unlock()
 
 	delete(r.agentStreams, agentID)
+	delete(r.generations, agentID)
+	delete(r.sendLocks, agentID)
+	delete(r.cancels, agentID)
 	r.logger.Info("Agent stream unregistered",
 		zap.String("agent_id", agentID),
 	)
 }
 
+// UnregisterAgentStreamIfCurrent removes an agent stream only if it is still the given generation
+// Returns false if a newer stream has replaced it (the agent reconnected), leaving that stream in place
+func (r *StreamRegistry) UnregisterAgentStreamIfCurrent(agentID string, generation uint64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current, exists := r.generations[agentID]; !exists || current != generation {
+		r.logger.Info("Skipping cleanup of replaced agent stream",
+			zap.String("agent_id", agentID),
+			zap.Uint64("generation", generation),
+		)
+		return false
+	}
+
+	delete(r.agentStreams, agentID)
+	delete(r.generations, agentID)
+	delete(r.sendLocks, agentID)
+	delete(r.cancels, agentID)
+	r.logger.Info("Agent stream unregistered",
+		zap.String("agent_id", agentID),
+		zap.Uint64("generation", generation),
+	)
+	return true
+}
+
 // GetAgentStream returns the stream for a specific agent
 func (r *StreamRegistry) GetAgentStream(agentID string) (pb.MasterService_AgentStreamServer, bool) {
 	r.mu.RLock()
@@ -69,21 +140,85 @@ func (r *StreamRegistry) GetAgentStream(agentID string) (pb.MasterService_AgentS
 }
 
 // SendToAgent sends a message to a specific agent
+// Send blocks while the agent is not reading (gRPC flow control), so it runs in its own goroutine
+// and the caller gives up after the send timeout with ErrSendTimeout. Sends to one stream are
+// serialized; a timed-out send cancels the stream, which ends the stuck send and the connection.
 func (r *StreamRegistry) SendToAgent(agentID string, msg *pb.MasterMessage) error {
-	stream, exists := r.GetAgentStream(agentID)
+	r.mu.RLock()
+	stream, exists := r.agentStreams[agentID]
+	r.mu.RUnlock()
 	if !exists {
 		return fmt.Errorf("agent %s not connected", agentID)
 	}
 
-	if err := stream.Send(msg); err != nil {
-		r.logger.Error("Failed to send message to agent",
-			zap.String("agent_id", agentID),
-			zap.Error(err),
-		)
-		return err
+	return r.SendOnStream(agentID, stream, msg)
+}
+
+// SendOnStream sends a message on a specific stream of an agent, e.g. a reply on the stream a
+// request arrived on. Sends on the agent's current stream share its send slot and timeout with
+// SendToAgent; a stream that is not registered (a failed or replaced registration) only gets the timeout.
+func (r *StreamRegistry) SendOnStream(agentID string, stream pb.MasterService_AgentStreamServer, msg *pb.MasterMessage) error {
+	r.mu.RLock()
+	current := r.agentStreams[agentID] == stream
+	sendLock := r.sendLocks[agentID]
+	cancel := r.cancels[agentID]
+	timeout := r.sendTimeout
+	r.mu.RUnlock()
+	if !current {
+		sendLock = make(chan struct{}, 1)
+		cancel = nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case sendLock <- struct{}{}:
+	case <-timer.C:
+		return r.sendTimedOut(agentID, timeout, current, cancel)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		defer func() { <-sendLock }()
+		result <- stream.Send(msg)
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			r.logger.Error("Failed to send message to agent",
+				zap.String("agent_id", agentID),
+				zap.Error(err),
+			)
+			return err
+		}
+		return nil
+	case <-timer.C:
+		return r.sendTimedOut(agentID, timeout, current, cancel)
+	}
+}
+
+// sendTimedOut reports a send that did not complete in time, cancels the stream so the stuck
+// send returns and releases the send slot, and runs the timeout hook if it is the current stream
+func (r *StreamRegistry) sendTimedOut(agentID string, timeout time.Duration, current bool, cancel context.CancelFunc) error {
+	r.logger.Error("Timed out sending message to agent",
+		zap.String("agent_id", agentID),
+		zap.Duration("timeout", timeout),
+	)
+
+	if cancel != nil {
+		cancel()
+	}
+
+	r.mu.RLock()
+	callback := r.onSendTimeout
+	r.mu.RUnlock()
+	if current && callback != nil {
+		go callback(agentID)
 	}
 
-	return nil
+	return fmt.Errorf("%w %s after %s", ErrSendTimeout, agentID, timeout)
 }
 
 // SendAndWaitForResponse sends a message and waits for response
@@ -97,11 +232,11 @@ func (r *StreamRegistry) SendAndWaitForResponse(ctx context.Context, agentID str
 	r.mu.Unlock()
 
 	// Cleanup on return
+	// The channel is left open: HandleResponse may still hold it after the request is removed
 	defer func() {
 		r.mu.Lock()
 		delete(r.pendingRequests, msg.RequestId)
 		r.mu.Unlock()
-		close(responseChan)
 	}()
 
 	// Send message
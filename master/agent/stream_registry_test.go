@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// stuckStream is an agent stream whose Send blocks until its context is cancelled,
+// like a gRPC stream the agent stopped reading
+type stuckStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *stuckStream) Send(*pb.MasterMessage) error {
+	<-s.ctx.Done()
+	return s.ctx.Err()
+}
+
+func (s *stuckStream) Recv() (*pb.AgentMessage, error) {
+	<-s.ctx.Done()
+	return nil, s.ctx.Err()
+}
+
+func (s *stuckStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestSendToAgentTimeoutCancelsStream(t *testing.T) {
+	r := NewStreamRegistry(zap.NewNop())
+	r.SetSendTimeout(20 * time.Millisecond)
+	timedOut := make(chan string, 1)
+	r.OnSendTimeout(func(agentID string) { timedOut <- agentID })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &stuckStream{ctx: ctx}
+	if _, err := r.RegisterAgentStream("a", stream, cancel); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.SendToAgent("a", &pb.MasterMessage{}); !errors.Is(err, ErrSendTimeout) {
+		t.Fatalf("SendToAgent error = %v, want ErrSendTimeout", err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("stream not cancelled after the send timed out")
+	}
+	select {
+	case id := <-timedOut:
+		if id != "a" {
+			t.Errorf("timeout hook called for %q, want a", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout hook not called")
+	}
+
+	// The cancelled send released the send slot: a reply on the same stream fails instead of queueing
+	if err := r.SendOnStream("a", stream, &pb.MasterMessage{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("SendOnStream after cancel error = %v, want context.Canceled", err)
+	}
+}
+
+// recordingStream records the messages sent on it and flags overlapping sends
+type recordingStream struct {
+	grpc.ServerStream
+	active  chan struct{}
+	sent    chan *pb.MasterMessage
+	overlap chan struct{}
+}
+
+func (s *recordingStream) Send(msg *pb.MasterMessage) error {
+	select {
+	case s.active <- struct{}{}:
+	default:
+		s.overlap <- struct{}{}
+	}
+	time.Sleep(time.Millisecond)
+	<-s.active
+	s.sent <- msg
+	return nil
+}
+
+func (s *recordingStream) Recv() (*pb.AgentMessage, error) {
+	select {}
+}
+
+func TestSendOnStreamSharesSendSlot(t *testing.T) {
+	r := NewStreamRegistry(zap.NewNop())
+	stream := &recordingStream{
+		active:  make(chan struct{}, 1),
+		sent:    make(chan *pb.MasterMessage, 20),
+		overlap: make(chan struct{}, 20),
+	}
+	if _, err := r.RegisterAgentStream("a", stream, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 20)
+	for i := 0; i < 10; i++ {
+		go func() { done <- r.SendToAgent("a", &pb.MasterMessage{}) }()
+		go func() { done <- r.SendOnStream("a", stream, &pb.MasterMessage{}) }()
+	}
+	for i := 0; i < 20; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	if len(stream.overlap) > 0 {
+		t.Errorf("%d sends overlapped on one stream", len(stream.overlap))
+	}
+	if len(stream.sent) != 20 {
+		t.Errorf("sent %d messages, want 20", len(stream.sent))
+	}
+}
+
+func TestUnregisterAgentStreamIfCurrent(t *testing.T) {
+	r := NewStreamRegistry(zap.NewNop())
+	old := &stuckStream{ctx: t.Context()}
+	replacement := &stuckStream{ctx: t.Context()}
+
+	oldGen, _ := r.RegisterAgentStream("agent-1", old, nil)
+	newGen, _ := r.RegisterAgentStream("agent-1", replacement, nil)
+	if newGen <= oldGen {
+		t.Fatalf("generations %d then %d, want increasing", oldGen, newGen)
+	}
+
+	// The replaced stream's cleanup leaves the reconnected agent alone
+	if r.UnregisterAgentStreamIfCurrent("agent-1", oldGen) {
+		t.Error("unregistered with the replaced stream's generation")
+	}
+	if current, ok := r.GetAgentStream("agent-1"); !ok || current != pb.MasterService_AgentStreamServer(replacement) {
+		t.Fatal("replacement stream no longer registered")
+	}
+
+	if !r.UnregisterAgentStreamIfCurrent("agent-1", newGen) {
+		t.Error("current generation not unregistered")
+	}
+	if _, ok := r.GetAgentStream("agent-1"); ok {
+		t.Error("stream still registered")
+	}
+	if r.UnregisterAgentStreamIfCurrent("agent-1", newGen) {
+		t.Error("unregistered an agent with no stream")
+	}
+}
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"context"
+	"crypto/subtle"
+	"crypto/x509"
 	"fmt"
 	"net"
 	"strings"
@@ -11,6 +13,7 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
@@ -33,12 +36,16 @@ type Config struct {
 	Mode        pb.AuthMode
 	APIKey      string
 	IPWhitelist []string
+	Backend     AuthBackend       // Key validation backend (nil = static APIKey)
+	AgentKeys   map[string]string // Optional agent ID -> own key, checked when x-agent-id is sent
+	CertAuth    bool              // Accept a verified client certificate naming the agent ID instead of a key
 }
 
 // authenticator implements the Authenticator interface
 type authenticator struct {
-	config *Config
-	ipNets []*net.IPNet
+	config  *Config
+	backend AuthBackend
+	ipNets  []*net.IPNet
 }
 
 // NewAuthenticator creates a new authenticator
@@ -47,13 +54,18 @@ func NewAuthenticator(config *Config) (Authenticator, error) {
 		return nil, fmt.Errorf("authentication mode must be specified")
 	}
 
-	if config.APIKey == "" {
+	backend := config.Backend
+	if backend == nil && config.APIKey != "" {
+		backend = NewStaticBackend(config.APIKey)
+	}
+	if backend == nil && !config.CertAuth {
 		return nil, fmt.Errorf("API key is required")
 	}
 
 	auth := &authenticator{
-		config: config,
-		ipNets: make([]*net.IPNet, 0),
+		config:  config,
+		backend: backend,
+		ipNets:  make([]*net.IPNet, 0),
 	}
 
 	// Parse IP whitelist if mode is IP_WHITELIST
@@ -102,15 +114,43 @@ func (a *authenticator) Authenticate(ctx context.Context) error {
 		return status.Error(codes.Unauthenticated, "missing metadata")
 	}
 
-	apiKeys := md.Get("x-api-key")
-	if len(apiKeys) == 0 {
-		return status.Error(codes.Unauthenticated, "missing API key")
+	var agentID string
+	if ids := md.Get("x-agent-id"); len(ids) > 0 {
+		agentID = ids[0]
 	}
 
-	apiKey := apiKeys[0]
-	if apiKey != a.config.APIKey {
-		logger.Warn("Invalid API key attempt")
-		return status.Error(codes.Unauthenticated, "invalid API key")
+	apiKeys := md.Get("x-api-key")
+	if a.config.CertAuth && agentID != "" && certNamesAgent(ctx, agentID) {
+		// The verified certificate is the agent's identity; no key needed
+		logger.Debug("Client certificate accepted",
+			zap.String("agent_id", agentID),
+		)
+	} else if len(apiKeys) == 0 || apiKeys[0] == "" {
+		return status.Error(codes.Unauthenticated, "missing API key")
+	} else if agentKey, ok := a.config.AgentKeys[agentID]; ok && agentKey != "" &&
+		subtle.ConstantTimeCompare([]byte(apiKeys[0]), []byte(agentKey)) == 1 {
+		// An agent with its own key may use it instead of the global key
+		logger.Debug("API key accepted",
+			zap.String("agent_id", agentID),
+			zap.String("key", "agent"),
+		)
+	} else {
+		if a.backend == nil {
+			return status.Error(codes.Unauthenticated, "invalid API key")
+		}
+		identity, err := a.backend.ValidateKey(apiKeys[0])
+		if err != nil {
+			logger.Warn("Invalid API key attempt",
+				zap.String("agent_id", agentID),
+				zap.Error(err),
+			)
+			return status.Error(codes.Unauthenticated, "invalid API key")
+		}
+		logger.Debug("API key accepted",
+			zap.String("agent_id", agentID),
+			zap.String("key", "global"),
+			zap.String("identity", identity),
+		)
 	}
 
 	// If IP whitelist mode, check client IP
@@ -150,6 +190,32 @@ func (a *authenticator) Authenticate(ctx context.Context) error {
 	return nil
 }
 
+// certNamesAgent reports whether the peer presented a verified client
+// certificate whose common name or a DNS SAN equals agentID
+func certNamesAgent(ctx context.Context, agentID string) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return false
+	}
+	return certHasName(tlsInfo.State.VerifiedChains[0][0], agentID)
+}
+
+func certHasName(cert *x509.Certificate, name string) bool {
+	if cert.Subject.CommonName == name {
+		return true
+	}
+	for _, dnsName := range cert.DNSNames {
+		if dnsName == name {
+			return true
+		}
+	}
+	return false
+}
+
 // UnaryInterceptor returns a gRPC unary interceptor for authentication
 func (a *authenticator) UnaryInterceptor() grpc.UnaryServerInterceptor {
 	return func(
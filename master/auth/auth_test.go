@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestAuthenticateAgentKeys(t *testing.T) {
+	a, err := NewAuthenticator(&Config{
+		Mode:      pb.AuthMode_AUTH_MODE_API_KEY,
+		APIKey:    "global",
+		AgentKeys: map[string]string{"agent-1": "agent-1-key"},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		agentID string
+		key     string
+		wantErr bool
+	}{
+		{"global key", "agent-2", "global", false},
+		{"global key for agent with own key", "agent-1", "global", false},
+		{"own key", "agent-1", "agent-1-key", false},
+		{"own key of another agent", "agent-2", "agent-1-key", true},
+		{"wrong key", "agent-1", "nope", true},
+		{"missing key", "agent-1", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := metadata.Pairs("x-agent-id", tt.agentID)
+			if tt.key != "" {
+				md.Set("x-api-key", tt.key)
+			}
+			err := a.Authenticate(metadata.NewIncomingContext(context.Background(), md))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthenticateClientCert(t *testing.T) {
+	certAuth, err := NewAuthenticator(&Config{Mode: pb.AuthMode_AUTH_MODE_API_KEY, APIKey: "global", CertAuth: true})
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+	keyOnly, err := NewAuthenticator(&Config{Mode: pb.AuthMode_AUTH_MODE_API_KEY, APIKey: "global"})
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+
+	// verified returns TLS state as the handshake leaves it for a client certificate that passed verification
+	verified := func(cert *x509.Certificate) credentials.TLSInfo {
+		return credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}}
+	}
+	byCN := &x509.Certificate{Subject: pkix.Name{CommonName: "agent-1"}}
+	bySAN := &x509.Certificate{Subject: pkix.Name{CommonName: "lookingglass agent"}, DNSNames: []string{"agent-0", "agent-1"}}
+	other := &x509.Certificate{Subject: pkix.Name{CommonName: "agent-2"}, DNSNames: []string{"agent-2.example.com"}}
+
+	tests := []struct {
+		name     string
+		auth     Authenticator
+		authInfo credentials.AuthInfo // nil = no peer
+		key      string
+		wantErr  bool
+	}{
+		{"common name", certAuth, verified(byCN), "", false},
+		{"DNS SAN", certAuth, verified(bySAN), "", false},
+		{"cert naming another agent", certAuth, verified(other), "", true},
+		{"cert naming another agent with key", certAuth, verified(other), "global", false},
+		{"unverified cert", certAuth, credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{byCN}}}, "", true},
+		{"no TLS", certAuth, nil, "", true},
+		{"cert auth disabled", keyOnly, verified(byCN), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := metadata.Pairs("x-agent-id", "agent-1")
+			if tt.key != "" {
+				md.Set("x-api-key", tt.key)
+			}
+			ctx := metadata.NewIncomingContext(context.Background(), md)
+			if tt.authInfo != nil {
+				ctx = peer.NewContext(ctx, &peer.Peer{
+					Addr:     &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 443},
+					AuthInfo: tt.authInfo,
+				})
+			}
+
+			err := tt.auth.Authenticate(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
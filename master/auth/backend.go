@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// errInvalidKey is returned by backends when a key is not accepted
+var errInvalidKey = errors.New("invalid API key")
+
+// AuthBackend validates agent API keys
+type AuthBackend interface {
+	// ValidateKey returns the identity the key belongs to, or an error if it is not accepted
+	ValidateKey(key string) (string, error)
+}
+
+// StaticBackend accepts a single shared API key (the api_key config option)
+type StaticBackend struct {
+	apiKey string
+}
+
+// NewStaticBackend creates a backend accepting only apiKey
+func NewStaticBackend(apiKey string) *StaticBackend {
+	return &StaticBackend{apiKey: apiKey}
+}
+
+// ValidateKey compares key against the configured API key
+func (b *StaticBackend) ValidateKey(key string) (string, error) {
+	if subtle.ConstantTimeCompare([]byte(key), []byte(b.apiKey)) != 1 {
+		return "", errInvalidKey
+	}
+	return "static", nil
+}
+
+// HTTPBackendConfig configures an HTTP key validation callout
+type HTTPBackendConfig struct {
+	URL      string        // Endpoint receiving {"key": "..."} as a JSON POST
+	Timeout  time.Duration // Per-request timeout
+	CacheTTL time.Duration // How long an accepted key is remembered (0 = no caching)
+}
+
+// HTTPBackend validates keys by POSTing them to an external service
+// A 200 response accepts the key; the body may carry {"identity": "..."}
+type HTTPBackend struct {
+	config HTTPBackendConfig
+	client *http.Client
+
+	cache   map[string]cachedIdentity // sha256(key) -> identity
+	cacheMu sync.Mutex
+}
+
+// cachedIdentity is an accepted key's identity and when it must be revalidated
+type cachedIdentity struct {
+	identity string
+	expires  time.Time
+}
+
+// NewHTTPBackend creates an HTTP callout backend
+func NewHTTPBackend(config HTTPBackendConfig) *HTTPBackend {
+	return &HTTPBackend{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		cache:  make(map[string]cachedIdentity),
+	}
+}
+
+// ValidateKey returns a cached identity or asks the external service
+// Rejections and service errors are not cached, so a newly issued key works immediately
+func (b *HTTPBackend) ValidateKey(key string) (string, error) {
+	sum := sha256.Sum256([]byte(key))
+	cacheKey := hex.EncodeToString(sum[:])
+	now := time.Now()
+
+	b.cacheMu.Lock()
+	cached, ok := b.cache[cacheKey]
+	if ok && now.After(cached.expires) {
+		delete(b.cache, cacheKey)
+		ok = false
+	}
+	b.cacheMu.Unlock()
+	if ok {
+		return cached.identity, nil
+	}
+
+	identity, err := b.callout(key)
+	if err != nil {
+		return "", err
+	}
+
+	if b.config.CacheTTL > 0 {
+		b.cacheMu.Lock()
+		b.cache[cacheKey] = cachedIdentity{identity: identity, expires: now.Add(b.config.CacheTTL)}
+		b.cacheMu.Unlock()
+	}
+	return identity, nil
+}
+
+// callout asks the external service whether key is valid
+func (b *HTTPBackend) callout(key string) (string, error) {
+	body, err := json.Marshal(map[string]string{"key": key})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode auth request: %w", err)
+	}
+
+	resp, err := b.client.Post(b.config.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("auth backend request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", errInvalidKey
+	default:
+		return "", fmt.Errorf("auth backend returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Identity string `json:"identity"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.Identity == "" {
+		return "http", nil // Identity is optional
+	}
+	return result.Identity, nil
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStaticBackend(t *testing.T) {
+	b := NewStaticBackend("secret")
+	if identity, err := b.ValidateKey("secret"); err != nil || identity != "static" {
+		t.Errorf("ValidateKey(valid) = %q, %v", identity, err)
+	}
+	if _, err := b.ValidateKey("wrong"); !errors.Is(err, errInvalidKey) {
+		t.Errorf("ValidateKey(wrong) error = %v, want %v", err, errInvalidKey)
+	}
+}
+
+func TestHTTPBackend(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req struct {
+			Key string `json:"key"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch req.Key {
+		case "named":
+			w.Write([]byte(`{"identity":"agent-1"}`))
+		case "anonymous":
+			w.WriteHeader(http.StatusOK)
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+	b := NewHTTPBackend(HTTPBackendConfig{URL: srv.URL, Timeout: time.Second, CacheTTL: time.Minute})
+
+	if identity, err := b.ValidateKey("named"); err != nil || identity != "agent-1" {
+		t.Errorf("ValidateKey(named) = %q, %v", identity, err)
+	}
+	if identity, err := b.ValidateKey("anonymous"); err != nil || identity != "http" {
+		t.Errorf("ValidateKey(anonymous) = %q, %v, want the default identity", identity, err)
+	}
+	if _, err := b.ValidateKey("wrong"); !errors.Is(err, errInvalidKey) {
+		t.Errorf("ValidateKey(wrong) error = %v, want %v", err, errInvalidKey)
+	}
+	if _, err := b.ValidateKey("broken"); err == nil || errors.Is(err, errInvalidKey) {
+		t.Errorf("ValidateKey(broken) error = %v, want a service error", err)
+	}
+
+	// Accepted keys are cached, rejections are not
+	before := calls.Load()
+	if _, err := b.ValidateKey("named"); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = b.ValidateKey("wrong")
+	if n := calls.Load() - before; n != 1 {
+		t.Errorf("callouts = %d, want 1 (only the uncached rejection)", n)
+	}
+}
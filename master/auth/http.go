@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"github.com/lureiny/lookingglass/pkg/netutil"
+	"go.uber.org/zap"
+)
+
+// DefaultPublicPaths are reachable without credentials so health checks and scrapers keep working
+// Shared results are public too: the token in the path is the credential
+var DefaultPublicPaths = []string{"/healthz", "/readyz", "/metrics", "/api/public/status", "/share/"}
+
+// WSProtocol is the WebSocket subprotocol the master accepts; browsers cannot set headers on
+// upgrades, so they offer it alongside WSTokenProtocolPrefix+token to authenticate
+const (
+	WSProtocol            = "lookingglass"
+	WSTokenProtocolPrefix = "lookingglass.token."
+)
+
+// HTTPMiddleware protects the HTTP/WebSocket surface with a shared token
+// Requests to public paths bypass authentication
+type HTTPMiddleware struct {
+	token        string
+	publicPaths  map[string]bool // exact matches
+	publicPrefix []string        // entries ending with "/" match as prefixes
+
+	trustedProxies *netutil.TrustedProxies // Used to log the real client IP behind a proxy
+}
+
+// NewHTTPMiddleware creates an HTTP auth middleware
+// An empty token disables authentication entirely
+func NewHTTPMiddleware(token string, publicPaths []string) *HTTPMiddleware {
+	m := &HTTPMiddleware{
+		token:       token,
+		publicPaths: make(map[string]bool),
+	}
+	for _, path := range publicPaths {
+		if strings.HasSuffix(path, "/") {
+			m.publicPrefix = append(m.publicPrefix, path)
+		} else {
+			m.publicPaths[path] = true
+		}
+	}
+	return m
+}
+
+// SetTrustedProxies sets the proxies allowed to report the client IP via forwarding headers
+func (m *HTTPMiddleware) SetTrustedProxies(proxies *netutil.TrustedProxies) {
+	m.trustedProxies = proxies
+}
+
+// Wrap returns a handler that enforces authentication before calling next
+func (m *HTTPMiddleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.token == "" || m.isPublic(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(requestToken(r)), []byte(m.token)) != 1 {
+			logger.Warn("Unauthorized HTTP request",
+				zap.String("path", r.URL.Path),
+				zap.String("remote_addr", r.RemoteAddr),
+				zap.String("client_ip", m.trustedProxies.ClientIP(r)),
+			)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WrapMux protects the routes registered on mux; requests no route matches are web UI
+// assets and go to static without credentials, so the frontend loads before it has a token
+func (m *HTTPMiddleware) WrapMux(mux *http.ServeMux, static http.Handler) http.Handler {
+	protected := m.Wrap(mux)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern == "" {
+			static.ServeHTTP(w, r)
+			return
+		}
+		protected.ServeHTTP(w, r)
+	})
+}
+
+// isPublic reports whether a path is allowed without credentials
+func (m *HTTPMiddleware) isPublic(path string) bool {
+	if m.publicPaths[path] {
+		return true
+	}
+	for _, prefix := range m.publicPrefix {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestToken extracts the token from X-API-Key, a Bearer Authorization header,
+// or a WebSocket subprotocol entry prefixed with WSTokenProtocolPrefix
+// Query parameters are never read: URLs end up in access logs and browser history
+func requestToken(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if authz := r.Header.Get("Authorization"); strings.HasPrefix(authz, "Bearer ") {
+		return strings.TrimPrefix(authz, "Bearer ")
+	}
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, proto := range strings.Split(header, ",") {
+			if token, ok := strings.CutPrefix(strings.TrimSpace(proto), WSTokenProtocolPrefix); ok {
+				return token
+			}
+		}
+	}
+	return ""
+}
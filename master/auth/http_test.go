@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	mux.HandleFunc("/api/agents", ok)
+	mux.HandleFunc("/ws", ok)
+	mux.HandleFunc("/healthz", ok)
+	mux.HandleFunc("/share/", ok)
+	return mux
+}
+
+func TestHTTPMiddlewareTokenSources(t *testing.T) {
+	m := NewHTTPMiddleware("secret", DefaultPublicPaths)
+	handler := m.WrapMux(newTestMux(), http.NotFoundHandler())
+
+	tests := []struct {
+		name   string
+		url    string
+		header map[string]string
+		want   int
+	}{
+		{"no token", "/api/agents", nil, http.StatusUnauthorized},
+		{"x-api-key", "/api/agents", map[string]string{"X-API-Key": "secret"}, http.StatusOK},
+		{"bearer", "/api/agents", map[string]string{"Authorization": "Bearer secret"}, http.StatusOK},
+		{"wrong token", "/api/agents", map[string]string{"X-API-Key": "nope"}, http.StatusUnauthorized},
+		{"query param rejected", "/api/agents?api_key=secret", nil, http.StatusUnauthorized},
+		{"ws subprotocol", "/ws", map[string]string{"Sec-WebSocket-Protocol": "lookingglass, lookingglass.token.secret"}, http.StatusOK},
+		{"ws subprotocol without token", "/ws", map[string]string{"Sec-WebSocket-Protocol": "lookingglass"}, http.StatusUnauthorized},
+		{"public path", "/healthz", nil, http.StatusOK},
+		{"public prefix", "/share/abc", nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPMiddlewareStaticAssetsPublic(t *testing.T) {
+	m := NewHTTPMiddleware("secret", nil)
+	static := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := m.WrapMux(newTestMux(), static)
+
+	for _, path := range []string{"/", "/index.html", "/js/app.js", "/css/style.css"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusTeapot {
+			t.Errorf("%s: status = %d, want static handler", path, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/agents", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("/api/agents: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestHTTPMiddlewareDisabled(t *testing.T) {
+	m := NewHTTPMiddleware("", nil)
+	handler := m.WrapMux(newTestMux(), http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/agents", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
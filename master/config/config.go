@@ -3,8 +3,11 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
 
 	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/netutil"
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,12 +21,41 @@ type Config struct {
 	Notification NotificationConfig `yaml:"notification"`
 	Log          LogConfig          `yaml:"log"`
 	Branding     BrandingConfig     `yaml:"branding"`
+	Admin        AdminConfig        `yaml:"admin"`
+	SelfCheck    SelfCheckConfig    `yaml:"self_check"`
+	Heatmap      HeatmapConfig      `yaml:"heatmap"`
+	OutputSink   OutputSinkConfig   `yaml:"output_sink"`
+	Templates    []TemplateConfig   `yaml:"templates"`
 }
 
 // ServerConfig contains server settings
 type ServerConfig struct {
 	GRPCPort int `yaml:"grpc_port"`
 	WSPort   int `yaml:"ws_port"`
+
+	WSCompression      bool `yaml:"ws_compression"`       // Enable permessage-deflate for WebSocket clients
+	WSCompressionLevel int  `yaml:"ws_compression_level"` // Deflate level: -2 (Huffman only) .. 9 (best), 0 = default
+
+	PublicStatusLocations bool `yaml:"public_status_locations"` // Include per-location counts in /api/public/status
+
+	TrustedProxies []string `yaml:"trusted_proxies"` // IPs/CIDRs whose X-Forwarded-For / X-Real-IP are believed
+
+	AllowedOrigins []string `yaml:"allowed_origins"` // Origins allowed to open the WebSocket ("*" = any, empty = same origin)
+
+	RunTimeout int `yaml:"run_timeout"` // Seconds POST /api/run waits for a task before cancelling it
+
+	WSIdleTimeout int `yaml:"ws_idle_timeout"` // Seconds without requests before a WebSocket client is closed (0 = never)
+
+	TLS GRPCTLSConfig `yaml:"tls"` // TLS for the agent gRPC port
+}
+
+// GRPCTLSConfig configures TLS (and optionally mutual TLS) on the gRPC port
+type GRPCTLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"` // Server certificate (PEM)
+	KeyFile  string `yaml:"key_file"`  // Server private key (PEM)
+	ClientCA string `yaml:"client_ca"` // CA bundle agent certificates must chain to (required when mutual)
+	Mutual   bool   `yaml:"mutual"`    // Require and verify agent client certificates
 }
 
 // AuthConfig contains authentication settings
@@ -31,12 +63,34 @@ type AuthConfig struct {
 	Mode        string   `yaml:"mode"` // "api_key" or "ip_whitelist"
 	APIKey      string   `yaml:"api_key"`
 	IPWhitelist []string `yaml:"ip_whitelist"`
+
+	// Agent key validation backend
+	Backend     string            `yaml:"backend"`      // "static" (api_key) or "http"
+	AgentKeys   map[string]string `yaml:"agent_keys"`   // Per-agent keys (agent ID -> key), accepted alongside the global key
+	HTTPBackend HTTPBackendConfig `yaml:"http_backend"` // Used when backend is "http"
+
+	// HTTP/WebSocket surface (gRPC agent auth is configured above)
+	HTTPToken   string   `yaml:"http_token"`   // Token required on HTTP/WS requests (empty = no HTTP auth)
+	PublicPaths []string `yaml:"public_paths"` // Paths reachable without http_token (trailing "/" = prefix)
+}
+
+// HTTPBackendConfig configures validation of agent keys by an external HTTP service
+type HTTPBackendConfig struct {
+	URL      string `yaml:"url"`       // Receives a JSON POST {"key": "..."}; 200 accepts, 401/403 rejects
+	Timeout  int    `yaml:"timeout"`   // seconds per request
+	CacheTTL int    `yaml:"cache_ttl"` // seconds an accepted key is cached (0 = no caching)
 }
 
 // ConcurrencyConfig contains concurrency settings
 type ConcurrencyConfig struct {
-	GlobalMax       int `yaml:"global_max"`
-	AgentDefaultMax int `yaml:"agent_default_max"`
+	GlobalMax             int     `yaml:"global_max"`
+	AgentDefaultMax       int     `yaml:"agent_default_max"`
+	AgentDispatchRate     float64 `yaml:"agent_dispatch_rate"`     // max task dispatches per second per agent (0 = unlimited)
+	AgentDispatchBurst    int     `yaml:"agent_dispatch_burst"`    // dispatches allowed in a burst before pacing applies
+	QueueMax              int     `yaml:"queue_max"`               // tasks queued when limits are reached (0 = reject immediately)
+	MaxBroadcasts         int     `yaml:"max_broadcasts"`          // multi-agent (fan-out) submissions in progress at once (0 = unlimited)
+	BroadcastMaxAgents    int     `yaml:"broadcast_max_agents"`    // agents a single fan-out submission may target (0 = unlimited)
+	BroadcastFlushTimeout int     `yaml:"broadcast_flush_timeout"` // seconds grouped delivery holds output of unfinished agents
 }
 
 // AgentConfig contains agent management settings
@@ -44,14 +98,62 @@ type AgentConfig struct {
 	HeartbeatTimeout     int `yaml:"heartbeat_timeout"`      // seconds
 	HeartbeatInterval    int `yaml:"heartbeat_interval"`     // seconds
 	OfflineCheckInterval int `yaml:"offline_check_interval"` // seconds
+	FlapHistorySize      int `yaml:"flap_history_size"`      // online/offline transitions kept per agent
+	OfflineGrace         int `yaml:"offline_grace"`          // seconds to wait for a reconnect before marking offline (0 = immediately)
+	SendTimeout          int `yaml:"send_timeout"`           // seconds a message to an agent may wait on its stream before the agent is marked offline
+
+	GeoIP GeoIPConfig `yaml:"geoip"` // Fill in missing agent location/provider from GeoIP
+}
+
+// GeoIPConfig contains settings for async GeoIP enrichment of agents
+type GeoIPConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	URL           string `yaml:"url"`            // Lookup URL, {ip} is replaced (ip-api.com compatible JSON)
+	Timeout       int    `yaml:"timeout"`        // seconds per lookup
+	MaxConcurrent int    `yaml:"max_concurrent"` // max lookups in flight
 }
 
 // TaskConfig contains task management settings
 type TaskConfig struct {
-	DefaultTimeout   int `yaml:"default_timeout"`    // seconds
-	HistoryRetention int `yaml:"history_retention"`  // hours
-	DefaultPingCount int `yaml:"default_ping_count"` // default ping count
-	DefaultMTRCount  int `yaml:"default_mtr_count"`  // default mtr count
+	DefaultTimeout   int  `yaml:"default_timeout"`    // seconds
+	HistoryRetention int  `yaml:"history_retention"`  // hours
+	HistoryAPI       bool `yaml:"history_api"`        // serve GET /api/history to admin token holders (default off)
+	ShareLinkTTL     int  `yaml:"share_link_ttl"`     // minutes a share link to a finished task stays valid (0 = disabled)
+	DefaultPingCount int  `yaml:"default_ping_count"` // default ping count
+	DefaultMTRCount  int  `yaml:"default_mtr_count"`  // default mtr count
+
+	// Request size limits (validated before scheduling)
+	MaxTargetLength      int `yaml:"max_target_length"`       // max length of NetworkTestParams.Target
+	MaxExtraOptions      int `yaml:"max_extra_options"`       // max number of extra_options entries
+	MaxExtraOptionLength int `yaml:"max_extra_option_length"` // max length of each extra_options key/value
+
+	ReportDispatchLatency bool `yaml:"report_dispatch_latency"` // include master->agent->master latency in completion summary
+	OutputCoalesceMs      int  `yaml:"output_coalesce_ms"`      // batch output lines per task over this window before forwarding (0 = disabled)
+	SubmitCooldown        int  `yaml:"submit_cooldown"`         // seconds before a client may rerun the same task+target (0 = disabled)
+	MaxAttempts           int  `yaml:"max_attempts"`            // cap on attempts a task's retry policy may request (1 = no retries)
+
+	DisabledTasks    []string `yaml:"disabled_tasks"`     // Task names hidden from every agent and rejected on submit
+	CacheTTLSeconds  int      `yaml:"cache_ttl_seconds"`  // Replay completed results to identical submissions for this long (0 = disabled)
+	MaxTransferBytes int64    `yaml:"max_transfer_bytes"` // Output bytes an agent may send per task before the master stops it (0 = unlimited)
+
+	DispatchProbeTimeoutMs int `yaml:"dispatch_probe_timeout_ms"` // Probe each agent's stream before dispatch, failing if it does not answer in time (0 = disabled)
+
+	OutputRedactions []RedactionConfig `yaml:"output_redactions"` // Regex rules applied, in order, to every output line
+
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"` // Pause dispatch to agents that keep failing tasks
+}
+
+// CircuitBreakerConfig pauses dispatch to an agent after consecutive task failures
+type CircuitBreakerConfig struct {
+	FailureThreshold int `yaml:"failure_threshold"` // Consecutive failures that degrade the agent (0 = disabled)
+	WindowSeconds    int `yaml:"window_seconds"`    // The failures must happen within this window
+	CooldownSeconds  int `yaml:"cooldown_seconds"`  // Tasks are rejected this long before the agent is tried again
+}
+
+// RedactionConfig replaces matches of a regular expression in task output
+type RedactionConfig struct {
+	Pattern     string `yaml:"pattern"`     // Regular expression matched against each output line
+	Replacement string `yaml:"replacement"` // Replaces each match; may use $1 group references (default "[REDACTED]")
 }
 
 // NotificationConfig contains notification settings
@@ -59,6 +161,12 @@ type NotificationConfig struct {
 	Enabled bool                `yaml:"enabled"`
 	Events  NotificationEvents  `yaml:"events"`
 	Bark    *BarkNotifierConfig `yaml:"bark,omitempty"`
+
+	DedupWindowSeconds    int `yaml:"dedup_window_seconds"`    // Drop events identical (type + agent) to one sent within this window (0 = off)
+	OfflineConfirmSeconds int `yaml:"offline_confirm_seconds"` // Agent must stay offline this long before the offline event fires (0 = immediately)
+
+	RetryTimes    int `yaml:"retry_times"`    // Retries of a failed send (0 = default, -1 = no retries)
+	RetryInterval int `yaml:"retry_interval"` // Seconds before the first retry, doubled after each
 	// Future notifiers can be added here:
 	// Telegram *TelegramConfig `yaml:"telegram,omitempty"`
 	// Feishu   *FeishuConfig   `yaml:"feishu,omitempty"`
@@ -81,6 +189,60 @@ type BarkNotifierConfig struct {
 	Sound     string `yaml:"sound"`      // Notification sound
 	Icon      string `yaml:"icon"`       // Icon URL
 	Group     string `yaml:"group"`      // Notification group
+	MaxBody   int    `yaml:"max_body"`   // Max body size in bytes before truncation (0 = default, -1 = unlimited)
+}
+
+// OutputSinkConfig contains settings for publishing task outputs to external pipelines
+type OutputSinkConfig struct {
+	NATS *NATSSinkConfig `yaml:"nats,omitempty"`
+}
+
+// NATSSinkConfig contains NATS-specific sink configuration
+type NATSSinkConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	URL            string `yaml:"url"`              // e.g. nats://127.0.0.1:4222
+	Subject        string `yaml:"subject"`          // Subject (or prefix when per_task_subject is set)
+	PerTaskSubject bool   `yaml:"per_task_subject"` // Publish to "<subject>.<task_id>"
+}
+
+// AdminConfig contains settings for administrative actions
+type AdminConfig struct {
+	Token string `yaml:"token"` // Confirmation token for admin actions (empty = admin actions disabled)
+}
+
+// SelfCheckConfig contains tasks auto-run against agents on first registration
+type SelfCheckConfig struct {
+	Enabled bool                  `yaml:"enabled"`
+	Tasks   []SelfCheckTaskConfig `yaml:"tasks"`
+}
+
+// SelfCheckTaskConfig describes a single self-check task
+type SelfCheckTaskConfig struct {
+	TaskName string `yaml:"task_name"` // Task name on the agent (e.g. "ping")
+	Target   string `yaml:"target"`    // Canary target
+	Count    int    `yaml:"count"`     // Packet/hop count (0 = task default)
+	Timeout  int    `yaml:"timeout"`   // Timeout in seconds (0 = task.default_timeout)
+}
+
+// HeatmapConfig contains the canary pings behind the latency heatmap (/api/heatmap)
+type HeatmapConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	Targets  []string `yaml:"targets"`  // Canary targets pinged from every agent
+	Interval int      `yaml:"interval"` // Seconds between refreshes
+	Count    int      `yaml:"count"`    // Packets per ping (0 = task.default_ping_count)
+	Timeout  int      `yaml:"timeout"`  // Timeout in seconds (0 = task.default_timeout)
+}
+
+// TemplateConfig describes a canned task offered to users as a one-click test
+type TemplateConfig struct {
+	Name        string            `yaml:"name"`        // Unique name clients submit
+	Description string            `yaml:"description"` // Label shown to users
+	TaskName    string            `yaml:"task_name"`   // Task name on the agent (e.g. "ping")
+	Target      string            `yaml:"target"`
+	Count       int               `yaml:"count"`   // Packet/hop count (0 = task default)
+	Timeout     int               `yaml:"timeout"` // Timeout in seconds (0 = task.default_timeout)
+	IPv6        bool              `yaml:"ipv6"`
+	Params      map[string]string `yaml:"params"` // Passed to the task as extra_options
 }
 
 // LogConfig contains logging settings
@@ -88,6 +250,20 @@ type LogConfig struct {
 	Level   string `yaml:"level"`
 	File    string `yaml:"file"`
 	Console bool   `yaml:"console"`
+	Format  string `yaml:"format"` // console (default) or json; also used by the audit log
+
+	// Log file rotation, also applied to the audit log (0 = default)
+	MaxSizeMB  int `yaml:"max_size_mb"`  // Rotate at this size (default 100)
+	MaxBackups int `yaml:"max_backups"`  // Rotated files kept (default 3)
+	MaxAgeDays int `yaml:"max_age_days"` // Rotated files deleted after this many days (default 28)
+
+	Audit AuditLogConfig `yaml:"audit"`
+}
+
+// AuditLogConfig contains task audit trail settings
+type AuditLogConfig struct {
+	Enabled bool   `yaml:"enabled"` // Log submit/finish of every task (client, agent, target, status, duration)
+	File    string `yaml:"file"`    // Separate audit log file (empty = write to the main log)
 }
 
 // BrandingConfig contains branding customization settings
@@ -132,6 +308,26 @@ func (c *Config) setDefaults() {
 		c.Server.WSPort = 8080
 	}
 
+	if c.Server.WSCompressionLevel == 0 {
+		c.Server.WSCompressionLevel = 6
+	}
+
+	if c.Server.RunTimeout == 0 {
+		c.Server.RunTimeout = 60
+	}
+
+	if c.Auth.Backend == "" {
+		c.Auth.Backend = "static"
+	}
+
+	if c.Auth.HTTPBackend.Timeout == 0 {
+		c.Auth.HTTPBackend.Timeout = 5
+	}
+
+	if c.Auth.HTTPBackend.CacheTTL == 0 {
+		c.Auth.HTTPBackend.CacheTTL = 60
+	}
+
 	if c.Concurrency.GlobalMax == 0 {
 		c.Concurrency.GlobalMax = 50
 	}
@@ -140,6 +336,14 @@ func (c *Config) setDefaults() {
 		c.Concurrency.AgentDefaultMax = 5
 	}
 
+	if c.Concurrency.AgentDispatchBurst == 0 {
+		c.Concurrency.AgentDispatchBurst = 1
+	}
+
+	if c.Concurrency.BroadcastFlushTimeout == 0 {
+		c.Concurrency.BroadcastFlushTimeout = 60
+	}
+
 	if c.Agent.HeartbeatTimeout == 0 {
 		c.Agent.HeartbeatTimeout = 60
 	}
@@ -152,14 +356,42 @@ func (c *Config) setDefaults() {
 		c.Agent.OfflineCheckInterval = 60
 	}
 
+	if c.Agent.FlapHistorySize == 0 {
+		c.Agent.FlapHistorySize = 20
+	}
+
+	if c.Agent.SendTimeout == 0 {
+		c.Agent.SendTimeout = 10
+	}
+
+	if c.Agent.GeoIP.URL == "" {
+		c.Agent.GeoIP.URL = "http://ip-api.com/json/{ip}?fields=status,message,country,city,isp"
+	}
+
+	if c.Agent.GeoIP.Timeout == 0 {
+		c.Agent.GeoIP.Timeout = 5
+	}
+
+	if c.Agent.GeoIP.MaxConcurrent == 0 {
+		c.Agent.GeoIP.MaxConcurrent = 4
+	}
+
 	if c.Task.DefaultTimeout == 0 {
 		c.Task.DefaultTimeout = 300
 	}
 
+	if c.Task.MaxAttempts == 0 {
+		c.Task.MaxAttempts = 3
+	}
+
 	if c.Task.HistoryRetention == 0 {
 		c.Task.HistoryRetention = 24
 	}
 
+	if c.Heatmap.Interval == 0 {
+		c.Heatmap.Interval = 300
+	}
+
 	if c.Task.DefaultPingCount == 0 {
 		c.Task.DefaultPingCount = 4
 	}
@@ -168,6 +400,40 @@ func (c *Config) setDefaults() {
 		c.Task.DefaultMTRCount = 4
 	}
 
+	if c.Task.MaxTargetLength == 0 {
+		c.Task.MaxTargetLength = 512
+	}
+
+	if c.Task.MaxExtraOptions == 0 {
+		c.Task.MaxExtraOptions = 16
+	}
+
+	if c.Task.MaxExtraOptionLength == 0 {
+		c.Task.MaxExtraOptionLength = 256
+	}
+
+	for i := range c.Task.OutputRedactions {
+		if c.Task.OutputRedactions[i].Replacement == "" {
+			c.Task.OutputRedactions[i].Replacement = "[REDACTED]"
+		}
+	}
+
+	if c.Notification.RetryTimes == 0 {
+		c.Notification.RetryTimes = 2
+	}
+
+	if c.Notification.RetryInterval == 0 {
+		c.Notification.RetryInterval = 1
+	}
+
+	if c.Task.CircuitBreaker.WindowSeconds == 0 {
+		c.Task.CircuitBreaker.WindowSeconds = 300
+	}
+
+	if c.Task.CircuitBreaker.CooldownSeconds == 0 {
+		c.Task.CircuitBreaker.CooldownSeconds = 60
+	}
+
 	if c.Log.Level == "" {
 		c.Log.Level = "info"
 	}
@@ -176,6 +442,18 @@ func (c *Config) setDefaults() {
 		c.Log.File = "logs/master.log"
 	}
 
+	if c.Log.MaxSizeMB == 0 {
+		c.Log.MaxSizeMB = 100
+	}
+
+	if c.Log.MaxBackups == 0 {
+		c.Log.MaxBackups = 3
+	}
+
+	if c.Log.MaxAgeDays == 0 {
+		c.Log.MaxAgeDays = 28
+	}
+
 	if c.Branding.SiteTitle == "" {
 		c.Branding.SiteTitle = "LookingGlass - Network Diagnostics"
 	}
@@ -192,6 +470,10 @@ func (c *Config) setDefaults() {
 		}
 	}
 	// Case 1 and 2: Use configured values (no additional initialization needed)
+
+	if c.OutputSink.NATS != nil && c.OutputSink.NATS.Subject == "" {
+		c.OutputSink.NATS.Subject = "lookingglass.output"
+	}
 }
 
 // validate validates the configuration
@@ -201,14 +483,70 @@ func (c *Config) validate() error {
 		return fmt.Errorf("auth.mode must be 'api_key' or 'ip_whitelist'")
 	}
 
-	if c.Auth.APIKey == "" {
-		return fmt.Errorf("auth.api_key is required")
+	if c.Log.Format != "" && c.Log.Format != "console" && c.Log.Format != "json" {
+		return fmt.Errorf("log.format must be 'console' or 'json'")
+	}
+
+	if c.Log.MaxSizeMB < 0 || c.Log.MaxBackups < 0 || c.Log.MaxAgeDays < 0 {
+		return fmt.Errorf("log.max_size_mb, log.max_backups and log.max_age_days cannot be negative")
+	}
+
+	switch c.Auth.Backend {
+	case "static":
+		if c.Auth.APIKey == "" && !c.Server.TLS.Mutual {
+			return fmt.Errorf("auth.api_key is required")
+		}
+	case "http":
+		if c.Auth.HTTPBackend.URL == "" {
+			return fmt.Errorf("auth.http_backend.url is required when auth.backend is 'http'")
+		}
+		if c.Auth.HTTPBackend.Timeout < 0 || c.Auth.HTTPBackend.CacheTTL < 0 {
+			return fmt.Errorf("auth.http_backend.timeout and auth.http_backend.cache_ttl cannot be negative")
+		}
+	default:
+		return fmt.Errorf("auth.backend must be 'static' or 'http'")
 	}
 
 	if c.Auth.Mode == "ip_whitelist" && len(c.Auth.IPWhitelist) == 0 {
 		return fmt.Errorf("auth.ip_whitelist cannot be empty when mode is 'ip_whitelist'")
 	}
 
+	if c.Server.WSCompressionLevel < -2 || c.Server.WSCompressionLevel > 9 {
+		return fmt.Errorf("server.ws_compression_level must be between -2 and 9")
+	}
+
+	if c.Server.RunTimeout < 0 {
+		return fmt.Errorf("server.run_timeout cannot be negative")
+	}
+
+	if c.Server.WSIdleTimeout < 0 {
+		return fmt.Errorf("server.ws_idle_timeout cannot be negative")
+	}
+
+	if c.Server.TLS.Enabled && (c.Server.TLS.CertFile == "" || c.Server.TLS.KeyFile == "") {
+		return fmt.Errorf("server.tls.cert_file and server.tls.key_file are required when TLS is enabled")
+	}
+
+	if c.Server.TLS.Mutual && (!c.Server.TLS.Enabled || c.Server.TLS.ClientCA == "") {
+		return fmt.Errorf("server.tls.mutual requires server.tls.enabled and server.tls.client_ca")
+	}
+
+	if c.Notification.DedupWindowSeconds < 0 || c.Notification.OfflineConfirmSeconds < 0 {
+		return fmt.Errorf("notification.dedup_window_seconds and notification.offline_confirm_seconds cannot be negative")
+	}
+
+	if c.Notification.RetryTimes < -1 {
+		return fmt.Errorf("notification.retry_times must be -1 (no retries) or greater")
+	}
+
+	if c.Notification.RetryInterval < 0 {
+		return fmt.Errorf("notification.retry_interval cannot be negative")
+	}
+
+	if _, err := netutil.ParseTrustedProxies(c.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("server.trusted_proxies: %w", err)
+	}
+
 	if c.Concurrency.GlobalMax < 1 {
 		return fmt.Errorf("concurrency.global_max must be at least 1")
 	}
@@ -217,6 +555,135 @@ func (c *Config) validate() error {
 		return fmt.Errorf("concurrency.agent_default_max must be at least 1")
 	}
 
+	if c.Concurrency.QueueMax < 0 {
+		return fmt.Errorf("concurrency.queue_max cannot be negative")
+	}
+
+	if c.Concurrency.MaxBroadcasts < 0 || c.Concurrency.BroadcastMaxAgents < 0 {
+		return fmt.Errorf("concurrency.max_broadcasts and concurrency.broadcast_max_agents cannot be negative")
+	}
+
+	if c.Concurrency.BroadcastFlushTimeout < 0 {
+		return fmt.Errorf("concurrency.broadcast_flush_timeout cannot be negative")
+	}
+
+	if c.Concurrency.AgentDispatchRate < 0 {
+		return fmt.Errorf("concurrency.agent_dispatch_rate cannot be negative")
+	}
+
+	if c.Concurrency.AgentDispatchBurst < 1 {
+		return fmt.Errorf("concurrency.agent_dispatch_burst must be at least 1")
+	}
+
+	if c.SelfCheck.Enabled {
+		for i, check := range c.SelfCheck.Tasks {
+			if check.TaskName == "" {
+				return fmt.Errorf("self_check.tasks[%d].task_name is required", i)
+			}
+		}
+	}
+
+	if c.Task.HistoryAPI && c.Admin.Token == "" {
+		return fmt.Errorf("task.history_api requires admin.token")
+	}
+
+	if c.Heatmap.Enabled {
+		if len(c.Heatmap.Targets) == 0 {
+			return fmt.Errorf("heatmap.targets is required when heatmap is enabled")
+		}
+		for i, target := range c.Heatmap.Targets {
+			if strings.TrimSpace(target) == "" {
+				return fmt.Errorf("heatmap.targets[%d] cannot be empty", i)
+			}
+		}
+		if c.Heatmap.Interval < 30 {
+			return fmt.Errorf("heatmap.interval must be at least 30 seconds")
+		}
+		if c.Task.HistoryRetention < 0 {
+			return fmt.Errorf("heatmap requires task history (task.history_retention > 0)")
+		}
+	}
+	if c.Heatmap.Count < 0 || c.Heatmap.Timeout < 0 {
+		return fmt.Errorf("heatmap.count and heatmap.timeout cannot be negative")
+	}
+
+	templateNames := make(map[string]bool, len(c.Templates))
+	for i, tmpl := range c.Templates {
+		if tmpl.Name == "" || tmpl.TaskName == "" {
+			return fmt.Errorf("templates[%d].name and templates[%d].task_name are required", i, i)
+		}
+		if templateNames[tmpl.Name] {
+			return fmt.Errorf("templates[%d].name %q is duplicated", i, tmpl.Name)
+		}
+		templateNames[tmpl.Name] = true
+		if tmpl.Count < 0 || tmpl.Timeout < 0 {
+			return fmt.Errorf("templates[%d].count and templates[%d].timeout cannot be negative", i, i)
+		}
+	}
+
+	if c.Task.OutputCoalesceMs < 0 {
+		return fmt.Errorf("task.output_coalesce_ms cannot be negative")
+	}
+	if c.Agent.HeartbeatInterval < 0 || c.Agent.HeartbeatTimeout < 0 {
+		return fmt.Errorf("agent.heartbeat_interval and agent.heartbeat_timeout cannot be negative")
+	}
+	// Agents adopt heartbeat_interval; the timeout must leave room for a late heartbeat or
+	// agents flap offline between beats
+	if 2*c.Agent.HeartbeatTimeout < 3*c.Agent.HeartbeatInterval {
+		return fmt.Errorf("agent.heartbeat_timeout (%ds) must be at least 1.5x agent.heartbeat_interval (%ds)",
+			c.Agent.HeartbeatTimeout, c.Agent.HeartbeatInterval)
+	}
+	if c.Agent.FlapHistorySize < 0 {
+		return fmt.Errorf("agent.flap_history_size cannot be negative")
+	}
+	if c.Agent.SendTimeout < 0 {
+		return fmt.Errorf("agent.send_timeout cannot be negative")
+	}
+	if c.Agent.OfflineGrace < 0 {
+		return fmt.Errorf("agent.offline_grace cannot be negative")
+	}
+	if c.Agent.GeoIP.Timeout < 0 || c.Agent.GeoIP.MaxConcurrent < 0 {
+		return fmt.Errorf("agent.geoip.timeout and agent.geoip.max_concurrent cannot be negative")
+	}
+
+	if c.Task.SubmitCooldown < 0 {
+		return fmt.Errorf("task.submit_cooldown cannot be negative")
+	}
+
+	if c.Task.MaxTransferBytes < 0 {
+		return fmt.Errorf("task.max_transfer_bytes cannot be negative")
+	}
+
+	if c.Task.CacheTTLSeconds < 0 {
+		return fmt.Errorf("task.cache_ttl_seconds cannot be negative")
+	}
+	if c.Task.ShareLinkTTL < 0 {
+		return fmt.Errorf("task.share_link_ttl cannot be negative")
+	}
+	if c.Task.DispatchProbeTimeoutMs < 0 {
+		return fmt.Errorf("task.dispatch_probe_timeout_ms cannot be negative")
+	}
+	for i, rule := range c.Task.OutputRedactions {
+		if rule.Pattern == "" {
+			return fmt.Errorf("task.output_redactions[%d].pattern is required", i)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("task.output_redactions[%d].pattern is invalid: %w", i, err)
+		}
+	}
+
+	if c.Task.CircuitBreaker.FailureThreshold < 0 || c.Task.CircuitBreaker.WindowSeconds < 0 || c.Task.CircuitBreaker.CooldownSeconds < 0 {
+		return fmt.Errorf("task.circuit_breaker values cannot be negative")
+	}
+
+	if c.Task.MaxAttempts < 0 {
+		return fmt.Errorf("task.max_attempts cannot be negative")
+	}
+
+	if nats := c.OutputSink.NATS; nats != nil && nats.Enabled && nats.URL == "" {
+		return fmt.Errorf("output_sink.nats.url is required when the NATS sink is enabled")
+	}
+
 	return nil
 }
 
@@ -0,0 +1,92 @@
+package config
+
+import "testing"
+
+// validConfig returns a config with defaults applied that passes validation
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+	cfg := &Config{Auth: AuthConfig{Mode: "api_key", APIKey: "key"}}
+	cfg.setDefaults()
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("default config invalid: %v", err)
+	}
+	return cfg
+}
+
+func TestValidateHistoryAPIRequiresAdminToken(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.Task.HistoryAPI = true
+	if err := cfg.validate(); err == nil {
+		t.Error("validate() accepted history_api without admin.token")
+	}
+
+	cfg.Admin.Token = "admin"
+	if err := cfg.validate(); err != nil {
+		t.Errorf("validate() error = %v", err)
+	}
+}
+
+func TestValidateWSCompressionLevel(t *testing.T) {
+	cfg := validConfig(t)
+	if cfg.Server.WSCompressionLevel != 6 {
+		t.Errorf("default compression level = %d, want 6", cfg.Server.WSCompressionLevel)
+	}
+
+	for _, level := range []int{-2, 1, 9} {
+		cfg.Server.WSCompressionLevel = level
+		if err := cfg.validate(); err != nil {
+			t.Errorf("level %d: validate() error = %v", level, err)
+		}
+	}
+	for _, level := range []int{-3, 10} {
+		cfg.Server.WSCompressionLevel = level
+		if err := cfg.validate(); err == nil {
+			t.Errorf("level %d: validate() accepted it", level)
+		}
+	}
+}
+
+func TestValidateHeartbeatTimeout(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.Agent.HeartbeatInterval = 10
+
+	for _, timeout := range []int{15, 30} {
+		cfg.Agent.HeartbeatTimeout = timeout
+		if err := cfg.validate(); err != nil {
+			t.Errorf("timeout %ds: validate() error = %v", timeout, err)
+		}
+	}
+	for _, timeout := range []int{14, 10, -1} {
+		cfg.Agent.HeartbeatTimeout = timeout
+		if err := cfg.validate(); err == nil {
+			t.Errorf("timeout %ds with a 10s interval accepted", timeout)
+		}
+	}
+}
+
+func TestValidateLogFormat(t *testing.T) {
+	cfg := validConfig(t)
+	for _, format := range []string{"", "console", "json"} {
+		cfg.Log.Format = format
+		if err := cfg.validate(); err != nil {
+			t.Errorf("format %q: validate() error = %v", format, err)
+		}
+	}
+	cfg.Log.Format = "xml"
+	if err := cfg.validate(); err == nil {
+		t.Error("validate() accepted log.format xml")
+	}
+}
+
+func TestLogRotationDefaults(t *testing.T) {
+	cfg := validConfig(t)
+	if cfg.Log.MaxSizeMB != 100 || cfg.Log.MaxBackups != 3 || cfg.Log.MaxAgeDays != 28 {
+		t.Errorf("rotation defaults = %d MB, %d backups, %d days, want 100, 3, 28",
+			cfg.Log.MaxSizeMB, cfg.Log.MaxBackups, cfg.Log.MaxAgeDays)
+	}
+
+	cfg.Log.MaxBackups = -1
+	if err := cfg.validate(); err == nil {
+		t.Error("validate() accepted a negative log.max_backups")
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces secrets in the redacted config
+const redactedValue = "[REDACTED]"
+
+// Redacted returns a copy of the config with secrets (API keys, tokens, notifier keys) masked
+// Unset secrets stay empty so operators can still tell whether they are configured
+func (c *Config) Redacted() *Config {
+	out := *c
+
+	out.Auth.APIKey = redact(c.Auth.APIKey)
+	out.Auth.HTTPToken = redact(c.Auth.HTTPToken)
+	out.Admin.Token = redact(c.Admin.Token)
+	out.Auth.HTTPBackend.URL = redactURLCredentials(c.Auth.HTTPBackend.URL)
+
+	if c.Auth.AgentKeys != nil {
+		out.Auth.AgentKeys = make(map[string]string, len(c.Auth.AgentKeys))
+		for agentID, key := range c.Auth.AgentKeys {
+			out.Auth.AgentKeys[agentID] = redact(key)
+		}
+	}
+
+	if c.Notification.Bark != nil {
+		bark := *c.Notification.Bark
+		bark.DeviceKey = redact(bark.DeviceKey)
+		bark.ServerURL = redact(bark.ServerURL) // Full Bark URLs embed the device key
+		out.Notification.Bark = &bark
+	}
+
+	if c.OutputSink.NATS != nil {
+		nats := *c.OutputSink.NATS
+		nats.URL = redactURLCredentials(nats.URL)
+		out.OutputSink.NATS = &nats
+	}
+
+	return &out
+}
+
+// ToMap converts the config to a generic map keyed by its YAML field names (e.g. for JSON output)
+func (c *Config) ToMap() (map[string]interface{}, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	var out map[string]interface{}
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return out, nil
+}
+
+// redact masks a non-empty secret
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redactURLCredentials masks the parts of a URL that can carry a credential:
+// the user info (NATS tokens go in the user name) and every query value
+func redactURLCredentials(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redact(raw)
+	}
+	if u.User != nil {
+		u.User = url.User(redactedValue)
+	}
+	if u.RawQuery != "" {
+		keys := slices.Sorted(maps.Keys(u.Query()))
+		for i, key := range keys {
+			keys[i] = url.QueryEscape(key) + "=" + redactedValue
+		}
+		u.RawQuery = strings.Join(keys, "&")
+	}
+	return u.String()
+}
@@ -2,26 +2,33 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"syscall"
 	"time"
 
 	"github.com/lureiny/lookingglass/master/agent"
 	"github.com/lureiny/lookingglass/master/auth"
 	"github.com/lureiny/lookingglass/master/config"
+	"github.com/lureiny/lookingglass/master/metrics"
 	"github.com/lureiny/lookingglass/master/notifier"
 	"github.com/lureiny/lookingglass/master/server"
+	"github.com/lureiny/lookingglass/master/sink"
 	"github.com/lureiny/lookingglass/master/task"
 	"github.com/lureiny/lookingglass/master/ws"
 	pb "github.com/lureiny/lookingglass/pb"
 	"github.com/lureiny/lookingglass/pkg/logger"
+	"github.com/lureiny/lookingglass/pkg/netutil"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
 )
 
@@ -51,12 +58,20 @@ func main() {
 		Level:   cfg.Log.Level,
 		File:    cfg.Log.File,
 		Console: cfg.Log.Console,
+		Format:  cfg.Log.Format,
+
+		MaxSizeMB:  cfg.Log.MaxSizeMB,
+		MaxBackups: cfg.Log.MaxBackups,
+		MaxAgeDays: cfg.Log.MaxAgeDays,
 	}); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
 		os.Exit(1)
 	}
 	defer logger.Sync()
 
+	// SIGUSR1 switches to debug logging, SIGUSR2 back to the configured level
+	logger.HandleLevelSignals()
+
 	logger.Info("Starting LookingGlass Master",
 		zap.Int("grpc_port", cfg.Server.GRPCPort),
 		zap.Int("ws_port", cfg.Server.WSPort),
@@ -68,6 +83,15 @@ func main() {
 		Mode:        cfg.GetAuthMode(),
 		APIKey:      cfg.Auth.APIKey,
 		IPWhitelist: cfg.Auth.IPWhitelist,
+		AgentKeys:   cfg.Auth.AgentKeys,
+		CertAuth:    cfg.Server.TLS.Mutual,
+	}
+	if cfg.Auth.Backend == "http" {
+		authConfig.Backend = auth.NewHTTPBackend(auth.HTTPBackendConfig{
+			URL:      cfg.Auth.HTTPBackend.URL,
+			Timeout:  time.Duration(cfg.Auth.HTTPBackend.Timeout) * time.Second,
+			CacheTTL: time.Duration(cfg.Auth.HTTPBackend.CacheTTL) * time.Second,
+		})
 	}
 	authenticator, err := auth.NewAuthenticator(authConfig)
 	if err != nil {
@@ -89,6 +113,7 @@ func main() {
 				Sound:     cfg.Notification.Bark.Sound,
 				Icon:      cfg.Notification.Bark.Icon,
 				Group:     cfg.Notification.Bark.Group,
+				MaxBody:   cfg.Notification.Bark.MaxBody,
 			}
 			barkNotifier, err := notifier.NewBarkNotifier(barkConfig)
 			if err != nil {
@@ -99,6 +124,11 @@ func main() {
 			}
 		}
 
+		// Suppress repeated and flapping notifications
+		notificationManager.SetDedupWindow(time.Duration(cfg.Notification.DedupWindowSeconds) * time.Second)
+		notificationManager.SetOfflineConfirm(time.Duration(cfg.Notification.OfflineConfirmSeconds) * time.Second)
+		notificationManager.SetRetry(cfg.Notification.RetryTimes, time.Duration(cfg.Notification.RetryInterval)*time.Second)
+
 		// Start notification manager
 		notificationManager.Start()
 	}
@@ -109,9 +139,22 @@ func main() {
 		time.Duration(cfg.Agent.OfflineCheckInterval)*time.Second,
 	)
 
+	agentManager.SetFlapHistorySize(cfg.Agent.FlapHistorySize)
+	agentManager.SetOfflineGrace(time.Duration(cfg.Agent.OfflineGrace) * time.Second)
+
+	// Fill in missing agent location/provider from GeoIP (async, off the registration path)
+	if cfg.Agent.GeoIP.Enabled {
+		agentManager.SetGeoEnrichment(
+			agent.NewHTTPGeoLookup(cfg.Agent.GeoIP.URL),
+			time.Duration(cfg.Agent.GeoIP.Timeout)*time.Second,
+			cfg.Agent.GeoIP.MaxConcurrent,
+		)
+	}
+
 	// Set notification manager for agent manager
+	var eventConfig *notifier.EventConfig
 	if cfg.Notification.Enabled {
-		eventConfig := &notifier.EventConfig{
+		eventConfig = &notifier.EventConfig{
 			AgentOnline:  cfg.Notification.Events.AgentOnline,
 			AgentOffline: cfg.Notification.Events.AgentOffline,
 			AgentError:   cfg.Notification.Events.AgentError,
@@ -122,20 +165,117 @@ func main() {
 
 	// Create stream registry for bidirectional agent streams
 	streamRegistry := agent.NewStreamRegistry(logger.Get())
+	streamRegistry.SetSendTimeout(time.Duration(cfg.Agent.SendTimeout) * time.Second)
+	streamRegistry.OnSendTimeout(func(agentID string) {
+		agentManager.MarkAgentUnhealthy(agentID, "send timed out")
+	})
 
 	// Create stream handler
 	streamHandler := server.NewStreamHandler(agentManager, streamRegistry, logger.Get())
+	streamHandler.SetDisabledTasks(cfg.Task.DisabledTasks)
 
 	// Create task scheduler
 	scheduler := task.NewScheduler(
 		agentManager,
 		cfg.Concurrency.GlobalMax,
 	)
+	if eventConfig != nil {
+		scheduler.SetNotifier(notificationManager, eventConfig)
+	}
+	scheduler.SetDispatchRateLimit(cfg.Concurrency.AgentDispatchRate, cfg.Concurrency.AgentDispatchBurst)
+	scheduler.SetReportDispatchLatency(cfg.Task.ReportDispatchLatency)
+	scheduler.SetOutputCoalescing(time.Duration(cfg.Task.OutputCoalesceMs) * time.Millisecond)
+	scheduler.SetSubmitCooldown(time.Duration(cfg.Task.SubmitCooldown) * time.Second)
+	scheduler.SetMaxAttempts(cfg.Task.MaxAttempts)
+	scheduler.SetCircuitBreaker(
+		cfg.Task.CircuitBreaker.FailureThreshold,
+		time.Duration(cfg.Task.CircuitBreaker.WindowSeconds)*time.Second,
+		time.Duration(cfg.Task.CircuitBreaker.CooldownSeconds)*time.Second,
+	)
+	scheduler.SetQueueMax(cfg.Concurrency.QueueMax)
+	scheduler.SetBroadcastLimits(cfg.Concurrency.MaxBroadcasts, cfg.Concurrency.BroadcastMaxAgents)
+	scheduler.SetBroadcastFlushTimeout(time.Duration(cfg.Concurrency.BroadcastFlushTimeout) * time.Second)
+	scheduler.SetDisabledTasks(cfg.Task.DisabledTasks)
+	scheduler.SetResultCacheTTL(time.Duration(cfg.Task.CacheTTLSeconds) * time.Second)
+	scheduler.SetMaxTransferBytes(cfg.Task.MaxTransferBytes)
+	redactions := make([]task.RedactionRule, 0, len(cfg.Task.OutputRedactions))
+	for _, rule := range cfg.Task.OutputRedactions {
+		redactions = append(redactions, task.RedactionRule{
+			Pattern:     regexp.MustCompile(rule.Pattern), // Validated by config.Load
+			Replacement: rule.Replacement,
+		})
+	}
+	scheduler.SetOutputRedactions(redactions)
+	scheduler.SetHistoryRetention(time.Duration(cfg.Task.HistoryRetention) * time.Hour)
+	scheduler.SetShareLinkTTL(time.Duration(cfg.Task.ShareLinkTTL) * time.Minute)
+
+	// Publish task outputs to NATS if configured
+	if natsCfg := cfg.OutputSink.NATS; natsCfg != nil && natsCfg.Enabled {
+		natsSink, err := sink.NewNATSSink(sink.NATSConfig{
+			URL:            natsCfg.URL,
+			Subject:        natsCfg.Subject,
+			PerTaskSubject: natsCfg.PerTaskSubject,
+		})
+		if err != nil {
+			logger.Fatal("Failed to create NATS output sink", zap.Error(err))
+		}
+		scheduler.SetOutputSink(natsSink)
+		// Deferred calls run in reverse: queued outputs are published before the connection drains
+		defer natsSink.Close()
+		defer scheduler.StopOutputSink()
+		logger.Info("NATS output sink enabled",
+			zap.String("url", natsCfg.URL),
+			zap.String("subject", natsCfg.Subject),
+		)
+	}
+
+	// Audit trail of task lifecycle events (optionally in its own file)
+	if cfg.Log.Audit.Enabled {
+		auditLogger := logger.Named("audit")
+		if cfg.Log.Audit.File != "" {
+			auditLogger, err = logger.New(logger.Config{
+				Level:  "info",
+				File:   cfg.Log.Audit.File,
+				Format: cfg.Log.Format,
+
+				MaxSizeMB:  cfg.Log.MaxSizeMB,
+				MaxBackups: cfg.Log.MaxBackups,
+				MaxAgeDays: cfg.Log.MaxAgeDays,
+			})
+			if err != nil {
+				logger.Fatal("Failed to create audit logger", zap.Error(err))
+			}
+			defer auditLogger.Sync()
+		}
+		scheduler.SetAuditLogger(auditLogger)
+	}
 
 	// Wire up scheduler and stream handler (bidirectional dependency)
+	// Must happen before any server starts accepting tasks
 	scheduler.SetStreamSender(streamHandler)
+	scheduler.SetDispatchProbe(streamHandler, time.Duration(cfg.Task.DispatchProbeTimeoutMs)*time.Millisecond)
 	streamHandler.SetTaskOutputHandler(scheduler)
 
+	// Run self-check tasks against agents on first registration
+	if cfg.SelfCheck.Enabled && len(cfg.SelfCheck.Tasks) > 0 {
+		checks := make([]task.SelfCheckTask, 0, len(cfg.SelfCheck.Tasks))
+		for _, check := range cfg.SelfCheck.Tasks {
+			timeout := check.Timeout
+			if timeout == 0 {
+				timeout = cfg.Task.DefaultTimeout
+			}
+			checks = append(checks, task.SelfCheckTask{
+				TaskName: check.TaskName,
+				Target:   check.Target,
+				Count:    int32(check.Count),
+				Timeout:  int32(timeout),
+			})
+		}
+		selfChecker := task.NewSelfChecker(scheduler, checks)
+		streamHandler.SetFirstRegistrationHandler(selfChecker.RunForAgent)
+		logger.Info("Agent self-check enabled", zap.Int("tasks", len(checks)))
+	}
+
 	// Create gRPC server with authentication interceptors
 	// 配置 Keepalive Enforcement Policy，允许在空闲时进行 PING，并设置最小 PING 间隔
 	kaep := keepalive.EnforcementPolicy{
@@ -148,18 +288,47 @@ func main() {
 		Time:    60 * time.Second, // 服务器空闲 60 秒后发送 PING
 		Timeout: 20 * time.Second,
 	}
-	grpcServer := grpc.NewServer(
+	grpcOpts := []grpc.ServerOption{
 		grpc.UnaryInterceptor(authenticator.UnaryInterceptor()),
 		grpc.StreamInterceptor(authenticator.StreamInterceptor()),
 		grpc.KeepaliveEnforcementPolicy(kaep),
 		grpc.KeepaliveParams(kasp),
-	)
+	}
+	if cfg.Server.TLS.Enabled {
+		tlsConfig, err := grpcTLSConfig(cfg.Server.TLS)
+		if err != nil {
+			logger.Fatal("Failed to load gRPC TLS configuration", zap.Error(err))
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		logger.Info("gRPC TLS enabled", zap.Bool("mutual", cfg.Server.TLS.Mutual))
+	}
+	grpcServer := grpc.NewServer(grpcOpts...)
+
+	// Create WebSocket server with branding configuration
+	// Config validation already rejected malformed entries
+	trustedProxies, _ := netutil.ParseTrustedProxies(cfg.Server.TrustedProxies)
+
+	wsServer := ws.NewServer(agentManager, scheduler, brandingInfo(cfg))
+	wsServer.SetTrustedProxies(trustedProxies)
+	wsServer.SetAllowedOrigins(cfg.Server.AllowedOrigins)
+	wsServer.SetAdminToken(cfg.Admin.Token)
+	wsServer.SetCompression(cfg.Server.WSCompression, cfg.Server.WSCompressionLevel)
+	wsServer.SetPublicStatusLocations(cfg.Server.PublicStatusLocations)
+	wsServer.SetRunTimeout(time.Duration(cfg.Server.RunTimeout) * time.Second)
+	wsServer.SetIdleTimeout(time.Duration(cfg.Server.WSIdleTimeout) * time.Second)
+	wsServer.SetInputLimits(&ws.InputLimits{
+		MaxTargetLength:      cfg.Task.MaxTargetLength,
+		MaxExtraOptions:      cfg.Task.MaxExtraOptions,
+		MaxExtraOptionLength: cfg.Task.MaxExtraOptionLength,
+	})
 
 	masterServer := server.NewMasterServer(
 		agentManager,
 		int32(cfg.Agent.HeartbeatInterval),
 		streamHandler,
 	)
+	masterServer.SetTaskSubmitter(scheduler)
+	masterServer.SetTaskValidator(wsServer) // Same request checks as the WebSocket API
 	pb.RegisterMasterServiceServer(grpcServer, masterServer)
 
 	// Start gRPC server
@@ -171,40 +340,124 @@ func main() {
 		)
 	}
 
+	// Prometheus gauges are read at scrape time
+	metrics.Register(streamRegistry, agentManager, scheduler.GetCurrentTaskCount)
+
+	// Readiness follows the gRPC server; the stream handler is wired above
+	healthChecker := server.NewHealthChecker(streamRegistry)
+
 	go func() {
 		logger.Info("Starting gRPC server",
 			zap.Int("port", cfg.Server.GRPCPort),
 		)
+		// The listener is already bound, so agents connecting from here on are served
+		healthChecker.SetReady(true)
 		if err := grpcServer.Serve(listener); err != nil {
 			logger.Fatal("Failed to serve gRPC", zap.Error(err))
 		}
 	}()
 
-	// Create WebSocket server with branding configuration
-	branding := &ws.BrandingInfo{
-		SiteTitle:  cfg.Branding.SiteTitle,
-		LogoURL:    cfg.Branding.LogoURL,
-		LogoText:   cfg.Branding.LogoText,
-		Subtitle:   cfg.Branding.Subtitle,
-		FooterText: cfg.Branding.FooterText,
+	// Offer canned tasks to users
+	if len(cfg.Templates) > 0 {
+		templates := make([]*task.Template, 0, len(cfg.Templates))
+		for _, tmpl := range cfg.Templates {
+			timeout := tmpl.Timeout
+			if timeout == 0 {
+				timeout = cfg.Task.DefaultTimeout
+			}
+			templates = append(templates, &task.Template{
+				Name:        tmpl.Name,
+				Description: tmpl.Description,
+				TaskName:    tmpl.TaskName,
+				Target:      tmpl.Target,
+				Count:       int32(tmpl.Count),
+				Timeout:     int32(timeout),
+				IPv6:        tmpl.IPv6,
+				Params:      tmpl.Params,
+			})
+		}
+		library, err := task.NewTemplateLibrary(templates)
+		if err != nil {
+			logger.Fatal("Invalid task templates", zap.Error(err))
+		}
+		wsServer.SetTemplates(library)
+		logger.Info("Task templates loaded", zap.Int("templates", len(templates)))
+	}
+
+	// Expose the effective config (secrets redacted) to admins
+	if effective, err := cfg.Redacted().ToMap(); err != nil {
+		logger.Warn("Failed to build effective config view", zap.Error(err))
+	} else {
+		wsServer.SetEffectiveConfig(effective)
+	}
+	wsServer.SetAgentDescriber(streamHandler)
+	wsServer.SetAgentLogFetcher(streamHandler)
+
+	// Keep the latency heatmap fresh with broadcast pings to the canary targets
+	var heatmapRunner *task.HeatmapRunner
+	if cfg.Heatmap.Enabled {
+		count := cfg.Heatmap.Count
+		if count == 0 {
+			count = cfg.Task.DefaultPingCount
+		}
+		timeout := cfg.Heatmap.Timeout
+		if timeout == 0 {
+			timeout = cfg.Task.DefaultTimeout
+		}
+		interval := time.Duration(cfg.Heatmap.Interval) * time.Second
+		heatmapRunner = task.NewHeatmapRunner(scheduler, cfg.Heatmap.Targets, int32(count), int32(timeout), interval)
+		heatmapRunner.Start()
+		wsServer.SetHeatmap(cfg.Heatmap.Targets, interval)
+		logger.Info("Latency heatmap enabled",
+			zap.Strings("targets", cfg.Heatmap.Targets),
+			zap.Duration("interval", interval),
+		)
 	}
-	wsServer := ws.NewServer(agentManager, scheduler, branding)
 
 	// Register agent status change callback to broadcast updates to WebSocket clients
 	agentManager.OnStatusChange(wsServer.BroadcastAgentStatusUpdate)
 
 	// Setup HTTP routes
+	http.HandleFunc("/healthz", healthChecker.HandleHealthz)
+	http.HandleFunc("/readyz", healthChecker.HandleReadyz)
+	http.Handle("/metrics", metrics.Handler())
 	http.HandleFunc("/ws", wsServer.HandleWebSocket)
 	http.HandleFunc("/api/agents", wsServer.HandleAgentList)
+	http.HandleFunc("/api/share", wsServer.HandleCreateShare)
+	http.HandleFunc("/share/", wsServer.HandleShare)
+	http.HandleFunc("/api/templates", wsServer.HandleTemplates)
 	http.HandleFunc("/api/branding", wsServer.HandleBranding)
+	http.HandleFunc("/api/public/status", wsServer.HandlePublicStatus)
+	http.HandleFunc("/api/config", wsServer.HandleConfig)
+	http.HandleFunc("/api/agent/config", wsServer.HandleAgentConfig)
+	http.HandleFunc("/api/agent/metadata", wsServer.HandleAgentMetadata)
+	http.HandleFunc("/api/run", wsServer.HandleRun)
+	http.HandleFunc("/api/heatmap", wsServer.HandleHeatmap)
+	if cfg.Task.HistoryAPI {
+		http.HandleFunc("/api/history", wsServer.HandleHistory)
+	}
 
 	// Serve static files from web/ directory
 	fs := http.FileServer(http.Dir("web"))
-	http.Handle("/", fs)
+
+	// Protect the HTTP/WebSocket surface (health/metrics paths and static assets stay public)
+	publicPaths := cfg.Auth.PublicPaths
+	if publicPaths == nil {
+		publicPaths = auth.DefaultPublicPaths
+	}
+	httpAuth := auth.NewHTTPMiddleware(cfg.Auth.HTTPToken, publicPaths)
+	httpAuth.SetTrustedProxies(trustedProxies)
+
+	// Agents in poll mode authenticate like on the gRPC stream, not with http_token
+	pollHandler := server.NewPollHandler(streamHandler, authenticator, time.Duration(cfg.Agent.HeartbeatTimeout)*time.Second)
+	rootMux := http.NewServeMux()
+	rootMux.Handle("/agent/poll", pollHandler)
+	rootMux.Handle("/", httpAuth.WrapMux(http.DefaultServeMux, fs))
 
 	// Create HTTP server for graceful shutdown
 	httpServer := &http.Server{
-		Addr: fmt.Sprintf(":%d", cfg.Server.WSPort),
+		Addr:    fmt.Sprintf(":%d", cfg.Server.WSPort),
+		Handler: rootMux,
 	}
 
 	// Start HTTP/WebSocket server
@@ -218,12 +471,22 @@ func main() {
 		}
 	}()
 
+	// Reload branding on SIGHUP (invalid config keeps the last-good branding)
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			reloadConfig(wsServer)
+		}
+	}()
+
 	// Wait for shutdown signal
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
 	logger.Info("Shutting down master...")
+	healthChecker.SetReady(false)
 
 	// Graceful shutdown with timeout
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -241,6 +504,9 @@ func main() {
 		// Shutdown other components
 		agentManager.Stop()
 		notificationManager.Stop()
+		if heatmapRunner != nil {
+			heatmapRunner.Stop()
+		}
 
 		// Force stop gRPC server (agents will auto-reconnect after master restarts)
 		// Using Stop() instead of GracefulStop() because:
@@ -262,3 +528,59 @@ func main() {
 
 	logger.Info("Master stopped")
 }
+
+// grpcTLSConfig loads the server certificate and, for mutual TLS, the CA
+// bundle agent certificates are verified against
+func grpcTLSConfig(cfg config.GRPCTLSConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.Mutual {
+		caPEM, err := os.ReadFile(cfg.ClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in client CA %s", cfg.ClientCA)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// brandingInfo converts branding configuration to the format served to the frontend
+func brandingInfo(cfg *config.Config) *ws.BrandingInfo {
+	return &ws.BrandingInfo{
+		SiteTitle:  cfg.Branding.SiteTitle,
+		LogoURL:    cfg.Branding.LogoURL,
+		LogoText:   cfg.Branding.LogoText,
+		Subtitle:   cfg.Branding.Subtitle,
+		FooterText: cfg.Branding.FooterText,
+	}
+}
+
+// reloadConfig re-reads the configuration file and applies settings that support live reload
+func reloadConfig(wsServer *ws.Server) {
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		logger.Error("Config reload failed, keeping current settings",
+			zap.String("path", *configPath),
+			zap.Error(err),
+		)
+		return
+	}
+
+	wsServer.SetBranding(brandingInfo(cfg))
+
+	logger.Info("Configuration reloaded",
+		zap.String("path", *configPath),
+		zap.String("site_title", cfg.Branding.SiteTitle),
+	)
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lureiny/lookingglass/master/agent"
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "lookingglass"
+
+var (
+	tasksSubmitted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_submitted_total",
+		Help:      "Tasks accepted by the scheduler.",
+	})
+
+	tasksFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_finished_total",
+		Help:      "Tasks that reached a final status, by status (completed, failed, cancelled).",
+	}, []string{"status"})
+
+	taskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "task_duration_seconds",
+		Help:      "Time from submission to final status, by status.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"status"})
+)
+
+// TaskSubmitted counts a task accepted by the scheduler
+func TaskSubmitted() {
+	tasksSubmitted.Inc()
+}
+
+// TaskFinished counts a task reaching a final status and records how long it took
+func TaskFinished(status pb.TaskStatus, duration time.Duration) {
+	label := strings.ToLower(strings.TrimPrefix(status.String(), "TASK_STATUS_"))
+	tasksFinished.WithLabelValues(label).Inc()
+	taskDuration.WithLabelValues(label).Observe(duration.Seconds())
+}
+
+// Register adds gauges read from the agent registries and the scheduler at scrape time
+func Register(streams *agent.StreamRegistry, agents *agent.Manager, runningTasks func() int) {
+	register(prometheus.DefaultRegisterer, streams, agents, runningTasks)
+}
+
+// register adds the scrape-time gauges to registerer
+func register(registerer prometheus.Registerer, streams *agent.StreamRegistry, agents *agent.Manager, runningTasks func() int) {
+	registerer.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "agents_connected",
+			Help:      "Agents with an open stream to the master.",
+		}, func() float64 {
+			return float64(streams.GetConnectedAgentCount())
+		}),
+		agentStatusGauge(agents, "online", true),
+		agentStatusGauge(agents, "offline", false),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "tasks_running",
+			Help:      "Tasks currently running.",
+		}, func() float64 {
+			return float64(runningTasks())
+		}),
+	)
+}
+
+// agentStatusGauge counts known agents that are (or are not) online
+func agentStatusGauge(agents *agent.Manager, status string, online bool) prometheus.GaugeFunc {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "agents",
+		Help:        "Known agents by status.",
+		ConstLabels: prometheus.Labels{"status": status},
+	}, func() float64 {
+		count := 0
+		for _, ag := range agents.GetAllAgents() {
+			if (ag.Status == pb.AgentStatus_AGENT_STATUS_ONLINE) == online {
+				count++
+			}
+		}
+		return float64(count)
+	})
+}
+
+// Handler serves the default registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
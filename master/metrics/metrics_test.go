@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lureiny/lookingglass/master/agent"
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func TestTaskCounters(t *testing.T) {
+	submitted := testutil.ToFloat64(tasksSubmitted)
+	TaskSubmitted()
+	if got := testutil.ToFloat64(tasksSubmitted); got != submitted+1 {
+		t.Errorf("tasks_submitted_total = %v, want %v", got, submitted+1)
+	}
+
+	failed := testutil.ToFloat64(tasksFinished.WithLabelValues("failed"))
+	TaskFinished(pb.TaskStatus_TASK_STATUS_FAILED, 3*time.Second)
+	if got := testutil.ToFloat64(tasksFinished.WithLabelValues("failed")); got != failed+1 {
+		t.Errorf(`tasks_finished_total{status="failed"} = %v, want %v`, got, failed+1)
+	}
+}
+
+func TestGauges(t *testing.T) {
+	agents := agent.NewManager(time.Minute, time.Minute)
+	t.Cleanup(agents.Stop)
+	if err := agents.RegisterAgentFromStream(&pb.AgentInfo{Id: "a1"}); err != nil {
+		t.Fatal(err)
+	}
+	registry := prometheus.NewRegistry()
+	register(registry, agent.NewStreamRegistry(zap.NewNop()), agents, func() int { return 3 })
+
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		"lookingglass_agents_connected 0",
+		`lookingglass_agents{status="online"} 1`,
+		`lookingglass_agents{status="offline"} 0`,
+		"lookingglass_tasks_running 3",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("/metrics is missing %q", want)
+		}
+	}
+}
@@ -19,6 +19,7 @@ type BarkConfig struct {
 	Sound     string // Notification sound (optional)
 	Icon      string // Notification icon URL (optional)
 	Group     string // Notification group (optional)
+	MaxBody   int    // Maximum body size in bytes (0 = BarkMaxBodyBytes, negative = unlimited)
 }
 
 // BarkNotifier implements the Notifier interface for Bark
@@ -103,16 +104,23 @@ func (b *BarkNotifier) Send(ctx context.Context, event *Event) error {
 		}
 	}
 
+	// Fit the provider payload limit
+	maxBody := b.config.MaxBody
+	if maxBody == 0 {
+		maxBody = BarkMaxBodyBytes
+	}
+	message.Body = TruncateBytes(message.Body, maxBody)
+
 	// Serialize message
 	jsonData, err := json.Marshal(message)
 	if err != nil {
-		return fmt.Errorf("failed to marshal bark message: %w", err)
+		return Permanent(fmt.Errorf("failed to marshal bark message: %w", err))
 	}
 
 	// Create HTTP request
 	req, err := http.NewRequestWithContext(ctx, "POST", b.config.ServerURL, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return Permanent(fmt.Errorf("failed to create request: %w", err))
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -126,7 +134,12 @@ func (b *BarkNotifier) Send(ctx context.Context, event *Event) error {
 
 	// Check response
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("bark API returned status %d", resp.StatusCode)
+		err := fmt.Errorf("bark API returned status %d", resp.StatusCode)
+		// Client errors (bad key, malformed request) fail the same way on every attempt
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return Permanent(err)
+		}
+		return err
 	}
 
 	logger.Debug("Bark notification sent successfully",
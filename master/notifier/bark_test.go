@@ -0,0 +1,46 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBarkTruncatesBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxBody int
+		wantLen int
+	}{
+		{"default limit", 0, BarkMaxBodyBytes},
+		{"configured limit", 100, 100},
+		{"unlimited", -1, 5000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got BarkMessage
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decode: %v", err)
+				}
+				w.Write([]byte(`{"code":200}`))
+			}))
+			defer srv.Close()
+
+			b, err := NewBarkNotifier(&BarkConfig{ServerURL: srv.URL, MaxBody: tt.maxBody})
+			if err != nil {
+				t.Fatalf("NewBarkNotifier() error = %v", err)
+			}
+			event := &Event{Type: EventTaskFailed, Title: "failed", Message: strings.Repeat("x", 5000)}
+			if err := b.Send(context.Background(), event); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if len(got.Body) != tt.wantLen {
+				t.Errorf("body is %d bytes, want %d", len(got.Body), tt.wantLen)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package notifier
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// dedupState suppresses repeated events and holds offline events until they are confirmed
+type dedupState struct {
+	mutex          sync.Mutex
+	window         time.Duration          // Identical events within this window are dropped (0 = off)
+	offlineConfirm time.Duration          // Agent offline events wait this long before firing (0 = immediately)
+	lastSent       map[string]time.Time   // Dedup key -> time the event was last queued
+	pendingOffline map[string]*time.Timer // Agent ID -> timer firing its offline event
+}
+
+// SetDedupWindow drops events identical to one queued within window (same type and agent_id)
+// A window <= 0 disables deduplication
+func (m *Manager) SetDedupWindow(window time.Duration) {
+	m.dedup.mutex.Lock()
+	defer m.dedup.mutex.Unlock()
+	m.dedup.window = window
+}
+
+// SetOfflineConfirm delays agent offline events by delay; an agent back online before then
+// fires neither its offline nor its online event
+// A delay <= 0 sends offline events immediately
+func (m *Manager) SetOfflineConfirm(delay time.Duration) {
+	m.dedup.mutex.Lock()
+	defer m.dedup.mutex.Unlock()
+	m.dedup.offlineConfirm = delay
+}
+
+// dedupKey identifies events that count as identical
+func dedupKey(event *Event) string {
+	return string(event.Type) + "|" + event.Metadata["agent_id"]
+}
+
+// hold delays an offline event for confirmation or drops an online event ending an unconfirmed
+// offline period, reporting whether the event was taken over
+func (m *Manager) hold(event *Event) bool {
+	agentID := event.Metadata["agent_id"]
+
+	m.dedup.mutex.Lock()
+	defer m.dedup.mutex.Unlock()
+
+	if m.dedup.offlineConfirm <= 0 || agentID == "" {
+		return false
+	}
+
+	switch event.Type {
+	case EventAgentOffline:
+		if _, pending := m.dedup.pendingOffline[agentID]; pending {
+			return true
+		}
+		m.dedup.pendingOffline[agentID] = time.AfterFunc(m.dedup.offlineConfirm, func() {
+			m.dedup.mutex.Lock()
+			delete(m.dedup.pendingOffline, agentID)
+			m.dedup.mutex.Unlock()
+			m.enqueue(event)
+		})
+		return true
+
+	case EventAgentOnline:
+		timer, pending := m.dedup.pendingOffline[agentID]
+		if !pending {
+			return false
+		}
+		timer.Stop()
+		delete(m.dedup.pendingOffline, agentID)
+		logger.Debug("Agent back online before offline was confirmed, suppressing both notifications",
+			zap.String("agent_id", agentID),
+		)
+		return true
+	}
+	return false
+}
+
+// isDuplicate reports whether an identical event was queued within the dedup window,
+// recording event as the latest otherwise
+func (m *Manager) isDuplicate(event *Event) bool {
+	m.dedup.mutex.Lock()
+	defer m.dedup.mutex.Unlock()
+
+	if m.dedup.window <= 0 {
+		return false
+	}
+
+	key := dedupKey(event)
+	if last, ok := m.dedup.lastSent[key]; ok && event.Timestamp.Sub(last) < m.dedup.window {
+		logger.Debug("Dropping duplicate notification",
+			zap.String("type", string(event.Type)),
+			zap.String("agent_id", event.Metadata["agent_id"]),
+			zap.Duration("since_last", event.Timestamp.Sub(last)),
+		)
+		return true
+	}
+
+	m.dedup.lastSent[key] = event.Timestamp
+	for k, t := range m.dedup.lastSent {
+		if event.Timestamp.Sub(t) >= m.dedup.window {
+			delete(m.dedup.lastSent, k)
+		}
+	}
+	return false
+}
+
+// stopPending discards offline events still waiting for confirmation
+func (m *Manager) stopPending() {
+	m.dedup.mutex.Lock()
+	defer m.dedup.mutex.Unlock()
+
+	for agentID, timer := range m.dedup.pendingOffline {
+		timer.Stop()
+		delete(m.dedup.pendingOffline, agentID)
+	}
+}
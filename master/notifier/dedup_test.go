@@ -0,0 +1,124 @@
+package notifier
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// chanNotifier hands every event it is sent to the test
+type chanNotifier chan *Event
+
+func (n chanNotifier) Name() string { return "chan" }
+
+func (n chanNotifier) Send(ctx context.Context, event *Event) error {
+	n <- event
+	return nil
+}
+
+func (n chanNotifier) Close() error { return nil }
+
+// newDedupManager starts a manager delivering to the returned channel
+func newDedupManager(t *testing.T, configure func(m *Manager)) (*Manager, chanNotifier) {
+	t.Helper()
+	n := make(chanNotifier, 100)
+	m := NewManager()
+	m.RegisterNotifier(n)
+	configure(m)
+	m.Start()
+	t.Cleanup(m.Stop)
+	return m, n
+}
+
+// received waits for want events, then gives any extra ones a moment to show up
+func received(t *testing.T, n chanNotifier, want int) []*Event {
+	t.Helper()
+	var events []*Event
+	timeout := time.After(2 * time.Second)
+	for len(events) < want {
+		select {
+		case event := <-n:
+			events = append(events, event)
+		case <-timeout:
+			t.Fatalf("received %d events, want %d", len(events), want)
+		}
+	}
+	for {
+		select {
+		case event := <-n:
+			events = append(events, event)
+		case <-time.After(50 * time.Millisecond):
+			return events
+		}
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	m, n := newDedupManager(t, func(m *Manager) { m.SetDedupWindow(time.Minute) })
+
+	start := time.Now()
+	notify := func(event *Event, at time.Duration) {
+		event.Timestamp = start.Add(at)
+		m.Notify(event)
+	}
+	notify(NewAgentErrorEvent("agent-1", "a1", "disk full"), 0)
+	notify(NewAgentErrorEvent("agent-1", "a1", "disk full"), 10*time.Second) // duplicate
+	notify(NewAgentErrorEvent("agent-2", "a2", "disk full"), 20*time.Second) // another agent
+	notify(NewAgentOnlineEvent("agent-1", "a1", "Tokyo"), 30*time.Second)    // another type
+	notify(NewAgentErrorEvent("agent-1", "a1", "disk full"), 2*time.Minute)  // window passed
+
+	if got := len(received(t, n, 4)); got != 4 {
+		t.Errorf("received %d events, want 4 (one duplicate dropped)", got)
+	}
+}
+
+func TestDedupWindowDisabled(t *testing.T) {
+	m, n := newDedupManager(t, func(*Manager) {})
+	for range 3 {
+		m.Notify(NewAgentErrorEvent("agent-1", "a1", "disk full"))
+	}
+	if got := len(received(t, n, 3)); got != 3 {
+		t.Errorf("received %d events without a dedup window, want 3", got)
+	}
+}
+
+func TestOfflineConfirm(t *testing.T) {
+	m, n := newDedupManager(t, func(m *Manager) { m.SetOfflineConfirm(50 * time.Millisecond) })
+
+	// Back online before the offline event is confirmed: neither is sent
+	m.Notify(NewAgentOfflineEvent("agent-1", "a1", "Tokyo"))
+	m.Notify(NewAgentOnlineEvent("agent-1", "a1", "Tokyo"))
+	time.Sleep(100 * time.Millisecond)
+	if events := received(t, n, 0); len(events) != 0 {
+		t.Errorf("flapping agent sent %d events, want 0", len(events))
+	}
+
+	// Still offline once the delay passes: the offline event is sent once
+	m.Notify(NewAgentOfflineEvent("agent-2", "a2", "Tokyo"))
+	m.Notify(NewAgentOfflineEvent("agent-2", "a2", "Tokyo"))
+	events := received(t, n, 1)
+	if len(events) != 1 || events[0].Type != EventAgentOffline {
+		t.Fatalf("confirmed offline events = %v, want one agent_offline", events)
+	}
+
+	// An online event after a confirmed offline one goes through
+	m.Notify(NewAgentOnlineEvent("agent-2", "a2", "Tokyo"))
+	if events := received(t, n, 1); len(events) != 1 || events[0].Type != EventAgentOnline {
+		t.Errorf("online after confirmed offline = %v, want one agent_online", events)
+	}
+}
+
+func TestStopDiscardsPendingOffline(t *testing.T) {
+	n := make(chanNotifier, 1)
+	m := NewManager()
+	m.RegisterNotifier(n)
+	m.SetOfflineConfirm(50 * time.Millisecond)
+	m.Start()
+
+	m.Notify(NewAgentOfflineEvent("agent-1", "a1", "Tokyo"))
+	m.Stop()
+	time.Sleep(100 * time.Millisecond)
+	if events := received(t, n, 0); len(events) != 0 {
+		t.Errorf("offline event sent after Stop: %v", events)
+	}
+}
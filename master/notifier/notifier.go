@@ -3,12 +3,20 @@ package notifier
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/lureiny/lookingglass/pkg/logger"
 	"go.uber.org/zap"
 )
 
+const (
+	// drainTimeout bounds how long Stop waits for queued and in-flight notifications
+	drainTimeout = 5 * time.Second
+	// sendTimeout bounds the delivery of one event to all notifiers, retries included
+	sendTimeout = 10 * time.Second
+)
+
 // EventType represents the type of notification event
 type EventType string
 
@@ -55,6 +63,12 @@ type Manager struct {
 	enabled   bool
 	eventChan chan *Event
 	stopChan  chan struct{}
+	doneChan  chan struct{}  // closed when processEvents has drained and exited
+	inflight  sync.WaitGroup // notifier sends in progress
+	dedup     dedupState     // duplicate and flapping suppression
+
+	retryTimes    int           // Extra attempts after a failed send
+	retryInterval time.Duration // Wait before the first retry, doubled after each
 }
 
 // NewManager creates a new notification manager
@@ -64,6 +78,11 @@ func NewManager() *Manager {
 		enabled:   false,
 		eventChan: make(chan *Event, 100), // Buffer for 100 events
 		stopChan:  make(chan struct{}),
+		doneChan:  make(chan struct{}),
+		dedup: dedupState{
+			lastSent:       make(map[string]time.Time),
+			pendingOffline: make(map[string]*time.Timer),
+		},
 	}
 }
 
@@ -93,14 +112,24 @@ func (m *Manager) Start() {
 }
 
 // Stop stops the notification manager
+// Queued and in-flight notifications are flushed first, bounded by drainTimeout
 func (m *Manager) Stop() {
 	if !m.enabled {
 		return
 	}
 
 	logger.Info("Stopping notification manager")
+	m.stopPending()
 	close(m.stopChan)
 
+	select {
+	case <-m.doneChan:
+	case <-time.After(drainTimeout):
+		logger.Warn("Timed out draining notifications",
+			zap.Int("queued", len(m.eventChan)),
+		)
+	}
+
 	// Close all notifiers
 	for _, notifier := range m.notifiers {
 		if err := notifier.Close(); err != nil {
@@ -128,6 +157,18 @@ func (m *Manager) Notify(event *Event) {
 		event.Timestamp = time.Now()
 	}
 
+	if m.hold(event) {
+		return
+	}
+	m.enqueue(event)
+}
+
+// enqueue queues an event for the notifiers unless it duplicates a recent one
+func (m *Manager) enqueue(event *Event) {
+	if m.isDuplicate(event) {
+		return
+	}
+
 	// Send to event channel (non-blocking)
 	select {
 	case m.eventChan <- event:
@@ -142,25 +183,47 @@ func (m *Manager) Notify(event *Event) {
 
 // processEvents processes notification events in a separate goroutine
 func (m *Manager) processEvents() {
+	defer close(m.doneChan)
+
 	for {
 		select {
 		case event := <-m.eventChan:
 			m.sendToNotifiers(event)
 
 		case <-m.stopChan:
+			m.drain()
 			logger.Info("Notification manager stopped")
 			return
 		}
 	}
 }
 
+// drain sends events still queued at shutdown and waits for in-flight sends
+func (m *Manager) drain() {
+	for {
+		select {
+		case event := <-m.eventChan:
+			m.sendToNotifiers(event)
+		default:
+			m.inflight.Wait()
+			return
+		}
+	}
+}
+
 // sendToNotifiers sends an event to all registered notifiers
 func (m *Manager) sendToNotifiers(event *Event) {
-	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
 
+	var sends sync.WaitGroup
 	for _, notifier := range m.notifiers {
+		m.inflight.Add(1)
+		sends.Add(1)
 		go func(n Notifier) {
-			if err := n.Send(ctx, event); err != nil {
+			defer m.inflight.Done()
+			defer sends.Done()
+
+			if err := m.sendWithRetry(ctx, n, event); err != nil {
 				logger.Error("Failed to send notification",
 					zap.String("notifier", n.Name()),
 					zap.String("event_type", string(event.Type)),
@@ -175,6 +238,12 @@ func (m *Manager) sendToNotifiers(event *Event) {
 			}
 		}(notifier)
 	}
+
+	// Release the shared deadline once every notifier is done
+	go func() {
+		sends.Wait()
+		cancel()
+	}()
 }
 
 // Helper functions to create common events
@@ -209,6 +278,22 @@ func NewAgentOfflineEvent(agentID, agentName, location string) *Event {
 	}
 }
 
+// NewTaskFailedEvent creates a task failed event
+func NewTaskFailedEvent(taskID, agentID, target, errorMsg string) *Event {
+	return &Event{
+		Type:     EventTaskFailed,
+		Title:    fmt.Sprintf("Task Failed: %s", taskID),
+		Message:  fmt.Sprintf("Task '%s' on agent '%s' (target: %s) failed: %s", taskID, agentID, target, errorMsg),
+		Priority: 1,
+		Metadata: map[string]string{
+			"task_id":  taskID,
+			"agent_id": agentID,
+			"target":   target,
+			"error":    errorMsg,
+		},
+	}
+}
+
 // NewAgentErrorEvent creates an agent error event
 func NewAgentErrorEvent(agentID, agentName, errorMsg string) *Event {
 	return &Event{
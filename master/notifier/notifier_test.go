@@ -0,0 +1,60 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowNotifier records events after a delay, like a provider behind a slow network
+type slowNotifier struct {
+	delay time.Duration
+
+	mu     sync.Mutex
+	sent   []string
+	closed bool
+	late   bool // an event arrived after Close
+}
+
+func (n *slowNotifier) Name() string { return "slow" }
+
+func (n *slowNotifier) Send(ctx context.Context, event *Event) error {
+	time.Sleep(n.delay)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		n.late = true
+	}
+	n.sent = append(n.sent, event.Title)
+	return nil
+}
+
+func (n *slowNotifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.closed = true
+	return nil
+}
+
+func TestStopFlushesQueuedNotifications(t *testing.T) {
+	n := &slowNotifier{delay: 20 * time.Millisecond}
+	m := NewManager()
+	m.RegisterNotifier(n)
+	m.Start()
+
+	for i := range 5 {
+		m.Notify(NewTaskFailedEvent(fmt.Sprintf("t%d", i), "agent-1", "192.0.2.1", "timeout"))
+	}
+	m.Stop()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.sent) != 5 {
+		t.Errorf("sent %d notifications before stopping, want 5", len(n.sent))
+	}
+	if !n.closed || n.late {
+		t.Errorf("closed = %v, late send = %v; want sends done before Close", n.closed, n.late)
+	}
+}
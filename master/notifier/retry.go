@@ -0,0 +1,76 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// PermanentError marks a send failure that retrying cannot fix (e.g. an HTTP 4xx response)
+// Notifiers wrap such errors with Permanent; any other error is retried
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err so the manager does not retry it
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// isPermanent reports whether err was marked as not worth retrying
+func isPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// SetRetry retries a failed send up to times more, waiting interval before the first retry and
+// doubling the wait after each; retries stop when the send timeout expires
+func (m *Manager) SetRetry(times int, interval time.Duration) {
+	if times < 0 {
+		times = 0
+	}
+	m.retryTimes = times
+	m.retryInterval = interval
+}
+
+// sendWithRetry sends event through n, retrying transient failures with exponential backoff
+func (m *Manager) sendWithRetry(ctx context.Context, n Notifier, event *Event) error {
+	delay := m.retryInterval
+	for attempt := 0; ; attempt++ {
+		err := n.Send(ctx, event)
+		if err == nil || isPermanent(err) || attempt >= m.retryTimes {
+			return err
+		}
+
+		logger.Debug("Notification send failed, retrying",
+			zap.String("notifier", n.Name()),
+			zap.String("event_type", string(event.Type)),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,124 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// flakyNotifier fails its first sends (up to failures) with err
+type flakyNotifier struct {
+	failures int
+	err      error
+	attempts int
+}
+
+func (n *flakyNotifier) Name() string { return "flaky" }
+
+func (n *flakyNotifier) Send(ctx context.Context, event *Event) error {
+	n.attempts++
+	if n.attempts <= n.failures {
+		return n.err
+	}
+	return nil
+}
+
+func (n *flakyNotifier) Close() error { return nil }
+
+func TestSendWithRetry(t *testing.T) {
+	transient := errors.New("connection reset")
+	tests := []struct {
+		name         string
+		retries      int
+		failures     int
+		err          error
+		wantErr      bool
+		wantAttempts int
+	}{
+		{"succeeds after retries", 3, 2, transient, false, 3},
+		{"gives up after retries", 2, 5, transient, true, 3},
+		{"no retries configured", 0, 1, transient, true, 1},
+		{"permanent error not retried", 3, 5, Permanent(errors.New("bad key")), true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager()
+			m.SetRetry(tt.retries, time.Millisecond)
+			n := &flakyNotifier{failures: tt.failures, err: tt.err}
+
+			err := m.sendWithRetry(context.Background(), n, &Event{Type: EventTaskFailed})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("sendWithRetry() error = %v, want error %v", err, tt.wantErr)
+			}
+			if n.attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", n.attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestSendWithRetryBacksOff(t *testing.T) {
+	m := NewManager()
+	m.SetRetry(2, 20*time.Millisecond)
+	n := &flakyNotifier{failures: 2, err: errors.New("timeout")}
+
+	// Waits 20ms, then 40ms
+	start := time.Now()
+	if err := m.sendWithRetry(context.Background(), n, &Event{Type: EventTaskFailed}); err != nil {
+		t.Fatalf("sendWithRetry() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("retries took %s, want at least 60ms of backoff", elapsed)
+	}
+}
+
+func TestSendWithRetryStopsAtDeadline(t *testing.T) {
+	m := NewManager()
+	m.SetRetry(5, time.Hour)
+	n := &flakyNotifier{failures: 10, err: errors.New("timeout")}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.sendWithRetry(ctx, n, &Event{Type: EventTaskFailed}); err == nil {
+		t.Error("sendWithRetry() succeeded past the send timeout")
+	}
+	if n.attempts != 1 {
+		t.Errorf("attempts = %d, want 1", n.attempts)
+	}
+}
+
+func TestBarkClientErrorsArePermanent(t *testing.T) {
+	tests := []struct {
+		status    int
+		permanent bool
+	}{
+		{http.StatusBadRequest, true},
+		{http.StatusNotFound, true},
+		{http.StatusTooManyRequests, false},
+		{http.StatusBadGateway, false},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+
+		b, err := NewBarkNotifier(&BarkConfig{ServerURL: srv.URL})
+		if err != nil {
+			srv.Close()
+			t.Fatalf("NewBarkNotifier() error = %v", err)
+		}
+		err = b.Send(context.Background(), &Event{Type: EventTaskFailed, Title: "failed"})
+		srv.Close()
+		if err == nil {
+			t.Errorf("status %d: Send() succeeded", tt.status)
+			continue
+		}
+		if isPermanent(err) != tt.permanent {
+			t.Errorf("status %d: permanent = %v, want %v", tt.status, isPermanent(err), tt.permanent)
+		}
+	}
+}
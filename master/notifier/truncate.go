@@ -0,0 +1,26 @@
+package notifier
+
+import "unicode/utf8"
+
+// TruncatedMarker is appended to messages cut to fit a provider's size limit
+const TruncatedMarker = "…[truncated]"
+
+// BarkMaxBodyBytes keeps the Bark body well inside the 4KB APNs payload limit
+const BarkMaxBodyBytes = 3000
+
+// TruncateBytes shortens text to at most maxBytes bytes, including the truncation marker
+// Cuts on a UTF-8 rune boundary; maxBytes <= 0 disables truncation
+func TruncateBytes(text string, maxBytes int) string {
+	if maxBytes <= 0 || len(text) <= maxBytes {
+		return text
+	}
+
+	keep := maxBytes - len(TruncatedMarker)
+	if keep <= 0 {
+		return ""
+	}
+	for keep > 0 && !utf8.RuneStart(text[keep]) {
+		keep--
+	}
+	return text[:keep] + TruncatedMarker
+}
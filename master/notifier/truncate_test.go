@@ -0,0 +1,43 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateBytes(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	tests := []struct {
+		name     string
+		text     string
+		maxBytes int
+		want     string
+	}{
+		{"fits", "short", 10, "short"},
+		{"exact fit", long, 100, long},
+		{"disabled", long, 0, long},
+		{"truncated", long, 30, strings.Repeat("a", 30-len(TruncatedMarker)) + TruncatedMarker},
+		{"limit below marker", long, 5, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TruncateBytes(tt.text, tt.maxBytes); got != tt.want {
+				t.Errorf("TruncateBytes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateBytesKeepsRunesWhole(t *testing.T) {
+	text := strings.Repeat("日本語", 20)
+	for maxBytes := len(TruncatedMarker) + 1; maxBytes < len(text); maxBytes++ {
+		got := TruncateBytes(text, maxBytes)
+		if len(got) > maxBytes {
+			t.Fatalf("maxBytes %d: got %d bytes", maxBytes, len(got))
+		}
+		if !utf8.ValidString(got) || !strings.HasSuffix(got, TruncatedMarker) {
+			t.Fatalf("maxBytes %d: got %q, want valid UTF-8 ending in the marker", maxBytes, got)
+		}
+	}
+}
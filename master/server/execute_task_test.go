@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/lureiny/lookingglass/master/task"
+	pb "github.com/lureiny/lookingglass/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// echoAgent answers every dispatched task with lines output lines and a COMPLETED status
+type echoAgent struct {
+	scheduler *task.Scheduler
+	lines     int
+}
+
+func (a *echoAgent) SendTaskToAgent(agentID string, t *pb.Task) error {
+	go func() {
+		for i := 0; i < a.lines; i++ {
+			a.scheduler.HandleTaskOutput(&pb.TaskOutput{
+				TaskId:     t.TaskId,
+				OutputLine: fmt.Sprintf("line %d", i),
+				Status:     pb.TaskStatus_TASK_STATUS_RUNNING,
+			})
+		}
+		a.scheduler.HandleTaskOutput(&pb.TaskOutput{TaskId: t.TaskId, Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+	}()
+	return nil
+}
+
+func (a *echoAgent) CancelTaskOnAgent(agentID string, taskID string) error { return nil }
+
+type rejectValidator struct{}
+
+func (rejectValidator) ValidateTask(t *pb.Task) error {
+	if t.GetNetworkTest().GetTarget() == "" {
+		return errors.New("target is required")
+	}
+	return nil
+}
+
+// startMasterService serves s over an in-memory listener and returns a connected client
+func startMasterService(t *testing.T, s *MasterServer) pb.MasterServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	pb.RegisterMasterServiceServer(grpcServer, s)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewMasterServiceClient(conn)
+}
+
+func newExecuteTaskServer(t *testing.T, lines int) pb.MasterServiceClient {
+	t.Helper()
+	am := newSeededManager(t, &pb.AgentInfo{Id: "agent-1", MaxConcurrent: 5})
+	scheduler := task.NewScheduler(am, 10)
+	scheduler.SetStreamSender(&echoAgent{scheduler: scheduler, lines: lines})
+
+	s := NewMasterServer(am, 30, nil)
+	s.SetTaskSubmitter(scheduler)
+	s.SetTaskValidator(rejectValidator{})
+	return startMasterService(t, s)
+}
+
+func TestExecuteTaskStreamsEveryOutput(t *testing.T) {
+	// More lines than the per-stream buffer: none may be dropped
+	const lines = apiOutputBuffer * 3
+	client := newExecuteTaskServer(t, lines)
+
+	stream, err := client.ExecuteTask(context.Background(), &pb.ExecuteTaskRequest{Task: &pb.Task{
+		AgentId:  "agent-1",
+		TaskName: "ping",
+		Params:   &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{Target: "192.0.2.1"}},
+	}})
+	if err != nil {
+		t.Fatalf("ExecuteTask() error = %v", err)
+	}
+
+	var got []string
+	var final pb.TaskStatus
+	for {
+		output, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		if output.OutputLine != "" {
+			got = append(got, output.OutputLine)
+		}
+		final = output.Status
+	}
+
+	if len(got) != lines {
+		t.Fatalf("received %d lines, want %d", len(got), lines)
+	}
+	for i, line := range got {
+		if line != fmt.Sprintf("line %d", i) {
+			t.Fatalf("line %d = %q, out of order", i, line)
+		}
+	}
+	if final != pb.TaskStatus_TASK_STATUS_COMPLETED {
+		t.Errorf("final status = %s, want COMPLETED", final)
+	}
+}
+
+func TestExecuteTaskRejectsInvalidTask(t *testing.T) {
+	client := newExecuteTaskServer(t, 0)
+
+	stream, err := client.ExecuteTask(context.Background(), &pb.ExecuteTaskRequest{Task: &pb.Task{
+		AgentId:  "agent-1",
+		TaskName: "ping",
+		Params:   &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{}},
+	}})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("error = %v, want InvalidArgument", err)
+	}
+
+	stream, err = client.ExecuteTask(context.Background(), &pb.ExecuteTaskRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("missing task error = %v, want InvalidArgument", err)
+	}
+}
@@ -3,19 +3,44 @@ package server
 import (
 	"context"
 	"fmt"
+	"sort"
 
+	"github.com/google/uuid"
 	"github.com/lureiny/lookingglass/master/agent"
+	"github.com/lureiny/lookingglass/master/task"
 	"github.com/lureiny/lookingglass/pkg/logger"
 	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/netutil"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// apiOutputBuffer is the number of task outputs buffered per ExecuteTask stream
+// Once it is full the scheduler waits for the client to catch up
+const apiOutputBuffer = 256
+
+// TaskSubmitter submits tasks on behalf of API clients (typically the scheduler)
+type TaskSubmitter interface {
+	SubmitTask(ctx context.Context, task *pb.Task, clientID string, outputHandler func(*pb.TaskOutput)) error
+	CancelTask(taskID string) error
+}
+
+// TaskValidator checks a task request before it is submitted
+type TaskValidator interface {
+	ValidateTask(task *pb.Task) error
+}
+
 // MasterServer implements the MasterService gRPC server
 type MasterServer struct {
 	pb.UnimplementedMasterServiceServer
 	agentManager      *agent.Manager
 	heartbeatInterval int32 // seconds
 	streamHandler     *StreamHandler
+	taskSubmitter     TaskSubmitter // Serves ExecuteTask (nil = unavailable)
+	taskValidator     TaskValidator // Checks ExecuteTask requests (nil = no checks)
 }
 
 // NewMasterServer creates a new master gRPC server
@@ -27,6 +52,16 @@ func NewMasterServer(agentManager *agent.Manager, heartbeatInterval int32, strea
 	}
 }
 
+// SetTaskSubmitter sets the submitter used by the ExecuteTask RPC
+func (s *MasterServer) SetTaskSubmitter(submitter TaskSubmitter) {
+	s.taskSubmitter = submitter
+}
+
+// SetTaskValidator sets the checks ExecuteTask requests must pass
+func (s *MasterServer) SetTaskValidator(validator TaskValidator) {
+	s.taskValidator = validator
+}
+
 // Register handles agent registration requests
 func (s *MasterServer) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
 	agentInfo := req.AgentInfo
@@ -101,3 +136,145 @@ func (s *MasterServer) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest)
 func (s *MasterServer) AgentStream(stream pb.MasterService_AgentStreamServer) error {
 	return s.streamHandler.AgentStream(stream)
 }
+
+// ListAgents returns the agent inventory, sorted by ID
+func (s *MasterServer) ListAgents(ctx context.Context, req *pb.ListAgentsRequest) (*pb.ListAgentsResponse, error) {
+	agents := s.agentManager.GetAllAgents()
+
+	infos := make([]*pb.AgentStatusInfo, 0, len(agents))
+	for _, ag := range agents {
+		if req.OnlineOnly && ag.Status != pb.AgentStatus_AGENT_STATUS_ONLINE {
+			continue
+		}
+		infos = append(infos, s.agentManager.ApplyMetadataOverride(agentStatusInfo(ag)))
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Id < infos[j].Id
+	})
+
+	return &pb.ListAgentsResponse{Agents: infos}, nil
+}
+
+// GetAgentDetail returns the status of a single agent
+func (s *MasterServer) GetAgentDetail(ctx context.Context, req *pb.GetAgentDetailRequest) (*pb.AgentStatusInfo, error) {
+	if req.AgentId == "" {
+		return nil, status.Error(codes.InvalidArgument, "agent_id is required")
+	}
+
+	ag, err := s.agentManager.GetAgent(req.AgentId)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "agent not found: %s", req.AgentId)
+	}
+
+	info := s.agentManager.ApplyMetadataOverride(agentStatusInfo(ag))
+	transitions, _ := s.agentManager.GetTransitions(req.AgentId)
+	for _, t := range transitions {
+		info.Transitions = append(info.Transitions, &pb.AgentTransition{
+			Time:   timestamppb.New(t.Time),
+			Status: t.Status,
+			Reason: t.Reason,
+		})
+	}
+	return info, nil
+}
+
+// agentStatusInfo converts an agent to its public status
+// IP addresses are omitted for agents that hide them
+func agentStatusInfo(ag *agent.Agent) *pb.AgentStatusInfo {
+	info := &pb.AgentStatusInfo{
+		Id:              ag.Info.Id,
+		Name:            ag.Info.Name,
+		Location:        ag.Info.Location,
+		Ipv4:            netutil.DisplayIP(ag.Info.Ipv4, ag.Info.HideIp),
+		Ipv6:            netutil.DisplayIP(ag.Info.Ipv6, ag.Info.HideIp),
+		Status:          ag.Status,
+		TaskDisplayInfo: ag.Info.TaskDisplayInfo,
+		CurrentTasks:    ag.CurrentTasks,
+		MaxConcurrent:   ag.Info.MaxConcurrent,
+		Provider:        ag.Info.Provider,
+		Idc:             ag.Info.Idc,
+		Description:     ag.Info.Description,
+		Tags:            ag.Info.Tags,
+		MemPercent:      ag.MemPercent,
+		DiskPercent:     ag.DiskPercent,
+		TaskConcurrency: ag.TaskUsage,
+		Degraded:        !ag.DegradedUntil.IsZero(),
+	}
+	return info
+}
+
+// ExecuteTask submits a task through the scheduler and streams its output until a final status
+// The task is cancelled if the client goes away first
+func (s *MasterServer) ExecuteTask(req *pb.ExecuteTaskRequest, stream pb.MasterService_ExecuteTaskServer) error {
+	if s.taskSubmitter == nil {
+		return status.Error(codes.Unavailable, "task execution is not available")
+	}
+
+	t := req.GetTask()
+	if t == nil || t.TaskName == "" {
+		return status.Error(codes.InvalidArgument, "task with task_name is required")
+	}
+	if t.TaskId == "" {
+		t.TaskId = uuid.New().String()
+	}
+	if s.taskValidator != nil {
+		if err := s.taskValidator.ValidateTask(t); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid task: %v", err)
+		}
+	}
+
+	clientID := "grpc-" + uuid.New().String()
+	clientAddr := ""
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		clientAddr = p.Addr.String()
+	}
+
+	// A slow client holds the scheduler back instead of losing output; once the RPC has
+	// returned (client gone or final status sent) further outputs are discarded
+	outputs := make(chan *pb.TaskOutput, apiOutputBuffer)
+	done := make(chan struct{})
+	defer close(done)
+	handler := func(output *pb.TaskOutput) {
+		select {
+		case outputs <- output:
+		case <-done:
+		}
+	}
+
+	ctx := task.WithClientAddr(context.Background(), clientAddr)
+	if err := s.taskSubmitter.SubmitTask(ctx, t, clientID, handler); err != nil {
+		return status.Errorf(codes.FailedPrecondition, "submit task fail: %v", err)
+	}
+
+	logger.Info("Task submitted via API",
+		zap.String("task_id", t.TaskId),
+		zap.String("client_id", clientID),
+		zap.String("client_addr", clientAddr),
+	)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			if err := s.taskSubmitter.CancelTask(t.TaskId); err != nil {
+				logger.Debug("Failed to cancel task after API client left",
+					zap.String("task_id", t.TaskId),
+					zap.Error(err),
+				)
+			}
+			return stream.Context().Err()
+
+		case output := <-outputs:
+			if err := stream.Send(output); err != nil {
+				_ = s.taskSubmitter.CancelTask(t.TaskId)
+				return err
+			}
+			switch output.Status {
+			case pb.TaskStatus_TASK_STATUS_COMPLETED,
+				pb.TaskStatus_TASK_STATUS_FAILED,
+				pb.TaskStatus_TASK_STATUS_CANCELLED:
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lureiny/lookingglass/master/agent"
+	pb "github.com/lureiny/lookingglass/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newSeededManager returns an agent manager with stream agents registered, all online
+func newSeededManager(t *testing.T, infos ...*pb.AgentInfo) *agent.Manager {
+	t.Helper()
+	am := agent.NewManager(time.Minute, time.Minute)
+	t.Cleanup(am.Stop)
+	for _, info := range infos {
+		if err := am.RegisterAgentFromStream(info); err != nil {
+			t.Fatalf("register %s: %v", info.Id, err)
+		}
+	}
+	return am
+}
+
+func TestListAgents(t *testing.T) {
+	am := newSeededManager(t,
+		&pb.AgentInfo{Id: "b", Name: "Tokyo", Ipv4: "203.0.113.7", MaxConcurrent: 5},
+		&pb.AgentInfo{Id: "a", Name: "Paris", Ipv4: "198.51.100.9", Ipv6: "2001:db8::7334", HideIp: true, MaxConcurrent: 3},
+	)
+	am.MarkAgentOffline("b")
+	s := NewMasterServer(am, 30, nil)
+
+	resp, err := s.ListAgents(context.Background(), &pb.ListAgentsRequest{})
+	if err != nil {
+		t.Fatalf("ListAgents() error = %v", err)
+	}
+	if len(resp.Agents) != 2 || resp.Agents[0].Id != "a" || resp.Agents[1].Id != "b" {
+		t.Fatalf("agents = %v, want a and b sorted by ID", resp.Agents)
+	}
+
+	// Hidden IPs are masked the same way as on the WebSocket API
+	hidden := resp.Agents[0]
+	if hidden.Ipv4 != "198.51.*.*" || hidden.Ipv6 != "2001:****:****:****:****:****:****:7334" {
+		t.Errorf("hidden agent IPs = %q, %q", hidden.Ipv4, hidden.Ipv6)
+	}
+	if resp.Agents[1].Ipv4 != "203.0.113.7" {
+		t.Errorf("visible agent IPv4 = %q", resp.Agents[1].Ipv4)
+	}
+
+	online, err := s.ListAgents(context.Background(), &pb.ListAgentsRequest{OnlineOnly: true})
+	if err != nil {
+		t.Fatalf("ListAgents(online_only) error = %v", err)
+	}
+	if len(online.Agents) != 1 || online.Agents[0].Id != "a" {
+		t.Errorf("online agents = %v, want only a", online.Agents)
+	}
+}
+
+func TestGetAgentDetail(t *testing.T) {
+	am := newSeededManager(t, &pb.AgentInfo{Id: "a", Name: "Paris", Location: "FR", MaxConcurrent: 3})
+	s := NewMasterServer(am, 30, nil)
+
+	info, err := s.GetAgentDetail(context.Background(), &pb.GetAgentDetailRequest{AgentId: "a"})
+	if err != nil {
+		t.Fatalf("GetAgentDetail() error = %v", err)
+	}
+	if info.Name != "Paris" || info.Location != "FR" || info.MaxConcurrent != 3 {
+		t.Errorf("detail = %v", info)
+	}
+
+	if _, err := s.GetAgentDetail(context.Background(), &pb.GetAgentDetailRequest{AgentId: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("missing agent error = %v, want NotFound", err)
+	}
+	if _, err := s.GetAgentDetail(context.Background(), &pb.GetAgentDetailRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("empty agent_id error = %v, want InvalidArgument", err)
+	}
+}
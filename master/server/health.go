@@ -0,0 +1,50 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/lureiny/lookingglass/master/agent"
+)
+
+// HealthChecker serves liveness and readiness probes
+type HealthChecker struct {
+	streamRegistry *agent.StreamRegistry
+	ready          atomic.Bool
+}
+
+// NewHealthChecker creates a health checker; it reports not ready until SetReady(true)
+func NewHealthChecker(streamRegistry *agent.StreamRegistry) *HealthChecker {
+	return &HealthChecker{streamRegistry: streamRegistry}
+}
+
+// SetReady marks the master as ready (or no longer ready) to accept agents and tasks
+func (h *HealthChecker) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// HandleHealthz handles the liveness probe: 200 whenever the process is serving HTTP
+func (h *HealthChecker) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// HandleReadyz handles the readiness probe: 200 once the gRPC server is serving, 503 before
+// The body reports the number of agents with a connected stream
+func (h *HealthChecker) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	ready := h.ready.Load()
+	status := "ready"
+	if !ready {
+		status = "not ready"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":           status,
+		"connected_agents": h.streamRegistry.GetConnectedAgentCount(),
+	})
+}
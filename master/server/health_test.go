@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lureiny/lookingglass/master/agent"
+	"go.uber.org/zap"
+)
+
+func TestHealthz(t *testing.T) {
+	h := NewHealthChecker(agent.NewStreamRegistry(zap.NewNop()))
+
+	// Liveness does not depend on readiness
+	rec := httptest.NewRecorder()
+	h.HandleHealthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/healthz status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	h := NewHealthChecker(agent.NewStreamRegistry(zap.NewNop()))
+
+	probe := func() (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		h.HandleReadyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode /readyz body: %v", err)
+		}
+		return rec.Code, body
+	}
+
+	if code, body := probe(); code != http.StatusServiceUnavailable || body["status"] != "not ready" {
+		t.Errorf("/readyz before ready = %d %v, want 503 not ready", code, body)
+	}
+
+	h.SetReady(true)
+	code, body := probe()
+	if code != http.StatusOK || body["status"] != "ready" {
+		t.Errorf("/readyz when ready = %d %v, want 200 ready", code, body)
+	}
+	if body["connected_agents"] != float64(0) {
+		t.Errorf("connected_agents = %v, want 0", body["connected_agents"])
+	}
+
+	// Shutdown flips readiness back off
+	h.SetReady(false)
+	if code, _ := probe(); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz after shutdown started = %d, want 503", code)
+	}
+}
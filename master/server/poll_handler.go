@@ -0,0 +1,284 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lureiny/lookingglass/master/auth"
+	pb "github.com/lureiny/lookingglass/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// pollMaxBodyBytes bounds one poll request (compressed output blocks travel in it too)
+	pollMaxBodyBytes = 64 << 20
+	// pollQueueMax bounds the messages queued for an agent between two polls
+	pollQueueMax = 1000
+)
+
+// pollContentType is the encoding of poll requests and responses
+const pollContentType = "application/x-protobuf"
+
+// errPollQueueFull is returned when an agent in poll mode has not collected its queued messages
+var errPollQueueFull = errors.New("poll queue full")
+
+// pollStream stands in for the gRPC stream of an agent in poll mode
+// Messages the master sends are queued until the agent's next poll collects them,
+// so the stream registry dispatches to polling agents like to streaming ones
+type pollStream struct {
+	grpc.ServerStream // Not used; only Send and Context are called on registered streams
+
+	ctx     context.Context
+	cancel  context.CancelFunc // Ends ctx once the session is dropped or replaced
+	timeout time.Duration      // The agent is considered gone when it stops polling for this long
+
+	mu       sync.Mutex
+	queue    []*pb.MasterMessage
+	lastPoll time.Time
+}
+
+// Send queues a message for the agent's next poll
+func (s *pollStream) Send(msg *pb.MasterMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timeout > 0 && time.Since(s.lastPoll) > s.timeout {
+		return fmt.Errorf("agent stopped polling %s ago", time.Since(s.lastPoll).Round(time.Second))
+	}
+	if len(s.queue) >= pollQueueMax {
+		return errPollQueueFull
+	}
+	s.queue = append(s.queue, msg)
+	return nil
+}
+
+// Recv is never called: agent messages arrive in poll requests
+func (s *pollStream) Recv() (*pb.AgentMessage, error) {
+	return nil, io.EOF
+}
+
+// Context returns the session's context, carrying the credentials of the poll that registered
+// the agent; it is cancelled once the session is dropped or replaced
+func (s *pollStream) Context() context.Context {
+	return s.ctx
+}
+
+// collect marks a poll and returns the messages queued since the previous one
+func (s *pollStream) collect() []*pb.MasterMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastPoll = time.Now()
+	queued := s.queue
+	s.queue = nil
+	return queued
+}
+
+// expired reports whether the agent stopped polling
+func (s *pollStream) expired() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.timeout > 0 && time.Since(s.lastPoll) > s.timeout
+}
+
+// PollHandler serves agents in poll mode over HTTP (POST /agent/poll)
+// Each request carries the agent's messages (registration, heartbeats, task output) and
+// returns the master messages (tasks, cancellations) queued for it. Agents authenticate
+// with the same key headers as on the gRPC stream (X-API-Key, X-Agent-ID).
+type PollHandler struct {
+	streamHandler *StreamHandler
+	authenticator auth.Authenticator
+	timeout       time.Duration // Polling gap after which an agent's session is dropped
+
+	mu       sync.Mutex
+	sessions map[string]*pollStream // Agent ID -> current session
+}
+
+// NewPollHandler creates a poll handler that drops agents which stop polling for timeout
+func NewPollHandler(streamHandler *StreamHandler, authenticator auth.Authenticator, timeout time.Duration) *PollHandler {
+	return &PollHandler{
+		streamHandler: streamHandler,
+		authenticator: authenticator,
+		timeout:       timeout,
+		sessions:      make(map[string]*pollStream),
+	}
+}
+
+// ServeHTTP handles one poll
+func (h *PollHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	agentID := r.Header.Get("X-Agent-ID")
+	if agentID == "" {
+		http.Error(w, "missing X-Agent-ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := pollContext(r)
+	if err := h.authenticator.Authenticate(ctx); err != nil {
+		code := http.StatusUnauthorized
+		if status.Code(err) == codes.PermissionDenied {
+			code = http.StatusForbidden
+		}
+		http.Error(w, status.Convert(err).Message(), code)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, pollMaxBodyBytes))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	var req pb.PollRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, "invalid poll request", http.StatusBadRequest)
+		return
+	}
+
+	h.pruneExpired()
+
+	session := h.session(agentID)
+	for _, msg := range req.Messages {
+		if msg.Type == pb.AgentMessage_TYPE_REGISTER {
+			session = h.register(agentID, ctx, msg)
+			continue
+		}
+		if session == nil {
+			// Unknown after a master restart or a dropped session: the agent registers again
+			http.Error(w, "agent not registered", http.StatusConflict)
+			return
+		}
+		h.streamHandler.handleAgentMessage(agentID, session, msg)
+	}
+	if session == nil {
+		http.Error(w, "agent not registered", http.StatusConflict)
+		return
+	}
+
+	data, err := proto.Marshal(&pb.PollResponse{Messages: session.collect()})
+	if err != nil {
+		http.Error(w, "failed to encode poll response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", pollContentType)
+	w.Write(data)
+}
+
+// register handles a registration in a poll, starting a new session for the agent
+// A failed registration still returns a session so its response reaches the agent
+func (h *PollHandler) register(agentID string, ctx context.Context, msg *pb.AgentMessage) *pollStream {
+	sessionCtx, cancel := context.WithCancel(metadata.NewIncomingContext(context.Background(), metadataFromContext(ctx)))
+	session := &pollStream{
+		ctx:      sessionCtx,
+		cancel:   cancel,
+		timeout:  h.timeout,
+		lastPoll: time.Now(),
+	}
+
+	if _, err := h.streamHandler.handleRegister(session, msg, cancel); err != nil {
+		cancel()
+		h.streamHandler.logger.Error("Poll registration failed",
+			zap.String("agent_id", agentID),
+			zap.Error(err),
+		)
+		return session
+	}
+
+	h.mu.Lock()
+	previous := h.sessions[agentID]
+	h.sessions[agentID] = session
+	h.mu.Unlock()
+	if previous != nil {
+		previous.cancel()
+	}
+
+	h.streamHandler.logger.Info("Agent registered in poll mode",
+		zap.String("agent_id", agentID),
+	)
+	return session
+}
+
+// session returns the agent's session if it is still the agent's registered stream
+func (h *PollHandler) session(agentID string) *pollStream {
+	h.mu.Lock()
+	session, ok := h.sessions[agentID]
+	h.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	// The agent may have registered again since, e.g. over a gRPC stream
+	if current, ok := h.streamHandler.streamRegistry.GetAgentStream(agentID); !ok || current != pb.MasterService_AgentStreamServer(session) {
+		h.mu.Lock()
+		if h.sessions[agentID] == session {
+			delete(h.sessions, agentID)
+		}
+		h.mu.Unlock()
+		session.cancel()
+		return nil
+	}
+	return session
+}
+
+// pruneExpired drops the sessions of agents that stopped polling and marks them offline
+func (h *PollHandler) pruneExpired() {
+	h.mu.Lock()
+	expired := make(map[string]*pollStream)
+	for agentID, session := range h.sessions {
+		if session.expired() {
+			expired[agentID] = session
+			delete(h.sessions, agentID)
+		}
+	}
+	h.mu.Unlock()
+
+	registry := h.streamHandler.streamRegistry
+	for agentID, session := range expired {
+		session.cancel()
+		h.streamHandler.logger.Info("Agent stopped polling",
+			zap.String("agent_id", agentID),
+		)
+		// Leave an agent alone that has registered again since
+		if current, ok := registry.GetAgentStream(agentID); ok && current == pb.MasterService_AgentStreamServer(session) {
+			registry.UnregisterAgentStream(agentID)
+			h.streamHandler.agentManager.MarkAgentOffline(agentID)
+		}
+	}
+}
+
+// pollContext carries a poll's credentials and client address the way gRPC would,
+// so the agent authenticator applies unchanged
+func pollContext(r *http.Request) context.Context {
+	md := metadata.Pairs(
+		"x-api-key", r.Header.Get("X-API-Key"),
+		"x-agent-id", r.Header.Get("X-Agent-ID"),
+	)
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: addr})
+	}
+	return ctx
+}
+
+// metadataFromContext returns the incoming metadata of ctx (empty if none)
+func metadataFromContext(ctx context.Context) metadata.MD {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return md.Copy()
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lureiny/lookingglass/master/agent"
+	"github.com/lureiny/lookingglass/master/auth"
+	pb "github.com/lureiny/lookingglass/pb"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// newTestPollHandler returns a poll handler accepting the key "secret"
+func newTestPollHandler(t *testing.T, timeout time.Duration) (*PollHandler, *agent.Manager, *agent.StreamRegistry) {
+	t.Helper()
+	am := agent.NewManager(time.Minute, time.Minute)
+	t.Cleanup(am.Stop)
+	registry := agent.NewStreamRegistry(zap.NewNop())
+	authenticator, err := auth.NewAuthenticator(&auth.Config{Mode: pb.AuthMode_AUTH_MODE_API_KEY, APIKey: "secret"})
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+	return NewPollHandler(NewStreamHandler(am, registry, zap.NewNop()), authenticator, timeout), am, registry
+}
+
+// poll sends one poll for agentID and returns the status code and decoded response
+func poll(t *testing.T, h *PollHandler, agentID, key string, msgs ...*pb.AgentMessage) (int, *pb.PollResponse) {
+	t.Helper()
+	body, err := proto.Marshal(&pb.PollRequest{Messages: msgs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/agent/poll", bytes.NewReader(body))
+	req.Header.Set("X-Agent-ID", agentID)
+	req.Header.Set("X-API-Key", key)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp pb.PollResponse
+	if rec.Code == http.StatusOK {
+		if err := proto.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode poll response: %v", err)
+		}
+	}
+	return rec.Code, &resp
+}
+
+func registerMessage(agentID string) *pb.AgentMessage {
+	return &pb.AgentMessage{
+		Type: pb.AgentMessage_TYPE_REGISTER,
+		Payload: &pb.AgentMessage_Register{Register: &pb.RegisterRequest{
+			AgentInfo: &pb.AgentInfo{Id: agentID, Name: agentID, MaxConcurrent: 5},
+		}},
+	}
+}
+
+func heartbeatMessage(agentID string) *pb.AgentMessage {
+	return &pb.AgentMessage{
+		Type:    pb.AgentMessage_TYPE_HEARTBEAT,
+		Payload: &pb.AgentMessage_Heartbeat{Heartbeat: &pb.HeartbeatRequest{AgentId: agentID}},
+	}
+}
+
+func TestPollCycle(t *testing.T) {
+	h, am, registry := newTestPollHandler(t, time.Minute)
+
+	if code, _ := poll(t, h, "agent-1", "wrong", registerMessage("agent-1")); code != http.StatusUnauthorized {
+		t.Errorf("wrong key: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code, _ := poll(t, h, "agent-1", "secret", heartbeatMessage("agent-1")); code != http.StatusConflict {
+		t.Errorf("unregistered: status = %d, want %d", code, http.StatusConflict)
+	}
+
+	code, resp := poll(t, h, "agent-1", "secret", registerMessage("agent-1"))
+	if code != http.StatusOK {
+		t.Fatalf("register: status = %d", code)
+	}
+	if len(resp.Messages) != 1 || !resp.Messages[0].GetRegisterResponse().GetSuccess() {
+		t.Fatalf("register: messages = %v, want a successful register response", resp.Messages)
+	}
+	if a, err := am.GetAgent("agent-1"); err != nil || a.Status != pb.AgentStatus_AGENT_STATUS_ONLINE {
+		t.Errorf("agent = %v, %v; want online", a, err)
+	}
+
+	// Messages sent between polls wait for the next one
+	if err := registry.SendToAgent("agent-1", &pb.MasterMessage{Type: pb.MasterMessage_TYPE_CANCEL_TASK}); err != nil {
+		t.Fatalf("SendToAgent() error = %v", err)
+	}
+	code, resp = poll(t, h, "agent-1", "secret", heartbeatMessage("agent-1"))
+	if code != http.StatusOK {
+		t.Fatalf("heartbeat: status = %d", code)
+	}
+	var types []pb.MasterMessage_Type
+	for _, msg := range resp.Messages {
+		types = append(types, msg.Type)
+	}
+	if len(types) != 2 || types[0] != pb.MasterMessage_TYPE_CANCEL_TASK || types[1] != pb.MasterMessage_TYPE_HEARTBEAT_RESPONSE {
+		t.Errorf("heartbeat: message types = %v, want the queued cancel then the heartbeat response", types)
+	}
+	if _, resp = poll(t, h, "agent-1", "secret"); len(resp.Messages) != 0 {
+		t.Errorf("empty poll: messages = %v, want none", resp.Messages)
+	}
+}
+
+func TestPollReregistrationCancelsSession(t *testing.T) {
+	h, _, _ := newTestPollHandler(t, time.Minute)
+
+	poll(t, h, "agent-1", "secret", registerMessage("agent-1"))
+	first := h.session("agent-1")
+	if first == nil {
+		t.Fatal("no session after registering")
+	}
+
+	poll(t, h, "agent-1", "secret", registerMessage("agent-1"))
+	second := h.session("agent-1")
+	if second == nil || second == first {
+		t.Fatal("registering again did not start a new session")
+	}
+	if first.Context().Err() == nil {
+		t.Error("replaced session's context not cancelled")
+	}
+	if second.Context().Err() != nil {
+		t.Error("current session's context cancelled")
+	}
+}
+
+func TestPollPrunesExpiredSession(t *testing.T) {
+	h, am, registry := newTestPollHandler(t, 50*time.Millisecond)
+
+	poll(t, h, "agent-1", "secret", registerMessage("agent-1"))
+	session := h.session("agent-1")
+	if session == nil {
+		t.Fatal("no session after registering")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	// Any poll prunes the sessions of agents that stopped polling
+	poll(t, h, "agent-2", "secret", registerMessage("agent-2"))
+
+	if session.Context().Err() == nil {
+		t.Error("expired session's context not cancelled")
+	}
+	if _, ok := registry.GetAgentStream("agent-1"); ok {
+		t.Error("expired session still registered")
+	}
+	if a, err := am.GetAgent("agent-1"); err != nil || a.Status != pb.AgentStatus_AGENT_STATUS_OFFLINE {
+		t.Errorf("agent = %v, %v; want offline", a, err)
+	}
+}
+
+func TestPollExpiredAgentReregisters(t *testing.T) {
+	h, am, registry := newTestPollHandler(t, 50*time.Millisecond)
+
+	poll(t, h, "agent-1", "secret", registerMessage("agent-1"))
+	time.Sleep(100 * time.Millisecond)
+
+	// The session expired, so the agent is told to register again
+	if code, _ := poll(t, h, "agent-1", "secret", heartbeatMessage("agent-1")); code != http.StatusConflict {
+		t.Fatalf("poll after expiry: status = %d, want %d", code, http.StatusConflict)
+	}
+	if a, err := am.GetAgent("agent-1"); err != nil || a.Status != pb.AgentStatus_AGENT_STATUS_OFFLINE {
+		t.Errorf("agent after expiry = %v, %v; want offline", a, err)
+	}
+
+	code, resp := poll(t, h, "agent-1", "secret", registerMessage("agent-1"))
+	if code != http.StatusOK || len(resp.Messages) != 1 || !resp.Messages[0].GetRegisterResponse().GetSuccess() {
+		t.Fatalf("re-register: status = %d, messages = %v", code, resp.Messages)
+	}
+	if a, err := am.GetAgent("agent-1"); err != nil || a.Status != pb.AgentStatus_AGENT_STATUS_ONLINE {
+		t.Errorf("agent after re-registering = %v, %v; want online", a, err)
+	}
+
+	// Messages for the agent reach it through the new session
+	if err := registry.SendToAgent("agent-1", &pb.MasterMessage{Type: pb.MasterMessage_TYPE_CANCEL_TASK}); err != nil {
+		t.Fatalf("SendToAgent() error = %v", err)
+	}
+	_, resp = poll(t, h, "agent-1", "secret")
+	if len(resp.Messages) != 1 || resp.Messages[0].Type != pb.MasterMessage_TYPE_CANCEL_TASK {
+		t.Errorf("messages after re-registering = %v, want the queued cancel", resp.Messages)
+	}
+}
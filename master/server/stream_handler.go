@@ -1,15 +1,21 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/lureiny/lookingglass/master/agent"
 	pb "github.com/lureiny/lookingglass/pb"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
 )
 
+// describeTimeout bounds how long DescribeAgent and FetchAgentLogs wait for the agent's reply
+const describeTimeout = 10 * time.Second
+
 // TaskOutputHandler interface for handling task outputs
 type TaskOutputHandler interface {
 	HandleTaskOutput(output *pb.TaskOutput)
@@ -20,6 +26,8 @@ type StreamHandler struct {
 	agentManager      *agent.Manager
 	streamRegistry    *agent.StreamRegistry
 	taskOutputHandler TaskOutputHandler
+	onFirstRegister   func(agentID string) // Optional hook for agents seen for the first time
+	disabledTasks     map[string]bool      // Task names stripped from every agent's advertised tasks
 	logger            *zap.Logger
 }
 
@@ -37,25 +45,111 @@ func (h *StreamHandler) SetTaskOutputHandler(handler TaskOutputHandler) {
 	h.taskOutputHandler = handler
 }
 
+// SetFirstRegistrationHandler sets a hook called after an agent registers for the first time
+// The hook runs asynchronously once the registration response has been sent
+func (h *StreamHandler) SetFirstRegistrationHandler(handler func(agentID string)) {
+	h.onFirstRegister = handler
+}
+
+// SetDisabledTasks strips the named tasks from the tasks agents advertise on registration
+func (h *StreamHandler) SetDisabledTasks(names []string) {
+	disabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		disabled[name] = true
+	}
+	h.disabledTasks = disabled
+}
+
+// stripDisabledTasks removes globally disabled tasks from an agent's advertised tasks
+func (h *StreamHandler) stripDisabledTasks(info *pb.AgentInfo) {
+	if len(h.disabledTasks) == 0 {
+		return
+	}
+
+	displayInfo := info.TaskDisplayInfo[:0:0]
+	for _, task := range info.TaskDisplayInfo {
+		if !h.disabledTasks[task.TaskName] {
+			displayInfo = append(displayInfo, task)
+		}
+	}
+	taskNames := info.TaskNames[:0:0]
+	for _, name := range info.TaskNames {
+		if !h.disabledTasks[name] {
+			taskNames = append(taskNames, name)
+		}
+	}
+
+	if removed := len(info.TaskDisplayInfo) - len(displayInfo); removed > 0 {
+		h.logger.Info("Stripped globally disabled tasks from agent",
+			zap.String("agent_id", info.Id),
+			zap.Int("removed", removed),
+		)
+	}
+	info.TaskDisplayInfo = displayInfo
+	info.TaskNames = taskNames
+}
+
+// recvResult is one message (or the final error) received from an agent's stream
+type recvResult struct {
+	msg *pb.AgentMessage
+	err error
+}
+
 // AgentStream handles the bidirectional stream with an agent
+// The stream ends when the agent closes it or when the registry cancels it after a send timed out;
+// returning from the handler is what makes gRPC abort a send stuck on the stream.
 func (h *StreamHandler) AgentStream(stream pb.MasterService_AgentStreamServer) error {
 	var agentID string
 	var registered bool
+	var generation uint64
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
 
 	// Cleanup on stream close
+	// Only the current stream may mark the agent offline; a replaced stream closing
+	// after the agent reconnected must not affect the new registration
 	defer func() {
 		if registered && agentID != "" {
-			h.streamRegistry.UnregisterAgentStream(agentID)
-			h.agentManager.MarkAgentOffline(agentID)
+			if h.streamRegistry.UnregisterAgentStreamIfCurrent(agentID, generation) {
+				h.agentManager.MarkAgentOffline(agentID)
+			}
 			h.logger.Info("Agent stream closed",
 				zap.String("agent_id", agentID),
 			)
 		}
 	}()
 
+	// Recv blocks until the agent sends, so it runs in its own goroutine and the loop below
+	// can also stop on cancellation
+	received := make(chan recvResult)
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			select {
+			case received <- recvResult{msg: msg, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
 	// Handle incoming messages from agent
 	for {
-		msg, err := stream.Recv()
+		var result recvResult
+		select {
+		case result = <-received:
+		case <-ctx.Done():
+			h.logger.Warn("Agent stream cancelled",
+				zap.String("agent_id", agentID),
+			)
+			return ctx.Err()
+		}
+
+		msg, err := result.msg, result.err
 		if err == io.EOF {
 			h.logger.Info("Agent closed stream",
 				zap.String("agent_id", agentID),
@@ -70,54 +164,67 @@ func (h *StreamHandler) AgentStream(stream pb.MasterService_AgentStreamServer) e
 			return err
 		}
 
-		// Handle message based on type
-		switch msg.Type {
-		case pb.AgentMessage_TYPE_REGISTER:
-			if err := h.handleRegister(stream, msg); err != nil {
+		if msg.Type == pb.AgentMessage_TYPE_REGISTER {
+			gen, err := h.handleRegister(stream, msg, cancel)
+			if err != nil {
 				h.logger.Error("Registration failed",
 					zap.Error(err),
 				)
 				return err
 			}
 			agentID = msg.GetRegister().GetAgentInfo().GetId()
+			generation = gen
 			registered = true
+			continue
+		}
 
-		case pb.AgentMessage_TYPE_HEARTBEAT:
-			if err := h.handleHeartbeat(stream, msg); err != nil {
-				h.logger.Error("Heartbeat handling failed",
-					zap.String("agent_id", agentID),
-					zap.Error(err),
-				)
-			}
-
-		case pb.AgentMessage_TYPE_TASK_OUTPUT:
-			h.handleTaskOutput(msg)
-
-		case pb.AgentMessage_TYPE_TASK_COMPLETE:
-			h.handleTaskComplete(msg)
-
-		case pb.AgentMessage_TYPE_TASK_FAILED:
-			h.handleTaskFailed(msg)
+		h.handleAgentMessage(agentID, stream, msg)
+	}
+}
 
-		default:
-			h.logger.Warn("Unknown message type",
+// handleAgentMessage processes a message other than registration from an agent
+func (h *StreamHandler) handleAgentMessage(agentID string, stream pb.MasterService_AgentStreamServer, msg *pb.AgentMessage) {
+	switch msg.Type {
+	case pb.AgentMessage_TYPE_HEARTBEAT:
+		if err := h.handleHeartbeat(stream, msg); err != nil {
+			h.logger.Error("Heartbeat handling failed",
 				zap.String("agent_id", agentID),
-				zap.Int32("type", int32(msg.Type)),
+				zap.Error(err),
 			)
 		}
+
+	case pb.AgentMessage_TYPE_TASK_OUTPUT:
+		h.handleTaskOutput(msg)
+
+	case pb.AgentMessage_TYPE_TASK_COMPLETE:
+		h.handleTaskComplete(msg)
+
+	case pb.AgentMessage_TYPE_TASK_FAILED:
+		h.handleTaskFailed(msg)
+
+	case pb.AgentMessage_TYPE_DESCRIBE_RESPONSE, pb.AgentMessage_TYPE_PROBE_RESPONSE, pb.AgentMessage_TYPE_LOGS_RESPONSE:
+		h.streamRegistry.HandleResponse(msg)
+
+	default:
+		h.logger.Warn("Unknown message type",
+			zap.String("agent_id", agentID),
+			zap.Int32("type", int32(msg.Type)),
+		)
 	}
 }
 
 // handleRegister processes agent registration
-func (h *StreamHandler) handleRegister(stream pb.MasterService_AgentStreamServer, msg *pb.AgentMessage) error {
+// cancel ends the stream if a send to it times out (nil if it cannot be cancelled)
+// Returns the stream generation assigned by the registry
+func (h *StreamHandler) handleRegister(stream pb.MasterService_AgentStreamServer, msg *pb.AgentMessage, cancel context.CancelFunc) (uint64, error) {
 	registerReq := msg.GetRegister()
 	if registerReq == nil {
-		return fmt.Errorf("missing registration data")
+		return 0, fmt.Errorf("missing registration data")
 	}
 
 	agentInfo := registerReq.GetAgentInfo()
 	if agentInfo == nil {
-		return fmt.Errorf("missing agent info")
+		return 0, fmt.Errorf("missing agent info")
 	}
 
 	agentID := agentInfo.GetId()
@@ -126,8 +233,25 @@ func (h *StreamHandler) handleRegister(stream pb.MasterService_AgentStreamServer
 		zap.String("agent_name", agentInfo.GetName()),
 	)
 
+	// The ID the connection authenticated with (per-agent keys) must be the one registered
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		if ids := md.Get("x-agent-id"); len(ids) > 0 && ids[0] != agentID {
+			h.logger.Warn("Agent registered under a different ID than it authenticated with",
+				zap.String("agent_id", agentID),
+				zap.String("authenticated_id", ids[0]),
+			)
+			return 0, fmt.Errorf("agent ID %q does not match authenticated ID %q", agentID, ids[0])
+		}
+	}
+
+	h.stripDisabledTasks(agentInfo)
+
+	_, lookupErr := h.agentManager.GetAgent(agentID)
+	firstRegistration := lookupErr != nil
+
 	// Check for duplicate registration
-	if err := h.streamRegistry.RegisterAgentStream(agentID, stream); err != nil {
+	generation, err := h.streamRegistry.RegisterAgentStream(agentID, stream, cancel)
+	if err != nil {
 		// Send failure response
 		response := &pb.MasterMessage{
 			RequestId: msg.RequestId,
@@ -139,13 +263,13 @@ func (h *StreamHandler) handleRegister(stream pb.MasterService_AgentStreamServer
 				},
 			},
 		}
-		stream.Send(response)
-		return err
+		h.streamRegistry.SendOnStream(agentID, stream, response)
+		return 0, err
 	}
 
 	// Register agent in manager
 	if err := h.agentManager.RegisterAgentFromStream(agentInfo); err != nil {
-		h.streamRegistry.UnregisterAgentStream(agentID)
+		h.streamRegistry.UnregisterAgentStreamIfCurrent(agentID, generation)
 		response := &pb.MasterMessage{
 			RequestId: msg.RequestId,
 			Type:      pb.MasterMessage_TYPE_REGISTER_RESPONSE,
@@ -156,8 +280,8 @@ func (h *StreamHandler) handleRegister(stream pb.MasterService_AgentStreamServer
 				},
 			},
 		}
-		stream.Send(response)
-		return err
+		h.streamRegistry.SendOnStream(agentID, stream, response)
+		return 0, err
 	}
 
 	// Send success response
@@ -173,19 +297,24 @@ func (h *StreamHandler) handleRegister(stream pb.MasterService_AgentStreamServer
 		},
 	}
 
-	if err := stream.Send(response); err != nil {
+	if err := h.streamRegistry.SendOnStream(agentID, stream, response); err != nil {
 		h.logger.Error("Failed to send registration response",
 			zap.String("agent_id", agentID),
 			zap.Error(err),
 		)
-		return err
+		return 0, err
 	}
 
 	h.logger.Info("Agent registered successfully",
 		zap.String("agent_id", agentID),
+		zap.Bool("first_registration", firstRegistration),
 	)
 
-	return nil
+	if firstRegistration && h.onFirstRegister != nil {
+		go h.onFirstRegister(agentID)
+	}
+
+	return generation, nil
 }
 
 // handleHeartbeat processes heartbeat messages
@@ -199,6 +328,8 @@ func (h *StreamHandler) handleHeartbeat(stream pb.MasterService_AgentStreamServe
 
 	// Update last heartbeat time
 	h.agentManager.UpdateHeartbeat(agentID, int(heartbeatReq.GetCurrentTasks()))
+	h.agentManager.UpdateResourceStats(agentID, heartbeatReq.GetMemPercent(), heartbeatReq.GetDiskPercent())
+	h.agentManager.UpdateTaskUsage(agentID, heartbeatReq.GetTaskConcurrency())
 
 	// Send acknowledgment
 	response := &pb.MasterMessage{
@@ -212,7 +343,7 @@ func (h *StreamHandler) handleHeartbeat(stream pb.MasterService_AgentStreamServe
 		},
 	}
 
-	return stream.Send(response)
+	return h.streamRegistry.SendOnStream(agentID, stream, response)
 }
 
 // handleTaskOutput processes task output messages
@@ -299,3 +430,58 @@ func (h *StreamHandler) CancelTaskOnAgent(agentID string, taskID string) error {
 
 	return h.streamRegistry.SendToAgent(agentID, msg)
 }
+
+// ProbeAgent checks that an agent's stream is writable and answered within timeout
+func (h *StreamHandler) ProbeAgent(ctx context.Context, agentID string, timeout time.Duration) error {
+	msg := &pb.MasterMessage{
+		RequestId: uuid.New().String(),
+		Type:      pb.MasterMessage_TYPE_PROBE,
+	}
+
+	_, err := h.streamRegistry.SendAndWaitForResponse(ctx, agentID, msg, timeout)
+	return err
+}
+
+// DescribeAgent asks an agent for its effective configuration (secrets redacted by the agent)
+func (h *StreamHandler) DescribeAgent(ctx context.Context, agentID string) (*pb.DescribeResponse, error) {
+	msg := &pb.MasterMessage{
+		RequestId: uuid.New().String(),
+		Type:      pb.MasterMessage_TYPE_DESCRIBE,
+		Payload: &pb.MasterMessage_Describe{
+			Describe: &pb.DescribeRequest{},
+		},
+	}
+
+	resp, err := h.streamRegistry.SendAndWaitForResponse(ctx, agentID, msg, describeTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	describe := resp.GetDescribeResponse()
+	if describe == nil {
+		return nil, fmt.Errorf("agent %s sent an empty describe response", agentID)
+	}
+	return describe, nil
+}
+
+// FetchAgentLogs asks an agent for its most recent log lines (lines <= 0 = agent default)
+func (h *StreamHandler) FetchAgentLogs(ctx context.Context, agentID string, lines int) (*pb.LogsResponse, error) {
+	msg := &pb.MasterMessage{
+		RequestId: uuid.New().String(),
+		Type:      pb.MasterMessage_TYPE_LOGS,
+		Payload: &pb.MasterMessage_Logs{
+			Logs: &pb.LogsRequest{Lines: int32(lines)},
+		},
+	}
+
+	resp, err := h.streamRegistry.SendAndWaitForResponse(ctx, agentID, msg, describeTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := resp.GetLogsResponse()
+	if logs == nil {
+		return nil, fmt.Errorf("agent %s sent an empty logs response", agentID)
+	}
+	return logs, nil
+}
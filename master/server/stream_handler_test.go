@@ -0,0 +1,36 @@
+package server
+
+import (
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"go.uber.org/zap"
+)
+
+func TestStripDisabledTasks(t *testing.T) {
+	h := NewStreamHandler(nil, nil, zap.NewNop())
+	info := &pb.AgentInfo{
+		Id:              "a",
+		TaskNames:       []string{"ping", "mtr", "nexttrace"},
+		TaskDisplayInfo: []*pb.TaskDisplayInfo{{TaskName: "ping"}, {TaskName: "mtr"}, {TaskName: "nexttrace"}},
+	}
+	advertised := info.TaskNames
+
+	// Nothing is disabled by default
+	h.stripDisabledTasks(info)
+	if len(info.TaskNames) != 3 || len(info.TaskDisplayInfo) != 3 {
+		t.Fatalf("tasks stripped with nothing disabled: %v", info.TaskNames)
+	}
+
+	h.SetDisabledTasks([]string{"mtr"})
+	h.stripDisabledTasks(info)
+	if len(info.TaskNames) != 2 || info.TaskNames[0] != "ping" || info.TaskNames[1] != "nexttrace" {
+		t.Errorf("task names = %v, want mtr removed", info.TaskNames)
+	}
+	if len(info.TaskDisplayInfo) != 2 || info.TaskDisplayInfo[1].TaskName != "nexttrace" {
+		t.Errorf("display info = %v, want mtr removed", info.TaskDisplayInfo)
+	}
+	if advertised[1] != "mtr" {
+		t.Error("stripping rewrote the agent's original slice")
+	}
+}
@@ -0,0 +1,62 @@
+package sink
+
+import (
+	"fmt"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// NATSConfig contains NATS sink settings
+type NATSConfig struct {
+	URL            string // Server URL, e.g. nats://127.0.0.1:4222
+	Subject        string // Subject (or subject prefix when PerTaskSubject is set)
+	PerTaskSubject bool   // Publish to "<subject>.<task_id>" instead of a single subject
+}
+
+// NATSSink publishes task outputs as JSON to a NATS subject
+type NATSSink struct {
+	conn   *nats.Conn
+	config NATSConfig
+}
+
+// NewNATSSink connects to NATS and returns a sink publishing to the configured subject
+func NewNATSSink(config NATSConfig) (*NATSSink, error) {
+	conn, err := nats.Connect(config.URL,
+		nats.Name("lookingglass-master"),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS %s: %w", config.URL, err)
+	}
+
+	return &NATSSink{
+		conn:   conn,
+		config: config,
+	}, nil
+}
+
+// Publish sends a task output to NATS
+func (s *NATSSink) Publish(taskID string, output *pb.TaskOutput) error {
+	data, err := protojson.Marshal(output)
+	if err != nil {
+		return fmt.Errorf("failed to encode task output: %w", err)
+	}
+
+	subject := s.config.Subject
+	if s.config.PerTaskSubject {
+		subject = subject + "." + taskID
+	}
+
+	return s.conn.Publish(subject, data)
+}
+
+// Close flushes pending messages and closes the connection
+func (s *NATSSink) Close() error {
+	if err := s.conn.Drain(); err != nil {
+		s.conn.Close()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"go.uber.org/zap"
+)
+
+type clientAddrKey struct{}
+
+// WithClientAddr attaches the submitting client's address to ctx for audit logging
+func WithClientAddr(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, clientAddrKey{}, addr)
+}
+
+// clientAddrFromContext returns the client address attached by WithClientAddr, if any
+func clientAddrFromContext(ctx context.Context) string {
+	addr, _ := ctx.Value(clientAddrKey{}).(string)
+	return addr
+}
+
+// SetAuditLogger enables audit entries for task submission and completion
+// A nil logger disables auditing
+func (s *Scheduler) SetAuditLogger(l *zap.Logger) {
+	s.audit = l
+}
+
+// auditFields returns the fields identifying who ran what, where
+func auditFields(taskInfo *TaskInfo) []zap.Field {
+	task := taskInfo.Task
+	return []zap.Field{
+		zap.String("task_id", task.TaskId),
+		zap.String("client_id", taskInfo.ClientID),
+		zap.String("client_addr", taskInfo.ClientAddr),
+		zap.String("agent_id", taskInfo.AgentID),
+		zap.String("task_name", task.TaskName),
+		zap.String("target", task.GetNetworkTest().GetTarget()),
+	}
+}
+
+// auditSubmit records a task submission
+func (s *Scheduler) auditSubmit(taskInfo *TaskInfo) {
+	if s.audit == nil {
+		return
+	}
+	s.audit.Info("audit: task submitted", auditFields(taskInfo)...)
+}
+
+// auditComplete records the outcome of a task
+func (s *Scheduler) auditComplete(taskInfo *TaskInfo, status pb.TaskStatus) {
+	if s.audit == nil {
+		return
+	}
+	fields := append(auditFields(taskInfo),
+		zap.String("status", status.String()),
+		zap.Duration("duration", time.Since(taskInfo.CreatedAt)),
+	)
+	s.audit.Info("audit: task finished", fields...)
+}
@@ -0,0 +1,55 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAuditEntriesPerTaskLifecycle(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	core, logs := observer.New(zap.InfoLevel)
+	s.SetAuditLogger(zap.New(core))
+
+	ctx := WithClientAddr(context.Background(), "203.0.113.7")
+	rec := newOutputRecorder()
+	if err := s.SubmitTask(ctx, pingTask("t1", "agent-1", "1.1.1.1"), "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	sender.waitSent(t)
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_FAILED, ErrorMessage: "boom"})
+	rec.wait(t)
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("got %d audit entries, want 2", len(entries))
+	}
+	if entries[0].Message != "audit: task submitted" || entries[1].Message != "audit: task finished" {
+		t.Fatalf("messages = %q, %q", entries[0].Message, entries[1].Message)
+	}
+	for _, e := range entries {
+		fields := e.ContextMap()
+		for key, want := range map[string]string{
+			"task_id":     "t1",
+			"client_id":   "client-1",
+			"client_addr": "203.0.113.7",
+			"agent_id":    "agent-1",
+			"task_name":   "ping",
+			"target":      "1.1.1.1",
+		} {
+			if fields[key] != want {
+				t.Errorf("%s: %s = %v, want %q", e.Message, key, fields[key], want)
+			}
+		}
+	}
+	finished := entries[1].ContextMap()
+	if finished["status"] != pb.TaskStatus_TASK_STATUS_FAILED.String() {
+		t.Errorf("status = %v", finished["status"])
+	}
+	if _, ok := finished["duration"]; !ok {
+		t.Error("finished entry has no duration")
+	}
+}
@@ -0,0 +1,211 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/lureiny/lookingglass/master/notifier"
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// agentBreaker stops dispatch to agents that fail task after task
+// Only agent faults count: dispatch and stream failures and failures the agent reports as its own
+// (e.g. a task binary that would not start), not tasks rejected by policy, stopped at an output
+// limit or run against a bad target.
+// After threshold consecutive faults within window an agent is degraded for cooldown; then a
+// single trial task (preceded by a stream probe when one is configured) decides whether it recovers
+type agentBreaker struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+
+	mutex  sync.Mutex
+	agents map[string]*breakerState
+}
+
+// breakerState is the failure record of one agent
+type breakerState struct {
+	failures     int
+	firstFailure time.Time
+	openUntil    time.Time // Degraded until then (zero = dispatching normally)
+	trialSince   time.Time // A trial task was admitted after the cooldown (zero = none)
+}
+
+// SetCircuitBreaker degrades an agent after threshold consecutive task failures within window,
+// rejecting its tasks for cooldown before trying it again
+// A threshold <= 0 disables the breaker
+func (s *Scheduler) SetCircuitBreaker(threshold int, window, cooldown time.Duration) {
+	if threshold <= 0 {
+		s.breaker = nil
+		return
+	}
+	s.breaker = &agentBreaker{
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		agents:    make(map[string]*breakerState),
+	}
+}
+
+// admit reports whether a task may be dispatched to agentID
+// trial is true when the task is the first after a cooldown and decides whether the agent recovers
+func (b *agentBreaker) admit(agentID string, now time.Time) (trial bool, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	state, ok := b.agents[agentID]
+	if !ok || state.openUntil.IsZero() {
+		return false, nil
+	}
+	if wait := state.openUntil.Sub(now); wait > 0 {
+		return false, fmt.Errorf("%w: %s (retry in %d seconds)", ErrAgentDegraded, agentID, int(math.Ceil(wait.Seconds())))
+	}
+	// A trial that never reported back (e.g. rejected after admission) expires after a cooldown
+	if !state.trialSince.IsZero() && now.Sub(state.trialSince) < b.cooldown {
+		return false, fmt.Errorf("%w: %s (recovery check in progress)", ErrAgentDegraded, agentID)
+	}
+	state.trialSince = now
+	return true, nil
+}
+
+// record counts a finished task, reporting whether this failure tripped the breaker
+// fault is whether a failure was the agent's own
+func (b *agentBreaker) record(agentID string, status pb.TaskStatus, fault bool, now time.Time) (tripped, recovered bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	state, ok := b.agents[agentID]
+	switch {
+	case status == pb.TaskStatus_TASK_STATUS_COMPLETED:
+		if ok {
+			recovered = !state.openUntil.IsZero()
+			delete(b.agents, agentID)
+		}
+		return false, recovered
+	case status == pb.TaskStatus_TASK_STATUS_FAILED && fault:
+	default:
+		// Cancellations and failures down to the task say nothing about the agent;
+		// a trial ending this way hands the check to the next task
+		if ok {
+			state.trialSince = time.Time{}
+		}
+		return false, false
+	}
+
+	if !ok {
+		state = &breakerState{}
+		b.agents[agentID] = state
+	}
+
+	// A failed trial degrades the agent again right away
+	if !state.trialSince.IsZero() {
+		state.trialSince = time.Time{}
+		state.openUntil = now.Add(b.cooldown)
+		return true, false
+	}
+	if !state.openUntil.IsZero() {
+		return false, false // Task dispatched before the breaker tripped
+	}
+
+	if state.failures == 0 || (b.window > 0 && now.Sub(state.firstFailure) > b.window) {
+		state.failures = 0
+		state.firstFailure = now
+	}
+	state.failures++
+	if state.failures < b.threshold {
+		return false, false
+	}
+	state.openUntil = now.Add(b.cooldown)
+	return true, false
+}
+
+// reopen degrades agentID for another cooldown after a failed recovery probe
+func (b *agentBreaker) reopen(agentID string, now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if state, ok := b.agents[agentID]; ok {
+		state.trialSince = time.Time{}
+		state.openUntil = now.Add(b.cooldown)
+	}
+}
+
+// checkBreaker rejects tasks for degraded agents; the first task after the cooldown is
+// preceded by a stream probe when one is configured (not for queued tasks, whose dispatch must not block)
+func (s *Scheduler) checkBreaker(ctx context.Context, agentID string, fromQueue bool) error {
+	b := s.breaker
+	if b == nil {
+		return nil
+	}
+
+	trial, err := b.admit(agentID, time.Now())
+	if err != nil || !trial || s.prober == nil || fromQueue {
+		return err
+	}
+
+	if err := s.prober.ProbeAgent(ctx, agentID, s.probeTimeout); err != nil {
+		now := time.Now()
+		b.reopen(agentID, now)
+		s.agentManager.SetAgentDegraded(agentID, now.Add(b.cooldown))
+		logger.Warn("Degraded agent failed recovery probe",
+			zap.String("agent_id", agentID),
+			zap.Duration("cooldown", b.cooldown),
+			zap.Error(err),
+		)
+		return fmt.Errorf("%w: %s (recovery probe failed)", ErrAgentDegraded, agentID)
+	}
+	return nil
+}
+
+// markAgentFault records that a task failed because of its agent or the agent's connection
+func (s *Scheduler) markAgentFault(taskID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if taskInfo, ok := s.tasks[taskID]; ok {
+		taskInfo.agentFault = true
+	}
+}
+
+// recordBreaker updates the breaker with a finished task and reports state changes
+func (s *Scheduler) recordBreaker(agentID string, status pb.TaskStatus, fault bool) {
+	b := s.breaker
+	if b == nil || agentID == "" {
+		return
+	}
+
+	now := time.Now()
+	tripped, recovered := b.record(agentID, status, fault, now)
+	switch {
+	case recovered:
+		s.agentManager.SetAgentDegraded(agentID, time.Time{})
+		logger.Info("Degraded agent recovered", zap.String("agent_id", agentID))
+	case tripped:
+		s.agentManager.SetAgentDegraded(agentID, now.Add(b.cooldown))
+		logger.Warn("Agent degraded after consecutive task failures",
+			zap.String("agent_id", agentID),
+			zap.Int("threshold", b.threshold),
+			zap.Duration("cooldown", b.cooldown),
+		)
+		s.notifyAgentDegraded(agentID, b)
+	}
+}
+
+// notifyAgentDegraded sends an agent-error notification for a tripped breaker
+func (s *Scheduler) notifyAgentDegraded(agentID string, b *agentBreaker) {
+	if s.notifier == nil || s.eventConfig == nil || !s.eventConfig.AgentError {
+		return
+	}
+
+	name := agentID
+	if ag, err := s.agentManager.GetAgent(agentID); err == nil && ag.Info.GetName() != "" {
+		name = ag.Info.GetName()
+	}
+	s.notifier.Notify(notifier.NewAgentErrorEvent(agentID, name,
+		fmt.Sprintf("%d consecutive task failures, dispatch paused for %s", b.threshold, b.cooldown)))
+}
@@ -0,0 +1,139 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func newTestBreaker(threshold int, cooldown time.Duration) *agentBreaker {
+	return &agentBreaker{
+		threshold: threshold,
+		window:    time.Minute,
+		cooldown:  cooldown,
+		agents:    make(map[string]*breakerState),
+	}
+}
+
+func TestBreakerTripsAfterConsecutiveAgentFaults(t *testing.T) {
+	b := newTestBreaker(3, time.Minute)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if tripped, _ := b.record("a", pb.TaskStatus_TASK_STATUS_FAILED, true, now); tripped {
+			t.Fatalf("tripped after %d faults, want 3", i+1)
+		}
+	}
+	if tripped, _ := b.record("a", pb.TaskStatus_TASK_STATUS_FAILED, true, now); !tripped {
+		t.Fatal("not tripped after 3 faults")
+	}
+	if _, err := b.admit("a", now.Add(time.Second)); !errors.Is(err, ErrAgentDegraded) {
+		t.Fatalf("admit during cooldown error = %v, want ErrAgentDegraded", err)
+	}
+	if _, err := b.admit("b", now); err != nil {
+		t.Fatalf("admit other agent error = %v", err)
+	}
+}
+
+func TestBreakerIgnoresTaskFailures(t *testing.T) {
+	b := newTestBreaker(2, time.Minute)
+	now := time.Now()
+
+	// Denylist rejections, output limits and bad targets are reported as failures without a fault
+	for i := 0; i < 5; i++ {
+		if tripped, _ := b.record("a", pb.TaskStatus_TASK_STATUS_FAILED, false, now); tripped {
+			t.Fatal("tripped on failures that are not agent faults")
+		}
+	}
+	if _, err := b.admit("a", now); err != nil {
+		t.Fatalf("admit error = %v", err)
+	}
+}
+
+func TestBreakerRecoversAfterCooldown(t *testing.T) {
+	b := newTestBreaker(1, time.Minute)
+	now := time.Now()
+	b.record("a", pb.TaskStatus_TASK_STATUS_FAILED, true, now)
+
+	after := now.Add(time.Minute + time.Second)
+	trial, err := b.admit("a", after)
+	if err != nil || !trial {
+		t.Fatalf("admit after cooldown = %v, %v, want a trial", trial, err)
+	}
+	if _, err := b.admit("a", after); !errors.Is(err, ErrAgentDegraded) {
+		t.Fatalf("second admit during trial error = %v, want ErrAgentDegraded", err)
+	}
+	if _, recovered := b.record("a", pb.TaskStatus_TASK_STATUS_COMPLETED, false, after); !recovered {
+		t.Fatal("successful trial did not recover the agent")
+	}
+	if trial, err := b.admit("a", after); err != nil || trial {
+		t.Fatalf("admit after recovery = %v, %v, want a normal dispatch", trial, err)
+	}
+}
+
+func TestBreakerFailedTrialReopens(t *testing.T) {
+	b := newTestBreaker(1, time.Minute)
+	now := time.Now()
+	b.record("a", pb.TaskStatus_TASK_STATUS_FAILED, true, now)
+
+	after := now.Add(time.Minute + time.Second)
+	b.admit("a", after)
+	if tripped, _ := b.record("a", pb.TaskStatus_TASK_STATUS_FAILED, true, after); !tripped {
+		t.Fatal("failed trial did not degrade the agent again")
+	}
+	if _, err := b.admit("a", after.Add(time.Second)); !errors.Is(err, ErrAgentDegraded) {
+		t.Fatalf("admit after failed trial error = %v, want ErrAgentDegraded", err)
+	}
+}
+
+func TestBreakerCancelledTrialClearsTrial(t *testing.T) {
+	b := newTestBreaker(1, time.Minute)
+	now := time.Now()
+	b.record("a", pb.TaskStatus_TASK_STATUS_FAILED, true, now)
+
+	after := now.Add(time.Minute + time.Second)
+	b.admit("a", after)
+	b.record("a", pb.TaskStatus_TASK_STATUS_CANCELLED, false, after)
+
+	// The next task becomes the trial instead of waiting for the abandoned one to expire
+	trial, err := b.admit("a", after.Add(time.Second))
+	if err != nil || !trial {
+		t.Fatalf("admit after cancelled trial = %v, %v, want a new trial", trial, err)
+	}
+}
+
+func TestSchedulerDegradesAgentOnAgentFaults(t *testing.T) {
+	s, am, sender := newTestScheduler(t, "agent-1")
+	s.SetCircuitBreaker(2, time.Minute, time.Minute)
+
+	fail := func(id string, fault bool) {
+		t.Helper()
+		rec := newOutputRecorder()
+		if err := s.SubmitTask(t.Context(), pingTask(id, "agent-1", "192.0.2.1"), "client-1", rec.handle); err != nil {
+			t.Fatalf("SubmitTask(%s) error = %v", id, err)
+		}
+		sender.waitSent(t)
+		s.HandleTaskOutput(&pb.TaskOutput{TaskId: id, Status: pb.TaskStatus_TASK_STATUS_FAILED, ErrorMessage: "failed", AgentFault: fault})
+		rec.wait(t)
+	}
+
+	for i := 0; i < 3; i++ {
+		fail(fmt.Sprintf("rejected-%d", i), false)
+	}
+	if a, _ := am.GetAgent("agent-1"); !a.DegradedUntil.IsZero() {
+		t.Fatal("agent degraded by task failures")
+	}
+
+	fail("fault-1", true)
+	fail("fault-2", true)
+	if a, _ := am.GetAgent("agent-1"); a.DegradedUntil.IsZero() {
+		t.Fatal("agent not marked degraded after 2 agent faults")
+	}
+	err := s.SubmitTask(t.Context(), pingTask("t3", "agent-1", "192.0.2.1"), "client-1", newOutputRecorder().handle)
+	if !errors.Is(err, ErrAgentDegraded) {
+		t.Fatalf("SubmitTask() to degraded agent error = %v, want ErrAgentDegraded", err)
+	}
+}
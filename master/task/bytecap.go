@@ -0,0 +1,90 @@
+package task
+
+import (
+	"fmt"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// SetMaxTransferBytes stops a task once the output its agent sent exceeds maxBytes bytes
+// Compressed blocks count at their compressed size, as received. The task fails with an error
+// naming the cap; maxBytes <= 0 disables the cap
+func (s *Scheduler) SetMaxTransferBytes(maxBytes int64) {
+	if maxBytes < 0 {
+		maxBytes = 0
+	}
+	s.maxTransferBytes = maxBytes
+}
+
+// countOutputBytes adds the output's line or compressed block to its task's byte count
+// over reports that the output is past the cap and must be dropped; crossed is set only
+// for the output that pushed the task over it, so the task is stopped once
+func (s *Scheduler) countOutputBytes(output *pb.TaskOutput) (over, crossed bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	taskInfo, ok := s.tasks[output.TaskId]
+	if !ok {
+		return false, false
+	}
+	if taskInfo.overByteCap {
+		return true, false
+	}
+	size := len(output.OutputLine) + len(output.CompressedOutput)
+	if size == 0 || isTerminalStatus(taskInfo.Status) {
+		return false, false
+	}
+
+	taskInfo.bytesTransferred += int64(size)
+	if s.maxTransferBytes > 0 && taskInfo.bytesTransferred > s.maxTransferBytes {
+		taskInfo.overByteCap = true
+		return true, true
+	}
+	return false, false
+}
+
+// bytesTransferred returns the output bytes received for a task so far
+func (s *Scheduler) bytesTransferred(taskID string) (int64, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	taskInfo, ok := s.tasks[taskID]
+	if !ok {
+		return 0, false
+	}
+	return taskInfo.bytesTransferred, true
+}
+
+// stopOverByteCap cancels a task on its agent and fails it after its output exceeded the cap
+func (s *Scheduler) stopOverByteCap(taskID string) {
+	s.mutex.RLock()
+	taskInfo, ok := s.tasks[taskID]
+	var agentID string
+	if ok {
+		agentID = taskInfo.AgentID
+	}
+	s.mutex.RUnlock()
+	if !ok {
+		return
+	}
+
+	logger.Warn("Task output exceeded byte cap",
+		zap.String("task_id", taskID),
+		zap.String("agent_id", agentID),
+		zap.Int64("max_transfer_bytes", s.maxTransferBytes),
+	)
+
+	if s.streamSender == nil {
+		logger.Warn("Stream sender not configured, skipping agent-side cancel after byte cap",
+			zap.String("task_id", taskID),
+		)
+	} else if err := s.streamSender.CancelTaskOnAgent(agentID, taskID); err != nil {
+		logger.Error("Failed to cancel task on agent after byte cap",
+			zap.String("task_id", taskID),
+			zap.Error(err),
+		)
+	}
+	s.handleTaskError(taskID, fmt.Errorf("task output exceeded %d bytes", s.maxTransferBytes))
+}
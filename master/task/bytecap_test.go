@@ -0,0 +1,80 @@
+package task
+
+import (
+	"strings"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/outputblock"
+)
+
+func TestByteCapStopsTask(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	s.SetMaxTransferBytes(10)
+	rec := newOutputRecorder()
+	if err := s.SubmitTask(t.Context(), pingTask("t1", "agent-1", "192.0.2.1"), "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	sender.waitSent(t)
+
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_RUNNING, OutputLine: "123456"})
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_RUNNING, OutputLine: "789012"})
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_RUNNING, OutputLine: "dropped"})
+
+	outputs := rec.wait(t)
+	final := outputs[len(outputs)-1]
+	if final.Status != pb.TaskStatus_TASK_STATUS_FAILED || !strings.Contains(final.ErrorMessage, "exceeded 10 bytes") {
+		t.Errorf("final output = %v, want a byte cap failure", final)
+	}
+	for _, output := range outputs {
+		if output.OutputLine == "789012" || output.OutputLine == "dropped" {
+			t.Errorf("forwarded %q past the cap", output.OutputLine)
+		}
+	}
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	if len(sender.cancelled) != 1 || sender.cancelled[0] != "t1" {
+		t.Errorf("cancelled on agent = %v, want [t1]", sender.cancelled)
+	}
+}
+
+func TestByteCapCountsCompressedSize(t *testing.T) {
+	// Highly compressible: far larger expanded than as sent
+	lines := make([]string, 1000)
+	for i := range lines {
+		lines[i] = strings.Repeat("a", 100)
+	}
+	block, err := outputblock.Encode(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, sender := newTestScheduler(t, "agent-1")
+	s.SetMaxTransferBytes(int64(len(block)) + 100)
+	rec := newOutputRecorder()
+	if err := s.SubmitTask(t.Context(), pingTask("t1", "agent-1", "192.0.2.1"), "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	sender.waitSent(t)
+
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_RUNNING, CompressedOutput: block, Sequence: 1})
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_COMPLETED, Sequence: 2})
+
+	outputs := rec.wait(t)
+	final := outputs[len(outputs)-1]
+	if final.Status != pb.TaskStatus_TASK_STATUS_COMPLETED {
+		t.Fatalf("final output = %v, want completed", final)
+	}
+	if got := final.GetSummary().GetBytesTransferred(); got != int64(len(block)) {
+		t.Errorf("bytes_transferred = %d, want the block's %d bytes", got, len(block))
+	}
+	forwarded := 0
+	for _, output := range outputs {
+		if output.OutputLine != "" {
+			forwarded += len(strings.Split(output.OutputLine, "\n"))
+		}
+	}
+	if forwarded != len(lines) {
+		t.Errorf("forwarded %d lines, want %d", forwarded, len(lines))
+	}
+}
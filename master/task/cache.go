@@ -0,0 +1,241 @@
+package task
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// resultCacheMaxEntries bounds how many results are cached; the least recently used go first
+	resultCacheMaxEntries = 1000
+	// resultCacheMaxOutputs bounds the outputs of a cacheable task; longer results are not cached
+	resultCacheMaxOutputs = 1000
+)
+
+// resultCache keeps the output of completed tasks for replay to identical submissions
+type resultCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	lru     *list.List                 // Of *cachedResult, most recently used first
+	entries map[string]*list.Element   // Cache key -> lru element
+	pending map[string]*capturedResult // Task ID -> outputs captured while it runs
+}
+
+// cachedResult is a completed task's output, stored under its cache key
+type cachedResult struct {
+	key      string
+	outputs  []*pb.TaskOutput
+	summary  *pb.TaskSummary
+	storedAt time.Time
+}
+
+// capturedResult collects the outputs of a running task
+type capturedResult struct {
+	outputs  []*pb.TaskOutput
+	summary  *pb.TaskSummary
+	overflow bool
+}
+
+// submitAckKey carries the channel a cache replay waits on before sending output
+type submitAckKey struct{}
+
+// WithSubmitAck delays output replayed from the result cache until acked is closed, so that it
+// follows the acknowledgment the caller sends once SubmitTask (or SubmitGroup) returns
+func WithSubmitAck(ctx context.Context, acked <-chan struct{}) context.Context {
+	return context.WithValue(ctx, submitAckKey{}, acked)
+}
+
+// submitAckFromContext returns the channel set by WithSubmitAck (nil = replay right away)
+func submitAckFromContext(ctx context.Context) <-chan struct{} {
+	acked, _ := ctx.Value(submitAckKey{}).(<-chan struct{})
+	return acked
+}
+
+// SetResultCacheTTL replays the output of a completed task to identical submissions (same agent,
+// task and parameters) for ttl instead of running it again
+// A ttl <= 0 disables the cache
+func (s *Scheduler) SetResultCacheTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		s.cache = nil
+		return
+	}
+	s.cache = &resultCache{
+		ttl:     ttl,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		pending: make(map[string]*capturedResult),
+	}
+}
+
+// resultCacheKey identifies a task by agent, task name, timeout and every parameter, with the
+// target normalized; only fields that cannot change a completed result (ID, timestamps, deadline,
+// retry policy) are left out
+func resultCacheKey(task *pb.Task) string {
+	normalized := proto.Clone(task).(*pb.Task)
+	normalized.TaskId = ""
+	normalized.CreatedAt = nil
+	normalized.Deadline = nil
+	normalized.Retry = nil
+	if params := normalized.GetNetworkTest(); params != nil {
+		params.Target = strings.ToLower(strings.TrimSpace(params.Target))
+	}
+
+	data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(normalized)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// replayCached replays a fresh cached result for task to outputHandler, reporting whether there
+// was one. The replay runs in the background once the submission is acknowledged (WithSubmitAck),
+// so a handler that blocks while its consumer catches up never holds up the submitter.
+func (s *Scheduler) replayCached(ctx context.Context, task *pb.Task, clientID string, outputHandler func(*pb.TaskOutput)) bool {
+	c := s.cache
+	if c == nil || outputHandler == nil || clientID == selfCheckClientID {
+		return false
+	}
+
+	key := resultCacheKey(task)
+	c.mu.Lock()
+	elem, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		return false
+	}
+	result := elem.Value.(*cachedResult)
+	if time.Since(result.storedAt) > c.ttl {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		c.mu.Unlock()
+		return false
+	}
+	c.lru.MoveToFront(elem)
+	c.mu.Unlock()
+
+	logger.Info("Task served from result cache",
+		zap.String("task_id", task.TaskId),
+		zap.String("agent_id", task.AgentId),
+		zap.Duration("age", time.Since(result.storedAt)),
+	)
+
+	acked := submitAckFromContext(ctx)
+	go func() {
+		if acked != nil {
+			<-acked
+		}
+
+		// Cached outputs are never modified; send copies carrying the new task ID, merged into
+		// batches so a long result is a few messages rather than one per line
+		outputs := make([]*pb.TaskOutput, 0, len(result.outputs))
+		for _, cached := range result.outputs {
+			output := proto.Clone(cached).(*pb.TaskOutput)
+			output.TaskId = task.TaskId
+			outputs = append(outputs, output)
+		}
+		for _, output := range batchOutputs(outputs) {
+			output.Cached = true
+			outputHandler(output)
+		}
+
+		var summary *pb.TaskSummary
+		if result.summary != nil {
+			summary = proto.Clone(result.summary).(*pb.TaskSummary)
+		}
+		outputHandler(&pb.TaskOutput{
+			TaskId:    task.TaskId,
+			Timestamp: timestamppb.New(time.Now()),
+			Status:    pb.TaskStatus_TASK_STATUS_COMPLETED,
+			Summary:   summary,
+			Cached:    true,
+		})
+	}()
+	return true
+}
+
+// captureResult records an output of a running task for caching
+func (s *Scheduler) captureResult(output *pb.TaskOutput) {
+	c := s.cache
+	if c == nil {
+		return
+	}
+
+	// Late outputs of finished tasks would never be collected
+	s.mutex.RLock()
+	taskInfo, known := s.tasks[output.TaskId]
+	running := known && !isTerminalStatus(taskInfo.Status)
+	s.mutex.RUnlock()
+	if !running {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	captured, ok := c.pending[output.TaskId]
+	if !ok {
+		captured = &capturedResult{}
+		c.pending[output.TaskId] = captured
+	}
+
+	switch {
+	case output.Status == pb.TaskStatus_TASK_STATUS_RUNNING:
+		if len(captured.outputs) >= resultCacheMaxOutputs {
+			captured.overflow = true
+			return
+		}
+		captured.outputs = append(captured.outputs, proto.Clone(output).(*pb.TaskOutput))
+	case output.Status == pb.TaskStatus_TASK_STATUS_COMPLETED && output.Summary != nil:
+		captured.summary = proto.Clone(output.Summary).(*pb.TaskSummary)
+	}
+}
+
+// storeResult caches the captured output of a finished task if it completed successfully
+func (s *Scheduler) storeResult(taskInfo *TaskInfo, status pb.TaskStatus) {
+	c := s.cache
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	captured, ok := c.pending[taskInfo.Task.TaskId]
+	delete(c.pending, taskInfo.Task.TaskId)
+	if !ok || captured.overflow || status != pb.TaskStatus_TASK_STATUS_COMPLETED || taskInfo.ClientID == selfCheckClientID {
+		return
+	}
+
+	// Key on the agent that actually ran it
+	task := proto.Clone(taskInfo.Task).(*pb.Task)
+	task.AgentId = taskInfo.AgentID
+	key := resultCacheKey(task)
+
+	result := &cachedResult{
+		key:      key,
+		outputs:  captured.outputs,
+		summary:  captured.summary,
+		storedAt: time.Now(),
+	}
+	if elem, exists := c.entries[key]; exists {
+		elem.Value = result
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(result)
+
+	for c.lru.Len() > resultCacheMaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResult).key)
+	}
+}
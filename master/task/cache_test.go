@@ -0,0 +1,124 @@
+package task
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestResultCacheKey(t *testing.T) {
+	base := pingTask("t1", "agent-1", "example.com")
+	key := resultCacheKey(base)
+
+	same := pingTask("t2", "agent-1", " Example.COM ")
+	if resultCacheKey(same) != key {
+		t.Error("task ID or target case/spacing changed the key")
+	}
+
+	variants := map[string]func(*pb.Task){
+		"agent":         func(t *pb.Task) { t.AgentId = "agent-2" },
+		"task name":     func(t *pb.Task) { t.TaskName = "mtr" },
+		"task timeout":  func(t *pb.Task) { t.Timeout = 5 },
+		"param timeout": func(t *pb.Task) { t.GetNetworkTest().Timeout = 5 },
+		"count":         func(t *pb.Task) { t.GetNetworkTest().Count = 10 },
+		"ipv6":          func(t *pb.Task) { t.GetNetworkTest().Ipv6 = true },
+		"extra option":  func(t *pb.Task) { t.GetNetworkTest().ExtraOptions = map[string]string{"size": "1400"} },
+		"compression":   func(t *pb.Task) { t.CompressOutput = true },
+	}
+	for name, mutate := range variants {
+		task := pingTask("t3", "agent-1", "example.com")
+		mutate(task)
+		if resultCacheKey(task) == key {
+			t.Errorf("%s does not change the key", name)
+		}
+	}
+}
+
+// runCachedTask runs a ping to completion so its result is cached
+func runCachedTask(t *testing.T, s *Scheduler, sender *fakeSender, lines int) {
+	t.Helper()
+	rec := newOutputRecorder()
+	if err := s.SubmitTask(t.Context(), pingTask("first", "agent-1", "1.1.1.1"), "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	sender.waitSent(t)
+	for i := range lines {
+		s.HandleTaskOutput(&pb.TaskOutput{TaskId: "first", OutputLine: "line", Sequence: uint64(i + 1), Status: pb.TaskStatus_TASK_STATUS_RUNNING})
+	}
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "first", Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+	rec.wait(t)
+}
+
+func TestCachedReplayFollowsSubmitAck(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	s.SetResultCacheTTL(time.Minute)
+	runCachedTask(t, s, sender, 250)
+
+	acked := make(chan struct{})
+	ctx := WithSubmitAck(context.Background(), acked)
+	rec := newOutputRecorder()
+	if err := s.SubmitTask(ctx, pingTask("second", "agent-1", "1.1.1.1"), "client-2", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	rec.mu.Lock()
+	early := len(rec.outputs)
+	rec.mu.Unlock()
+	if early != 0 {
+		t.Fatalf("%d outputs replayed before the ack", early)
+	}
+
+	close(acked)
+	outputs := rec.wait(t)
+
+	// 250 lines replay as batches of up to maxCoalescedLines, then the completion
+	if len(outputs) != 4 {
+		t.Fatalf("got %d outputs, want 3 batches and the completion", len(outputs))
+	}
+	lines := 0
+	for _, o := range outputs[:3] {
+		if o.TaskId != "second" || !o.Cached {
+			t.Errorf("replayed output = %v, want task second, cached", o)
+		}
+		lines += strings.Count(o.OutputLine, "line")
+	}
+	if lines != 250 {
+		t.Errorf("replayed %d lines, want 250", lines)
+	}
+	if last := outputs[3]; last.Status != pb.TaskStatus_TASK_STATUS_COMPLETED || !last.Cached {
+		t.Errorf("last output = %v, want cached COMPLETED", last)
+	}
+	select {
+	case task := <-sender.sent:
+		t.Errorf("cached task dispatched to agent: %s", task.TaskId)
+	default:
+	}
+}
+
+func TestCachedReplayDoesNotBlockSubmit(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	s.SetResultCacheTTL(time.Minute)
+	runCachedTask(t, s, sender, 10)
+
+	// A handler whose consumer never reads must not hold up SubmitTask
+	block := make(chan struct{})
+	defer close(block)
+	handler := func(*pb.TaskOutput) { <-block }
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.SubmitTask(context.Background(), pingTask("second", "agent-1", "1.1.1.1"), "client-2", handler)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("SubmitTask() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SubmitTask blocked on the cache replay")
+	}
+}
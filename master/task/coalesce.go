@@ -0,0 +1,146 @@
+package task
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxCoalescedLines bounds how many lines are merged into a single forwarded output
+const maxCoalescedLines = 100
+
+// coalescingHandler batches plain output lines of one task over a short window
+// before invoking the wrapped handler. Any other output (status changes, errors,
+// summaries) flushes the pending batch first, so ordering is preserved.
+type coalescingHandler struct {
+	handler func(*pb.TaskOutput)
+	window  time.Duration
+
+	mu      sync.Mutex
+	pending *pb.TaskOutput
+	lines   int
+	timer   *time.Timer
+}
+
+// newCoalescingHandler wraps handler with a coalescing buffer of the given window
+func newCoalescingHandler(handler func(*pb.TaskOutput), window time.Duration) *coalescingHandler {
+	return &coalescingHandler{
+		handler: handler,
+		window:  window,
+	}
+}
+
+// handle buffers or forwards a single output
+func (c *coalescingHandler) handle(output *pb.TaskOutput) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !isCoalescable(output) {
+		c.flushLocked()
+		c.handler(output)
+		return
+	}
+
+	n := lineCount(output.OutputLine)
+	if c.pending != nil && c.lines+n > maxCoalescedLines {
+		c.flushLocked()
+	}
+
+	if c.pending == nil {
+		c.pending = &pb.TaskOutput{
+			TaskId:     output.TaskId,
+			OutputLine: output.OutputLine,
+			Timestamp:  output.Timestamp,
+			Status:     output.Status,
+			Sequence:   output.Sequence,
+		}
+		c.lines = n
+		c.timer = time.AfterFunc(c.window, c.flush)
+	} else {
+		// Lines without their own newline (e.g. ping) need a separator
+		if !strings.HasSuffix(c.pending.OutputLine, "\n") {
+			c.pending.OutputLine += "\n"
+		}
+		c.pending.OutputLine += output.OutputLine
+		c.pending.Sequence = output.Sequence
+		c.lines += n
+	}
+
+	if c.lines >= maxCoalescedLines {
+		c.flushLocked()
+	}
+}
+
+// flush sends any pending batch (called by the window timer)
+func (c *coalescingHandler) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushLocked()
+}
+
+// flushLocked sends the pending batch; caller must hold c.mu
+func (c *coalescingHandler) flushLocked() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.pending == nil {
+		return
+	}
+
+	pending := c.pending
+	c.pending = nil
+	c.lines = 0
+	c.handler(pending)
+}
+
+// isCoalescable reports whether an output is a plain running line that may be merged
+func isCoalescable(output *pb.TaskOutput) bool {
+	return output.Status == pb.TaskStatus_TASK_STATUS_RUNNING &&
+		output.ErrorMessage == "" &&
+		output.Summary == nil
+}
+
+// batchOutputs merges runs of plain output lines of the same task into outputs of up to
+// maxCoalescedLines lines each, the way coalescingHandler does; other outputs pass unchanged
+// Outputs that already carry several lines count with all of them
+func batchOutputs(outputs []*pb.TaskOutput) []*pb.TaskOutput {
+	batched := make([]*pb.TaskOutput, 0, len(outputs))
+	var pending *pb.TaskOutput
+	lines := 0
+	for _, output := range outputs {
+		n := lineCount(output.OutputLine)
+		if pending != nil && (!isCoalescable(output) || output.TaskId != pending.TaskId || lines+n > maxCoalescedLines) {
+			batched = append(batched, pending)
+			pending = nil
+		}
+		if !isCoalescable(output) {
+			batched = append(batched, output)
+			continue
+		}
+		if pending == nil {
+			pending = proto.Clone(output).(*pb.TaskOutput)
+			lines = n
+			continue
+		}
+		// Lines without their own newline (e.g. ping) need a separator
+		if !strings.HasSuffix(pending.OutputLine, "\n") {
+			pending.OutputLine += "\n"
+		}
+		pending.OutputLine += output.OutputLine
+		pending.Sequence = output.Sequence
+		lines += n
+	}
+	if pending != nil {
+		batched = append(batched, pending)
+	}
+	return batched
+}
+
+// lineCount returns how many lines an output line holds (batches hold several)
+func lineCount(text string) int {
+	return strings.Count(strings.TrimSuffix(text, "\n"), "\n") + 1
+}
@@ -0,0 +1,94 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// handledOutputs collects what a coalescing handler forwards
+type handledOutputs struct {
+	mu      sync.Mutex
+	outputs []*pb.TaskOutput
+}
+
+func (h *handledOutputs) handle(output *pb.TaskOutput) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.outputs = append(h.outputs, output)
+}
+
+func (h *handledOutputs) get() []*pb.TaskOutput {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]*pb.TaskOutput(nil), h.outputs...)
+}
+
+func runningLine(line string, seq uint64) *pb.TaskOutput {
+	return &pb.TaskOutput{TaskId: "t1", OutputLine: line, Status: pb.TaskStatus_TASK_STATUS_RUNNING, Sequence: seq}
+}
+
+func TestCoalescingHandlerBatchesWithinWindow(t *testing.T) {
+	got := &handledOutputs{}
+	c := newCoalescingHandler(got.handle, 20*time.Millisecond)
+
+	c.handle(runningLine("one", 1))
+	c.handle(runningLine("two\n", 2))
+	c.handle(runningLine("three", 3))
+	if n := len(got.get()); n != 0 {
+		t.Fatalf("forwarded %d outputs before the window closed", n)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(got.get()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	outputs := got.get()
+	if len(outputs) != 1 {
+		t.Fatalf("forwarded %d outputs, want 1 batch", len(outputs))
+	}
+	if outputs[0].OutputLine != "one\ntwo\nthree" || outputs[0].Sequence != 3 {
+		t.Errorf("batch = %q (sequence %d), want the three lines ending at sequence 3", outputs[0].OutputLine, outputs[0].Sequence)
+	}
+}
+
+func TestCoalescingHandlerFlushesBeforeStatus(t *testing.T) {
+	got := &handledOutputs{}
+	c := newCoalescingHandler(got.handle, time.Hour)
+
+	c.handle(runningLine("one", 1))
+	c.handle(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_COMPLETED, Sequence: 2})
+
+	outputs := got.get()
+	if len(outputs) != 2 {
+		t.Fatalf("forwarded %d outputs, want the batch then the status", len(outputs))
+	}
+	if outputs[0].OutputLine != "one" || outputs[1].Status != pb.TaskStatus_TASK_STATUS_COMPLETED {
+		t.Errorf("outputs = %v, want the line before the completion", outputs)
+	}
+}
+
+func TestCoalescingHandlerCapsBatchLines(t *testing.T) {
+	got := &handledOutputs{}
+	c := newCoalescingHandler(got.handle, time.Hour)
+
+	for i := range maxCoalescedLines + 10 {
+		c.handle(runningLine(fmt.Sprintf("line %d", i), uint64(i+1)))
+	}
+	c.flush()
+
+	outputs := got.get()
+	if len(outputs) != 2 {
+		t.Fatalf("forwarded %d batches, want 2", len(outputs))
+	}
+	if n := len(strings.Split(outputs[0].OutputLine, "\n")); n != maxCoalescedLines {
+		t.Errorf("first batch has %d lines, want %d", n, maxCoalescedLines)
+	}
+	if n := len(strings.Split(outputs[1].OutputLine, "\n")); n != 10 {
+		t.Errorf("second batch has %d lines, want 10", n)
+	}
+}
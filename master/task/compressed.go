@@ -0,0 +1,44 @@
+package task
+
+import (
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"github.com/lureiny/lookingglass/pkg/outputblock"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// compressedMaxBytes bounds the decompressed size of one compressed output block
+const compressedMaxBytes = 64 << 20
+
+// forwardCompressed expands a compressed output block and forwards its lines to the client in
+// batches of up to maxCoalescedLines, so a large block does not flood the client one line at a time
+// The block already counted against the byte cap at its compressed size in HandleTaskOutput
+func (s *Scheduler) forwardCompressed(output *pb.TaskOutput) {
+	lines, err := outputblock.Decode(output.CompressedOutput, compressedMaxBytes)
+	if err != nil {
+		logger.Warn("Dropped compressed task output",
+			zap.String("task_id", output.TaskId),
+			zap.Error(err),
+		)
+		lines = []string{"[" + err.Error() + "]"}
+	}
+
+	expanded := make([]*pb.TaskOutput, 0, len(lines))
+	for _, line := range lines {
+		lineOutput := proto.Clone(output).(*pb.TaskOutput)
+		lineOutput.CompressedOutput = nil
+		lineOutput.OutputLine = line
+		if !s.filterOutput(lineOutput) {
+			expanded = append(expanded, lineOutput)
+		}
+	}
+	s.forwardBatches(expanded)
+}
+
+// forwardBatches forwards outputs merged into batches by batchOutputs
+func (s *Scheduler) forwardBatches(outputs []*pb.TaskOutput) {
+	for _, batch := range batchOutputs(outputs) {
+		s.forwardOutput(batch)
+	}
+}
@@ -0,0 +1,70 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/outputblock"
+)
+
+func TestForwardCompressedSendsBoundedBatches(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	rec := newOutputRecorder()
+	if err := s.SubmitTask(t.Context(), pingTask("t1", "agent-1", "192.0.2.1"), "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	sender.waitSent(t)
+
+	lines := make([]string, 250)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+	block, err := outputblock.Encode(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_RUNNING, CompressedOutput: block, Sequence: 1})
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_COMPLETED, Sequence: 2})
+
+	var got []string
+	batches := 0
+	for _, output := range rec.wait(t) {
+		if output.OutputLine == "" {
+			continue
+		}
+		batches++
+		if n := len(strings.Split(output.OutputLine, "\n")); n > maxCoalescedLines {
+			t.Errorf("batch of %d lines, want at most %d", n, maxCoalescedLines)
+		}
+		got = append(got, strings.Split(output.OutputLine, "\n")...)
+	}
+	if batches != 3 {
+		t.Errorf("forwarded %d batches, want 3", batches)
+	}
+	if strings.Join(got, "|") != strings.Join(lines, "|") {
+		t.Errorf("forwarded lines differ from the block's")
+	}
+}
+
+func TestBatchOutputsCountsEveryLine(t *testing.T) {
+	var outputs []*pb.TaskOutput
+	for i := 0; i < 5; i++ {
+		outputs = append(outputs, &pb.TaskOutput{
+			TaskId:     "t1",
+			Status:     pb.TaskStatus_TASK_STATUS_RUNNING,
+			OutputLine: strings.Repeat("x\n", 39) + "x",
+		})
+	}
+
+	batched := batchOutputs(outputs)
+	for _, output := range batched {
+		if n := lineCount(output.OutputLine); n > maxCoalescedLines {
+			t.Errorf("batch of %d lines, want at most %d", n, maxCoalescedLines)
+		}
+	}
+	if len(batched) != 3 {
+		t.Errorf("got %d batches of 200 lines, want 3", len(batched))
+	}
+}
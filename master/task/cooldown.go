@@ -0,0 +1,60 @@
+package task
+
+import (
+	"sync"
+	"time"
+)
+
+// cooldownKey identifies a repeated submission of the same test by the same client
+type cooldownKey struct {
+	clientID string
+	taskName string
+	target   string
+}
+
+// submitCooldown rejects resubmissions of the same task+target by a client within a window
+type submitCooldown struct {
+	window    time.Duration
+	last      map[cooldownKey]time.Time
+	lastSweep time.Time
+	mutex     sync.Mutex
+}
+
+// newSubmitCooldown creates a cooldown tracker with the given window
+func newSubmitCooldown(window time.Duration) *submitCooldown {
+	return &submitCooldown{
+		window: window,
+		last:   make(map[cooldownKey]time.Time),
+	}
+}
+
+// remaining returns how long the client must still wait before resubmitting (0 = allowed)
+func (c *submitCooldown) remaining(key cooldownKey, now time.Time) time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	last, ok := c.last[key]
+	if !ok {
+		return 0
+	}
+	if wait := c.window - now.Sub(last); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// record stores a submission time, dropping expired entries at most once per window
+func (c *submitCooldown) record(key cooldownKey, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if now.Sub(c.lastSweep) >= c.window {
+		for k, last := range c.last {
+			if now.Sub(last) >= c.window {
+				delete(c.last, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.last[key] = now
+}
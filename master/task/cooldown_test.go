@@ -0,0 +1,57 @@
+package task
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestSubmitCooldownRemaining(t *testing.T) {
+	c := newSubmitCooldown(10 * time.Second)
+	now := time.Now()
+	key := cooldownKey{clientID: "c1", taskName: "ping", target: "1.1.1.1"}
+
+	if wait := c.remaining(key, now); wait != 0 {
+		t.Fatalf("first submission wait = %v, want 0", wait)
+	}
+	c.record(key, now)
+	if wait := c.remaining(key, now.Add(4*time.Second)); wait != 6*time.Second {
+		t.Errorf("wait after 4s = %v, want 6s", wait)
+	}
+	other := cooldownKey{clientID: "c1", taskName: "ping", target: "8.8.8.8"}
+	if wait := c.remaining(other, now); wait != 0 {
+		t.Errorf("other target wait = %v, want 0", wait)
+	}
+	if wait := c.remaining(key, now.Add(10*time.Second)); wait != 0 {
+		t.Errorf("wait after the window = %v, want 0", wait)
+	}
+
+	// Expired entries are swept on the next record
+	c.record(other, now.Add(time.Minute))
+	if _, ok := c.last[key]; ok {
+		t.Error("expired entry not swept")
+	}
+}
+
+func TestSubmitTaskCooldown(t *testing.T) {
+	s, _, _ := newTestScheduler(t, "a")
+	s.SetSubmitCooldown(time.Minute)
+	noop := func(*pb.TaskOutput) {}
+
+	if err := s.SubmitTask(context.Background(), pingTask("t1", "a", "1.1.1.1"), "c1", noop); err != nil {
+		t.Fatalf("first submit: %v", err)
+	}
+	err := s.SubmitTask(context.Background(), pingTask("t2", "a", "1.1.1.1"), "c1", noop)
+	if err == nil || !strings.Contains(err.Error(), "please wait 60 seconds") {
+		t.Errorf("repeat submit: err = %v, want cooldown error", err)
+	}
+	if err := s.SubmitTask(context.Background(), pingTask("t3", "a", "1.1.1.1"), "c2", noop); err != nil {
+		t.Errorf("other client: %v", err)
+	}
+	if err := s.SubmitTask(context.Background(), pingTask("t4", "a", "8.8.8.8"), "c1", noop); err != nil {
+		t.Errorf("other target: %v", err)
+	}
+}
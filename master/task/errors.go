@@ -0,0 +1,16 @@
+package task
+
+import "errors"
+
+// Reasons SubmitTask rejects a task; callers can branch with errors.Is
+var (
+	ErrGlobalLimit   = errors.New("system busy: global task limit reached")
+	ErrAgentNotFound = errors.New("agent not found")
+	ErrAgentOffline  = errors.New("agent is offline")
+	ErrAgentBusy     = errors.New("agent busy: task limit reached")
+	ErrQueueFull     = errors.New("task queue full")
+	ErrBroadcastBusy = errors.New("too many broadcasts in progress, try again later")
+	ErrBroadcastSize = errors.New("broadcast targets too many agents")
+	ErrTaskDisabled  = errors.New("task is disabled")
+	ErrAgentDegraded = errors.New("agent degraded after repeated task failures")
+)
@@ -0,0 +1,53 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestSubmitTaskRejectionErrors(t *testing.T) {
+	s, am, _ := newTestScheduler(t, "a", "b")
+	noop := func(*pb.TaskOutput) {}
+	submit := func(taskID, agentID string) error {
+		return s.SubmitTask(context.Background(), pingTask(taskID, agentID, "1.1.1."+taskID[1:]), "c1", noop)
+	}
+
+	if err := submit("t0", "missing"); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("unknown agent: err = %v, want %v", err, ErrAgentNotFound)
+	}
+	am.MarkAgentOffline("b")
+	if err := submit("t0", "b"); !errors.Is(err, ErrAgentOffline) {
+		t.Errorf("offline agent: err = %v, want %v", err, ErrAgentOffline)
+	}
+
+	// Agents in newTestScheduler run at most 5 tasks
+	for i := 1; i <= 5; i++ {
+		if err := submit(fmt.Sprintf("t%d", i), "a"); err != nil {
+			t.Fatalf("submit %d: %v", i, err)
+		}
+	}
+	if err := submit("t6", "a"); !errors.Is(err, ErrAgentBusy) {
+		t.Errorf("busy agent: err = %v, want %v", err, ErrAgentBusy)
+	}
+
+	s.mutex.Lock()
+	s.globalMaxTasks = 5
+	s.mutex.Unlock()
+	if err := submit("t7", "a"); !errors.Is(err, ErrGlobalLimit) {
+		t.Errorf("global limit: err = %v, want %v", err, ErrGlobalLimit)
+	}
+}
+
+func TestSubmitTaskRejectsDisabledTask(t *testing.T) {
+	s, _, _ := newTestScheduler(t, "a")
+	s.SetDisabledTasks([]string{"ping"})
+
+	err := s.SubmitTask(context.Background(), pingTask("t1", "a", "1.1.1.1"), "c1", func(*pb.TaskOutput) {})
+	if !errors.Is(err, ErrTaskDisabled) {
+		t.Errorf("err = %v, want %v", err, ErrTaskDisabled)
+	}
+}
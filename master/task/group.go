@@ -0,0 +1,283 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// AllAgents as the only entry of a fan-out agent list selects every online agent
+const AllAgents = "all"
+
+// groupContextKey carries the fan-out group ID of a child task submission
+type groupContextKey struct{}
+
+// withGroup marks ctx as belonging to a fan-out group
+func withGroup(ctx context.Context, groupID string) context.Context {
+	return context.WithValue(ctx, groupContextKey{}, groupID)
+}
+
+// groupFromContext returns the fan-out group ID carried by ctx, if any
+func groupFromContext(ctx context.Context) string {
+	groupID, _ := ctx.Value(groupContextKey{}).(string)
+	return groupID
+}
+
+// taskGroup tracks the child tasks of one fan-out submission
+type taskGroup struct {
+	id       string
+	children []string // Child task IDs that were submitted
+
+	mu        sync.Mutex
+	remaining int                   // Children that have not reached a final status
+	done      map[string]bool       // Child task IDs that reached a final status
+	counts    map[pb.TaskStatus]int // Final statuses seen so far
+}
+
+// finish records a child's final status (once per child) and reports whether it was the last one
+func (g *taskGroup) finish(taskID string, status pb.TaskStatus) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.done[taskID] {
+		return false
+	}
+	g.done[taskID] = true
+	g.counts[status]++
+	g.remaining--
+	return g.remaining == 0
+}
+
+// SetBroadcastLimits bounds fan-out submissions so one broadcast cannot starve single-agent tasks
+// maxConcurrent caps broadcasts in progress at once and maxAgents the agents one broadcast may
+// target; excess broadcasts are rejected. A value <= 0 disables that limit.
+func (s *Scheduler) SetBroadcastLimits(maxConcurrent, maxAgents int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.maxGroups = max(maxConcurrent, 0)
+	s.maxAgentsPerGroup = max(maxAgents, 0)
+}
+
+// SubmitGroup runs a copy of task on each agent in agentIDs under a shared group ID (task.TaskId)
+// Child outputs carry their agent and group ID; once every child has finished, a final
+// COMPLETED output whose TaskId equals the group ID is sent. Children that cannot be
+// submitted are reported as failed; an error is returned only if none could be submitted.
+// With WithGroupedDelivery(ctx), each child's outputs are delivered as one block when it finishes.
+func (s *Scheduler) SubmitGroup(ctx context.Context, task *pb.Task, agentIDs []string, clientID string, outputHandler func(*pb.TaskOutput)) error {
+	groupID := task.TaskId
+	if groupID == "" {
+		return fmt.Errorf("task_id is required for a task group")
+	}
+
+	if len(agentIDs) == 1 && agentIDs[0] == AllAgents {
+		agentIDs = s.onlineAgentIDs()
+	}
+	agentIDs = uniqueAgentIDs(agentIDs)
+	if len(agentIDs) == 0 {
+		return fmt.Errorf("no agents selected")
+	}
+
+	s.mutex.RLock()
+	maxAgents := s.maxAgentsPerGroup
+	flushTimeout := s.groupedFlushTimeout
+	s.mutex.RUnlock()
+	if maxAgents > 0 && len(agentIDs) > maxAgents {
+		return fmt.Errorf("%w (%d selected, limit %d)", ErrBroadcastSize, len(agentIDs), maxAgents)
+	}
+
+	// The whole group counts as one submission for the per-client cooldown
+	submitKey := cooldownKey{clientID: clientID, taskName: task.TaskName, target: task.GetNetworkTest().GetTarget()}
+	if s.cooldown != nil {
+		if wait := s.cooldown.remaining(submitKey, time.Now()); wait > 0 {
+			return fmt.Errorf("please wait %d seconds before running this test again", int(math.Ceil(wait.Seconds())))
+		}
+	}
+
+	group := &taskGroup{
+		id:        groupID,
+		remaining: len(agentIDs),
+		done:      make(map[string]bool),
+		counts:    make(map[pb.TaskStatus]int),
+	}
+
+	s.mutex.Lock()
+	if _, exists := s.groups[groupID]; exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("task group already exists: %s", groupID)
+	}
+	if s.maxGroups > 0 && len(s.groups) >= s.maxGroups {
+		running := len(s.groups)
+		s.mutex.Unlock()
+		return fmt.Errorf("%w (%d/%d)", ErrBroadcastBusy, running, s.maxGroups)
+	}
+	s.groups[groupID] = group
+	s.mutex.Unlock()
+
+	if outputHandler != nil && groupedDeliveryFromContext(ctx) {
+		outputHandler = newGroupedDelivery(outputHandler, flushTimeout).handle
+	}
+
+	type rejectedChild struct {
+		taskID  string
+		handler func(*pb.TaskOutput)
+		err     error
+	}
+	var rejected []rejectedChild
+	var errs []error
+
+	groupCtx := withGroup(ctx, groupID)
+	for _, agentID := range agentIDs {
+		child := proto.Clone(task).(*pb.Task)
+		child.TaskId = groupID + "-" + agentID
+		child.AgentId = agentID
+		handler := s.groupChildHandler(group, agentID, outputHandler)
+
+		if err := s.SubmitTask(groupCtx, child, clientID, handler); err != nil {
+			rejected = append(rejected, rejectedChild{taskID: child.TaskId, handler: handler, err: err})
+			errs = append(errs, fmt.Errorf("%s: %w", agentID, err))
+			continue
+		}
+
+		s.mutex.Lock()
+		group.children = append(group.children, child.TaskId)
+		s.mutex.Unlock()
+	}
+
+	if len(rejected) == len(agentIDs) {
+		s.mutex.Lock()
+		delete(s.groups, groupID)
+		s.mutex.Unlock()
+		return fmt.Errorf("no agent accepted the task: %w", errors.Join(errs...))
+	}
+
+	if s.cooldown != nil {
+		s.cooldown.record(submitKey, time.Now())
+	}
+
+	logger.Info("Task group submitted",
+		zap.String("group_id", groupID),
+		zap.Int("agents", len(agentIDs)),
+		zap.Int("rejected", len(rejected)),
+	)
+
+	// Report rejected children only now, so they cannot complete the group mid-submission
+	for _, r := range rejected {
+		r.handler(&pb.TaskOutput{
+			TaskId:       r.taskID,
+			Timestamp:    timestamppb.New(time.Now()),
+			Status:       pb.TaskStatus_TASK_STATUS_FAILED,
+			ErrorMessage: r.err.Error(),
+		})
+	}
+	return nil
+}
+
+// groupChildHandler tags a child's outputs with its agent and group, and completes the
+// group once the last child reaches a final status
+func (s *Scheduler) groupChildHandler(group *taskGroup, agentID string, handler func(*pb.TaskOutput)) func(*pb.TaskOutput) {
+	return func(output *pb.TaskOutput) {
+		// Outputs may be shared with the sink; tag a copy
+		tagged := proto.Clone(output).(*pb.TaskOutput)
+		tagged.AgentId = agentID
+		tagged.GroupId = group.id
+		if handler != nil {
+			handler(tagged)
+		}
+
+		if isTerminalStatus(output.Status) && group.finish(output.TaskId, output.Status) {
+			s.completeGroup(group, handler)
+		}
+	}
+}
+
+// completeGroup removes a finished group and signals its completion
+func (s *Scheduler) completeGroup(group *taskGroup, handler func(*pb.TaskOutput)) {
+	s.mutex.Lock()
+	delete(s.groups, group.id)
+	s.mutex.Unlock()
+
+	group.mu.Lock()
+	total := len(group.done)
+	completed := group.counts[pb.TaskStatus_TASK_STATUS_COMPLETED]
+	failed := group.counts[pb.TaskStatus_TASK_STATUS_FAILED]
+	cancelled := group.counts[pb.TaskStatus_TASK_STATUS_CANCELLED]
+	group.mu.Unlock()
+
+	logger.Info("Task group completed",
+		zap.String("group_id", group.id),
+		zap.Int("completed", completed),
+		zap.Int("failed", failed),
+		zap.Int("cancelled", cancelled),
+	)
+
+	if handler != nil {
+		handler(&pb.TaskOutput{
+			TaskId:     group.id,
+			GroupId:    group.id,
+			OutputLine: fmt.Sprintf("%d/%d completed, %d failed, %d cancelled", completed, total, failed, cancelled),
+			Timestamp:  timestamppb.New(time.Now()),
+			Status:     pb.TaskStatus_TASK_STATUS_COMPLETED,
+		})
+	}
+}
+
+// cancelGroup cancels every unfinished child of a group, reporting whether the group was found
+func (s *Scheduler) cancelGroup(groupID string) bool {
+	s.mutex.RLock()
+	group, ok := s.groups[groupID]
+	var children []string
+	if ok {
+		children = append(children, group.children...)
+	}
+	s.mutex.RUnlock()
+
+	if !ok {
+		return false
+	}
+
+	for _, childID := range children {
+		if err := s.CancelTask(childID); err != nil {
+			logger.Debug("Failed to cancel group child task",
+				zap.String("group_id", groupID),
+				zap.String("task_id", childID),
+				zap.Error(err),
+			)
+		}
+	}
+	logger.Info("Task group cancelled", zap.String("group_id", groupID))
+	return true
+}
+
+// onlineAgentIDs returns the IDs of all online agents
+func (s *Scheduler) onlineAgentIDs() []string {
+	var ids []string
+	for _, ag := range s.agentManager.GetAllAgents() {
+		if ag.Status == pb.AgentStatus_AGENT_STATUS_ONLINE {
+			ids = append(ids, ag.Info.Id)
+		}
+	}
+	return ids
+}
+
+// uniqueAgentIDs drops empty and repeated agent IDs, keeping the first occurrence
+func uniqueAgentIDs(agentIDs []string) []string {
+	seen := make(map[string]bool, len(agentIDs))
+	unique := make([]string, 0, len(agentIDs))
+	for _, id := range agentIDs {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
@@ -0,0 +1,79 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestSubmitGroupBroadcastLimits(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "a", "b", "c")
+	s.SetBroadcastLimits(1, 2)
+	noop := func(*pb.TaskOutput) {}
+
+	err := s.SubmitGroup(context.Background(), pingTask("g1", "", "1.1.1.1"), []string{"a", "b", "c"}, "c1", noop)
+	if !errors.Is(err, ErrBroadcastSize) {
+		t.Errorf("3 agents: err = %v, want %v", err, ErrBroadcastSize)
+	}
+
+	if err := s.SubmitGroup(context.Background(), pingTask("g2", "", "1.1.1.1"), []string{"a", "b"}, "c1", noop); err != nil {
+		t.Fatalf("SubmitGroup: %v", err)
+	}
+	sender.waitSent(t)
+	sender.waitSent(t)
+	err = s.SubmitGroup(context.Background(), pingTask("g3", "", "8.8.8.8"), []string{"c"}, "c2", noop)
+	if !errors.Is(err, ErrBroadcastBusy) {
+		t.Errorf("second broadcast: err = %v, want %v", err, ErrBroadcastBusy)
+	}
+
+	// Limits of 0 turn them off
+	s.SetBroadcastLimits(0, 0)
+	if err := s.SubmitGroup(context.Background(), pingTask("g4", "", "8.8.8.8"), []string{"a", "b", "c"}, "c3", noop); err != nil {
+		t.Errorf("unlimited: %v", err)
+	}
+}
+
+func TestSubmitGroup(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "a", "b")
+	var mu sync.Mutex
+	var outputs []*pb.TaskOutput
+	record := func(output *pb.TaskOutput) {
+		mu.Lock()
+		defer mu.Unlock()
+		outputs = append(outputs, output)
+	}
+
+	// Repeated agents run once; an unknown agent is reported as a failed child
+	err := s.SubmitGroup(context.Background(), pingTask("g1", "", "1.1.1.1"), []string{"a", "b", "missing", "a"}, "c1", record)
+	if err != nil {
+		t.Fatalf("SubmitGroup: %v", err)
+	}
+	dispatched := map[string]string{}
+	for range 2 {
+		task := sender.waitSent(t)
+		dispatched[task.AgentId] = task.TaskId
+	}
+	if dispatched["a"] != "g1-a" || dispatched["b"] != "g1-b" {
+		t.Fatalf("dispatched = %v, want g1-a on a and g1-b on b", dispatched)
+	}
+
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "g1-a", OutputLine: "reply"})
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "g1-a", Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "g1-b", Status: pb.TaskStatus_TASK_STATUS_FAILED, ErrorMessage: "boom"})
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, output := range outputs[:len(outputs)-1] {
+		if output.GroupId != "g1" || output.AgentId == "" {
+			t.Errorf("child output %v not tagged with its agent and group", output)
+		}
+	}
+	last := outputs[len(outputs)-1]
+	if last.TaskId != "g1" || last.Status != pb.TaskStatus_TASK_STATUS_COMPLETED ||
+		last.OutputLine != "1/3 completed, 2 failed, 0 cancelled" {
+		t.Errorf("group output = %v, want the group summary", last)
+	}
+}
@@ -0,0 +1,115 @@
+package task
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+const (
+	// DefaultGroupedFlushTimeout is how long grouped delivery holds output of unfinished agents
+	DefaultGroupedFlushTimeout = 60 * time.Second
+	// groupedMaxOutputs bounds the outputs held per agent; an agent that prints more streams the rest
+	groupedMaxOutputs = 10000
+)
+
+// groupedDeliveryKey marks a fan-out submission for grouped delivery
+type groupedDeliveryKey struct{}
+
+// WithGroupedDelivery asks SubmitGroup to deliver each agent's output as one contiguous block
+// when that agent finishes, instead of interleaving the outputs of all agents as they arrive
+func WithGroupedDelivery(ctx context.Context) context.Context {
+	return context.WithValue(ctx, groupedDeliveryKey{}, true)
+}
+
+// groupedDeliveryFromContext reports whether ctx asks for grouped delivery
+func groupedDeliveryFromContext(ctx context.Context) bool {
+	grouped, _ := ctx.Value(groupedDeliveryKey{}).(bool)
+	return grouped
+}
+
+// SetBroadcastFlushTimeout sets how long grouped delivery holds the output of agents that have not
+// finished; after it, held output is sent and those agents stream the rest as it arrives
+func (s *Scheduler) SetBroadcastFlushTimeout(timeout time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.groupedFlushTimeout = timeout
+}
+
+// groupedDelivery holds the outputs of each child of a fan-out group until the child finishes
+// Blocks are sent under one lock so the outputs of different agents never interleave, their lines
+// merged into batches (batchOutputs) so a large block takes few sends
+type groupedDelivery struct {
+	handler func(*pb.TaskOutput)
+
+	mu        sync.Mutex
+	held      map[string][]*pb.TaskOutput // Child task ID -> outputs not yet delivered
+	streaming map[string]bool             // Child task IDs delivered as they arrive (held too many)
+	flushed   bool                        // The flush timeout passed: every child streams
+	timer     *time.Timer
+}
+
+// newGroupedDelivery wraps handler, flushing whatever is held once timeout passes
+func newGroupedDelivery(handler func(*pb.TaskOutput), timeout time.Duration) *groupedDelivery {
+	d := &groupedDelivery{
+		handler:   handler,
+		held:      make(map[string][]*pb.TaskOutput),
+		streaming: make(map[string]bool),
+	}
+	if timeout > 0 {
+		d.timer = time.AfterFunc(timeout, d.flushAll)
+	}
+	return d
+}
+
+// handle holds a child output, or delivers it with everything held before it once the child finishes
+func (d *groupedDelivery) handle(output *pb.TaskOutput) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// The group's own final output comes after every child has finished
+	if output.TaskId == output.GroupId {
+		if d.timer != nil {
+			d.timer.Stop()
+		}
+		d.handler(output)
+		return
+	}
+
+	if d.flushed || d.streaming[output.TaskId] {
+		d.handler(output)
+		return
+	}
+
+	held := append(d.held[output.TaskId], output)
+	if isTerminalStatus(output.Status) || len(held) >= groupedMaxOutputs {
+		delete(d.held, output.TaskId)
+		d.streaming[output.TaskId] = !isTerminalStatus(output.Status)
+		d.deliver(held)
+		return
+	}
+	d.held[output.TaskId] = held
+}
+
+// flushAll delivers the held output of every unfinished child, one block per child
+func (d *groupedDelivery) flushAll() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.flushed = true
+	for _, taskID := range slices.Sorted(maps.Keys(d.held)) {
+		d.deliver(d.held[taskID])
+	}
+	clear(d.held)
+}
+
+// deliver sends a held block in batches; caller must hold d.mu
+func (d *groupedDelivery) deliver(held []*pb.TaskOutput) {
+	for _, o := range batchOutputs(held) {
+		d.handler(o)
+	}
+}
@@ -0,0 +1,69 @@
+package task
+
+import (
+	"fmt"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// childLine builds a running output line of a fan-out child task
+func childLine(taskID, line string) *pb.TaskOutput {
+	return &pb.TaskOutput{TaskId: taskID, GroupId: "g1", Status: pb.TaskStatus_TASK_STATUS_RUNNING, OutputLine: line}
+}
+
+func TestGroupedDeliveryHoldsUntilChildFinishes(t *testing.T) {
+	var delivered []*pb.TaskOutput
+	d := newGroupedDelivery(func(o *pb.TaskOutput) { delivered = append(delivered, o) }, 0)
+
+	d.handle(childLine("c1", "a1"))
+	d.handle(childLine("c2", "b1"))
+	d.handle(childLine("c1", "a2"))
+	if len(delivered) != 0 {
+		t.Fatalf("delivered %d outputs before any child finished", len(delivered))
+	}
+
+	d.handle(&pb.TaskOutput{TaskId: "c2", GroupId: "g1", Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+	if len(delivered) != 2 || delivered[0].OutputLine != "b1" || delivered[1].Status != pb.TaskStatus_TASK_STATUS_COMPLETED {
+		t.Fatalf("delivered %v, want c2's block", delivered)
+	}
+	d.handle(&pb.TaskOutput{TaskId: "c1", GroupId: "g1", Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+	if len(delivered) != 4 || delivered[2].OutputLine != "a1\na2" {
+		t.Fatalf("delivered %v, want c1's lines as one batch", delivered)
+	}
+}
+
+func TestGroupedDeliveryFlushAllBatches(t *testing.T) {
+	var delivered []*pb.TaskOutput
+	d := newGroupedDelivery(func(o *pb.TaskOutput) { delivered = append(delivered, o) }, 0)
+
+	for i := 0; i < 250; i++ {
+		d.handle(childLine("c1", fmt.Sprintf("a%d", i)))
+	}
+	d.handle(childLine("c2", "b0"))
+	d.flushAll()
+
+	// 250 lines of c1 in batches of 100, then c2's line
+	if len(delivered) != 4 {
+		t.Fatalf("flushAll delivered %d outputs, want 4 batches", len(delivered))
+	}
+	lines := 0
+	for _, o := range delivered[:3] {
+		if o.TaskId != "c1" {
+			t.Fatalf("c1's block interleaved with %s", o.TaskId)
+		}
+		if n := lineCount(o.OutputLine); n > maxCoalescedLines {
+			t.Errorf("batch of %d lines, want at most %d", n, maxCoalescedLines)
+		}
+		lines += lineCount(o.OutputLine)
+	}
+	if lines != 250 || delivered[3].OutputLine != "b0" {
+		t.Errorf("delivered %d lines of c1 then %q, want 250 then b0", lines, delivered[3].OutputLine)
+	}
+
+	// After the flush every child streams
+	d.handle(childLine("c2", "b1"))
+	if last := delivered[len(delivered)-1]; last.OutputLine != "b1" {
+		t.Errorf("output after flush not streamed: %v", last)
+	}
+}
@@ -0,0 +1,142 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// HeatmapClientID marks the heatmap's canary pings in the history and audit log
+	HeatmapClientID = "heatmap"
+	// HeatmapTaskName is the task the heatmap measures latency with
+	HeatmapTaskName = "ping"
+	// heatmapStartDelay lets agents reconnect to a restarted master before the first round
+	heatmapStartDelay = 30 * time.Second
+)
+
+// HeatmapRunner keeps the latency heatmap fresh by broadcasting pings to the canary targets
+// Each round pings every target from every online agent that supports ping, one target at a
+// time; the results land in the task history, which the heatmap endpoint reads.
+type HeatmapRunner struct {
+	scheduler *Scheduler
+	targets   []string
+	count     int32
+	timeout   int32 // seconds
+	interval  time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewHeatmapRunner creates a runner pinging targets every interval with count packets
+func NewHeatmapRunner(scheduler *Scheduler, targets []string, count, timeout int32, interval time.Duration) *HeatmapRunner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &HeatmapRunner{
+		scheduler: scheduler,
+		targets:   targets,
+		count:     count,
+		timeout:   timeout,
+		interval:  interval,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start runs a first round shortly after startup, then one every interval until Stop
+func (r *HeatmapRunner) Start() {
+	go func() {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-time.After(heatmapStartDelay):
+		}
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			r.runRound()
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends the rounds; a broadcast in progress finishes on its own
+func (r *HeatmapRunner) Stop() {
+	r.cancel()
+}
+
+// runRound broadcasts a ping to each target in turn, waiting for one broadcast to finish
+// before the next so a round never holds more than one broadcast slot
+func (r *HeatmapRunner) runRound() {
+	var agentIDs []string
+	for _, ag := range r.scheduler.agentManager.GetAgentsSupportingTaskByName(HeatmapTaskName) {
+		if ag.Status == pb.AgentStatus_AGENT_STATUS_ONLINE {
+			agentIDs = append(agentIDs, ag.Info.Id)
+		}
+	}
+	if len(agentIDs) == 0 {
+		logger.Debug("Heatmap round skipped: no online agents support ping")
+		return
+	}
+
+	for _, target := range r.targets {
+		if r.ctx.Err() != nil {
+			return
+		}
+		r.broadcast(target, agentIDs)
+	}
+}
+
+// broadcast pings target from agentIDs and waits until every agent finished or the interval passed
+func (r *HeatmapRunner) broadcast(target string, agentIDs []string) {
+	groupID := uuid.New().String()
+	task := &pb.Task{
+		TaskId:    groupID,
+		TaskName:  HeatmapTaskName,
+		CreatedAt: timestamppb.Now(),
+		Timeout:   r.timeout,
+		Params: &pb.Task_NetworkTest{
+			NetworkTest: &pb.NetworkTestParams{
+				Target:  target,
+				Count:   r.count,
+				Timeout: r.timeout,
+			},
+		},
+	}
+
+	done := make(chan struct{})
+	handler := func(output *pb.TaskOutput) {
+		if output.TaskId == groupID && isTerminalStatus(output.Status) {
+			close(done)
+		}
+	}
+
+	if err := r.scheduler.SubmitGroup(r.ctx, task, agentIDs, HeatmapClientID, handler); err != nil {
+		logger.Warn("Failed to submit heatmap pings",
+			zap.String("target", target),
+			zap.Error(err),
+		)
+		return
+	}
+
+	select {
+	case <-done:
+	case <-r.ctx.Done():
+	case <-time.After(r.interval):
+		logger.Warn("Heatmap pings still running after the refresh interval",
+			zap.String("target", target),
+			zap.String("group_id", groupID),
+		)
+	}
+}
@@ -0,0 +1,164 @@
+package task
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+const (
+	// historyMaxEntries bounds how many finished tasks are kept, regardless of retention
+	historyMaxEntries = 1000
+	// historyMaxLines bounds the output lines captured per task
+	historyMaxLines = 500
+)
+
+// HistoryEntry is a finished task kept for later review
+type HistoryEntry struct {
+	TaskID    string    `json:"task_id"`
+	TaskName  string    `json:"task_name"`
+	Target    string    `json:"target"`
+	AgentID   string    `json:"agent_id"`
+	ClientID  string    `json:"client_id"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Output    []string  `json:"output"`
+	Truncated bool      `json:"truncated,omitempty"` // Output beyond historyMaxLines was dropped
+}
+
+// taskHistory keeps finished tasks in memory, oldest first, pruned by age and count
+type taskHistory struct {
+	mu        sync.Mutex
+	retention time.Duration
+	entries   []*HistoryEntry
+	output    map[string]*capturedOutput // Task ID -> output captured while it runs
+	shares    map[string]*shareLink      // Share token -> link to an entry
+}
+
+// capturedOutput holds the output lines of a running task
+type capturedOutput struct {
+	lines     []string
+	truncated bool
+}
+
+// SetHistoryRetention keeps finished tasks and their output for the given duration
+// A retention <= 0 disables history
+func (s *Scheduler) SetHistoryRetention(retention time.Duration) {
+	if retention <= 0 {
+		s.history = nil
+		return
+	}
+	s.history = &taskHistory{
+		retention: retention,
+		output:    make(map[string]*capturedOutput),
+		shares:    make(map[string]*shareLink),
+	}
+}
+
+// GetHistory returns finished tasks, newest first, optionally for one agent
+// A limit <= 0 returns every retained entry
+func (s *Scheduler) GetHistory(agentID string, limit int) []*HistoryEntry {
+	if s.history == nil {
+		return nil
+	}
+	h := s.history
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pruneLocked(time.Now())
+
+	result := make([]*HistoryEntry, 0)
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		if agentID != "" && h.entries[i].AgentID != agentID {
+			continue
+		}
+		result = append(result, h.entries[i])
+	}
+	return result
+}
+
+// captureOutput records an output line of a running task
+func (s *Scheduler) captureOutput(output *pb.TaskOutput) {
+	if s.history == nil || output.OutputLine == "" {
+		return
+	}
+	h := s.history
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	captured, ok := h.output[output.TaskId]
+	if !ok {
+		captured = &capturedOutput{}
+		h.output[output.TaskId] = captured
+	}
+	// Batched outputs (e.g. expanded compressed blocks) carry several lines
+	for _, line := range strings.Split(output.OutputLine, "\n") {
+		if len(captured.lines) >= historyMaxLines {
+			captured.truncated = true
+			return
+		}
+		captured.lines = append(captured.lines, line)
+	}
+}
+
+// recordHistory moves a finished task and its captured output into the history
+func (s *Scheduler) recordHistory(taskInfo *TaskInfo, status pb.TaskStatus) {
+	if s.history == nil {
+		return
+	}
+
+	s.mutex.RLock()
+	errorMessage := taskInfo.errorMessage
+	s.mutex.RUnlock()
+
+	entry := &HistoryEntry{
+		TaskID:    taskInfo.Task.TaskId,
+		TaskName:  taskInfo.Task.TaskName,
+		Target:    taskInfo.Task.GetNetworkTest().GetTarget(),
+		AgentID:   taskInfo.AgentID,
+		ClientID:  taskInfo.ClientID,
+		StartedAt: taskInfo.CreatedAt,
+		EndedAt:   time.Now(),
+		Status:    status.String(),
+		Error:     errorMessage,
+		Output:    []string{},
+	}
+
+	h := s.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if captured, ok := h.output[entry.TaskID]; ok {
+		entry.Output = captured.lines
+		entry.Truncated = captured.truncated
+		delete(h.output, entry.TaskID)
+	}
+	h.entries = append(h.entries, entry)
+	h.pruneLocked(entry.EndedAt)
+}
+
+// pruneLocked drops entries past the retention or over the size bound, and expired share links;
+// caller must hold h.mu
+func (h *taskHistory) pruneLocked(now time.Time) {
+	h.pruneSharesLocked(now)
+
+	cutoff := now.Add(-h.retention)
+	drop := 0
+	for drop < len(h.entries) && h.entries[drop].EndedAt.Before(cutoff) {
+		drop++
+	}
+	if over := len(h.entries) - drop - historyMaxEntries; over > 0 {
+		drop += over
+	}
+	if drop > 0 {
+		h.entries = append([]*HistoryEntry(nil), h.entries[drop:]...)
+	}
+}
@@ -0,0 +1,45 @@
+package task
+
+import (
+	"github.com/lureiny/lookingglass/master/notifier"
+)
+
+// SetNotifier sets the notification manager and event configuration
+// Failed tasks are reported when cfg.TaskFailed is set
+func (s *Scheduler) SetNotifier(n *notifier.Manager, cfg *notifier.EventConfig) {
+	s.notifier = n
+	s.eventConfig = cfg
+}
+
+// recordFailure remembers why a task failed, for the task-failed notification
+func (s *Scheduler) recordFailure(taskID, errorMessage string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if taskInfo, ok := s.tasks[taskID]; ok && taskInfo.errorMessage == "" {
+		taskInfo.errorMessage = s.redactText(errorMessage)
+	}
+}
+
+// notifyTaskFailed sends a task-failed notification
+// Called once per task from completeTask, so cancelled or already-finished tasks never fire
+func (s *Scheduler) notifyTaskFailed(taskInfo *TaskInfo) {
+	if s.notifier == nil || s.eventConfig == nil || !s.eventConfig.TaskFailed {
+		return
+	}
+
+	s.mutex.RLock()
+	errorMessage := taskInfo.errorMessage
+	s.mutex.RUnlock()
+	if errorMessage == "" {
+		errorMessage = "unknown error"
+	}
+
+	event := notifier.NewTaskFailedEvent(
+		taskInfo.Task.TaskId,
+		taskInfo.AgentID,
+		taskInfo.Task.GetNetworkTest().GetTarget(),
+		errorMessage,
+	)
+	s.notifier.Notify(event)
+}
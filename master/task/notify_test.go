@@ -0,0 +1,63 @@
+package task
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/lureiny/lookingglass/master/notifier"
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// recordingNotifier keeps the events it is sent
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []*notifier.Event
+}
+
+func (n *recordingNotifier) Name() string { return "recording" }
+
+func (n *recordingNotifier) Send(ctx context.Context, event *notifier.Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return nil
+}
+
+func (n *recordingNotifier) Close() error { return nil }
+
+func TestNotifyTaskFailed(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	n := &recordingNotifier{}
+	m := notifier.NewManager()
+	m.RegisterNotifier(n)
+	m.Start()
+	s.SetNotifier(m, &notifier.EventConfig{TaskFailed: true})
+
+	if err := s.SubmitTask(t.Context(), pingTask("t1", "agent-1", "192.0.2.1"), "c1", func(*pb.TaskOutput) {}); err != nil {
+		t.Fatalf("SubmitTask: %v", err)
+	}
+	sender.waitSent(t)
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_FAILED, ErrorMessage: "ping: unknown host"})
+
+	// A cancelled task is not a failure
+	if err := s.SubmitTask(t.Context(), pingTask("t2", "agent-1", "192.0.2.2"), "c1", func(*pb.TaskOutput) {}); err != nil {
+		t.Fatalf("SubmitTask: %v", err)
+	}
+	sender.waitSent(t)
+	if err := s.CancelTask("t2"); err != nil {
+		t.Fatalf("CancelTask: %v", err)
+	}
+	m.Stop()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.events) != 1 {
+		t.Fatalf("sent %d notifications, want 1 for the failed task", len(n.events))
+	}
+	event := n.events[0]
+	if event.Type != notifier.EventTaskFailed || event.Metadata["task_id"] != "t1" ||
+		event.Metadata["agent_id"] != "agent-1" || event.Metadata["error"] != "ping: unknown host" {
+		t.Errorf("event = %+v", event)
+	}
+}
@@ -0,0 +1,155 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// queuedTask is a submission waiting for a free global or agent slot
+type queuedTask struct {
+	ctx           context.Context
+	task          *pb.Task
+	clientID      string
+	outputHandler func(*pb.TaskOutput)
+	queuedAt      time.Time
+}
+
+// SetQueueMax enables a FIFO queue of up to max tasks for submissions that exceed the
+// global or per-agent concurrency limit; they are dispatched as slots free up
+// A max <= 0 disables queueing (over-limit submissions are rejected)
+func (s *Scheduler) SetQueueMax(max int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.queueMax = max
+}
+
+// GetQueueLength returns the number of tasks waiting for a slot
+func (s *Scheduler) GetQueueLength() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.queue)
+}
+
+// enqueueLocked queues a submission rejected for capacity, or returns capacityErr
+// Must be called with s.mutex held; releases it
+// Requeued entries (fromQueue) go back to the front and do not count against the limit
+func (s *Scheduler) enqueueLocked(ctx context.Context, task *pb.Task, clientID string, outputHandler func(*pb.TaskOutput), fromQueue bool, capacityErr error) (bool, error) {
+	if s.queueMax <= 0 {
+		s.mutex.Unlock()
+		return false, capacityErr
+	}
+
+	entry := &queuedTask{
+		ctx:           ctx,
+		task:          task,
+		clientID:      clientID,
+		outputHandler: outputHandler,
+		queuedAt:      time.Now(),
+	}
+
+	if fromQueue {
+		s.queue = slices.Insert(s.queue, 0, entry)
+		s.mutex.Unlock()
+		return true, nil
+	}
+
+	if len(s.queue) >= s.queueMax {
+		s.mutex.Unlock()
+		return false, fmt.Errorf("%w: %w", ErrQueueFull, capacityErr)
+	}
+
+	s.queue = append(s.queue, entry)
+	position := len(s.queue)
+	s.mutex.Unlock()
+
+	logger.Info("Task queued",
+		zap.String("task_id", task.TaskId),
+		zap.String("agent_id", task.AgentId),
+		zap.Int("position", position),
+		zap.String("reason", capacityErr.Error()),
+	)
+
+	if outputHandler != nil {
+		outputHandler(&pb.TaskOutput{
+			TaskId:     task.TaskId,
+			OutputLine: fmt.Sprintf("Queued, position %d", position),
+			Timestamp:  timestamppb.New(time.Now()),
+			Status:     pb.TaskStatus_TASK_STATUS_PENDING,
+		})
+	}
+	return true, nil
+}
+
+// dispatchQueue starts queued tasks, in order, while slots are available
+// Entries whose agent has since gone away are submitted anyway so the client gets the error
+func (s *Scheduler) dispatchQueue() {
+	for {
+		s.mutex.Lock()
+		idx := -1
+		if s.currentTasks < s.globalMaxTasks {
+			for i, entry := range s.queue {
+				agent, err := s.agentManager.GetAgent(entry.task.AgentId)
+				if err != nil || agent.Status != pb.AgentStatus_AGENT_STATUS_ONLINE ||
+					agent.CurrentTasks < agent.Info.MaxConcurrent {
+					idx = i
+					break
+				}
+			}
+		}
+		if idx < 0 {
+			s.mutex.Unlock()
+			return
+		}
+		entry := s.queue[idx]
+		s.queue = slices.Delete(s.queue, idx, idx+1)
+		s.mutex.Unlock()
+
+		logger.Info("Dispatching queued task",
+			zap.String("task_id", entry.task.TaskId),
+			zap.Duration("waited", time.Since(entry.queuedAt)),
+		)
+
+		requeued, err := s.submit(entry.ctx, entry.task, entry.clientID, entry.outputHandler, true)
+		if requeued {
+			return // Slot was taken in the meantime; wait for the next one
+		}
+		if err != nil && entry.outputHandler != nil {
+			entry.outputHandler(&pb.TaskOutput{
+				TaskId:       entry.task.TaskId,
+				Timestamp:    timestamppb.New(time.Now()),
+				Status:       pb.TaskStatus_TASK_STATUS_FAILED,
+				ErrorMessage: err.Error(),
+			})
+		}
+	}
+}
+
+// cancelQueued removes a queued task, reporting whether it was found
+func (s *Scheduler) cancelQueued(taskID string) bool {
+	s.mutex.Lock()
+	idx := slices.IndexFunc(s.queue, func(entry *queuedTask) bool { return entry.task.TaskId == taskID })
+	if idx < 0 {
+		s.mutex.Unlock()
+		return false
+	}
+	entry := s.queue[idx]
+	s.queue = slices.Delete(s.queue, idx, idx+1)
+	s.mutex.Unlock()
+
+	if entry.outputHandler != nil {
+		entry.outputHandler(&pb.TaskOutput{
+			TaskId:    taskID,
+			Timestamp: timestamppb.New(time.Now()),
+			Status:    pb.TaskStatus_TASK_STATUS_CANCELLED,
+		})
+	}
+	logger.Info("Queued task cancelled", zap.String("task_id", taskID))
+	return true
+}
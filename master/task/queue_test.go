@@ -0,0 +1,82 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestQueueDispatchesInOrder(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	s.SetQueueMax(2)
+
+	// The agent runs 5 tasks at once; the next two wait and the one after is rejected
+	for i := range 5 {
+		if err := s.SubmitTask(t.Context(), pingTask(fmt.Sprintf("t%d", i), "agent-1", "192.0.2.1"), "c1", func(*pb.TaskOutput) {}); err != nil {
+			t.Fatalf("SubmitTask(t%d) error = %v", i, err)
+		}
+		sender.waitSent(t)
+	}
+	queued := newOutputRecorder()
+	for _, taskID := range []string{"q1", "q2"} {
+		if err := s.SubmitTask(t.Context(), pingTask(taskID, "agent-1", "192.0.2.1"), "c1", queued.handle); err != nil {
+			t.Fatalf("SubmitTask(%s) error = %v", taskID, err)
+		}
+	}
+	if err := s.SubmitTask(t.Context(), pingTask("q3", "agent-1", "192.0.2.1"), "c1", func(*pb.TaskOutput) {}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("SubmitTask(q3) error = %v, want %v", err, ErrQueueFull)
+	}
+	if got := s.GetQueueLength(); got != 2 {
+		t.Fatalf("queue length = %d, want 2", got)
+	}
+
+	// Each finished task frees a slot for the oldest queued one
+	for i, want := range []string{"q1", "q2"} {
+		s.HandleTaskOutput(&pb.TaskOutput{TaskId: fmt.Sprintf("t%d", i), Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+		if got := sender.waitSent(t); got.TaskId != want {
+			t.Errorf("dispatched %s, want %s", got.TaskId, want)
+		}
+	}
+	if got := s.GetQueueLength(); got != 0 {
+		t.Errorf("queue length = %d, want 0", got)
+	}
+
+	queued.mu.Lock()
+	defer queued.mu.Unlock()
+	if len(queued.outputs) == 0 || queued.outputs[0].OutputLine != "Queued, position 1" {
+		t.Errorf("first queued output = %v, want the queue position", queued.outputs)
+	}
+}
+
+func TestCancelQueuedTask(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	s.SetQueueMax(1)
+	for i := range 5 {
+		if err := s.SubmitTask(t.Context(), pingTask(fmt.Sprintf("t%d", i), "agent-1", "192.0.2.1"), "c1", func(*pb.TaskOutput) {}); err != nil {
+			t.Fatalf("SubmitTask(t%d) error = %v", i, err)
+		}
+		sender.waitSent(t)
+	}
+	rec := newOutputRecorder()
+	if err := s.SubmitTask(t.Context(), pingTask("q1", "agent-1", "192.0.2.1"), "c1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask(q1) error = %v", err)
+	}
+
+	if err := s.CancelTask("q1"); err != nil {
+		t.Fatalf("CancelTask(q1) error = %v", err)
+	}
+	outputs := rec.wait(t)
+	if last := outputs[len(outputs)-1]; last.Status != pb.TaskStatus_TASK_STATUS_CANCELLED {
+		t.Errorf("last status = %s, want CANCELLED", last.Status)
+	}
+
+	// The freed slot is not given to the cancelled task
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t0", Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+	select {
+	case task := <-sender.sent:
+		t.Errorf("dispatched %s after its cancellation", task.TaskId)
+	default:
+	}
+}
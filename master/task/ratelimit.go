@@ -0,0 +1,62 @@
+package task
+
+import (
+	"sync"
+	"time"
+)
+
+// dispatchLimiter paces task dispatch per agent using a token bucket per agent
+type dispatchLimiter struct {
+	rate    float64 // tokens added per second
+	burst   float64 // bucket capacity
+	buckets map[string]*tokenBucket
+	mutex   sync.Mutex
+}
+
+// tokenBucket tracks available dispatch tokens for one agent
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newDispatchLimiter creates a limiter allowing rate dispatches per second with the given burst
+func newDispatchLimiter(rate float64, burst int) *dispatchLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &dispatchLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow consumes a token for the agent if one is available
+// Returns false and the wait until the next token when the agent is over its rate
+func (l *dispatchLimiter) allow(agentID string, now time.Time) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	bucket, ok := l.buckets[agentID]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[agentID] = bucket
+	}
+
+	// Refill based on elapsed time
+	if elapsed := now.Sub(bucket.last).Seconds(); elapsed > 0 {
+		bucket.tokens += elapsed * l.rate
+		if bucket.tokens > l.burst {
+			bucket.tokens = l.burst
+		}
+		bucket.last = now
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
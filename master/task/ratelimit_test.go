@@ -0,0 +1,73 @@
+package task
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDispatchLimiterAllow(t *testing.T) {
+	l := newDispatchLimiter(2, 3)
+	now := time.Now()
+
+	// The burst is available at once, per agent
+	for i := range 3 {
+		if ok, _ := l.allow("agent-1", now); !ok {
+			t.Fatalf("dispatch %d within burst rejected", i+1)
+		}
+	}
+	ok, wait := l.allow("agent-1", now)
+	if ok {
+		t.Fatal("dispatch over burst allowed")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("wait = %v, want 500ms at 2/s", wait)
+	}
+	if ok, _ := l.allow("agent-2", now); !ok {
+		t.Error("other agent limited by agent-1's bucket")
+	}
+
+	// Tokens refill at the rate, capped at the burst
+	if ok, _ := l.allow("agent-1", now.Add(500*time.Millisecond)); !ok {
+		t.Error("dispatch after refill rejected")
+	}
+	later := now.Add(time.Hour)
+	for i := range 3 {
+		if ok, _ := l.allow("agent-1", later); !ok {
+			t.Fatalf("dispatch %d after long idle rejected", i+1)
+		}
+	}
+	if ok, _ := l.allow("agent-1", later); ok {
+		t.Error("bucket refilled past its burst")
+	}
+}
+
+func TestDispatchLimiterMinimumBurst(t *testing.T) {
+	l := newDispatchLimiter(1, 0)
+	now := time.Now()
+	if ok, _ := l.allow("agent-1", now); !ok {
+		t.Fatal("first dispatch rejected with burst 0")
+	}
+	if ok, _ := l.allow("agent-1", now); ok {
+		t.Error("burst 0 allowed more than one dispatch")
+	}
+}
+
+func TestSubmitTaskRateLimited(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	s.SetDispatchRateLimit(0.001, 1)
+
+	if err := s.SubmitTask(t.Context(), pingTask("t1", "agent-1", "1.1.1.1"), "client-1", newOutputRecorder().handle); err != nil {
+		t.Fatalf("first SubmitTask() error = %v", err)
+	}
+	sender.waitSent(t)
+	err := s.SubmitTask(t.Context(), pingTask("t2", "agent-1", "1.1.1.1"), "client-1", newOutputRecorder().handle)
+	if err == nil || !strings.Contains(err.Error(), "agent rate limited") {
+		t.Errorf("second SubmitTask() error = %v, want rate limited", err)
+	}
+
+	s.SetDispatchRateLimit(0, 0)
+	if err := s.SubmitTask(t.Context(), pingTask("t3", "agent-1", "1.1.1.1"), "client-1", newOutputRecorder().handle); err != nil {
+		t.Errorf("SubmitTask() with the limit disabled error = %v", err)
+	}
+}
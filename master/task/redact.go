@@ -0,0 +1,59 @@
+package task
+
+import (
+	"regexp"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// RedactionRule replaces every match of Pattern in task output lines with Replacement
+// Replacement may refer to capture groups ($1, ${name})
+type RedactionRule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// SetOutputRedactions applies rules, in order, to every output line, error message and summary
+// text before it reaches clients, the result cache, the history or the output sink; a later rule
+// sees the result of the earlier ones
+func (s *Scheduler) SetOutputRedactions(rules []RedactionRule) {
+	s.redactions = rules
+}
+
+// redactOutput applies the redaction rules to an output's line, error message and summary in place
+func (s *Scheduler) redactOutput(output *pb.TaskOutput) {
+	if len(s.redactions) == 0 {
+		return
+	}
+	output.OutputLine = s.redactText(output.OutputLine)
+	output.ErrorMessage = s.redactText(output.ErrorMessage)
+
+	summary := output.Summary
+	if summary == nil {
+		return
+	}
+	for i, ip := range summary.ResolvedIps {
+		summary.ResolvedIps[i] = s.redactText(ip)
+	}
+	for _, stats := range summary.PingStats {
+		stats.Target = s.redactText(stats.Target)
+	}
+	for _, hop := range summary.TraceHops {
+		hop.Ip = s.redactText(hop.Ip)
+		hop.Hostname = s.redactText(hop.Hostname)
+		hop.Asn = s.redactText(hop.Asn)
+		hop.Geo = s.redactText(hop.Geo)
+		hop.Owner = s.redactText(hop.Owner)
+	}
+}
+
+// redactText applies the redaction rules to one string
+func (s *Scheduler) redactText(text string) string {
+	if text == "" {
+		return text
+	}
+	for _, rule := range s.redactions {
+		text = rule.Pattern.ReplaceAllString(text, rule.Replacement)
+	}
+	return text
+}
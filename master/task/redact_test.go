@@ -0,0 +1,64 @@
+package task
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestRedactOutput(t *testing.T) {
+	s, _, _ := newTestScheduler(t)
+	s.SetOutputRedactions([]RedactionRule{
+		{Pattern: regexp.MustCompile(`[a-z0-9-]+\.internal\.example\.com`), Replacement: "[REDACTED]"},
+		{Pattern: regexp.MustCompile(`10\.\d+\.\d+\.\d+`), Replacement: "10.x.x.x"},
+	})
+
+	output := &pb.TaskOutput{
+		OutputLine:   "64 bytes from db.internal.example.com (10.1.2.3)",
+		ErrorMessage: "connect to db.internal.example.com failed",
+		Summary: &pb.TaskSummary{
+			ResolvedIps: []string{"10.1.2.3"},
+			PingStats:   []*pb.PingStats{{Target: "db.internal.example.com"}},
+			TraceHops:   []*pb.TraceHop{{Ip: "10.0.0.1", Hostname: "gw.internal.example.com", Owner: "owner"}},
+		},
+	}
+	s.redactOutput(output)
+
+	if output.OutputLine != "64 bytes from [REDACTED] (10.x.x.x)" {
+		t.Errorf("line = %q", output.OutputLine)
+	}
+	if output.ErrorMessage != "connect to [REDACTED] failed" {
+		t.Errorf("error = %q", output.ErrorMessage)
+	}
+	summary := output.Summary
+	if summary.ResolvedIps[0] != "10.x.x.x" || summary.PingStats[0].Target != "[REDACTED]" {
+		t.Errorf("summary = %v", summary)
+	}
+	if hop := summary.TraceHops[0]; hop.Ip != "10.x.x.x" || hop.Hostname != "[REDACTED]" || hop.Owner != "owner" {
+		t.Errorf("hop = %v", hop)
+	}
+}
+
+func TestRedactedFailureReachesHistory(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	s.SetOutputRedactions([]RedactionRule{{Pattern: regexp.MustCompile(`secret-host`), Replacement: "[REDACTED]"}})
+	s.SetHistoryRetention(time.Hour)
+
+	rec := newOutputRecorder()
+	if err := s.SubmitTask(t.Context(), pingTask("t1", "agent-1", "1.1.1.1"), "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	sender.waitSent(t)
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_FAILED, ErrorMessage: "unreachable: secret-host"})
+
+	outputs := rec.wait(t)
+	if got := outputs[len(outputs)-1].ErrorMessage; got != "unreachable: [REDACTED]" {
+		t.Errorf("client error = %q", got)
+	}
+	history := s.GetHistory("", 0)
+	if len(history) != 1 || history[0].Error != "unreachable: [REDACTED]" {
+		t.Errorf("history = %v", history)
+	}
+}
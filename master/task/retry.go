@@ -0,0 +1,165 @@
+package task
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Failure kinds a RetryPolicy may list in retry_on
+const (
+	RetryOnDispatch = "dispatch" // The task could not be sent to its agent
+	RetryOnAgent    = "agent"    // The agent reported the task as failed
+)
+
+// SetMaxAttempts caps the total attempts (first run included) a retry policy may request
+// A limit <= 1 disables retries
+func (s *Scheduler) SetMaxAttempts(limit int) {
+	s.maxAttempts = limit
+}
+
+// retryable reports whether a failure of the given kind may be retried under policy
+// An empty retry_on list retries every kind
+func retryable(policy *pb.RetryPolicy, kind string) bool {
+	return len(policy.RetryOn) == 0 || slices.Contains(policy.RetryOn, kind)
+}
+
+// firstAttempt reports whether a task is on its first attempt
+func (s *Scheduler) firstAttempt(taskID string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	taskInfo, ok := s.tasks[taskID]
+	return ok && taskInfo.attempt <= 1
+}
+
+// failOrRetry re-dispatches a failed task when its retry policy allows, and fails it otherwise
+func (s *Scheduler) failOrRetry(taskID, kind string, err error) {
+	if s.scheduleRetry(taskID, kind, err.Error()) {
+		return
+	}
+	if kind == RetryOnDispatch {
+		s.markAgentFault(taskID)
+	}
+	s.handleTaskError(taskID, err)
+}
+
+// scheduleRetry re-dispatches a failed task after the policy delay, on the same agent unless the
+// policy opts into other_agents: then on the least-loaded agent in the original agent's region it
+// has not failed on yet, or back on the original agent when there is none (fan-out children always
+// stay on their agent, which is what they report on)
+// Returns false when the task must fail instead (no policy, attempts exhausted, cancelled, past deadline)
+func (s *Scheduler) scheduleRetry(taskID, kind, errorMessage string) bool {
+	s.mutex.Lock()
+	taskInfo, ok := s.tasks[taskID]
+	if !ok || isTerminalStatus(taskInfo.Status) || taskInfo.cancelledBeforeStart {
+		s.mutex.Unlock()
+		return false
+	}
+
+	policy := taskInfo.Task.GetRetry()
+	if policy == nil || !retryable(policy, kind) {
+		s.mutex.Unlock()
+		return false
+	}
+	maxAttempts := int(policy.MaxAttempts)
+	if maxAttempts > s.maxAttempts {
+		maxAttempts = s.maxAttempts
+	}
+	if taskInfo.attempt >= maxAttempts || taskInfo.ctx.Err() != nil {
+		s.mutex.Unlock()
+		return false
+	}
+
+	delay := time.Duration(policy.DelayMs) * time.Millisecond
+	if deadline := taskInfo.Task.GetDeadline(); deadline != nil && !time.Now().Add(delay).Before(deadline.AsTime()) {
+		s.mutex.Unlock()
+		return false
+	}
+
+	failedAgent := taskInfo.AgentID
+	nextAgent := failedAgent
+	if policy.OtherAgents && groupFromContext(taskInfo.ctx) == "" {
+		if !slices.Contains(taskInfo.failedAgents, failedAgent) {
+			taskInfo.failedAgents = append(taskInfo.failedAgents, failedAgent)
+		}
+		origin := taskInfo.failedAgents[0]
+		nextAgent = origin
+		if next, err := s.agentManager.SelectAgentNear(taskInfo.Task.TaskName, origin, taskInfo.failedAgents); err == nil {
+			nextAgent = next.Info.Id
+		} else {
+			logger.Debug("No other agent to retry on, retrying on the original agent",
+				zap.String("task_id", taskID),
+				zap.String("agent_id", origin),
+				zap.Error(err),
+			)
+		}
+	}
+
+	taskInfo.attempt++
+	attempt := taskInfo.attempt
+	taskInfo.Status = pb.TaskStatus_TASK_STATUS_PENDING
+	taskInfo.lastSequence = 0 // The agent numbers each attempt's output from 1
+	taskInfo.AgentID = nextAgent
+	taskInfo.Task.AgentId = nextAgent
+	ctx := taskInfo.ctx
+	s.mutex.Unlock()
+
+	// The task's slot moves with it
+	if nextAgent != failedAgent {
+		_ = s.agentManager.DecrementTaskCount(failedAgent)
+		_ = s.agentManager.IncrementTaskCount(nextAgent)
+	}
+
+	logger.Info("Retrying failed task",
+		zap.String("task_id", taskID),
+		zap.String("failed_agent_id", failedAgent),
+		zap.String("agent_id", nextAgent),
+		zap.String("failure", kind),
+		zap.String("error", errorMessage),
+		zap.Int("attempt", attempt),
+		zap.Int("max_attempts", maxAttempts),
+	)
+
+	s.forwardOutput(&pb.TaskOutput{
+		TaskId:     taskID,
+		OutputLine: fmt.Sprintf("Attempt %d/%d failed on %s: %s; retrying on %s in %s", attempt-1, maxAttempts, failedAgent, errorMessage, nextAgent, delay),
+		Timestamp:  timestamppb.New(time.Now()),
+		Status:     pb.TaskStatus_TASK_STATUS_RUNNING,
+	})
+
+	go func() {
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+			}
+		}
+		// executeTask handles a cancellation or deadline that happened during the delay
+		s.executeTask(ctx, taskInfo)
+	}()
+	return true
+}
+
+// tagRetriedAgent sets the agent ID on the outputs of a task retried on another agent than the one
+// it was submitted to, so clients can tell which agent produced the result
+func (s *Scheduler) tagRetriedAgent(output *pb.TaskOutput) {
+	if output.AgentId != "" {
+		return
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	taskInfo, ok := s.tasks[output.TaskId]
+	if ok && len(taskInfo.failedAgents) > 0 && taskInfo.AgentID != taskInfo.failedAgents[0] {
+		output.AgentId = taskInfo.AgentID
+	}
+}
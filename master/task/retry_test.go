@@ -0,0 +1,197 @@
+package task
+
+import (
+	"strings"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// retryTask is a ping task with a retry policy of maxAttempts
+func retryTask(taskID, agentID string, maxAttempts int32) *pb.Task {
+	t := pingTask(taskID, agentID, "192.0.2.1")
+	t.Retry = &pb.RetryPolicy{MaxAttempts: maxAttempts}
+	return t
+}
+
+func TestRetryFailsOnceThenSucceeds(t *testing.T) {
+	s, am, sender := newTestScheduler(t, "agent-1", "agent-2")
+	s.SetMaxAttempts(3)
+	rec := newOutputRecorder()
+
+	if err := s.SubmitTask(t.Context(), retryTask("t1", "agent-1", 3), "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	if first := sender.waitSent(t); first.AgentId != "agent-1" {
+		t.Fatalf("first attempt on %s, want agent-1", first.AgentId)
+	}
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_FAILED, ErrorMessage: "agent busy"})
+
+	// Without other_agents the retry stays on the agent the user picked
+	if second := sender.waitSent(t); second.AgentId != "agent-1" {
+		t.Fatalf("retry on %s, want agent-1", second.AgentId)
+	}
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+
+	outputs := rec.wait(t)
+	var retryLine string
+	for _, output := range outputs {
+		if output.Status == pb.TaskStatus_TASK_STATUS_FAILED {
+			t.Errorf("client saw the retried failure: %v", output)
+		}
+		if output.AgentId != "" {
+			t.Errorf("output of a task retried on its own agent tagged with %s", output.AgentId)
+		}
+		if strings.HasPrefix(output.OutputLine, "Attempt 1/3 failed") {
+			retryLine = output.OutputLine
+		}
+	}
+	if !strings.Contains(retryLine, "retrying on agent-1") {
+		t.Errorf("retry notice = %q", retryLine)
+	}
+	if last := outputs[len(outputs)-1]; last.Status != pb.TaskStatus_TASK_STATUS_COMPLETED {
+		t.Errorf("final status = %s, want COMPLETED", last.Status)
+	}
+
+	a, _ := am.GetAgent("agent-1")
+	if a.CurrentTasks != 0 {
+		t.Errorf("agent-1 task count = %d, want 0", a.CurrentTasks)
+	}
+}
+
+func TestRetryOnOtherAgentInRegion(t *testing.T) {
+	s, am, sender := newTestScheduler(t)
+	for _, info := range []*pb.AgentInfo{
+		{Id: "agent-1", Location: "Tokyo"},
+		{Id: "agent-2", Location: "Tokyo", Tags: map[string]string{"region": "tokyo"}},
+		{Id: "agent-3", Location: "Paris"},
+	} {
+		info.MaxConcurrent = 5
+		info.TaskDisplayInfo = []*pb.TaskDisplayInfo{{TaskName: "ping", RequiresTarget: true}}
+		if err := am.RegisterAgentFromStream(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// agent-2 is busier than agent-3, but agent-3 is outside the original region
+	_ = am.IncrementTaskCount("agent-2")
+	s.SetMaxAttempts(3)
+	rec := newOutputRecorder()
+
+	task := retryTask("t1", "agent-1", 3)
+	task.Retry.OtherAgents = true
+	if err := s.SubmitTask(t.Context(), task, "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	sender.waitSent(t)
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_FAILED, ErrorMessage: "agent busy"})
+
+	if second := sender.waitSent(t); second.AgentId != "agent-2" {
+		t.Fatalf("retry on %s, want agent-2 (same region, not the agent that failed)", second.AgentId)
+	}
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", OutputLine: "64 bytes from 192.0.2.1"})
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+
+	// The result names the agent that produced it
+	outputs := rec.wait(t)
+	last := outputs[len(outputs)-1]
+	if last.Status != pb.TaskStatus_TASK_STATUS_COMPLETED || last.AgentId != "agent-2" {
+		t.Errorf("final output = %v, want COMPLETED from agent-2", last)
+	}
+
+	// The task's slot moved with it and was released on completion
+	for id, want := range map[string]int32{"agent-1": 0, "agent-2": 1} {
+		a, _ := am.GetAgent(id)
+		if a.CurrentTasks != want {
+			t.Errorf("%s task count = %d, want %d", id, a.CurrentTasks, want)
+		}
+	}
+}
+
+func TestRetryExhaustsAttempts(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1", "agent-2", "agent-3")
+	s.SetMaxAttempts(2)
+	rec := newOutputRecorder()
+
+	// The policy asks for 3 attempts; the master caps it at 2
+	if err := s.SubmitTask(t.Context(), retryTask("t1", "agent-1", 3), "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	for attempt := 1; attempt <= 2; attempt++ {
+		sender.waitSent(t)
+		s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_FAILED, ErrorMessage: "boom"})
+	}
+
+	outputs := rec.wait(t)
+	if last := outputs[len(outputs)-1]; last.Status != pb.TaskStatus_TASK_STATUS_FAILED || last.ErrorMessage != "boom" {
+		t.Errorf("final output = %v, want FAILED boom", last)
+	}
+	select {
+	case extra := <-sender.sent:
+		t.Errorf("dispatched a third attempt to %s", extra.AgentId)
+	default:
+	}
+}
+
+func TestRetryWithoutOtherAgentStaysOnOriginal(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	s.SetMaxAttempts(3)
+	rec := newOutputRecorder()
+
+	task := retryTask("t1", "agent-1", 3)
+	task.Retry.OtherAgents = true
+	if err := s.SubmitTask(t.Context(), task, "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	sender.waitSent(t)
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_FAILED, ErrorMessage: "boom"})
+
+	// With no other agent in the region the retry goes back to the original agent
+	if second := sender.waitSent(t); second.AgentId != "agent-1" {
+		t.Fatalf("retry on %s, want agent-1", second.AgentId)
+	}
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+
+	outputs := rec.wait(t)
+	if last := outputs[len(outputs)-1]; last.Status != pb.TaskStatus_TASK_STATUS_COMPLETED {
+		t.Errorf("final status = %s, want COMPLETED", last.Status)
+	}
+}
+
+func TestRetrySkipsCancellation(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1", "agent-2")
+	s.SetMaxAttempts(3)
+	rec := newOutputRecorder()
+
+	if err := s.SubmitTask(t.Context(), retryTask("t1", "agent-1", 3), "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	sender.waitSent(t)
+	if err := s.CancelTask("t1"); err != nil {
+		t.Fatalf("CancelTask() error = %v", err)
+	}
+
+	outputs := rec.wait(t)
+	if last := outputs[len(outputs)-1]; last.Status != pb.TaskStatus_TASK_STATUS_CANCELLED {
+		t.Errorf("final status = %s, want CANCELLED", last.Status)
+	}
+	select {
+	case extra := <-sender.sent:
+		t.Errorf("cancelled task was retried on %s", extra.AgentId)
+	default:
+	}
+}
+
+func TestRetryDisabledWithoutMaxAttempts(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1", "agent-2")
+	rec := newOutputRecorder()
+
+	if err := s.SubmitTask(t.Context(), retryTask("t1", "agent-1", 3), "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	sender.waitSent(t)
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_FAILED, ErrorMessage: "boom"})
+
+	if last := rec.wait(t); last[len(last)-1].Status != pb.TaskStatus_TASK_STATUS_FAILED {
+		t.Errorf("final status = %s, want FAILED", last[len(last)-1].Status)
+	}
+}
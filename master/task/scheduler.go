@@ -2,12 +2,16 @@ package task
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/lureiny/lookingglass/master/agent"
+	"github.com/lureiny/lookingglass/master/metrics"
+	"github.com/lureiny/lookingglass/master/notifier"
 	pb "github.com/lureiny/lookingglass/pb"
 	"github.com/lureiny/lookingglass/pkg/logger"
 	"go.uber.org/zap"
@@ -21,15 +25,37 @@ type TaskInfo struct {
 	Status     pb.TaskStatus
 	CreatedAt  time.Time
 	ClientID   string // WebSocket client ID for output routing
+	ClientAddr string // Submitting client's address (for audit), if known
 	CancelFunc context.CancelFunc
+
+	DispatchedAt    time.Time // When the task was sent to the agent
+	FirstResponseAt time.Time // When the first output for the task came back
+
+	cancelledBeforeStart bool            // Set when a pending task is cancelled; it must never be dispatched
+	errorMessage         string          // First reported failure reason, for the task-failed notification
+	agentFault           bool            // The failure is the agent's or its connection's; only these count toward the circuit breaker
+	lastSequence         uint64          // Highest agent output sequence seen, for gap detection
+	bytesTransferred     int64           // Output bytes received from the agent as sent, across attempts
+	overByteCap          bool            // Output exceeded maxTransferBytes; further output is dropped
+	attempt              int             // Current attempt, from 1
+	failedAgents         []string        // Agents earlier attempts failed on, the submitted one first (other_agents retries only)
+	ctx                  context.Context // Task context, reused by retries
 }
 
+// errStreamSenderNotConfigured is returned when a stream agent is used before SetStreamSender
+var errStreamSenderNotConfigured = errors.New("stream sender not configured")
+
 // StreamSender interface for sending tasks to agents via stream
 type StreamSender interface {
 	SendTaskToAgent(agentID string, task *pb.Task) error
 	CancelTaskOnAgent(agentID string, taskID string) error
 }
 
+// AgentProber checks an agent's stream with a round trip before a task is dispatched to it
+type AgentProber interface {
+	ProbeAgent(ctx context.Context, agentID string, timeout time.Duration) error
+}
+
 // Scheduler manages task scheduling and execution
 type Scheduler struct {
 	agentManager   *agent.Manager
@@ -40,55 +66,196 @@ type Scheduler struct {
 	mutex          sync.RWMutex
 	outputHandlers map[string]func(*pb.TaskOutput) // Task ID -> output handler
 	handlerMutex   sync.RWMutex
+	dispatchLimit  *dispatchLimiter // Optional per-agent dispatch rate limit (nil = unlimited)
+	prober         AgentProber      // Optional pre-dispatch stream health check (nil = disabled)
+	probeTimeout   time.Duration
+
+	reportDispatchLatency bool            // Include dispatch latency in the completion summary
+	coalesceWindow        time.Duration   // Batch output lines per task over this window (0 = forward immediately)
+	audit                 *zap.Logger     // Optional audit trail of task lifecycle events (nil = disabled)
+	cooldown              *submitCooldown // Optional per-client resubmission cooldown (nil = disabled)
+	sink                  *sinkPublisher  // Optional copy of task outputs for external pipelines (nil = disabled)
+	history               *taskHistory    // Optional record of finished tasks (nil = disabled)
+	shareTTL              time.Duration   // Lifetime of share links to history entries (0 = disabled)
+	cache                 *resultCache    // Optional replay of recent identical results (nil = disabled)
+	redactions            []RedactionRule // Applied to output lines before they leave the scheduler
+	breaker               *agentBreaker   // Optional pause of agents that keep failing tasks (nil = disabled)
+
+	notifier    *notifier.Manager
+	eventConfig *notifier.EventConfig
+	maxAttempts int // Cap on RetryPolicy.max_attempts (0 = retries disabled until SetMaxAttempts)
+
+	queue    []*queuedTask // Submissions waiting for a slot, in arrival order (guarded by mutex)
+	queueMax int           // Queue capacity (0 = queueing disabled)
+
+	groups              map[string]*taskGroup // Fan-out group ID -> group, until all children finish (guarded by mutex)
+	maxGroups           int                   // Fan-out groups allowed in progress at once (0 = unlimited)
+	maxAgentsPerGroup   int                   // Agents a single fan-out may target (0 = unlimited)
+	groupedFlushTimeout time.Duration         // How long grouped delivery holds output of unfinished agents
+
+	maxTransferBytes int64 // Output bytes an agent may send per task before it is stopped (0 = unlimited)
+
+	disabledTasks map[string]bool // Task names rejected on submit, whatever agents advertise
 }
 
 // NewScheduler creates a new task scheduler
 func NewScheduler(agentManager *agent.Manager, globalMaxTasks int) *Scheduler {
 	return &Scheduler{
-		agentManager:   agentManager,
-		globalMaxTasks: globalMaxTasks,
-		tasks:          make(map[string]*TaskInfo),
-		outputHandlers: make(map[string]func(*pb.TaskOutput)),
+		agentManager:        agentManager,
+		globalMaxTasks:      globalMaxTasks,
+		tasks:               make(map[string]*TaskInfo),
+		outputHandlers:      make(map[string]func(*pb.TaskOutput)),
+		groups:              make(map[string]*taskGroup),
+		groupedFlushTimeout: DefaultGroupedFlushTimeout,
 	}
 }
 
 // SetStreamSender sets the stream sender for stream-based communication
+// It must be called before the scheduler accepts tasks (i.e. before the gRPC and
+// WebSocket servers start); until then tasks for stream agents are rejected
 func (s *Scheduler) SetStreamSender(sender StreamSender) {
 	s.streamSender = sender
 }
 
+// SetDispatchRateLimit paces task dispatch to each agent to rate tasks/second with the given burst
+// A rate <= 0 disables the limit
+func (s *Scheduler) SetDispatchRateLimit(rate float64, burst int) {
+	if rate <= 0 {
+		s.dispatchLimit = nil
+		return
+	}
+	s.dispatchLimit = newDispatchLimiter(rate, burst)
+}
+
+// SetDispatchProbe checks each stream agent with prober before dispatching a task to it
+// An agent that does not answer within timeout fails the dispatch (retried if the task's retry
+// policy allows) instead of the task hanging on a half-open stream; a timeout <= 0 disables the probe
+func (s *Scheduler) SetDispatchProbe(prober AgentProber, timeout time.Duration) {
+	if timeout <= 0 {
+		s.prober = nil
+		return
+	}
+	s.prober = prober
+	s.probeTimeout = timeout
+}
+
+// SetSubmitCooldown rejects resubmissions of the same task+target by a client within window
+// A window <= 0 disables the cooldown
+func (s *Scheduler) SetSubmitCooldown(window time.Duration) {
+	if window <= 0 {
+		s.cooldown = nil
+		return
+	}
+	s.cooldown = newSubmitCooldown(window)
+}
+
+// SetDisabledTasks rejects submissions of the named tasks on every agent
+func (s *Scheduler) SetDisabledTasks(names []string) {
+	disabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		disabled[name] = true
+	}
+	s.disabledTasks = disabled
+}
+
+// SetReportDispatchLatency enables reporting dispatch latency in the completion summary
+func (s *Scheduler) SetReportDispatchLatency(enabled bool) {
+	s.reportDispatchLatency = enabled
+}
+
+// SetOutputCoalescing batches output lines per task over window before forwarding them
+// Terminal statuses and errors flush immediately; a window <= 0 disables batching
+func (s *Scheduler) SetOutputCoalescing(window time.Duration) {
+	s.coalesceWindow = window
+}
+
 // SubmitTask submits a task for execution
+// When queueing is enabled, a task over the concurrency limits is queued instead of rejected
 func (s *Scheduler) SubmitTask(ctx context.Context, task *pb.Task, clientID string, outputHandler func(*pb.TaskOutput)) error {
+	_, err := s.submit(ctx, task, clientID, outputHandler, false)
+	return err
+}
+
+// submit admits a task, queueing it if it is over the concurrency limits and queueing is enabled
+// fromQueue marks a dispatch of an already-queued task; it reports true if the task was (re)queued
+func (s *Scheduler) submit(ctx context.Context, task *pb.Task, clientID string, outputHandler func(*pb.TaskOutput), fromQueue bool) (bool, error) {
+	// Fail fast on deadlines that have already passed
+	if task.Deadline != nil && !time.Now().Before(task.Deadline.AsTime()) {
+		return false, fmt.Errorf("task deadline already passed: %s", task.Deadline.AsTime().Format(time.RFC3339))
+	}
+
+	if s.disabledTasks[task.TaskName] {
+		return false, fmt.Errorf("%w: %s", ErrTaskDisabled, task.TaskName)
+	}
+
+	// An identical task completed recently: replay its output instead of running it again
+	if !fromQueue && s.replayCached(ctx, task, clientID, outputHandler) {
+		return false, nil
+	}
+
+	// Agents failing every task are paused for a while
+	if err := s.checkBreaker(ctx, task.AgentId, fromQueue); err != nil {
+		return false, err
+	}
+
 	s.mutex.Lock()
 
 	// Check global concurrency limit
 	if s.currentTasks >= s.globalMaxTasks {
-		s.mutex.Unlock()
-		return fmt.Errorf("system busy: global task limit reached (%d/%d)", s.currentTasks, s.globalMaxTasks)
+		capacityErr := fmt.Errorf("%w (%d/%d)", ErrGlobalLimit, s.currentTasks, s.globalMaxTasks)
+		return s.enqueueLocked(ctx, task, clientID, outputHandler, fromQueue, capacityErr)
 	}
 
 	// Get agent
 	agent, err := s.agentManager.GetAgent(task.AgentId)
 	if err != nil {
 		s.mutex.Unlock()
-		return fmt.Errorf("agent not found: %w", err)
+		return false, fmt.Errorf("%w: %w", ErrAgentNotFound, err)
 	}
 
 	// Check agent status
 	if agent.Status != pb.AgentStatus_AGENT_STATUS_ONLINE {
 		s.mutex.Unlock()
-		return fmt.Errorf("agent is offline: %s", task.AgentId)
+		return false, fmt.Errorf("%w: %s", ErrAgentOffline, task.AgentId)
+	}
+
+	// Stream agents can only be reached once the stream sender is wired up
+	if agent.UseStream && s.streamSender == nil {
+		s.mutex.Unlock()
+		return false, errStreamSenderNotConfigured
 	}
 
 	// Check agent concurrency limit
 	if agent.CurrentTasks >= agent.Info.MaxConcurrent {
-		s.mutex.Unlock()
-		return fmt.Errorf("agent busy: task limit reached (%d/%d)", agent.CurrentTasks, agent.Info.MaxConcurrent)
+		capacityErr := fmt.Errorf("%w (%d/%d)", ErrAgentBusy, agent.CurrentTasks, agent.Info.MaxConcurrent)
+		return s.enqueueLocked(ctx, task, clientID, outputHandler, fromQueue, capacityErr)
+	}
+
+	// Check per-client cooldown for repeated submissions (self-checks are exempt; groups check it once for all children)
+	submitKey := cooldownKey{clientID: clientID, taskName: task.TaskName, target: task.GetNetworkTest().GetTarget()}
+	useCooldown := s.cooldown != nil && clientID != selfCheckClientID && groupFromContext(ctx) == ""
+	if useCooldown {
+		if wait := s.cooldown.remaining(submitKey, time.Now()); wait > 0 {
+			s.mutex.Unlock()
+			return false, fmt.Errorf("please wait %d seconds before running this test again", int(math.Ceil(wait.Seconds())))
+		}
+	}
+
+	// Check agent dispatch rate
+	if s.dispatchLimit != nil {
+		if ok, wait := s.dispatchLimit.allow(task.AgentId, time.Now()); !ok {
+			s.mutex.Unlock()
+			return false, fmt.Errorf("agent rate limited: retry in %s", wait.Round(time.Millisecond))
+		}
 	}
 
 	// Master acts as pure forwarder - no task type validation
 	// Agent will validate if it supports the task and return error if not
 
+	if useCooldown {
+		s.cooldown.record(submitKey, time.Now())
+	}
+
 	// Increment counters
 	s.currentTasks++
 	s.mutex.Unlock()
@@ -98,18 +265,27 @@ func (s *Scheduler) SubmitTask(ctx context.Context, task *pb.Task, clientID stri
 		s.mutex.Lock()
 		s.currentTasks--
 		s.mutex.Unlock()
-		return err
+		return false, err
 	}
 
-	// Create task info
-	taskCtx, cancel := context.WithCancel(ctx)
+	// Create task info (an absolute deadline bounds the whole execution)
+	var taskCtx context.Context
+	var cancel context.CancelFunc
+	if task.Deadline != nil {
+		taskCtx, cancel = context.WithDeadline(ctx, task.Deadline.AsTime())
+	} else {
+		taskCtx, cancel = context.WithCancel(ctx)
+	}
 	taskInfo := &TaskInfo{
 		Task:       task,
 		AgentID:    task.AgentId,
 		Status:     pb.TaskStatus_TASK_STATUS_PENDING,
 		CreatedAt:  time.Now(),
 		ClientID:   clientID,
+		ClientAddr: clientAddrFromContext(ctx),
 		CancelFunc: cancel,
+		attempt:    1,
+		ctx:        taskCtx,
 	}
 
 	// Store task info
@@ -118,6 +294,9 @@ func (s *Scheduler) SubmitTask(ctx context.Context, task *pb.Task, clientID stri
 	s.mutex.Unlock()
 
 	// Register output handler
+	if s.coalesceWindow > 0 && outputHandler != nil {
+		outputHandler = newCoalescingHandler(outputHandler, s.coalesceWindow).handle
+	}
 	s.handlerMutex.Lock()
 	s.outputHandlers[task.TaskId] = outputHandler
 	s.handlerMutex.Unlock()
@@ -127,19 +306,32 @@ func (s *Scheduler) SubmitTask(ctx context.Context, task *pb.Task, clientID stri
 		zap.String("agent_id", task.AgentId),
 		zap.String("type", task.Type.String()),
 	)
+	s.auditSubmit(taskInfo)
+	metrics.TaskSubmitted()
 
 	// Execute task asynchronously
 	go s.executeTask(taskCtx, taskInfo)
 
-	return nil
+	return false, nil
 }
 
 // executeTask executes a task on an agent
 func (s *Scheduler) executeTask(ctx context.Context, taskInfo *TaskInfo) {
 	task := taskInfo.Task
 
-	// Update task status to running
-	s.updateTaskStatus(task.TaskId, pb.TaskStatus_TASK_STATUS_RUNNING)
+	// Deadline may have passed while waiting to be dispatched
+	if task.Deadline != nil && ctx.Err() != nil {
+		s.handleTaskError(task.TaskId, fmt.Errorf("task deadline passed before dispatch"))
+		return
+	}
+
+	// Update task status to running (unless it was cancelled while pending)
+	if !s.startTask(task.TaskId) {
+		logger.Info("Task cancelled before dispatch",
+			zap.String("task_id", task.TaskId),
+		)
+		return
+	}
 
 	// Check if agent uses stream communication
 	agent, err := s.agentManager.GetAgent(task.AgentId)
@@ -148,7 +340,7 @@ func (s *Scheduler) executeTask(ctx context.Context, taskInfo *TaskInfo) {
 			zap.String("task_id", task.TaskId),
 			zap.Error(err),
 		)
-		s.handleTaskError(task.TaskId, err)
+		s.failOrRetry(task.TaskId, RetryOnDispatch, err)
 		return
 	}
 
@@ -158,37 +350,60 @@ func (s *Scheduler) executeTask(ctx context.Context, taskInfo *TaskInfo) {
 			logger.Error("Stream sender not configured",
 				zap.String("task_id", task.TaskId),
 			)
-			s.handleTaskError(task.TaskId, fmt.Errorf("stream sender not configured"))
+			s.handleTaskError(task.TaskId, errStreamSenderNotConfigured)
 			return
 		}
 
+		// A half-open stream would accept the task and never answer; fail fast instead
+		if s.prober != nil {
+			if err := s.prober.ProbeAgent(ctx, task.AgentId, s.probeTimeout); err != nil {
+				logger.Warn("Agent failed pre-dispatch probe",
+					zap.String("task_id", task.TaskId),
+					zap.String("agent_id", task.AgentId),
+					zap.Error(err),
+				)
+				s.failOrRetry(task.TaskId, RetryOnDispatch, fmt.Errorf("agent health probe failed: %w", err))
+				return
+			}
+		}
+
 		// Send task to agent via stream (fire-and-forget)
-		// Outputs will come back asynchronously via HandleTaskOutput
-		err := s.streamSender.SendTaskToAgent(task.AgentId, task)
+		// Outputs will come back asynchronously via HandleTaskOutput; a retry may move the task
+		// to another agent as soon as it is sent, so its agent is read once beforehand
+		agentID := task.AgentId
+		s.markDispatched(task.TaskId)
+		err := s.streamSender.SendTaskToAgent(agentID, task)
 		if err != nil {
 			logger.Error("Failed to send task to agent via stream",
 				zap.String("task_id", task.TaskId),
 				zap.Error(err),
 			)
-			s.handleTaskError(task.TaskId, err)
+			s.failOrRetry(task.TaskId, RetryOnDispatch, err)
 			return
 		}
 
 		logger.Info("Task sent to agent via stream",
 			zap.String("task_id", task.TaskId),
-			zap.String("agent_id", task.AgentId),
+			zap.String("agent_id", agentID),
 		)
+
+		// One watcher per task: retries reuse the context it is already waiting on
+		if task.Deadline != nil && s.firstAttempt(task.TaskId) {
+			go s.enforceDeadline(ctx, task.TaskId, agentID)
+		}
 		// Task will complete asynchronously via HandleTaskOutput callbacks
 		return
 	}
 
 	// Legacy gRPC-based communication (deprecated)
+	s.markDispatched(task.TaskId)
 	stream, err := s.agentManager.ExecuteTaskOnAgent(ctx, task.AgentId, task)
 	if err != nil {
 		logger.Error("Failed to execute task on agent",
 			zap.String("task_id", task.TaskId),
 			zap.Error(err),
 		)
+		s.markAgentFault(task.TaskId)
 		s.handleTaskError(task.TaskId, err)
 		return
 	}
@@ -208,10 +423,12 @@ func (s *Scheduler) executeTask(ctx context.Context, taskInfo *TaskInfo) {
 					zap.String("task_id", task.TaskId),
 					zap.Error(err),
 				)
+				s.markAgentFault(task.TaskId)
 				s.handleTaskError(task.TaskId, err)
 			}
 			break
 		}
+		s.markFirstResponse(task.TaskId)
 
 		// Filter out message
 		if !s.filterOutput(output) {
@@ -224,6 +441,10 @@ func (s *Scheduler) executeTask(ctx context.Context, taskInfo *TaskInfo) {
 			s.completeTask(task.TaskId, pb.TaskStatus_TASK_STATUS_COMPLETED)
 			break
 		} else if output.Status == pb.TaskStatus_TASK_STATUS_FAILED {
+			s.recordFailure(task.TaskId, output.ErrorMessage)
+			if output.AgentFault {
+				s.markAgentFault(task.TaskId)
+			}
 			s.completeTask(task.TaskId, pb.TaskStatus_TASK_STATUS_FAILED)
 			break
 		} else if output.Status == pb.TaskStatus_TASK_STATUS_CANCELLED {
@@ -233,6 +454,40 @@ func (s *Scheduler) executeTask(ctx context.Context, taskInfo *TaskInfo) {
 	}
 }
 
+// enforceDeadline fails a stream task that is still running when its deadline expires
+// Returns early when the task finishes (completeTask releases the context)
+func (s *Scheduler) enforceDeadline(ctx context.Context, taskID, agentID string) {
+	<-ctx.Done()
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return
+	}
+
+	s.mutex.RLock()
+	taskInfo, ok := s.tasks[taskID]
+	finished := !ok || isTerminalStatus(taskInfo.Status)
+	s.mutex.RUnlock()
+	if finished {
+		return
+	}
+
+	logger.Warn("Task deadline exceeded",
+		zap.String("task_id", taskID),
+		zap.String("agent_id", agentID),
+	)
+
+	if s.streamSender == nil {
+		logger.Warn("Stream sender not configured, skipping agent-side cancel after deadline",
+			zap.String("task_id", taskID),
+		)
+	} else if err := s.streamSender.CancelTaskOnAgent(agentID, taskID); err != nil {
+		logger.Error("Failed to cancel task on agent after deadline",
+			zap.String("task_id", taskID),
+			zap.Error(err),
+		)
+	}
+	s.handleTaskError(taskID, fmt.Errorf("task deadline exceeded"))
+}
+
 // HandleTaskOutput handles task output from stream (called by StreamHandler)
 func (s *Scheduler) HandleTaskOutput(output *pb.TaskOutput) {
 	if output == nil {
@@ -240,6 +495,32 @@ func (s *Scheduler) HandleTaskOutput(output *pb.TaskOutput) {
 	}
 
 	taskID := output.TaskId
+	s.markFirstResponse(taskID)
+	s.checkSequence(output)
+
+	// Output past the byte cap is dropped; crossing it stops the task
+	if over, crossed := s.countOutputBytes(output); over {
+		if crossed {
+			s.stopOverByteCap(taskID)
+		}
+		return
+	}
+
+	// A retried attempt replaces the failure the client would otherwise see
+	if output.Status == pb.TaskStatus_TASK_STATUS_FAILED && s.scheduleRetry(taskID, RetryOnAgent, output.ErrorMessage) {
+		return
+	}
+
+	// Attach master-side metrics to the agent's final status
+	if isTerminalStatus(output.Status) {
+		s.attachCompletionSummary(output)
+	}
+
+	// A compressed block carries the output lines of the whole task
+	if len(output.CompressedOutput) > 0 {
+		s.forwardCompressed(output)
+		return
+	}
 
 	// Filter out message
 	if !s.filterOutput(output) {
@@ -251,6 +532,10 @@ func (s *Scheduler) HandleTaskOutput(output *pb.TaskOutput) {
 	if output.Status == pb.TaskStatus_TASK_STATUS_COMPLETED {
 		s.completeTask(taskID, pb.TaskStatus_TASK_STATUS_COMPLETED)
 	} else if output.Status == pb.TaskStatus_TASK_STATUS_FAILED {
+		s.recordFailure(taskID, output.ErrorMessage)
+		if output.AgentFault {
+			s.markAgentFault(taskID)
+		}
 		s.completeTask(taskID, pb.TaskStatus_TASK_STATUS_FAILED)
 	} else if output.Status == pb.TaskStatus_TASK_STATUS_CANCELLED {
 		s.completeTask(taskID, pb.TaskStatus_TASK_STATUS_CANCELLED)
@@ -264,9 +549,35 @@ func (s *Scheduler) CancelTask(taskID string) error {
 	s.mutex.RUnlock()
 
 	if !ok {
+		if s.cancelQueued(taskID) || s.cancelGroup(taskID) {
+			return nil
+		}
 		return fmt.Errorf("task not found: %s", taskID)
 	}
 
+	if isTerminalStatus(taskInfo.Status) {
+		return fmt.Errorf("task already finished: %s", taskID)
+	}
+
+	// A pending task has not reached an agent yet: drop it locally without an agent-side cancel
+	s.mutex.Lock()
+	pending := taskInfo.Status == pb.TaskStatus_TASK_STATUS_PENDING
+	if pending {
+		taskInfo.cancelledBeforeStart = true
+	}
+	s.mutex.Unlock()
+
+	if pending {
+		if taskInfo.CancelFunc != nil {
+			taskInfo.CancelFunc()
+		}
+		s.completeTask(taskID, pb.TaskStatus_TASK_STATUS_CANCELLED)
+		logger.Info("Pending task cancelled before dispatch",
+			zap.String("task_id", taskID),
+		)
+		return nil
+	}
+
 	// Cancel context
 	if taskInfo.CancelFunc != nil {
 		taskInfo.CancelFunc()
@@ -274,10 +585,13 @@ func (s *Scheduler) CancelTask(taskID string) error {
 
 	// Check if agent uses stream
 	agent, err := s.agentManager.GetAgent(taskInfo.AgentID)
-	if err == nil && agent.UseStream && s.streamSender != nil {
+	if err == nil && agent.UseStream {
 		// Use stream-based cancellation
-		err := s.streamSender.CancelTaskOnAgent(taskInfo.AgentID, taskID)
-		if err != nil {
+		if s.streamSender == nil {
+			logger.Warn("Stream sender not configured, cancelling task locally only",
+				zap.String("task_id", taskID),
+			)
+		} else if err := s.streamSender.CancelTaskOnAgent(taskInfo.AgentID, taskID); err != nil {
 			logger.Error("Failed to cancel task on agent via stream",
 				zap.String("task_id", taskID),
 				zap.Error(err),
@@ -308,6 +622,72 @@ func (s *Scheduler) CancelTask(taskID string) error {
 	return nil
 }
 
+// CancelAllTasks cancels every pending or running task across all agents
+// Returns the number of tasks that were cancelled
+func (s *Scheduler) CancelAllTasks() int {
+	// Drop queued tasks first so slots freed below are not refilled from the queue
+	s.mutex.RLock()
+	queuedIDs := make([]string, 0, len(s.queue))
+	for _, entry := range s.queue {
+		queuedIDs = append(queuedIDs, entry.task.TaskId)
+	}
+	s.mutex.RUnlock()
+
+	cancelled := 0
+	for _, taskID := range queuedIDs {
+		if s.cancelQueued(taskID) {
+			cancelled++
+		}
+	}
+
+	s.mutex.RLock()
+	taskIDs := make([]string, 0, len(s.tasks))
+	for taskID, taskInfo := range s.tasks {
+		if !isTerminalStatus(taskInfo.Status) {
+			taskIDs = append(taskIDs, taskID)
+		}
+	}
+	s.mutex.RUnlock()
+
+	for _, taskID := range taskIDs {
+		if err := s.CancelTask(taskID); err != nil {
+			logger.Warn("Failed to cancel task during cancel-all",
+				zap.String("task_id", taskID),
+				zap.Error(err),
+			)
+			continue
+		}
+		cancelled++
+	}
+
+	logger.Warn("All tasks cancelled",
+		zap.Int("count", cancelled),
+	)
+
+	return cancelled
+}
+
+// isTerminalStatus reports whether a task status is final
+func isTerminalStatus(status pb.TaskStatus) bool {
+	return status == pb.TaskStatus_TASK_STATUS_COMPLETED ||
+		status == pb.TaskStatus_TASK_STATUS_FAILED ||
+		status == pb.TaskStatus_TASK_STATUS_CANCELLED
+}
+
+// startTask moves a pending task to running
+// Returns false if the task was cancelled (or finished) before it could start
+func (s *Scheduler) startTask(taskID string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	taskInfo, ok := s.tasks[taskID]
+	if !ok || taskInfo.cancelledBeforeStart || isTerminalStatus(taskInfo.Status) {
+		return false
+	}
+	taskInfo.Status = pb.TaskStatus_TASK_STATUS_RUNNING
+	return true
+}
+
 // updateTaskStatus updates the status of a task
 func (s *Scheduler) updateTaskStatus(taskID string, status pb.TaskStatus) {
 	s.mutex.Lock()
@@ -322,7 +702,8 @@ func (s *Scheduler) updateTaskStatus(taskID string, status pb.TaskStatus) {
 func (s *Scheduler) completeTask(taskID string, status pb.TaskStatus) {
 	s.mutex.Lock()
 	taskInfo, ok := s.tasks[taskID]
-	if !ok {
+	if !ok || isTerminalStatus(taskInfo.Status) {
+		// Unknown or already completed (e.g. agent reports CANCELLED after a local cancel)
 		s.mutex.Unlock()
 		return
 	}
@@ -332,51 +713,173 @@ func (s *Scheduler) completeTask(taskID string, status pb.TaskStatus) {
 	// Decrement counters
 	s.currentTasks--
 	agentID := taskInfo.AgentID
+	cancel := taskInfo.CancelFunc
+	agentFault := taskInfo.agentFault
 	s.mutex.Unlock()
 
+	// Release the task context (stops deadline enforcement)
+	if cancel != nil {
+		cancel()
+	}
+
 	// Decrement agent task count
 	_ = s.agentManager.DecrementTaskCount(agentID)
 
-	// Send completion notification to client BEFORE removing handler
+	// Remove the handler, then send the completion notification to the client outside the lock,
+	// since a handler may wait for its client to catch up
 	// (only if not already sent via forwardOutput, which happens in handleTaskError)
 	s.handlerMutex.Lock()
 	handler, ok := s.outputHandlers[taskID]
+	delete(s.outputHandlers, taskID)
+	s.handlerMutex.Unlock()
 	if ok && handler != nil {
 		// Send final status update to client
 		// Note: For FAILED status, this is already sent by handleTaskError
 		// For COMPLETED/CANCELLED, we need to send it here
 		if status == pb.TaskStatus_TASK_STATUS_COMPLETED || status == pb.TaskStatus_TASK_STATUS_CANCELLED {
-			handler(&pb.TaskOutput{
+			final := &pb.TaskOutput{
 				TaskId: taskID,
 				Status: status,
-			})
+			}
+			s.tagRetriedAgent(final)
+			s.attachCompletionSummary(final)
+			s.publishOutput(final)
+			handler(final)
 		}
 	}
-	// Now remove the handler
-	delete(s.outputHandlers, taskID)
-	s.handlerMutex.Unlock()
 
-	logger.Info("Task completed",
+	fields := []zap.Field{
 		zap.String("task_id", taskID),
 		zap.String("status", status.String()),
-	)
+	}
+	if latency, ok := s.dispatchLatency(taskID); ok {
+		fields = append(fields, zap.Duration("dispatch_latency", latency))
+	}
+	logger.Info("Task completed", fields...)
+	s.auditComplete(taskInfo, status)
+	s.recordHistory(taskInfo, status)
+	s.storeResult(taskInfo, status)
+	s.recordBreaker(agentID, status, agentFault)
+	// Failures reported through handleTaskError end up here as well
+	metrics.TaskFinished(status, time.Since(taskInfo.CreatedAt))
+
+	// A slot freed up: start whatever was waiting for it
+	s.dispatchQueue()
+
+	if status == pb.TaskStatus_TASK_STATUS_FAILED {
+		s.notifyTaskFailed(taskInfo)
+	}
+}
+
+// markDispatched records when a task was sent to its agent
+func (s *Scheduler) markDispatched(taskID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if taskInfo, ok := s.tasks[taskID]; ok {
+		taskInfo.DispatchedAt = time.Now()
+	}
+}
+
+// markFirstResponse records when the first output for a task arrived
+func (s *Scheduler) markFirstResponse(taskID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if taskInfo, ok := s.tasks[taskID]; ok && taskInfo.FirstResponseAt.IsZero() {
+		taskInfo.FirstResponseAt = time.Now()
+	}
+}
+
+// checkSequence logs a gap in a task's agent output sequence, i.e. output dropped on the way
+func (s *Scheduler) checkSequence(output *pb.TaskOutput) {
+	if output.Sequence == 0 {
+		return
+	}
+
+	s.mutex.Lock()
+	taskInfo, ok := s.tasks[output.TaskId]
+	if !ok {
+		s.mutex.Unlock()
+		return
+	}
+	expected := taskInfo.lastSequence + 1
+	if output.Sequence > taskInfo.lastSequence {
+		taskInfo.lastSequence = output.Sequence
+	}
+	s.mutex.Unlock()
+
+	if output.Sequence > expected {
+		logger.Warn("Gap in task output sequence",
+			zap.String("task_id", output.TaskId),
+			zap.Uint64("expected", expected),
+			zap.Uint64("received", output.Sequence),
+			zap.Uint64("missing", output.Sequence-expected),
+		)
+	}
+}
+
+// dispatchLatency returns the time between dispatch and the first agent response
+func (s *Scheduler) dispatchLatency(taskID string) (time.Duration, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	taskInfo, ok := s.tasks[taskID]
+	if !ok || taskInfo.DispatchedAt.IsZero() || taskInfo.FirstResponseAt.IsZero() {
+		return 0, false
+	}
+	return taskInfo.FirstResponseAt.Sub(taskInfo.DispatchedAt), true
+}
+
+// attachCompletionSummary adds master-side metrics to a final task output
+func (s *Scheduler) attachCompletionSummary(output *pb.TaskOutput) {
+	if transferred, ok := s.bytesTransferred(output.TaskId); ok && transferred > 0 {
+		if output.Summary == nil {
+			output.Summary = &pb.TaskSummary{}
+		}
+		output.Summary.BytesTransferred = transferred
+	}
+
+	if !s.reportDispatchLatency {
+		return
+	}
+
+	latency, ok := s.dispatchLatency(output.TaskId)
+	if !ok {
+		return
+	}
+
+	if output.Summary == nil {
+		output.Summary = &pb.TaskSummary{}
+	}
+	output.Summary.DispatchLatencyMs = latency.Milliseconds()
 }
 
 // handleTaskError handles task execution errors
 func (s *Scheduler) handleTaskError(taskID string, err error) {
+	s.recordFailure(taskID, err.Error())
+
 	// Send error output to client
-	s.forwardOutput(&pb.TaskOutput{
+	failed := &pb.TaskOutput{
 		TaskId:       taskID,
 		Timestamp:    timestamppb.New(time.Now()),
 		Status:       pb.TaskStatus_TASK_STATUS_FAILED,
 		ErrorMessage: err.Error(),
-	})
+	}
+	s.attachCompletionSummary(failed)
+	s.forwardOutput(failed)
 
 	s.completeTask(taskID, pb.TaskStatus_TASK_STATUS_FAILED)
 }
 
 // forwardOutput forwards task output to the registered handler
 func (s *Scheduler) forwardOutput(output *pb.TaskOutput) {
+	s.tagRetriedAgent(output)
+	s.redactOutput(output)
+	s.publishOutput(output)
+	s.captureOutput(output)
+	s.captureResult(output)
+
 	s.handlerMutex.RLock()
 	handler, ok := s.outputHandlers[output.TaskId]
 	s.handlerMutex.RUnlock()
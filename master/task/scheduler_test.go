@@ -0,0 +1,390 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lureiny/lookingglass/master/agent"
+	pb "github.com/lureiny/lookingglass/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeSender records the tasks the scheduler dispatches instead of streaming them to an agent
+type fakeSender struct {
+	mu        sync.Mutex
+	sent      chan *pb.Task
+	cancelled []string
+	err       error
+}
+
+func newFakeSender() *fakeSender {
+	return &fakeSender{sent: make(chan *pb.Task, 64)}
+}
+
+func (f *fakeSender) SendTaskToAgent(agentID string, task *pb.Task) error {
+	f.mu.Lock()
+	err := f.err
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	f.sent <- task
+	return nil
+}
+
+func (f *fakeSender) CancelTaskOnAgent(agentID string, taskID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cancelled = append(f.cancelled, taskID)
+	return nil
+}
+
+// waitSent returns the next dispatched task or fails the test
+func (f *fakeSender) waitSent(t *testing.T) *pb.Task {
+	t.Helper()
+	select {
+	case task := <-f.sent:
+		return task
+	case <-time.After(2 * time.Second):
+		t.Fatal("no task dispatched")
+		return nil
+	}
+}
+
+// newTestScheduler returns a scheduler with the given stream agents online, each supporting ping
+func newTestScheduler(t *testing.T, agentIDs ...string) (*Scheduler, *agent.Manager, *fakeSender) {
+	t.Helper()
+	am := agent.NewManager(time.Minute, time.Minute)
+	t.Cleanup(am.Stop)
+	for _, id := range agentIDs {
+		err := am.RegisterAgentFromStream(&pb.AgentInfo{
+			Id:              id,
+			Name:            id,
+			MaxConcurrent:   5,
+			TaskDisplayInfo: []*pb.TaskDisplayInfo{{TaskName: "ping", RequiresTarget: true}},
+		})
+		if err != nil {
+			t.Fatalf("register %s: %v", id, err)
+		}
+	}
+	s := NewScheduler(am, 10)
+	sender := newFakeSender()
+	s.SetStreamSender(sender)
+	return s, am, sender
+}
+
+// pingTask builds a ping task for agentID
+func pingTask(taskID, agentID, target string) *pb.Task {
+	return &pb.Task{
+		TaskId:   taskID,
+		AgentId:  agentID,
+		TaskName: "ping",
+		Params: &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{
+			Target: target,
+			Count:  4,
+		}},
+	}
+}
+
+// outputRecorder collects the outputs a scheduler forwards to a client
+type outputRecorder struct {
+	mu      sync.Mutex
+	outputs []*pb.TaskOutput
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newOutputRecorder() *outputRecorder {
+	return &outputRecorder{done: make(chan struct{})}
+}
+
+func (r *outputRecorder) handle(output *pb.TaskOutput) {
+	r.mu.Lock()
+	r.outputs = append(r.outputs, output)
+	r.mu.Unlock()
+	if isTerminalStatus(output.Status) {
+		r.once.Do(func() { close(r.done) })
+	}
+}
+
+// wait blocks until a terminal output arrives and returns everything recorded
+func (r *outputRecorder) wait(t *testing.T) []*pb.TaskOutput {
+	t.Helper()
+	select {
+	case <-r.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no terminal output")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*pb.TaskOutput(nil), r.outputs...)
+}
+
+func TestSubmitTaskDispatchesAndCompletes(t *testing.T) {
+	s, am, sender := newTestScheduler(t, "agent-1")
+	rec := newOutputRecorder()
+
+	if err := s.SubmitTask(t.Context(), pingTask("t1", "agent-1", "1.1.1.1"), "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	if got := sender.waitSent(t); got.TaskId != "t1" {
+		t.Fatalf("dispatched %q, want t1", got.TaskId)
+	}
+
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", OutputLine: "64 bytes from 1.1.1.1", Status: pb.TaskStatus_TASK_STATUS_RUNNING})
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+
+	outputs := rec.wait(t)
+	if outputs[0].OutputLine != "64 bytes from 1.1.1.1" {
+		t.Fatalf("first output = %v", outputs[0])
+	}
+	if last := outputs[len(outputs)-1]; last.Status != pb.TaskStatus_TASK_STATUS_COMPLETED {
+		t.Fatalf("last status = %s, want COMPLETED", last.Status)
+	}
+	a, _ := am.GetAgent("agent-1")
+	if a.CurrentTasks != 0 {
+		t.Errorf("agent task count = %d after completion, want 0", a.CurrentTasks)
+	}
+}
+
+func TestCancelAllTasksCancelsRunningAndQueued(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	s.SetQueueMax(5)
+
+	// The agent runs 5 tasks at once; the sixth waits in the queue
+	recorders := make([]*outputRecorder, 6)
+	for i := range recorders {
+		recorders[i] = newOutputRecorder()
+		taskID := fmt.Sprintf("t%d", i)
+		if err := s.SubmitTask(t.Context(), pingTask(taskID, "agent-1", "1.1.1.1"), "client-1", recorders[i].handle); err != nil {
+			t.Fatalf("SubmitTask(%s) error = %v", taskID, err)
+		}
+	}
+	for range 5 {
+		sender.waitSent(t)
+	}
+	if got := s.GetQueueLength(); got != 1 {
+		t.Fatalf("queue length = %d, want 1", got)
+	}
+
+	if got := s.CancelAllTasks(); got != 6 {
+		t.Errorf("CancelAllTasks() = %d, want 6", got)
+	}
+	for i, rec := range recorders {
+		outputs := rec.wait(t)
+		if last := outputs[len(outputs)-1]; last.Status != pb.TaskStatus_TASK_STATUS_CANCELLED {
+			t.Errorf("t%d: last status = %s, want CANCELLED", i, last.Status)
+		}
+	}
+	if got := s.GetQueueLength(); got != 0 {
+		t.Errorf("queue length = %d after cancel-all, want 0", got)
+	}
+	sender.mu.Lock()
+	if len(sender.cancelled) != 5 {
+		t.Errorf("cancelled on agent = %v, want the 5 dispatched tasks", sender.cancelled)
+	}
+	sender.mu.Unlock()
+
+	if got := s.CancelAllTasks(); got != 0 {
+		t.Errorf("second CancelAllTasks() = %d, want 0", got)
+	}
+}
+
+func TestReportDispatchLatency(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		s, _, sender := newTestScheduler(t, "agent-1")
+		s.SetReportDispatchLatency(enabled)
+		rec := newOutputRecorder()
+		if err := s.SubmitTask(t.Context(), pingTask("t1", "agent-1", "1.1.1.1"), "client-1", rec.handle); err != nil {
+			t.Fatalf("SubmitTask() error = %v", err)
+		}
+		sender.waitSent(t)
+		time.Sleep(20 * time.Millisecond)
+		s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+
+		outputs := rec.wait(t)
+		latency := outputs[len(outputs)-1].GetSummary().GetDispatchLatencyMs()
+		if enabled && latency < 20 {
+			t.Errorf("dispatch latency = %dms, want at least the 20ms before the first response", latency)
+		}
+		if !enabled && latency != 0 {
+			t.Errorf("dispatch latency = %dms reported while disabled", latency)
+		}
+	}
+}
+
+func TestSubmitTaskRejectsPastDeadline(t *testing.T) {
+	s, _, _ := newTestScheduler(t, "agent-1")
+	task := pingTask("t1", "agent-1", "1.1.1.1")
+	task.Deadline = timestamppb.New(time.Now().Add(-time.Second))
+
+	err := s.SubmitTask(t.Context(), task, "client-1", newOutputRecorder().handle)
+	if err == nil || !strings.Contains(err.Error(), "deadline already passed") {
+		t.Errorf("SubmitTask() error = %v, want deadline already passed", err)
+	}
+}
+
+func TestTaskDeadlineExceeded(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	task := pingTask("t1", "agent-1", "1.1.1.1")
+	task.Deadline = timestamppb.New(time.Now().Add(50 * time.Millisecond))
+
+	rec := newOutputRecorder()
+	if err := s.SubmitTask(t.Context(), task, "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	sender.waitSent(t)
+
+	// The agent never answers: the deadline fails the task and cancels it on the agent
+	outputs := rec.wait(t)
+	last := outputs[len(outputs)-1]
+	if last.Status != pb.TaskStatus_TASK_STATUS_FAILED || !strings.Contains(last.ErrorMessage, "deadline exceeded") {
+		t.Errorf("last output = %v, want deadline exceeded failure", last)
+	}
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	if len(sender.cancelled) != 1 || sender.cancelled[0] != "t1" {
+		t.Errorf("cancelled on agent = %v, want [t1]", sender.cancelled)
+	}
+}
+
+func TestCancelPendingTaskSkipsDispatch(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "a")
+	rec := newOutputRecorder()
+	task := pingTask("t1", "a", "1.1.1.1")
+	ctx, cancel := context.WithCancel(context.Background())
+	taskInfo := &TaskInfo{
+		Task:       task,
+		AgentID:    "a",
+		Status:     pb.TaskStatus_TASK_STATUS_PENDING,
+		CreatedAt:  time.Now(),
+		CancelFunc: cancel,
+	}
+	s.mutex.Lock()
+	s.tasks[task.TaskId] = taskInfo
+	s.currentTasks++
+	s.mutex.Unlock()
+	s.handlerMutex.Lock()
+	s.outputHandlers[task.TaskId] = rec.handle
+	s.handlerMutex.Unlock()
+
+	if err := s.CancelTask("t1"); err != nil {
+		t.Fatalf("CancelTask: %v", err)
+	}
+	outputs := rec.wait(t)
+	if last := outputs[len(outputs)-1]; last.Status != pb.TaskStatus_TASK_STATUS_CANCELLED {
+		t.Errorf("final status = %v, want CANCELLED", last.Status)
+	}
+	if ctx.Err() == nil {
+		t.Error("task context not cancelled")
+	}
+
+	// The dispatch goroutine picking the task up afterwards must not send it
+	s.executeTask(ctx, taskInfo)
+	select {
+	case sent := <-sender.sent:
+		t.Fatalf("cancelled pending task %s was dispatched", sent.TaskId)
+	default:
+	}
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	if len(sender.cancelled) != 0 {
+		t.Errorf("agent-side cancel sent for a pending task: %v", sender.cancelled)
+	}
+}
+
+func TestMissingStreamSender(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "a")
+	s.SetStreamSender(nil)
+	err := s.SubmitTask(context.Background(), pingTask("t1", "a", "1.1.1.1"), "c1", func(*pb.TaskOutput) {})
+	if !errors.Is(err, errStreamSenderNotConfigured) {
+		t.Fatalf("submit without a stream sender: err = %v, want %v", err, errStreamSenderNotConfigured)
+	}
+
+	// A running task whose sender went away is still cancelled locally
+	s.SetStreamSender(sender)
+	rec := newOutputRecorder()
+	if err := s.SubmitTask(context.Background(), pingTask("t2", "a", "1.1.1.1"), "c1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask: %v", err)
+	}
+	sender.waitSent(t)
+	s.SetStreamSender(nil)
+	if err := s.CancelTask("t2"); err != nil {
+		t.Fatalf("CancelTask: %v", err)
+	}
+	outputs := rec.wait(t)
+	if last := outputs[len(outputs)-1]; last.Status != pb.TaskStatus_TASK_STATUS_CANCELLED {
+		t.Errorf("final status = %v, want CANCELLED", last.Status)
+	}
+}
+
+func TestCheckSequenceTracksHighest(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "a")
+	rec := newOutputRecorder()
+	if err := s.SubmitTask(context.Background(), pingTask("t1", "a", "1.1.1.1"), "c1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask: %v", err)
+	}
+	sender.waitSent(t)
+
+	// 2 is skipped, then arrives late; unnumbered output is ignored
+	for _, seq := range []uint64{1, 3, 2, 0} {
+		s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_RUNNING, Sequence: seq})
+	}
+	s.mutex.RLock()
+	last := s.tasks["t1"].lastSequence
+	s.mutex.RUnlock()
+	if last != 3 {
+		t.Errorf("lastSequence = %d, want 3", last)
+	}
+
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_COMPLETED, Sequence: 4})
+	outputs := rec.wait(t)
+	for _, output := range outputs[:3] {
+		if output.Sequence == 0 {
+			t.Errorf("forwarded output lost its sequence: %v", output)
+		}
+	}
+}
+
+// fakeProber answers pre-dispatch probes with a fixed result
+type fakeProber struct {
+	err error
+}
+
+func (f *fakeProber) ProbeAgent(ctx context.Context, agentID string, timeout time.Duration) error {
+	return f.err
+}
+
+func TestDispatchProbe(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "a")
+	prober := &fakeProber{err: errors.New("probe timed out")}
+	s.SetDispatchProbe(prober, time.Second)
+
+	rec := newOutputRecorder()
+	if err := s.SubmitTask(context.Background(), pingTask("t1", "a", "1.1.1.1"), "c1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask: %v", err)
+	}
+	outputs := rec.wait(t)
+	last := outputs[len(outputs)-1]
+	if last.Status != pb.TaskStatus_TASK_STATUS_FAILED || !strings.Contains(last.ErrorMessage, "agent health probe failed") {
+		t.Errorf("final output = %v, want a probe failure", last)
+	}
+	select {
+	case task := <-sender.sent:
+		t.Errorf("task %s dispatched to an agent that failed its probe", task.TaskId)
+	default:
+	}
+
+	prober.err = nil
+	if err := s.SubmitTask(context.Background(), pingTask("t2", "a", "1.1.1.1"), "c1", func(*pb.TaskOutput) {}); err != nil {
+		t.Fatalf("SubmitTask: %v", err)
+	}
+	if task := sender.waitSent(t); task.TaskId != "t2" {
+		t.Errorf("dispatched %s, want t2", task.TaskId)
+	}
+}
@@ -0,0 +1,100 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// selfCheckClientID marks self-check tasks; their output is logged and never routed to users
+const selfCheckClientID = "self-check"
+
+// SelfCheckTask describes a task run against newly registered agents
+type SelfCheckTask struct {
+	TaskName string
+	Target   string
+	Count    int32
+	Timeout  int32 // seconds
+}
+
+// SelfChecker dispatches self-check tasks to agents on first registration
+type SelfChecker struct {
+	scheduler *Scheduler
+	tasks     []SelfCheckTask
+}
+
+// NewSelfChecker creates a self-checker that runs tasks through the scheduler
+func NewSelfChecker(scheduler *Scheduler, tasks []SelfCheckTask) *SelfChecker {
+	return &SelfChecker{
+		scheduler: scheduler,
+		tasks:     tasks,
+	}
+}
+
+// RunForAgent submits every configured self-check task to the agent
+func (c *SelfChecker) RunForAgent(agentID string) {
+	for _, check := range c.tasks {
+		task := &pb.Task{
+			TaskId:    uuid.New().String(),
+			AgentId:   agentID,
+			TaskName:  check.TaskName,
+			CreatedAt: timestamppb.Now(),
+			Timeout:   check.Timeout,
+			Params: &pb.Task_NetworkTest{
+				NetworkTest: &pb.NetworkTestParams{
+					Target:  check.Target,
+					Count:   check.Count,
+					Timeout: check.Timeout,
+				},
+			},
+		}
+
+		start := time.Now()
+		handler := func(output *pb.TaskOutput) {
+			c.logOutput(agentID, check.TaskName, start, output)
+		}
+
+		if err := c.scheduler.SubmitTask(context.Background(), task, selfCheckClientID, handler); err != nil {
+			logger.Warn("Failed to submit self-check task",
+				zap.String("agent_id", agentID),
+				zap.String("task_name", check.TaskName),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		logger.Info("Self-check task dispatched",
+			zap.String("agent_id", agentID),
+			zap.String("task_name", check.TaskName),
+			zap.String("task_id", task.TaskId),
+		)
+	}
+}
+
+// logOutput records self-check output in the master log
+func (c *SelfChecker) logOutput(agentID, taskName string, start time.Time, output *pb.TaskOutput) {
+	fields := []zap.Field{
+		zap.String("agent_id", agentID),
+		zap.String("task_name", taskName),
+		zap.String("task_id", output.TaskId),
+	}
+
+	switch output.Status {
+	case pb.TaskStatus_TASK_STATUS_COMPLETED:
+		logger.Info("Self-check passed", append(fields, zap.Duration("duration", time.Since(start)))...)
+	case pb.TaskStatus_TASK_STATUS_FAILED, pb.TaskStatus_TASK_STATUS_CANCELLED:
+		logger.Warn("Self-check failed", append(fields,
+			zap.String("status", output.Status.String()),
+			zap.String("error", output.ErrorMessage),
+		)...)
+	default:
+		if output.OutputLine != "" {
+			logger.Debug("Self-check output", append(fields, zap.String("line", output.OutputLine))...)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package task
+
+import (
+	"testing"
+)
+
+func TestSelfCheckerRunForAgent(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	checker := NewSelfChecker(s, []SelfCheckTask{
+		{TaskName: "ping", Target: "192.0.2.1", Count: 3, Timeout: 10},
+		{TaskName: "ping", Target: "192.0.2.2", Count: 1, Timeout: 5},
+	})
+
+	checker.RunForAgent("agent-1")
+
+	// Tasks are dispatched concurrently, in any order
+	targets := make(map[string]bool)
+	for range 2 {
+		task := sender.waitSent(t)
+		if task.AgentId != "agent-1" || task.TaskName != "ping" {
+			t.Errorf("dispatched %v, want ping on agent-1", task)
+		}
+		targets[task.GetNetworkTest().GetTarget()] = true
+		info, err := s.GetTask(task.TaskId)
+		if err != nil {
+			t.Fatalf("GetTask() error = %v", err)
+		}
+		if info.ClientID != selfCheckClientID {
+			t.Errorf("client ID = %q, want %q", info.ClientID, selfCheckClientID)
+		}
+	}
+	if !targets["192.0.2.1"] || !targets["192.0.2.2"] {
+		t.Errorf("dispatched targets %v, want both self-checks", targets)
+	}
+}
+
+func TestSelfCheckerSkipsRejectedTasks(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	checker := NewSelfChecker(s, []SelfCheckTask{{TaskName: "ping", Target: "192.0.2.1"}})
+
+	// An unknown agent rejects the submission; nothing is dispatched
+	checker.RunForAgent("missing")
+	select {
+	case task := <-sender.sent:
+		t.Errorf("dispatched %v for an unknown agent", task)
+	default:
+	}
+}
@@ -0,0 +1,128 @@
+package task
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// shareTokenBytes is the entropy of a share token; 256 bits cannot be guessed
+	shareTokenBytes = 32
+	// shareMaxLinks bounds the share links alive at once
+	shareMaxLinks = 10000
+)
+
+// Reasons CreateShareLink and SharedResult fail; callers can branch with errors.Is
+var (
+	ErrShareDisabled  = errors.New("share links are disabled")
+	ErrShareNotFound  = errors.New("result not found or expired")
+	ErrShareForbidden = errors.New("only the client that submitted the task may share it")
+)
+
+// shareLink grants read-only access to a history entry until it expires
+type shareLink struct {
+	taskID    string
+	expiresAt time.Time
+}
+
+// SetShareLinkTTL lets finished tasks in the history be shared by link for ttl
+// A ttl <= 0 disables share links; they also need history to be enabled
+func (s *Scheduler) SetShareLinkTTL(ttl time.Duration) {
+	s.shareTTL = max(ttl, 0)
+}
+
+// CreateShareLink creates a token giving read-only access to a finished task's result
+// The link expires after the share TTL, or earlier if the task leaves the history
+// clientID is the requesting client, which must be the one that submitted the task; an empty
+// clientID skips the check and is for callers that authorized the request themselves (admins)
+func (s *Scheduler) CreateShareLink(taskID, clientID string) (string, time.Time, error) {
+	if s.history == nil || s.shareTTL <= 0 {
+		return "", time.Time{}, ErrShareDisabled
+	}
+	h := s.history
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	h.pruneLocked(now)
+
+	entry := h.findLocked(taskID)
+	if entry == nil {
+		return "", time.Time{}, fmt.Errorf("%w: %s", ErrShareNotFound, taskID)
+	}
+	if clientID != "" && entry.ClientID != clientID {
+		return "", time.Time{}, ErrShareForbidden
+	}
+	if len(h.shares) >= shareMaxLinks {
+		return "", time.Time{}, fmt.Errorf("too many share links, try again later")
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := now.Add(s.shareTTL)
+	if retained := entry.EndedAt.Add(h.retention); retained.Before(expiresAt) {
+		expiresAt = retained
+	}
+	h.shares[token] = &shareLink{taskID: taskID, expiresAt: expiresAt}
+	return token, expiresAt, nil
+}
+
+// SharedResult returns the result a share token grants access to and when the link expires
+// The entry is a copy without the submitting client's ID
+func (s *Scheduler) SharedResult(token string) (*HistoryEntry, time.Time, error) {
+	if s.history == nil || s.shareTTL <= 0 {
+		return nil, time.Time{}, ErrShareDisabled
+	}
+	h := s.history
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pruneLocked(time.Now())
+
+	link, ok := h.shares[token]
+	if !ok {
+		return nil, time.Time{}, ErrShareNotFound
+	}
+	entry := h.findLocked(link.taskID)
+	if entry == nil {
+		delete(h.shares, token)
+		return nil, time.Time{}, ErrShareNotFound
+	}
+
+	shared := *entry
+	shared.ClientID = ""
+	return &shared, link.expiresAt, nil
+}
+
+// findLocked returns the newest history entry of a task, or nil; caller must hold h.mu
+func (h *taskHistory) findLocked(taskID string) *HistoryEntry {
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if h.entries[i].TaskID == taskID {
+			return h.entries[i]
+		}
+	}
+	return nil
+}
+
+// pruneSharesLocked drops expired share links; caller must hold h.mu
+func (h *taskHistory) pruneSharesLocked(now time.Time) {
+	for token, link := range h.shares {
+		if !now.Before(link.expiresAt) {
+			delete(h.shares, token)
+		}
+	}
+}
+
+// newShareToken returns a random URL-safe token
+func newShareToken() (string, error) {
+	b := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate share token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,95 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// finishTask submits a ping task for clientID and completes it
+func finishTask(t *testing.T, s *Scheduler, sender *fakeSender, taskID, clientID string) {
+	t.Helper()
+	rec := newOutputRecorder()
+	if err := s.SubmitTask(t.Context(), pingTask(taskID, "agent-1", "1.1.1.1"), clientID, rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	sender.waitSent(t)
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: taskID, OutputLine: "reply"})
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: taskID, Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+	rec.wait(t)
+}
+
+func TestCreateShareLink(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	s.SetHistoryRetention(time.Hour)
+	s.SetShareLinkTTL(time.Minute)
+	finishTask(t, s, sender, "t1", "client-1")
+
+	token, expiresAt, err := s.CreateShareLink("t1", "client-1")
+	if err != nil {
+		t.Fatalf("CreateShareLink() error = %v", err)
+	}
+	if until := time.Until(expiresAt); until <= 0 || until > time.Minute {
+		t.Errorf("expires in %v, want within the share TTL", until)
+	}
+
+	entry, _, err := s.SharedResult(token)
+	if err != nil {
+		t.Fatalf("SharedResult() error = %v", err)
+	}
+	if entry.TaskID != "t1" || entry.ClientID != "" {
+		t.Errorf("shared entry = %+v, want t1 without the client ID", entry)
+	}
+	if _, _, err := s.SharedResult("unknown"); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("unknown token: error = %v, want ErrShareNotFound", err)
+	}
+}
+
+func TestCreateShareLinkChecksClient(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	s.SetHistoryRetention(time.Hour)
+	s.SetShareLinkTTL(time.Minute)
+	finishTask(t, s, sender, "t1", "client-1")
+
+	if _, _, err := s.CreateShareLink("t1", "client-2"); !errors.Is(err, ErrShareForbidden) {
+		t.Errorf("other client: error = %v, want ErrShareForbidden", err)
+	}
+	// An empty client ID is an admin caller that already authorized the request
+	if _, _, err := s.CreateShareLink("t1", ""); err != nil {
+		t.Errorf("admin: error = %v", err)
+	}
+	if _, _, err := s.CreateShareLink("missing", ""); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("unknown task: error = %v, want ErrShareNotFound", err)
+	}
+}
+
+func TestCreateShareLinkDisabled(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	s.SetHistoryRetention(time.Hour)
+	finishTask(t, s, sender, "t1", "client-1")
+
+	if _, _, err := s.CreateShareLink("t1", "client-1"); !errors.Is(err, ErrShareDisabled) {
+		t.Errorf("error = %v, want ErrShareDisabled", err)
+	}
+}
+
+func TestShareLinkExpires(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	s.SetHistoryRetention(time.Hour)
+	s.SetShareLinkTTL(time.Minute)
+	finishTask(t, s, sender, "t1", "client-1")
+
+	token, _, err := s.CreateShareLink("t1", "client-1")
+	if err != nil {
+		t.Fatalf("CreateShareLink() error = %v", err)
+	}
+	s.history.mu.Lock()
+	s.history.shares[token].expiresAt = time.Now().Add(-time.Second)
+	s.history.mu.Unlock()
+
+	if _, _, err := s.SharedResult(token); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("expired link: error = %v, want ErrShareNotFound", err)
+	}
+}
@@ -0,0 +1,95 @@
+package task
+
+import (
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// sinkQueueSize bounds the outputs waiting to be published; a slow sink drops beyond it
+const sinkQueueSize = 4096
+
+// OutputSink receives a copy of every task output forwarded to clients (e.g. a message bus)
+type OutputSink interface {
+	Publish(taskID string, output *pb.TaskOutput) error
+}
+
+// sinkPublisher feeds a sink from its own goroutine so publishing never blocks task output
+type sinkPublisher struct {
+	sink  OutputSink
+	queue chan *pb.TaskOutput
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// SetOutputSink sets an optional sink that receives task outputs alongside the client
+// A nil sink disables publishing; call StopOutputSink before closing the sink
+func (s *Scheduler) SetOutputSink(sink OutputSink) {
+	if sink == nil {
+		s.sink = nil
+		return
+	}
+	p := &sinkPublisher{
+		sink:  sink,
+		queue: make(chan *pb.TaskOutput, sinkQueueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go p.run()
+	s.sink = p
+}
+
+// StopOutputSink publishes the outputs still queued and stops the sink goroutine
+func (s *Scheduler) StopOutputSink() {
+	if s.sink == nil {
+		return
+	}
+	close(s.sink.stop)
+	<-s.sink.done
+}
+
+// publishOutput queues a copy of an output for the sink, if configured
+// A full queue drops the copy: sink failures never affect delivery to the client
+func (s *Scheduler) publishOutput(output *pb.TaskOutput) {
+	if s.sink == nil {
+		return
+	}
+	select {
+	case s.sink.queue <- proto.Clone(output).(*pb.TaskOutput):
+	default:
+		logger.Warn("Output sink queue full, dropping task output",
+			zap.String("task_id", output.TaskId),
+		)
+	}
+}
+
+// run publishes queued outputs until stopped, then drains what is left
+func (p *sinkPublisher) run() {
+	defer close(p.done)
+	for {
+		select {
+		case output := <-p.queue:
+			p.publish(output)
+		case <-p.stop:
+			for {
+				select {
+				case output := <-p.queue:
+					p.publish(output)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// publish sends one output to the sink, logging failures
+func (p *sinkPublisher) publish(output *pb.TaskOutput) {
+	if err := p.sink.Publish(output.TaskId, output); err != nil {
+		logger.Warn("Failed to publish task output to sink",
+			zap.String("task_id", output.TaskId),
+			zap.Error(err),
+		)
+	}
+}
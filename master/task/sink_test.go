@@ -0,0 +1,81 @@
+package task
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// fakeSink records published outputs; block holds Publish until closed
+type fakeSink struct {
+	mu        sync.Mutex
+	published []*pb.TaskOutput
+	block     chan struct{}
+	err       error
+}
+
+func (f *fakeSink) Publish(taskID string, output *pb.TaskOutput) error {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if taskID != output.TaskId {
+		return errors.New("task ID mismatch")
+	}
+	f.published = append(f.published, output)
+	return f.err
+}
+
+func (f *fakeSink) outputs() []*pb.TaskOutput {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*pb.TaskOutput(nil), f.published...)
+}
+
+func TestOutputSinkReceivesEveryOutput(t *testing.T) {
+	s, _, sender := newTestScheduler(t, "agent-1")
+	fs := &fakeSink{}
+	s.SetOutputSink(fs)
+
+	rec := newOutputRecorder()
+	if err := s.SubmitTask(t.Context(), pingTask("t1", "agent-1", "1.1.1.1"), "client-1", rec.handle); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	sender.waitSent(t)
+	for _, line := range []string{"line 1", "line 2", "line 3"} {
+		s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", OutputLine: line, Status: pb.TaskStatus_TASK_STATUS_RUNNING})
+	}
+	s.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+	delivered := rec.wait(t)
+	s.StopOutputSink()
+
+	published := fs.outputs()
+	if len(published) != len(delivered) {
+		t.Fatalf("published %d outputs, client got %d", len(published), len(delivered))
+	}
+	for i, out := range delivered {
+		if published[i].OutputLine != out.OutputLine || published[i].Status != out.Status {
+			t.Errorf("output %d: published %v, delivered %v", i, published[i], out)
+		}
+	}
+}
+
+func TestOutputSinkDoesNotBlockDelivery(t *testing.T) {
+	s, _, _ := newTestScheduler(t)
+	fs := &fakeSink{block: make(chan struct{}), err: errors.New("bus down")}
+	s.SetOutputSink(fs)
+
+	// A stuck sink must not hold up publishing, even past the queue size
+	for i := 0; i < sinkQueueSize+10; i++ {
+		s.publishOutput(&pb.TaskOutput{TaskId: "t1", OutputLine: "x"})
+	}
+
+	close(fs.block)
+	s.StopOutputSink()
+	if got := len(fs.outputs()); got == 0 || got > sinkQueueSize+1 {
+		t.Errorf("published %d outputs, want between 1 and %d", got, sinkQueueSize+1)
+	}
+}
@@ -0,0 +1,104 @@
+package task
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/google/uuid"
+	pb "github.com/lureiny/lookingglass/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Template is a named, pre-filled task users can run without choosing parameters
+type Template struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	TaskName    string            `json:"task_name"`
+	Target      string            `json:"target"`
+	Count       int32             `json:"count,omitempty"`
+	Timeout     int32             `json:"timeout,omitempty"` // seconds
+	IPv6        bool              `json:"ipv6,omitempty"`
+	Params      map[string]string `json:"params,omitempty"` // Task extra_options
+}
+
+// TemplateLibrary holds the configured task templates in configuration order
+type TemplateLibrary struct {
+	templates []*Template
+	byName    map[string]*Template
+}
+
+// NewTemplateLibrary creates a library from templates; names must be unique
+func NewTemplateLibrary(templates []*Template) (*TemplateLibrary, error) {
+	lib := &TemplateLibrary{byName: make(map[string]*Template, len(templates))}
+	for _, t := range templates {
+		if t.Name == "" || t.TaskName == "" {
+			return nil, fmt.Errorf("template name and task_name are required")
+		}
+		if _, exists := lib.byName[t.Name]; exists {
+			return nil, fmt.Errorf("duplicate template name: %s", t.Name)
+		}
+		lib.byName[t.Name] = t
+		lib.templates = append(lib.templates, t)
+	}
+	return lib, nil
+}
+
+// List returns every template in configuration order
+func (l *TemplateLibrary) List() []*Template {
+	if l == nil {
+		return []*Template{}
+	}
+	return append([]*Template{}, l.templates...)
+}
+
+// Proto returns the templates as protobuf messages
+func (l *TemplateLibrary) Proto() []*pb.TaskTemplate {
+	templates := l.List()
+	result := make([]*pb.TaskTemplate, 0, len(templates))
+	for _, t := range templates {
+		result = append(result, &pb.TaskTemplate{
+			Name:        t.Name,
+			Description: t.Description,
+			TaskName:    t.TaskName,
+			Target:      t.Target,
+			Count:       t.Count,
+			Timeout:     t.Timeout,
+			Ipv6:        t.IPv6,
+			Params:      maps.Clone(t.Params),
+		})
+	}
+	return result
+}
+
+// Expand builds a task from the named template
+// Only the task ID and agent ID are taken from the request; an empty task ID gets a new one
+func (l *TemplateLibrary) Expand(name, taskID, agentID string) (*pb.Task, error) {
+	var t *Template
+	if l != nil {
+		t = l.byName[name]
+	}
+	if t == nil {
+		return nil, fmt.Errorf("unknown template: %s", name)
+	}
+
+	if taskID == "" {
+		taskID = uuid.New().String()
+	}
+
+	return &pb.Task{
+		TaskId:    taskID,
+		AgentId:   agentID,
+		TaskName:  t.TaskName,
+		CreatedAt: timestamppb.Now(),
+		Timeout:   t.Timeout,
+		Params: &pb.Task_NetworkTest{
+			NetworkTest: &pb.NetworkTestParams{
+				Target:       t.Target,
+				Count:        t.Count,
+				Timeout:      t.Timeout,
+				Ipv6:         t.IPv6,
+				ExtraOptions: maps.Clone(t.Params),
+			},
+		},
+	}, nil
+}
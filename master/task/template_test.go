@@ -0,0 +1,57 @@
+package task
+
+import "testing"
+
+func TestTemplateLibrary(t *testing.T) {
+	if _, err := NewTemplateLibrary([]*Template{{Name: "a", TaskName: "ping"}, {Name: "a", TaskName: "mtr"}}); err == nil {
+		t.Error("duplicate names accepted")
+	}
+	if _, err := NewTemplateLibrary([]*Template{{Name: "a"}}); err == nil {
+		t.Error("template without task_name accepted")
+	}
+
+	lib, err := NewTemplateLibrary([]*Template{
+		{Name: "cf-dns", TaskName: "ping", Target: "1.1.1.1", Count: 10, Timeout: 30, Params: map[string]string{"interval": "0.2"}},
+		{Name: "google-trace", TaskName: "mtr", Target: "8.8.8.8"},
+	})
+	if err != nil {
+		t.Fatalf("NewTemplateLibrary() error = %v", err)
+	}
+	if list := lib.List(); len(list) != 2 || list[0].Name != "cf-dns" || list[1].Name != "google-trace" {
+		t.Errorf("List() = %v, want configuration order", list)
+	}
+	if got := lib.Proto(); len(got) != 2 || got[0].Count != 10 || got[0].Params["interval"] != "0.2" {
+		t.Errorf("Proto() = %v", got)
+	}
+
+	task, err := lib.Expand("cf-dns", "t1", "agent-1")
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	params := task.GetNetworkTest()
+	if task.TaskId != "t1" || task.AgentId != "agent-1" || task.TaskName != "ping" || task.Timeout != 30 {
+		t.Errorf("task = %v", task)
+	}
+	if params.Target != "1.1.1.1" || params.Count != 10 || params.ExtraOptions["interval"] != "0.2" {
+		t.Errorf("params = %v", params)
+	}
+
+	// Expanded tasks do not share the template's params
+	params.ExtraOptions["interval"] = "1"
+	if lib.List()[0].Params["interval"] != "0.2" {
+		t.Error("editing an expanded task changed the template")
+	}
+	if generated, _ := lib.Expand("google-trace", "", "agent-1"); generated.TaskId == "" {
+		t.Error("empty task ID not generated")
+	}
+	if _, err := lib.Expand("missing", "t2", "agent-1"); err == nil {
+		t.Error("unknown template expanded")
+	}
+	var none *TemplateLibrary
+	if _, err := none.Expand("cf-dns", "t3", "agent-1"); err == nil {
+		t.Error("nil library expanded a template")
+	}
+	if list := none.List(); list == nil || len(list) != 0 {
+		t.Errorf("nil library List() = %v, want empty", list)
+	}
+}
@@ -2,15 +2,23 @@ package ws
 
 import (
 	"context"
-	"strings"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/lureiny/lookingglass/master/task"
 	pb "github.com/lureiny/lookingglass/pb"
 	"github.com/lureiny/lookingglass/pkg/logger"
+	"github.com/lureiny/lookingglass/pkg/netutil"
+	"github.com/lureiny/lookingglass/pkg/params"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
@@ -18,24 +26,54 @@ const (
 	pongWait       = 60 * time.Second
 	pingPeriod     = (pongWait * 9) / 10
 	maxMessageSize = 512 * 1024
+
+	// replaySendTimeout bounds how long replayed task output waits for room in a full send queue
+	replaySendTimeout = 5 * time.Second
 )
 
 // Client represents a WebSocket client connection
 type Client struct {
-	ID     string
-	conn   *websocket.Conn
-	server *Server
-	send   chan interface{}
+	ID         string
+	RemoteAddr string // Client IP (from forwarding headers only via a trusted proxy), used for audit logging
+	conn       *websocket.Conn
+	server     *Server
+	send       chan interface{}
+	done       chan struct{} // Closed once the connection is gone
+	closeOnce  sync.Once
+	lagging    atomic.Bool // Task output found the queue full; drop instead of waiting until the queue has room
+
+	outputFormat pb.OutputFormat // Preferred output format, from the "format" query parameter at connect
+
+	idleTimeout time.Duration // Close the connection after this long without requests (0 = never)
+	lastActive  atomic.Int64  // Unix nanoseconds of the last request (or of the last task finishing)
+	activeTasks atomic.Int32  // Submitted tasks (or fan-out groups) that have not finished
 }
 
 // NewClient creates a new WebSocket client
 func NewClient(conn *websocket.Conn, server *Server) *Client {
-	return &Client{
-		ID:     uuid.New().String(),
-		conn:   conn,
-		server: server,
-		send:   make(chan interface{}, 256),
+	c := &Client{
+		ID:          uuid.New().String(),
+		conn:        conn,
+		server:      server,
+		send:        make(chan interface{}, 256),
+		done:        make(chan struct{}),
+		idleTimeout: server.idleTimeout,
+	}
+	c.touch()
+	return c
+}
+
+// touch records client activity for the idle reaper
+func (c *Client) touch() {
+	c.lastActive.Store(time.Now().UnixNano())
+}
+
+// isIdle reports whether the client has sent nothing for idleTimeout and is not watching a task
+func (c *Client) isIdle(now time.Time) bool {
+	if c.idleTimeout <= 0 || c.activeTasks.Load() > 0 {
+		return false
 	}
+	return now.Sub(time.Unix(0, c.lastActive.Load())) >= c.idleTimeout
 }
 
 // Note: RequestMessage and ResponseMessage are now defined in protobuf
@@ -46,8 +84,10 @@ func (c *Client) ReadMessages() {
 	defer func() {
 		c.server.UnregisterClient(c.ID)
 		c.conn.Close()
+		c.closeOnce.Do(func() { close(c.done) })
 	}()
 
+	c.conn.SetReadLimit(maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -70,6 +110,15 @@ func (c *Client) ReadMessages() {
 // WriteMessages writes messages to the WebSocket connection
 func (c *Client) WriteMessages() {
 	ticker := time.NewTicker(pingPeriod)
+
+	// Pongs keep the read deadline alive but do not count as activity for the idle reaper
+	var idleCheck <-chan time.Time
+	if c.idleTimeout > 0 {
+		idleTicker := time.NewTicker(c.idleTimeout / 4)
+		defer idleTicker.Stop()
+		idleCheck = idleTicker.C
+	}
+
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
@@ -77,6 +126,19 @@ func (c *Client) WriteMessages() {
 
 	for {
 		select {
+		case now := <-idleCheck:
+			if !c.isIdle(now) {
+				continue
+			}
+			logger.Info("Closing idle WebSocket client",
+				zap.String("client_id", c.ID),
+				zap.Duration("idle_timeout", c.idleTimeout),
+			)
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, "idle timeout"))
+			return
+
 		case message, ok := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
@@ -121,8 +183,58 @@ func (c *Client) Send(message interface{}) error {
 	}
 }
 
+// sendOutput queues live task output without waiting. It runs on the agent stream's output
+// fan-out, where waiting for one slow client would hold up every other client of the agent and
+// its heartbeats, so a full queue marks the client lagging and drops the output.
+func (c *Client) sendOutput(message interface{}) error {
+	select {
+	case c.send <- message:
+		c.lagging.Store(false)
+		return nil
+	default:
+	}
+	if !c.lagging.Swap(true) {
+		logger.Warn("WebSocket client not keeping up, dropping task output",
+			zap.String("client_id", c.ID),
+		)
+	}
+	return websocket.ErrCloseSent
+}
+
+// sendReplay queues replayed (cached) task output, waiting up to replaySendTimeout for room in a
+// full queue so a replay burst slows its own goroutine instead of being dropped. A lagging client
+// is not waited for.
+func (c *Client) sendReplay(message interface{}) error {
+	select {
+	case c.send <- message:
+		c.lagging.Store(false)
+		return nil
+	default:
+	}
+	if c.lagging.Load() {
+		return websocket.ErrCloseSent
+	}
+
+	timer := time.NewTimer(replaySendTimeout)
+	defer timer.Stop()
+	select {
+	case c.send <- message:
+		return nil
+	case <-c.done:
+		return websocket.ErrCloseSent
+	case <-timer.C:
+		c.lagging.Store(true)
+		logger.Warn("WebSocket client not keeping up, dropping replayed task output",
+			zap.String("client_id", c.ID),
+		)
+		return websocket.ErrCloseSent
+	}
+}
+
 // handleMessage handles an incoming message from the client
 func (c *Client) handleMessage(data []byte) {
+	c.touch()
+
 	var req pb.WSRequest
 	if err := proto.Unmarshal(data, &req); err != nil {
 		logger.Error("Failed to parse message", zap.Error(err))
@@ -145,6 +257,15 @@ func (c *Client) handleMessage(data []byte) {
 		c.handleCancel(&req)
 	case pb.WSRequest_ACTION_LIST_AGENTS:
 		c.handleListAgents(&req)
+	case pb.WSRequest_ACTION_CANCEL_ALL:
+		c.handleCancelAll(&req)
+	case pb.WSRequest_ACTION_LIST_TEMPLATES:
+		c.handleListTemplates(&req)
+	case pb.WSRequest_ACTION_AGENT_LOGS:
+		// Waits for the agent's reply; keep reading other requests meanwhile
+		go c.handleAgentLogs(&req)
+	case pb.WSRequest_ACTION_SHARE:
+		c.handleShare(&req)
 	default:
 		c.Send(&pb.WSResponse{
 			Type:    pb.WSResponse_TYPE_ERROR,
@@ -155,8 +276,33 @@ func (c *Client) handleMessage(data []byte) {
 
 // handleExecute handles task execution requests
 func (c *Client) handleExecute(req *pb.WSRequest) {
-	task := req.Task
-	if task == nil {
+	// Client address travels with the task for audit logging
+	ctx := task.WithClientAddr(context.Background(), c.RemoteAddr)
+	if req.Delivery == pb.BroadcastDelivery_BROADCAST_DELIVERY_GROUPED {
+		ctx = task.WithGroupedDelivery(ctx)
+	}
+	// Cached results are replayed only after the acknowledgment below
+	acked := make(chan struct{})
+	defer close(acked)
+	ctx = task.WithSubmitAck(ctx, acked)
+
+	// A template expands into a normal task; the request only picks task and agent IDs (and compression)
+	if req.Template != "" {
+		expanded, err := c.server.templates.Expand(req.Template, req.Task.GetTaskId(), req.Task.GetAgentId())
+		if err != nil {
+			c.Send(&pb.WSResponse{
+				Type:    pb.WSResponse_TYPE_ERROR,
+				TaskId:  req.Task.GetTaskId(),
+				Message: err.Error(),
+			})
+			return
+		}
+		expanded.CompressOutput = req.Task.GetCompressOutput()
+		req.Task = expanded
+	}
+
+	t := req.Task
+	if t == nil {
 		c.Send(&pb.WSResponse{
 			Type:    pb.WSResponse_TYPE_ERROR,
 			Message: "task is required",
@@ -165,7 +311,7 @@ func (c *Client) handleExecute(req *pb.WSRequest) {
 	}
 
 	// Validate task_name is provided (basic request validation, not business logic)
-	if task.TaskName == "" {
+	if t.TaskName == "" {
 		c.Send(&pb.WSResponse{
 			Type:    pb.WSResponse_TYPE_ERROR,
 			Message: "task_name is required",
@@ -173,49 +319,162 @@ func (c *Client) handleExecute(req *pb.WSRequest) {
 		return
 	}
 
+	// A region lets the master pick the agent with the most free capacity
+	if req.Region != "" && len(req.AgentIds) == 0 {
+		selected, err := c.server.agentManager.SelectAgentForTask(t.TaskName, req.Region)
+		if err != nil {
+			c.Send(&pb.WSResponse{
+				Type:    pb.WSResponse_TYPE_ERROR,
+				TaskId:  t.TaskId,
+				Message: err.Error(),
+			})
+			return
+		}
+		t.AgentId = selected.Info.Id
+	}
+
+	// Reject oversized requests before they reach the scheduler
+	if err := c.server.inputLimits.validateTaskInput(t); err != nil {
+		logger.Warn("Rejected oversized task request",
+			zap.String("client_id", c.ID),
+			zap.Error(err),
+		)
+		c.Send(&pb.WSResponse{
+			Type:    pb.WSResponse_TYPE_ERROR,
+			TaskId:  t.TaskId,
+			Message: "invalid task: " + err.Error(),
+		})
+		return
+	}
+
+	// Check parameters against the schema each target agent declares for the task
+	if err := c.server.validateTaskParams(t, req.AgentIds); err != nil {
+		c.Send(&pb.WSResponse{
+			Type:    pb.WSResponse_TYPE_ERROR,
+			TaskId:  t.TaskId,
+			Message: "invalid task: " + err.Error(),
+		})
+		return
+	}
+
+	// The request may override the connection's output format for this task
+	format := c.outputFormat
+	if req.OutputFormat != pb.OutputFormat_OUTPUT_FORMAT_UNSPECIFIED {
+		format = req.OutputFormat
+	}
+	formatter := newOutputFormatter(format, t)
+
+	// The client counts as active until its task (or whole fan-out group) finishes
+	c.activeTasks.Add(1)
+	finished := sync.OnceFunc(func() {
+		c.touch()
+		c.activeTasks.Add(-1)
+	})
+
 	// Output handler
 	outputHandler := func(output *pb.TaskOutput) {
 		// Check task status to determine response type
 		var respType pb.WSResponse_Type
 
-		switch output.Status {
-		case pb.TaskStatus_TASK_STATUS_COMPLETED:
+		switch {
+		case output.GroupId != "" && output.TaskId == output.GroupId:
+			// Final output of a fan-out group, sent once every child task has finished
+			respType = pb.WSResponse_TYPE_GROUP_COMPLETE
+		case output.Status == pb.TaskStatus_TASK_STATUS_COMPLETED:
 			respType = pb.WSResponse_TYPE_COMPLETE
-		case pb.TaskStatus_TASK_STATUS_FAILED:
+		case output.Status == pb.TaskStatus_TASK_STATUS_FAILED:
 			respType = pb.WSResponse_TYPE_ERROR
-		case pb.TaskStatus_TASK_STATUS_CANCELLED:
+		case output.Status == pb.TaskStatus_TASK_STATUS_CANCELLED:
 			respType = pb.WSResponse_TYPE_COMPLETE
 		default:
 			// RUNNING or PENDING status - regular output
 			respType = pb.WSResponse_TYPE_OUTPUT
 		}
 
-		c.Send(&pb.WSResponse{
-			Type:    respType,
-			TaskId:  output.TaskId,
-			Output:  output.OutputLine,
-			Message: output.ErrorMessage,
-		})
+		resp := &pb.WSResponse{
+			Type:     respType,
+			TaskId:   output.TaskId,
+			Output:   output.OutputLine,
+			Message:  output.ErrorMessage,
+			Summary:  output.Summary,
+			Sequence: output.Sequence,
+			AgentId:  output.AgentId,
+			GroupId:  output.GroupId,
+			Cached:   output.Cached,
+			Status:   output.Status,
+		}
+		for _, r := range formatter.apply(resp) {
+			if output.Cached {
+				c.sendReplay(r)
+			} else {
+				c.sendOutput(r)
+			}
+		}
+
+		final := respType == pb.WSResponse_TYPE_COMPLETE || respType == pb.WSResponse_TYPE_ERROR
+		if respType == pb.WSResponse_TYPE_GROUP_COMPLETE || (output.GroupId == "" && final) {
+			finished()
+		}
 	}
 
-	// Submit task
-	ctx := context.Background()
-	err := c.server.scheduler.SubmitTask(ctx, task, c.ID, outputHandler)
+	// Submit task, fanning out to several agents if requested
+	var err error
+	if len(req.AgentIds) > 0 {
+		err = c.server.scheduler.SubmitGroup(ctx, t, req.AgentIds, c.ID, outputHandler)
+	} else {
+		err = c.server.scheduler.SubmitTask(ctx, t, c.ID, outputHandler)
+	}
 	if err != nil {
+		finished()
 		logger.Error("Failed to submit task", zap.Error(err))
 		c.Send(&pb.WSResponse{
 			Type:    pb.WSResponse_TYPE_ERROR,
-			TaskId:  task.TaskId,
+			TaskId:  t.TaskId,
 			Message: "submit task fail: " + err.Error(),
 		})
 		return
 	}
 
-	// Send acknowledgment
-	c.Send(&pb.WSResponse{
+	// Send acknowledgment, naming the agent when the master picked it
+	ack := &pb.WSResponse{
 		Type:   pb.WSResponse_TYPE_TASK_STARTED,
-		TaskId: task.TaskId,
-	})
+		TaskId: t.TaskId,
+	}
+	if req.Region != "" && len(req.AgentIds) == 0 {
+		ack.AgentId = t.AgentId
+	}
+	c.Send(ack)
+}
+
+// validateTaskParams validates task parameters against the param schema of every agent
+// the task is for (agentIDs for a fan-out, otherwise task.AgentId)
+// Unknown agents are left to the scheduler to report
+func (s *Server) validateTaskParams(t *pb.Task, agentIDs []string) error {
+	if len(agentIDs) == 0 {
+		agentIDs = []string{t.AgentId}
+	}
+	all := len(agentIDs) == 1 && agentIDs[0] == task.AllAgents
+
+	for _, ag := range s.agentManager.GetAllAgents() {
+		if all && ag.Status != pb.AgentStatus_AGENT_STATUS_ONLINE {
+			continue
+		}
+		if !all && !slices.Contains(agentIDs, ag.Info.Id) {
+			continue
+		}
+		for _, info := range ag.Info.TaskDisplayInfo {
+			if info.TaskName != t.TaskName {
+				continue
+			}
+			if err := params.Validate(info.Params, t.GetNetworkTest()); err != nil {
+				if len(agentIDs) > 1 || all {
+					return fmt.Errorf("%s: %w", ag.Info.Id, err)
+				}
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // handleCancel handles task cancellation requests
@@ -246,42 +505,130 @@ func (c *Client) handleCancel(req *pb.WSRequest) {
 	})
 }
 
-// maskIP masks IP addresses for privacy
-// IPv4: 127.0.0.1 -> 127.0.*.*
-// IPv6: 2001:0db8:85a3:0000:0000:8a2e:0370:7334 -> 2001:0db8:85a3:****:****:****:****:****
-func maskIP(ip string, shouldMask bool) string {
-	if !shouldMask || ip == "" {
-		return ip
+// handleCancelAll handles admin requests to cancel every running task
+func (c *Client) handleCancelAll(req *pb.WSRequest) {
+	if err := c.server.checkAdminToken(req.ConfirmToken); err != nil {
+		logger.Warn("Rejected cancel-all request",
+			zap.String("client_id", c.ID),
+			zap.Error(err),
+		)
+		c.Send(&pb.WSResponse{
+			Type:    pb.WSResponse_TYPE_ERROR,
+			Message: err.Error(),
+		})
+		return
 	}
 
-	// Check if it's IPv6 (contains colons)
-	if strings.Contains(ip, ":") {
-		return maskIPv6Address(ip)
+	logger.Warn("Cancel-all requested",
+		zap.String("client_id", c.ID),
+	)
+
+	count := c.server.scheduler.CancelAllTasks()
+
+	c.Send(&pb.WSResponse{
+		Type:    pb.WSResponse_TYPE_COMPLETE,
+		Message: fmt.Sprintf("Cancelled %d tasks", count),
+	})
+}
+
+// handleAgentLogs handles admin requests for an agent's recent log lines
+func (c *Client) handleAgentLogs(req *pb.WSRequest) {
+	if err := c.server.checkAdminToken(req.ConfirmToken); err != nil {
+		logger.Warn("Rejected agent logs request",
+			zap.String("client_id", c.ID),
+			zap.Error(err),
+		)
+		c.Send(&pb.WSResponse{
+			Type:    pb.WSResponse_TYPE_ERROR,
+			Message: err.Error(),
+		})
+		return
+	}
+	if req.AgentId == "" {
+		c.Send(&pb.WSResponse{
+			Type:    pb.WSResponse_TYPE_ERROR,
+			Message: "agent_id is required",
+		})
+		return
+	}
+	if c.server.agentLogs == nil {
+		c.Send(&pb.WSResponse{
+			Type:    pb.WSResponse_TYPE_ERROR,
+			AgentId: req.AgentId,
+			Message: "agent logs not available",
+		})
+		return
 	}
 
-	// IPv4 handling
-	parts := strings.Split(ip, ".")
-	if len(parts) != 4 {
-		// Not a valid IPv4, return as-is
-		return ip
+	logger.Info("Agent logs requested",
+		zap.String("client_id", c.ID),
+		zap.String("agent_id", req.AgentId),
+		zap.Int32("lines", req.Lines),
+	)
+
+	resp, err := c.server.agentLogs.FetchAgentLogs(context.Background(), req.AgentId, int(req.Lines))
+	if err == nil && resp.Error != "" {
+		err = errors.New(resp.Error)
+	}
+	if err != nil {
+		c.Send(&pb.WSResponse{
+			Type:    pb.WSResponse_TYPE_ERROR,
+			AgentId: req.AgentId,
+			Message: "failed to fetch agent logs: " + err.Error(),
+		})
+		return
 	}
 
-	// Mask last 2 octets
-	return parts[0] + "." + parts[1] + ".*.*"
+	c.Send(&pb.WSResponse{
+		Type:     pb.WSResponse_TYPE_AGENT_LOGS,
+		AgentId:  req.AgentId,
+		LogLines: resp.Lines,
+	})
+}
+
+// handleShare creates a share link to a finished task submitted over this connection
+func (c *Client) handleShare(req *pb.WSRequest) {
+	if req.TaskId == "" {
+		c.Send(&pb.WSResponse{
+			Type:    pb.WSResponse_TYPE_ERROR,
+			Message: "task_id is required",
+		})
+		return
+	}
+
+	token, expiresAt, err := c.server.scheduler.CreateShareLink(req.TaskId, c.ID)
+	if err != nil {
+		c.Send(&pb.WSResponse{
+			Type:    pb.WSResponse_TYPE_ERROR,
+			TaskId:  req.TaskId,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.Send(&pb.WSResponse{
+		Type:      pb.WSResponse_TYPE_SHARE_LINK,
+		TaskId:    req.TaskId,
+		Message:   "/share/" + token,
+		ExpiresAt: timestamppb.New(expiresAt),
+	})
 }
 
 // handleListAgents handles agent list requests
 func (c *Client) handleListAgents(req *pb.WSRequest) {
-	// Get all agents from agent manager
+	// Get all agents from agent manager, narrowed to the requested tags
 	agents := c.server.agentManager.GetAllAgents()
+	if len(req.Tags) > 0 {
+		agents = c.server.agentManager.GetAgentsByTag(req.Tags)
+	}
 
 	// Convert to AgentStatusInfo
 	agentInfos := make([]*pb.AgentStatusInfo, 0, len(agents))
 	for _, agent := range agents {
-		ipv4 := maskIP(agent.Info.Ipv4, agent.Info.HideIp)
-		ipv6 := maskIP(agent.Info.Ipv6, agent.Info.HideIp)
+		ipv4 := netutil.DisplayIP(agent.Info.Ipv4, agent.Info.HideIp)
+		ipv6 := netutil.DisplayIP(agent.Info.Ipv6, agent.Info.HideIp)
 
-		agentInfos = append(agentInfos, &pb.AgentStatusInfo{
+		agentInfos = append(agentInfos, c.server.agentManager.ApplyMetadataOverride(&pb.AgentStatusInfo{
 			Id:              agent.Info.Id,
 			Name:            agent.Info.Name,
 			Location:        agent.Info.Location,
@@ -294,7 +641,12 @@ func (c *Client) handleListAgents(req *pb.WSRequest) {
 			Provider:        agent.Info.Provider,
 			Idc:             agent.Info.Idc,
 			Description:     agent.Info.Description,
-		})
+			Tags:            agent.Info.Tags,
+			MemPercent:      agent.MemPercent,
+			DiskPercent:     agent.DiskPercent,
+			TaskConcurrency: agent.TaskUsage,
+			Degraded:        !agent.DegradedUntil.IsZero(),
+		}))
 	}
 
 	// Send agent list response
@@ -308,3 +660,11 @@ func (c *Client) handleListAgents(req *pb.WSRequest) {
 		zap.Int("agent_count", len(agentInfos)),
 	)
 }
+
+// handleListTemplates handles task template list requests
+func (c *Client) handleListTemplates(req *pb.WSRequest) {
+	c.Send(&pb.WSResponse{
+		Type:      pb.WSResponse_TYPE_TEMPLATE_LIST,
+		Templates: c.server.templates.Proto(),
+	})
+}
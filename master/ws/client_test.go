@@ -0,0 +1,263 @@
+package ws
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lureiny/lookingglass/master/task"
+	pb "github.com/lureiny/lookingglass/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSendReplayWaitsForRoom(t *testing.T) {
+	c := &Client{ID: "c1", send: make(chan interface{}, 1), done: make(chan struct{})}
+	c.send <- &pb.WSResponse{}
+
+	// The queue drains shortly: the replayed output waits instead of being dropped
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		<-c.send
+	}()
+	if err := c.sendReplay(&pb.WSResponse{Output: "line"}); err != nil {
+		t.Fatalf("sendReplay() error = %v", err)
+	}
+	if got := (<-c.send).(*pb.WSResponse); got.Output != "line" {
+		t.Errorf("queued %v, want the output", got)
+	}
+}
+
+func TestSendReplayStopsWhenClientGone(t *testing.T) {
+	c := &Client{ID: "c1", send: make(chan interface{}, 1), done: make(chan struct{})}
+	c.send <- &pb.WSResponse{}
+	close(c.done)
+
+	start := time.Now()
+	if err := c.sendReplay(&pb.WSResponse{}); err == nil {
+		t.Fatal("sendReplay() queued to a closed client")
+	}
+	if time.Since(start) > time.Second {
+		t.Error("sendReplay() waited for a closed client")
+	}
+}
+
+func TestSendOutputDropsOnFullQueue(t *testing.T) {
+	c := &Client{ID: "c1", send: make(chan interface{}, 1), done: make(chan struct{})}
+	c.send <- &pb.WSResponse{}
+
+	start := time.Now()
+	if err := c.sendOutput(&pb.WSResponse{}); err == nil {
+		t.Fatal("sendOutput() queued to a full queue")
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Error("sendOutput() waited for room")
+	}
+	if !c.lagging.Load() {
+		t.Error("client with a full queue not marked lagging")
+	}
+
+	// Room again: sending resumes and the client is no longer lagging
+	<-c.send
+	if err := c.sendOutput(&pb.WSResponse{}); err != nil {
+		t.Fatalf("sendOutput() error = %v", err)
+	}
+	if c.lagging.Load() {
+		t.Error("client still lagging after a successful send")
+	}
+}
+
+func TestStalledClientDoesNotBlockOthers(t *testing.T) {
+	s, am := newTestServer(t, &pb.AgentInfo{
+		Id:              "agent-1",
+		MaxConcurrent:   5,
+		TaskDisplayInfo: []*pb.TaskDisplayInfo{{TaskName: "ping", RequiresTarget: true}},
+	})
+	s.scheduler = task.NewScheduler(am, 10)
+	s.scheduler.SetStreamSender(&shareSender{sent: make(chan *pb.Task, 2)})
+
+	execute := func(c *Client, taskID string) {
+		c.handleExecute(&pb.WSRequest{Action: pb.WSRequest_ACTION_EXECUTE, Task: &pb.Task{
+			TaskId:   taskID,
+			AgentId:  "agent-1",
+			TaskName: "ping",
+			Params:   &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{Target: "192.0.2.1"}},
+		}})
+	}
+
+	// The stalled client never reads; its one-slot queue is already full
+	stalled := &Client{ID: "stalled", server: s, send: make(chan interface{}, 1), done: make(chan struct{})}
+	stalled.send <- &pb.WSResponse{}
+	execute(stalled, "t-stalled")
+
+	healthy := &Client{ID: "healthy", server: s, send: make(chan interface{}, 256), done: make(chan struct{})}
+	execute(healthy, "t-healthy")
+
+	// One agent stream delivers the output of both tasks, interleaved
+	const lines = 20
+	start := time.Now()
+	for i := range lines {
+		s.scheduler.HandleTaskOutput(&pb.TaskOutput{TaskId: "t-stalled", OutputLine: "stalled", Sequence: uint64(i + 1)})
+		s.scheduler.HandleTaskOutput(&pb.TaskOutput{TaskId: "t-healthy", OutputLine: "healthy", Sequence: uint64(i + 1)})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("delivering output took %s with a stalled client", elapsed)
+	}
+
+	received := 0
+	for len(healthy.send) > 0 {
+		if resp := (<-healthy.send).(*pb.WSResponse); resp.Output == "healthy" {
+			received++
+		}
+	}
+	if received != lines {
+		t.Errorf("healthy client received %d lines, want %d", received, lines)
+	}
+	if !stalled.lagging.Load() {
+		t.Error("stalled client not marked lagging")
+	}
+}
+
+func TestHandleCancelAllRequiresAdminToken(t *testing.T) {
+	s, am := newTestServer(t)
+	s.scheduler = task.NewScheduler(am, 10)
+
+	cancelAll := func(token string) *pb.WSResponse {
+		c := &Client{ID: "c1", server: s, send: make(chan interface{}, 1), done: make(chan struct{})}
+		c.handleCancelAll(&pb.WSRequest{Action: pb.WSRequest_ACTION_CANCEL_ALL, ConfirmToken: token})
+		return (<-c.send).(*pb.WSResponse)
+	}
+
+	// Admin actions disabled: nobody may cancel everything
+	if resp := cancelAll(""); resp.Type != pb.WSResponse_TYPE_ERROR {
+		t.Errorf("without admin token configured: response = %v, want an error", resp)
+	}
+
+	s.SetAdminToken("admin")
+	if resp := cancelAll("wrong"); resp.Type != pb.WSResponse_TYPE_ERROR {
+		t.Errorf("wrong token: response = %v, want an error", resp)
+	}
+	if resp := cancelAll("admin"); resp.Type != pb.WSResponse_TYPE_COMPLETE || resp.Message != "Cancelled 0 tasks" {
+		t.Errorf("admin token: response = %v, want the cancelled count", resp)
+	}
+}
+
+// dialIdleServer starts a WebSocket server whose clients are reaped after idleTimeout and connects to it
+func dialIdleServer(t *testing.T, idleTimeout time.Duration) *websocket.Conn {
+	t.Helper()
+	s, am := newTestServer(t)
+	s.scheduler = task.NewScheduler(am, 10)
+	s.SetIdleTimeout(idleTimeout)
+	srv := httptest.NewServer(http.HandlerFunc(s.HandleWebSocket))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readUntilClosed reads from conn in the background and reports the error that ended the connection
+func readUntilClosed(conn *websocket.Conn) <-chan error {
+	closed := make(chan error, 1)
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				closed <- err
+				return
+			}
+		}
+	}()
+	return closed
+}
+
+func TestIdleClientReaped(t *testing.T) {
+	conn := dialIdleServer(t, 100*time.Millisecond)
+	start := time.Now()
+
+	select {
+	case err := <-readUntilClosed(conn):
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Text != "idle timeout" {
+			t.Fatalf("connection ended with %v, want an idle timeout close frame", err)
+		}
+		if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+			t.Errorf("reaped after %s, before the idle timeout", elapsed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle client was not reaped")
+	}
+}
+
+func TestActiveClientNotReaped(t *testing.T) {
+	conn := dialIdleServer(t, 200*time.Millisecond)
+	closed := readUntilClosed(conn)
+
+	// Requests keep the client active for well past the timeout
+	request, _ := proto.Marshal(&pb.WSRequest{Action: pb.WSRequest_ACTION_LIST_AGENTS})
+	for range 10 {
+		if err := conn.WriteMessage(websocket.BinaryMessage, request); err != nil {
+			t.Fatalf("WriteMessage() error = %v", err)
+		}
+		select {
+		case err := <-closed:
+			t.Fatalf("active client closed: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestIsIdle(t *testing.T) {
+	now := time.Now()
+	c := &Client{idleTimeout: 50 * time.Millisecond}
+	c.lastActive.Store(now.Add(-time.Minute).UnixNano())
+	if !c.isIdle(now) {
+		t.Fatal("client without activity for a minute is not idle")
+	}
+
+	// A running task keeps the client even without requests
+	c.activeTasks.Add(1)
+	if c.isIdle(now) {
+		t.Error("client with a running task is idle")
+	}
+	c.activeTasks.Add(-1)
+
+	c.touch()
+	if c.isIdle(time.Now()) {
+		t.Error("client just touched is idle")
+	}
+
+	// No timeout: never idle
+	c.idleTimeout = 0
+	if c.isIdle(now.Add(time.Hour)) {
+		t.Error("client without an idle timeout is idle")
+	}
+}
+
+func TestHandleListAgentsFiltersByTag(t *testing.T) {
+	s, _ := newTestServer(t,
+		&pb.AgentInfo{Id: "tokyo", Tags: map[string]string{"continent": "asia"}},
+		&pb.AgentInfo{Id: "paris", Tags: map[string]string{"continent": "europe"}},
+	)
+	list := func(tags map[string]string) []*pb.AgentStatusInfo {
+		c := &Client{ID: "c1", server: s, send: make(chan interface{}, 1), done: make(chan struct{})}
+		c.handleListAgents(&pb.WSRequest{Action: pb.WSRequest_ACTION_LIST_AGENTS, Tags: tags})
+		return (<-c.send).(*pb.WSResponse).Agents
+	}
+
+	agents := list(map[string]string{"continent": "asia"})
+	if len(agents) != 1 || agents[0].Id != "tokyo" {
+		t.Fatalf("continent=asia = %v, want tokyo", agents)
+	}
+	if agents[0].Tags["continent"] != "asia" {
+		t.Errorf("tags = %v, want continent=asia", agents[0].Tags)
+	}
+	if agents := list(nil); len(agents) != 2 {
+		t.Errorf("no tag filter = %d agents, want 2", len(agents))
+	}
+}
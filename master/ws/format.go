@@ -0,0 +1,295 @@
+package ws
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	pb "github.com/lureiny/lookingglass/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// structuredMaxLines bounds the output lines buffered per task for a structured client
+const structuredMaxLines = 5000
+
+// outputParser builds a summary from the output lines of a task, or returns nil if it cannot
+type outputParser func(target string, lines []string) *pb.TaskSummary
+
+// outputParsers are the master-side parsers for structured clients, by task name
+var outputParsers = map[string]outputParser{
+	"ping":  parsePingOutput,
+	"mtr":   parseMTROutput,
+	"fping": parseFpingOutput,
+}
+
+// parseOutputFormat maps a format name ("raw", "structured") to its protobuf value
+func parseOutputFormat(name string) (pb.OutputFormat, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return pb.OutputFormat_OUTPUT_FORMAT_UNSPECIFIED, nil
+	case "raw":
+		return pb.OutputFormat_OUTPUT_FORMAT_RAW, nil
+	case "structured":
+		return pb.OutputFormat_OUTPUT_FORMAT_STRUCTURED, nil
+	default:
+		return 0, fmt.Errorf("unknown output format: %s", name)
+	}
+}
+
+// outputFormatter adapts the responses of one submitted task (or fan-out group) to an output format
+// Raw clients get output lines without summaries. Structured clients get summaries only: output
+// lines are buffered and parsed when each task finishes; if neither the agent nor the master
+// produced a summary, the buffered lines are sent instead so the result is not lost.
+type outputFormatter struct {
+	format   pb.OutputFormat
+	taskName string
+	target   string
+
+	mutex sync.Mutex
+	tasks map[string]*bufferedOutput // Task ID -> buffered output (fan-out children each have their own)
+}
+
+// bufferedOutput is the withheld output of a task for a structured client
+type bufferedOutput struct {
+	responses []*pb.WSResponse
+	traced    bool // The agent sent trace hops
+}
+
+// newOutputFormatter creates a formatter for the responses of t
+func newOutputFormatter(format pb.OutputFormat, t *pb.Task) *outputFormatter {
+	return &outputFormatter{
+		format:   format,
+		taskName: t.TaskName,
+		target:   t.GetNetworkTest().GetTarget(),
+		tasks:    make(map[string]*bufferedOutput),
+	}
+}
+
+// apply returns the responses to send in place of resp (possibly none)
+func (f *outputFormatter) apply(resp *pb.WSResponse) []*pb.WSResponse {
+	switch f.format {
+	case pb.OutputFormat_OUTPUT_FORMAT_RAW:
+		if resp.Summary == nil {
+			return []*pb.WSResponse{resp}
+		}
+		resp.Summary = nil
+		if resp.Type == pb.WSResponse_TYPE_OUTPUT && resp.Output == "" && resp.Message == "" {
+			return nil
+		}
+		return []*pb.WSResponse{resp}
+	case pb.OutputFormat_OUTPUT_FORMAT_STRUCTURED:
+		return f.applyStructured(resp)
+	default:
+		return []*pb.WSResponse{resp}
+	}
+}
+
+// applyStructured withholds output lines and attaches parsed summaries to final responses
+func (f *outputFormatter) applyStructured(resp *pb.WSResponse) []*pb.WSResponse {
+	if resp.Type == pb.WSResponse_TYPE_GROUP_COMPLETE {
+		return []*pb.WSResponse{resp}
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	buffered, ok := f.tasks[resp.TaskId]
+	if !ok {
+		buffered = &bufferedOutput{}
+		f.tasks[resp.TaskId] = buffered
+	}
+
+	if resp.Type == pb.WSResponse_TYPE_OUTPUT {
+		if resp.Output != "" && len(buffered.responses) < structuredMaxLines {
+			line := resp
+			if resp.Summary != nil || resp.Message != "" {
+				line = proto.Clone(resp).(*pb.WSResponse)
+				line.Summary, line.Message = nil, ""
+			}
+			buffered.responses = append(buffered.responses, line)
+		}
+		if resp.Summary == nil && resp.Message == "" {
+			return nil
+		}
+		if len(resp.Summary.GetTraceHops()) > 0 {
+			buffered.traced = true
+		}
+		summaryOnly := proto.Clone(resp).(*pb.WSResponse)
+		summaryOnly.Output = ""
+		return []*pb.WSResponse{summaryOnly}
+	}
+
+	if resp.Type != pb.WSResponse_TYPE_COMPLETE && resp.Type != pb.WSResponse_TYPE_ERROR {
+		return []*pb.WSResponse{resp}
+	}
+
+	// Final response of the task
+	delete(f.tasks, resp.TaskId)
+
+	var parsed *pb.TaskSummary
+	if parse := outputParsers[f.taskName]; parse != nil {
+		lines := make([]string, 0, len(buffered.responses))
+		for _, r := range buffered.responses {
+			lines = append(lines, r.Output)
+		}
+		parsed = parse(f.target, lines)
+	}
+
+	if parsed == nil {
+		if buffered.traced {
+			return []*pb.WSResponse{resp}
+		}
+		return append(buffered.responses, resp)
+	}
+
+	final := proto.Clone(resp).(*pb.WSResponse)
+	if final.Summary == nil {
+		final.Summary = parsed
+	} else {
+		proto.Merge(final.Summary, parsed)
+	}
+	return []*pb.WSResponse{final}
+}
+
+var (
+	pingLabelRe       = regexp.MustCompile(`^\[([^\]]+)\] (.*)$`) // Multi-target ping prefixes lines with the target
+	pingTransmittedRe = regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received`)
+	pingLossRe        = regexp.MustCompile(`([\d.]+)% packet loss`)
+	pingRTTRe         = regexp.MustCompile(`min/avg/max\S* = ([\d.]+)/([\d.]+)/([\d.]+)`)
+)
+
+// parsePingOutput parses the statistics section of ping output, one entry per target
+func parsePingOutput(target string, lines []string) *pb.TaskSummary {
+	var stats []*pb.PingStats
+	byTarget := make(map[string]*pb.PingStats)
+	statsFor := func(t string) *pb.PingStats {
+		s, ok := byTarget[t]
+		if !ok {
+			s = &pb.PingStats{Target: t}
+			byTarget[t] = s
+		}
+		return s
+	}
+
+	for _, line := range splitOutputLines(lines) {
+		lineTarget := target
+		if m := pingLabelRe.FindStringSubmatch(line); m != nil {
+			lineTarget, line = m[1], m[2]
+		}
+
+		if m := pingTransmittedRe.FindStringSubmatch(line); m != nil {
+			s := statsFor(lineTarget)
+			s.Transmitted = atoi32(m[1])
+			s.Received = atoi32(m[2])
+			if m := pingLossRe.FindStringSubmatch(line); m != nil {
+				s.LossPercent, _ = strconv.ParseFloat(m[1], 64)
+			}
+			stats = append(stats, s)
+			continue
+		}
+		if m := pingRTTRe.FindStringSubmatch(line); m != nil {
+			s := statsFor(lineTarget)
+			s.RttMinMs, _ = strconv.ParseFloat(m[1], 64)
+			s.RttAvgMs, _ = strconv.ParseFloat(m[2], 64)
+			s.RttMaxMs, _ = strconv.ParseFloat(m[3], 64)
+		}
+	}
+
+	if len(stats) == 0 {
+		return nil
+	}
+	return &pb.TaskSummary{PingStats: stats}
+}
+
+// fpingSummaryRe matches a summary line of the fping task: "1.1.1.1 : xmt/rcv/%loss = 5/4/20%"
+// followed by ", min/avg/max = 1.23/1.35/1.45" unless every probe was lost
+var fpingSummaryRe = regexp.MustCompile(`^(\S+)\s+:\s+xmt/rcv/%loss = (\d+)/(\d+)/([\d.]+)%(?:, min/avg/max = ([\d.]+)/([\d.]+)/([\d.]+))?`)
+
+// parseFpingOutput parses the per-target summary lines of fping output
+func parseFpingOutput(target string, lines []string) *pb.TaskSummary {
+	var stats []*pb.PingStats
+	for _, line := range splitOutputLines(lines) {
+		m := fpingSummaryRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+
+		s := &pb.PingStats{
+			Target:      m[1],
+			Transmitted: atoi32(m[2]),
+			Received:    atoi32(m[3]),
+		}
+		s.LossPercent, _ = strconv.ParseFloat(m[4], 64)
+		if m[5] != "" {
+			s.RttMinMs, _ = strconv.ParseFloat(m[5], 64)
+			s.RttAvgMs, _ = strconv.ParseFloat(m[6], 64)
+			s.RttMaxMs, _ = strconv.ParseFloat(m[7], 64)
+		}
+		stats = append(stats, s)
+	}
+
+	if len(stats) == 0 {
+		return nil
+	}
+	return &pb.TaskSummary{PingStats: stats}
+}
+
+// mtrHopRe matches a hop row of `mtr --report`: hop, host, Loss%, Snt, Last, Avg, Best, Wrst, StDev
+var mtrHopRe = regexp.MustCompile(`^\s*(\d+)\.\|--\s+(\S+)\s+([\d.]+)%?\s+(\d+)\s+([\d.]+)\s+([\d.]+)\s+`)
+
+// parseMTROutput parses the hop rows of an mtr report into trace hops
+func parseMTROutput(target string, lines []string) *pb.TaskSummary {
+	var hops []*pb.TraceHop
+	for _, line := range splitOutputLines(lines) {
+		m := mtrHopRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		loss, _ := strconv.ParseFloat(m[3], 64)
+		sent := atoi32(m[4])
+		hop := &pb.TraceHop{
+			Ttl:            atoi32(m[1]),
+			ProbesSent:     sent,
+			ProbesReceived: int32(math.Round(float64(sent) * (100 - loss) / 100)),
+		}
+		switch host := m[2]; {
+		case host == "???":
+		case net.ParseIP(host) != nil:
+			hop.Ip = host
+		default:
+			hop.Hostname = host
+		}
+		if hop.ProbesReceived > 0 {
+			hop.RttMs, _ = strconv.ParseFloat(m[6], 64)
+		}
+		hops = append(hops, hop)
+	}
+
+	if len(hops) == 0 {
+		return nil
+	}
+	return &pb.TaskSummary{TraceHops: hops}
+}
+
+// splitOutputLines splits outputs that carry several lines (coalesced, batched or from a
+// pseudo-terminal) into single lines for the parsers
+func splitOutputLines(outputs []string) []string {
+	lines := make([]string, 0, len(outputs))
+	for _, output := range outputs {
+		for _, line := range strings.Split(output, "\n") {
+			lines = append(lines, strings.TrimSuffix(line, "\r"))
+		}
+	}
+	return lines
+}
+
+// atoi32 converts a string of digits matched by a parser regexp
+func atoi32(s string) int32 {
+	n, _ := strconv.ParseInt(s, 10, 32)
+	return int32(n)
+}
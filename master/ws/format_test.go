@@ -0,0 +1,91 @@
+package ws
+
+import (
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestParsePingOutputSplitsBatchedLines(t *testing.T) {
+	// Statistics arrive in one coalesced output
+	lines := []string{
+		"64 bytes from 1.1.1.1: icmp_seq=1 ttl=57 time=1.10 ms",
+		"--- 1.1.1.1 ping statistics ---\n4 packets transmitted, 3 received, 25% packet loss, time 3004ms\nrtt min/avg/max/mdev = 1.100/1.200/1.300/0.080 ms\n",
+	}
+	summary := parsePingOutput("1.1.1.1", lines)
+	if summary == nil || len(summary.PingStats) != 1 {
+		t.Fatalf("summary = %v, want one ping stats entry", summary)
+	}
+	s := summary.PingStats[0]
+	if s.Transmitted != 4 || s.Received != 3 || s.LossPercent != 25 || s.RttAvgMs != 1.2 {
+		t.Errorf("stats = %v", s)
+	}
+}
+
+func TestParsePingOutputMultiTarget(t *testing.T) {
+	lines := []string{
+		"[1.1.1.1] 2 packets transmitted, 2 received, 0% packet loss\n[1.1.1.1] rtt min/avg/max/mdev = 1.0/1.5/2.0/0.5 ms",
+		"[8.8.8.8] 2 packets transmitted, 0 received, 100% packet loss",
+	}
+	summary := parsePingOutput("1.1.1.1,8.8.8.8", lines)
+	if summary == nil || len(summary.PingStats) != 2 {
+		t.Fatalf("summary = %v, want two targets", summary)
+	}
+	if got := summary.PingStats[0]; got.Target != "1.1.1.1" || got.RttAvgMs != 1.5 {
+		t.Errorf("first target = %v", got)
+	}
+	if got := summary.PingStats[1]; got.Target != "8.8.8.8" || got.LossPercent != 100 {
+		t.Errorf("second target = %v", got)
+	}
+}
+
+func TestParseFpingOutputSplitsBatchedLines(t *testing.T) {
+	lines := []string{"1.1.1.1 : xmt/rcv/%loss = 5/4/20%, min/avg/max = 1.23/1.35/1.45\r\n8.8.8.8 : xmt/rcv/%loss = 5/0/100%"}
+	summary := parseFpingOutput("", lines)
+	if summary == nil || len(summary.PingStats) != 2 {
+		t.Fatalf("summary = %v, want two targets", summary)
+	}
+	if got := summary.PingStats[0]; got.Target != "1.1.1.1" || got.Received != 4 || got.RttAvgMs != 1.35 {
+		t.Errorf("first target = %v", got)
+	}
+	if got := summary.PingStats[1]; got.Target != "8.8.8.8" || got.LossPercent != 100 {
+		t.Errorf("second target = %v", got)
+	}
+}
+
+func TestParseMTROutputSplitsBatchedLines(t *testing.T) {
+	lines := []string{
+		"HOST: agent                Loss%   Snt   Last   Avg  Best  Wrst StDev\n" +
+			"  1.|-- 192.168.1.1          0.0%     4    0.5   0.6   0.4   0.9   0.2\n" +
+			"  2.|-- ???                 100.0     4    0.0   0.0   0.0   0.0   0.0\n" +
+			"  3.|-- one.one.one.one      25.0%    4    1.2   1.3   1.1   1.5   0.1",
+	}
+	summary := parseMTROutput("1.1.1.1", lines)
+	if summary == nil || len(summary.TraceHops) != 3 {
+		t.Fatalf("summary = %v, want three hops", summary)
+	}
+	hops := summary.TraceHops
+	if hops[0].Ip != "192.168.1.1" || hops[0].RttMs != 0.6 {
+		t.Errorf("hop 1 = %v", hops[0])
+	}
+	if hops[1].Ip != "" || hops[1].ProbesReceived != 0 {
+		t.Errorf("hop 2 = %v", hops[1])
+	}
+	if hops[2].Hostname != "one.one.one.one" || hops[2].ProbesReceived != 3 {
+		t.Errorf("hop 3 = %v", hops[2])
+	}
+}
+
+func TestStructuredFormatterAttachesParsedSummary(t *testing.T) {
+	task := &pb.Task{TaskId: "t1", TaskName: "ping", Params: &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{Target: "1.1.1.1"}}}
+	f := newOutputFormatter(pb.OutputFormat_OUTPUT_FORMAT_STRUCTURED, task)
+
+	out := f.apply(&pb.WSResponse{Type: pb.WSResponse_TYPE_OUTPUT, TaskId: "t1", Output: "4 packets transmitted, 4 received, 0% packet loss\nrtt min/avg/max/mdev = 1.0/1.1/1.2/0.1 ms"})
+	if len(out) != 0 {
+		t.Fatalf("output line not withheld: %v", out)
+	}
+	out = f.apply(&pb.WSResponse{Type: pb.WSResponse_TYPE_COMPLETE, TaskId: "t1"})
+	if len(out) != 1 || len(out[0].Summary.GetPingStats()) != 1 {
+		t.Fatalf("final = %v, want one response with ping stats", out)
+	}
+}
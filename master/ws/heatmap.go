@@ -0,0 +1,116 @@
+package ws
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/lureiny/lookingglass/master/task"
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// HeatmapAgent is a row of the latency heatmap
+type HeatmapAgent struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Location string `json:"location"`
+}
+
+// HeatmapCell is the latest ping result of one agent toward one target
+type HeatmapCell struct {
+	AvgMs       float64   `json:"avg_ms,omitempty"` // Omitted when every packet was lost
+	LossPercent float64   `json:"loss_percent"`
+	MeasuredAt  time.Time `json:"measured_at"`
+}
+
+// Heatmap is the agent x target latency matrix served by /api/heatmap
+// Matrix[i][j] holds the result of Agents[i] toward Targets[j], null if there is none in the history
+type Heatmap struct {
+	Targets         []string         `json:"targets"`
+	Agents          []HeatmapAgent   `json:"agents"`
+	Matrix          [][]*HeatmapCell `json:"matrix"`
+	IntervalSeconds int              `json:"interval_seconds"` // How often the canary pings are refreshed
+}
+
+// SetHeatmap enables /api/heatmap for the canary targets refreshed every interval
+func (s *Server) SetHeatmap(targets []string, interval time.Duration) {
+	s.heatmapTargets = targets
+	s.heatmapInterval = interval
+}
+
+// HandleHeatmap handles HTTP GET request for the latency heatmap
+// Built from the ping results in the task history, so it also reflects users' pings to the targets
+func (s *Server) HandleHeatmap(w http.ResponseWriter, r *http.Request) {
+	if len(s.heatmapTargets) == 0 {
+		http.Error(w, "heatmap is not enabled", http.StatusNotFound)
+		return
+	}
+
+	agents := s.agentManager.GetAllAgents()
+	rows := make([]HeatmapAgent, 0, len(agents))
+	for _, ag := range agents {
+		meta := s.agentManager.ApplyMetadataOverride(&pb.AgentStatusInfo{
+			Id:       ag.Info.Id,
+			Name:     ag.Info.Name,
+			Location: ag.Info.Location,
+		})
+		rows = append(rows, HeatmapAgent{ID: meta.Id, Name: meta.Name, Location: meta.Location})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].ID < rows[j].ID
+	})
+
+	heatmap := buildHeatmap(s.scheduler.GetHistory("", 0), s.heatmapTargets, rows)
+	heatmap.IntervalSeconds = int(s.heatmapInterval.Seconds())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(heatmap)
+}
+
+// buildHeatmap fills the matrix from history entries (newest first) with the latest ping
+// of each agent toward each target
+func buildHeatmap(history []*task.HistoryEntry, targets []string, agents []HeatmapAgent) *Heatmap {
+	heatmap := &Heatmap{
+		Targets: targets,
+		Agents:  agents,
+		Matrix:  make([][]*HeatmapCell, len(agents)),
+	}
+
+	column := make(map[string]int, len(targets))
+	for j, target := range targets {
+		column[target] = j
+	}
+	row := make(map[string]int, len(agents))
+	for i, ag := range agents {
+		row[ag.ID] = i
+		heatmap.Matrix[i] = make([]*HeatmapCell, len(targets))
+	}
+
+	for _, entry := range history {
+		if entry.TaskName != task.HeatmapTaskName {
+			continue
+		}
+		i, ok := row[entry.AgentID]
+		if !ok {
+			continue
+		}
+		j, ok := column[entry.Target]
+		if !ok || heatmap.Matrix[i][j] != nil {
+			continue
+		}
+
+		// Ping exits non-zero when every packet was lost; that still is a result
+		summary := parsePingOutput(entry.Target, entry.Output)
+		if summary == nil {
+			continue
+		}
+		stats := summary.PingStats[0]
+		heatmap.Matrix[i][j] = &HeatmapCell{
+			AvgMs:       stats.RttAvgMs,
+			LossPercent: stats.LossPercent,
+			MeasuredAt:  entry.EndedAt,
+		}
+	}
+	return heatmap
+}
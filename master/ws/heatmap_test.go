@@ -0,0 +1,41 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lureiny/lookingglass/master/task"
+)
+
+func TestBuildHeatmap(t *testing.T) {
+	newer := time.Date(2026, 1, 1, 12, 5, 0, 0, time.UTC)
+	older := newer.Add(-5 * time.Minute)
+	ping := func(agentID, target string, ended time.Time, stats string) *task.HistoryEntry {
+		return &task.HistoryEntry{TaskName: "ping", AgentID: agentID, Target: target, EndedAt: ended, Output: []string{stats}}
+	}
+
+	// Newest first, as the scheduler returns the history
+	history := []*task.HistoryEntry{
+		ping("a", "1.1.1.1", newer, "4 packets transmitted, 4 received, 0% packet loss\nrtt min/avg/max/mdev = 1.0/2.0/3.0/0.5 ms"),
+		ping("b", "1.1.1.1", newer, "4 packets transmitted, 0 received, 100% packet loss"),
+		ping("a", "1.1.1.1", older, "4 packets transmitted, 4 received, 0% packet loss\nrtt min/avg/max/mdev = 9.0/9.0/9.0/0.0 ms"),
+		ping("a", "9.9.9.9", newer, "4 packets transmitted, 4 received, 0% packet loss\nrtt min/avg/max/mdev = 1.0/1.0/1.0/0.0 ms"),
+		ping("c", "1.1.1.1", newer, "4 packets transmitted, 4 received, 0% packet loss\nrtt min/avg/max/mdev = 1.0/1.0/1.0/0.0 ms"),
+		{TaskName: "mtr", AgentID: "a", Target: "8.8.8.8", EndedAt: newer, Output: []string{"mtr output"}},
+	}
+	heatmap := buildHeatmap(history, []string{"1.1.1.1", "8.8.8.8"}, []HeatmapAgent{{ID: "a"}, {ID: "b"}})
+
+	if len(heatmap.Matrix) != 2 || len(heatmap.Matrix[0]) != 2 || len(heatmap.Matrix[1]) != 2 {
+		t.Fatalf("matrix = %v, want 2x2", heatmap.Matrix)
+	}
+	if cell := heatmap.Matrix[0][0]; cell == nil || cell.AvgMs != 2 || cell.LossPercent != 0 || !cell.MeasuredAt.Equal(newer) {
+		t.Errorf("a -> 1.1.1.1 = %+v, want the newest ping", cell)
+	}
+	if cell := heatmap.Matrix[1][0]; cell == nil || cell.LossPercent != 100 || cell.AvgMs != 0 {
+		t.Errorf("b -> 1.1.1.1 = %+v, want total loss", cell)
+	}
+	// Only pings count; unknown targets and agents are ignored
+	if heatmap.Matrix[0][1] != nil || heatmap.Matrix[1][1] != nil {
+		t.Errorf("8.8.8.8 column = %+v, %+v, want no results", heatmap.Matrix[0][1], heatmap.Matrix[1][1])
+	}
+}
@@ -0,0 +1,217 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lureiny/lookingglass/master/task"
+	pb "github.com/lureiny/lookingglass/pb"
+	"github.com/lureiny/lookingglass/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// defaultRunTimeout is how long POST /api/run waits when no timeout is configured
+	defaultRunTimeout = 60 * time.Second
+	// runMaxOutputLines bounds the output lines collected for one POST /api/run request
+	runMaxOutputLines = 10000
+)
+
+// runRequest is the body of POST /api/run
+type runRequest struct {
+	AgentID      string            `json:"agent_id"`
+	TaskName     string            `json:"task_name"`
+	Target       string            `json:"target"`
+	Count        int32             `json:"count"`
+	Timeout      int32             `json:"timeout"` // Task timeout in seconds (0 = master default)
+	IPv6         bool              `json:"ipv6"`
+	ExtraOptions map[string]string `json:"extra_options"`
+	Compress     bool              `json:"compress"` // Agent sends the output as one compressed block at completion
+}
+
+// runResponse is the result of POST /api/run
+type runResponse struct {
+	TaskID    string          `json:"task_id"`
+	AgentID   string          `json:"agent_id"`
+	Status    string          `json:"status"` // completed, failed, cancelled or timeout
+	Error     string          `json:"error,omitempty"`
+	Output    []string        `json:"output"`
+	Truncated bool            `json:"truncated,omitempty"` // Output beyond runMaxOutputLines was dropped
+	Summary   json.RawMessage `json:"summary,omitempty"`
+}
+
+// runCollector buffers the output of a task submitted by POST /api/run
+type runCollector struct {
+	mutex     sync.Mutex
+	output    []string
+	truncated bool
+	summary   *pb.TaskSummary
+	final     *pb.TaskOutput
+	done      chan struct{}
+}
+
+// handle is the scheduler output handler; it never blocks
+func (c *runCollector) handle(output *pb.TaskOutput) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.final != nil {
+		return
+	}
+	if output.OutputLine != "" {
+		if len(c.output) < runMaxOutputLines {
+			c.output = append(c.output, output.OutputLine)
+		} else {
+			c.truncated = true
+		}
+	}
+	if output.Summary != nil {
+		c.summary = output.Summary
+	}
+
+	switch output.Status {
+	case pb.TaskStatus_TASK_STATUS_COMPLETED,
+		pb.TaskStatus_TASK_STATUS_FAILED,
+		pb.TaskStatus_TASK_STATUS_CANCELLED:
+		c.final = output
+		close(c.done)
+	}
+}
+
+// response builds the result from what was collected; status overrides the final status when set
+func (c *runCollector) response(t *pb.Task, status string) *runResponse {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	resp := &runResponse{
+		TaskID:    t.TaskId,
+		AgentID:   t.AgentId,
+		Status:    status,
+		Output:    c.output,
+		Truncated: c.truncated,
+	}
+	if resp.Output == nil {
+		resp.Output = []string{}
+	}
+	if c.final != nil && status == "" {
+		resp.Status = taskStatusName(c.final.Status)
+		resp.Error = c.final.ErrorMessage
+	}
+	if c.summary != nil {
+		if data, err := (protojson.MarshalOptions{UseProtoNames: true}).Marshal(c.summary); err == nil {
+			resp.Summary = data
+		}
+	}
+	return resp
+}
+
+// SetRunTimeout sets how long POST /api/run waits for a task before cancelling it
+func (s *Server) SetRunTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultRunTimeout
+	}
+	s.runTimeout = timeout
+}
+
+// HandleRun handles POST /api/run: it submits a task, waits for it to finish and returns the
+// collected output and final status as one JSON document
+// A task still running after the run timeout is cancelled and its partial output returned
+// with status "timeout" (HTTP 504)
+func (s *Server) HandleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req runRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.TaskName == "" || req.AgentID == "" {
+		http.Error(w, "agent_id and task_name are required", http.StatusBadRequest)
+		return
+	}
+
+	t := &pb.Task{
+		TaskId:         uuid.New().String(),
+		AgentId:        req.AgentID,
+		TaskName:       req.TaskName,
+		Timeout:        req.Timeout,
+		CompressOutput: req.Compress,
+		Params: &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{
+			Target:       req.Target,
+			Count:        req.Count,
+			Ipv6:         req.IPv6,
+			ExtraOptions: req.ExtraOptions,
+		}},
+	}
+	if err := s.inputLimits.validateTaskInput(t); err != nil {
+		http.Error(w, "invalid task: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.validateTaskParams(t, nil); err != nil {
+		http.Error(w, "invalid task: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	clientID := "http-" + uuid.New().String()
+	clientAddr := s.trustedProxies.ClientIP(r)
+	collector := &runCollector{done: make(chan struct{})}
+
+	ctx := task.WithClientAddr(context.Background(), clientAddr)
+	if err := s.scheduler.SubmitTask(ctx, t, clientID, collector.handle); err != nil {
+		http.Error(w, "submit task fail: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	logger.Info("Task submitted via HTTP",
+		zap.String("task_id", t.TaskId),
+		zap.String("client_id", clientID),
+		zap.String("client_addr", clientAddr),
+	)
+
+	timer := time.NewTimer(s.runTimeout)
+	defer timer.Stop()
+
+	var resp *runResponse
+	httpStatus := http.StatusOK
+	select {
+	case <-collector.done:
+		resp = collector.response(t, "")
+	case <-timer.C:
+		s.cancelRun(t.TaskId)
+		resp = collector.response(t, "timeout")
+		resp.Error = "task did not finish within " + s.runTimeout.String()
+		httpStatus = http.StatusGatewayTimeout
+	case <-r.Context().Done():
+		s.cancelRun(t.TaskId)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// cancelRun cancels a task whose POST /api/run caller stopped waiting
+func (s *Server) cancelRun(taskID string) {
+	if err := s.scheduler.CancelTask(taskID); err != nil {
+		logger.Debug("Failed to cancel task after HTTP run ended",
+			zap.String("task_id", taskID),
+			zap.Error(err),
+		)
+	}
+}
+
+// taskStatusName returns the lowercase name of a task status ("completed", "failed", ...)
+func taskStatusName(status pb.TaskStatus) string {
+	return strings.ToLower(strings.TrimPrefix(status.String(), "TASK_STATUS_"))
+}
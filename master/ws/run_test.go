@@ -0,0 +1,119 @@
+package ws
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lureiny/lookingglass/master/task"
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// newRunServer returns a server with a scheduler dispatching to sender
+func newRunServer(t *testing.T) (*Server, *shareSender) {
+	t.Helper()
+	s, am := newTestServer(t, &pb.AgentInfo{
+		Id:              "agent-1",
+		MaxConcurrent:   5,
+		TaskDisplayInfo: []*pb.TaskDisplayInfo{{TaskName: "ping", RequiresTarget: true}},
+	})
+	s.scheduler = task.NewScheduler(am, 10)
+	sender := &shareSender{sent: make(chan *pb.Task, 1)}
+	s.scheduler.SetStreamSender(sender)
+	return s, sender
+}
+
+// startRun serves body to HandleRun in the background and returns the recorder once it finishes
+func startRun(s *Server, body string) <-chan *httptest.ResponseRecorder {
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		s.HandleRun(rec, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(body)))
+		done <- rec
+	}()
+	return done
+}
+
+func decodeRun(t *testing.T, rec *httptest.ResponseRecorder) *runResponse {
+	t.Helper()
+	var resp runResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response %q: %v", rec.Body.String(), err)
+	}
+	return &resp
+}
+
+func TestHandleRunCompleted(t *testing.T) {
+	s, sender := newRunServer(t)
+	done := startRun(s, `{"agent_id":"agent-1","task_name":"ping","target":"192.0.2.1"}`)
+
+	var dispatched *pb.Task
+	select {
+	case dispatched = <-sender.sent:
+	case <-time.After(2 * time.Second):
+		t.Fatal("task was not dispatched")
+	}
+	s.scheduler.HandleTaskOutput(&pb.TaskOutput{TaskId: dispatched.TaskId, OutputLine: "64 bytes from 192.0.2.1"})
+	s.scheduler.HandleTaskOutput(&pb.TaskOutput{
+		TaskId:  dispatched.TaskId,
+		Status:  pb.TaskStatus_TASK_STATUS_COMPLETED,
+		Summary: &pb.TaskSummary{},
+	})
+
+	rec := <-done
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	resp := decodeRun(t, rec)
+	if resp.TaskID != dispatched.TaskId || resp.AgentID != "agent-1" || resp.Status != "completed" {
+		t.Errorf("response = %+v, want completed task %s on agent-1", resp, dispatched.TaskId)
+	}
+	if len(resp.Output) != 1 || resp.Output[0] != "64 bytes from 192.0.2.1" {
+		t.Errorf("output = %q, want the one output line", resp.Output)
+	}
+}
+
+func TestHandleRunTimeout(t *testing.T) {
+	s, sender := newRunServer(t)
+	s.SetRunTimeout(50 * time.Millisecond)
+	done := startRun(s, `{"agent_id":"agent-1","task_name":"ping","target":"192.0.2.1"}`)
+
+	dispatched := <-sender.sent
+	s.scheduler.HandleTaskOutput(&pb.TaskOutput{TaskId: dispatched.TaskId, OutputLine: "partial"})
+
+	rec := <-done
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", rec.Code)
+	}
+	resp := decodeRun(t, rec)
+	if resp.Status != "timeout" || resp.Error == "" {
+		t.Errorf("response = %+v, want status timeout with an error", resp)
+	}
+	if len(resp.Output) != 1 || resp.Output[0] != "partial" {
+		t.Errorf("output = %q, want the partial output", resp.Output)
+	}
+	if n := s.scheduler.GetCurrentTaskCount(); n != 0 {
+		t.Errorf("running tasks = %d after timeout, want the task cancelled", n)
+	}
+}
+
+func TestHandleRunRejectsBadRequests(t *testing.T) {
+	s, _ := newRunServer(t)
+
+	rec := httptest.NewRecorder()
+	s.HandleRun(rec, httptest.NewRequest(http.MethodGet, "/api/run", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want 405", rec.Code)
+	}
+
+	for _, body := range []string{`not json`, `{"agent_id":"agent-1"}`, `{"task_name":"ping"}`} {
+		rec := httptest.NewRecorder()
+		s.HandleRun(rec, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("body %s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
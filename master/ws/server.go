@@ -1,27 +1,43 @@
 package ws
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/lureiny/lookingglass/master/agent"
+	"github.com/lureiny/lookingglass/master/auth"
 	"github.com/lureiny/lookingglass/master/task"
 	pb "github.com/lureiny/lookingglass/pb"
 	"github.com/lureiny/lookingglass/pkg/logger"
+	"github.com/lureiny/lookingglass/pkg/netutil"
 	"go.uber.org/zap"
 )
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// TODO: Add proper origin checking for production
-		return true
-	},
+	// Echoed back when offered so browsers authenticating via a token subprotocol can connect
+	Subprotocols: []string{auth.WSProtocol},
+}
+
+// AgentDescriber fetches an agent's effective configuration over its stream
+type AgentDescriber interface {
+	DescribeAgent(ctx context.Context, agentID string) (*pb.DescribeResponse, error)
+}
+
+// AgentLogFetcher fetches an agent's recent log lines over its stream
+type AgentLogFetcher interface {
+	FetchAgentLogs(ctx context.Context, agentID string, lines int) (*pb.LogsResponse, error)
 }
 
 // BrandingInfo contains branding customization information
@@ -40,6 +56,31 @@ type Server struct {
 	clients      map[string]*Client
 	clientsMutex sync.RWMutex
 	branding     *BrandingInfo
+	brandingMu   sync.RWMutex
+	adminToken   string
+	inputLimits  *InputLimits
+
+	publicStatusLocations bool // Include per-location counts in /api/public/status
+
+	effectiveConfig interface{}     // Redacted effective config served by /api/config (nil = not exposed)
+	agentDescriber  AgentDescriber  // Serves /api/agent/config (nil = not available)
+	agentLogs       AgentLogFetcher // Serves ACTION_AGENT_LOGS (nil = not available)
+
+	trustedProxies *netutil.TrustedProxies // Proxies whose forwarding headers are believed (nil = none)
+	allowedOrigins []string                // Origins allowed to open the WebSocket ("*" = any, empty = same origin)
+
+	templates *task.TemplateLibrary // Canned tasks offered to users (nil = none)
+
+	runTimeout time.Duration // How long POST /api/run waits for a task to finish
+
+	heatmapTargets  []string      // Canary targets of /api/heatmap (empty = disabled)
+	heatmapInterval time.Duration // How often the heatmap runner refreshes them
+
+	idleTimeout time.Duration // Close WebSocket clients idle this long (0 = never)
+
+	// permessage-deflate settings
+	compression      bool
+	compressionLevel int
 }
 
 // NewServer creates a new WebSocket server
@@ -49,19 +90,128 @@ func NewServer(agentManager *agent.Manager, scheduler *task.Scheduler, branding
 		scheduler:    scheduler,
 		clients:      make(map[string]*Client),
 		branding:     branding,
+		runTimeout:   defaultRunTimeout,
+	}
+}
+
+// SetAdminToken sets the confirmation token required by admin actions
+// An empty token disables admin actions
+func (s *Server) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+// SetCompression enables permessage-deflate with the given compression level
+// Level follows compress/flate: -2 (Huffman only) to 9 (best compression)
+func (s *Server) SetCompression(enabled bool, level int) {
+	s.compression = enabled
+	s.compressionLevel = level
+}
+
+// SetPublicStatusLocations controls whether the public status includes per-location counts
+func (s *Server) SetPublicStatusLocations(enabled bool) {
+	s.publicStatusLocations = enabled
+}
+
+// SetEffectiveConfig sets the (already redacted) config served to admins by /api/config
+func (s *Server) SetEffectiveConfig(cfg interface{}) {
+	s.effectiveConfig = cfg
+}
+
+// SetAgentLogFetcher sets the source of agent log lines for ACTION_AGENT_LOGS
+func (s *Server) SetAgentLogFetcher(fetcher AgentLogFetcher) {
+	s.agentLogs = fetcher
+}
+
+// SetAgentDescriber sets the source of agent effective configs for /api/agent/config
+func (s *Server) SetAgentDescriber(describer AgentDescriber) {
+	s.agentDescriber = describer
+}
+
+// SetTrustedProxies sets the proxies allowed to report the client IP via forwarding headers
+func (s *Server) SetTrustedProxies(proxies *netutil.TrustedProxies) {
+	s.trustedProxies = proxies
+}
+
+// SetIdleTimeout closes WebSocket clients that send no request for timeout while no task of
+// theirs is running; pongs do not count. A timeout <= 0 keeps idle clients connected
+func (s *Server) SetIdleTimeout(timeout time.Duration) {
+	s.idleTimeout = max(timeout, 0)
+}
+
+// SetTemplates sets the task template library served to clients
+func (s *Server) SetTemplates(templates *task.TemplateLibrary) {
+	s.templates = templates
+}
+
+// SetAllowedOrigins sets the origins (e.g. "https://lg.example.com") allowed to open the WebSocket
+// "*" allows any origin; an empty list allows only same-origin pages
+func (s *Server) SetAllowedOrigins(origins []string) {
+	s.allowedOrigins = origins
+}
+
+// checkOrigin reports whether a WebSocket upgrade request comes from an allowed origin
+// Requests without an Origin header (non-browser clients) are always allowed
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
 	}
+
+	if len(s.allowedOrigins) == 0 {
+		u, err := url.Parse(origin)
+		if err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+	}
+	for _, allowed := range s.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	logger.Warn("Rejected WebSocket connection from disallowed origin",
+		zap.String("origin", origin),
+		zap.String("remote_addr", r.RemoteAddr),
+	)
+	return false
+}
+
+// SetInputLimits sets the size limits applied to task requests
+func (s *Server) SetInputLimits(limits *InputLimits) {
+	s.inputLimits = limits
 }
 
 // HandleWebSocket handles WebSocket upgrade and connection
 func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	wsUpgrader := upgrader
+	wsUpgrader.EnableCompression = s.compression
+	wsUpgrader.CheckOrigin = s.checkOrigin
+
+	format, err := parseOutputFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
 	if err != nil {
 		logger.Error("Failed to upgrade WebSocket", zap.Error(err))
 		return
 	}
 
+	if s.compression {
+		if err := conn.SetCompressionLevel(s.compressionLevel); err != nil {
+			logger.Warn("Failed to set WebSocket compression level",
+				zap.Int("level", s.compressionLevel),
+				zap.Error(err),
+			)
+		}
+	}
+
 	// Create client
 	client := NewClient(conn, s)
+	client.RemoteAddr = s.trustedProxies.ClientIP(r)
+	client.outputFormat = format
 
 	// Register client
 	s.clientsMutex.Lock()
@@ -73,10 +223,11 @@ func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		zap.String("remote_addr", r.RemoteAddr),
 	}
 
-	xForwardFor := r.Header.Get("X-Forwarded-For")
-
-	if len(xForwardFor) > 0 {
-		fields = append(fields, zap.String("real_ip", xForwardFor))
+	if xForwardFor := r.Header.Get("X-Forwarded-For"); len(xForwardFor) > 0 {
+		fields = append(fields,
+			zap.String("x_forwarded_for", xForwardFor),
+			zap.String("real_ip", client.RemoteAddr),
+		)
 	}
 
 	logger.Info("New WebSocket client connected", fields...)
@@ -91,14 +242,16 @@ func (s *Server) HandleAgentList(w http.ResponseWriter, r *http.Request) {
 	agents := s.agentManager.GetAllAgents()
 
 	type AgentResponse struct {
-		ID            string `json:"id"`
-		Name          string `json:"name"`
-		Location      string `json:"location"`
-		IPv4          string `json:"ipv4"`
-		IPv6          string `json:"ipv6"`
-		Status        string `json:"status"`
-		CurrentTasks  int32  `json:"current_tasks"`
-		MaxConcurrent int32  `json:"max_concurrent"`
+		ID            string            `json:"id"`
+		Name          string            `json:"name"`
+		Location      string            `json:"location"`
+		IPv4          string            `json:"ipv4"`
+		IPv6          string            `json:"ipv6"`
+		Status        string            `json:"status"`
+		CurrentTasks  int32             `json:"current_tasks"`
+		MaxConcurrent int32             `json:"max_concurrent"`
+		Degraded      bool              `json:"degraded,omitempty"`
+		Tags          map[string]string `json:"tags,omitempty"`
 	}
 
 	response := make([]AgentResponse, 0, len(agents))
@@ -108,15 +261,23 @@ func (s *Server) HandleAgentList(w http.ResponseWriter, r *http.Request) {
 			status = "online"
 		}
 
+		meta := s.agentManager.ApplyMetadataOverride(&pb.AgentStatusInfo{
+			Id:       agent.Info.Id,
+			Name:     agent.Info.Name,
+			Location: agent.Info.Location,
+		})
+
 		response = append(response, AgentResponse{
 			ID:            agent.Info.Id,
-			Name:          agent.Info.Name,
-			Location:      agent.Info.Location,
-			IPv4:          maskIPAddress(agent.Info.Ipv4, agent.Info.HideIp),
-			IPv6:          maskIPAddress(agent.Info.Ipv6, agent.Info.HideIp),
+			Name:          meta.Name,
+			Location:      meta.Location,
+			IPv4:          netutil.DisplayIP(agent.Info.Ipv4, agent.Info.HideIp),
+			IPv6:          netutil.DisplayIP(agent.Info.Ipv6, agent.Info.HideIp),
 			Status:        status,
 			CurrentTasks:  agent.CurrentTasks,
 			MaxConcurrent: agent.Info.MaxConcurrent,
+			Degraded:      !agent.DegradedUntil.IsZero(),
+			Tags:          agent.Info.Tags,
 		})
 	}
 
@@ -126,28 +287,97 @@ func (s *Server) HandleAgentList(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// maskIPAddress masks IP addresses for privacy (supports both IPv4 and IPv6)
-// IPv4: 127.0.0.1 -> 127.0.*.*
-// IPv6: 2001:0db8:85a3:0000:0000:8a2e:0370:7334 -> 2001:0db8:85a3:****:****:****:****:****
-func maskIPAddress(ip string, shouldMask bool) string {
-	if !shouldMask || ip == "" {
-		return ip
+// HandleHistory handles HTTP GET request for recently finished tasks, newest first
+// Requires the admin token in the X-Admin-Token header, since entries span every client's tasks
+// Optional query params: agent=<id> to filter by agent, limit=<n> (default 50, max 500)
+func (s *Server) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	if err := s.checkAdminToken(r.Header.Get("X-Admin-Token")); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, 500)
 	}
 
-	// Check if it's IPv6 (contains colons)
-	if strings.Contains(ip, ":") {
-		return maskIPv6Address(ip)
+	history := s.scheduler.GetHistory(r.URL.Query().Get("agent"), limit)
+	if history == nil {
+		history = []*task.HistoryEntry{}
 	}
 
-	// IPv4 handling
-	parts := strings.Split(ip, ".")
-	if len(parts) != 4 {
-		// Not a valid IPv4, return as-is
-		return ip
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tasks": history,
+	})
+}
+
+// HandleTemplates handles HTTP GET request for the task template library
+func (s *Server) HandleTemplates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"templates": s.templates.List(),
+	})
+}
+
+// HandlePublicStatus handles HTTP GET request for the public status summary
+// Only aggregate counts are returned; no IDs, names or addresses, so it is safe to embed publicly
+func (s *Server) HandlePublicStatus(w http.ResponseWriter, r *http.Request) {
+	agents := s.agentManager.GetAllAgents()
+
+	type LocationStatus struct {
+		Location string `json:"location"`
+		Online   int    `json:"online"`
+		Total    int    `json:"total"`
 	}
 
-	// Mask last 2 octets
-	return parts[0] + "." + parts[1] + ".*.*"
+	type PublicStatus struct {
+		Online    int               `json:"online"`
+		Total     int               `json:"total"`
+		Locations []*LocationStatus `json:"locations,omitempty"`
+	}
+
+	status := &PublicStatus{Total: len(agents)}
+	byLocation := make(map[string]*LocationStatus)
+	for _, agent := range agents {
+		online := agent.Status == pb.AgentStatus_AGENT_STATUS_ONLINE
+		if online {
+			status.Online++
+		}
+
+		if !s.publicStatusLocations {
+			continue
+		}
+		location := s.agentManager.ApplyMetadataOverride(&pb.AgentStatusInfo{
+			Id:       agent.Info.Id,
+			Location: agent.Info.Location,
+		}).Location
+		if location == "" {
+			location = "Unknown"
+		}
+		entry, ok := byLocation[location]
+		if !ok {
+			entry = &LocationStatus{Location: location}
+			byLocation[location] = entry
+			status.Locations = append(status.Locations, entry)
+		}
+		entry.Total++
+		if online {
+			entry.Online++
+		}
+	}
+
+	sort.Slice(status.Locations, func(i, j int) bool {
+		return status.Locations[i].Location < status.Locations[j].Location
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(status)
 }
 
 // UnregisterClient removes a client from the server
@@ -190,10 +420,10 @@ func (s *Server) BroadcastAgentStatusUpdate(agents []*agent.Agent) {
 	// Convert to AgentStatusInfo
 	agentInfos := make([]*pb.AgentStatusInfo, 0, len(agents))
 	for _, ag := range agents {
-		ipv4 := maskIPAddress(ag.Info.Ipv4, ag.Info.HideIp)
-		ipv6 := maskIPAddress(ag.Info.Ipv6, ag.Info.HideIp)
+		ipv4 := netutil.DisplayIP(ag.Info.Ipv4, ag.Info.HideIp)
+		ipv6 := netutil.DisplayIP(ag.Info.Ipv6, ag.Info.HideIp)
 
-		agentInfos = append(agentInfos, &pb.AgentStatusInfo{
+		agentInfos = append(agentInfos, s.agentManager.ApplyMetadataOverride(&pb.AgentStatusInfo{
 			Id:              ag.Info.Id,
 			Name:            ag.Info.Name,
 			Location:        ag.Info.Location,
@@ -206,7 +436,12 @@ func (s *Server) BroadcastAgentStatusUpdate(agents []*agent.Agent) {
 			Provider:        ag.Info.Provider,
 			Idc:             ag.Info.Idc,
 			Description:     ag.Info.Description,
-		})
+			Tags:            ag.Info.Tags,
+			MemPercent:      ag.MemPercent,
+			DiskPercent:     ag.DiskPercent,
+			TaskConcurrency: ag.TaskUsage,
+			Degraded:        !ag.DegradedUntil.IsZero(),
+		}))
 	}
 
 	// Create and broadcast update message
@@ -223,8 +458,148 @@ func (s *Server) BroadcastAgentStatusUpdate(agents []*agent.Agent) {
 	)
 }
 
+// checkAdminToken validates a confirmation token for admin actions
+func (s *Server) checkAdminToken(token string) error {
+	if s.adminToken == "" {
+		return fmt.Errorf("admin actions are disabled")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+		return fmt.Errorf("invalid confirmation token")
+	}
+	return nil
+}
+
+// HandleConfig handles HTTP GET request for the effective (redacted) master configuration
+// Requires the admin token in the X-Admin-Token header
+func (s *Server) HandleConfig(w http.ResponseWriter, r *http.Request) {
+	if err := s.checkAdminToken(r.Header.Get("X-Admin-Token")); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if s.effectiveConfig == nil {
+		http.Error(w, "config not available", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.effectiveConfig)
+}
+
+// HandleAgentConfig handles HTTP GET request for an agent's effective (redacted) configuration
+// Requires the admin token in the X-Admin-Token header and ?agent_id=<id>
+func (s *Server) HandleAgentConfig(w http.ResponseWriter, r *http.Request) {
+	if err := s.checkAdminToken(r.Header.Get("X-Admin-Token")); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if s.agentDescriber == nil {
+		http.Error(w, "agent config not available", http.StatusNotFound)
+		return
+	}
+
+	agentID := r.URL.Query().Get("agent_id")
+	if agentID == "" {
+		http.Error(w, "agent_id is required", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := s.agentDescriber.DescribeAgent(r.Context(), agentID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if resp.Error != "" {
+		http.Error(w, resp.Error, http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"agent_id": resp.AgentId,
+		"config":   json.RawMessage(resp.EffectiveConfig),
+	})
+}
+
+// HandleAgentMetadata lets admins override the metadata agents are displayed with
+// GET lists the overrides, PUT ?agent_id=<id> sets one from a JSON body
+// (name, location, provider, idc, description; empty fields keep the agent's value),
+// DELETE ?agent_id=<id> restores what the agent reports. Overrides live until the master restarts.
+func (s *Server) HandleAgentMetadata(w http.ResponseWriter, r *http.Request) {
+	if err := s.checkAdminToken(r.Header.Get("X-Admin-Token")); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	agentID := r.URL.Query().Get("agent_id")
+	switch r.Method {
+	case http.MethodGet:
+		overrides := s.agentManager.GetMetadataOverrides()
+		if agentID != "" {
+			override, ok := overrides[agentID]
+			if !ok {
+				http.Error(w, "no metadata override for agent", http.StatusNotFound)
+				return
+			}
+			overrides = map[string]agent.MetadataOverride{agentID: override}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"overrides": overrides,
+		})
+
+	case http.MethodPut:
+		if agentID == "" {
+			http.Error(w, "agent_id is required", http.StatusBadRequest)
+			return
+		}
+		var override agent.MetadataOverride
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&override); err != nil {
+			http.Error(w, "invalid metadata: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.agentManager.SetMetadataOverride(agentID, override); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Info("Agent metadata overridden",
+			zap.String("agent_id", agentID),
+			zap.String("remote_addr", r.RemoteAddr),
+		)
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		if agentID == "" {
+			http.Error(w, "agent_id is required", http.StatusBadRequest)
+			return
+		}
+		s.agentManager.ClearMetadataOverride(agentID)
+		logger.Info("Agent metadata override cleared",
+			zap.String("agent_id", agentID),
+			zap.String("remote_addr", r.RemoteAddr),
+		)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// SetBranding replaces the branding served to clients (used for live reloads)
+func (s *Server) SetBranding(branding *BrandingInfo) {
+	s.brandingMu.Lock()
+	defer s.brandingMu.Unlock()
+	s.branding = branding
+}
+
 // HandleBranding handles HTTP GET request for branding configuration
 func (s *Server) HandleBranding(w http.ResponseWriter, r *http.Request) {
+	s.brandingMu.RLock()
+	branding := s.branding
+	s.brandingMu.RUnlock()
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(s.branding)
+	json.NewEncoder(w).Encode(branding)
 }
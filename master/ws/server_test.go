@@ -0,0 +1,250 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lureiny/lookingglass/master/task"
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func TestHandleHistoryRequiresAdminToken(t *testing.T) {
+	s, am := newTestServer(t)
+	s.scheduler = task.NewScheduler(am, 10)
+	s.scheduler.SetHistoryRetention(time.Hour)
+
+	request := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/history", nil)
+		if token != "" {
+			req.Header.Set("X-Admin-Token", token)
+		}
+		rec := httptest.NewRecorder()
+		s.HandleHistory(rec, req)
+		return rec
+	}
+
+	// Admin actions disabled: nobody gets the history
+	if rec := request(""); rec.Code != http.StatusForbidden {
+		t.Errorf("without admin token configured: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	s.SetAdminToken("admin")
+	if rec := request(""); rec.Code != http.StatusForbidden {
+		t.Errorf("missing token: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := request("wrong"); rec.Code != http.StatusForbidden {
+		t.Errorf("wrong token: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec := request("admin")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("admin token: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var body struct {
+		Tasks []*task.HistoryEntry `json:"tasks"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Tasks == nil {
+		t.Error("tasks = null, want []")
+	}
+}
+
+func TestSetBrandingReplacesServedBranding(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.SetBranding(&BrandingInfo{SiteTitle: "Old"})
+	s.SetBranding(&BrandingInfo{SiteTitle: "New", FooterText: "footer"})
+
+	rec := httptest.NewRecorder()
+	s.HandleBranding(rec, httptest.NewRequest(http.MethodGet, "/api/branding", nil))
+
+	var got BrandingInfo
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.SiteTitle != "New" || got.FooterText != "footer" {
+		t.Errorf("branding = %+v, want the replacement", got)
+	}
+}
+
+func TestWebSocketCompression(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		s, am := newTestServer(t)
+		s.scheduler = task.NewScheduler(am, 10)
+		s.SetCompression(enabled, 1)
+		srv := httptest.NewServer(http.HandlerFunc(s.HandleWebSocket))
+
+		dialer := websocket.Dialer{EnableCompression: true}
+		conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+		if err != nil {
+			srv.Close()
+			t.Fatalf("Dial() error = %v", err)
+		}
+		negotiated := strings.Contains(resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+		conn.Close()
+		srv.Close()
+
+		if negotiated != enabled {
+			t.Errorf("compression enabled = %v: negotiated permessage-deflate = %v", enabled, negotiated)
+		}
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	request := func(origin string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://lg.example.com/ws", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{"no origin header", nil, "", true},
+		{"same origin by default", nil, "https://lg.example.com", true},
+		{"cross origin by default", nil, "https://evil.example", false},
+		{"listed origin", []string{"https://app.example.com/"}, "https://app.example.com", true},
+		{"listed origin is case-insensitive", []string{"https://App.Example.com"}, "https://app.example.com", true},
+		{"unlisted origin", []string{"https://app.example.com"}, "https://evil.example", false},
+		{"same origin not implied by a list", []string{"https://app.example.com"}, "https://lg.example.com", false},
+		{"wildcard", []string{"*"}, "https://evil.example", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			s.SetAllowedOrigins(tt.allowed)
+			if got := s.checkOrigin(request(tt.origin)); got != tt.want {
+				t.Errorf("checkOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebSocketRejectsDisallowedOrigin(t *testing.T) {
+	s, am := newTestServer(t)
+	s.scheduler = task.NewScheduler(am, 10)
+	srv := httptest.NewServer(http.HandlerFunc(s.HandleWebSocket))
+	defer srv.Close()
+
+	header := http.Header{"Origin": []string{"https://evil.example"}}
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), header)
+	if err == nil {
+		conn.Close()
+		t.Fatal("Dial() from a disallowed origin succeeded")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Dial() response = %v, want %d", resp, http.StatusForbidden)
+	}
+}
+
+func TestHandlePublicStatus(t *testing.T) {
+	s, am := newTestServer(t,
+		&pb.AgentInfo{Id: "a", Name: "Paris-1", Location: "Paris", Ipv4: "198.51.100.9"},
+		&pb.AgentInfo{Id: "b", Name: "Paris-2", Location: "Paris"},
+		&pb.AgentInfo{Id: "c", Name: "Tokyo", Location: "Tokyo"},
+	)
+	am.MarkAgentOffline("b")
+
+	status := func() map[string]any {
+		rec := httptest.NewRecorder()
+		s.HandlePublicStatus(rec, httptest.NewRequest(http.MethodGet, "/api/public/status", nil))
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+		}
+		if strings.Contains(rec.Body.String(), "198.51.100.9") || strings.Contains(rec.Body.String(), "Paris-1") {
+			t.Errorf("body %s exposes agent details", rec.Body.String())
+		}
+		var body map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return body
+	}
+
+	body := status()
+	if body["online"] != 2.0 || body["total"] != 3.0 {
+		t.Errorf("counts = %v/%v, want 2/3", body["online"], body["total"])
+	}
+	if _, ok := body["locations"]; ok {
+		t.Errorf("locations reported while disabled: %v", body["locations"])
+	}
+
+	s.SetPublicStatusLocations(true)
+	body = status()
+	locations, _ := body["locations"].([]any)
+	if len(locations) != 2 {
+		t.Fatalf("locations = %v, want Paris and Tokyo", body["locations"])
+	}
+	paris := locations[0].(map[string]any)
+	if paris["location"] != "Paris" || paris["online"] != 1.0 || paris["total"] != 2.0 {
+		t.Errorf("first location = %v, want Paris 1/2", paris)
+	}
+}
+
+// fakeDescriber answers DescribeAgent from a fixed response
+type fakeDescriber struct {
+	resp *pb.DescribeResponse
+	err  error
+}
+
+func (f *fakeDescriber) DescribeAgent(ctx context.Context, agentID string) (*pb.DescribeResponse, error) {
+	return f.resp, f.err
+}
+
+func TestHandleAgentConfig(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.SetAdminToken("admin")
+
+	request := func(token, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/agent/config"+query, nil)
+		req.Header.Set("X-Admin-Token", token)
+		rec := httptest.NewRecorder()
+		s.HandleAgentConfig(rec, req)
+		return rec
+	}
+
+	if rec := request("admin", "?agent_id=a"); rec.Code != http.StatusNotFound {
+		t.Errorf("without describer: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	describer := &fakeDescriber{resp: &pb.DescribeResponse{AgentId: "a", EffectiveConfig: `{"agent":{"id":"a"}}`}}
+	s.SetAgentDescriber(describer)
+	if rec := request("wrong", "?agent_id=a"); rec.Code != http.StatusForbidden {
+		t.Errorf("wrong token: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := request("admin", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing agent_id: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec := request("admin", "?agent_id=a")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"agent_id":"a","config":{"agent":{"id":"a"}}}` {
+		t.Errorf("body = %s", got)
+	}
+
+	describer.err = errors.New("agent not connected")
+	if rec := request("admin", "?agent_id=a"); rec.Code != http.StatusBadGateway {
+		t.Errorf("describe error: status = %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+	describer.err = nil
+	describer.resp = &pb.DescribeResponse{AgentId: "a", Error: "marshal failed"}
+	if rec := request("admin", "?agent_id=a"); rec.Code != http.StatusBadGateway {
+		t.Errorf("agent-side error: status = %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+}
@@ -0,0 +1,124 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lureiny/lookingglass/master/task"
+)
+
+// sharePathPrefix is where shared results are served; the rest of the path is the token
+const sharePathPrefix = "/share/"
+
+// sharePage renders a shared result read-only
+var sharePage = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+dt { font-weight: bold; float: left; clear: left; width: 8em; }
+dd { margin-left: 9em; }
+pre { background: #111; color: #eee; padding: 1em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<dl>
+<dt>Task</dt><dd>{{.Entry.TaskName}}</dd>
+<dt>Target</dt><dd>{{.Entry.Target}}</dd>
+<dt>Agent</dt><dd>{{.Entry.AgentID}}</dd>
+<dt>Status</dt><dd>{{.Entry.Status}}{{if .Entry.Error}} ({{.Entry.Error}}){{end}}</dd>
+<dt>Finished</dt><dd>{{.Entry.EndedAt.Format "2006-01-02 15:04:05 MST"}}</dd>
+<dt>Link expires</dt><dd>{{.ExpiresAt.Format "2006-01-02 15:04:05 MST"}}</dd>
+</dl>
+<pre>{{range .Entry.Output}}{{.}}
+{{end}}{{if .Entry.Truncated}}[output truncated]
+{{end}}</pre>
+</body>
+</html>
+`))
+
+// HandleCreateShare handles POST /api/share?task_id=<id>: a link to a finished task's result
+// Any task can be shared here, so it needs the admin token; clients share their own tasks with ACTION_SHARE
+func (s *Server) HandleCreateShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.checkAdminToken(r.Header.Get("X-Admin-Token")); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	taskID := r.FormValue("task_id")
+	if taskID == "" {
+		http.Error(w, "task_id is required", http.StatusBadRequest)
+		return
+	}
+
+	token, expiresAt, err := s.scheduler.CreateShareLink(taskID, "")
+	switch {
+	case errors.Is(err, task.ErrShareDisabled), errors.Is(err, task.ErrShareNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      token,
+		"url":        sharePathPrefix + token,
+		"expires_at": expiresAt,
+	})
+}
+
+// HandleShare handles GET /share/<token>: the shared result as a read-only page (?format=json for JSON)
+func (s *Server) HandleShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := strings.TrimPrefix(r.URL.Path, sharePathPrefix)
+	entry, expiresAt, err := s.scheduler.SharedResult(token)
+	if err != nil {
+		http.Error(w, "link not found or expired", http.StatusNotFound)
+		return
+	}
+
+	// Keep the token out of Referer headers and shared caches
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"task":       entry,
+			"expires_at": expiresAt,
+		})
+		return
+	}
+
+	title := "Shared result"
+	s.brandingMu.RLock()
+	if s.branding != nil && s.branding.SiteTitle != "" {
+		title = s.branding.SiteTitle + " - " + title
+	}
+	s.brandingMu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	sharePage.Execute(w, struct {
+		Title     string
+		Entry     *task.HistoryEntry
+		ExpiresAt time.Time
+	}{title, entry, expiresAt})
+}
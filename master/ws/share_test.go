@@ -0,0 +1,104 @@
+package ws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lureiny/lookingglass/master/task"
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// shareSender accepts every dispatched task
+type shareSender struct {
+	sent chan *pb.Task
+}
+
+func (f *shareSender) SendTaskToAgent(agentID string, task *pb.Task) error {
+	f.sent <- task
+	return nil
+}
+
+func (f *shareSender) CancelTaskOnAgent(agentID string, taskID string) error { return nil }
+
+// newShareServer returns a server whose history holds task t1, finished for client c1
+func newShareServer(t *testing.T) *Server {
+	t.Helper()
+	s, am := newTestServer(t, &pb.AgentInfo{
+		Id:              "agent-1",
+		MaxConcurrent:   5,
+		TaskDisplayInfo: []*pb.TaskDisplayInfo{{TaskName: "ping", RequiresTarget: true}},
+	})
+	s.scheduler = task.NewScheduler(am, 10)
+	sender := &shareSender{sent: make(chan *pb.Task, 1)}
+	s.scheduler.SetStreamSender(sender)
+	s.scheduler.SetHistoryRetention(time.Hour)
+	s.scheduler.SetShareLinkTTL(time.Minute)
+
+	ping := &pb.Task{
+		TaskId:   "t1",
+		AgentId:  "agent-1",
+		TaskName: "ping",
+		Params:   &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{Target: "1.1.1.1", Count: 1}},
+	}
+	if err := s.scheduler.SubmitTask(t.Context(), ping, "c1", func(*pb.TaskOutput) {}); err != nil {
+		t.Fatalf("SubmitTask() error = %v", err)
+	}
+	select {
+	case <-sender.sent:
+	case <-time.After(time.Second):
+		t.Fatal("task was not dispatched")
+	}
+	// Completion records the history entry before returning
+	s.scheduler.HandleTaskOutput(&pb.TaskOutput{TaskId: "t1", Status: pb.TaskStatus_TASK_STATUS_COMPLETED})
+	return s
+}
+
+func TestHandleCreateShareRequiresAdminToken(t *testing.T) {
+	s := newShareServer(t)
+	s.SetAdminToken("admin")
+
+	request := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/share?task_id=t1", nil)
+		if token != "" {
+			req.Header.Set("X-Admin-Token", token)
+		}
+		rec := httptest.NewRecorder()
+		s.HandleCreateShare(rec, req)
+		return rec
+	}
+
+	if rec := request(""); rec.Code != http.StatusForbidden {
+		t.Errorf("missing token: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := request("wrong"); rec.Code != http.StatusForbidden {
+		t.Errorf("wrong token: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := request("admin"); rec.Code != http.StatusOK {
+		t.Errorf("admin token: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestHandleShareOnlyForSubmitter(t *testing.T) {
+	s := newShareServer(t)
+
+	share := func(clientID string) *pb.WSResponse {
+		c := &Client{ID: clientID, server: s, send: make(chan interface{}, 1), done: make(chan struct{})}
+		c.handleShare(&pb.WSRequest{Action: pb.WSRequest_ACTION_SHARE, TaskId: "t1"})
+		return (<-c.send).(*pb.WSResponse)
+	}
+
+	resp := share("c1")
+	if resp.Type != pb.WSResponse_TYPE_SHARE_LINK || !strings.HasPrefix(resp.Message, "/share/") {
+		t.Fatalf("submitter: response = %v, want a share link", resp)
+	}
+	if resp.ExpiresAt == nil || !resp.ExpiresAt.AsTime().After(time.Now()) {
+		t.Errorf("expires_at = %v, want a future time", resp.ExpiresAt)
+	}
+
+	if resp := share("c2"); resp.Type != pb.WSResponse_TYPE_ERROR {
+		t.Errorf("other client: response = %v, want an error", resp)
+	}
+}
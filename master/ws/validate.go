@@ -0,0 +1,54 @@
+package ws
+
+import (
+	"fmt"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// InputLimits bounds the size of task requests accepted from clients
+// Zero values disable the corresponding check
+type InputLimits struct {
+	MaxTargetLength      int // Max length of NetworkTestParams.Target
+	MaxExtraOptions      int // Max number of ExtraOptions entries
+	MaxExtraOptionLength int // Max length of each ExtraOptions key and value
+}
+
+// ValidateTask applies the checks a WebSocket task request goes through (size limits and the
+// target agent's param schema), for other APIs that submit tasks
+func (s *Server) ValidateTask(task *pb.Task) error {
+	if err := s.inputLimits.validateTaskInput(task); err != nil {
+		return err
+	}
+	return s.validateTaskParams(task, nil)
+}
+
+// validateTaskInput rejects tasks whose parameters exceed the configured size limits
+func (l *InputLimits) validateTaskInput(task *pb.Task) error {
+	if l == nil {
+		return nil
+	}
+
+	params := task.GetNetworkTest()
+	if params == nil {
+		return nil
+	}
+
+	if l.MaxTargetLength > 0 && len(params.Target) > l.MaxTargetLength {
+		return fmt.Errorf("target too long: %d characters (max %d)", len(params.Target), l.MaxTargetLength)
+	}
+
+	if l.MaxExtraOptions > 0 && len(params.ExtraOptions) > l.MaxExtraOptions {
+		return fmt.Errorf("too many extra options: %d (max %d)", len(params.ExtraOptions), l.MaxExtraOptions)
+	}
+
+	if l.MaxExtraOptionLength > 0 {
+		for key, value := range params.ExtraOptions {
+			if len(key) > l.MaxExtraOptionLength || len(value) > l.MaxExtraOptionLength {
+				return fmt.Errorf("extra option too long (max %d characters)", l.MaxExtraOptionLength)
+			}
+		}
+	}
+
+	return nil
+}
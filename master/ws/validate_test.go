@@ -0,0 +1,113 @@
+package ws
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lureiny/lookingglass/master/agent"
+	pb "github.com/lureiny/lookingglass/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// newTestServer returns a WS server over a manager with the given stream agents online
+func newTestServer(t *testing.T, infos ...*pb.AgentInfo) (*Server, *agent.Manager) {
+	t.Helper()
+	am := agent.NewManager(time.Minute, time.Minute)
+	t.Cleanup(am.Stop)
+	for _, info := range infos {
+		if err := am.RegisterAgentFromStream(info); err != nil {
+			t.Fatalf("register %s: %v", info.Id, err)
+		}
+	}
+	return NewServer(am, nil, nil), am
+}
+
+func TestValidateTask(t *testing.T) {
+	s, _ := newTestServer(t, &pb.AgentInfo{
+		Id:            "agent-1",
+		MaxConcurrent: 5,
+		TaskDisplayInfo: []*pb.TaskDisplayInfo{{
+			TaskName: "ping",
+			Params: []*pb.ParamSchema{
+				{Name: "target", Type: "string", Required: true},
+				{Name: "count", Type: "int", Min: proto.Int64(1), Max: proto.Int64(10)},
+			},
+		}},
+	})
+	s.SetInputLimits(&InputLimits{MaxTargetLength: 16})
+
+	ping := func(target string, count int32) *pb.Task {
+		return &pb.Task{
+			AgentId:  "agent-1",
+			TaskName: "ping",
+			Params:   &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{Target: target, Count: count}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		task    *pb.Task
+		wantErr string
+	}{
+		{"valid", ping("192.0.2.1", 4), ""},
+		{"target too long", ping(strings.Repeat("a", 17), 4), "target too long"},
+		{"count over schema max", ping("192.0.2.1", 50), "count must be at most 10"},
+		{"missing required target", ping("", 4), "target is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.ValidateTask(tt.task)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateTask() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateTask() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTaskInput(t *testing.T) {
+	limits := &InputLimits{MaxTargetLength: 8, MaxExtraOptions: 2, MaxExtraOptionLength: 4}
+	withParams := func(target string, options map[string]string) *pb.Task {
+		return &pb.Task{
+			TaskName: "ping",
+			Params:   &pb.Task_NetworkTest{NetworkTest: &pb.NetworkTestParams{Target: target, ExtraOptions: options}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		limits  *InputLimits
+		task    *pb.Task
+		wantErr string
+	}{
+		{"within limits", limits, withParams("1.1.1.1", map[string]string{"a": "1", "b": "2"}), ""},
+		{"target at max", limits, withParams("12345678", nil), ""},
+		{"target too long", limits, withParams("123456789", nil), "target too long: 9 characters (max 8)"},
+		{"too many options", limits, withParams("x", map[string]string{"a": "1", "b": "2", "c": "3"}), "too many extra options: 3 (max 2)"},
+		{"option key too long", limits, withParams("x", map[string]string{"abcde": "1"}), "extra option too long"},
+		{"option value too long", limits, withParams("x", map[string]string{"a": "12345"}), "extra option too long"},
+		{"no params", limits, &pb.Task{TaskName: "ping"}, ""},
+		{"no limits", nil, withParams("123456789", map[string]string{"abcde": "12345"}), ""},
+		{"zero limits", &InputLimits{}, withParams("123456789", map[string]string{"abcde": "12345"}), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.validateTaskInput(tt.task)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateTaskInput() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateTaskInput() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
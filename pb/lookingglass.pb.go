@@ -243,15 +243,115 @@ func (AuthMode) EnumDescriptor() ([]byte, []int) {
 	return file_proto_lookingglass_proto_rawDescGZIP(), []int{3}
 }
 
+// Output format a WebSocket client wants task results in
+type OutputFormat int32
+
+const (
+	OutputFormat_OUTPUT_FORMAT_UNSPECIFIED OutputFormat = 0 // Client default (raw lines plus any agent summaries)
+	OutputFormat_OUTPUT_FORMAT_RAW         OutputFormat = 1 // Output lines only, no summaries
+	OutputFormat_OUTPUT_FORMAT_STRUCTURED  OutputFormat = 2 // Parsed summaries instead of output lines where the task can be parsed
+)
+
+// Enum value maps for OutputFormat.
+var (
+	OutputFormat_name = map[int32]string{
+		0: "OUTPUT_FORMAT_UNSPECIFIED",
+		1: "OUTPUT_FORMAT_RAW",
+		2: "OUTPUT_FORMAT_STRUCTURED",
+	}
+	OutputFormat_value = map[string]int32{
+		"OUTPUT_FORMAT_UNSPECIFIED": 0,
+		"OUTPUT_FORMAT_RAW":         1,
+		"OUTPUT_FORMAT_STRUCTURED":  2,
+	}
+)
+
+func (x OutputFormat) Enum() *OutputFormat {
+	p := new(OutputFormat)
+	*p = x
+	return p
+}
+
+func (x OutputFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OutputFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_lookingglass_proto_enumTypes[4].Descriptor()
+}
+
+func (OutputFormat) Type() protoreflect.EnumType {
+	return &file_proto_lookingglass_proto_enumTypes[4]
+}
+
+func (x OutputFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OutputFormat.Descriptor instead.
+func (OutputFormat) EnumDescriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{4}
+}
+
+// How the outputs of a multi-agent submission reach the client
+type BroadcastDelivery int32
+
+const (
+	BroadcastDelivery_BROADCAST_DELIVERY_INTERLEAVED BroadcastDelivery = 0 // Outputs of all agents as they arrive
+	BroadcastDelivery_BROADCAST_DELIVERY_GROUPED     BroadcastDelivery = 1 // Each agent's output as one block when that agent finishes (or on the flush timeout)
+)
+
+// Enum value maps for BroadcastDelivery.
+var (
+	BroadcastDelivery_name = map[int32]string{
+		0: "BROADCAST_DELIVERY_INTERLEAVED",
+		1: "BROADCAST_DELIVERY_GROUPED",
+	}
+	BroadcastDelivery_value = map[string]int32{
+		"BROADCAST_DELIVERY_INTERLEAVED": 0,
+		"BROADCAST_DELIVERY_GROUPED":     1,
+	}
+)
+
+func (x BroadcastDelivery) Enum() *BroadcastDelivery {
+	p := new(BroadcastDelivery)
+	*p = x
+	return p
+}
+
+func (x BroadcastDelivery) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (BroadcastDelivery) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_lookingglass_proto_enumTypes[5].Descriptor()
+}
+
+func (BroadcastDelivery) Type() protoreflect.EnumType {
+	return &file_proto_lookingglass_proto_enumTypes[5]
+}
+
+func (x BroadcastDelivery) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use BroadcastDelivery.Descriptor instead.
+func (BroadcastDelivery) EnumDescriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{5}
+}
+
 type AgentMessage_Type int32
 
 const (
-	AgentMessage_TYPE_UNSPECIFIED   AgentMessage_Type = 0
-	AgentMessage_TYPE_REGISTER      AgentMessage_Type = 1 // Agent registration
-	AgentMessage_TYPE_HEARTBEAT     AgentMessage_Type = 2 // Heartbeat
-	AgentMessage_TYPE_TASK_OUTPUT   AgentMessage_Type = 3 // Task execution output
-	AgentMessage_TYPE_TASK_COMPLETE AgentMessage_Type = 4 // Task completion
-	AgentMessage_TYPE_TASK_FAILED   AgentMessage_Type = 5 // Task failure
+	AgentMessage_TYPE_UNSPECIFIED       AgentMessage_Type = 0
+	AgentMessage_TYPE_REGISTER          AgentMessage_Type = 1 // Agent registration
+	AgentMessage_TYPE_HEARTBEAT         AgentMessage_Type = 2 // Heartbeat
+	AgentMessage_TYPE_TASK_OUTPUT       AgentMessage_Type = 3 // Task execution output
+	AgentMessage_TYPE_TASK_COMPLETE     AgentMessage_Type = 4 // Task completion
+	AgentMessage_TYPE_TASK_FAILED       AgentMessage_Type = 5 // Task failure
+	AgentMessage_TYPE_DESCRIBE_RESPONSE AgentMessage_Type = 6 // Effective config (response to TYPE_DESCRIBE)
+	AgentMessage_TYPE_PROBE_RESPONSE    AgentMessage_Type = 7 // Reply to TYPE_PROBE (no payload)
+	AgentMessage_TYPE_LOGS_RESPONSE     AgentMessage_Type = 8 // Recent log lines (response to TYPE_LOGS)
 )
 
 // Enum value maps for AgentMessage_Type.
@@ -263,14 +363,20 @@ var (
 		3: "TYPE_TASK_OUTPUT",
 		4: "TYPE_TASK_COMPLETE",
 		5: "TYPE_TASK_FAILED",
+		6: "TYPE_DESCRIBE_RESPONSE",
+		7: "TYPE_PROBE_RESPONSE",
+		8: "TYPE_LOGS_RESPONSE",
 	}
 	AgentMessage_Type_value = map[string]int32{
-		"TYPE_UNSPECIFIED":   0,
-		"TYPE_REGISTER":      1,
-		"TYPE_HEARTBEAT":     2,
-		"TYPE_TASK_OUTPUT":   3,
-		"TYPE_TASK_COMPLETE": 4,
-		"TYPE_TASK_FAILED":   5,
+		"TYPE_UNSPECIFIED":       0,
+		"TYPE_REGISTER":          1,
+		"TYPE_HEARTBEAT":         2,
+		"TYPE_TASK_OUTPUT":       3,
+		"TYPE_TASK_COMPLETE":     4,
+		"TYPE_TASK_FAILED":       5,
+		"TYPE_DESCRIBE_RESPONSE": 6,
+		"TYPE_PROBE_RESPONSE":    7,
+		"TYPE_LOGS_RESPONSE":     8,
 	}
 )
 
@@ -285,11 +391,11 @@ func (x AgentMessage_Type) String() string {
 }
 
 func (AgentMessage_Type) Descriptor() protoreflect.EnumDescriptor {
-	return file_proto_lookingglass_proto_enumTypes[4].Descriptor()
+	return file_proto_lookingglass_proto_enumTypes[6].Descriptor()
 }
 
 func (AgentMessage_Type) Type() protoreflect.EnumType {
-	return &file_proto_lookingglass_proto_enumTypes[4]
+	return &file_proto_lookingglass_proto_enumTypes[6]
 }
 
 func (x AgentMessage_Type) Number() protoreflect.EnumNumber {
@@ -298,7 +404,7 @@ func (x AgentMessage_Type) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use AgentMessage_Type.Descriptor instead.
 func (AgentMessage_Type) EnumDescriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{13, 0}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{26, 0}
 }
 
 type MasterMessage_Type int32
@@ -310,6 +416,9 @@ const (
 	MasterMessage_TYPE_EXECUTE_TASK       MasterMessage_Type = 3 // Execute task command
 	MasterMessage_TYPE_CANCEL_TASK        MasterMessage_Type = 4 // Cancel task command
 	MasterMessage_TYPE_ACK                MasterMessage_Type = 5 // Generic acknowledgment
+	MasterMessage_TYPE_DESCRIBE           MasterMessage_Type = 6 // Request the agent's effective config
+	MasterMessage_TYPE_PROBE              MasterMessage_Type = 7 // Stream health check before dispatch (no payload)
+	MasterMessage_TYPE_LOGS               MasterMessage_Type = 8 // Request the agent's recent log lines
 )
 
 // Enum value maps for MasterMessage_Type.
@@ -321,6 +430,9 @@ var (
 		3: "TYPE_EXECUTE_TASK",
 		4: "TYPE_CANCEL_TASK",
 		5: "TYPE_ACK",
+		6: "TYPE_DESCRIBE",
+		7: "TYPE_PROBE",
+		8: "TYPE_LOGS",
 	}
 	MasterMessage_Type_value = map[string]int32{
 		"TYPE_UNSPECIFIED":        0,
@@ -329,6 +441,9 @@ var (
 		"TYPE_EXECUTE_TASK":       3,
 		"TYPE_CANCEL_TASK":        4,
 		"TYPE_ACK":                5,
+		"TYPE_DESCRIBE":           6,
+		"TYPE_PROBE":              7,
+		"TYPE_LOGS":               8,
 	}
 )
 
@@ -343,11 +458,11 @@ func (x MasterMessage_Type) String() string {
 }
 
 func (MasterMessage_Type) Descriptor() protoreflect.EnumDescriptor {
-	return file_proto_lookingglass_proto_enumTypes[5].Descriptor()
+	return file_proto_lookingglass_proto_enumTypes[7].Descriptor()
 }
 
 func (MasterMessage_Type) Type() protoreflect.EnumType {
-	return &file_proto_lookingglass_proto_enumTypes[5]
+	return &file_proto_lookingglass_proto_enumTypes[7]
 }
 
 func (x MasterMessage_Type) Number() protoreflect.EnumNumber {
@@ -356,16 +471,20 @@ func (x MasterMessage_Type) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use MasterMessage_Type.Descriptor instead.
 func (MasterMessage_Type) EnumDescriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{14, 0}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{27, 0}
 }
 
 type WSRequest_Action int32
 
 const (
-	WSRequest_ACTION_UNSPECIFIED WSRequest_Action = 0
-	WSRequest_ACTION_EXECUTE     WSRequest_Action = 1
-	WSRequest_ACTION_CANCEL      WSRequest_Action = 2
-	WSRequest_ACTION_LIST_AGENTS WSRequest_Action = 3 // Request agent list
+	WSRequest_ACTION_UNSPECIFIED    WSRequest_Action = 0
+	WSRequest_ACTION_EXECUTE        WSRequest_Action = 1
+	WSRequest_ACTION_CANCEL         WSRequest_Action = 2
+	WSRequest_ACTION_LIST_AGENTS    WSRequest_Action = 3 // Request agent list
+	WSRequest_ACTION_CANCEL_ALL     WSRequest_Action = 4 // Admin: cancel every running task (requires confirm_token)
+	WSRequest_ACTION_LIST_TEMPLATES WSRequest_Action = 5 // Request the task template library
+	WSRequest_ACTION_AGENT_LOGS     WSRequest_Action = 6 // Request an agent's recent log lines (requires confirm_token)
+	WSRequest_ACTION_SHARE          WSRequest_Action = 7 // Create a share link to a finished task this connection submitted
 )
 
 // Enum value maps for WSRequest_Action.
@@ -375,12 +494,20 @@ var (
 		1: "ACTION_EXECUTE",
 		2: "ACTION_CANCEL",
 		3: "ACTION_LIST_AGENTS",
+		4: "ACTION_CANCEL_ALL",
+		5: "ACTION_LIST_TEMPLATES",
+		6: "ACTION_AGENT_LOGS",
+		7: "ACTION_SHARE",
 	}
 	WSRequest_Action_value = map[string]int32{
-		"ACTION_UNSPECIFIED": 0,
-		"ACTION_EXECUTE":     1,
-		"ACTION_CANCEL":      2,
-		"ACTION_LIST_AGENTS": 3,
+		"ACTION_UNSPECIFIED":    0,
+		"ACTION_EXECUTE":        1,
+		"ACTION_CANCEL":         2,
+		"ACTION_LIST_AGENTS":    3,
+		"ACTION_CANCEL_ALL":     4,
+		"ACTION_LIST_TEMPLATES": 5,
+		"ACTION_AGENT_LOGS":     6,
+		"ACTION_SHARE":          7,
 	}
 )
 
@@ -395,11 +522,11 @@ func (x WSRequest_Action) String() string {
 }
 
 func (WSRequest_Action) Descriptor() protoreflect.EnumDescriptor {
-	return file_proto_lookingglass_proto_enumTypes[6].Descriptor()
+	return file_proto_lookingglass_proto_enumTypes[8].Descriptor()
 }
 
 func (WSRequest_Action) Type() protoreflect.EnumType {
-	return &file_proto_lookingglass_proto_enumTypes[6]
+	return &file_proto_lookingglass_proto_enumTypes[8]
 }
 
 func (x WSRequest_Action) Number() protoreflect.EnumNumber {
@@ -408,7 +535,7 @@ func (x WSRequest_Action) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use WSRequest_Action.Descriptor instead.
 func (WSRequest_Action) EnumDescriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{20, 0}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{36, 0}
 }
 
 type WSResponse_Type int32
@@ -419,20 +546,28 @@ const (
 	WSResponse_TYPE_ERROR               WSResponse_Type = 2
 	WSResponse_TYPE_COMPLETE            WSResponse_Type = 3
 	WSResponse_TYPE_TASK_STARTED        WSResponse_Type = 4
-	WSResponse_TYPE_AGENT_LIST          WSResponse_Type = 5 // Agent list response
-	WSResponse_TYPE_AGENT_STATUS_UPDATE WSResponse_Type = 6 // Agent status update (server push)
+	WSResponse_TYPE_AGENT_LIST          WSResponse_Type = 5  // Agent list response
+	WSResponse_TYPE_AGENT_STATUS_UPDATE WSResponse_Type = 6  // Agent status update (server push)
+	WSResponse_TYPE_GROUP_COMPLETE      WSResponse_Type = 7  // Every child task of a fan-out group has finished (task_id is the group ID)
+	WSResponse_TYPE_TEMPLATE_LIST       WSResponse_Type = 8  // Task template library response
+	WSResponse_TYPE_AGENT_LOGS          WSResponse_Type = 9  // Agent log lines response
+	WSResponse_TYPE_SHARE_LINK          WSResponse_Type = 10 // Share link response: message is the /share/<token> path
 )
 
 // Enum value maps for WSResponse_Type.
 var (
 	WSResponse_Type_name = map[int32]string{
-		0: "TYPE_UNSPECIFIED",
-		1: "TYPE_OUTPUT",
-		2: "TYPE_ERROR",
-		3: "TYPE_COMPLETE",
-		4: "TYPE_TASK_STARTED",
-		5: "TYPE_AGENT_LIST",
-		6: "TYPE_AGENT_STATUS_UPDATE",
+		0:  "TYPE_UNSPECIFIED",
+		1:  "TYPE_OUTPUT",
+		2:  "TYPE_ERROR",
+		3:  "TYPE_COMPLETE",
+		4:  "TYPE_TASK_STARTED",
+		5:  "TYPE_AGENT_LIST",
+		6:  "TYPE_AGENT_STATUS_UPDATE",
+		7:  "TYPE_GROUP_COMPLETE",
+		8:  "TYPE_TEMPLATE_LIST",
+		9:  "TYPE_AGENT_LOGS",
+		10: "TYPE_SHARE_LINK",
 	}
 	WSResponse_Type_value = map[string]int32{
 		"TYPE_UNSPECIFIED":         0,
@@ -442,6 +577,10 @@ var (
 		"TYPE_TASK_STARTED":        4,
 		"TYPE_AGENT_LIST":          5,
 		"TYPE_AGENT_STATUS_UPDATE": 6,
+		"TYPE_GROUP_COMPLETE":      7,
+		"TYPE_TEMPLATE_LIST":       8,
+		"TYPE_AGENT_LOGS":          9,
+		"TYPE_SHARE_LINK":          10,
 	}
 )
 
@@ -456,11 +595,11 @@ func (x WSResponse_Type) String() string {
 }
 
 func (WSResponse_Type) Descriptor() protoreflect.EnumDescriptor {
-	return file_proto_lookingglass_proto_enumTypes[7].Descriptor()
+	return file_proto_lookingglass_proto_enumTypes[9].Descriptor()
 }
 
 func (WSResponse_Type) Type() protoreflect.EnumType {
-	return &file_proto_lookingglass_proto_enumTypes[7]
+	return &file_proto_lookingglass_proto_enumTypes[9]
 }
 
 func (x WSResponse_Type) Number() protoreflect.EnumNumber {
@@ -469,7 +608,7 @@ func (x WSResponse_Type) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use WSResponse_Type.Descriptor instead.
 func (WSResponse_Type) EnumDescriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{21, 0}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{37, 0}
 }
 
 // Task metadata for frontend display (used for both builtin and custom tasks)
@@ -479,6 +618,7 @@ type TaskDisplayInfo struct {
 	DisplayName    string                 `protobuf:"bytes,2,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`           // Display name for frontend (e.g., "Ping", "HTTP Check")
 	Description    string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`                              // Optional description
 	RequiresTarget bool                   `protobuf:"varint,4,opt,name=requires_target,json=requiresTarget,proto3" json:"requires_target,omitempty"` // Whether this task requires a target parameter (default: true)
+	Params         []*ParamSchema         `protobuf:"bytes,5,rep,name=params,proto3" json:"params,omitempty"`                                        // Accepted parameters (empty = not validated)
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
@@ -541,6 +681,98 @@ func (x *TaskDisplayInfo) GetRequiresTarget() bool {
 	return false
 }
 
+func (x *TaskDisplayInfo) GetParams() []*ParamSchema {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+// ParamSchema describes one task parameter, for input rendering and validation
+type ParamSchema struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`               // "target", "count", "timeout", "ipv6", or a NetworkTestParams.extra_options key
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`               // "string", "int" or "bool"
+	Required      bool                   `protobuf:"varint,3,opt,name=required,proto3" json:"required,omitempty"`      // Submissions without this parameter are rejected
+	Min           *int64                 `protobuf:"varint,4,opt,name=min,proto3,oneof" json:"min,omitempty"`          // Inclusive lower bound (int only)
+	Max           *int64                 `protobuf:"varint,5,opt,name=max,proto3,oneof" json:"max,omitempty"`          // Inclusive upper bound (int only)
+	Description   string                 `protobuf:"bytes,6,opt,name=description,proto3" json:"description,omitempty"` // Optional hint for the frontend
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ParamSchema) Reset() {
+	*x = ParamSchema{}
+	mi := &file_proto_lookingglass_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ParamSchema) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ParamSchema) ProtoMessage() {}
+
+func (x *ParamSchema) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ParamSchema.ProtoReflect.Descriptor instead.
+func (*ParamSchema) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ParamSchema) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ParamSchema) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ParamSchema) GetRequired() bool {
+	if x != nil {
+		return x.Required
+	}
+	return false
+}
+
+func (x *ParamSchema) GetMin() int64 {
+	if x != nil && x.Min != nil {
+		return *x.Min
+	}
+	return 0
+}
+
+func (x *ParamSchema) GetMax() int64 {
+	if x != nil && x.Max != nil {
+		return *x.Max
+	}
+	return 0
+}
+
+func (x *ParamSchema) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
 // Deprecated: Use TaskDisplayInfo instead
 type CustomCommandInfo struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -553,7 +785,7 @@ type CustomCommandInfo struct {
 
 func (x *CustomCommandInfo) Reset() {
 	*x = CustomCommandInfo{}
-	mi := &file_proto_lookingglass_proto_msgTypes[1]
+	mi := &file_proto_lookingglass_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -565,7 +797,7 @@ func (x *CustomCommandInfo) String() string {
 func (*CustomCommandInfo) ProtoMessage() {}
 
 func (x *CustomCommandInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[1]
+	mi := &file_proto_lookingglass_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -578,7 +810,7 @@ func (x *CustomCommandInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CustomCommandInfo.ProtoReflect.Descriptor instead.
 func (*CustomCommandInfo) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{1}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *CustomCommandInfo) GetTaskName() string {
@@ -619,13 +851,14 @@ type AgentInfo struct {
 	CustomCommands  []*CustomCommandInfo   `protobuf:"bytes,13,rep,name=custom_commands,json=customCommands,proto3" json:"custom_commands,omitempty"`                                   // [DEPRECATED] Use task_display_info instead
 	TaskNames       []string               `protobuf:"bytes,14,rep,name=task_names,json=taskNames,proto3" json:"task_names,omitempty"`                                                  // [DEPRECATED] Use task_display_info instead
 	TaskDisplayInfo []*TaskDisplayInfo     `protobuf:"bytes,15,rep,name=task_display_info,json=taskDisplayInfo,proto3" json:"task_display_info,omitempty"`                              // Task display information (name + display_name)
+	Tags            map[string]string      `protobuf:"bytes,16,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`   // Grouping labels (e.g., continent=asia, network=cn2)
 	unknownFields   protoimpl.UnknownFields
 	sizeCache       protoimpl.SizeCache
 }
 
 func (x *AgentInfo) Reset() {
 	*x = AgentInfo{}
-	mi := &file_proto_lookingglass_proto_msgTypes[2]
+	mi := &file_proto_lookingglass_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -637,7 +870,7 @@ func (x *AgentInfo) String() string {
 func (*AgentInfo) ProtoMessage() {}
 
 func (x *AgentInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[2]
+	mi := &file_proto_lookingglass_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -650,7 +883,7 @@ func (x *AgentInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AgentInfo.ProtoReflect.Descriptor instead.
 func (*AgentInfo) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{2}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *AgentInfo) GetId() string {
@@ -758,6 +991,13 @@ func (x *AgentInfo) GetTaskDisplayInfo() []*TaskDisplayInfo {
 	return nil
 }
 
+func (x *AgentInfo) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
 type AgentStatus_Message struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
@@ -770,7 +1010,7 @@ type AgentStatus_Message struct {
 
 func (x *AgentStatus_Message) Reset() {
 	*x = AgentStatus_Message{}
-	mi := &file_proto_lookingglass_proto_msgTypes[3]
+	mi := &file_proto_lookingglass_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -782,7 +1022,7 @@ func (x *AgentStatus_Message) String() string {
 func (*AgentStatus_Message) ProtoMessage() {}
 
 func (x *AgentStatus_Message) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[3]
+	mi := &file_proto_lookingglass_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -795,7 +1035,7 @@ func (x *AgentStatus_Message) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AgentStatus_Message.ProtoReflect.Descriptor instead.
 func (*AgentStatus_Message) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{3}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *AgentStatus_Message) GetAgentId() string {
@@ -841,7 +1081,7 @@ type NetworkTestParams struct {
 
 func (x *NetworkTestParams) Reset() {
 	*x = NetworkTestParams{}
-	mi := &file_proto_lookingglass_proto_msgTypes[4]
+	mi := &file_proto_lookingglass_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -853,7 +1093,7 @@ func (x *NetworkTestParams) String() string {
 func (*NetworkTestParams) ProtoMessage() {}
 
 func (x *NetworkTestParams) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[4]
+	mi := &file_proto_lookingglass_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -866,7 +1106,7 @@ func (x *NetworkTestParams) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NetworkTestParams.ProtoReflect.Descriptor instead.
 func (*NetworkTestParams) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{4}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *NetworkTestParams) GetTarget() string {
@@ -924,7 +1164,7 @@ type BenchmarkParams struct {
 
 func (x *BenchmarkParams) Reset() {
 	*x = BenchmarkParams{}
-	mi := &file_proto_lookingglass_proto_msgTypes[5]
+	mi := &file_proto_lookingglass_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -936,7 +1176,7 @@ func (x *BenchmarkParams) String() string {
 func (*BenchmarkParams) ProtoMessage() {}
 
 func (x *BenchmarkParams) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[5]
+	mi := &file_proto_lookingglass_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -949,7 +1189,7 @@ func (x *BenchmarkParams) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BenchmarkParams.ProtoReflect.Descriptor instead.
 func (*BenchmarkParams) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{5}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *BenchmarkParams) GetTestType() string {
@@ -991,7 +1231,7 @@ type CustomParams struct {
 
 func (x *CustomParams) Reset() {
 	*x = CustomParams{}
-	mi := &file_proto_lookingglass_proto_msgTypes[6]
+	mi := &file_proto_lookingglass_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1003,7 +1243,7 @@ func (x *CustomParams) String() string {
 func (*CustomParams) ProtoMessage() {}
 
 func (x *CustomParams) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[6]
+	mi := &file_proto_lookingglass_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1016,7 +1256,7 @@ func (x *CustomParams) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CustomParams.ProtoReflect.Descriptor instead.
 func (*CustomParams) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{6}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *CustomParams) GetRawData() []byte {
@@ -1035,13 +1275,16 @@ func (x *CustomParams) GetContentType() string {
 
 // Task definition
 type Task struct {
-	state     protoimpl.MessageState `protogen:"open.v1"`
-	TaskId    string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`           // Unique task identifier
-	AgentId   string                 `protobuf:"bytes,2,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`        // Target agent ID
-	TaskName  string                 `protobuf:"bytes,3,opt,name=task_name,json=taskName,proto3" json:"task_name,omitempty"`     // Task name (e.g., "ping", "mtr", "curl_test")
-	Type      TaskType               `protobuf:"varint,4,opt,name=type,proto3,enum=lookingglass.TaskType" json:"type,omitempty"` // [DEPRECATED] Task type enum - use task_name instead
-	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
-	Timeout   int32                  `protobuf:"varint,6,opt,name=timeout,proto3" json:"timeout,omitempty"` // Task timeout in seconds
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	TaskId         string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`           // Unique task identifier
+	AgentId        string                 `protobuf:"bytes,2,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`        // Target agent ID
+	TaskName       string                 `protobuf:"bytes,3,opt,name=task_name,json=taskName,proto3" json:"task_name,omitempty"`     // Task name (e.g., "ping", "mtr", "curl_test")
+	Type           TaskType               `protobuf:"varint,4,opt,name=type,proto3,enum=lookingglass.TaskType" json:"type,omitempty"` // [DEPRECATED] Task type enum - use task_name instead
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Timeout        int32                  `protobuf:"varint,6,opt,name=timeout,proto3" json:"timeout,omitempty"`                                     // Task timeout in seconds
+	Deadline       *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=deadline,proto3" json:"deadline,omitempty"`                                    // Absolute deadline (optional, takes precedence over timeout)
+	Retry          *RetryPolicy           `protobuf:"bytes,8,opt,name=retry,proto3" json:"retry,omitempty"`                                          // Automatic retry of failed attempts (optional, unset = no retry)
+	CompressOutput bool                   `protobuf:"varint,9,opt,name=compress_output,json=compressOutput,proto3" json:"compress_output,omitempty"` // Agent buffers the output and sends it in gzip blocks (at completion, or each 1 MiB of lines)
 	// Task parameters (oneof for type safety)
 	//
 	// Types that are valid to be assigned to Params:
@@ -1056,7 +1299,7 @@ type Task struct {
 
 func (x *Task) Reset() {
 	*x = Task{}
-	mi := &file_proto_lookingglass_proto_msgTypes[7]
+	mi := &file_proto_lookingglass_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1068,7 +1311,7 @@ func (x *Task) String() string {
 func (*Task) ProtoMessage() {}
 
 func (x *Task) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[7]
+	mi := &file_proto_lookingglass_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1081,7 +1324,7 @@ func (x *Task) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Task.ProtoReflect.Descriptor instead.
 func (*Task) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{7}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *Task) GetTaskId() string {
@@ -1126,6 +1369,27 @@ func (x *Task) GetTimeout() int32 {
 	return 0
 }
 
+func (x *Task) GetDeadline() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Deadline
+	}
+	return nil
+}
+
+func (x *Task) GetRetry() *RetryPolicy {
+	if x != nil {
+		return x.Retry
+	}
+	return nil
+}
+
+func (x *Task) GetCompressOutput() bool {
+	if x != nil {
+		return x.CompressOutput
+	}
+	return false
+}
+
 func (x *Task) GetParams() isTask_Params {
 	if x != nil {
 		return x.Params
@@ -1182,21 +1446,97 @@ func (*Task_Benchmark) isTask_Params() {}
 
 func (*Task_Custom) isTask_Params() {}
 
-// Task output (streamed from Agent to Master)
-type TaskOutput struct {
+// Retry policy for a task; retries go to the same agent
+type RetryPolicy struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
-	OutputLine    string                 `protobuf:"bytes,2,opt,name=output_line,json=outputLine,proto3" json:"output_line,omitempty"` // Single line of output
-	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	Status        TaskStatus             `protobuf:"varint,4,opt,name=status,proto3,enum=lookingglass.TaskStatus" json:"status,omitempty"`   // Current task status
-	ErrorMessage  string                 `protobuf:"bytes,5,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"` // Error message (if failed)
+	MaxAttempts   int32                  `protobuf:"varint,1,opt,name=max_attempts,json=maxAttempts,proto3" json:"max_attempts,omitempty"` // Total attempts including the first (capped by the master)
+	DelayMs       int32                  `protobuf:"varint,2,opt,name=delay_ms,json=delayMs,proto3" json:"delay_ms,omitempty"`             // Wait between attempts
+	RetryOn       []string               `protobuf:"bytes,3,rep,name=retry_on,json=retryOn,proto3" json:"retry_on,omitempty"`              // Failure kinds to retry: "dispatch", "agent" (empty = all)
+	OtherAgents   bool                   `protobuf:"varint,4,opt,name=other_agents,json=otherAgents,proto3" json:"other_agents,omitempty"` // Retry on the least-loaded agent in the original agent's region instead of the same agent
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
+func (x *RetryPolicy) Reset() {
+	*x = RetryPolicy{}
+	mi := &file_proto_lookingglass_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetryPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetryPolicy) ProtoMessage() {}
+
+func (x *RetryPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetryPolicy.ProtoReflect.Descriptor instead.
+func (*RetryPolicy) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *RetryPolicy) GetMaxAttempts() int32 {
+	if x != nil {
+		return x.MaxAttempts
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetDelayMs() int32 {
+	if x != nil {
+		return x.DelayMs
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetRetryOn() []string {
+	if x != nil {
+		return x.RetryOn
+	}
+	return nil
+}
+
+func (x *RetryPolicy) GetOtherAgents() bool {
+	if x != nil {
+		return x.OtherAgents
+	}
+	return false
+}
+
+// Task output (streamed from Agent to Master)
+type TaskOutput struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	TaskId           string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	OutputLine       string                 `protobuf:"bytes,2,opt,name=output_line,json=outputLine,proto3" json:"output_line,omitempty"` // Single line of output
+	Timestamp        *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Status           TaskStatus             `protobuf:"varint,4,opt,name=status,proto3,enum=lookingglass.TaskStatus" json:"status,omitempty"`                // Current task status
+	ErrorMessage     string                 `protobuf:"bytes,5,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`              // Error message (if failed)
+	Summary          *TaskSummary           `protobuf:"bytes,6,opt,name=summary,proto3" json:"summary,omitempty"`                                            // Structured result summary (optional, usually sent near completion)
+	Sequence         uint64                 `protobuf:"varint,7,opt,name=sequence,proto3" json:"sequence,omitempty"`                                         // Per-task output number set by the agent, from 1 (0 = unsequenced, e.g. master-generated); gaps mean dropped output
+	AgentId          string                 `protobuf:"bytes,8,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`                             // Set by the master on outputs of fan-out child tasks and of tasks retried on another agent
+	GroupId          string                 `protobuf:"bytes,9,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`                             // Fan-out group ID set by the master; the group's final output has task_id == group_id
+	Cached           bool                   `protobuf:"varint,10,opt,name=cached,proto3" json:"cached,omitempty"`                                            // Replayed by the master from the result of a recent identical task
+	CompressedOutput []byte                 `protobuf:"bytes,11,opt,name=compressed_output,json=compressedOutput,proto3" json:"compressed_output,omitempty"` // Gzip of output lines joined by "\n" (compress_output tasks); expanded by the master
+	AgentFault       bool                   `protobuf:"varint,12,opt,name=agent_fault,json=agentFault,proto3" json:"agent_fault,omitempty"`                  // Failure caused by the agent itself (e.g. a task binary would not start), not by the task
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
 func (x *TaskOutput) Reset() {
 	*x = TaskOutput{}
-	mi := &file_proto_lookingglass_proto_msgTypes[8]
+	mi := &file_proto_lookingglass_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1208,7 +1548,7 @@ func (x *TaskOutput) String() string {
 func (*TaskOutput) ProtoMessage() {}
 
 func (x *TaskOutput) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[8]
+	mi := &file_proto_lookingglass_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1221,7 +1561,7 @@ func (x *TaskOutput) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TaskOutput.ProtoReflect.Descriptor instead.
 func (*TaskOutput) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{8}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *TaskOutput) GetTaskId() string {
@@ -1259,8 +1599,487 @@ func (x *TaskOutput) GetErrorMessage() string {
 	return ""
 }
 
-// Register request
-type RegisterRequest struct {
+func (x *TaskOutput) GetSummary() *TaskSummary {
+	if x != nil {
+		return x.Summary
+	}
+	return nil
+}
+
+func (x *TaskOutput) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *TaskOutput) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *TaskOutput) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *TaskOutput) GetCached() bool {
+	if x != nil {
+		return x.Cached
+	}
+	return false
+}
+
+func (x *TaskOutput) GetCompressedOutput() []byte {
+	if x != nil {
+		return x.CompressedOutput
+	}
+	return nil
+}
+
+func (x *TaskOutput) GetAgentFault() bool {
+	if x != nil {
+		return x.AgentFault
+	}
+	return false
+}
+
+// Structured task result summary
+type TaskSummary struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	TraceHops         []*TraceHop            `protobuf:"bytes,1,rep,name=trace_hops,json=traceHops,proto3" json:"trace_hops,omitempty"`                            // Per-hop route data (nexttrace JSON mode)
+	ResolvedIps       []string               `protobuf:"bytes,2,rep,name=resolved_ips,json=resolvedIps,proto3" json:"resolved_ips,omitempty"`                      // IPs the target hostname resolved to on the agent
+	DispatchLatencyMs int64                  `protobuf:"varint,3,opt,name=dispatch_latency_ms,json=dispatchLatencyMs,proto3" json:"dispatch_latency_ms,omitempty"` // Master -> agent -> master round trip of task dispatch (set by master)
+	AgentStartTime    string                 `protobuf:"bytes,4,opt,name=agent_start_time,json=agentStartTime,proto3" json:"agent_start_time,omitempty"`           // When the task started, in the agent's local time (RFC3339 with offset)
+	AgentTimezone     string                 `protobuf:"bytes,5,opt,name=agent_timezone,json=agentTimezone,proto3" json:"agent_timezone,omitempty"`                // Agent's local time zone name (e.g. "JST")
+	PingStats         []*PingStats           `protobuf:"bytes,6,rep,name=ping_stats,json=pingStats,proto3" json:"ping_stats,omitempty"`                            // Per-target ping statistics (parsed by the master for structured clients)
+	BytesTransferred  int64                  `protobuf:"varint,7,opt,name=bytes_transferred,json=bytesTransferred,proto3" json:"bytes_transferred,omitempty"`      // Output line bytes the agent sent for the task (set by master)
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *TaskSummary) Reset() {
+	*x = TaskSummary{}
+	mi := &file_proto_lookingglass_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskSummary) ProtoMessage() {}
+
+func (x *TaskSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskSummary.ProtoReflect.Descriptor instead.
+func (*TaskSummary) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *TaskSummary) GetTraceHops() []*TraceHop {
+	if x != nil {
+		return x.TraceHops
+	}
+	return nil
+}
+
+func (x *TaskSummary) GetResolvedIps() []string {
+	if x != nil {
+		return x.ResolvedIps
+	}
+	return nil
+}
+
+func (x *TaskSummary) GetDispatchLatencyMs() int64 {
+	if x != nil {
+		return x.DispatchLatencyMs
+	}
+	return 0
+}
+
+func (x *TaskSummary) GetAgentStartTime() string {
+	if x != nil {
+		return x.AgentStartTime
+	}
+	return ""
+}
+
+func (x *TaskSummary) GetAgentTimezone() string {
+	if x != nil {
+		return x.AgentTimezone
+	}
+	return ""
+}
+
+func (x *TaskSummary) GetPingStats() []*PingStats {
+	if x != nil {
+		return x.PingStats
+	}
+	return nil
+}
+
+func (x *TaskSummary) GetBytesTransferred() int64 {
+	if x != nil {
+		return x.BytesTransferred
+	}
+	return 0
+}
+
+// Statistics of a ping run against one target
+type PingStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        string                 `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Transmitted   int32                  `protobuf:"varint,2,opt,name=transmitted,proto3" json:"transmitted,omitempty"`
+	Received      int32                  `protobuf:"varint,3,opt,name=received,proto3" json:"received,omitempty"`
+	LossPercent   float64                `protobuf:"fixed64,4,opt,name=loss_percent,json=lossPercent,proto3" json:"loss_percent,omitempty"`
+	RttMinMs      float64                `protobuf:"fixed64,5,opt,name=rtt_min_ms,json=rttMinMs,proto3" json:"rtt_min_ms,omitempty"`
+	RttAvgMs      float64                `protobuf:"fixed64,6,opt,name=rtt_avg_ms,json=rttAvgMs,proto3" json:"rtt_avg_ms,omitempty"`
+	RttMaxMs      float64                `protobuf:"fixed64,7,opt,name=rtt_max_ms,json=rttMaxMs,proto3" json:"rtt_max_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingStats) Reset() {
+	*x = PingStats{}
+	mi := &file_proto_lookingglass_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingStats) ProtoMessage() {}
+
+func (x *PingStats) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingStats.ProtoReflect.Descriptor instead.
+func (*PingStats) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PingStats) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *PingStats) GetTransmitted() int32 {
+	if x != nil {
+		return x.Transmitted
+	}
+	return 0
+}
+
+func (x *PingStats) GetReceived() int32 {
+	if x != nil {
+		return x.Received
+	}
+	return 0
+}
+
+func (x *PingStats) GetLossPercent() float64 {
+	if x != nil {
+		return x.LossPercent
+	}
+	return 0
+}
+
+func (x *PingStats) GetRttMinMs() float64 {
+	if x != nil {
+		return x.RttMinMs
+	}
+	return 0
+}
+
+func (x *PingStats) GetRttAvgMs() float64 {
+	if x != nil {
+		return x.RttAvgMs
+	}
+	return 0
+}
+
+func (x *PingStats) GetRttMaxMs() float64 {
+	if x != nil {
+		return x.RttMaxMs
+	}
+	return 0
+}
+
+// Single hop of a route trace
+type TraceHop struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Ttl            int32                  `protobuf:"varint,1,opt,name=ttl,proto3" json:"ttl,omitempty"`                                             // Hop number
+	Ip             string                 `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`                                                // Responding IP (empty if no response)
+	Hostname       string                 `protobuf:"bytes,3,opt,name=hostname,proto3" json:"hostname,omitempty"`                                    // Reverse DNS hostname
+	Asn            string                 `protobuf:"bytes,4,opt,name=asn,proto3" json:"asn,omitempty"`                                              // Autonomous system number
+	Geo            string                 `protobuf:"bytes,5,opt,name=geo,proto3" json:"geo,omitempty"`                                              // Human-readable location (country/region/city)
+	Owner          string                 `protobuf:"bytes,6,opt,name=owner,proto3" json:"owner,omitempty"`                                          // Network owner / ISP
+	RttMs          float64                `protobuf:"fixed64,7,opt,name=rtt_ms,json=rttMs,proto3" json:"rtt_ms,omitempty"`                           // Average RTT of successful probes in milliseconds
+	ProbesSent     int32                  `protobuf:"varint,8,opt,name=probes_sent,json=probesSent,proto3" json:"probes_sent,omitempty"`             // Number of probes sent for this hop
+	ProbesReceived int32                  `protobuf:"varint,9,opt,name=probes_received,json=probesReceived,proto3" json:"probes_received,omitempty"` // Number of probes that got a response
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *TraceHop) Reset() {
+	*x = TraceHop{}
+	mi := &file_proto_lookingglass_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TraceHop) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TraceHop) ProtoMessage() {}
+
+func (x *TraceHop) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TraceHop.ProtoReflect.Descriptor instead.
+func (*TraceHop) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *TraceHop) GetTtl() int32 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *TraceHop) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *TraceHop) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *TraceHop) GetAsn() string {
+	if x != nil {
+		return x.Asn
+	}
+	return ""
+}
+
+func (x *TraceHop) GetGeo() string {
+	if x != nil {
+		return x.Geo
+	}
+	return ""
+}
+
+func (x *TraceHop) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *TraceHop) GetRttMs() float64 {
+	if x != nil {
+		return x.RttMs
+	}
+	return 0
+}
+
+func (x *TraceHop) GetProbesSent() int32 {
+	if x != nil {
+		return x.ProbesSent
+	}
+	return 0
+}
+
+func (x *TraceHop) GetProbesReceived() int32 {
+	if x != nil {
+		return x.ProbesReceived
+	}
+	return 0
+}
+
+// List agents request
+type ListAgentsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OnlineOnly    bool                   `protobuf:"varint,1,opt,name=online_only,json=onlineOnly,proto3" json:"online_only,omitempty"` // Only return online agents
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAgentsRequest) Reset() {
+	*x = ListAgentsRequest{}
+	mi := &file_proto_lookingglass_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAgentsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAgentsRequest) ProtoMessage() {}
+
+func (x *ListAgentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAgentsRequest.ProtoReflect.Descriptor instead.
+func (*ListAgentsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListAgentsRequest) GetOnlineOnly() bool {
+	if x != nil {
+		return x.OnlineOnly
+	}
+	return false
+}
+
+// List agents response
+type ListAgentsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Agents        []*AgentStatusInfo     `protobuf:"bytes,1,rep,name=agents,proto3" json:"agents,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAgentsResponse) Reset() {
+	*x = ListAgentsResponse{}
+	mi := &file_proto_lookingglass_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAgentsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAgentsResponse) ProtoMessage() {}
+
+func (x *ListAgentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAgentsResponse.ProtoReflect.Descriptor instead.
+func (*ListAgentsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ListAgentsResponse) GetAgents() []*AgentStatusInfo {
+	if x != nil {
+		return x.Agents
+	}
+	return nil
+}
+
+// Get agent detail request
+type GetAgentDetailRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAgentDetailRequest) Reset() {
+	*x = GetAgentDetailRequest{}
+	mi := &file_proto_lookingglass_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAgentDetailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAgentDetailRequest) ProtoMessage() {}
+
+func (x *GetAgentDetailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAgentDetailRequest.ProtoReflect.Descriptor instead.
+func (*GetAgentDetailRequest) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetAgentDetailRequest) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+// Register request
+type RegisterRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	AgentInfo     *AgentInfo             `protobuf:"bytes,1,opt,name=agent_info,json=agentInfo,proto3" json:"agent_info,omitempty"`
 	unknownFields protoimpl.UnknownFields
@@ -1269,7 +2088,7 @@ type RegisterRequest struct {
 
 func (x *RegisterRequest) Reset() {
 	*x = RegisterRequest{}
-	mi := &file_proto_lookingglass_proto_msgTypes[9]
+	mi := &file_proto_lookingglass_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1281,7 +2100,7 @@ func (x *RegisterRequest) String() string {
 func (*RegisterRequest) ProtoMessage() {}
 
 func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[9]
+	mi := &file_proto_lookingglass_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1294,7 +2113,7 @@ func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RegisterRequest.ProtoReflect.Descriptor instead.
 func (*RegisterRequest) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{9}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *RegisterRequest) GetAgentInfo() *AgentInfo {
@@ -1314,21 +2133,310 @@ type RegisterResponse struct {
 	sizeCache         protoimpl.SizeCache
 }
 
-func (x *RegisterResponse) Reset() {
-	*x = RegisterResponse{}
-	mi := &file_proto_lookingglass_proto_msgTypes[10]
+func (x *RegisterResponse) Reset() {
+	*x = RegisterResponse{}
+	mi := &file_proto_lookingglass_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterResponse) ProtoMessage() {}
+
+func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterResponse.ProtoReflect.Descriptor instead.
+func (*RegisterResponse) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *RegisterResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RegisterResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *RegisterResponse) GetHeartbeatInterval() int32 {
+	if x != nil {
+		return x.HeartbeatInterval
+	}
+	return 0
+}
+
+// Heartbeat request
+type HeartbeatRequest struct {
+	state           protoimpl.MessageState      `protogen:"open.v1"`
+	AgentId         string                      `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	CurrentTasks    int32                       `protobuf:"varint,2,opt,name=current_tasks,json=currentTasks,proto3" json:"current_tasks,omitempty"` // Number of currently running tasks
+	Timestamp       *timestamppb.Timestamp      `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	MemPercent      float64                     `protobuf:"fixed64,4,opt,name=mem_percent,json=memPercent,proto3" json:"mem_percent,omitempty"`                                                                                        // Memory usage percent (0 if not reported)
+	DiskPercent     float64                     `protobuf:"fixed64,5,opt,name=disk_percent,json=diskPercent,proto3" json:"disk_percent,omitempty"`                                                                                     // Disk usage percent (0 if not reported)
+	TaskConcurrency map[string]*TaskConcurrency `protobuf:"bytes,6,rep,name=task_concurrency,json=taskConcurrency,proto3" json:"task_concurrency,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Per-task-name utilization (tasks with a concurrency limit)
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *HeartbeatRequest) Reset() {
+	*x = HeartbeatRequest{}
+	mi := &file_proto_lookingglass_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatRequest) ProtoMessage() {}
+
+func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *HeartbeatRequest) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetCurrentTasks() int32 {
+	if x != nil {
+		return x.CurrentTasks
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetMemPercent() float64 {
+	if x != nil {
+		return x.MemPercent
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetDiskPercent() float64 {
+	if x != nil {
+		return x.DiskPercent
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetTaskConcurrency() map[string]*TaskConcurrency {
+	if x != nil {
+		return x.TaskConcurrency
+	}
+	return nil
+}
+
+// Per-task concurrency utilization
+type TaskConcurrency struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Current       int32                  `protobuf:"varint,1,opt,name=current,proto3" json:"current,omitempty"` // Running tasks of this name
+	Max           int32                  `protobuf:"varint,2,opt,name=max,proto3" json:"max,omitempty"`         // Concurrency limit for this task name
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskConcurrency) Reset() {
+	*x = TaskConcurrency{}
+	mi := &file_proto_lookingglass_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskConcurrency) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskConcurrency) ProtoMessage() {}
+
+func (x *TaskConcurrency) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskConcurrency.ProtoReflect.Descriptor instead.
+func (*TaskConcurrency) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *TaskConcurrency) GetCurrent() int32 {
+	if x != nil {
+		return x.Current
+	}
+	return 0
+}
+
+func (x *TaskConcurrency) GetMax() int32 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+// Heartbeat response
+type HeartbeatResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
+	mi := &file_proto_lookingglass_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatResponse) ProtoMessage() {}
+
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *HeartbeatResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *HeartbeatResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Describe request (master asks an agent for its effective configuration)
+type DescribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DescribeRequest) Reset() {
+	*x = DescribeRequest{}
+	mi := &file_proto_lookingglass_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DescribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DescribeRequest) ProtoMessage() {}
+
+func (x *DescribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DescribeRequest.ProtoReflect.Descriptor instead.
+func (*DescribeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{22}
+}
+
+// Describe response
+type DescribeResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	AgentId         string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	EffectiveConfig string                 `protobuf:"bytes,2,opt,name=effective_config,json=effectiveConfig,proto3" json:"effective_config,omitempty"` // JSON of the agent's config after defaults/merging, secrets redacted
+	Error           string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`                                            // Set if the config could not be produced
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *DescribeResponse) Reset() {
+	*x = DescribeResponse{}
+	mi := &file_proto_lookingglass_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RegisterResponse) String() string {
+func (x *DescribeResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterResponse) ProtoMessage() {}
+func (*DescribeResponse) ProtoMessage() {}
 
-func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[10]
+func (x *DescribeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1339,57 +2447,55 @@ func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterResponse.ProtoReflect.Descriptor instead.
-func (*RegisterResponse) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use DescribeResponse.ProtoReflect.Descriptor instead.
+func (*DescribeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{23}
 }
 
-func (x *RegisterResponse) GetSuccess() bool {
+func (x *DescribeResponse) GetAgentId() string {
 	if x != nil {
-		return x.Success
+		return x.AgentId
 	}
-	return false
+	return ""
 }
 
-func (x *RegisterResponse) GetMessage() string {
+func (x *DescribeResponse) GetEffectiveConfig() string {
 	if x != nil {
-		return x.Message
+		return x.EffectiveConfig
 	}
 	return ""
 }
 
-func (x *RegisterResponse) GetHeartbeatInterval() int32 {
+func (x *DescribeResponse) GetError() string {
 	if x != nil {
-		return x.HeartbeatInterval
+		return x.Error
 	}
-	return 0
+	return ""
 }
 
-// Heartbeat request
-type HeartbeatRequest struct {
+// Logs request (master asks an agent for its most recent log lines)
+type LogsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
-	CurrentTasks  int32                  `protobuf:"varint,2,opt,name=current_tasks,json=currentTasks,proto3" json:"current_tasks,omitempty"` // Number of currently running tasks
-	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Lines         int32                  `protobuf:"varint,1,opt,name=lines,proto3" json:"lines,omitempty"` // Number of lines wanted (0 = agent default)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *HeartbeatRequest) Reset() {
-	*x = HeartbeatRequest{}
-	mi := &file_proto_lookingglass_proto_msgTypes[11]
+func (x *LogsRequest) Reset() {
+	*x = LogsRequest{}
+	mi := &file_proto_lookingglass_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HeartbeatRequest) String() string {
+func (x *LogsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HeartbeatRequest) ProtoMessage() {}
+func (*LogsRequest) ProtoMessage() {}
 
-func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[11]
+func (x *LogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1400,56 +2506,43 @@ func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
-func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{11}
-}
-
-func (x *HeartbeatRequest) GetAgentId() string {
-	if x != nil {
-		return x.AgentId
-	}
-	return ""
+// Deprecated: Use LogsRequest.ProtoReflect.Descriptor instead.
+func (*LogsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{24}
 }
 
-func (x *HeartbeatRequest) GetCurrentTasks() int32 {
+func (x *LogsRequest) GetLines() int32 {
 	if x != nil {
-		return x.CurrentTasks
+		return x.Lines
 	}
 	return 0
 }
 
-func (x *HeartbeatRequest) GetTimestamp() *timestamppb.Timestamp {
-	if x != nil {
-		return x.Timestamp
-	}
-	return nil
-}
-
-// Heartbeat response
-type HeartbeatResponse struct {
+// Logs response
+type LogsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Lines         []string               `protobuf:"bytes,2,rep,name=lines,proto3" json:"lines,omitempty"` // Most recent log lines, oldest first
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"` // Set if the agent keeps no log buffer
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *HeartbeatResponse) Reset() {
-	*x = HeartbeatResponse{}
-	mi := &file_proto_lookingglass_proto_msgTypes[12]
+func (x *LogsResponse) Reset() {
+	*x = LogsResponse{}
+	mi := &file_proto_lookingglass_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HeartbeatResponse) String() string {
+func (x *LogsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HeartbeatResponse) ProtoMessage() {}
+func (*LogsResponse) ProtoMessage() {}
 
-func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[12]
+func (x *LogsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1460,21 +2553,28 @@ func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
-func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use LogsResponse.ProtoReflect.Descriptor instead.
+func (*LogsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{25}
 }
 
-func (x *HeartbeatResponse) GetSuccess() bool {
+func (x *LogsResponse) GetAgentId() string {
 	if x != nil {
-		return x.Success
+		return x.AgentId
 	}
-	return false
+	return ""
 }
 
-func (x *HeartbeatResponse) GetMessage() string {
+func (x *LogsResponse) GetLines() []string {
 	if x != nil {
-		return x.Message
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *LogsResponse) GetError() string {
+	if x != nil {
+		return x.Error
 	}
 	return ""
 }
@@ -1491,6 +2591,8 @@ type AgentMessage struct {
 	//	*AgentMessage_Register
 	//	*AgentMessage_Heartbeat
 	//	*AgentMessage_TaskOutput
+	//	*AgentMessage_DescribeResponse
+	//	*AgentMessage_LogsResponse
 	Payload       isAgentMessage_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -1498,7 +2600,7 @@ type AgentMessage struct {
 
 func (x *AgentMessage) Reset() {
 	*x = AgentMessage{}
-	mi := &file_proto_lookingglass_proto_msgTypes[13]
+	mi := &file_proto_lookingglass_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1510,7 +2612,7 @@ func (x *AgentMessage) String() string {
 func (*AgentMessage) ProtoMessage() {}
 
 func (x *AgentMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[13]
+	mi := &file_proto_lookingglass_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1523,7 +2625,7 @@ func (x *AgentMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AgentMessage.ProtoReflect.Descriptor instead.
 func (*AgentMessage) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{13}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *AgentMessage) GetRequestId() string {
@@ -1574,6 +2676,24 @@ func (x *AgentMessage) GetTaskOutput() *TaskOutput {
 	return nil
 }
 
+func (x *AgentMessage) GetDescribeResponse() *DescribeResponse {
+	if x != nil {
+		if x, ok := x.Payload.(*AgentMessage_DescribeResponse); ok {
+			return x.DescribeResponse
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetLogsResponse() *LogsResponse {
+	if x != nil {
+		if x, ok := x.Payload.(*AgentMessage_LogsResponse); ok {
+			return x.LogsResponse
+		}
+	}
+	return nil
+}
+
 type isAgentMessage_Payload interface {
 	isAgentMessage_Payload()
 }
@@ -1590,12 +2710,24 @@ type AgentMessage_TaskOutput struct {
 	TaskOutput *TaskOutput `protobuf:"bytes,12,opt,name=task_output,json=taskOutput,proto3,oneof"`
 }
 
+type AgentMessage_DescribeResponse struct {
+	DescribeResponse *DescribeResponse `protobuf:"bytes,13,opt,name=describe_response,json=describeResponse,proto3,oneof"`
+}
+
+type AgentMessage_LogsResponse struct {
+	LogsResponse *LogsResponse `protobuf:"bytes,14,opt,name=logs_response,json=logsResponse,proto3,oneof"`
+}
+
 func (*AgentMessage_Register) isAgentMessage_Payload() {}
 
 func (*AgentMessage_Heartbeat) isAgentMessage_Payload() {}
 
 func (*AgentMessage_TaskOutput) isAgentMessage_Payload() {}
 
+func (*AgentMessage_DescribeResponse) isAgentMessage_Payload() {}
+
+func (*AgentMessage_LogsResponse) isAgentMessage_Payload() {}
+
 // Master -> Agent message
 type MasterMessage struct {
 	state     protoimpl.MessageState `protogen:"open.v1"`
@@ -1609,6 +2741,8 @@ type MasterMessage struct {
 	//	*MasterMessage_HeartbeatResponse
 	//	*MasterMessage_ExecuteTask
 	//	*MasterMessage_CancelTask
+	//	*MasterMessage_Describe
+	//	*MasterMessage_Logs
 	Payload       isMasterMessage_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -1616,7 +2750,7 @@ type MasterMessage struct {
 
 func (x *MasterMessage) Reset() {
 	*x = MasterMessage{}
-	mi := &file_proto_lookingglass_proto_msgTypes[14]
+	mi := &file_proto_lookingglass_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1628,7 +2762,7 @@ func (x *MasterMessage) String() string {
 func (*MasterMessage) ProtoMessage() {}
 
 func (x *MasterMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[14]
+	mi := &file_proto_lookingglass_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1641,7 +2775,7 @@ func (x *MasterMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MasterMessage.ProtoReflect.Descriptor instead.
 func (*MasterMessage) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{14}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{27}
 }
 
 func (x *MasterMessage) GetRequestId() string {
@@ -1701,6 +2835,24 @@ func (x *MasterMessage) GetCancelTask() *CancelTaskRequest {
 	return nil
 }
 
+func (x *MasterMessage) GetDescribe() *DescribeRequest {
+	if x != nil {
+		if x, ok := x.Payload.(*MasterMessage_Describe); ok {
+			return x.Describe
+		}
+	}
+	return nil
+}
+
+func (x *MasterMessage) GetLogs() *LogsRequest {
+	if x != nil {
+		if x, ok := x.Payload.(*MasterMessage_Logs); ok {
+			return x.Logs
+		}
+	}
+	return nil
+}
+
 type isMasterMessage_Payload interface {
 	isMasterMessage_Payload()
 }
@@ -1721,6 +2873,14 @@ type MasterMessage_CancelTask struct {
 	CancelTask *CancelTaskRequest `protobuf:"bytes,13,opt,name=cancel_task,json=cancelTask,proto3,oneof"`
 }
 
+type MasterMessage_Describe struct {
+	Describe *DescribeRequest `protobuf:"bytes,14,opt,name=describe,proto3,oneof"`
+}
+
+type MasterMessage_Logs struct {
+	Logs *LogsRequest `protobuf:"bytes,15,opt,name=logs,proto3,oneof"`
+}
+
 func (*MasterMessage_RegisterResponse) isMasterMessage_Payload() {}
 
 func (*MasterMessage_HeartbeatResponse) isMasterMessage_Payload() {}
@@ -1729,6 +2889,101 @@ func (*MasterMessage_ExecuteTask) isMasterMessage_Payload() {}
 
 func (*MasterMessage_CancelTask) isMasterMessage_Payload() {}
 
+func (*MasterMessage_Describe) isMasterMessage_Payload() {}
+
+func (*MasterMessage_Logs) isMasterMessage_Payload() {}
+
+// Agent -> Master batch in poll mode (POST /agent/poll, binary protobuf)
+// Carries the messages the agent would otherwise send on its stream
+type PollRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*AgentMessage        `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PollRequest) Reset() {
+	*x = PollRequest{}
+	mi := &file_proto_lookingglass_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PollRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PollRequest) ProtoMessage() {}
+
+func (x *PollRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PollRequest.ProtoReflect.Descriptor instead.
+func (*PollRequest) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *PollRequest) GetMessages() []*AgentMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+// Master -> Agent batch in poll mode: the messages queued for the agent since its last poll
+type PollResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*MasterMessage       `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PollResponse) Reset() {
+	*x = PollResponse{}
+	mi := &file_proto_lookingglass_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PollResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PollResponse) ProtoMessage() {}
+
+func (x *PollResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PollResponse.ProtoReflect.Descriptor instead.
+func (*PollResponse) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *PollResponse) GetMessages() []*MasterMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
 // Execute task request
 type ExecuteTaskRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1739,7 +2994,7 @@ type ExecuteTaskRequest struct {
 
 func (x *ExecuteTaskRequest) Reset() {
 	*x = ExecuteTaskRequest{}
-	mi := &file_proto_lookingglass_proto_msgTypes[15]
+	mi := &file_proto_lookingglass_proto_msgTypes[30]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1751,7 +3006,7 @@ func (x *ExecuteTaskRequest) String() string {
 func (*ExecuteTaskRequest) ProtoMessage() {}
 
 func (x *ExecuteTaskRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[15]
+	mi := &file_proto_lookingglass_proto_msgTypes[30]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1764,7 +3019,7 @@ func (x *ExecuteTaskRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExecuteTaskRequest.ProtoReflect.Descriptor instead.
 func (*ExecuteTaskRequest) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{15}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{30}
 }
 
 func (x *ExecuteTaskRequest) GetTask() *Task {
@@ -1784,7 +3039,7 @@ type CancelTaskRequest struct {
 
 func (x *CancelTaskRequest) Reset() {
 	*x = CancelTaskRequest{}
-	mi := &file_proto_lookingglass_proto_msgTypes[16]
+	mi := &file_proto_lookingglass_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1796,7 +3051,7 @@ func (x *CancelTaskRequest) String() string {
 func (*CancelTaskRequest) ProtoMessage() {}
 
 func (x *CancelTaskRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[16]
+	mi := &file_proto_lookingglass_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1809,7 +3064,7 @@ func (x *CancelTaskRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CancelTaskRequest.ProtoReflect.Descriptor instead.
 func (*CancelTaskRequest) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{16}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{31}
 }
 
 func (x *CancelTaskRequest) GetTaskId() string {
@@ -1830,7 +3085,7 @@ type CancelTaskResponse struct {
 
 func (x *CancelTaskResponse) Reset() {
 	*x = CancelTaskResponse{}
-	mi := &file_proto_lookingglass_proto_msgTypes[17]
+	mi := &file_proto_lookingglass_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1842,7 +3097,7 @@ func (x *CancelTaskResponse) String() string {
 func (*CancelTaskResponse) ProtoMessage() {}
 
 func (x *CancelTaskResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[17]
+	mi := &file_proto_lookingglass_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1855,7 +3110,7 @@ func (x *CancelTaskResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CancelTaskResponse.ProtoReflect.Descriptor instead.
 func (*CancelTaskResponse) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{17}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{32}
 }
 
 func (x *CancelTaskResponse) GetSuccess() bool {
@@ -1882,7 +3137,7 @@ type HealthCheckRequest struct {
 
 func (x *HealthCheckRequest) Reset() {
 	*x = HealthCheckRequest{}
-	mi := &file_proto_lookingglass_proto_msgTypes[18]
+	mi := &file_proto_lookingglass_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1894,7 +3149,55 @@ func (x *HealthCheckRequest) String() string {
 func (*HealthCheckRequest) ProtoMessage() {}
 
 func (x *HealthCheckRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[18]
+	mi := &file_proto_lookingglass_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthCheckRequest.ProtoReflect.Descriptor instead.
+func (*HealthCheckRequest) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *HealthCheckRequest) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+// Health check response
+type HealthCheckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Healthy       bool                   `protobuf:"varint,1,opt,name=healthy,proto3" json:"healthy,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	CurrentTasks  int32                  `protobuf:"varint,3,opt,name=current_tasks,json=currentTasks,proto3" json:"current_tasks,omitempty"`
+	MaxConcurrent int32                  `protobuf:"varint,4,opt,name=max_concurrent,json=maxConcurrent,proto3" json:"max_concurrent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthCheckResponse) Reset() {
+	*x = HealthCheckResponse{}
+	mi := &file_proto_lookingglass_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthCheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthCheckResponse) ProtoMessage() {}
+
+func (x *HealthCheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[34]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1905,44 +3208,70 @@ func (x *HealthCheckRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HealthCheckRequest.ProtoReflect.Descriptor instead.
-func (*HealthCheckRequest) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{18}
+// Deprecated: Use HealthCheckResponse.ProtoReflect.Descriptor instead.
+func (*HealthCheckResponse) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *HealthCheckResponse) GetHealthy() bool {
+	if x != nil {
+		return x.Healthy
+	}
+	return false
+}
+
+func (x *HealthCheckResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *HealthCheckResponse) GetCurrentTasks() int32 {
+	if x != nil {
+		return x.CurrentTasks
+	}
+	return 0
 }
 
-func (x *HealthCheckRequest) GetTimestamp() *timestamppb.Timestamp {
+func (x *HealthCheckResponse) GetMaxConcurrent() int32 {
 	if x != nil {
-		return x.Timestamp
+		return x.MaxConcurrent
 	}
-	return nil
+	return 0
 }
 
-// Health check response
-type HealthCheckResponse struct {
+// WebSocket request message
+// Canned task offered to users as a one-click test (configured on the master)
+type TaskTemplate struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Healthy       bool                   `protobuf:"varint,1,opt,name=healthy,proto3" json:"healthy,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	CurrentTasks  int32                  `protobuf:"varint,3,opt,name=current_tasks,json=currentTasks,proto3" json:"current_tasks,omitempty"`
-	MaxConcurrent int32                  `protobuf:"varint,4,opt,name=max_concurrent,json=maxConcurrent,proto3" json:"max_concurrent,omitempty"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`                         // Unique template name, used to submit it
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`           // Human-readable label
+	TaskName      string                 `protobuf:"bytes,3,opt,name=task_name,json=taskName,proto3" json:"task_name,omitempty"` // Agent task to run (e.g. "ping")
+	Target        string                 `protobuf:"bytes,4,opt,name=target,proto3" json:"target,omitempty"`
+	Count         int32                  `protobuf:"varint,5,opt,name=count,proto3" json:"count,omitempty"`     // Packet/hop count (0 = task default)
+	Timeout       int32                  `protobuf:"varint,6,opt,name=timeout,proto3" json:"timeout,omitempty"` // Timeout in seconds
+	Ipv6          bool                   `protobuf:"varint,7,opt,name=ipv6,proto3" json:"ipv6,omitempty"`
+	Params        map[string]string      `protobuf:"bytes,8,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Task extra_options
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *HealthCheckResponse) Reset() {
-	*x = HealthCheckResponse{}
-	mi := &file_proto_lookingglass_proto_msgTypes[19]
+func (x *TaskTemplate) Reset() {
+	*x = TaskTemplate{}
+	mi := &file_proto_lookingglass_proto_msgTypes[35]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HealthCheckResponse) String() string {
+func (x *TaskTemplate) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HealthCheckResponse) ProtoMessage() {}
+func (*TaskTemplate) ProtoMessage() {}
 
-func (x *HealthCheckResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[19]
+func (x *TaskTemplate) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[35]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1953,52 +3282,88 @@ func (x *HealthCheckResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HealthCheckResponse.ProtoReflect.Descriptor instead.
-func (*HealthCheckResponse) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{19}
+// Deprecated: Use TaskTemplate.ProtoReflect.Descriptor instead.
+func (*TaskTemplate) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{35}
 }
 
-func (x *HealthCheckResponse) GetHealthy() bool {
+func (x *TaskTemplate) GetName() string {
 	if x != nil {
-		return x.Healthy
+		return x.Name
 	}
-	return false
+	return ""
 }
 
-func (x *HealthCheckResponse) GetMessage() string {
+func (x *TaskTemplate) GetDescription() string {
 	if x != nil {
-		return x.Message
+		return x.Description
 	}
 	return ""
 }
 
-func (x *HealthCheckResponse) GetCurrentTasks() int32 {
+func (x *TaskTemplate) GetTaskName() string {
 	if x != nil {
-		return x.CurrentTasks
+		return x.TaskName
+	}
+	return ""
+}
+
+func (x *TaskTemplate) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *TaskTemplate) GetCount() int32 {
+	if x != nil {
+		return x.Count
 	}
 	return 0
 }
 
-func (x *HealthCheckResponse) GetMaxConcurrent() int32 {
+func (x *TaskTemplate) GetTimeout() int32 {
 	if x != nil {
-		return x.MaxConcurrent
+		return x.Timeout
 	}
 	return 0
 }
 
-// WebSocket request message
+func (x *TaskTemplate) GetIpv6() bool {
+	if x != nil {
+		return x.Ipv6
+	}
+	return false
+}
+
+func (x *TaskTemplate) GetParams() map[string]string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
 type WSRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Action        WSRequest_Action       `protobuf:"varint,1,opt,name=action,proto3,enum=lookingglass.WSRequest_Action" json:"action,omitempty"`
-	Task          *Task                  `protobuf:"bytes,2,opt,name=task,proto3" json:"task,omitempty"`                   // For ACTION_EXECUTE
-	TaskId        string                 `protobuf:"bytes,3,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"` // For ACTION_CANCEL
+	Task          *Task                  `protobuf:"bytes,2,opt,name=task,proto3" json:"task,omitempty"`                                                                            // For ACTION_EXECUTE
+	TaskId        string                 `protobuf:"bytes,3,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`                                                          // For ACTION_CANCEL and ACTION_SHARE
+	ConfirmToken  string                 `protobuf:"bytes,4,opt,name=confirm_token,json=confirmToken,proto3" json:"confirm_token,omitempty"`                                        // For ACTION_CANCEL_ALL (must match master admin token)
+	AgentIds      []string               `protobuf:"bytes,5,rep,name=agent_ids,json=agentIds,proto3" json:"agent_ids,omitempty"`                                                    // For ACTION_EXECUTE: run the task on each of these agents ("all" = every online agent), overriding task.agent_id
+	Template      string                 `protobuf:"bytes,6,opt,name=template,proto3" json:"template,omitempty"`                                                                    // For ACTION_EXECUTE: run this template; task then only supplies task_id and agent_id
+	OutputFormat  OutputFormat           `protobuf:"varint,7,opt,name=output_format,json=outputFormat,proto3,enum=lookingglass.OutputFormat" json:"output_format,omitempty"`        // For ACTION_EXECUTE: output format for this task, overriding the connection's preference
+	AgentId       string                 `protobuf:"bytes,8,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`                                                       // For ACTION_AGENT_LOGS
+	Lines         int32                  `protobuf:"varint,9,opt,name=lines,proto3" json:"lines,omitempty"`                                                                         // For ACTION_AGENT_LOGS: number of lines (0 = agent default)
+	Tags          map[string]string      `protobuf:"bytes,10,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // For ACTION_LIST_AGENTS: only agents carrying all of these tags (optional)
+	Delivery      BroadcastDelivery      `protobuf:"varint,11,opt,name=delivery,proto3,enum=lookingglass.BroadcastDelivery" json:"delivery,omitempty"`                              // For ACTION_EXECUTE with agent_ids: how agent outputs are delivered
+	Region        string                 `protobuf:"bytes,12,opt,name=region,proto3" json:"region,omitempty"`                                                                       // For ACTION_EXECUTE: run on the least-loaded online agent in this region (location or "region" tag) instead of task.agent_id
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *WSRequest) Reset() {
 	*x = WSRequest{}
-	mi := &file_proto_lookingglass_proto_msgTypes[20]
+	mi := &file_proto_lookingglass_proto_msgTypes[36]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2010,7 +3375,7 @@ func (x *WSRequest) String() string {
 func (*WSRequest) ProtoMessage() {}
 
 func (x *WSRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[20]
+	mi := &file_proto_lookingglass_proto_msgTypes[36]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2023,7 +3388,7 @@ func (x *WSRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WSRequest.ProtoReflect.Descriptor instead.
 func (*WSRequest) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{20}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{36}
 }
 
 func (x *WSRequest) GetAction() WSRequest_Action {
@@ -2047,21 +3412,93 @@ func (x *WSRequest) GetTaskId() string {
 	return ""
 }
 
+func (x *WSRequest) GetConfirmToken() string {
+	if x != nil {
+		return x.ConfirmToken
+	}
+	return ""
+}
+
+func (x *WSRequest) GetAgentIds() []string {
+	if x != nil {
+		return x.AgentIds
+	}
+	return nil
+}
+
+func (x *WSRequest) GetTemplate() string {
+	if x != nil {
+		return x.Template
+	}
+	return ""
+}
+
+func (x *WSRequest) GetOutputFormat() OutputFormat {
+	if x != nil {
+		return x.OutputFormat
+	}
+	return OutputFormat_OUTPUT_FORMAT_UNSPECIFIED
+}
+
+func (x *WSRequest) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *WSRequest) GetLines() int32 {
+	if x != nil {
+		return x.Lines
+	}
+	return 0
+}
+
+func (x *WSRequest) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *WSRequest) GetDelivery() BroadcastDelivery {
+	if x != nil {
+		return x.Delivery
+	}
+	return BroadcastDelivery_BROADCAST_DELIVERY_INTERLEAVED
+}
+
+func (x *WSRequest) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
 // WebSocket response message
 type WSResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Type          WSResponse_Type        `protobuf:"varint,1,opt,name=type,proto3,enum=lookingglass.WSResponse_Type" json:"type,omitempty"`
 	TaskId        string                 `protobuf:"bytes,2,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
-	Output        string                 `protobuf:"bytes,3,opt,name=output,proto3" json:"output,omitempty"`   // Output line for TYPE_OUTPUT
-	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"` // Error message or status message
-	Agents        []*AgentStatusInfo     `protobuf:"bytes,5,rep,name=agents,proto3" json:"agents,omitempty"`   // Agent list for TYPE_AGENT_LIST and TYPE_AGENT_STATUS_UPDATE
+	Output        string                 `protobuf:"bytes,3,opt,name=output,proto3" json:"output,omitempty"`                                // Output line for TYPE_OUTPUT
+	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`                              // Error message or status message
+	Agents        []*AgentStatusInfo     `protobuf:"bytes,5,rep,name=agents,proto3" json:"agents,omitempty"`                                // Agent list for TYPE_AGENT_LIST and TYPE_AGENT_STATUS_UPDATE
+	Summary       *TaskSummary           `protobuf:"bytes,6,opt,name=summary,proto3" json:"summary,omitempty"`                              // Structured result summary for TYPE_OUTPUT (optional)
+	Sequence      uint64                 `protobuf:"varint,7,opt,name=sequence,proto3" json:"sequence,omitempty"`                           // Agent output sequence (coalesced output carries its last line's); 0 = unsequenced
+	AgentId       string                 `protobuf:"bytes,8,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`               // Originating agent for fan-out child task output
+	GroupId       string                 `protobuf:"bytes,9,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`               // Fan-out group ID (empty for single-agent tasks)
+	Templates     []*TaskTemplate        `protobuf:"bytes,10,rep,name=templates,proto3" json:"templates,omitempty"`                         // Templates for TYPE_TEMPLATE_LIST
+	Cached        bool                   `protobuf:"varint,11,opt,name=cached,proto3" json:"cached,omitempty"`                              // Output replayed from the master's result cache
+	LogLines      []string               `protobuf:"bytes,12,rep,name=log_lines,json=logLines,proto3" json:"log_lines,omitempty"`           // Log lines for TYPE_AGENT_LOGS, oldest first
+	Status        TaskStatus             `protobuf:"varint,13,opt,name=status,proto3,enum=lookingglass.TaskStatus" json:"status,omitempty"` // Task status of the output (TYPE_COMPLETE covers both completed and cancelled tasks)
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,14,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`        // For TYPE_SHARE_LINK: when the link expires
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *WSResponse) Reset() {
 	*x = WSResponse{}
-	mi := &file_proto_lookingglass_proto_msgTypes[21]
+	mi := &file_proto_lookingglass_proto_msgTypes[37]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2073,7 +3510,7 @@ func (x *WSResponse) String() string {
 func (*WSResponse) ProtoMessage() {}
 
 func (x *WSResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[21]
+	mi := &file_proto_lookingglass_proto_msgTypes[37]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2086,7 +3523,7 @@ func (x *WSResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WSResponse.ProtoReflect.Descriptor instead.
 func (*WSResponse) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{21}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{37}
 }
 
 func (x *WSResponse) GetType() WSResponse_Type {
@@ -2124,31 +3561,100 @@ func (x *WSResponse) GetAgents() []*AgentStatusInfo {
 	return nil
 }
 
+func (x *WSResponse) GetSummary() *TaskSummary {
+	if x != nil {
+		return x.Summary
+	}
+	return nil
+}
+
+func (x *WSResponse) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *WSResponse) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *WSResponse) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *WSResponse) GetTemplates() []*TaskTemplate {
+	if x != nil {
+		return x.Templates
+	}
+	return nil
+}
+
+func (x *WSResponse) GetCached() bool {
+	if x != nil {
+		return x.Cached
+	}
+	return false
+}
+
+func (x *WSResponse) GetLogLines() []string {
+	if x != nil {
+		return x.LogLines
+	}
+	return nil
+}
+
+func (x *WSResponse) GetStatus() TaskStatus {
+	if x != nil {
+		return x.Status
+	}
+	return TaskStatus_TASK_STATUS_UNSPECIFIED
+}
+
+func (x *WSResponse) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
 // Agent status info for WebSocket response
 type AgentStatusInfo struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	Id              string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Name            string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Location        string                 `protobuf:"bytes,3,opt,name=location,proto3" json:"location,omitempty"`
-	Ipv4            string                 `protobuf:"bytes,4,opt,name=ipv4,proto3" json:"ipv4,omitempty"`
-	Ipv6            string                 `protobuf:"bytes,5,opt,name=ipv6,proto3" json:"ipv6,omitempty"`
-	Status          AgentStatus            `protobuf:"varint,6,opt,name=status,proto3,enum=lookingglass.AgentStatus" json:"status,omitempty"`
-	SupportedTasks  []TaskType             `protobuf:"varint,7,rep,packed,name=supported_tasks,json=supportedTasks,proto3,enum=lookingglass.TaskType" json:"supported_tasks,omitempty"` // [DEPRECATED] Use task_names instead
-	CurrentTasks    int32                  `protobuf:"varint,8,opt,name=current_tasks,json=currentTasks,proto3" json:"current_tasks,omitempty"`
-	MaxConcurrent   int32                  `protobuf:"varint,9,opt,name=max_concurrent,json=maxConcurrent,proto3" json:"max_concurrent,omitempty"`
-	Provider        string                 `protobuf:"bytes,10,opt,name=provider,proto3" json:"provider,omitempty"`                                        // Service provider
-	Idc             string                 `protobuf:"bytes,11,opt,name=idc,proto3" json:"idc,omitempty"`                                                  // Data center
-	Description     string                 `protobuf:"bytes,12,opt,name=description,proto3" json:"description,omitempty"`                                  // Additional description
-	CustomCommands  []*CustomCommandInfo   `protobuf:"bytes,13,rep,name=custom_commands,json=customCommands,proto3" json:"custom_commands,omitempty"`      // [DEPRECATED] Use task_display_info instead
-	TaskNames       []string               `protobuf:"bytes,14,rep,name=task_names,json=taskNames,proto3" json:"task_names,omitempty"`                     // [DEPRECATED] Use task_display_info instead
-	TaskDisplayInfo []*TaskDisplayInfo     `protobuf:"bytes,15,rep,name=task_display_info,json=taskDisplayInfo,proto3" json:"task_display_info,omitempty"` // Task display information (name + display_name)
+	state           protoimpl.MessageState      `protogen:"open.v1"`
+	Id              string                      `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name            string                      `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Location        string                      `protobuf:"bytes,3,opt,name=location,proto3" json:"location,omitempty"`
+	Ipv4            string                      `protobuf:"bytes,4,opt,name=ipv4,proto3" json:"ipv4,omitempty"`
+	Ipv6            string                      `protobuf:"bytes,5,opt,name=ipv6,proto3" json:"ipv6,omitempty"`
+	Status          AgentStatus                 `protobuf:"varint,6,opt,name=status,proto3,enum=lookingglass.AgentStatus" json:"status,omitempty"`
+	SupportedTasks  []TaskType                  `protobuf:"varint,7,rep,packed,name=supported_tasks,json=supportedTasks,proto3,enum=lookingglass.TaskType" json:"supported_tasks,omitempty"` // [DEPRECATED] Use task_names instead
+	CurrentTasks    int32                       `protobuf:"varint,8,opt,name=current_tasks,json=currentTasks,proto3" json:"current_tasks,omitempty"`
+	MaxConcurrent   int32                       `protobuf:"varint,9,opt,name=max_concurrent,json=maxConcurrent,proto3" json:"max_concurrent,omitempty"`
+	Provider        string                      `protobuf:"bytes,10,opt,name=provider,proto3" json:"provider,omitempty"`                                                                                                                // Service provider
+	Idc             string                      `protobuf:"bytes,11,opt,name=idc,proto3" json:"idc,omitempty"`                                                                                                                          // Data center
+	Description     string                      `protobuf:"bytes,12,opt,name=description,proto3" json:"description,omitempty"`                                                                                                          // Additional description
+	CustomCommands  []*CustomCommandInfo        `protobuf:"bytes,13,rep,name=custom_commands,json=customCommands,proto3" json:"custom_commands,omitempty"`                                                                              // [DEPRECATED] Use task_display_info instead
+	TaskNames       []string                    `protobuf:"bytes,14,rep,name=task_names,json=taskNames,proto3" json:"task_names,omitempty"`                                                                                             // [DEPRECATED] Use task_display_info instead
+	TaskDisplayInfo []*TaskDisplayInfo          `protobuf:"bytes,15,rep,name=task_display_info,json=taskDisplayInfo,proto3" json:"task_display_info,omitempty"`                                                                         // Task display information (name + display_name)
+	MemPercent      float64                     `protobuf:"fixed64,16,opt,name=mem_percent,json=memPercent,proto3" json:"mem_percent,omitempty"`                                                                                        // Agent memory usage percent (0 if not reported)
+	DiskPercent     float64                     `protobuf:"fixed64,17,opt,name=disk_percent,json=diskPercent,proto3" json:"disk_percent,omitempty"`                                                                                     // Agent disk usage percent (0 if not reported)
+	TaskConcurrency map[string]*TaskConcurrency `protobuf:"bytes,18,rep,name=task_concurrency,json=taskConcurrency,proto3" json:"task_concurrency,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Per-task-name utilization from heartbeats
+	Transitions     []*AgentTransition          `protobuf:"bytes,19,rep,name=transitions,proto3" json:"transitions,omitempty"`                                                                                                          // Recent online/offline transitions, oldest first (detail view only)
+	Tags            map[string]string           `protobuf:"bytes,20,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`                                              // Grouping labels from the agent's metadata
+	Degraded        bool                        `protobuf:"varint,21,opt,name=degraded,proto3" json:"degraded,omitempty"`                                                                                                               // Dispatch paused after repeated agent faults, until a trial task succeeds
 	unknownFields   protoimpl.UnknownFields
 	sizeCache       protoimpl.SizeCache
 }
 
 func (x *AgentStatusInfo) Reset() {
 	*x = AgentStatusInfo{}
-	mi := &file_proto_lookingglass_proto_msgTypes[22]
+	mi := &file_proto_lookingglass_proto_msgTypes[38]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2160,7 +3666,7 @@ func (x *AgentStatusInfo) String() string {
 func (*AgentStatusInfo) ProtoMessage() {}
 
 func (x *AgentStatusInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_lookingglass_proto_msgTypes[22]
+	mi := &file_proto_lookingglass_proto_msgTypes[38]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2173,7 +3679,7 @@ func (x *AgentStatusInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AgentStatusInfo.ProtoReflect.Descriptor instead.
 func (*AgentStatusInfo) Descriptor() ([]byte, []int) {
-	return file_proto_lookingglass_proto_rawDescGZIP(), []int{22}
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{38}
 }
 
 func (x *AgentStatusInfo) GetId() string {
@@ -2281,20 +3787,133 @@ func (x *AgentStatusInfo) GetTaskDisplayInfo() []*TaskDisplayInfo {
 	return nil
 }
 
+func (x *AgentStatusInfo) GetMemPercent() float64 {
+	if x != nil {
+		return x.MemPercent
+	}
+	return 0
+}
+
+func (x *AgentStatusInfo) GetDiskPercent() float64 {
+	if x != nil {
+		return x.DiskPercent
+	}
+	return 0
+}
+
+func (x *AgentStatusInfo) GetTaskConcurrency() map[string]*TaskConcurrency {
+	if x != nil {
+		return x.TaskConcurrency
+	}
+	return nil
+}
+
+func (x *AgentStatusInfo) GetTransitions() []*AgentTransition {
+	if x != nil {
+		return x.Transitions
+	}
+	return nil
+}
+
+func (x *AgentStatusInfo) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *AgentStatusInfo) GetDegraded() bool {
+	if x != nil {
+		return x.Degraded
+	}
+	return false
+}
+
+// AgentTransition is a recorded change of an agent's online/offline status
+type AgentTransition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Status        AgentStatus            `protobuf:"varint,2,opt,name=status,proto3,enum=lookingglass.AgentStatus" json:"status,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"` // e.g. "registered", "reconnected", "heartbeat timeout", "stream closed"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentTransition) Reset() {
+	*x = AgentTransition{}
+	mi := &file_proto_lookingglass_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentTransition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentTransition) ProtoMessage() {}
+
+func (x *AgentTransition) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_lookingglass_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentTransition.ProtoReflect.Descriptor instead.
+func (*AgentTransition) Descriptor() ([]byte, []int) {
+	return file_proto_lookingglass_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *AgentTransition) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *AgentTransition) GetStatus() AgentStatus {
+	if x != nil {
+		return x.Status
+	}
+	return AgentStatus_AGENT_STATUS_UNSPECIFIED
+}
+
+func (x *AgentTransition) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
 var File_proto_lookingglass_proto protoreflect.FileDescriptor
 
 const file_proto_lookingglass_proto_rawDesc = "" +
 	"\n" +
-	"\x18proto/lookingglass.proto\x12\flookingglass\x1a\x1fgoogle/protobuf/timestamp.proto\"\x9c\x01\n" +
+	"\x18proto/lookingglass.proto\x12\flookingglass\x1a\x1fgoogle/protobuf/timestamp.proto\"\xcf\x01\n" +
 	"\x0fTaskDisplayInfo\x12\x1b\n" +
 	"\ttask_name\x18\x01 \x01(\tR\btaskName\x12!\n" +
 	"\fdisplay_name\x18\x02 \x01(\tR\vdisplayName\x12 \n" +
 	"\vdescription\x18\x03 \x01(\tR\vdescription\x12'\n" +
-	"\x0frequires_target\x18\x04 \x01(\bR\x0erequiresTarget\"u\n" +
+	"\x0frequires_target\x18\x04 \x01(\bR\x0erequiresTarget\x121\n" +
+	"\x06params\x18\x05 \x03(\v2\x19.lookingglass.ParamSchemaR\x06params\"\xb1\x01\n" +
+	"\vParamSchema\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x1a\n" +
+	"\brequired\x18\x03 \x01(\bR\brequired\x12\x15\n" +
+	"\x03min\x18\x04 \x01(\x03H\x00R\x03min\x88\x01\x01\x12\x15\n" +
+	"\x03max\x18\x05 \x01(\x03H\x01R\x03max\x88\x01\x01\x12 \n" +
+	"\vdescription\x18\x06 \x01(\tR\vdescriptionB\x06\n" +
+	"\x04_minB\x06\n" +
+	"\x04_max\"u\n" +
 	"\x11CustomCommandInfo\x12\x1b\n" +
 	"\ttask_name\x18\x01 \x01(\tR\btaskName\x12!\n" +
 	"\fdisplay_name\x18\x02 \x01(\tR\vdisplayName\x12 \n" +
-	"\vdescription\x18\x03 \x01(\tR\vdescription\"\x8c\x04\n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\"\xfc\x04\n" +
 	"\tAgentInfo\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1a\n" +
@@ -2312,7 +3931,11 @@ const file_proto_lookingglass_proto_rawDesc = "" +
 	"\x0fcustom_commands\x18\r \x03(\v2\x1f.lookingglass.CustomCommandInfoR\x0ecustomCommands\x12\x1d\n" +
 	"\n" +
 	"task_names\x18\x0e \x03(\tR\ttaskNames\x12I\n" +
-	"\x11task_display_info\x18\x0f \x03(\v2\x1d.lookingglass.TaskDisplayInfoR\x0ftaskDisplayInfo\"\xcb\x01\n" +
+	"\x11task_display_info\x18\x0f \x03(\v2\x1d.lookingglass.TaskDisplayInfoR\x0ftaskDisplayInfo\x125\n" +
+	"\x04tags\x18\x10 \x03(\v2!.lookingglass.AgentInfo.TagsEntryR\x04tags\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xcb\x01\n" +
 	"\x13AgentStatus_Message\x12\x19\n" +
 	"\bagent_id\x18\x01 \x01(\tR\aagentId\x121\n" +
 	"\x06status\x18\x02 \x01(\x0e2\x19.lookingglass.AgentStatusR\x06status\x12A\n" +
@@ -2338,7 +3961,7 @@ const file_proto_lookingglass_proto_rawDesc = "" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"L\n" +
 	"\fCustomParams\x12\x19\n" +
 	"\braw_data\x18\x01 \x01(\fR\arawData\x12!\n" +
-	"\fcontent_type\x18\x02 \x01(\tR\vcontentType\"\x9d\x03\n" +
+	"\fcontent_type\x18\x02 \x01(\tR\vcontentType\"\xaf\x04\n" +
 	"\x04Task\x12\x17\n" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x19\n" +
 	"\bagent_id\x18\x02 \x01(\tR\aagentId\x12\x1b\n" +
@@ -2346,12 +3969,20 @@ const file_proto_lookingglass_proto_rawDesc = "" +
 	"\x04type\x18\x04 \x01(\x0e2\x16.lookingglass.TaskTypeR\x04type\x129\n" +
 	"\n" +
 	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x18\n" +
-	"\atimeout\x18\x06 \x01(\x05R\atimeout\x12D\n" +
+	"\atimeout\x18\x06 \x01(\x05R\atimeout\x126\n" +
+	"\bdeadline\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\bdeadline\x12/\n" +
+	"\x05retry\x18\b \x01(\v2\x19.lookingglass.RetryPolicyR\x05retry\x12'\n" +
+	"\x0fcompress_output\x18\t \x01(\bR\x0ecompressOutput\x12D\n" +
 	"\fnetwork_test\x18\n" +
 	" \x01(\v2\x1f.lookingglass.NetworkTestParamsH\x00R\vnetworkTest\x12=\n" +
 	"\tbenchmark\x18\v \x01(\v2\x1d.lookingglass.BenchmarkParamsH\x00R\tbenchmark\x124\n" +
 	"\x06custom\x18\f \x01(\v2\x1a.lookingglass.CustomParamsH\x00R\x06customB\b\n" +
-	"\x06params\"\xd7\x01\n" +
+	"\x06params\"\x89\x01\n" +
+	"\vRetryPolicy\x12!\n" +
+	"\fmax_attempts\x18\x01 \x01(\x05R\vmaxAttempts\x12\x19\n" +
+	"\bdelay_ms\x18\x02 \x01(\x05R\adelayMs\x12\x19\n" +
+	"\bretry_on\x18\x03 \x03(\tR\aretryOn\x12!\n" +
+	"\fother_agents\x18\x04 \x01(\bR\votherAgents\"\xc4\x03\n" +
 	"\n" +
 	"TaskOutput\x12\x17\n" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x1f\n" +
@@ -2359,21 +3990,90 @@ const file_proto_lookingglass_proto_rawDesc = "" +
 	"outputLine\x128\n" +
 	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x120\n" +
 	"\x06status\x18\x04 \x01(\x0e2\x18.lookingglass.TaskStatusR\x06status\x12#\n" +
-	"\rerror_message\x18\x05 \x01(\tR\ferrorMessage\"I\n" +
+	"\rerror_message\x18\x05 \x01(\tR\ferrorMessage\x123\n" +
+	"\asummary\x18\x06 \x01(\v2\x19.lookingglass.TaskSummaryR\asummary\x12\x1a\n" +
+	"\bsequence\x18\a \x01(\x04R\bsequence\x12\x19\n" +
+	"\bagent_id\x18\b \x01(\tR\aagentId\x12\x19\n" +
+	"\bgroup_id\x18\t \x01(\tR\agroupId\x12\x16\n" +
+	"\x06cached\x18\n" +
+	" \x01(\bR\x06cached\x12+\n" +
+	"\x11compressed_output\x18\v \x01(\fR\x10compressedOutput\x12\x1f\n" +
+	"\vagent_fault\x18\f \x01(\bR\n" +
+	"agentFault\"\xcd\x02\n" +
+	"\vTaskSummary\x125\n" +
+	"\n" +
+	"trace_hops\x18\x01 \x03(\v2\x16.lookingglass.TraceHopR\ttraceHops\x12!\n" +
+	"\fresolved_ips\x18\x02 \x03(\tR\vresolvedIps\x12.\n" +
+	"\x13dispatch_latency_ms\x18\x03 \x01(\x03R\x11dispatchLatencyMs\x12(\n" +
+	"\x10agent_start_time\x18\x04 \x01(\tR\x0eagentStartTime\x12%\n" +
+	"\x0eagent_timezone\x18\x05 \x01(\tR\ragentTimezone\x126\n" +
+	"\n" +
+	"ping_stats\x18\x06 \x03(\v2\x17.lookingglass.PingStatsR\tpingStats\x12+\n" +
+	"\x11bytes_transferred\x18\a \x01(\x03R\x10bytesTransferred\"\xde\x01\n" +
+	"\tPingStats\x12\x16\n" +
+	"\x06target\x18\x01 \x01(\tR\x06target\x12 \n" +
+	"\vtransmitted\x18\x02 \x01(\x05R\vtransmitted\x12\x1a\n" +
+	"\breceived\x18\x03 \x01(\x05R\breceived\x12!\n" +
+	"\floss_percent\x18\x04 \x01(\x01R\vlossPercent\x12\x1c\n" +
+	"\n" +
+	"rtt_min_ms\x18\x05 \x01(\x01R\brttMinMs\x12\x1c\n" +
+	"\n" +
+	"rtt_avg_ms\x18\x06 \x01(\x01R\brttAvgMs\x12\x1c\n" +
+	"\n" +
+	"rtt_max_ms\x18\a \x01(\x01R\brttMaxMs\"\xe3\x01\n" +
+	"\bTraceHop\x12\x10\n" +
+	"\x03ttl\x18\x01 \x01(\x05R\x03ttl\x12\x0e\n" +
+	"\x02ip\x18\x02 \x01(\tR\x02ip\x12\x1a\n" +
+	"\bhostname\x18\x03 \x01(\tR\bhostname\x12\x10\n" +
+	"\x03asn\x18\x04 \x01(\tR\x03asn\x12\x10\n" +
+	"\x03geo\x18\x05 \x01(\tR\x03geo\x12\x14\n" +
+	"\x05owner\x18\x06 \x01(\tR\x05owner\x12\x15\n" +
+	"\x06rtt_ms\x18\a \x01(\x01R\x05rttMs\x12\x1f\n" +
+	"\vprobes_sent\x18\b \x01(\x05R\n" +
+	"probesSent\x12'\n" +
+	"\x0fprobes_received\x18\t \x01(\x05R\x0eprobesReceived\"4\n" +
+	"\x11ListAgentsRequest\x12\x1f\n" +
+	"\vonline_only\x18\x01 \x01(\bR\n" +
+	"onlineOnly\"K\n" +
+	"\x12ListAgentsResponse\x125\n" +
+	"\x06agents\x18\x01 \x03(\v2\x1d.lookingglass.AgentStatusInfoR\x06agents\"2\n" +
+	"\x15GetAgentDetailRequest\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\"I\n" +
 	"\x0fRegisterRequest\x126\n" +
 	"\n" +
 	"agent_info\x18\x01 \x01(\v2\x17.lookingglass.AgentInfoR\tagentInfo\"u\n" +
 	"\x10RegisterResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12-\n" +
-	"\x12heartbeat_interval\x18\x03 \x01(\x05R\x11heartbeatInterval\"\x8c\x01\n" +
+	"\x12heartbeat_interval\x18\x03 \x01(\x05R\x11heartbeatInterval\"\x93\x03\n" +
 	"\x10HeartbeatRequest\x12\x19\n" +
 	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12#\n" +
 	"\rcurrent_tasks\x18\x02 \x01(\x05R\fcurrentTasks\x128\n" +
-	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"G\n" +
+	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x1f\n" +
+	"\vmem_percent\x18\x04 \x01(\x01R\n" +
+	"memPercent\x12!\n" +
+	"\fdisk_percent\x18\x05 \x01(\x01R\vdiskPercent\x12^\n" +
+	"\x10task_concurrency\x18\x06 \x03(\v23.lookingglass.HeartbeatRequest.TaskConcurrencyEntryR\x0ftaskConcurrency\x1aa\n" +
+	"\x14TaskConcurrencyEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x123\n" +
+	"\x05value\x18\x02 \x01(\v2\x1d.lookingglass.TaskConcurrencyR\x05value:\x028\x01\"=\n" +
+	"\x0fTaskConcurrency\x12\x18\n" +
+	"\acurrent\x18\x01 \x01(\x05R\acurrent\x12\x10\n" +
+	"\x03max\x18\x02 \x01(\x05R\x03max\"G\n" +
 	"\x11HeartbeatResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"\xb1\x03\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x11\n" +
+	"\x0fDescribeRequest\"n\n" +
+	"\x10DescribeResponse\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12)\n" +
+	"\x10effective_config\x18\x02 \x01(\tR\x0feffectiveConfig\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"#\n" +
+	"\vLogsRequest\x12\x14\n" +
+	"\x05lines\x18\x01 \x01(\x05R\x05lines\"U\n" +
+	"\fLogsResponse\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12\x14\n" +
+	"\x05lines\x18\x02 \x03(\tR\x05lines\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\x90\x05\n" +
 	"\fAgentMessage\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x123\n" +
@@ -2382,15 +4082,20 @@ const file_proto_lookingglass_proto_rawDesc = "" +
 	" \x01(\v2\x1d.lookingglass.RegisterRequestH\x00R\bregister\x12>\n" +
 	"\theartbeat\x18\v \x01(\v2\x1e.lookingglass.HeartbeatRequestH\x00R\theartbeat\x12;\n" +
 	"\vtask_output\x18\f \x01(\v2\x18.lookingglass.TaskOutputH\x00R\n" +
-	"taskOutput\"\x87\x01\n" +
+	"taskOutput\x12M\n" +
+	"\x11describe_response\x18\r \x01(\v2\x1e.lookingglass.DescribeResponseH\x00R\x10describeResponse\x12A\n" +
+	"\rlogs_response\x18\x0e \x01(\v2\x1a.lookingglass.LogsResponseH\x00R\flogsResponse\"\xd4\x01\n" +
 	"\x04Type\x12\x14\n" +
 	"\x10TYPE_UNSPECIFIED\x10\x00\x12\x11\n" +
 	"\rTYPE_REGISTER\x10\x01\x12\x12\n" +
 	"\x0eTYPE_HEARTBEAT\x10\x02\x12\x14\n" +
 	"\x10TYPE_TASK_OUTPUT\x10\x03\x12\x16\n" +
 	"\x12TYPE_TASK_COMPLETE\x10\x04\x12\x14\n" +
-	"\x10TYPE_TASK_FAILED\x10\x05B\t\n" +
-	"\apayload\"\xae\x04\n" +
+	"\x10TYPE_TASK_FAILED\x10\x05\x12\x1a\n" +
+	"\x16TYPE_DESCRIBE_RESPONSE\x10\x06\x12\x17\n" +
+	"\x13TYPE_PROBE_RESPONSE\x10\a\x12\x16\n" +
+	"\x12TYPE_LOGS_RESPONSE\x10\bB\t\n" +
+	"\apayload\"\xce\x05\n" +
 	"\rMasterMessage\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x124\n" +
@@ -2400,15 +4105,25 @@ const file_proto_lookingglass_proto_rawDesc = "" +
 	"\x12heartbeat_response\x18\v \x01(\v2\x1f.lookingglass.HeartbeatResponseH\x00R\x11heartbeatResponse\x12E\n" +
 	"\fexecute_task\x18\f \x01(\v2 .lookingglass.ExecuteTaskRequestH\x00R\vexecuteTask\x12B\n" +
 	"\vcancel_task\x18\r \x01(\v2\x1f.lookingglass.CancelTaskRequestH\x00R\n" +
-	"cancelTask\"\x90\x01\n" +
+	"cancelTask\x12;\n" +
+	"\bdescribe\x18\x0e \x01(\v2\x1d.lookingglass.DescribeRequestH\x00R\bdescribe\x12/\n" +
+	"\x04logs\x18\x0f \x01(\v2\x19.lookingglass.LogsRequestH\x00R\x04logs\"\xc2\x01\n" +
 	"\x04Type\x12\x14\n" +
 	"\x10TYPE_UNSPECIFIED\x10\x00\x12\x1a\n" +
 	"\x16TYPE_REGISTER_RESPONSE\x10\x01\x12\x1b\n" +
 	"\x17TYPE_HEARTBEAT_RESPONSE\x10\x02\x12\x15\n" +
 	"\x11TYPE_EXECUTE_TASK\x10\x03\x12\x14\n" +
 	"\x10TYPE_CANCEL_TASK\x10\x04\x12\f\n" +
-	"\bTYPE_ACK\x10\x05B\t\n" +
-	"\apayload\"<\n" +
+	"\bTYPE_ACK\x10\x05\x12\x11\n" +
+	"\rTYPE_DESCRIBE\x10\x06\x12\x0e\n" +
+	"\n" +
+	"TYPE_PROBE\x10\a\x12\r\n" +
+	"\tTYPE_LOGS\x10\bB\t\n" +
+	"\apayload\"E\n" +
+	"\vPollRequest\x126\n" +
+	"\bmessages\x18\x01 \x03(\v2\x1a.lookingglass.AgentMessageR\bmessages\"G\n" +
+	"\fPollResponse\x127\n" +
+	"\bmessages\x18\x01 \x03(\v2\x1b.lookingglass.MasterMessageR\bmessages\"<\n" +
 	"\x12ExecuteTaskRequest\x12&\n" +
 	"\x04task\x18\x01 \x01(\v2\x12.lookingglass.TaskR\x04task\",\n" +
 	"\x11CancelTaskRequest\x12\x17\n" +
@@ -2422,23 +4137,63 @@ const file_proto_lookingglass_proto_rawDesc = "" +
 	"\ahealthy\x18\x01 \x01(\bR\ahealthy\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12#\n" +
 	"\rcurrent_tasks\x18\x03 \x01(\x05R\fcurrentTasks\x12%\n" +
-	"\x0emax_concurrent\x18\x04 \x01(\x05R\rmaxConcurrent\"\xe5\x01\n" +
+	"\x0emax_concurrent\x18\x04 \x01(\x05R\rmaxConcurrent\"\xb8\x02\n" +
+	"\fTaskTemplate\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x1b\n" +
+	"\ttask_name\x18\x03 \x01(\tR\btaskName\x12\x16\n" +
+	"\x06target\x18\x04 \x01(\tR\x06target\x12\x14\n" +
+	"\x05count\x18\x05 \x01(\x05R\x05count\x12\x18\n" +
+	"\atimeout\x18\x06 \x01(\x05R\atimeout\x12\x12\n" +
+	"\x04ipv6\x18\a \x01(\bR\x04ipv6\x12>\n" +
+	"\x06params\x18\b \x03(\v2&.lookingglass.TaskTemplate.ParamsEntryR\x06params\x1a9\n" +
+	"\vParamsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xd6\x05\n" +
 	"\tWSRequest\x126\n" +
 	"\x06action\x18\x01 \x01(\x0e2\x1e.lookingglass.WSRequest.ActionR\x06action\x12&\n" +
 	"\x04task\x18\x02 \x01(\v2\x12.lookingglass.TaskR\x04task\x12\x17\n" +
-	"\atask_id\x18\x03 \x01(\tR\x06taskId\"_\n" +
+	"\atask_id\x18\x03 \x01(\tR\x06taskId\x12#\n" +
+	"\rconfirm_token\x18\x04 \x01(\tR\fconfirmToken\x12\x1b\n" +
+	"\tagent_ids\x18\x05 \x03(\tR\bagentIds\x12\x1a\n" +
+	"\btemplate\x18\x06 \x01(\tR\btemplate\x12?\n" +
+	"\routput_format\x18\a \x01(\x0e2\x1a.lookingglass.OutputFormatR\foutputFormat\x12\x19\n" +
+	"\bagent_id\x18\b \x01(\tR\aagentId\x12\x14\n" +
+	"\x05lines\x18\t \x01(\x05R\x05lines\x125\n" +
+	"\x04tags\x18\n" +
+	" \x03(\v2!.lookingglass.WSRequest.TagsEntryR\x04tags\x12;\n" +
+	"\bdelivery\x18\v \x01(\x0e2\x1f.lookingglass.BroadcastDeliveryR\bdelivery\x12\x16\n" +
+	"\x06region\x18\f \x01(\tR\x06region\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xba\x01\n" +
 	"\x06Action\x12\x16\n" +
 	"\x12ACTION_UNSPECIFIED\x10\x00\x12\x12\n" +
 	"\x0eACTION_EXECUTE\x10\x01\x12\x11\n" +
 	"\rACTION_CANCEL\x10\x02\x12\x16\n" +
-	"\x12ACTION_LIST_AGENTS\x10\x03\"\xde\x02\n" +
+	"\x12ACTION_LIST_AGENTS\x10\x03\x12\x15\n" +
+	"\x11ACTION_CANCEL_ALL\x10\x04\x12\x19\n" +
+	"\x15ACTION_LIST_TEMPLATES\x10\x05\x12\x15\n" +
+	"\x11ACTION_AGENT_LOGS\x10\x06\x12\x10\n" +
+	"\fACTION_SHARE\x10\a\"\x9c\x06\n" +
 	"\n" +
 	"WSResponse\x121\n" +
 	"\x04type\x18\x01 \x01(\x0e2\x1d.lookingglass.WSResponse.TypeR\x04type\x12\x17\n" +
 	"\atask_id\x18\x02 \x01(\tR\x06taskId\x12\x16\n" +
 	"\x06output\x18\x03 \x01(\tR\x06output\x12\x18\n" +
 	"\amessage\x18\x04 \x01(\tR\amessage\x125\n" +
-	"\x06agents\x18\x05 \x03(\v2\x1d.lookingglass.AgentStatusInfoR\x06agents\"\x9a\x01\n" +
+	"\x06agents\x18\x05 \x03(\v2\x1d.lookingglass.AgentStatusInfoR\x06agents\x123\n" +
+	"\asummary\x18\x06 \x01(\v2\x19.lookingglass.TaskSummaryR\asummary\x12\x1a\n" +
+	"\bsequence\x18\a \x01(\x04R\bsequence\x12\x19\n" +
+	"\bagent_id\x18\b \x01(\tR\aagentId\x12\x19\n" +
+	"\bgroup_id\x18\t \x01(\tR\agroupId\x128\n" +
+	"\ttemplates\x18\n" +
+	" \x03(\v2\x1a.lookingglass.TaskTemplateR\ttemplates\x12\x16\n" +
+	"\x06cached\x18\v \x01(\bR\x06cached\x12\x1b\n" +
+	"\tlog_lines\x18\f \x03(\tR\blogLines\x120\n" +
+	"\x06status\x18\r \x01(\x0e2\x18.lookingglass.TaskStatusR\x06status\x129\n" +
+	"\n" +
+	"expires_at\x18\x0e \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\"\xf5\x01\n" +
 	"\x04Type\x12\x14\n" +
 	"\x10TYPE_UNSPECIFIED\x10\x00\x12\x0f\n" +
 	"\vTYPE_OUTPUT\x10\x01\x12\x0e\n" +
@@ -2447,7 +4202,12 @@ const file_proto_lookingglass_proto_rawDesc = "" +
 	"\rTYPE_COMPLETE\x10\x03\x12\x15\n" +
 	"\x11TYPE_TASK_STARTED\x10\x04\x12\x13\n" +
 	"\x0fTYPE_AGENT_LIST\x10\x05\x12\x1c\n" +
-	"\x18TYPE_AGENT_STATUS_UPDATE\x10\x06\"\xbd\x04\n" +
+	"\x18TYPE_AGENT_STATUS_UPDATE\x10\x06\x12\x17\n" +
+	"\x13TYPE_GROUP_COMPLETE\x10\a\x12\x16\n" +
+	"\x12TYPE_TEMPLATE_LIST\x10\b\x12\x13\n" +
+	"\x0fTYPE_AGENT_LOGS\x10\t\x12\x13\n" +
+	"\x0fTYPE_SHARE_LINK\x10\n" +
+	"\"\x96\b\n" +
 	"\x0fAgentStatusInfo\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1a\n" +
@@ -2465,7 +4225,24 @@ const file_proto_lookingglass_proto_rawDesc = "" +
 	"\x0fcustom_commands\x18\r \x03(\v2\x1f.lookingglass.CustomCommandInfoR\x0ecustomCommands\x12\x1d\n" +
 	"\n" +
 	"task_names\x18\x0e \x03(\tR\ttaskNames\x12I\n" +
-	"\x11task_display_info\x18\x0f \x03(\v2\x1d.lookingglass.TaskDisplayInfoR\x0ftaskDisplayInfo*^\n" +
+	"\x11task_display_info\x18\x0f \x03(\v2\x1d.lookingglass.TaskDisplayInfoR\x0ftaskDisplayInfo\x12\x1f\n" +
+	"\vmem_percent\x18\x10 \x01(\x01R\n" +
+	"memPercent\x12!\n" +
+	"\fdisk_percent\x18\x11 \x01(\x01R\vdiskPercent\x12]\n" +
+	"\x10task_concurrency\x18\x12 \x03(\v22.lookingglass.AgentStatusInfo.TaskConcurrencyEntryR\x0ftaskConcurrency\x12?\n" +
+	"\vtransitions\x18\x13 \x03(\v2\x1d.lookingglass.AgentTransitionR\vtransitions\x12;\n" +
+	"\x04tags\x18\x14 \x03(\v2'.lookingglass.AgentStatusInfo.TagsEntryR\x04tags\x12\x1a\n" +
+	"\bdegraded\x18\x15 \x01(\bR\bdegraded\x1aa\n" +
+	"\x14TaskConcurrencyEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x123\n" +
+	"\x05value\x18\x02 \x01(\v2\x1d.lookingglass.TaskConcurrencyR\x05value:\x028\x01\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x8c\x01\n" +
+	"\x0fAgentTransition\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x121\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x19.lookingglass.AgentStatusR\x06status\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason*^\n" +
 	"\vAgentStatus\x12\x1c\n" +
 	"\x18AGENT_STATUS_UNSPECIFIED\x10\x00\x12\x17\n" +
 	"\x13AGENT_STATUS_ONLINE\x10\x01\x12\x18\n" +
@@ -2489,11 +4266,22 @@ const file_proto_lookingglass_proto_rawDesc = "" +
 	"\bAuthMode\x12\x19\n" +
 	"\x15AUTH_MODE_UNSPECIFIED\x10\x00\x12\x15\n" +
 	"\x11AUTH_MODE_API_KEY\x10\x01\x12\x1a\n" +
-	"\x16AUTH_MODE_IP_WHITELIST\x10\x022\xf4\x01\n" +
+	"\x16AUTH_MODE_IP_WHITELIST\x10\x02*b\n" +
+	"\fOutputFormat\x12\x1d\n" +
+	"\x19OUTPUT_FORMAT_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11OUTPUT_FORMAT_RAW\x10\x01\x12\x1c\n" +
+	"\x18OUTPUT_FORMAT_STRUCTURED\x10\x02*W\n" +
+	"\x11BroadcastDelivery\x12\"\n" +
+	"\x1eBROADCAST_DELIVERY_INTERLEAVED\x10\x00\x12\x1e\n" +
+	"\x1aBROADCAST_DELIVERY_GROUPED\x10\x012\xe8\x03\n" +
 	"\rMasterService\x12I\n" +
 	"\bRegister\x12\x1d.lookingglass.RegisterRequest\x1a\x1e.lookingglass.RegisterResponse\x12L\n" +
 	"\tHeartbeat\x12\x1e.lookingglass.HeartbeatRequest\x1a\x1f.lookingglass.HeartbeatResponse\x12J\n" +
-	"\vAgentStream\x12\x1a.lookingglass.AgentMessage\x1a\x1b.lookingglass.MasterMessage(\x010\x012\x80\x02\n" +
+	"\vAgentStream\x12\x1a.lookingglass.AgentMessage\x1a\x1b.lookingglass.MasterMessage(\x010\x01\x12O\n" +
+	"\n" +
+	"ListAgents\x12\x1f.lookingglass.ListAgentsRequest\x1a .lookingglass.ListAgentsResponse\x12T\n" +
+	"\x0eGetAgentDetail\x12#.lookingglass.GetAgentDetailRequest\x1a\x1d.lookingglass.AgentStatusInfo\x12K\n" +
+	"\vExecuteTask\x12 .lookingglass.ExecuteTaskRequest\x1a\x18.lookingglass.TaskOutput0\x012\x80\x02\n" +
 	"\fAgentService\x12K\n" +
 	"\vExecuteTask\x12 .lookingglass.ExecuteTaskRequest\x1a\x18.lookingglass.TaskOutput0\x01\x12O\n" +
 	"\n" +
@@ -2512,97 +4300,158 @@ func file_proto_lookingglass_proto_rawDescGZIP() []byte {
 	return file_proto_lookingglass_proto_rawDescData
 }
 
-var file_proto_lookingglass_proto_enumTypes = make([]protoimpl.EnumInfo, 8)
-var file_proto_lookingglass_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
+var file_proto_lookingglass_proto_enumTypes = make([]protoimpl.EnumInfo, 10)
+var file_proto_lookingglass_proto_msgTypes = make([]protoimpl.MessageInfo, 48)
 var file_proto_lookingglass_proto_goTypes = []any{
 	(AgentStatus)(0),              // 0: lookingglass.AgentStatus
 	(TaskStatus)(0),               // 1: lookingglass.TaskStatus
 	(TaskType)(0),                 // 2: lookingglass.TaskType
 	(AuthMode)(0),                 // 3: lookingglass.AuthMode
-	(AgentMessage_Type)(0),        // 4: lookingglass.AgentMessage.Type
-	(MasterMessage_Type)(0),       // 5: lookingglass.MasterMessage.Type
-	(WSRequest_Action)(0),         // 6: lookingglass.WSRequest.Action
-	(WSResponse_Type)(0),          // 7: lookingglass.WSResponse.Type
-	(*TaskDisplayInfo)(nil),       // 8: lookingglass.TaskDisplayInfo
-	(*CustomCommandInfo)(nil),     // 9: lookingglass.CustomCommandInfo
-	(*AgentInfo)(nil),             // 10: lookingglass.AgentInfo
-	(*AgentStatus_Message)(nil),   // 11: lookingglass.AgentStatus_Message
-	(*NetworkTestParams)(nil),     // 12: lookingglass.NetworkTestParams
-	(*BenchmarkParams)(nil),       // 13: lookingglass.BenchmarkParams
-	(*CustomParams)(nil),          // 14: lookingglass.CustomParams
-	(*Task)(nil),                  // 15: lookingglass.Task
-	(*TaskOutput)(nil),            // 16: lookingglass.TaskOutput
-	(*RegisterRequest)(nil),       // 17: lookingglass.RegisterRequest
-	(*RegisterResponse)(nil),      // 18: lookingglass.RegisterResponse
-	(*HeartbeatRequest)(nil),      // 19: lookingglass.HeartbeatRequest
-	(*HeartbeatResponse)(nil),     // 20: lookingglass.HeartbeatResponse
-	(*AgentMessage)(nil),          // 21: lookingglass.AgentMessage
-	(*MasterMessage)(nil),         // 22: lookingglass.MasterMessage
-	(*ExecuteTaskRequest)(nil),    // 23: lookingglass.ExecuteTaskRequest
-	(*CancelTaskRequest)(nil),     // 24: lookingglass.CancelTaskRequest
-	(*CancelTaskResponse)(nil),    // 25: lookingglass.CancelTaskResponse
-	(*HealthCheckRequest)(nil),    // 26: lookingglass.HealthCheckRequest
-	(*HealthCheckResponse)(nil),   // 27: lookingglass.HealthCheckResponse
-	(*WSRequest)(nil),             // 28: lookingglass.WSRequest
-	(*WSResponse)(nil),            // 29: lookingglass.WSResponse
-	(*AgentStatusInfo)(nil),       // 30: lookingglass.AgentStatusInfo
-	nil,                           // 31: lookingglass.NetworkTestParams.ExtraOptionsEntry
-	nil,                           // 32: lookingglass.BenchmarkParams.OptionsEntry
-	(*timestamppb.Timestamp)(nil), // 33: google.protobuf.Timestamp
+	(OutputFormat)(0),             // 4: lookingglass.OutputFormat
+	(BroadcastDelivery)(0),        // 5: lookingglass.BroadcastDelivery
+	(AgentMessage_Type)(0),        // 6: lookingglass.AgentMessage.Type
+	(MasterMessage_Type)(0),       // 7: lookingglass.MasterMessage.Type
+	(WSRequest_Action)(0),         // 8: lookingglass.WSRequest.Action
+	(WSResponse_Type)(0),          // 9: lookingglass.WSResponse.Type
+	(*TaskDisplayInfo)(nil),       // 10: lookingglass.TaskDisplayInfo
+	(*ParamSchema)(nil),           // 11: lookingglass.ParamSchema
+	(*CustomCommandInfo)(nil),     // 12: lookingglass.CustomCommandInfo
+	(*AgentInfo)(nil),             // 13: lookingglass.AgentInfo
+	(*AgentStatus_Message)(nil),   // 14: lookingglass.AgentStatus_Message
+	(*NetworkTestParams)(nil),     // 15: lookingglass.NetworkTestParams
+	(*BenchmarkParams)(nil),       // 16: lookingglass.BenchmarkParams
+	(*CustomParams)(nil),          // 17: lookingglass.CustomParams
+	(*Task)(nil),                  // 18: lookingglass.Task
+	(*RetryPolicy)(nil),           // 19: lookingglass.RetryPolicy
+	(*TaskOutput)(nil),            // 20: lookingglass.TaskOutput
+	(*TaskSummary)(nil),           // 21: lookingglass.TaskSummary
+	(*PingStats)(nil),             // 22: lookingglass.PingStats
+	(*TraceHop)(nil),              // 23: lookingglass.TraceHop
+	(*ListAgentsRequest)(nil),     // 24: lookingglass.ListAgentsRequest
+	(*ListAgentsResponse)(nil),    // 25: lookingglass.ListAgentsResponse
+	(*GetAgentDetailRequest)(nil), // 26: lookingglass.GetAgentDetailRequest
+	(*RegisterRequest)(nil),       // 27: lookingglass.RegisterRequest
+	(*RegisterResponse)(nil),      // 28: lookingglass.RegisterResponse
+	(*HeartbeatRequest)(nil),      // 29: lookingglass.HeartbeatRequest
+	(*TaskConcurrency)(nil),       // 30: lookingglass.TaskConcurrency
+	(*HeartbeatResponse)(nil),     // 31: lookingglass.HeartbeatResponse
+	(*DescribeRequest)(nil),       // 32: lookingglass.DescribeRequest
+	(*DescribeResponse)(nil),      // 33: lookingglass.DescribeResponse
+	(*LogsRequest)(nil),           // 34: lookingglass.LogsRequest
+	(*LogsResponse)(nil),          // 35: lookingglass.LogsResponse
+	(*AgentMessage)(nil),          // 36: lookingglass.AgentMessage
+	(*MasterMessage)(nil),         // 37: lookingglass.MasterMessage
+	(*PollRequest)(nil),           // 38: lookingglass.PollRequest
+	(*PollResponse)(nil),          // 39: lookingglass.PollResponse
+	(*ExecuteTaskRequest)(nil),    // 40: lookingglass.ExecuteTaskRequest
+	(*CancelTaskRequest)(nil),     // 41: lookingglass.CancelTaskRequest
+	(*CancelTaskResponse)(nil),    // 42: lookingglass.CancelTaskResponse
+	(*HealthCheckRequest)(nil),    // 43: lookingglass.HealthCheckRequest
+	(*HealthCheckResponse)(nil),   // 44: lookingglass.HealthCheckResponse
+	(*TaskTemplate)(nil),          // 45: lookingglass.TaskTemplate
+	(*WSRequest)(nil),             // 46: lookingglass.WSRequest
+	(*WSResponse)(nil),            // 47: lookingglass.WSResponse
+	(*AgentStatusInfo)(nil),       // 48: lookingglass.AgentStatusInfo
+	(*AgentTransition)(nil),       // 49: lookingglass.AgentTransition
+	nil,                           // 50: lookingglass.AgentInfo.TagsEntry
+	nil,                           // 51: lookingglass.NetworkTestParams.ExtraOptionsEntry
+	nil,                           // 52: lookingglass.BenchmarkParams.OptionsEntry
+	nil,                           // 53: lookingglass.HeartbeatRequest.TaskConcurrencyEntry
+	nil,                           // 54: lookingglass.TaskTemplate.ParamsEntry
+	nil,                           // 55: lookingglass.WSRequest.TagsEntry
+	nil,                           // 56: lookingglass.AgentStatusInfo.TaskConcurrencyEntry
+	nil,                           // 57: lookingglass.AgentStatusInfo.TagsEntry
+	(*timestamppb.Timestamp)(nil), // 58: google.protobuf.Timestamp
 }
 var file_proto_lookingglass_proto_depIdxs = []int32{
-	2,  // 0: lookingglass.AgentInfo.supported_tasks:type_name -> lookingglass.TaskType
-	9,  // 1: lookingglass.AgentInfo.custom_commands:type_name -> lookingglass.CustomCommandInfo
-	8,  // 2: lookingglass.AgentInfo.task_display_info:type_name -> lookingglass.TaskDisplayInfo
-	0,  // 3: lookingglass.AgentStatus_Message.status:type_name -> lookingglass.AgentStatus
-	33, // 4: lookingglass.AgentStatus_Message.last_heartbeat:type_name -> google.protobuf.Timestamp
-	31, // 5: lookingglass.NetworkTestParams.extra_options:type_name -> lookingglass.NetworkTestParams.ExtraOptionsEntry
-	32, // 6: lookingglass.BenchmarkParams.options:type_name -> lookingglass.BenchmarkParams.OptionsEntry
-	2,  // 7: lookingglass.Task.type:type_name -> lookingglass.TaskType
-	33, // 8: lookingglass.Task.created_at:type_name -> google.protobuf.Timestamp
-	12, // 9: lookingglass.Task.network_test:type_name -> lookingglass.NetworkTestParams
-	13, // 10: lookingglass.Task.benchmark:type_name -> lookingglass.BenchmarkParams
-	14, // 11: lookingglass.Task.custom:type_name -> lookingglass.CustomParams
-	33, // 12: lookingglass.TaskOutput.timestamp:type_name -> google.protobuf.Timestamp
-	1,  // 13: lookingglass.TaskOutput.status:type_name -> lookingglass.TaskStatus
-	10, // 14: lookingglass.RegisterRequest.agent_info:type_name -> lookingglass.AgentInfo
-	33, // 15: lookingglass.HeartbeatRequest.timestamp:type_name -> google.protobuf.Timestamp
-	4,  // 16: lookingglass.AgentMessage.type:type_name -> lookingglass.AgentMessage.Type
-	17, // 17: lookingglass.AgentMessage.register:type_name -> lookingglass.RegisterRequest
-	19, // 18: lookingglass.AgentMessage.heartbeat:type_name -> lookingglass.HeartbeatRequest
-	16, // 19: lookingglass.AgentMessage.task_output:type_name -> lookingglass.TaskOutput
-	5,  // 20: lookingglass.MasterMessage.type:type_name -> lookingglass.MasterMessage.Type
-	18, // 21: lookingglass.MasterMessage.register_response:type_name -> lookingglass.RegisterResponse
-	20, // 22: lookingglass.MasterMessage.heartbeat_response:type_name -> lookingglass.HeartbeatResponse
-	23, // 23: lookingglass.MasterMessage.execute_task:type_name -> lookingglass.ExecuteTaskRequest
-	24, // 24: lookingglass.MasterMessage.cancel_task:type_name -> lookingglass.CancelTaskRequest
-	15, // 25: lookingglass.ExecuteTaskRequest.task:type_name -> lookingglass.Task
-	33, // 26: lookingglass.HealthCheckRequest.timestamp:type_name -> google.protobuf.Timestamp
-	6,  // 27: lookingglass.WSRequest.action:type_name -> lookingglass.WSRequest.Action
-	15, // 28: lookingglass.WSRequest.task:type_name -> lookingglass.Task
-	7,  // 29: lookingglass.WSResponse.type:type_name -> lookingglass.WSResponse.Type
-	30, // 30: lookingglass.WSResponse.agents:type_name -> lookingglass.AgentStatusInfo
-	0,  // 31: lookingglass.AgentStatusInfo.status:type_name -> lookingglass.AgentStatus
-	2,  // 32: lookingglass.AgentStatusInfo.supported_tasks:type_name -> lookingglass.TaskType
-	9,  // 33: lookingglass.AgentStatusInfo.custom_commands:type_name -> lookingglass.CustomCommandInfo
-	8,  // 34: lookingglass.AgentStatusInfo.task_display_info:type_name -> lookingglass.TaskDisplayInfo
-	17, // 35: lookingglass.MasterService.Register:input_type -> lookingglass.RegisterRequest
-	19, // 36: lookingglass.MasterService.Heartbeat:input_type -> lookingglass.HeartbeatRequest
-	21, // 37: lookingglass.MasterService.AgentStream:input_type -> lookingglass.AgentMessage
-	23, // 38: lookingglass.AgentService.ExecuteTask:input_type -> lookingglass.ExecuteTaskRequest
-	24, // 39: lookingglass.AgentService.CancelTask:input_type -> lookingglass.CancelTaskRequest
-	26, // 40: lookingglass.AgentService.HealthCheck:input_type -> lookingglass.HealthCheckRequest
-	18, // 41: lookingglass.MasterService.Register:output_type -> lookingglass.RegisterResponse
-	20, // 42: lookingglass.MasterService.Heartbeat:output_type -> lookingglass.HeartbeatResponse
-	22, // 43: lookingglass.MasterService.AgentStream:output_type -> lookingglass.MasterMessage
-	16, // 44: lookingglass.AgentService.ExecuteTask:output_type -> lookingglass.TaskOutput
-	25, // 45: lookingglass.AgentService.CancelTask:output_type -> lookingglass.CancelTaskResponse
-	27, // 46: lookingglass.AgentService.HealthCheck:output_type -> lookingglass.HealthCheckResponse
-	41, // [41:47] is the sub-list for method output_type
-	35, // [35:41] is the sub-list for method input_type
-	35, // [35:35] is the sub-list for extension type_name
-	35, // [35:35] is the sub-list for extension extendee
-	0,  // [0:35] is the sub-list for field type_name
+	11, // 0: lookingglass.TaskDisplayInfo.params:type_name -> lookingglass.ParamSchema
+	2,  // 1: lookingglass.AgentInfo.supported_tasks:type_name -> lookingglass.TaskType
+	12, // 2: lookingglass.AgentInfo.custom_commands:type_name -> lookingglass.CustomCommandInfo
+	10, // 3: lookingglass.AgentInfo.task_display_info:type_name -> lookingglass.TaskDisplayInfo
+	50, // 4: lookingglass.AgentInfo.tags:type_name -> lookingglass.AgentInfo.TagsEntry
+	0,  // 5: lookingglass.AgentStatus_Message.status:type_name -> lookingglass.AgentStatus
+	58, // 6: lookingglass.AgentStatus_Message.last_heartbeat:type_name -> google.protobuf.Timestamp
+	51, // 7: lookingglass.NetworkTestParams.extra_options:type_name -> lookingglass.NetworkTestParams.ExtraOptionsEntry
+	52, // 8: lookingglass.BenchmarkParams.options:type_name -> lookingglass.BenchmarkParams.OptionsEntry
+	2,  // 9: lookingglass.Task.type:type_name -> lookingglass.TaskType
+	58, // 10: lookingglass.Task.created_at:type_name -> google.protobuf.Timestamp
+	58, // 11: lookingglass.Task.deadline:type_name -> google.protobuf.Timestamp
+	19, // 12: lookingglass.Task.retry:type_name -> lookingglass.RetryPolicy
+	15, // 13: lookingglass.Task.network_test:type_name -> lookingglass.NetworkTestParams
+	16, // 14: lookingglass.Task.benchmark:type_name -> lookingglass.BenchmarkParams
+	17, // 15: lookingglass.Task.custom:type_name -> lookingglass.CustomParams
+	58, // 16: lookingglass.TaskOutput.timestamp:type_name -> google.protobuf.Timestamp
+	1,  // 17: lookingglass.TaskOutput.status:type_name -> lookingglass.TaskStatus
+	21, // 18: lookingglass.TaskOutput.summary:type_name -> lookingglass.TaskSummary
+	23, // 19: lookingglass.TaskSummary.trace_hops:type_name -> lookingglass.TraceHop
+	22, // 20: lookingglass.TaskSummary.ping_stats:type_name -> lookingglass.PingStats
+	48, // 21: lookingglass.ListAgentsResponse.agents:type_name -> lookingglass.AgentStatusInfo
+	13, // 22: lookingglass.RegisterRequest.agent_info:type_name -> lookingglass.AgentInfo
+	58, // 23: lookingglass.HeartbeatRequest.timestamp:type_name -> google.protobuf.Timestamp
+	53, // 24: lookingglass.HeartbeatRequest.task_concurrency:type_name -> lookingglass.HeartbeatRequest.TaskConcurrencyEntry
+	6,  // 25: lookingglass.AgentMessage.type:type_name -> lookingglass.AgentMessage.Type
+	27, // 26: lookingglass.AgentMessage.register:type_name -> lookingglass.RegisterRequest
+	29, // 27: lookingglass.AgentMessage.heartbeat:type_name -> lookingglass.HeartbeatRequest
+	20, // 28: lookingglass.AgentMessage.task_output:type_name -> lookingglass.TaskOutput
+	33, // 29: lookingglass.AgentMessage.describe_response:type_name -> lookingglass.DescribeResponse
+	35, // 30: lookingglass.AgentMessage.logs_response:type_name -> lookingglass.LogsResponse
+	7,  // 31: lookingglass.MasterMessage.type:type_name -> lookingglass.MasterMessage.Type
+	28, // 32: lookingglass.MasterMessage.register_response:type_name -> lookingglass.RegisterResponse
+	31, // 33: lookingglass.MasterMessage.heartbeat_response:type_name -> lookingglass.HeartbeatResponse
+	40, // 34: lookingglass.MasterMessage.execute_task:type_name -> lookingglass.ExecuteTaskRequest
+	41, // 35: lookingglass.MasterMessage.cancel_task:type_name -> lookingglass.CancelTaskRequest
+	32, // 36: lookingglass.MasterMessage.describe:type_name -> lookingglass.DescribeRequest
+	34, // 37: lookingglass.MasterMessage.logs:type_name -> lookingglass.LogsRequest
+	36, // 38: lookingglass.PollRequest.messages:type_name -> lookingglass.AgentMessage
+	37, // 39: lookingglass.PollResponse.messages:type_name -> lookingglass.MasterMessage
+	18, // 40: lookingglass.ExecuteTaskRequest.task:type_name -> lookingglass.Task
+	58, // 41: lookingglass.HealthCheckRequest.timestamp:type_name -> google.protobuf.Timestamp
+	54, // 42: lookingglass.TaskTemplate.params:type_name -> lookingglass.TaskTemplate.ParamsEntry
+	8,  // 43: lookingglass.WSRequest.action:type_name -> lookingglass.WSRequest.Action
+	18, // 44: lookingglass.WSRequest.task:type_name -> lookingglass.Task
+	4,  // 45: lookingglass.WSRequest.output_format:type_name -> lookingglass.OutputFormat
+	55, // 46: lookingglass.WSRequest.tags:type_name -> lookingglass.WSRequest.TagsEntry
+	5,  // 47: lookingglass.WSRequest.delivery:type_name -> lookingglass.BroadcastDelivery
+	9,  // 48: lookingglass.WSResponse.type:type_name -> lookingglass.WSResponse.Type
+	48, // 49: lookingglass.WSResponse.agents:type_name -> lookingglass.AgentStatusInfo
+	21, // 50: lookingglass.WSResponse.summary:type_name -> lookingglass.TaskSummary
+	45, // 51: lookingglass.WSResponse.templates:type_name -> lookingglass.TaskTemplate
+	1,  // 52: lookingglass.WSResponse.status:type_name -> lookingglass.TaskStatus
+	58, // 53: lookingglass.WSResponse.expires_at:type_name -> google.protobuf.Timestamp
+	0,  // 54: lookingglass.AgentStatusInfo.status:type_name -> lookingglass.AgentStatus
+	2,  // 55: lookingglass.AgentStatusInfo.supported_tasks:type_name -> lookingglass.TaskType
+	12, // 56: lookingglass.AgentStatusInfo.custom_commands:type_name -> lookingglass.CustomCommandInfo
+	10, // 57: lookingglass.AgentStatusInfo.task_display_info:type_name -> lookingglass.TaskDisplayInfo
+	56, // 58: lookingglass.AgentStatusInfo.task_concurrency:type_name -> lookingglass.AgentStatusInfo.TaskConcurrencyEntry
+	49, // 59: lookingglass.AgentStatusInfo.transitions:type_name -> lookingglass.AgentTransition
+	57, // 60: lookingglass.AgentStatusInfo.tags:type_name -> lookingglass.AgentStatusInfo.TagsEntry
+	58, // 61: lookingglass.AgentTransition.time:type_name -> google.protobuf.Timestamp
+	0,  // 62: lookingglass.AgentTransition.status:type_name -> lookingglass.AgentStatus
+	30, // 63: lookingglass.HeartbeatRequest.TaskConcurrencyEntry.value:type_name -> lookingglass.TaskConcurrency
+	30, // 64: lookingglass.AgentStatusInfo.TaskConcurrencyEntry.value:type_name -> lookingglass.TaskConcurrency
+	27, // 65: lookingglass.MasterService.Register:input_type -> lookingglass.RegisterRequest
+	29, // 66: lookingglass.MasterService.Heartbeat:input_type -> lookingglass.HeartbeatRequest
+	36, // 67: lookingglass.MasterService.AgentStream:input_type -> lookingglass.AgentMessage
+	24, // 68: lookingglass.MasterService.ListAgents:input_type -> lookingglass.ListAgentsRequest
+	26, // 69: lookingglass.MasterService.GetAgentDetail:input_type -> lookingglass.GetAgentDetailRequest
+	40, // 70: lookingglass.MasterService.ExecuteTask:input_type -> lookingglass.ExecuteTaskRequest
+	40, // 71: lookingglass.AgentService.ExecuteTask:input_type -> lookingglass.ExecuteTaskRequest
+	41, // 72: lookingglass.AgentService.CancelTask:input_type -> lookingglass.CancelTaskRequest
+	43, // 73: lookingglass.AgentService.HealthCheck:input_type -> lookingglass.HealthCheckRequest
+	28, // 74: lookingglass.MasterService.Register:output_type -> lookingglass.RegisterResponse
+	31, // 75: lookingglass.MasterService.Heartbeat:output_type -> lookingglass.HeartbeatResponse
+	37, // 76: lookingglass.MasterService.AgentStream:output_type -> lookingglass.MasterMessage
+	25, // 77: lookingglass.MasterService.ListAgents:output_type -> lookingglass.ListAgentsResponse
+	48, // 78: lookingglass.MasterService.GetAgentDetail:output_type -> lookingglass.AgentStatusInfo
+	20, // 79: lookingglass.MasterService.ExecuteTask:output_type -> lookingglass.TaskOutput
+	20, // 80: lookingglass.AgentService.ExecuteTask:output_type -> lookingglass.TaskOutput
+	42, // 81: lookingglass.AgentService.CancelTask:output_type -> lookingglass.CancelTaskResponse
+	44, // 82: lookingglass.AgentService.HealthCheck:output_type -> lookingglass.HealthCheckResponse
+	74, // [74:83] is the sub-list for method output_type
+	65, // [65:74] is the sub-list for method input_type
+	65, // [65:65] is the sub-list for extension type_name
+	65, // [65:65] is the sub-list for extension extendee
+	0,  // [0:65] is the sub-list for field type_name
 }
 
 func init() { file_proto_lookingglass_proto_init() }
@@ -2610,29 +4459,34 @@ func file_proto_lookingglass_proto_init() {
 	if File_proto_lookingglass_proto != nil {
 		return
 	}
-	file_proto_lookingglass_proto_msgTypes[7].OneofWrappers = []any{
+	file_proto_lookingglass_proto_msgTypes[1].OneofWrappers = []any{}
+	file_proto_lookingglass_proto_msgTypes[8].OneofWrappers = []any{
 		(*Task_NetworkTest)(nil),
 		(*Task_Benchmark)(nil),
 		(*Task_Custom)(nil),
 	}
-	file_proto_lookingglass_proto_msgTypes[13].OneofWrappers = []any{
+	file_proto_lookingglass_proto_msgTypes[26].OneofWrappers = []any{
 		(*AgentMessage_Register)(nil),
 		(*AgentMessage_Heartbeat)(nil),
 		(*AgentMessage_TaskOutput)(nil),
+		(*AgentMessage_DescribeResponse)(nil),
+		(*AgentMessage_LogsResponse)(nil),
 	}
-	file_proto_lookingglass_proto_msgTypes[14].OneofWrappers = []any{
+	file_proto_lookingglass_proto_msgTypes[27].OneofWrappers = []any{
 		(*MasterMessage_RegisterResponse)(nil),
 		(*MasterMessage_HeartbeatResponse)(nil),
 		(*MasterMessage_ExecuteTask)(nil),
 		(*MasterMessage_CancelTask)(nil),
+		(*MasterMessage_Describe)(nil),
+		(*MasterMessage_Logs)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_lookingglass_proto_rawDesc), len(file_proto_lookingglass_proto_rawDesc)),
-			NumEnums:      8,
-			NumMessages:   25,
+			NumEnums:      10,
+			NumMessages:   48,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
@@ -19,9 +19,12 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	MasterService_Register_FullMethodName    = "/lookingglass.MasterService/Register"
-	MasterService_Heartbeat_FullMethodName   = "/lookingglass.MasterService/Heartbeat"
-	MasterService_AgentStream_FullMethodName = "/lookingglass.MasterService/AgentStream"
+	MasterService_Register_FullMethodName       = "/lookingglass.MasterService/Register"
+	MasterService_Heartbeat_FullMethodName      = "/lookingglass.MasterService/Heartbeat"
+	MasterService_AgentStream_FullMethodName    = "/lookingglass.MasterService/AgentStream"
+	MasterService_ListAgents_FullMethodName     = "/lookingglass.MasterService/ListAgents"
+	MasterService_GetAgentDetail_FullMethodName = "/lookingglass.MasterService/GetAgentDetail"
+	MasterService_ExecuteTask_FullMethodName    = "/lookingglass.MasterService/ExecuteTask"
 )
 
 // MasterServiceClient is the client API for MasterService service.
@@ -36,6 +39,11 @@ type MasterServiceClient interface {
 	// Agent initiates this stream and keeps it alive
 	// Supports: registration, heartbeat, task execution, task output
 	AgentStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AgentMessage, MasterMessage], error)
+	// Agent inventory for automation clients (same authentication as agents)
+	ListAgents(ctx context.Context, in *ListAgentsRequest, opts ...grpc.CallOption) (*ListAgentsResponse, error)
+	GetAgentDetail(ctx context.Context, in *GetAgentDetailRequest, opts ...grpc.CallOption) (*AgentStatusInfo, error)
+	// Submit a task through the scheduler and stream its output until it finishes
+	ExecuteTask(ctx context.Context, in *ExecuteTaskRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TaskOutput], error)
 }
 
 type masterServiceClient struct {
@@ -79,6 +87,45 @@ func (c *masterServiceClient) AgentStream(ctx context.Context, opts ...grpc.Call
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type MasterService_AgentStreamClient = grpc.BidiStreamingClient[AgentMessage, MasterMessage]
 
+func (c *masterServiceClient) ListAgents(ctx context.Context, in *ListAgentsRequest, opts ...grpc.CallOption) (*ListAgentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAgentsResponse)
+	err := c.cc.Invoke(ctx, MasterService_ListAgents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterServiceClient) GetAgentDetail(ctx context.Context, in *GetAgentDetailRequest, opts ...grpc.CallOption) (*AgentStatusInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AgentStatusInfo)
+	err := c.cc.Invoke(ctx, MasterService_GetAgentDetail_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterServiceClient) ExecuteTask(ctx context.Context, in *ExecuteTaskRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TaskOutput], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MasterService_ServiceDesc.Streams[1], MasterService_ExecuteTask_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExecuteTaskRequest, TaskOutput]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MasterService_ExecuteTaskClient = grpc.ServerStreamingClient[TaskOutput]
+
 // MasterServiceServer is the server API for MasterService service.
 // All implementations must embed UnimplementedMasterServiceServer
 // for forward compatibility.
@@ -91,6 +138,11 @@ type MasterServiceServer interface {
 	// Agent initiates this stream and keeps it alive
 	// Supports: registration, heartbeat, task execution, task output
 	AgentStream(grpc.BidiStreamingServer[AgentMessage, MasterMessage]) error
+	// Agent inventory for automation clients (same authentication as agents)
+	ListAgents(context.Context, *ListAgentsRequest) (*ListAgentsResponse, error)
+	GetAgentDetail(context.Context, *GetAgentDetailRequest) (*AgentStatusInfo, error)
+	// Submit a task through the scheduler and stream its output until it finishes
+	ExecuteTask(*ExecuteTaskRequest, grpc.ServerStreamingServer[TaskOutput]) error
 	mustEmbedUnimplementedMasterServiceServer()
 }
 
@@ -110,6 +162,15 @@ func (UnimplementedMasterServiceServer) Heartbeat(context.Context, *HeartbeatReq
 func (UnimplementedMasterServiceServer) AgentStream(grpc.BidiStreamingServer[AgentMessage, MasterMessage]) error {
 	return status.Errorf(codes.Unimplemented, "method AgentStream not implemented")
 }
+func (UnimplementedMasterServiceServer) ListAgents(context.Context, *ListAgentsRequest) (*ListAgentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAgents not implemented")
+}
+func (UnimplementedMasterServiceServer) GetAgentDetail(context.Context, *GetAgentDetailRequest) (*AgentStatusInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAgentDetail not implemented")
+}
+func (UnimplementedMasterServiceServer) ExecuteTask(*ExecuteTaskRequest, grpc.ServerStreamingServer[TaskOutput]) error {
+	return status.Errorf(codes.Unimplemented, "method ExecuteTask not implemented")
+}
 func (UnimplementedMasterServiceServer) mustEmbedUnimplementedMasterServiceServer() {}
 func (UnimplementedMasterServiceServer) testEmbeddedByValue()                       {}
 
@@ -174,6 +235,53 @@ func _MasterService_AgentStream_Handler(srv interface{}, stream grpc.ServerStrea
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type MasterService_AgentStreamServer = grpc.BidiStreamingServer[AgentMessage, MasterMessage]
 
+func _MasterService_ListAgents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAgentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServiceServer).ListAgents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MasterService_ListAgents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServiceServer).ListAgents(ctx, req.(*ListAgentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MasterService_GetAgentDetail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAgentDetailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServiceServer).GetAgentDetail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MasterService_GetAgentDetail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServiceServer).GetAgentDetail(ctx, req.(*GetAgentDetailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MasterService_ExecuteTask_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExecuteTaskRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MasterServiceServer).ExecuteTask(m, &grpc.GenericServerStream[ExecuteTaskRequest, TaskOutput]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MasterService_ExecuteTaskServer = grpc.ServerStreamingServer[TaskOutput]
+
 // MasterService_ServiceDesc is the grpc.ServiceDesc for MasterService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -189,6 +297,14 @@ var MasterService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Heartbeat",
 			Handler:    _MasterService_Heartbeat_Handler,
 		},
+		{
+			MethodName: "ListAgents",
+			Handler:    _MasterService_ListAgents_Handler,
+		},
+		{
+			MethodName: "GetAgentDetail",
+			Handler:    _MasterService_GetAgentDetail_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -197,6 +313,11 @@ var MasterService_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "ExecuteTask",
+			Handler:       _MasterService_ExecuteTask_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "proto/lookingglass.proto",
 }
@@ -9,12 +9,24 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/buffer"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Log file rotation defaults, applied when the Config leaves them at 0
+const (
+	defaultMaxSizeMB  = 100
+	defaultMaxBackups = 3
+	defaultMaxAgeDays = 28
 )
 
 var (
 	globalLogger *zap.Logger
 	once         sync.Once
 	mu           sync.RWMutex
+
+	// globalLevel controls the global logger's level at runtime; baseLevel is the configured one
+	globalLevel = zap.NewAtomicLevel()
+	baseLevel   = zapcore.InfoLevel
 )
 
 // Config represents logger configuration
@@ -22,6 +34,14 @@ type Config struct {
 	Level   string // debug, info, warn, error
 	File    string // log file path (empty for no file output)
 	Console bool   // output to console
+	Format  string // console (default: single line, fields merged into the message) or json
+
+	// Log file rotation (0 = default)
+	MaxSizeMB  int // Rotate the file once it reaches this size (default 100)
+	MaxBackups int // Rotated files to keep (default 3)
+	MaxAgeDays int // Delete rotated files older than this (default 28)
+
+	RingSize int // Recent lines kept in memory for Tail (0 = none; global logger only)
 }
 
 // Init initializes the global logger with the given configuration
@@ -29,11 +49,59 @@ type Config struct {
 func Init(cfg Config) error {
 	var err error
 	once.Do(func() {
-		globalLogger, err = buildLogger(cfg)
+		var level zapcore.Level
+		if level, err = parseLevel(cfg.Level); err != nil {
+			return
+		}
+		baseLevel = level
+		globalLevel.SetLevel(level)
+		globalLogger, err = buildLogger(cfg, globalLevel)
+		if err != nil {
+			return
+		}
+		// Skip the package-level helper frame so callers are reported, not this file
+		globalLogger = globalLogger.WithOptions(zap.AddCallerSkip(1))
+		if cfg.RingSize > 0 {
+			ring = newRingBuffer(cfg.RingSize)
+			encoder, _ := newEncoder(cfg.Format) // Format already checked by buildLogger
+			globalLogger = globalLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+				return zapcore.NewTee(core, &ringCore{LevelEnabler: globalLevel, encoder: encoder, buffer: ring})
+			}))
+		}
 	})
 	return err
 }
 
+// SetLevel changes the global logger's level at runtime, including loggers already derived from it
+func SetLevel(level string) error {
+	l, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	globalLevel.SetLevel(l)
+	return nil
+}
+
+// GetLevel returns the global logger's current level
+func GetLevel() string {
+	return globalLevel.Level().String()
+}
+
+// ResetLevel restores the global logger's configured level
+func ResetLevel() {
+	globalLevel.SetLevel(baseLevel)
+}
+
+// New builds a standalone logger (e.g. a dedicated audit log)
+// The global logger is not affected
+func New(cfg Config) (*zap.Logger, error) {
+	level, err := parseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	return buildLogger(cfg, zap.NewAtomicLevelAt(level))
+}
+
 // Get returns the global logger
 // If the logger hasn't been initialized, it returns a no-op logger
 func Get() *zap.Logger {
@@ -119,16 +187,49 @@ func (e *customEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field)
 	return e.Encoder.EncodeEntry(entry, nil)
 }
 
-// buildLogger creates a zap logger with the given configuration
-func buildLogger(cfg Config) (*zap.Logger, error) {
-	// Parse log level
+// parseLevel parses a level name (empty = info)
+func parseLevel(name string) (zapcore.Level, error) {
 	level := zapcore.InfoLevel
-	if cfg.Level != "" {
-		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
-			return nil, fmt.Errorf("invalid log level %q: %w", cfg.Level, err)
+	if name != "" {
+		if err := level.UnmarshalText([]byte(name)); err != nil {
+			return level, fmt.Errorf("invalid log level %q: %w", name, err)
 		}
 	}
+	return level, nil
+}
+
+// newEncoder creates the encoder shared by all outputs for a format name (empty = console)
+func newEncoder(format string) (zapcore.Encoder, error) {
+	switch format {
+	case "", "console":
+		return newConsoleEncoder(), nil
+	case "json":
+		return newJSONEncoder(), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q (want console or json)", format)
+	}
+}
 
+// newJSONEncoder creates an encoder writing one JSON object per entry, fields as their own keys
+func newJSONEncoder() zapcore.Encoder {
+	return zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		TimeKey:        "time",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		FunctionKey:    zapcore.OmitKey,
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	})
+}
+
+// newConsoleEncoder creates the single-line console encoder
+func newConsoleEncoder() zapcore.Encoder {
 	// Create encoder config for compact single-line format: time|level|caller|msg
 	encoderConfig := zapcore.EncoderConfig{
 		TimeKey:        "T",
@@ -146,6 +247,17 @@ func buildLogger(cfg Config) (*zap.Logger, error) {
 		ConsoleSeparator: "|",
 	}
 
+	// Use custom encoder to merge fields into message
+	return &customEncoder{Encoder: zapcore.NewConsoleEncoder(encoderConfig)}
+}
+
+// buildLogger creates a zap logger with the given configuration, filtered by level
+func buildLogger(cfg Config, level zap.AtomicLevel) (*zap.Logger, error) {
+	encoder, err := newEncoder(cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build output paths
 	outputPaths := []string{}
 	if cfg.Console {
@@ -164,10 +276,6 @@ func buildLogger(cfg Config) (*zap.Logger, error) {
 		outputPaths = []string{"stdout"}
 	}
 
-	// Use custom encoder to merge fields into message
-	baseEncoder := zapcore.NewConsoleEncoder(encoderConfig)
-	encoder := &customEncoder{Encoder: baseEncoder}
-
 	// Build cores for each output
 	var cores []zapcore.Core
 	for _, path := range outputPaths {
@@ -177,8 +285,9 @@ func buildLogger(cfg Config) (*zap.Logger, error) {
 		} else if path == "stderr" {
 			writeSyncer = zapcore.AddSync(os.Stderr)
 		} else {
-			file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-			if err != nil {
+			file := newRotatingFile(path, cfg)
+			// Open the file now so a bad path fails here rather than on the first log write
+			if _, err := file.Write(nil); err != nil {
 				return nil, fmt.Errorf("failed to open log file %q: %w", path, err)
 			}
 			writeSyncer = zapcore.AddSync(file)
@@ -190,11 +299,27 @@ func buildLogger(cfg Config) (*zap.Logger, error) {
 	core := zapcore.NewTee(cores...)
 
 	// Build logger with caller info
-	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
+	logger := zap.New(core, zap.AddCaller())
 
 	return logger, nil
 }
 
+// newRotatingFile returns a writer appending to path that rotates by size and prunes old files
+func newRotatingFile(path string, cfg Config) *lumberjack.Logger {
+	withDefault := func(v, def int) int {
+		if v <= 0 {
+			return def
+		}
+		return v
+	}
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    withDefault(cfg.MaxSizeMB, defaultMaxSizeMB),
+		MaxBackups: withDefault(cfg.MaxBackups, defaultMaxBackups),
+		MaxAge:     withDefault(cfg.MaxAgeDays, defaultMaxAgeDays),
+	}
+}
+
 // ensureLogDir ensures the directory for the log file exists
 func ensureLogDir(logFile string) error {
 	dir := logFile
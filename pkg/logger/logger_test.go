@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestNewReportsCallerOfLogCall(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit.log")
+	l, err := New(Config{Level: "info", File: file, Format: "json"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	l.Info("audit entry")
+	l.Sync()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	if !strings.Contains(string(data), "logger_test.go") {
+		t.Errorf("caller is not this file: %s", data)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	t.Cleanup(ResetLevel)
+
+	if err := SetLevel("debug"); err != nil {
+		t.Fatalf("SetLevel() error = %v", err)
+	}
+	if got := GetLevel(); got != "debug" {
+		t.Errorf("level = %q, want debug", got)
+	}
+	if err := SetLevel("verbose"); err == nil {
+		t.Error("SetLevel(verbose) accepted")
+	}
+	ResetLevel()
+	if got := GetLevel(); got != baseLevel.String() {
+		t.Errorf("level after reset = %q, want %q", got, baseLevel)
+	}
+}
+
+func TestLogFormat(t *testing.T) {
+	read := func(format string) string {
+		t.Helper()
+		file := filepath.Join(t.TempDir(), "app.log")
+		l, err := New(Config{Level: "info", File: file, Format: format})
+		if err != nil {
+			t.Fatalf("New(%q) error = %v", format, err)
+		}
+		l.Info("agent connected", zap.String("agent_id", "a1"), zap.Int("tasks", 3))
+		l.Sync()
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read log: %v", err)
+		}
+		return strings.TrimSpace(string(data))
+	}
+
+	// JSON: one object per line, fields as their own keys
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(read("json")), &entry); err != nil {
+		t.Fatalf("json line does not decode: %v", err)
+	}
+	if entry["msg"] != "agent connected" || entry["level"] != "info" || entry["agent_id"] != "a1" || entry["tasks"] != float64(3) {
+		t.Errorf("json entry = %v", entry)
+	}
+
+	// Console (the default): fields merged into the message
+	line := read("")
+	if !strings.Contains(line, "|INFO|") || !strings.Contains(line, "agent connected [agent_id=a1, tasks=3]") {
+		t.Errorf("console line = %q", line)
+	}
+
+	if _, err := New(Config{Format: "xml"}); err == nil {
+		t.Error("New() accepted an unknown format")
+	}
+}
+
+func TestNewRotatingFileDefaults(t *testing.T) {
+	f := newRotatingFile("app.log", Config{})
+	if f.MaxSize != defaultMaxSizeMB || f.MaxBackups != defaultMaxBackups || f.MaxAge != defaultMaxAgeDays {
+		t.Errorf("defaults = %d MB, %d backups, %d days", f.MaxSize, f.MaxBackups, f.MaxAge)
+	}
+
+	f = newRotatingFile("app.log", Config{MaxSizeMB: 5, MaxBackups: 1, MaxAgeDays: 7})
+	if f.MaxSize != 5 || f.MaxBackups != 1 || f.MaxAge != 7 {
+		t.Errorf("configured = %d MB, %d backups, %d days, want 5, 1, 7", f.MaxSize, f.MaxBackups, f.MaxAge)
+	}
+}
+
+func TestLogFileRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(Config{Level: "info", File: filepath.Join(dir, "app.log"), MaxSizeMB: 1, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// Just over 1 MB of entries forces one rotation
+	line := strings.Repeat("x", 1024)
+	for range 1100 {
+		l.Info(line)
+	}
+	l.Sync()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("log directory = %q, want the active file and one rotated backup", names)
+	}
+}
+
+func TestLogFileBadPath(t *testing.T) {
+	// A regular file where the log directory should be
+	parent := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(parent, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(Config{File: filepath.Join(parent, "app.log")}); err == nil {
+		t.Error("New() accepted a log file that cannot be opened")
+	}
+}
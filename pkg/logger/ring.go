@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ring keeps the most recent log lines of the global logger (nil = not kept)
+var ring *ringBuffer
+
+// ringBuffer is a fixed-size buffer of formatted log lines
+type ringBuffer struct {
+	mutex sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+// newRingBuffer creates a buffer holding up to size lines
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{lines: make([]string, size)}
+}
+
+// add stores a line, overwriting the oldest once full
+func (r *ringBuffer) add(line string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// tail returns up to n of the most recent lines, oldest first (n <= 0 = all)
+func (r *ringBuffer) tail(n int) []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var all []string
+	if r.full {
+		all = append(append(all, r.lines[r.next:]...), r.lines[:r.next]...)
+	} else {
+		all = append(all, r.lines[:r.next]...)
+	}
+	if n > 0 && n < len(all) {
+		all = all[len(all)-n:]
+	}
+	return all
+}
+
+// ringCore is a zapcore.Core writing formatted entries into a ringBuffer
+type ringCore struct {
+	zapcore.LevelEnabler
+	encoder zapcore.Encoder
+	buffer  *ringBuffer
+}
+
+// With adds structured context to the core
+func (c *ringCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &ringCore{LevelEnabler: c.LevelEnabler, encoder: c.encoder.Clone(), buffer: c.buffer}
+	for _, field := range fields {
+		field.AddTo(clone.encoder)
+	}
+	return clone
+}
+
+// Check adds the core to the checked entry if the level is enabled
+func (c *ringCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write formats the entry as a single line and stores it
+func (c *ringCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	c.buffer.add(strings.TrimSuffix(buf.String(), "\n"))
+	buf.Free()
+	return nil
+}
+
+// Sync is a no-op: the buffer is in memory
+func (c *ringCore) Sync() error {
+	return nil
+}
+
+// Tail returns up to n of the most recent lines logged by the global logger, oldest first
+// It reports false if the logger was initialized without a ring buffer (Config.RingSize)
+func Tail(n int) ([]string, bool) {
+	if ring == nil {
+		return nil, false
+	}
+	return ring.tail(n), true
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRingBufferTail(t *testing.T) {
+	r := newRingBuffer(3)
+	if got := r.tail(0); len(got) != 0 {
+		t.Errorf("empty tail = %q", got)
+	}
+
+	r.add("a")
+	r.add("b")
+	if got := r.tail(0); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("tail before wrapping = %q, want [a b]", got)
+	}
+
+	// The oldest lines are overwritten once full
+	r.add("c")
+	r.add("d")
+	r.add("e")
+	if got := r.tail(0); !reflect.DeepEqual(got, []string{"c", "d", "e"}) {
+		t.Errorf("tail after wrapping = %q, want [c d e]", got)
+	}
+	if got := r.tail(2); !reflect.DeepEqual(got, []string{"d", "e"}) {
+		t.Errorf("tail(2) = %q, want [d e]", got)
+	}
+	if got := r.tail(10); len(got) != 3 {
+		t.Errorf("tail(10) = %q, want all 3 lines", got)
+	}
+}
+
+func TestRingCoreKeepsEnabledEntries(t *testing.T) {
+	encoder, err := newEncoder("console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := newRingBuffer(10)
+	l := zap.New(&ringCore{LevelEnabler: zapcore.InfoLevel, encoder: encoder, buffer: buffer})
+
+	l.Debug("hidden")
+	l.With(zap.String("agent_id", "a1")).Info("connected")
+
+	lines := buffer.tail(0)
+	if len(lines) != 1 {
+		t.Fatalf("kept %d lines, want 1 (debug is below the level): %q", len(lines), lines)
+	}
+	if !strings.Contains(lines[0], "connected") || !strings.Contains(lines[0], "a1") || strings.HasSuffix(lines[0], "\n") {
+		t.Errorf("line = %q, want the message and its fields on one line", lines[0])
+	}
+}
+
+func TestTailWithoutRing(t *testing.T) {
+	saved := ring
+	t.Cleanup(func() { ring = saved })
+
+	ring = nil
+	if _, ok := Tail(10); ok {
+		t.Error("Tail() reported lines without a ring buffer")
+	}
+
+	ring = newRingBuffer(2)
+	ring.add("line")
+	if lines, ok := Tail(10); !ok || !reflect.DeepEqual(lines, []string{"line"}) {
+		t.Errorf("Tail() = %q, %v", lines, ok)
+	}
+}
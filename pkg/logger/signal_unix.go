@@ -0,0 +1,32 @@
+//go:build !windows
+
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// HandleLevelSignals switches the global logger to debug on SIGUSR1 and back to
+// the configured level on SIGUSR2, for debugging a running process without a restart
+func HandleLevelSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGUSR1 {
+				globalLevel.SetLevel(zap.DebugLevel)
+			} else {
+				ResetLevel()
+			}
+			Get().Warn("Log level changed by signal",
+				zap.String("signal", sig.String()),
+				zap.String("level", GetLevel()),
+			)
+		}
+	}()
+}
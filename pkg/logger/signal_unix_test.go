@@ -0,0 +1,34 @@
+//go:build !windows
+
+package logger
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleLevelSignals(t *testing.T) {
+	t.Cleanup(ResetLevel)
+	HandleLevelSignals()
+
+	waitLevel := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for GetLevel() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("level = %q, want %q", GetLevel(), want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	waitLevel("debug")
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	waitLevel(baseLevel.String())
+}
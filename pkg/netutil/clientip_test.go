@@ -0,0 +1,54 @@
+package netutil
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	if _, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 127.0.0.1 ", "::1"}); err != nil {
+		t.Errorf("valid entries: %v", err)
+	}
+	for _, entry := range []string{"proxy.local", "10.0.0.0/33"} {
+		if _, err := ParseTrustedProxies([]string{entry}); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) accepted", entry)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		proxies *TrustedProxies
+		remote  string
+		xff     string
+		realIP  string
+		want    string
+	}{
+		{"no proxies trusted", nil, "10.0.0.1:443", "203.0.113.7", "", "10.0.0.1"},
+		{"untrusted peer", proxies, "198.51.100.1:443", "203.0.113.7", "", "198.51.100.1"},
+		{"trusted peer", proxies, "10.0.0.1:443", "203.0.113.7", "", "203.0.113.7"},
+		{"spoofed prefix", proxies, "10.0.0.1:443", "192.0.2.1, 203.0.113.7, 10.0.0.2", "", "203.0.113.7"},
+		{"garbage hop", proxies, "10.0.0.1:443", "junk, 10.0.0.2", "203.0.113.9", "203.0.113.9"},
+		{"real ip", proxies, "10.0.0.1:443", "", "203.0.113.9", "203.0.113.9"},
+		{"no headers", proxies, "10.0.0.1:443", "", "", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.xff != "" {
+			r.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if got := tt.proxies.ClientIP(r); got != tt.want {
+			t.Errorf("%s: ClientIP() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
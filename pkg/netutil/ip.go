@@ -9,14 +9,30 @@ import (
 	"time"
 )
 
+// DefaultIPv4Services 默认的公网 IPv4 检测服务，按顺序尝试
+var DefaultIPv4Services = []string{
+	"https://api.ipify.org",
+	"https://ifconfig.me/ip",
+	"https://icanhazip.com",
+	"https://ident.me",
+}
+
+// DefaultIPv6Services 默认的公网 IPv6 检测服务，按顺序尝试
+var DefaultIPv6Services = []string{
+	"https://api6.ipify.org",
+	"https://ifconfig.co/ip",
+}
+
 // GetPublicIPv4 获取公网 IPv4 地址
 func GetPublicIPv4() (string, error) {
+	return GetPublicIPv4WithServices(nil)
+}
+
+// GetPublicIPv4WithServices 使用指定的检测服务获取公网 IPv4 地址（为空时使用 DefaultIPv4Services）
+func GetPublicIPv4WithServices(services []string) (string, error) {
 	// 尝试多个服务，提高成功率
-	services := []string{
-		"https://api.ipify.org",
-		"https://ifconfig.me/ip",
-		"https://icanhazip.com",
-		"https://ident.me",
+	if len(services) == 0 {
+		services = DefaultIPv4Services
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -35,10 +51,14 @@ func GetPublicIPv4() (string, error) {
 
 // GetPublicIPv6 获取公网 IPv6 地址
 func GetPublicIPv6() (string, error) {
+	return GetPublicIPv6WithServices(nil)
+}
+
+// GetPublicIPv6WithServices 使用指定的检测服务获取公网 IPv6 地址（为空时使用 DefaultIPv6Services）
+func GetPublicIPv6WithServices(services []string) (string, error) {
 	// 尝试多个支持 IPv6 的服务
-	services := []string{
-		"https://api6.ipify.org",
-		"https://ifconfig.co/ip",
+	if len(services) == 0 {
+		services = DefaultIPv6Services
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -0,0 +1,39 @@
+package netutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// ipService returns a detection service answering body
+func ipService(t *testing.T, body string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body + "\n"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestGetPublicIPv4WithServices(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	down := closed.URL
+	closed.Close()
+	invalid := ipService(t, "<html>rate limited</html>")
+	ipv6 := ipService(t, "2001:db8::1")
+	ok := ipService(t, "203.0.113.7")
+
+	// Services are tried in order until one answers with an IPv4 address
+	ip, err := GetPublicIPv4WithServices([]string{down, invalid, ipv6, ok, ipService(t, "198.51.100.1")})
+	if err != nil || ip != "203.0.113.7" {
+		t.Errorf("GetPublicIPv4WithServices() = %q, %v, want 203.0.113.7", ip, err)
+	}
+}
+
+func TestGetPublicIPv6WithServices(t *testing.T) {
+	ip, err := GetPublicIPv6WithServices([]string{ipService(t, "203.0.113.7"), ipService(t, "2001:db8::1")})
+	if err != nil || ip != "2001:db8::1" {
+		t.Errorf("GetPublicIPv6WithServices() = %q, %v, want 2001:db8::1", ip, err)
+	}
+}
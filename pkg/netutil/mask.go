@@ -0,0 +1,35 @@
+package netutil
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// MaskIP 隐藏 IP 地址的中间部分，用于 hide_ip
+// IPv4 保留前两段: 203.0.113.7 -> 203.0.*.*
+// IPv6 保留首尾两段: 2001:db8::7334 -> 2001:****:****:****:****:****:****:7334
+// 无法解析的地址原样返回
+func MaskIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+
+	if v4 := parsed.To4(); v4 != nil && !strings.Contains(ip, ":") {
+		return fmt.Sprintf("%d.%d.*.*", v4[0], v4[1])
+	}
+
+	v6 := parsed.To16()
+	first := uint16(v6[0])<<8 | uint16(v6[1])
+	last := uint16(v6[14])<<8 | uint16(v6[15])
+	return fmt.Sprintf("%04x:****:****:****:****:****:****:%04x", first, last)
+}
+
+// DisplayIP 返回可展示给用户的地址: hide 为 true 时经 MaskIP 隐藏，空地址保持为空
+func DisplayIP(ip string, hide bool) string {
+	if !hide || ip == "" {
+		return ip
+	}
+	return MaskIP(ip)
+}
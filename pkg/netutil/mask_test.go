@@ -0,0 +1,33 @@
+package netutil
+
+import "testing"
+
+func TestMaskIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"203.0.113.7", "203.0.*.*"},
+		{"2001:db8::7334", "2001:****:****:****:****:****:****:7334"},
+		{"fe80::1", "fe80:****:****:****:****:****:****:0001"},
+		{"::ffff:203.0.113.7", "0000:****:****:****:****:****:****:7107"},
+		{"not-an-ip", "not-an-ip"},
+	}
+	for _, tt := range tests {
+		if got := MaskIP(tt.ip); got != tt.want {
+			t.Errorf("MaskIP(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayIP(t *testing.T) {
+	if got := DisplayIP("203.0.113.7", false); got != "203.0.113.7" {
+		t.Errorf("DisplayIP(hide=false) = %q", got)
+	}
+	if got := DisplayIP("203.0.113.7", true); got != "203.0.*.*" {
+		t.Errorf("DisplayIP(hide=true) = %q", got)
+	}
+	if got := DisplayIP("", true); got != "" {
+		t.Errorf("DisplayIP(empty) = %q", got)
+	}
+}
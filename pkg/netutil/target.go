@@ -0,0 +1,192 @@
+package netutil
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// targetResolveTimeout bounds the lookup used to check what a hostname target resolves to
+const targetResolveTimeout = 5 * time.Second
+
+// TargetDenylist blocks task targets by IP range or hostname pattern
+// A nil *TargetDenylist blocks nothing
+type TargetDenylist struct {
+	nets     []*net.IPNet
+	globs    []string
+	patterns []*regexp.Regexp
+}
+
+// ParseTargetDenylist parses denylist entries, each one of:
+//   - an IP or CIDR (e.g. "169.254.169.254", "10.0.0.0/8"), matched against IP targets and
+//     against every address a hostname target resolves to
+//   - a hostname glob (e.g. "*.internal.corp", "localhost"), matched case-insensitively
+//   - a regular expression prefixed with "re:" (e.g. "re:^metadata\."), matched against the hostname
+//
+// No entries yields a nil *TargetDenylist, which blocks nothing
+func ParseTargetDenylist(entries []string) (*TargetDenylist, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	d := &TargetDenylist{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+			return nil, fmt.Errorf("empty target denylist entry")
+		case strings.HasPrefix(entry, "re:"):
+			re, err := regexp.Compile("(?i)" + strings.TrimPrefix(entry, "re:"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid target denylist pattern %s: %w", entry, err)
+			}
+			d.patterns = append(d.patterns, re)
+		case net.ParseIP(entry) != nil:
+			ip := net.ParseIP(entry)
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			d.nets = append(d.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		case strings.Contains(entry, "/"):
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid target denylist CIDR: %s", entry)
+			}
+			d.nets = append(d.nets, ipNet)
+		default:
+			glob := strings.ToLower(strings.TrimSuffix(entry, "."))
+			if _, err := path.Match(glob, ""); err != nil {
+				return nil, fmt.Errorf("invalid target denylist glob: %s", entry)
+			}
+			d.globs = append(d.globs, glob)
+		}
+	}
+	return d, nil
+}
+
+// Check returns an error if target is denied; an empty target is not checked
+// Hostnames are matched against the patterns before resolution and, when IP ranges are
+// configured, every address they resolve to is checked as well. The check fails closed:
+// a target without a host, or a hostname that cannot be resolved, is denied.
+func (d *TargetDenylist) Check(ctx context.Context, target string) error {
+	if d == nil || strings.TrimSpace(target) == "" {
+		return nil
+	}
+	_, err := d.check(ctx, target, "ip", len(d.nets) > 0)
+	return err
+}
+
+// DialContext returns a dial function that resolves each address once, checks every resolved
+// IP and connects only to those IPs, so a name cannot re-resolve to a denied address between
+// the check and the connection. A nil *TargetDenylist dials directly.
+func (d *TargetDenylist) DialContext(dialer *net.Dialer, network string) func(ctx context.Context, _, addr string) (net.Conn, error) {
+	return func(ctx context.Context, _, addr string) (net.Conn, error) {
+		if d == nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		lookupNetwork := "ip"
+		switch network {
+		case "tcp4":
+			lookupNetwork = "ip4"
+		case "tcp6":
+			lookupNetwork = "ip6"
+		}
+		ips, err := d.check(ctx, addr, lookupNetwork, true)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// check denies target by pattern and IP range and returns the IPs it checked
+// With resolve set, a hostname is resolved over lookupNetwork and every address is checked
+func (d *TargetDenylist) check(ctx context.Context, target, lookupNetwork string, resolve bool) ([]net.IP, error) {
+	host := targetHost(target)
+	if host == "" {
+		return nil, fmt.Errorf("target %s has no host to check against policy", target)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if n := d.matchIP(ip); n != nil {
+			return nil, fmt.Errorf("target %s is blocked by policy (%s)", target, n)
+		}
+		return []net.IP{ip}, nil
+	}
+
+	for _, glob := range d.globs {
+		if ok, _ := path.Match(glob, host); ok {
+			return nil, fmt.Errorf("target %s is blocked by policy (%s)", target, glob)
+		}
+	}
+	for _, re := range d.patterns {
+		if re.MatchString(host) {
+			return nil, fmt.Errorf("target %s is blocked by policy (re:%s)", target, strings.TrimPrefix(re.String(), "(?i)"))
+		}
+	}
+
+	if !resolve {
+		return nil, nil
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, targetResolveTimeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupIP(lookupCtx, lookupNetwork, host)
+	if err != nil {
+		return nil, fmt.Errorf("target %s could not be resolved to check it against policy: %w", target, err)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("target %s did not resolve to any address", target)
+	}
+	for _, ip := range ips {
+		if n := d.matchIP(ip); n != nil {
+			return nil, fmt.Errorf("target %s resolves to %s, which is blocked by policy (%s)", target, ip, n)
+		}
+	}
+	return ips, nil
+}
+
+// matchIP returns the denied network containing ip, if any
+func (d *TargetDenylist) matchIP(ip net.IP) *net.IPNet {
+	for _, n := range d.nets {
+		if n.Contains(ip) {
+			return n
+		}
+	}
+	return nil
+}
+
+// targetHost extracts the lowercased host from a target that may be a URL, host:port or [IPv6]
+func targetHost(target string) string {
+	target = strings.TrimSpace(target)
+	if strings.Contains(target, "://") {
+		if u, err := url.Parse(target); err == nil {
+			target = u.Host
+		}
+	} else if i := strings.Index(target, "/"); i >= 0 {
+		target = target[:i]
+	}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		target = host
+	}
+	target = strings.TrimSuffix(strings.TrimPrefix(target, "["), "]")
+	return strings.ToLower(strings.TrimSuffix(target, "."))
+}
@@ -0,0 +1,102 @@
+package netutil
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseTargetDenylist(t *testing.T) {
+	if _, err := ParseTargetDenylist([]string{"10.0.0.0/8", "169.254.169.254", "*.internal", "re:^metadata\\."}); err != nil {
+		t.Fatalf("ParseTargetDenylist() error = %v", err)
+	}
+	for _, bad := range []string{"", "10.0.0.0/99", "re:(", "[a-"} {
+		if _, err := ParseTargetDenylist([]string{bad}); err == nil {
+			t.Errorf("ParseTargetDenylist(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestTargetDenylistCheck(t *testing.T) {
+	d, err := ParseTargetDenylist([]string{"10.0.0.0/8", "169.254.169.254", "fd00::/8", "*.internal.corp", "re:^metadata\\."})
+	if err != nil {
+		t.Fatalf("ParseTargetDenylist() error = %v", err)
+	}
+
+	tests := []struct {
+		target  string
+		blocked bool
+	}{
+		{"", false},
+		{"10.1.2.3", true},
+		{"169.254.169.254", true},
+		{"http://169.254.169.254/latest/meta-data", true},
+		{"10.1.2.3:8080", true},
+		{"[fd00::1]:53", true},
+		{"db.internal.corp", true},
+		{"DB.Internal.Corp.", true},
+		{"metadata.google.internal", true},
+		{"1.1.1.1", false},
+		{"https://1.1.1.1/", false},
+		{"localhost", false},
+		// Fail closed: no host, or a name that cannot be resolved while IP ranges are configured
+		{"http://", true},
+		{"unresolvable.invalid", true},
+	}
+
+	for _, tt := range tests {
+		err := d.Check(context.Background(), tt.target)
+		if (err != nil) != tt.blocked {
+			t.Errorf("Check(%q) error = %v, want blocked %v", tt.target, err, tt.blocked)
+		}
+	}
+}
+
+func TestTargetDenylistCheckResolvedAddress(t *testing.T) {
+	d, err := ParseTargetDenylist([]string{"127.0.0.0/8", "::1"})
+	if err != nil {
+		t.Fatalf("ParseTargetDenylist() error = %v", err)
+	}
+	if err := d.Check(context.Background(), "localhost"); err == nil {
+		t.Error("Check(localhost) allowed a name resolving to a denied range")
+	}
+}
+
+func TestNilTargetDenylistAllowsEverything(t *testing.T) {
+	var d *TargetDenylist
+	if err := d.Check(context.Background(), "10.0.0.1"); err != nil {
+		t.Errorf("nil denylist Check() error = %v", err)
+	}
+}
+
+func TestTargetDenylistDialContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	dialer := &net.Dialer{Timeout: time.Second}
+
+	// Allowed: the checked address is the one connected to
+	allow, _ := ParseTargetDenylist([]string{"10.0.0.0/8"})
+	conn, err := allow.DialContext(dialer, "tcp4")(context.Background(), "tcp", net.JoinHostPort("localhost", port))
+	if err != nil {
+		t.Fatalf("dial allowed address: %v", err)
+	}
+	conn.Close()
+
+	// Denied: the name resolves into a denied range, so nothing is dialed
+	deny, _ := ParseTargetDenylist([]string{"127.0.0.0/8"})
+	if _, err := deny.DialContext(dialer, "tcp4")(context.Background(), "tcp", net.JoinHostPort("localhost", port)); err == nil {
+		t.Error("dial succeeded to a denied address")
+	}
+
+	// Nil denylist dials directly
+	var none *TargetDenylist
+	conn, err = none.DialContext(dialer, "tcp4")(context.Background(), "tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("nil denylist dial: %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,49 @@
+package outputblock
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MaxRawBytes is how many bytes of output lines a sender packs into one block before starting another
+// Gzip adds at most a few bytes per 32 KiB of incompressible input, so a block stays well under
+// the 4 MiB default gRPC message limit
+const MaxRawBytes = 1 << 20
+
+// Encode packs output lines into one gzip block, lines joined by "\n"
+func Encode(lines []string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(zw, strings.Join(lines, "\n")); err != nil {
+		return nil, fmt.Errorf("failed to compress output: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress output: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode unpacks a block made by Encode
+// Blocks that expand to more than maxBytes are rejected so a small block cannot exhaust memory
+func Decode(block []byte, maxBytes int64) ([]string, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(block))
+	if err != nil {
+		return nil, fmt.Errorf("invalid compressed output: %w", err)
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(io.LimitReader(zr, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid compressed output: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("compressed output exceeds %d bytes", maxBytes)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return strings.Split(string(data), "\n"), nil
+}
@@ -0,0 +1,31 @@
+package outputblock
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	lines := []string{"first", "", "third"}
+	block, err := Encode(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(block, 1024)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if strings.Join(got, "|") != strings.Join(lines, "|") {
+		t.Errorf("Decode() = %q, want %q", got, lines)
+	}
+}
+
+func TestDecodeRejectsOversizedBlock(t *testing.T) {
+	block, err := Encode([]string{strings.Repeat("a", 2048)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decode(block, 1024); err == nil {
+		t.Error("Decode() accepted a block expanding past maxBytes")
+	}
+}
@@ -0,0 +1,85 @@
+// Package params validates task parameters against the schema a task declares
+// Used by the master before dispatch and by agents before running a command
+package params
+
+import (
+	"fmt"
+	"strconv"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+// builtinParams are the parameters carried in dedicated NetworkTestParams fields;
+// any other schema name refers to an extra_options key
+var builtinParams = map[string]bool{
+	"target":  true,
+	"count":   true,
+	"timeout": true,
+	"ipv6":    true,
+}
+
+// Validate checks task parameters against a task's declared schema
+// An empty schema accepts anything; otherwise undeclared extra options are rejected
+func Validate(schema []*pb.ParamSchema, params *pb.NetworkTestParams) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	declared := make(map[string]bool, len(schema))
+	for _, p := range schema {
+		declared[p.Name] = true
+
+		value, present := paramValue(p.Name, params)
+		if !present {
+			if p.Required {
+				return fmt.Errorf("%s is required", p.Name)
+			}
+			continue
+		}
+
+		switch p.Type {
+		case "int":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s must be an integer, got %q", p.Name, value)
+			}
+			if p.Min != nil && n < *p.Min {
+				return fmt.Errorf("%s must be at least %d", p.Name, *p.Min)
+			}
+			if p.Max != nil && n > *p.Max {
+				return fmt.Errorf("%s must be at most %d", p.Name, *p.Max)
+			}
+		case "bool":
+			if _, err := strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("%s must be true or false, got %q", p.Name, value)
+			}
+		}
+	}
+
+	for key := range params.GetExtraOptions() {
+		if !declared[key] {
+			return fmt.Errorf("unknown parameter: %s", key)
+		}
+	}
+	return nil
+}
+
+// paramValue returns a parameter as a string, reporting whether it was set
+// Zero values of the dedicated fields count as unset
+func paramValue(name string, params *pb.NetworkTestParams) (string, bool) {
+	if !builtinParams[name] {
+		value, ok := params.GetExtraOptions()[name]
+		return value, ok && value != ""
+	}
+
+	switch name {
+	case "target":
+		return params.GetTarget(), params.GetTarget() != ""
+	case "count":
+		return strconv.Itoa(int(params.GetCount())), params.GetCount() != 0
+	case "timeout":
+		return strconv.Itoa(int(params.GetTimeout())), params.GetTimeout() != 0
+	default: // ipv6
+		return strconv.FormatBool(params.GetIpv6()), params.GetIpv6()
+	}
+}
@@ -0,0 +1,46 @@
+package params
+
+import (
+	"testing"
+
+	pb "github.com/lureiny/lookingglass/pb"
+)
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestValidate(t *testing.T) {
+	// Schema of a port-check task: target and port required, port within range
+	schema := []*pb.ParamSchema{
+		{Name: "target", Type: "string", Required: true},
+		{Name: "port", Type: "int", Required: true, Min: int64Ptr(1), Max: int64Ptr(65535)},
+	}
+
+	tests := []struct {
+		name    string
+		params  *pb.NetworkTestParams
+		wantErr bool
+	}{
+		{"valid", &pb.NetworkTestParams{Target: "example.com", ExtraOptions: map[string]string{"port": "443"}}, false},
+		{"missing port", &pb.NetworkTestParams{Target: "example.com"}, true},
+		{"missing target", &pb.NetworkTestParams{ExtraOptions: map[string]string{"port": "443"}}, true},
+		{"port not an integer", &pb.NetworkTestParams{Target: "example.com", ExtraOptions: map[string]string{"port": "https"}}, true},
+		{"port out of range", &pb.NetworkTestParams{Target: "example.com", ExtraOptions: map[string]string{"port": "70000"}}, true},
+		{"undeclared option", &pb.NetworkTestParams{Target: "example.com", ExtraOptions: map[string]string{"port": "443", "flags": "x"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(schema, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateEmptySchemaAcceptsAnything(t *testing.T) {
+	params := &pb.NetworkTestParams{ExtraOptions: map[string]string{"anything": "goes"}}
+	if err := Validate(nil, params); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
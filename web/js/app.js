@@ -431,8 +431,11 @@ class LookingGlassApp {
             agentItem.classList.add('expanded');
         }
 
-        const statusText = agent.status === 1 ? 'online' : 'offline';
-        const statusClass = agent.status === 1 ? 'online' : 'offline';
+        let statusText = agent.status === 1 ? 'online' : 'offline';
+        if (agent.status === 1 && agent.degraded) {  // Dispatch paused after repeated failures
+            statusText = 'degraded';
+        }
+        const statusClass = statusText;
 
         // Compact info line: IDC • Provider • Location
         const infoLine = [
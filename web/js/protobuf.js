@@ -97,6 +97,7 @@ message AgentStatusInfo {
     repeated CustomCommandInfo custom_commands = 13;
     repeated string task_names = 14;
     repeated TaskDisplayInfo task_display_info = 15;
+    bool degraded = 21;
 }
 
 message WSResponse {